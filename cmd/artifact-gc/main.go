@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"sigs.k8s.io/prow/pkg/pjutil/pprof"
+
+	"sigs.k8s.io/prow/pkg/artifactgc"
+	"sigs.k8s.io/prow/pkg/flagutil"
+	configflagutil "sigs.k8s.io/prow/pkg/flagutil/config"
+	"sigs.k8s.io/prow/pkg/interrupts"
+	"sigs.k8s.io/prow/pkg/logrusutil"
+	"sigs.k8s.io/prow/pkg/metrics"
+	_ "sigs.k8s.io/prow/pkg/version"
+)
+
+type options struct {
+	runOnce                bool
+	config                 configflagutil.ConfigOptions
+	dryRun                 bool
+	storage                flagutil.StorageClientOptions
+	instrumentationOptions flagutil.InstrumentationOptions
+}
+
+func gatherOptions(fs *flag.FlagSet, args ...string) options {
+	o := options{}
+	fs.BoolVar(&o.runOnce, "run-once", false, "If true, run only once then quit, printing the report of the deleted runs.")
+
+	fs.BoolVar(&o.dryRun, "dry-run", true, "Whether or not to delete the runs from job storage. In dry-run the runs to delete are only reported.")
+
+	for _, group := range []flagutil.OptionGroup{&o.config, &o.storage, &o.instrumentationOptions} {
+		group.AddFlags(fs)
+	}
+	fs.Parse(args)
+	return o
+}
+
+func (o *options) Validate() error {
+	for _, group := range []flagutil.OptionGroup{&o.config, &o.storage} {
+		if err := group.Validate(o.dryRun); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func main() {
+	logrusutil.ComponentInit()
+
+	o := gatherOptions(flag.NewFlagSet(os.Args[0], flag.ExitOnError), os.Args[1:]...)
+	if err := o.Validate(); err != nil {
+		logrus.WithError(err).Fatal("Invalid options")
+	}
+
+	defer interrupts.WaitForGracefulShutdown()
+
+	pprof.Instrument(o.instrumentationOptions)
+
+	configAgent, err := o.config.ConfigAgent()
+	if err != nil {
+		logrus.WithError(err).Fatal("Error starting config agent.")
+	}
+	cfg := configAgent.Config
+
+	metrics.ExposeMetrics("artifact-gc", cfg().PushGateway, o.instrumentationOptions.MetricsPort)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	opener, err := o.storage.StorageClient(ctx)
+	if err != nil {
+		logrus.WithError(err).Fatal("Cannot create opener")
+	}
+
+	c := artifactgc.NewController(opener, cfg, o.dryRun)
+
+	if o.runOnce {
+		report, err := c.Sync(interrupts.Context())
+		if err != nil {
+			logrus.WithError(err).Error("Failed to collect some runs.")
+		}
+		if err := json.NewEncoder(os.Stdout).Encode(report); err != nil {
+			logrus.WithError(err).Fatal("Failed to print the report.")
+		}
+		return
+	}
+
+	interrupts.Tick(func() {
+		start := time.Now()
+		report, err := c.Sync(interrupts.Context())
+		if err != nil {
+			logrus.WithError(err).Error("Failed to collect some runs.")
+		}
+		logrus.WithFields(logrus.Fields{
+			"duration": time.Since(start).String(),
+			"runs":     len(report.Runs),
+			"bytes":    report.Bytes,
+			"dry-run":  o.dryRun,
+		}).Info("Synced.")
+	}, func() time.Duration {
+		return cfg().ArtifactGC.ResyncPeriod.Duration
+	})
+}
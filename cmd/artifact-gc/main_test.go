@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/prow/pkg/flagutil"
+	configflagutil "sigs.k8s.io/prow/pkg/flagutil/config"
+)
+
+func TestGatherOptions(t *testing.T) {
+	cases := []struct {
+		name        string
+		args        []string
+		expected    func(*options)
+		expectedErr bool
+	}{
+		{
+			name: "minimal flags work",
+		},
+		{
+			name: "run-once and dry-run=false",
+			args: []string{"--run-once", "--dry-run=false"},
+			expected: func(o *options) {
+				o.runOnce = true
+				o.dryRun = false
+			},
+		},
+		{
+			name: "gcs-credentials-file sets the GCS credentials on the storage client",
+			args: []string{"--gcs-credentials-file=/creds"},
+			expected: func(o *options) {
+				o.storage = flagutil.StorageClientOptions{
+					GCSCredentialsFile: "/creds",
+				}
+			},
+		},
+		{
+			name:        "reserved S3-compatible scheme is rejected",
+			args:        []string{"--s3-compatible-credentials-file=gs=/creds"},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			expected := &options{
+				dryRun: true,
+				config: configflagutil.ConfigOptions{
+					ConfigPath:                            "yo",
+					ConfigPathFlagName:                    "config-path",
+					JobConfigPathFlagName:                 "job-config-path",
+					SupplementalProwConfigsFileNameSuffix: "_prowconfig.yaml",
+					InRepoConfigCacheSize:                 200,
+				},
+				instrumentationOptions: flagutil.DefaultInstrumentationOptions(),
+			}
+			if tc.expected != nil {
+				tc.expected(expected)
+			}
+
+			fs := flag.NewFlagSet("fake-flags", flag.PanicOnError)
+			actual := gatherOptions(fs, append(tc.args, "--config-path=yo")...)
+			err := actual.Validate()
+			if tc.expectedErr {
+				if err == nil {
+					t.Error("expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(*expected, actual, cmp.Exporter(func(_ reflect.Type) bool { return true })); diff != "" {
+				t.Errorf("options differ from expected (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
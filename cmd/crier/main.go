@@ -35,6 +35,7 @@ import (
 	"sigs.k8s.io/prow/pkg/config"
 	"sigs.k8s.io/prow/pkg/config/secret"
 	"sigs.k8s.io/prow/pkg/crier"
+	chatreporter "sigs.k8s.io/prow/pkg/crier/reporters/chat"
 	gcsreporter "sigs.k8s.io/prow/pkg/crier/reporters/gcs"
 	k8sgcsreporter "sigs.k8s.io/prow/pkg/crier/reporters/gcs/kubernetes"
 	gerritreporter "sigs.k8s.io/prow/pkg/crier/reporters/gerrit"
@@ -42,12 +43,14 @@ import (
 	pubsubreporter "sigs.k8s.io/prow/pkg/crier/reporters/pubsub"
 	resultstorereporter "sigs.k8s.io/prow/pkg/crier/reporters/resultstore"
 	slackreporter "sigs.k8s.io/prow/pkg/crier/reporters/slack"
+	webhookreporter "sigs.k8s.io/prow/pkg/crier/reporters/webhook"
 	prowflagutil "sigs.k8s.io/prow/pkg/flagutil"
 	configflagutil "sigs.k8s.io/prow/pkg/flagutil/config"
 	"sigs.k8s.io/prow/pkg/interrupts"
 	"sigs.k8s.io/prow/pkg/logrusutil"
 	"sigs.k8s.io/prow/pkg/metrics"
 	slackclient "sigs.k8s.io/prow/pkg/slack"
+	"sigs.k8s.io/prow/pkg/tracing"
 )
 
 type options struct {
@@ -66,10 +69,20 @@ type options struct {
 	blobStorageWorkers    int
 	k8sBlobStorageWorkers int
 	resultStoreWorkers    int
+	webhookWorkers        int
+	larkWorkers           int
+	teamsWorkers          int
 
 	slackTokenFile            string
 	additionalSlackTokenFiles slackclient.HostsFlag
 
+	webhookHMACSecretFile string
+
+	githubCheckRunAnnotations bool
+
+	larkWebhooksFile  string
+	teamsWebhooksFile string
+
 	storage prowflagutil.StorageClientOptions
 
 	instrumentationOptions prowflagutil.InstrumentationOptions
@@ -83,7 +96,7 @@ type options struct {
 }
 
 func (o *options) validate() error {
-	if o.gerritWorkers+o.pubsubWorkers+o.githubWorkers+o.slackWorkers+o.blobStorageWorkers+o.k8sBlobStorageWorkers+o.resultStoreWorkers <= 0 {
+	if o.gerritWorkers+o.pubsubWorkers+o.githubWorkers+o.slackWorkers+o.blobStorageWorkers+o.k8sBlobStorageWorkers+o.resultStoreWorkers+o.webhookWorkers+o.larkWorkers+o.teamsWorkers <= 0 {
 		return errors.New("crier need to have at least one report worker to start")
 	}
 
@@ -112,6 +125,14 @@ func (o *options) validate() error {
 		}
 	}
 
+	if o.larkWorkers > 0 && o.larkWebhooksFile == "" {
+		return errors.New("--lark-webhooks-file must be set")
+	}
+
+	if o.teamsWorkers > 0 && o.teamsWebhooksFile == "" {
+		return errors.New("--teams-webhooks-file must be set")
+	}
+
 	for _, opt := range []interface{ Validate(bool) error }{&o.client, &o.githubEnablement, &o.config} {
 		if err := opt.Validate(o.dryrun); err != nil {
 			return err
@@ -126,6 +147,7 @@ func (o *options) parseArgs(fs *flag.FlagSet, args []string) error {
 	fs.IntVar(&o.gerritWorkers, "gerrit-workers", 0, "Number of gerrit report workers (0 means disabled)")
 	fs.IntVar(&o.pubsubWorkers, "pubsub-workers", 0, "Number of pubsub report workers (0 means disabled)")
 	fs.IntVar(&o.githubWorkers, "github-workers", 0, "Number of github report workers (0 means disabled)")
+	fs.BoolVar(&o.githubCheckRunAnnotations, "github-check-run-annotations", false, "Read the JUnit results of the failed jobs from storage to annotate their check runs (effective for github only)")
 	fs.IntVar(&o.slackWorkers, "slack-workers", 0, "Number of Slack report workers (0 means disabled)")
 	fs.Var(&o.additionalSlackTokenFiles, "additional-slack-token-files", "Map of additional slack token files. example: --additional-slack-token-files=foo=/etc/foo-slack-tokens/token, repeat flag for each host")
 	fs.IntVar(&o.blobStorageWorkers, "blob-storage-workers", 0, "Number of blob storage report workers (0 means disabled)")
@@ -134,6 +156,12 @@ func (o *options) parseArgs(fs *flag.FlagSet, args []string) error {
 	fs.StringVar(&o.slackTokenFile, "slack-token-file", "", "Path to a Slack token file")
 	fs.StringVar(&o.reportAgent, "report-agent", "", "Only report specified agent - empty means report to all agents (effective for github and Slack only)")
 	fs.IntVar(&o.resultStoreWorkers, "resultstore-workers", 0, "Number of ResultStore report workers (0 means disabled)")
+	fs.IntVar(&o.webhookWorkers, "webhook-workers", 0, "Number of webhook report workers (0 means disabled)")
+	fs.StringVar(&o.webhookHMACSecretFile, "webhook-hmac-secret-file", "", "Path to the file containing the key the webhook payloads are signed with, leave empty to not sign them")
+	fs.IntVar(&o.larkWorkers, "lark-workers", 0, "Number of Lark report workers (0 means disabled)")
+	fs.StringVar(&o.larkWebhooksFile, "lark-webhooks-file", "", "Path to the file mapping the Lark channels to the URLs of their custom bots")
+	fs.IntVar(&o.teamsWorkers, "teams-workers", 0, "Number of Microsoft Teams report workers (0 means disabled)")
+	fs.StringVar(&o.teamsWebhooksFile, "teams-webhooks-file", "", "Path to the file mapping the Microsoft Teams channels to the URLs of their incoming webhooks")
 	fs.BoolVar(&o.resultstoreArtifactsDirOnly, "resultstore-artifacts-dir-only", false, "Report the artifacts/ dir instead of subtree files (testing)")
 
 	// TODO(krzyzacy): implement dryrun for gerrit/pubsub
@@ -168,6 +196,9 @@ func main() {
 	o := parseOptions()
 
 	pprof.Instrument(o.instrumentationOptions)
+	if err := tracing.Init("crier", o.instrumentationOptions); err != nil {
+		logrus.WithError(err).Fatal("Error initializing tracing.")
+	}
 
 	configAgent, err := o.config.ConfigAgent()
 	if err != nil {
@@ -231,6 +262,40 @@ func main() {
 		}
 	}
 
+	if o.webhookWorkers > 0 {
+		var hmacSecret func() []byte
+		if o.webhookHMACSecretFile != "" {
+			if err := secret.Add(o.webhookHMACSecretFile); err != nil {
+				logrus.WithError(err).Fatal("could not read webhook HMAC secret")
+			}
+			hmacSecret = secret.GetTokenGenerator(o.webhookHMACSecretFile)
+		}
+		hasReporter = true
+		if err := crier.New(mgr, webhookreporter.New(cfg, hmacSecret, o.dryrun), o.webhookWorkers, o.githubEnablement.EnablementChecker()); err != nil {
+			logrus.WithError(err).Fatal("failed to construct webhook reporter controller")
+		}
+	}
+
+	if o.larkWorkers > 0 {
+		if err := secret.Add(o.larkWebhooksFile); err != nil {
+			logrus.WithError(err).Fatal("could not read Lark webhooks")
+		}
+		hasReporter = true
+		if err := crier.New(mgr, chatreporter.NewLark(secret.GetTokenGenerator(o.larkWebhooksFile), o.dryrun), o.larkWorkers, o.githubEnablement.EnablementChecker()); err != nil {
+			logrus.WithError(err).Fatal("failed to construct lark reporter controller")
+		}
+	}
+
+	if o.teamsWorkers > 0 {
+		if err := secret.Add(o.teamsWebhooksFile); err != nil {
+			logrus.WithError(err).Fatal("could not read Microsoft Teams webhooks")
+		}
+		hasReporter = true
+		if err := crier.New(mgr, chatreporter.NewTeams(secret.GetTokenGenerator(o.teamsWebhooksFile), o.dryrun), o.teamsWorkers, o.githubEnablement.EnablementChecker()); err != nil {
+			logrus.WithError(err).Fatal("failed to construct teams reporter controller")
+		}
+	}
+
 	if o.gerritWorkers > 0 {
 		orgRepoConfigGetter := func() *config.GerritOrgRepoConfigs {
 			return cfg().Gerrit.OrgReposConfig
@@ -253,6 +318,14 @@ func main() {
 		}
 	}
 
+	var opener io.Opener
+	if o.blobStorageWorkers+o.k8sBlobStorageWorkers+o.resultStoreWorkers > 0 || (o.githubWorkers > 0 && o.githubCheckRunAnnotations) {
+		opener, err = o.storage.StorageClient(context.Background())
+		if err != nil {
+			logrus.WithError(err).Fatal("Error creating opener")
+		}
+	}
+
 	if o.githubWorkers > 0 {
 		if o.github.TokenPath != "" {
 			if err := secret.Add(o.github.TokenPath); err != nil {
@@ -265,21 +338,18 @@ func main() {
 			logrus.WithError(err).Fatal("Error getting GitHub client.")
 		}
 
+		var checkRunOpener io.Opener
+		if o.githubCheckRunAnnotations {
+			checkRunOpener = opener
+		}
+
 		hasReporter = true
-		githubReporter := githubreporter.NewReporter(githubClient, cfg, prowapi.ProwJobAgent(o.reportAgent), mgr.GetCache())
+		githubReporter := githubreporter.NewReporter(githubClient, cfg, prowapi.ProwJobAgent(o.reportAgent), mgr.GetCache(), checkRunOpener)
 		if err := crier.New(mgr, githubReporter, o.githubWorkers, o.githubEnablement.EnablementChecker()); err != nil {
 			logrus.WithError(err).Fatal("failed to construct github reporter controller")
 		}
 	}
 
-	var opener io.Opener
-	if o.blobStorageWorkers+o.k8sBlobStorageWorkers+o.resultStoreWorkers > 0 {
-		opener, err = o.storage.StorageClient(context.Background())
-		if err != nil {
-			logrus.WithError(err).Fatal("Error creating opener")
-		}
-	}
-
 	if o.blobStorageWorkers > 0 || o.k8sBlobStorageWorkers > 0 {
 		hasReporter = true
 		if o.blobStorageWorkers > 0 {
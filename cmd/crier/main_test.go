@@ -104,6 +104,54 @@ func TestOptions(t *testing.T) {
 			name: "pubsub workers set to negative, rejects",
 			args: []string{"--pubsub-workers=-3", "--config-path=foo"},
 		},
+		//Webhook Reporter
+		{
+			name: "webhook workers, sets workers",
+			args: []string{"--webhook-workers=3", "--webhook-hmac-secret-file=/etc/webhook/hmac", "--config-path=foo"},
+			expected: &options{
+				webhookWorkers:        3,
+				webhookHMACSecretFile: "/etc/webhook/hmac",
+				config: configflagutil.ConfigOptions{
+					ConfigPathFlagName:                    "config-path",
+					JobConfigPathFlagName:                 "job-config-path",
+					ConfigPath:                            "foo",
+					SupplementalProwConfigsFileNameSuffix: "_prowconfig.yaml",
+					InRepoConfigCacheSize:                 200,
+				},
+				github:                 defaultGitHubOptions,
+				k8sReportFraction:      1.0,
+				instrumentationOptions: flagutil.DefaultInstrumentationOptions(),
+			},
+		},
+		//Chat Reporters
+		{
+			name: "lark and teams workers, sets workers",
+			args: []string{"--lark-workers=2", "--lark-webhooks-file=/etc/lark/webhooks", "--teams-workers=3", "--teams-webhooks-file=/etc/teams/webhooks", "--config-path=foo"},
+			expected: &options{
+				larkWorkers:       2,
+				larkWebhooksFile:  "/etc/lark/webhooks",
+				teamsWorkers:      3,
+				teamsWebhooksFile: "/etc/teams/webhooks",
+				config: configflagutil.ConfigOptions{
+					ConfigPathFlagName:                    "config-path",
+					JobConfigPathFlagName:                 "job-config-path",
+					ConfigPath:                            "foo",
+					SupplementalProwConfigsFileNameSuffix: "_prowconfig.yaml",
+					InRepoConfigCacheSize:                 200,
+				},
+				github:                 defaultGitHubOptions,
+				k8sReportFraction:      1.0,
+				instrumentationOptions: flagutil.DefaultInstrumentationOptions(),
+			},
+		},
+		{
+			name: "lark missing --lark-webhooks-file, rejects",
+			args: []string{"--lark-workers=1", "--config-path=foo"},
+		},
+		{
+			name: "teams missing --teams-webhooks-file, rejects",
+			args: []string{"--teams-workers=1", "--config-path=foo"},
+		},
 		//Slack Reporter
 		{
 			name: "slack workers, sets workers",
@@ -236,10 +284,11 @@ This unit testing covers only the public portions
 */
 func TestGitHubOptions(t *testing.T) {
 	cases := []struct {
-		name              string
-		args              []string
-		expectedWorkers   int
-		expectedTokenPath string
+		name                      string
+		args                      []string
+		expectedWorkers           int
+		expectedTokenPath         string
+		expectedCheckRunAnnotated bool
 	}{
 		{
 			name:              "github workers, only support single worker",
@@ -247,6 +296,13 @@ func TestGitHubOptions(t *testing.T) {
 			expectedWorkers:   5,
 			expectedTokenPath: "tkpath",
 		},
+		{
+			name:                      "github workers, annotate check runs",
+			args:                      []string{"--github-workers=2", "--github-token-path=tkpath", "--github-check-run-annotations", "--config-path=foo"},
+			expectedWorkers:           2,
+			expectedTokenPath:         "tkpath",
+			expectedCheckRunAnnotated: true,
+		},
 	}
 
 	for _, tc := range cases {
@@ -265,5 +321,9 @@ func TestGitHubOptions(t *testing.T) {
 			t.Errorf("%s: path mismatch: actual %s != expected %s",
 				tc.name, actual.github.TokenPath, tc.expectedTokenPath)
 		}
+		if actual.githubCheckRunAnnotations != tc.expectedCheckRunAnnotated {
+			t.Errorf("%s: check run annotations mismatch: actual %t != expected %t",
+				tc.name, actual.githubCheckRunAnnotations, tc.expectedCheckRunAnnotated)
+		}
 	}
 }
@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/spyglass"
+)
+
+type runComparer interface {
+	RunSource(jobPath, buildID string) (string, error)
+	CompareRuns(ctx context.Context, baseSrc, headSrc string) (*spyglass.RunComparison, error)
+}
+
+type compareTemplate struct {
+	JobPath        string
+	JobHistoryLink string
+	BaseLink       string
+	HeadLink       string
+	*spyglass.RunComparison
+}
+
+// compareLink returns the link to the comparison of two runs of the job whose
+// results are under jobPath, e.g. "gs/bucket/logs/job".
+func compareLink(jobPath, base, head string) string {
+	segments := strings.Split(strings.Trim(jobPath, "/"), "/")
+	for i := range segments {
+		segments[i] = url.PathEscape(segments[i])
+	}
+	return fmt.Sprintf("/compare/%s?%s", strings.Join(segments, "/"), url.Values{"base": {base}, "head": {head}}.Encode())
+}
+
+// handleCompare compares two runs of a job. The url must specify the
+// path of the job as for the job history, with a storage key type, and the
+// build IDs of both runs:
+//
+// /compare/<key-type>/<job-path>?base=<build-id>&head=<build-id>
+//
+// Example:
+// - /compare/gs/kubernetes-jenkins/logs/ci-kubernetes-e2e-prow-canary?base=1046875594609922048&head=1046875594609922049
+func handleCompare(o options, cfg config.Getter, rc runComparer, log *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setHeadersNoCaching(w)
+		jobPath := strings.Trim(strings.TrimPrefix(r.URL.Path, "/compare/"), "/")
+		base, head := r.URL.Query().Get("base"), r.URL.Query().Get("head")
+		if jobPath == "" || base == "" || head == "" {
+			http.Error(w, "The path of the job and the base and head build IDs are required: /compare/<key-type>/<job-path>?base=<build-id>&head=<build-id>", http.StatusBadRequest)
+			return
+		}
+
+		baseSrc, err := rc.RunSource(jobPath, base)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to locate build %s: %v", base, err), http.StatusNotFound)
+			return
+		}
+		headSrc, err := rc.RunSource(jobPath, head)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to locate build %s: %v", head, err), http.StatusNotFound)
+			return
+		}
+		comparison, err := rc.CompareRuns(r.Context(), baseSrc, headSrc)
+		if err != nil {
+			msg := fmt.Sprintf("Failed to compare builds %s and %s: %v", base, head, err)
+			if shouldLogHTTPErrors(err) {
+				log.WithError(err).Debug(msg)
+			}
+			http.Error(w, msg, httpStatusForError(err))
+			return
+		}
+
+		tmpl := compareTemplate{
+			JobPath:        jobPath,
+			JobHistoryLink: path.Join("/job-history", jobPath),
+			BaseLink:       path.Join("/view", baseSrc),
+			HeadLink:       path.Join("/view", headSrc),
+			RunComparison:  comparison,
+		}
+		handleSimpleTemplate(o, cfg, "compare.html", tmpl)(w, r)
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/spyglass"
+)
+
+type fakeRunComparer struct {
+	builds map[string]bool
+}
+
+func (c *fakeRunComparer) RunSource(jobPath, buildID string) (string, error) {
+	if !c.builds[buildID] {
+		return "", errors.New("not found")
+	}
+	return jobPath + "/" + buildID, nil
+}
+
+func (c *fakeRunComparer) CompareRuns(_ context.Context, baseSrc, headSrc string) (*spyglass.RunComparison, error) {
+	return &spyglass.RunComparison{
+		Job:        "job",
+		BaseBuild:  "1",
+		HeadBuild:  "2",
+		BaseSource: baseSrc,
+		HeadSource: headSrc,
+		Tests: spyglass.TestComparison{
+			NewFailures: []spyglass.TestDiff{{Name: "pkg: TestA", Base: spyglass.TestResult{State: spyglass.TestPassed}, Head: spyglass.TestResult{State: spyglass.TestFailed}}},
+		},
+		Refs: []spyglass.FieldDiff{{Field: "refs.base_sha", Base: "aaa", Head: "bbb"}},
+	}, nil
+}
+
+func TestHandleCompare(t *testing.T) {
+	rc := &fakeRunComparer{builds: map[string]bool{"1": true, "2": true}}
+	cfg := func() *config.Config { return &config.Config{} }
+	o := options{templateFilesLocation: "template"}
+	for _, tc := range []struct {
+		name       string
+		url        string
+		wantStatus int
+		wantBody   []string
+	}{
+		{
+			name:       "comparison",
+			url:        "/compare/gs/bucket/logs/job?base=1&head=2",
+			wantStatus: http.StatusOK,
+			wantBody: []string{
+				`href="/view/gs/bucket/logs/job/1"`,
+				`href="/view/gs/bucket/logs/job/2"`,
+				`href="/job-history/gs/bucket/logs/job"`,
+				"pkg: TestA",
+				"refs.base_sha",
+			},
+		},
+		{
+			name:       "missing build",
+			url:        "/compare/gs/bucket/logs/job?base=1",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown build",
+			url:        "/compare/gs/bucket/logs/job?base=1&head=3",
+			wantStatus: http.StatusNotFound,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			rr := httptest.NewRecorder()
+			handleCompare(o, cfg, rc, logrus.WithField("handler", "/compare")).ServeHTTP(rr, req)
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tc.wantStatus, rr.Code, rr.Body.String())
+			}
+			for _, want := range tc.wantBody {
+				if !strings.Contains(rr.Body.String(), want) {
+					t.Errorf("Expected %q in the body:\n%s", want, rr.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestCompareLink(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		jobPath string
+		base    string
+		head    string
+		want    string
+	}{
+		{
+			name:    "build IDs",
+			jobPath: "gs/bucket/logs/job",
+			base:    "1",
+			head:    "2",
+			want:    "/compare/gs/bucket/logs/job?base=1&head=2",
+		},
+		{
+			name:    "escaped path and builds",
+			jobPath: "/gs/bucket/logs/job?x=y#z/",
+			base:    "1&head=3",
+			head:    "2 3",
+			want:    "/compare/gs/bucket/logs/job%3Fx=y%23z?base=1%26head%3D3&head=2+3",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := compareLink(tc.jobPath, tc.base, tc.head); got != tc.want {
+				t.Errorf("Expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
@@ -130,10 +130,12 @@ func GetConfiguredJobs(cfg config.Getter, org, repo string) (*configuredjobs.Job
 					return nil, fmt.Errorf("could not get storage provider and bucket: %w", err)
 				}
 				cjRepo.Jobs = append(cjRepo.Jobs, configuredjobs.JobInfo{
-					Name:           postsubmit.Name,
-					Type:           v1.PostsubmitJob,
-					JobHistoryLink: jobHistoryLink(provider, bucket, postsubmit.Name, false),
-					YAMLDefinition: string(definition),
+					Name:            postsubmit.Name,
+					Type:            v1.PostsubmitJob,
+					JobHistoryLink:  jobHistoryLink(provider, bucket, postsubmit.Name, false),
+					YAMLDefinition:  string(definition),
+					RunAfterSuccess: postsubmit.RunAfterSuccess,
+					Downstreams:     jobConfig.DownstreamJobs(postsubmit.Name),
 				})
 			}
 			periodics := jobConfig.PeriodicsMatchingExtraRefs(org, r)
@@ -148,10 +150,12 @@ func GetConfiguredJobs(cfg config.Getter, org, repo string) (*configuredjobs.Job
 					return nil, fmt.Errorf("could not get storage provider and bucket: %w", err)
 				}
 				cjRepo.Jobs = append(cjRepo.Jobs, configuredjobs.JobInfo{
-					Name:           periodic.Name,
-					Type:           v1.PeriodicJob,
-					JobHistoryLink: jobHistoryLink(provider, bucket, periodic.Name, false),
-					YAMLDefinition: string(definition),
+					Name:            periodic.Name,
+					Type:            v1.PeriodicJob,
+					JobHistoryLink:  jobHistoryLink(provider, bucket, periodic.Name, false),
+					YAMLDefinition:  string(definition),
+					RunAfterSuccess: periodic.RunAfterSuccess,
+					Downstreams:     jobConfig.DownstreamJobs(periodic.Name),
 				})
 			}
 		}
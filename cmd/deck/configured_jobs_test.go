@@ -100,12 +100,14 @@ func TestGetConfiguredJobs(t *testing.T) {
 								Type:           "postsubmit",
 								JobHistoryLink: "/job-history/gs/prow-results/logs/some-postsubmit",
 								YAMLDefinition: "name: some-postsubmit\n",
+								Downstreams:    []string{"other-postsubmit"},
 							},
 							{
-								Name:           "other-postsubmit",
-								Type:           "postsubmit",
-								JobHistoryLink: "/job-history/gs/prow-results/logs/other-postsubmit",
-								YAMLDefinition: "name: other-postsubmit\n",
+								Name:            "other-postsubmit",
+								Type:            "postsubmit",
+								JobHistoryLink:  "/job-history/gs/prow-results/logs/other-postsubmit",
+								YAMLDefinition:  "name: other-postsubmit\nrun_after_success:\n- some-postsubmit\n",
+								RunAfterSuccess: []string{"some-postsubmit"},
 							},
 							{
 								Name:           "some-prow-periodic",
@@ -309,6 +311,7 @@ func getConfigGetter(repos []string) config.Getter {
 						JobBase: config.JobBase{
 							Name: "other-postsubmit",
 						},
+						RunAfterSuccess: []string{"some-postsubmit"},
 					},
 				},
 				"kubernetes/test-infra": {
@@ -0,0 +1,51 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/prow/pkg/durations"
+)
+
+type durationsAgent interface {
+	Report() durations.Report
+	Trend(job string) durations.Trend
+}
+
+// handleDurationsJSON serves the current duration regressions, or the
+// duration trend of a job when it is given.
+func handleDurationsJSON(da durationsAgent, log *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setHeadersNoCaching(w)
+		var resp interface{} = da.Report()
+		if job := r.URL.Query().Get("job"); job != "" {
+			resp = da.Trend(job)
+		}
+
+		b, err := json.Marshal(resp)
+		if err != nil {
+			log.WithError(err).Error("Error marshaling the duration trends.")
+			http.Error(w, "Error marshaling the duration trends.", http.StatusInternalServerError)
+			return
+		}
+		writeJSONResponse(w, r, b)
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/prow/pkg/durations"
+)
+
+type fakeDurationsAgent struct {
+	report durations.Report
+	trends map[string]durations.Trend
+}
+
+func (a *fakeDurationsAgent) Report() durations.Report {
+	return a.report
+}
+
+func (a *fakeDurationsAgent) Trend(job string) durations.Trend {
+	if trend, ok := a.trends[job]; ok {
+		return trend
+	}
+	return durations.Trend{Job: durations.Series{Job: job}}
+}
+
+func TestHandleDurationsJSON(t *testing.T) {
+	regression := durations.Regression{Job: "job", Test: "TestA", Baseline: time.Minute, Recent: 2 * time.Minute, IncreasePercent: 100, FirstBuild: "3"}
+	da := &fakeDurationsAgent{
+		report: durations.Report{Regressions: []durations.Regression{regression}},
+		trends: map[string]durations.Trend{
+			"job": {
+				Job:         durations.Series{Job: "job", Points: []durations.Point{{BuildID: "1", Duration: time.Hour}}},
+				Tests:       []durations.Series{{Job: "job", Test: "TestA", Points: []durations.Point{{BuildID: "1", Duration: time.Minute}}}},
+				Regressions: []durations.Regression{regression},
+			},
+		},
+	}
+	for _, tc := range []struct {
+		name  string
+		query string
+		want  interface{}
+		got   interface{}
+	}{
+		{
+			name: "report",
+			want: &da.report,
+			got:  &durations.Report{},
+		},
+		{
+			name:  "trend of a job",
+			query: "?job=job",
+			want:  &durations.Trend{Job: da.trends["job"].Job, Tests: da.trends["job"].Tests, Regressions: da.trends["job"].Regressions},
+			got:   &durations.Trend{},
+		},
+		{
+			name:  "unknown job",
+			query: "?job=other",
+			want:  &durations.Trend{Job: durations.Series{Job: "other"}},
+			got:   &durations.Trend{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handleDurationsJSON(da, logrus.WithField("handler", "/durations.js"))(rr, httptest.NewRequest(http.MethodGet, "/durations.js"+tc.query, nil))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), tc.got); err != nil {
+				t.Fatalf("Failed to unmarshal the response: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, tc.got); diff != "" {
+				t.Errorf("Unexpected response: %s", diff)
+			}
+		})
+	}
+}
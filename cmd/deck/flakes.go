@@ -0,0 +1,98 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/flakes"
+)
+
+type flakesAgent interface {
+	Report() flakes.Report
+	Ingest(pj *prowapi.ProwJob) (flakes.Run, error)
+}
+
+type prowJobGetter interface {
+	GetProwJob(job, id string) (prowapi.ProwJob, error)
+}
+
+type flakesTemplate struct {
+	Generated time.Time
+	Lookback  time.Duration
+	MinFlakes int
+	Flakes    []flakes.Flake
+}
+
+// handleFlakes renders the report of the known-flaky tests.
+func handleFlakes(o options, cfg config.Getter, fa flakesAgent) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setHeadersNoCaching(w)
+		tmpl := flakesTemplate{}
+		if c := cfg().Deck.Flakes; c != nil {
+			report := fa.Report()
+			tmpl = flakesTemplate{
+				Generated: report.Generated,
+				Lookback:  c.Lookback.Duration,
+				MinFlakes: c.MinFlakes,
+				Flakes:    report.Flakes,
+			}
+		}
+		handleSimpleTemplate(o, cfg, "flakes.html", tmpl)(w, r)
+	}
+}
+
+// handleFlakesJSON serves the report of the known-flaky tests, or the
+// failures of a run when the job and build are given.
+func handleFlakesJSON(fa flakesAgent, ja prowJobGetter, log *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setHeadersNoCaching(w)
+		report := fa.Report()
+		var resp interface{} = report
+
+		job, build := r.URL.Query().Get("job"), r.URL.Query().Get("build")
+		if job != "" || build != "" {
+			pj, err := ja.GetProwJob(job, build)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Failed to get job %s/%s: %v", job, build, err), http.StatusNotFound)
+				return
+			}
+			run, err := fa.Ingest(&pj)
+			if err != nil {
+				log.WithError(err).WithField("job", job).WithField("build", build).Warn("Failed to ingest the results of the job.")
+				http.Error(w, fmt.Sprintf("Failed to get the results of job %s/%s: %v", job, build, err), http.StatusInternalServerError)
+				return
+			}
+			resp = report.Failures(run)
+		}
+
+		b, err := json.Marshal(resp)
+		if err != nil {
+			log.WithError(err).Error("Error marshaling the flaky test report.")
+			http.Error(w, "Error marshaling the flaky test report.", http.StatusInternalServerError)
+			return
+		}
+		writeJSONResponse(w, r, b)
+	}
+}
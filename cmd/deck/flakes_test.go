@@ -0,0 +1,120 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/flakes"
+)
+
+type fakeFlakesAgent struct {
+	report flakes.Report
+	runs   map[string]flakes.Run
+}
+
+func (a *fakeFlakesAgent) Report() flakes.Report {
+	return a.report
+}
+
+func (a *fakeFlakesAgent) Ingest(pj *prowapi.ProwJob) (flakes.Run, error) {
+	run, ok := a.runs[pj.Status.BuildID]
+	if !ok {
+		return flakes.Run{}, errors.New("no results")
+	}
+	return run, nil
+}
+
+type fakeProwJobGetter map[string]prowapi.ProwJob
+
+func (g fakeProwJobGetter) GetProwJob(job, id string) (prowapi.ProwJob, error) {
+	pj, ok := g[job+"/"+id]
+	if !ok {
+		return prowapi.ProwJob{}, errors.New("not found")
+	}
+	return pj, nil
+}
+
+func TestHandleFlakesJSON(t *testing.T) {
+	fa := &fakeFlakesAgent{
+		report: flakes.Report{Flakes: []flakes.Flake{{Job: "job", Test: "TestA", Runs: 4, Flakes: 2}}},
+		runs: map[string]flakes.Run{
+			"1": {Job: "job", BuildID: "1", Tests: map[string]flakes.TestStatus{"TestA": flakes.Failed, "TestB": flakes.Passed}},
+		},
+	}
+	ja := fakeProwJobGetter{
+		"job/1": {Spec: prowapi.ProwJobSpec{Job: "job"}, Status: prowapi.ProwJobStatus{BuildID: "1"}},
+		"job/2": {Spec: prowapi.ProwJobSpec{Job: "job"}, Status: prowapi.ProwJobStatus{BuildID: "2"}},
+	}
+	for _, tc := range []struct {
+		name       string
+		query      string
+		wantStatus int
+		want       interface{}
+		got        interface{}
+	}{
+		{
+			name:       "report",
+			wantStatus: http.StatusOK,
+			want:       &fa.report,
+			got:        &flakes.Report{},
+		},
+		{
+			name:       "failures of a run",
+			query:      "?job=job&build=1",
+			wantStatus: http.StatusOK,
+			want:       &flakes.RunFailures{Job: "job", BuildID: "1", Failures: []flakes.Failure{{Test: "TestA", KnownFlake: true, Flakes: 2}}},
+			got:        &flakes.RunFailures{},
+		},
+		{
+			name:       "unknown job",
+			query:      "?job=job&build=3",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "job without results",
+			query:      "?job=job&build=2",
+			wantStatus: http.StatusInternalServerError,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/flakes.js"+tc.query, nil)
+			rr := httptest.NewRecorder()
+			handleFlakesJSON(fa, ja, logrus.WithField("handler", "/flakes.js")).ServeHTTP(rr, req)
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tc.wantStatus, rr.Code, rr.Body.String())
+			}
+			if tc.want == nil {
+				return
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), tc.got); err != nil {
+				t.Fatalf("Failed to unmarshal the response: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, tc.got); diff != "" {
+				t.Errorf("Unexpected response: %s", diff)
+			}
+		})
+	}
+}
@@ -61,6 +61,7 @@ type buildData struct {
 	jobName      string
 	prefix       string
 	SpyglassLink string
+	CompareLink  string
 	ID           string
 	Started      time.Time
 	Duration     time.Duration
@@ -99,6 +100,7 @@ type jobHistoryTemplate struct {
 	NewerLink    string
 	LatestLink   string
 	Name         string
+	Job          string
 	ResultsShown int
 	ResultsTotal int
 	Builds       []buildData
@@ -448,6 +450,7 @@ func getJobHistory(ctx context.Context, url *url.URL, cfg config.Getter, opener
 		return tmpl, err
 	}
 	tmpl.Name = root
+	tmpl.Job = path.Base(root)
 	latest, err := readLatestBuild(ctx, bucket, root)
 	if err != nil {
 		return tmpl, fmt.Errorf("failed to locate build data: %w", err)
@@ -517,6 +520,10 @@ func getJobHistory(ctx context.Context, url *url.URL, cfg config.Getter, opener
 			if err != nil {
 				logrus.WithError(err).Errorf("failed to get spyglass link")
 			}
+			if previous := firstIndex + i + 1; previous < len(buildIDs) {
+				jobPath := path.Join(bucket.storageProvider, bucket.name, root)
+				b.CompareLink = compareLink(jobPath, strconv.FormatUint(buildIDs[previous], 10), id)
+			}
 			bch <- b
 		}(i, buildID)
 	}
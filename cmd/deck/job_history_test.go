@@ -301,12 +301,14 @@ func Test_getJobHistory(t *testing.T) {
 	}
 	wantedPRLogsJobHistoryTemplate := jobHistoryTemplate{
 		Name:         "pr-logs/directory/pull-test-infra-bazel",
+		Job:          "pull-test-infra-bazel",
 		ResultsShown: 2,
 		ResultsTotal: 2,
 		Builds: []buildData{
 			{
 				index:        0,
 				SpyglassLink: "/view/gs/kubernetes-jenkins/pr-logs/pull/test-infra/17183/pull-test-infra-bazel/1254406011708510210",
+				CompareLink:  "/compare/gs/kubernetes-jenkins/pr-logs/directory/pull-test-infra-bazel?base=1221704015146913792&head=1254406011708510210",
 				ID:           "1254406011708510210",
 				Started:      time.Unix(1587908709, 0),
 				Duration:     436000000000,
@@ -326,6 +328,7 @@ func Test_getJobHistory(t *testing.T) {
 	}
 	wantedLogsJobHistoryTemplate := jobHistoryTemplate{
 		Name:         "logs/post-cluster-api-provider-openstack-push-images",
+		Job:          "post-cluster-api-provider-openstack-push-images",
 		ResultsShown: 1,
 		ResultsTotal: 1,
 		Builds: []buildData{
@@ -62,10 +62,13 @@ import (
 	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
 	prowv1 "sigs.k8s.io/prow/pkg/client/clientset/versioned/typed/prowjobs/v1"
 	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/config/secret"
 	"sigs.k8s.io/prow/pkg/deck/jobs"
+	"sigs.k8s.io/prow/pkg/durations"
 	prowflagutil "sigs.k8s.io/prow/pkg/flagutil"
 	configflagutil "sigs.k8s.io/prow/pkg/flagutil/config"
 	pluginsflagutil "sigs.k8s.io/prow/pkg/flagutil/plugins"
+	"sigs.k8s.io/prow/pkg/flakes"
 	"sigs.k8s.io/prow/pkg/git/v2"
 	prowgithub "sigs.k8s.io/prow/pkg/github"
 	"sigs.k8s.io/prow/pkg/githuboauth"
@@ -79,6 +82,7 @@ import (
 	"sigs.k8s.io/prow/pkg/plugins"
 	"sigs.k8s.io/prow/pkg/prstatus"
 	"sigs.k8s.io/prow/pkg/simplifypath"
+	"sigs.k8s.io/prow/pkg/slack"
 	"sigs.k8s.io/prow/pkg/spyglass"
 	spyglassapi "sigs.k8s.io/prow/pkg/spyglass/api"
 	"sigs.k8s.io/prow/pkg/spyglass/lenses/common"
@@ -91,6 +95,7 @@ import (
 	_ "sigs.k8s.io/prow/pkg/spyglass/lenses/html"
 	_ "sigs.k8s.io/prow/pkg/spyglass/lenses/junit"
 	_ "sigs.k8s.io/prow/pkg/spyglass/lenses/links"
+	_ "sigs.k8s.io/prow/pkg/spyglass/lenses/logsteps"
 	_ "sigs.k8s.io/prow/pkg/spyglass/lenses/metadata"
 	_ "sigs.k8s.io/prow/pkg/spyglass/lenses/podinfo"
 	_ "sigs.k8s.io/prow/pkg/spyglass/lenses/restcoverage"
@@ -140,6 +145,7 @@ type options struct {
 	controllerManager     prowflagutil.ControllerManagerOptions
 	dryRun                bool
 	tenantIDs             prowflagutil.Strings
+	slackTokenFile        string
 }
 
 func (o *options) Validate() error {
@@ -186,6 +192,7 @@ func gatherOptions(fs *flag.FlagSet, args ...string) options {
 	fs.BoolVar(&o.allowInsecure, "allow-insecure", false, "Allows insecure requests for CSRF and GitHub oauth.")
 	fs.BoolVar(&o.dryRun, "dry-run", false, "Whether or not to make mutating API calls to GitHub.")
 	fs.Var(&o.tenantIDs, "tenant-id", "The tenantID(s) used by the ProwJobs that should be displayed by this instance of Deck. This flag can be repeated.")
+	fs.StringVar(&o.slackTokenFile, "slack-token-file", "", "Path to the file containing the Slack token used to report duration regressions.")
 	o.config.AddFlags(fs)
 	o.instrumentation.AddFlags(fs)
 	o.controllerManager.TimeoutListingProwJobsDefault = 30 * time.Second
@@ -222,12 +229,17 @@ var simplifier = simplifypath.NewSimplifier(l("", // shadow element mimicking th
 	l(""),
 	l("badge.svg"),
 	l("command-help"),
+	l("compare",
+		l("gs", v("bucket", l("logs", v("job"))))),
 	l("config"),
 	l("configured-jobs",
 		v("org"),
 		v("repo")),
 	l("data.js"),
 	l("favicon.ico"),
+	l("flakes"),
+	l("flakes.js"),
+	l("durations.js"),
 	l("github-login",
 		l("redirect")),
 	l("github-link"),
@@ -695,11 +707,32 @@ func initSpyglass(cfg config.Getter, o options, mux *http.ServeMux, ja *jobs.Job
 	sg := spyglass.New(ctx, ja, cfg, opener, o.gcsCookieAuth)
 	sg.Start()
 
+	fi := flakes.NewIngester(ctx, opener, cfg, ja.ProwJobs)
+
+	var slackClient durations.SlackClient
+	if o.slackTokenFile != "" {
+		if err := secret.Add(o.slackTokenFile); err != nil {
+			logrus.WithError(err).Fatal("Error reading the Slack token")
+		}
+		if o.dryRun {
+			slackClient = slack.NewFakeClient()
+		} else {
+			slackClient = slack.NewClient(secret.GetTokenGenerator(o.slackTokenFile))
+		}
+	}
+	di := durations.NewDetector(ctx, opener, cfg, slackClient)
+	fi.OnSync(di.Sync)
+	fi.Start()
+
 	mux.Handle("/spyglass/static/", http.StripPrefix("/spyglass/static", staticHandlerFromDir(o.spyglassFilesLocation)))
 	mux.Handle("/spyglass/lens/", gziphandler.GzipHandler(http.StripPrefix("/spyglass/lens/", handleArtifactView(o, sg, cfg))))
 	mux.Handle("/view/", gziphandler.GzipHandler(handleRequestJobViews(sg, cfg, o, logrus.WithField("handler", "/view"))))
+	mux.Handle("/compare/", gziphandler.GzipHandler(handleCompare(o, cfg, sg, logrus.WithField("handler", "/compare"))))
 	mux.Handle("/job-history/", gziphandler.GzipHandler(handleJobHistory(o, cfg, opener, logrus.WithField("handler", "/job-history"))))
 	mux.Handle("/pr-history/", gziphandler.GzipHandler(handlePRHistory(o, cfg, opener, gitHubClient, gitClient, logrus.WithField("handler", "/pr-history"))))
+	mux.Handle("/flakes", gziphandler.GzipHandler(handleFlakes(o, cfg, fi)))
+	mux.Handle("/flakes.js", gziphandler.GzipHandler(handleFlakesJSON(fi, ja, logrus.WithField("handler", "/flakes.js"))))
+	mux.Handle("/durations.js", gziphandler.GzipHandler(handleDurationsJSON(di, logrus.WithField("handler", "/durations.js"))))
 	if err := initLocalLensHandler(cfg, o, sg); err != nil {
 		logrus.WithError(err).Fatal("Failed to initialize local lens handler")
 	}
@@ -1096,7 +1129,7 @@ lensesLoop:
 
 	artifactsLink := ""
 	bucket := ""
-	if jobPath != "" && (strings.HasPrefix(jobPath, providers.GS) || strings.HasPrefix(jobPath, providers.S3)) {
+	if jobPath != "" && providers.HasStorageProviderPrefix(jobPath) {
 		bucket = strings.Split(jobPath, "/")[1] // The provider (gs) will be in index 0, followed by the bucket name
 	}
 	gcswebPrefix := cfg().Deck.Spyglass.GetGCSBrowserPrefix(org, repo, bucket)
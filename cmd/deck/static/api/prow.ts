@@ -122,6 +122,7 @@ export interface ProwJobStatus {
   build_id?: string;
   jenkins_build_id?: string;
   prev_report_states?: { [key: string]: ProwJobState };
+  queue_position?: number;
 }
 
 // PodSpec is a description of a pod.
@@ -49,7 +49,7 @@ export namespace cell {
     return c;
   }
 
-  export function state(s: ProwJobState): HTMLTableDataCellElement {
+  export function state(s: ProwJobState, queuePosition?: number): HTMLTableDataCellElement {
     const c = document.createElement("td");
     c.classList.add("mdl-data-table__cell--non-numeric");
     if (!s) {
@@ -85,6 +85,9 @@ export namespace cell {
     stateIndicator.innerText = displayIcon;
     c.appendChild(stateIndicator);
     c.title = displayState;
+    if (s === State.TRIGGERED && queuePosition) {
+      c.title += `, position ${queuePosition} in queue`;
+    }
 
     return c;
   }
@@ -33,6 +33,8 @@ interface Job {
     type: string;
     yamlDefinition: string;
     jobHistoryLink: string;
+    runAfterSuccess?: string[];
+    downstreams?: string[];
 }
 
 /**
@@ -173,6 +175,16 @@ function genJobDetails(job: Job): HTMLDivElement {
 
     const jobDetails = document.createElement('div');
     jobDetails.appendChild(jobType);
+    if (job.runAfterSuccess) {
+        const upstreams = document.createElement('summary');
+        upstreams.appendChild(document.createTextNode(`Runs after the success of: ${job.runAfterSuccess.join(', ')}`));
+        jobDetails.appendChild(upstreams);
+    }
+    if (job.downstreams) {
+        const downstreams = document.createElement('summary');
+        downstreams.appendChild(document.createTextNode(`Triggers on success: ${job.downstreams.join(', ')}`));
+        jobDetails.appendChild(downstreams);
+    }
     jobDetails.appendChild(jobDefinition);
     jobDetails.appendChild(jobHistoryLink);
 
@@ -2,8 +2,94 @@ import moment from "moment";
 import {cell, formatDuration} from '../common/common';
 
 declare const allBuilds: any;
+declare const jobName: string;
+
+interface Point {
+  build_id: string;
+  duration: number;
+}
+
+interface Series {
+  job: string;
+  test?: string;
+  points: Point[] | null;
+}
+
+interface Regression {
+  job: string;
+  test?: string;
+  baseline: number;
+  recent: number;
+  increase_percent: number;
+  first_build: string;
+}
+
+interface Trend {
+  job: Series;
+  tests?: Series[];
+  regressions?: Regression[];
+}
+
+const sparklineWidth = 300;
+const sparklineHeight = 30;
+
+// sparkline draws the durations of the series, oldest first.
+function sparkline(series: Series): SVGSVGElement {
+  const ns = "http://www.w3.org/2000/svg";
+  const svg = document.createElementNS(ns, "svg");
+  svg.setAttribute("width", String(sparklineWidth));
+  svg.setAttribute("height", String(sparklineHeight));
+  const points = series.points || [];
+  const max = Math.max(...points.map((p) => p.duration));
+  const step = points.length > 1 ? sparklineWidth / (points.length - 1) : 0;
+  const line = document.createElementNS(ns, "polyline");
+  line.setAttribute("points", points.map((p, i) =>
+    `${i * step},${sparklineHeight - 1 - (p.duration / max) * (sparklineHeight - 2)}`).join(" "));
+  svg.appendChild(line);
+  const title = document.createElementNS(ns, "title");
+  title.textContent = points.map((p) => `${p.build_id}: ${formatDuration(p.duration / 1000000000)}`).join("\n");
+  svg.appendChild(title);
+  return svg;
+}
+
+function trendRow(label: string, series: Series, regression?: Regression): HTMLElement {
+  const row = document.createElement("div");
+  row.classList.add("duration-trend");
+  row.appendChild(sparkline(series));
+  const text = document.createElement("span");
+  text.textContent = label;
+  if (regression) {
+    row.classList.add("regressed");
+    text.textContent += `: ${regression.increase_percent.toFixed(0)}% slower since ${regression.first_build} ` +
+      `(${formatDuration(regression.recent / 1000000000)}, was ${formatDuration(regression.baseline / 1000000000)})`;
+  }
+  row.appendChild(text);
+  return row;
+}
+
+async function renderTrends(): Promise<void> {
+  const resp = await fetch(`/durations.js?job=${encodeURIComponent(jobName)}`);
+  if (!resp.ok) {
+    return;
+  }
+  const trend: Trend = await resp.json();
+  if (!trend.job.points || trend.job.points.length < 2) {
+    return;
+  }
+  const regressions = trend.regressions || [];
+  const container = document.getElementById("duration-trends")!;
+  const title = document.createElement("h6");
+  title.textContent = "Duration of the passing runs";
+  container.appendChild(title);
+  container.appendChild(trendRow("Job", trend.job, regressions.find((r) => !r.test)));
+  for (const series of trend.tests || []) {
+    container.appendChild(trendRow(series.test!, series, regressions.find((r) => r.test === series.test)));
+  }
+}
 
 window.onload = (): void => {
+  renderTrends();
+
   const tbody = document.getElementById("history-table-body")!;
 
   for (const build of allBuilds) {
@@ -42,6 +128,11 @@ window.onload = (): void => {
     tr.appendChild(cell.time(build.ID, moment.unix(started)));
     tr.appendChild(cell.text(formatDuration(build.Duration / 1000000000 ))); // convert from ns to s.
     tr.appendChild(cell.text(build.Result));
+    if (build.CompareLink) {
+      tr.appendChild(cell.link("Compare with previous", build.CompareLink));
+    } else {
+      tr.appendChild(cell.text(""));
+    }
 
     for (const child of tr.children) {
       child.classList.add("mdl-data-table__cell--non-numeric");
@@ -513,7 +513,7 @@ function redraw(fz: FuzzySearch, pushState = true): void {
         refs: {repo_link = "", base_sha = "", base_link = "", pulls = [], base_ref = ""} = {},
         pod_spec,
       },
-      status: {startTime, completionTime = "", state = "", pod_name, build_id = "", url = ""},
+      status: {startTime, completionTime = "", state = "", pod_name, build_id = "", url = "", queue_position},
     } = build;
 
     let buildUrl = url;
@@ -609,7 +609,7 @@ function redraw(fz: FuzzySearch, pushState = true): void {
     displayedJob++;
     const r = document.createElement("tr");
     // State column
-    r.appendChild(cell.state(state));
+    r.appendChild(cell.state(state, queue_position));
     // Log column
     r.appendChild(createLogCell(build, buildUrl));
     // Rerun column
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"sigs.k8s.io/prow/pkg/github"
+)
+
+const (
+	defaultChunkConcurrency  = 4
+	defaultChunkTokens       = 2000
+	defaultChunkTokenBudget  = 100000
+	defaultSummaryPrompt     = "The pull request is too large to review at once, so it was reviewed in parts. Here are the reviews of the parts, please merge them into one coherent review, remove the duplicates and keep the important problems and suggestions:"
+	defaultInlineSummaryNote = "Only answer the merged overall review in markdown format."
+)
+
+// ChunkingConfig represents how to review a large pull request in parts.
+//
+// The diff is split by files and hunks into chunks, every chunk is reviewed
+// concurrently, then a summarizing pass merges the partial reviews into one.
+type ChunkingConfig struct {
+	MaxChunkTokens int    `yaml:"max_chunk_tokens,omitempty" json:"max_chunk_tokens,omitempty"` // max tokens of the diff in a chunk, defaults to half of the model context.
+	MaxConcurrency int    `yaml:"max_concurrency,omitempty" json:"max_concurrency,omitempty"`   // max chunks reviewed at the same time, defaults to 4.
+	TokenBudget    int    `yaml:"token_budget,omitempty" json:"token_budget,omitempty"`         // max estimated tokens spent on the chunks, the remaining chunks are skipped. defaults to 100000.
+	SummaryPrompt  string `yaml:"summary_prompt,omitempty" json:"summary_prompt,omitempty"`     // prompt for the summarizing pass.
+}
+
+// diffFile is the diff of a single file.
+type diffFile struct {
+	path   string
+	header string   // lines before the first hunk, such as `diff --git ...`, `---` and `+++`.
+	hunks  []string // every hunk starts with its `@@` line.
+}
+
+func (f *diffFile) String() string {
+	return f.header + strings.Join(f.hunks, "")
+}
+
+// diffChunk is a part of the diff reviewed in one request.
+type diffChunk struct {
+	files  []string
+	patch  string
+	tokens int
+}
+
+// splitDiffFiles splits the git diff into files.
+func splitDiffFiles(diff string) []*diffFile {
+	var ret []*diffFile
+	var cur *diffFile
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		if line == "" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			cur = &diffFile{header: line}
+			// diff --git a/path b/path
+			if fields := strings.Fields(line); len(fields) >= 4 {
+				cur.path = strings.TrimPrefix(fields[3], "b/")
+			}
+			ret = append(ret, cur)
+		case cur == nil:
+			// content before the first file, such as the mail header.
+			continue
+		case strings.HasPrefix(line, "@@"):
+			cur.hunks = append(cur.hunks, line)
+		case len(cur.hunks) != 0:
+			cur.hunks[len(cur.hunks)-1] += line
+		default:
+			if p, ok := strings.CutPrefix(strings.TrimSuffix(line, "\n"), "+++ b/"); ok {
+				cur.path = p
+			}
+			cur.header += line
+		}
+	}
+
+	return ret
+}
+
+// matchPathGlob returns whether the file matches the glob, a trailing `/**`
+// matches all files under the directory, and a glob without `/` matches the
+// base name of the file.
+func matchPathGlob(glob, file string) bool {
+	if dir, ok := strings.CutSuffix(glob, "/**"); ok {
+		return strings.HasPrefix(file, dir+"/")
+	}
+	if !strings.Contains(glob, "/") {
+		file = path.Base(file)
+	}
+	matched, _ := path.Match(glob, file)
+	return matched
+}
+
+// filterDiffFiles drops the files matching any of the globs.
+func filterDiffFiles(files []*diffFile, globs []string) []*diffFile {
+	if len(globs) == 0 {
+		return files
+	}
+
+	var ret []*diffFile
+	for _, f := range files {
+		skip := false
+		for _, g := range globs {
+			if matchPathGlob(g, f.path) {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			ret = append(ret, f)
+		}
+	}
+
+	return ret
+}
+
+// joinDiffFiles composes the files to a diff.
+func joinDiffFiles(files []*diffFile) string {
+	var b strings.Builder
+	for _, f := range files {
+		b.WriteString(f.String())
+	}
+
+	return b.String()
+}
+
+// splitDiffChunks packs the files into chunks under the token limit. Files
+// larger than the limit are split by hunks, and hunks larger than the limit
+// are split by lines with the hunk head repeated.
+func splitDiffChunks(files []*diffFile, maxTokens int, countTokens func(string) int) []*diffChunk {
+	var ret []*diffChunk
+	cur := &diffChunk{}
+	add := func(file, patch string, tokens int) {
+		if cur.tokens > 0 && cur.tokens+tokens > maxTokens {
+			ret = append(ret, cur)
+			cur = &diffChunk{}
+		}
+		if len(cur.files) == 0 || cur.files[len(cur.files)-1] != file {
+			cur.files = append(cur.files, file)
+		}
+		cur.patch += patch
+		cur.tokens += tokens
+	}
+
+	for _, f := range files {
+		if tokens := countTokens(f.String()); tokens <= maxTokens {
+			add(f.path, f.String(), tokens)
+			continue
+		}
+
+		headerTokens := countTokens(f.header)
+		for _, h := range f.hunks {
+			// every part of a split file carries the file header.
+			if tokens := headerTokens + countTokens(h); tokens <= maxTokens {
+				add(f.path, f.header+h, tokens)
+				continue
+			}
+			for _, part := range splitHunk(h, maxTokens-headerTokens, countTokens) {
+				add(f.path, f.header+part, headerTokens+countTokens(part))
+			}
+		}
+	}
+	if cur.tokens > 0 {
+		ret = append(ret, cur)
+	}
+
+	return ret
+}
+
+// splitHunk splits the hunk by lines, every part starts with the hunk head.
+func splitHunk(hunk string, maxTokens int, countTokens func(string) int) []string {
+	lines := strings.SplitAfter(hunk, "\n")
+	head := lines[0]
+	headTokens := countTokens(head)
+
+	var ret []string
+	part, partTokens := head, headTokens
+	for _, line := range lines[1:] {
+		if line == "" {
+			continue
+		}
+		tokens := countTokens(line)
+		if partTokens > headTokens && partTokens+tokens > maxTokens {
+			ret = append(ret, part)
+			part, partTokens = head, headTokens
+		}
+		part += line
+		partTokens += tokens
+	}
+	if partTokens > headTokens {
+		ret = append(ret, part)
+	}
+
+	return ret
+}
+
+// maxChunkTokens returns the max tokens of the diff in a chunk, the chunk
+// messages without the diff take messageTokens.
+func (c *ChunkingConfig) maxChunkTokens(provider LLMProvider, maxResponseTokens, messageTokens int) int {
+	room := 0
+	if limit := provider.MaxContextTokens(); limit > 0 {
+		room = limit - maxResponseTokens - messageTokens
+	}
+	if c.MaxChunkTokens > 0 {
+		if room > 0 {
+			return min(c.MaxChunkTokens, room)
+		}
+		return c.MaxChunkTokens
+	}
+	if room > 0 {
+		return room / 2
+	}
+
+	return defaultChunkTokens
+}
+
+func (c *ChunkingConfig) tokenBudget() int {
+	if c.TokenBudget > 0 {
+		return c.TokenBudget
+	}
+
+	return defaultChunkTokenBudget
+}
+
+// mapReduceReview reviews the chunks of the patch concurrently and merges
+// the partial reviews by a summarizing pass.
+func (s *Server) mapReduceReview(logger *logrus.Entry, key usageKey, task *Task, pr *github.PullRequest, patch string, files []*diffFile) (string, error) {
+	// the chunks are reviewed by the provider routed for the whole patch, so
+	// they are sized for the model reviewing them.
+	messages := taskMessages(task, taskMessage(task, pr, patch))
+	provider, err := s.providerFor(task, len(messages[0].Content)+len(messages[1].Content))
+	if err != nil {
+		return "", err
+	}
+	model := provider.Model()
+
+	messageTokens := numTokensFromMessages(taskMessages(task, taskMessage(task, pr, "")), model)
+	chunks := splitDiffChunks(files, task.Chunking.maxChunkTokens(provider, task.MaxResponseTokens, messageTokens), tokenCounter(model))
+	if len(chunks) <= 1 {
+		return s.chatWithProvider(logger, key, task, provider, messages)
+	}
+	logger.Debugf("review in %d chunks", len(chunks))
+
+	// apply the budget in order, the chunks out of budget are skipped.
+	var selected [][]openai.ChatCompletionMessage
+	var selectedFiles [][]string
+	skippedFiles := sets.New[string]()
+	spent, budget := 0, task.Chunking.tokenBudget()
+	for _, c := range chunks {
+		chunkMessages := taskMessages(task, taskMessage(task, pr, c.patch))
+		cost := numTokensFromMessages(chunkMessages, model) + task.MaxResponseTokens
+		if spent+cost > budget {
+			skippedFiles.Insert(c.files...)
+			continue
+		}
+		spent += cost
+		selected = append(selected, chunkMessages)
+		selectedFiles = append(selectedFiles, c.files)
+	}
+	if len(selected) == 0 {
+		return "", fmt.Errorf("no chunks fit in the token budget %d", budget)
+	}
+
+	concurrency := task.Chunking.MaxConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultChunkConcurrency
+	}
+	partials := make([]string, len(selected))
+	errs := make([]error, len(selected))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, m := range selected {
+		wg.Add(1)
+		go func(i int, m []openai.ChatCompletionMessage) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			partials[i], errs[i] = s.chatWithProvider(logger.WithField("chunk", i), key, task, provider, m)
+		}(i, m)
+	}
+	wg.Wait()
+
+	var reviews []string
+	for i, err := range errs {
+		if err != nil {
+			logger.WithError(err).WithField("chunk", i).Warn("Failed to review the chunk.")
+			skippedFiles.Insert(selectedFiles[i]...)
+			continue
+		}
+		reviews = append(reviews, partials[i])
+	}
+	if len(reviews) == 0 {
+		return "", fmt.Errorf("all the %d chunks failed, the first error: %w", len(errs), errs[0])
+	}
+
+	return s.reduceReviews(logger, key, task, reviews, skippedFiles)
+}
+
+// reduceReviews merges the partial reviews into one. In inline review mode
+// only the summaries are merged by the AI server, the findings are collected.
+func (s *Server) reduceReviews(logger *logrus.Entry, key usageKey, task *Task, reviews []string, skippedFiles sets.Set[string]) (string, error) {
+	var findings []reviewFinding
+	summaries := reviews
+	if task.InlineReview {
+		summaries = nil
+		for _, r := range reviews {
+			result, err := parseReviewResult(r)
+			if err != nil {
+				logger.WithError(err).Warn("Failed to parse the structured review of the chunk.")
+				summaries = append(summaries, r)
+				continue
+			}
+			summaries = append(summaries, result.Summary)
+			findings = append(findings, result.Findings...)
+		}
+	}
+
+	summaryPrompt := task.Chunking.SummaryPrompt
+	if summaryPrompt == "" {
+		summaryPrompt = defaultSummaryPrompt
+	}
+	parts := []string{summaryPrompt}
+	for i, r := range summaries {
+		parts = append(parts,
+			fmt.Sprintf("[START PART %d/%d]", i+1, len(summaries)),
+			r,
+			fmt.Sprintf("[END PART %d/%d]", i+1, len(summaries)))
+	}
+	if task.InlineReview {
+		parts = append(parts, defaultInlineSummaryNote)
+	}
+
+	summary, err := s.chatWithAIServer(logger.WithField("chunk", "summary"), key, task, strings.Join(parts, "\n"))
+	if err != nil {
+		logger.WithError(err).Warn("Failed to summarize the partial reviews, join them instead.")
+		summary = strings.Join(summaries, "\n\n------\n\n")
+	}
+	if skippedFiles.Len() != 0 {
+		summary += fmt.Sprintf("\n\n> Skipped reviewing some parts of the files: %s", strings.Join(sets.List(skippedFiles), ", "))
+	}
+
+	if !task.InlineReview {
+		return summary, nil
+	}
+
+	ret, err := json.Marshal(reviewResult{Summary: summary, Findings: findings})
+	if err != nil {
+		return "", err
+	}
+
+	return string(ret), nil
+}
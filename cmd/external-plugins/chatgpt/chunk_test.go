@@ -0,0 +1,450 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"sigs.k8s.io/prow/pkg/github"
+)
+
+const testChunkDiff = `diff --git a/pkg/a.go b/pkg/a.go
+index 1111111..2222222 100644
+--- a/pkg/a.go
++++ b/pkg/a.go
+@@ -1,2 +1,3 @@
+ package pkg
++// a
+ 
+@@ -10,2 +11,3 @@ func A() {
+ 	a := 1
++	b := 2
+ }
+diff --git a/vendor/x/x.go b/vendor/x/x.go
+new file mode 100644
+--- /dev/null
++++ b/vendor/x/x.go
+@@ -0,0 +1,1 @@
++package x
+diff --git a/api/zz_generated.deepcopy.go b/api/zz_generated.deepcopy.go
+--- a/api/zz_generated.deepcopy.go
++++ b/api/zz_generated.deepcopy.go
+@@ -1,1 +1,1 @@
+-package api
++package api // generated
+`
+
+// countLines counts every line as a token.
+func countLines(text string) int {
+	return strings.Count(text, "\n")
+}
+
+func Test_splitDiffFiles(t *testing.T) {
+	files := splitDiffFiles(testChunkDiff)
+
+	var paths []string
+	var hunks []int
+	for _, f := range files {
+		paths = append(paths, f.path)
+		hunks = append(hunks, len(f.hunks))
+	}
+	if diff := cmp.Diff([]string{"pkg/a.go", "vendor/x/x.go", "api/zz_generated.deepcopy.go"}, paths); diff != "" {
+		t.Errorf("paths mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]int{2, 1, 1}, hunks); diff != "" {
+		t.Errorf("hunks mismatch (-want +got):\n%s", diff)
+	}
+	if got := joinDiffFiles(files); got != testChunkDiff {
+		t.Errorf("joinDiffFiles() does not restore the diff:\n%s", got)
+	}
+}
+
+func Test_filterDiffFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		globs []string
+		want  []string
+	}{
+		{
+			name: "no globs",
+			want: []string{"pkg/a.go", "vendor/x/x.go", "api/zz_generated.deepcopy.go"},
+		},
+		{
+			name:  "directory glob",
+			globs: []string{"vendor/**"},
+			want:  []string{"pkg/a.go", "api/zz_generated.deepcopy.go"},
+		},
+		{
+			name:  "base name glob",
+			globs: []string{"zz_generated.*.go", "vendor/**"},
+			want:  []string{"pkg/a.go"},
+		},
+		{
+			name:  "path glob",
+			globs: []string{"pkg/*.go"},
+			want:  []string{"vendor/x/x.go", "api/zz_generated.deepcopy.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, f := range filterDiffFiles(splitDiffFiles(testChunkDiff), tt.globs) {
+				got = append(got, f.path)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("filterDiffFiles() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_taskPatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		globs     []string
+		wantFiles []string
+	}{
+		{
+			name:      "no globs",
+			wantFiles: []string{"pkg/a.go", "vendor/x/x.go", "api/zz_generated.deepcopy.go"},
+		},
+		{
+			name:      "skipped files are not in the patch",
+			globs:     []string{"zz_generated.*.go", "vendor/**"},
+			wantFiles: []string{"pkg/a.go"},
+		},
+		{
+			name:  "all files skipped",
+			globs: []string{"**"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, files := taskPatch(&Task{SkipPathGlobs: tt.globs}, testChunkDiff)
+			var got []string
+			for _, f := range files {
+				got = append(got, f.path)
+			}
+			if diff := cmp.Diff(tt.wantFiles, got); diff != "" {
+				t.Errorf("taskPatch() files mismatch (-want +got):\n%s", diff)
+			}
+			if want := joinDiffFiles(files); patch != want {
+				t.Errorf("taskPatch() patch = %q, want %q", patch, want)
+			}
+			if len(tt.globs) > 0 && len(patch) >= len(testChunkDiff) {
+				t.Errorf("taskPatch() patch size %d is not smaller than the diff size %d", len(patch), len(testChunkDiff))
+			}
+		})
+	}
+}
+
+func Test_splitDiffChunks(t *testing.T) {
+	files := splitDiffFiles(testChunkDiff)
+
+	tests := []struct {
+		name       string
+		maxTokens  int
+		wantFiles  [][]string
+		wantTokens []int
+	}{
+		{
+			name:       "all in one",
+			maxTokens:  100,
+			wantFiles:  [][]string{{"pkg/a.go", "vendor/x/x.go", "api/zz_generated.deepcopy.go"}},
+			wantTokens: []int{24},
+		},
+		{
+			name:       "pack files",
+			maxTokens:  16,
+			wantFiles:  [][]string{{"pkg/a.go"}, {"vendor/x/x.go", "api/zz_generated.deepcopy.go"}},
+			wantTokens: []int{12, 12},
+		},
+		{
+			name:       "split file by hunks",
+			maxTokens:  9,
+			wantFiles:  [][]string{{"pkg/a.go"}, {"pkg/a.go"}, {"vendor/x/x.go"}, {"api/zz_generated.deepcopy.go"}},
+			wantTokens: []int{8, 8, 6, 6},
+		},
+		{
+			name:       "split hunks by lines",
+			maxTokens:  6,
+			wantFiles:  [][]string{{"pkg/a.go"}, {"pkg/a.go"}, {"pkg/a.go"}, {"pkg/a.go"}, {"pkg/a.go"}, {"pkg/a.go"}, {"vendor/x/x.go"}, {"api/zz_generated.deepcopy.go"}},
+			wantTokens: []int{6, 6, 6, 6, 6, 6, 6, 6},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotFiles [][]string
+			var gotTokens []int
+			for _, c := range splitDiffChunks(files, tt.maxTokens, countLines) {
+				gotFiles = append(gotFiles, c.files)
+				gotTokens = append(gotTokens, c.tokens)
+				if c.tokens != countLines(c.patch) {
+					t.Errorf("chunk tokens %d does not match the patch:\n%s", c.tokens, c.patch)
+				}
+			}
+			if diff := cmp.Diff(tt.wantFiles, gotFiles); diff != "" {
+				t.Errorf("files mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantTokens, gotTokens); diff != "" {
+				t.Errorf("tokens mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+const testTwoFilesDiff = `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1,1 +1,1 @@
++package a
+diff --git a/b.go b/b.go
+--- a/b.go
++++ b/b.go
+@@ -1,1 +1,1 @@
++package b
+`
+
+// fakeProvider answers the summarizing pass with "merged" and the chunks with
+// the reviews of their files.
+type fakeProvider struct {
+	providerLimits
+
+	fail string // fails the chunks of the file.
+
+	mu       sync.Mutex
+	messages []string
+}
+
+func (p *fakeProvider) ChatCompletion(_ context.Context, req chatRequest) (*chatResponse, error) {
+	message := req.Messages[len(req.Messages)-1].Content
+	p.mu.Lock()
+	p.messages = append(p.messages, message)
+	p.mu.Unlock()
+
+	if strings.HasPrefix(message, defaultSummaryPrompt) {
+		return &chatResponse{Content: "merged"}, nil
+	}
+	var files []string
+	for _, f := range splitDiffFiles(message) {
+		if f.path == p.fail {
+			return nil, errors.New("fake error")
+		}
+		files = append(files, f.path)
+	}
+
+	return &chatResponse{Content: "review of " + strings.Join(files, ", ")}, nil
+}
+
+func TestServer_mapReduceReview(t *testing.T) {
+	pr := &github.PullRequest{Title: "title", Body: "body"}
+	files := splitDiffFiles(testTwoFilesDiff)
+
+	tests := []struct {
+		name         string
+		chunking     ChunkingConfig
+		fail         string
+		want         string
+		wantErr      bool
+		wantMessages int
+	}{
+		{
+			name:         "small patch is reviewed at once",
+			chunking:     ChunkingConfig{MaxChunkTokens: 1000},
+			want:         "review of a.go, b.go",
+			wantMessages: 1,
+		},
+		{
+			name:         "chunks are reviewed and merged",
+			chunking:     ChunkingConfig{MaxChunkTokens: 1},
+			want:         "merged",
+			wantMessages: 3,
+		},
+		{
+			name:         "chunks out of budget are skipped",
+			chunking:     ChunkingConfig{MaxChunkTokens: 1, TokenBudget: 1500},
+			want:         "merged\n\n> Skipped reviewing some parts of the files: b.go",
+			wantMessages: 2,
+		},
+		{
+			name:         "failed chunks are skipped",
+			chunking:     ChunkingConfig{MaxChunkTokens: 1},
+			fail:         "a.go",
+			want:         "merged\n\n> Skipped reviewing some parts of the files: a.go",
+			wantMessages: 3,
+		},
+		{
+			name:     "all chunks failed",
+			chunking: ChunkingConfig{MaxChunkTokens: 1, TokenBudget: 1500},
+			fail:     "a.go",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &fakeProvider{providerLimits: providerLimits{model: "fake-model"}, fail: tt.fail}
+			s := &Server{providerAgent: &ProviderAgent{ConfigAgent: ConfigAgent[ProvidersConfig]{
+				config: ProvidersConfig{"fake": {provider: provider}},
+			}}}
+			task := &Task{Provider: "fake", UserPrompt: "review", MaxResponseTokens: 1000, Chunking: &tt.chunking}
+
+			got, err := s.mapReduceReview(logrus.WithField("test", tt.name), usageKey{}, task, pr, testTwoFilesDiff, files)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("mapReduceReview() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("mapReduceReview() = %q, want %q", got, tt.want)
+			}
+			if !tt.wantErr && len(provider.messages) != tt.wantMessages {
+				t.Errorf("sent %d messages, want %d", len(provider.messages), tt.wantMessages)
+			}
+		})
+	}
+}
+
+func TestServer_mapReduceReviewRoutesChunksWithPatch(t *testing.T) {
+	var b strings.Builder
+	for _, f := range []string{"a.go", "b.go"} {
+		b.WriteString("diff --git a/" + f + " b/" + f + "\n--- a/" + f + "\n+++ b/" + f + "\n@@ -1,30 +1,30 @@\n")
+		b.WriteString(strings.Repeat("+// a comment line\n", 30))
+	}
+	patch := b.String()
+	files := splitDiffFiles(patch)
+
+	pr := &github.PullRequest{Title: "title", Body: "body"}
+	// every chunk holds a file.
+	maxChunkTokens := tokenCounter("fake-model")(files[0].String())
+	task := &Task{UserPrompt: "review", Chunking: &ChunkingConfig{MaxChunkTokens: maxChunkTokens, MaxConcurrency: 1}}
+	small, smallCalls := fakeServer(t, "/v1/chat/completions", nil, fakeOpenaiResponse, nil)
+	large, largeCalls := fakeServer(t, "/v1/chat/completions", nil, fakeOpenaiResponse, nil)
+	agent := func(url string) *OpenaiAgent {
+		cfg := OpenaiConfig{BaseURL: url + "/v1", Model: "fake-model"}
+		if err := cfg.initClient(); err != nil {
+			t.Fatal(err)
+		}
+		return &OpenaiAgent{ConfigAgent: ConfigAgent[OpenaiConfig]{config: cfg}}
+	}
+	s := &Server{openaiClientAgent: &OpenaiWrapAgent{
+		small: agent(small.URL),
+		large: agent(large.URL),
+		// the whole patch is routed to the large model, but not a single chunk.
+		largeDownThreshold: len(taskMessage(task, pr, patch)) - 1,
+	}}
+
+	if _, err := s.mapReduceReview(logrus.WithField("test", t.Name()), usageKey{}, task, pr, patch, files); err != nil {
+		t.Fatalf("mapReduceReview() error = %v", err)
+	}
+	if *largeCalls != 2 {
+		t.Errorf("the large model reviewed %d chunks, want 2", *largeCalls)
+	}
+	if *smallCalls != 1 {
+		t.Errorf("the small model got %d requests, want only the summary", *smallCalls)
+	}
+}
+
+func TestServer_reduceReviews(t *testing.T) {
+	finding := func(file string) reviewFinding {
+		return reviewFinding{File: file, StartLine: 1, EndLine: 1, Severity: "minor", Comment: "fix " + file}
+	}
+	structured := func(summary string, findings ...reviewFinding) string {
+		b, err := json.Marshal(reviewResult{Summary: summary, Findings: findings})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "```json\n" + string(b) + "\n```"
+	}
+
+	tests := []struct {
+		name         string
+		inline       bool
+		reviews      []string
+		skippedFiles sets.Set[string]
+		fail         string
+		wantSummary  string
+		wantParts    []string
+		wantFindings []reviewFinding
+	}{
+		{
+			name:        "merge reviews",
+			reviews:     []string{"review of a.go", "review of b.go"},
+			wantSummary: "merged",
+			wantParts:   []string{"[START PART 1/2]\nreview of a.go\n[END PART 1/2]", "[START PART 2/2]\nreview of b.go\n[END PART 2/2]"},
+		},
+		{
+			name:         "list skipped files once and sorted",
+			reviews:      []string{"review of a.go"},
+			skippedFiles: sets.New("c.go", "b.go"),
+			wantSummary:  "merged\n\n> Skipped reviewing some parts of the files: b.go, c.go",
+		},
+		{
+			name:         "inline review merges the summaries and collects the findings",
+			inline:       true,
+			reviews:      []string{structured("summary of a.go", finding("a.go")), "not structured", structured("summary of b.go", finding("b.go"))},
+			wantSummary:  "merged",
+			wantParts:    []string{"summary of a.go", "not structured", "summary of b.go", defaultInlineSummaryNote},
+			wantFindings: []reviewFinding{finding("a.go"), finding("b.go")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &fakeProvider{providerLimits: providerLimits{model: "fake-model"}}
+			s := &Server{providerAgent: &ProviderAgent{ConfigAgent: ConfigAgent[ProvidersConfig]{
+				config: ProvidersConfig{"fake": {provider: provider}},
+			}}}
+			task := &Task{Provider: "fake", InlineReview: tt.inline, Chunking: &ChunkingConfig{}}
+
+			got, err := s.reduceReviews(logrus.WithField("test", tt.name), usageKey{}, task, tt.reviews, tt.skippedFiles)
+			if err != nil {
+				t.Fatalf("reduceReviews() error = %v", err)
+			}
+			if len(provider.messages) != 1 {
+				t.Fatalf("sent %d messages, want 1", len(provider.messages))
+			}
+			for _, part := range tt.wantParts {
+				if !strings.Contains(provider.messages[0], part) {
+					t.Errorf("summary message does not contain %q:\n%s", part, provider.messages[0])
+				}
+			}
+
+			if !tt.inline {
+				if got != tt.wantSummary {
+					t.Errorf("reduceReviews() = %q, want %q", got, tt.wantSummary)
+				}
+				return
+			}
+			result, err := parseReviewResult(got)
+			if err != nil {
+				t.Fatalf("reduceReviews() = %q is not structured: %v", got, err)
+			}
+			if diff := cmp.Diff(&reviewResult{Summary: tt.wantSummary, Findings: tt.wantFindings}, result); diff != "" {
+				t.Errorf("result mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -81,12 +81,17 @@ func NewWrapOpenaiAgent(defaultPath, largePath string, largeDownThreshold int, w
 	return ret, nil
 }
 
-func (a *OpenaiWrapAgent) ClientFor(msgLen int) (*openai.Client, string) {
+// ProviderFor returns the provider routed by the message length.
+func (a *OpenaiWrapAgent) ProviderFor(msgLen int) LLMProvider {
+	cfg := a.small.Data()
 	if a.large != nil &&
 		a.largeDownThreshold > 0 &&
 		msgLen > a.largeDownThreshold {
-		return a.large.Data().client, a.large.Data().Model
+		cfg = a.large.Data()
 	}
 
-	return a.small.Data().client, a.small.Data().Model
+	return &openaiProvider{
+		providerLimits: providerLimits{model: cfg.Model, maxContextTokens: maxTokens[cfg.Model]},
+		client:         cfg.client,
+	}
 }
@@ -56,6 +56,11 @@ type Task struct {
 	OutputStaticHeadNote string             `yaml:"output_static_head_note,omitempty" json:"output_static_head_note,omitempty"`
 	MaxResponseTokens    int                `yaml:"max_response_tokens,omitempty" json:"max_response_tokens,omitempty"`
 	ExternalContexts     []*ExternalContext `yaml:"external_contexts,omitempty" json:"external_contexts,omitempty"`
+	Provider             string             `yaml:"provider,omitempty" json:"provider,omitempty"`                   // name of the LLM provider to use, defaults to the OpenAI config.
+	SkipPathGlobs        []string           `yaml:"skip_path_globs,omitempty" json:"skip_path_globs,omitempty"`     // skip the files matched the globs in the diff, such as generated files or vendored code.
+	Chunking             *ChunkingConfig    `yaml:"chunking,omitempty" json:"chunking,omitempty"`                   // review the large pull request in chunks.
+	DailyTokenQuota      int                `yaml:"daily_token_quota,omitempty" json:"daily_token_quota,omitempty"` // max tokens the task can consume per day (UTC) in a repository, 0 means no limit.
+	InlineReview         bool               `yaml:"inline_review,omitempty" json:"inline_review,omitempty"`         // ask for structured findings and post them as line comments of a pull request review.
 
 	AlwaysRun       bool     `yaml:"always_run,omitempty" json:"always_run,omitempty"`               // automatic run or should triggered by comments.
 	SkipAuthors     []string `yaml:"skip_authors,omitempty" json:"skip_authors,omitempty"`           // skip the pull request created by the authors.
@@ -103,11 +108,14 @@ func (ec *ExternalContext) Content() ([]byte, error) {
 // TaskAgent agent for fetch tasks with watching and hot reload.
 type TaskAgent struct {
 	ConfigAgent[TasksConfig]
+
+	// defaultDailyTokenQuota is the daily token quota of the default task, 0 means no limit.
+	defaultDailyTokenQuota int
 }
 
-// NewTaskAgent returns a new ConfigLoader.
-func NewTaskAgent(path string, watchInterval time.Duration) (*TaskAgent, error) {
-	c := &TaskAgent{ConfigAgent: ConfigAgent[TasksConfig]{path: path}}
+// NewTaskAgent returns a new ConfigLoader, the default task gets the daily token quota.
+func NewTaskAgent(path string, watchInterval time.Duration, defaultDailyTokenQuota int) (*TaskAgent, error) {
+	c := &TaskAgent{ConfigAgent: ConfigAgent[TasksConfig]{path: path}, defaultDailyTokenQuota: defaultDailyTokenQuota}
 	if err := c.Reload(path); err != nil {
 		return nil, err
 	}
@@ -170,11 +178,14 @@ func (c *TaskAgent) Task(org, repo, taskName string, needDefault bool) (*Task, e
 	return nil, nil
 }
 
+// DefaultTask returns the task used when no task is configured for the
+// command or the command gives its own prompt.
 func (c *TaskAgent) DefaultTask() *Task {
 	return &Task{
 		SystemMessage:        defaultSystemMessage,
 		UserPrompt:           defaultPromte,
 		MaxResponseTokens:    defaultMaxResponseTokens,
 		PatchIntroducePrompt: defaultPrPatchIntroducePromte,
+		DailyTokenQuota:      c.defaultDailyTokenQuota,
 	}
 }
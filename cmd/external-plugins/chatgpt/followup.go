@@ -0,0 +1,249 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/prow/pkg/github"
+)
+
+const (
+	followupForeword        = "followup"
+	followupHistoryPrompt   = "The following messages are the previous conversation about the pull request."
+	followupPatchPrompt     = "This is the diff for the pull request under discussion:"
+	followupMinQuoteLength  = 10
+	followupDefaultBudget   = 4000
+	followupDetailsSplitter = "\n<details>"
+
+	// answerMarker is the hidden mark appended to the answers of the plugin,
+	// only the marked comments and reviews are taken as the conversation history.
+	answerMarker = "<!-- chatgpt-plugin-answer -->"
+)
+
+// followupQuestion returns the question when the command is a follow-up.
+func followupQuestion(foreword string) (string, bool) {
+	question, ok := strings.CutPrefix(foreword, followupForeword)
+	if !ok || (question != "" && question[0] != ' ' && question[0] != '\t') {
+		return "", false
+	}
+
+	question = strings.TrimSpace(question)
+	return question, question != ""
+}
+
+// splitQuoteReply splits the comment that replies by quoting into the quoted
+// text and the reply. It returns false when the comment does not start with a quote.
+func splitQuoteReply(body string) (quoted, reply string, ok bool) {
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	var quotedLines []string
+	i := 0
+	for ; i < len(lines) && strings.HasPrefix(lines[i], ">"); i++ {
+		quotedLines = append(quotedLines, strings.TrimSpace(strings.TrimPrefix(lines[i], ">")))
+	}
+	if i == 0 {
+		return "", "", false
+	}
+
+	quoted = strings.TrimSpace(strings.Join(quotedLines, "\n"))
+	reply = strings.TrimSpace(strings.Join(lines[i:], "\n"))
+	return quoted, reply, quoted != "" && reply != ""
+}
+
+// chatTurn is a message in the conversation history of the pull request.
+type chatTurn struct {
+	role    string
+	content string
+	at      time.Time
+}
+
+// conversationHistory rebuilds the previous exchange from the comments and
+// reviews of the pull request. The answers of the plugin are assistant messages,
+// the commands to the bot are user messages, other comments are ignored.
+func conversationHistory(comments []github.IssueComment, reviews []github.Review, isBot func(string) bool,
+	commandForeword func(string) (string, bool), excludeCommentID int) []chatTurn {
+	var ret []chatTurn
+	for _, c := range comments {
+		if c.ID == excludeCommentID {
+			continue
+		}
+		if isBot(c.User.Login) {
+			if isAnswer(c.Body) {
+				ret = append(ret, chatTurn{role: openai.ChatMessageRoleAssistant, content: trimBotDetails(c.Body), at: c.CreatedAt})
+			}
+			continue
+		}
+		if foreword, ok := commandForeword(c.Body); ok {
+			if question, ok := followupQuestion(foreword); ok {
+				foreword = question
+			}
+			ret = append(ret, chatTurn{role: openai.ChatMessageRoleUser, content: foreword, at: c.CreatedAt})
+		}
+	}
+	for _, r := range reviews {
+		if isBot(r.User.Login) && isAnswer(r.Body) {
+			ret = append(ret, chatTurn{role: openai.ChatMessageRoleAssistant, content: trimBotDetails(r.Body), at: r.SubmittedAt})
+		}
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool { return ret[i].at.Before(ret[j].at) })
+	return ret
+}
+
+// markAnswer marks the response as an answer of the plugin.
+func markAnswer(resp string) string {
+	return resp + "\n\n" + answerMarker
+}
+
+// isAnswer returns whether the body is an answer of the plugin.
+func isAnswer(body string) bool {
+	return strings.Contains(body, answerMarker)
+}
+
+// trimBotDetails removes the details section and the answer marker appended
+// to the bot responses.
+func trimBotDetails(body string) string {
+	if i := strings.Index(body, followupDetailsSplitter); i >= 0 {
+		body = body[:i]
+	}
+
+	return strings.TrimSpace(strings.ReplaceAll(body, answerMarker, ""))
+}
+
+// quotesAssistant returns whether the quoted text comes from any assistant
+// message. The markdown marks are ignored since the quote reply of GitHub
+// quotes the rendered text.
+func quotesAssistant(history []chatTurn, quoted string) bool {
+	quoted = normalizeQuoteText(quoted)
+	if len(quoted) < followupMinQuoteLength {
+		return false
+	}
+	for _, t := range history {
+		if t.role == openai.ChatMessageRoleAssistant && strings.Contains(normalizeQuoteText(t.content), quoted) {
+			return true
+		}
+	}
+
+	return false
+}
+
+var quoteMarkdownReplacer = strings.NewReplacer("*", "", "`", "", "_", "", "#", "", ">", "")
+
+func normalizeQuoteText(text string) string {
+	return strings.Join(strings.Fields(quoteMarkdownReplacer.Replace(text)), " ")
+}
+
+// pruneHistory keeps the most recent turns that fit the token budget.
+func pruneHistory(history []chatTurn, budget int, countTokens func(string) int) []chatTurn {
+	start := len(history)
+	for ; start > 0; start-- {
+		tokens := countTokens(history[start-1].content)
+		if tokens > budget {
+			break
+		}
+		budget -= tokens
+	}
+
+	return history[start:]
+}
+
+// followupMessages composes the chat messages for the follow-up question. The
+// question is always kept, then the history as recent as possible and at last
+// the diff of the pull request when there are tokens left.
+func followupMessages(task *Task, history []chatTurn, patch, question string, budget int, countTokens func(string) int) []openai.ChatCompletionMessage {
+	budget -= countTokens(task.SystemMessage) + countTokens(followupHistoryPrompt) + countTokens(question)
+	history = pruneHistory(history, budget, countTokens)
+	for _, t := range history {
+		budget -= countTokens(t.content)
+	}
+
+	messages := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleSystem, Content: task.SystemMessage}}
+	patchMessage := strings.Join([]string{followupPatchPrompt, "```diff", patch, "```"}, "\n")
+	if patch != "" && countTokens(patchMessage) <= budget {
+		messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: patchMessage})
+	}
+	if len(history) != 0 {
+		messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: followupHistoryPrompt})
+	}
+	for _, t := range history {
+		messages = append(messages, openai.ChatCompletionMessage{Role: t.role, Content: t.content})
+	}
+
+	return append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: question})
+}
+
+// handleFollowup answers the question with the previous exchange in the pull
+// request as chat context. When quoted is not empty, the comment is a reply
+// by quoting, and it's answered only when it quotes an answer of the plugin.
+func (s *Server) handleFollowup(logger *logrus.Entry, pr *github.PullRequest, comment *github.IssueComment, question, quoted string) error {
+	org := pr.Base.Repo.Owner.Login
+	repo := pr.Base.Repo.Name
+	num := pr.Number
+
+	isBot, err := s.ghc.BotUserChecker()
+	if err != nil {
+		return err
+	}
+	if isBot(comment.User.Login) {
+		return nil
+	}
+	comments, err := s.ghc.ListIssueComments(org, repo, num)
+	if err != nil {
+		return err
+	}
+	reviews, err := s.ghc.ListReviews(org, repo, num)
+	if err != nil {
+		return err
+	}
+
+	history := conversationHistory(comments, reviews, isBot, s.commandForeword, comment.ID)
+	if quoted != "" {
+		if !quotesAssistant(history, quoted) {
+			logger.Debug("the quoted text is not from the bot, skip it.")
+			return nil
+		}
+		question = fmt.Sprintf("About your words:\n> %s\n\n%s", strings.ReplaceAll(quoted, "\n", "\n> "), question)
+	}
+
+	task, err := s.openaiTaskAgent.Task(org, repo, defaultIssueReviewWorld, true)
+	if err != nil {
+		return err
+	}
+	provider, err := s.providerFor(task, 0)
+	if err != nil {
+		return err
+	}
+
+	diff, err := s.getPullRequestDiff(logger, org, repo, num)
+	if err != nil {
+		return err
+	}
+	patch := string(diff)
+	if len(diff) > s.maxDiffSize {
+		patch = ""
+	}
+
+	budget := provider.MaxContextTokens() - task.MaxResponseTokens
+	if provider.MaxContextTokens() == 0 {
+		budget = followupDefaultBudget
+	}
+	messages := followupMessages(task, history, patch, question, budget, tokenCounter(provider.Model()))
+	logger.Debugf("follow up with %d messages", len(messages))
+
+	resp, err := s.chatMessagesWithAIServer(logger, usageKey{org: org, repo: repo, task: userCommentTask}, task, messages)
+	if err == nil {
+		resp = markAnswer(resp)
+	} else if errors.Is(err, errQuotaExhausted) {
+		resp = quotaExhaustedMessage
+	} else if err != nil {
+		logger.Errorf("Failed to send message to OpenAI server: %v", err)
+		resp = "Sorry, some error happened!"
+	}
+
+	return s.createComment(logger, org, repo, num, comment, resp)
+}
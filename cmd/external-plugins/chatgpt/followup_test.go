@@ -0,0 +1,210 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	openai "github.com/sashabaranov/go-openai"
+
+	"sigs.k8s.io/prow/pkg/github"
+)
+
+func Test_followupQuestion(t *testing.T) {
+	tests := []struct {
+		foreword string
+		want     string
+		wantOK   bool
+	}{
+		{foreword: "followup why is line 40 a problem?", want: "why is line 40 a problem?", wantOK: true},
+		{foreword: "followup", wantOK: false},
+		{foreword: "followups are welcome", wantOK: false},
+		{foreword: "default", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := followupQuestion(tt.foreword)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("followupQuestion(%q) = (%q, %v), want (%q, %v)", tt.foreword, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func Test_splitQuoteReply(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantQuoted string
+		wantReply  string
+		wantOK     bool
+	}{
+		{
+			name:       "quote reply",
+			body:       "> the error is ignored\n> on line 40\n\nwhy is it a problem?",
+			wantQuoted: "the error is ignored\non line 40",
+			wantReply:  "why is it a problem?",
+			wantOK:     true,
+		},
+		{
+			name:   "no quote",
+			body:   "looks good",
+			wantOK: false,
+		},
+		{
+			name:       "only quote",
+			body:       "> the error is ignored",
+			wantQuoted: "the error is ignored",
+			wantOK:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quoted, reply, ok := splitQuoteReply(tt.body)
+			if quoted != tt.wantQuoted || reply != tt.wantReply || ok != tt.wantOK {
+				t.Errorf("splitQuoteReply() = (%q, %q, %v), want (%q, %q, %v)", quoted, reply, ok, tt.wantQuoted, tt.wantReply, tt.wantOK)
+			}
+		})
+	}
+}
+
+func Test_conversationHistory(t *testing.T) {
+	s := &Server{issueCommentMatchRegex: regexp.MustCompile(`(?m)^/review\s+(.+)$`)}
+	isBot := func(login string) bool { return login == "bot" }
+	base := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	comments := []github.IssueComment{
+		{ID: 1, User: github.User{Login: "alice"}, Body: "/review default", CreatedAt: base},
+		{ID: 2, User: github.User{Login: "bob"}, Body: "unrelated comment", CreatedAt: base.Add(time.Minute)},
+		{ID: 8, User: github.User{Login: "bot"}, Body: "@alice: I Skip the comment since it is not mergable.", CreatedAt: base.Add(time.Minute)},
+		{ID: 9, User: github.User{Login: "bot"}, Body: "/lgtm", CreatedAt: base.Add(time.Minute)},
+		{ID: 4, User: github.User{Login: "alice"}, Body: "/review followup why?", CreatedAt: base.Add(3 * time.Minute)},
+		{ID: 5, User: github.User{Login: "bot"}, Body: "@alice: " + markAnswer("Because.") + "\n\n<details>\n\nbot instructions\n</details>", CreatedAt: base.Add(4 * time.Minute)},
+		{ID: 6, User: github.User{Login: "alice"}, Body: "/review followup and then?", CreatedAt: base.Add(5 * time.Minute)},
+	}
+	reviews := []github.Review{
+		{ID: 3, User: github.User{Login: "bot"}, Body: markAnswer("The error on line 40 is ignored."), SubmittedAt: base.Add(2 * time.Minute)},
+		{ID: 10, User: github.User{Login: "bot"}, Body: "Approved by another plugin.", SubmittedAt: base.Add(2 * time.Minute)},
+		{ID: 7, User: github.User{Login: "bob"}, Body: "LGTM", SubmittedAt: base.Add(2 * time.Minute)},
+	}
+
+	var got []string
+	for _, turn := range conversationHistory(comments, reviews, isBot, s.commandForeword, 6) {
+		got = append(got, turn.role+": "+turn.content)
+	}
+	want := []string{
+		"user: default",
+		"assistant: The error on line 40 is ignored.",
+		"user: why?",
+		"assistant: @alice: Because.",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("conversationHistory() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_quotesAssistant(t *testing.T) {
+	history := []chatTurn{
+		{role: openai.ChatMessageRoleUser, content: "please check the error handling"},
+		{role: openai.ChatMessageRoleAssistant, content: "1. **The error** of `os.Open` is\n   ignored."},
+	}
+
+	tests := []struct {
+		quoted string
+		want   bool
+	}{
+		{quoted: "The error of os.Open is ignored.", want: true},
+		{quoted: "please check the error handling", want: false},
+		{quoted: "error", want: false},
+	}
+	for _, tt := range tests {
+		if got := quotesAssistant(history, tt.quoted); got != tt.want {
+			t.Errorf("quotesAssistant(%q) = %v, want %v", tt.quoted, got, tt.want)
+		}
+	}
+}
+
+func Test_followupMessages(t *testing.T) {
+	countWords := func(text string) int { return len(strings.Fields(text)) }
+	task := &Task{SystemMessage: "be a reviewer"}
+	history := []chatTurn{
+		{role: openai.ChatMessageRoleUser, content: "review it please"},
+		{role: openai.ChatMessageRoleAssistant, content: "line 40 ignores the error"},
+	}
+	patch := "+ a b c d e f"
+
+	roles := func(messages []openai.ChatCompletionMessage) []string {
+		var ret []string
+		for _, m := range messages {
+			ret = append(ret, m.Role+": "+m.Content)
+		}
+		return ret
+	}
+
+	tests := []struct {
+		name   string
+		budget int
+		want   []string
+	}{
+		{
+			name:   "all fit",
+			budget: 100,
+			want: []string{
+				"system: be a reviewer",
+				"user: " + strings.Join([]string{followupPatchPrompt, "```diff", patch, "```"}, "\n"),
+				"system: " + followupHistoryPrompt,
+				"user: review it please",
+				"assistant: line 40 ignores the error",
+				"user: why?",
+			},
+		},
+		{
+			name:   "drop the patch",
+			budget: 30,
+			want: []string{
+				"system: be a reviewer",
+				"system: " + followupHistoryPrompt,
+				"user: review it please",
+				"assistant: line 40 ignores the error",
+				"user: why?",
+			},
+		},
+		{
+			name:   "drop the older history",
+			budget: 22,
+			want: []string{
+				"system: be a reviewer",
+				"system: " + followupHistoryPrompt,
+				"assistant: line 40 ignores the error",
+				"user: why?",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := roles(followupMessages(task, history, patch, "why?", tt.budget, countWords))
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("followupMessages() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
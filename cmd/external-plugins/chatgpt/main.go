@@ -17,6 +17,7 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"net/http"
@@ -27,10 +28,12 @@ import (
 
 	"github.com/sirupsen/logrus"
 
+	"sigs.k8s.io/prow/pkg/config"
 	"sigs.k8s.io/prow/pkg/config/secret"
 	"sigs.k8s.io/prow/pkg/flagutil"
 	"sigs.k8s.io/prow/pkg/interrupts"
 	"sigs.k8s.io/prow/pkg/logrusutil"
+	"sigs.k8s.io/prow/pkg/metrics"
 	"sigs.k8s.io/prow/pkg/pjutil"
 	"sigs.k8s.io/prow/pkg/pluginhelp/externalplugins"
 )
@@ -41,15 +44,21 @@ type options struct {
 	openaiConfigFile           string
 	openaiConfigFileLarge      string
 	openaiTasksFile            string
+	llmProvidersFile           string
 	openaiConfigReloadInterval time.Duration
 	openaiTasksReloadInterval  time.Duration
 
 	largeDownThreshold  int
 	maxAcceptDiffSize   int
+	maxChunkedDiffSize  int
 	issueCommentCommand string
 
+	quotaStatePath         string
+	defaultDailyTokenQuota int
+
 	dryRun                 bool
 	github                 flagutil.GitHubOptions
+	storage                flagutil.StorageClientOptions
 	instrumentationOptions flagutil.InstrumentationOptions
 	logLevel               string
 
@@ -57,7 +66,7 @@ type options struct {
 }
 
 func (o *options) Validate() error {
-	for idx, group := range []flagutil.OptionGroup{&o.github} {
+	for idx, group := range []flagutil.OptionGroup{&o.github, &o.storage} {
 		if err := group.Validate(o.dryRun); err != nil {
 			return fmt.Errorf("%d: %w", idx, err)
 		}
@@ -75,13 +84,17 @@ func gatherOptions() options {
 	fs.StringVar(&o.openaiConfigFile, "openai-config-file", "/etc/openai/config.yaml", "Path to the file containing the access credential.")
 	fs.StringVar(&o.openaiConfigFileLarge, "openai-config-file-large", "", "Path to the file containing the access credential route for large pull requests.")
 	fs.DurationVar(&o.openaiConfigReloadInterval, "openai-config-reload-interval", time.Minute, "Interval to reload the openai access credential file.")
+	fs.StringVar(&o.llmProvidersFile, "llm-providers-file", "", "Path to the file containing the LLM providers that tasks can choose by name.")
 	fs.StringVar(&o.openaiTasksFile, "openai-tasks-file", "/etc/openai/tasks.yaml", "Path to the file containing the default openai tasks.")
 	fs.DurationVar(&o.openaiTasksReloadInterval, "openai-tasks-reload-interval", time.Minute, "Interval to reload the openai tasks file.")
 	fs.IntVar(&o.largeDownThreshold, "large-down-threshold", 3*4096, "down threshold bytes of message will route to client given by `openai-config-file-large` option.")
 	fs.IntVar(&o.maxAcceptDiffSize, "max-accept-diff-size", 80000, "maximum bytes of PR diff")
+	fs.IntVar(&o.maxChunkedDiffSize, "max-chunked-diff-size", 800000, "maximum bytes of PR diff for the tasks reviewing in chunks")
 	fs.StringVar(&o.issueCommentCommand, "issue-comment-command", "review", "comment command to match for, such as `command1` (you should send comment with `/command1 ...`)")
+	fs.StringVar(&o.quotaStatePath, "quota-state-path", "", "The /local/path, gs://path/to/object or s3://path/to/object to persist the daily token usages for quotas. Usages are kept in memory only when empty.")
+	fs.IntVar(&o.defaultDailyTokenQuota, "default-daily-token-quota", 100000, "Max tokens the default task, used by the comments giving their own prompt and by the comment command without a configured task, can consume per day (UTC) in a repository, 0 means no limit.")
 	fs.StringVar(&o.logLevel, "log-level", "debug", fmt.Sprintf("Log level is one of %v.", logrus.AllLevels))
-	for _, group := range []flagutil.OptionGroup{&o.github, &o.instrumentationOptions} {
+	for _, group := range []flagutil.OptionGroup{&o.github, &o.storage, &o.instrumentationOptions} {
 		group.AddFlags(fs)
 	}
 	fs.Parse(os.Args[1:])
@@ -116,11 +129,30 @@ func main() {
 		logrus.WithError(err).Fatal("Error load OpenAI config.")
 	}
 
-	taskAgent, err := NewTaskAgent(o.openaiTasksFile, o.openaiTasksReloadInterval)
+	var providerAgent *ProviderAgent
+	if o.llmProvidersFile != "" {
+		providerAgent, err = NewProviderAgent(o.llmProvidersFile, o.openaiConfigReloadInterval)
+		if err != nil {
+			logrus.WithError(err).Fatal("Error load LLM providers config.")
+		}
+	}
+
+	taskAgent, err := NewTaskAgent(o.openaiTasksFile, o.openaiTasksReloadInterval, o.defaultDailyTokenQuota)
 	if err != nil {
 		logrus.WithError(err).Fatal("Failed to start task agent")
 	}
 
+	opener, err := o.storage.StorageClient(context.Background())
+	if err != nil {
+		logrus.WithError(err).Fatal("Cannot create opener")
+	}
+	tokenQuota, err := newTokenQuota(opener, o.quotaStatePath)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to load the token quota state.")
+	}
+	interrupts.TickLiteral(tokenQuota.Flush, quotaStateFlushInterval)
+	interrupts.OnInterrupt(tokenQuota.Flush)
+
 	issueCommentMatchRegex := regexp.MustCompile(fmt.Sprintf(`(?m)^/%s\s+(.+)$`, o.issueCommentCommand))
 	server := &Server{
 		ghc:                    githubClient,
@@ -128,10 +160,15 @@ func main() {
 		log:                    log,
 		openaiClientAgent:      openaiAgent,
 		openaiTaskAgent:        taskAgent,
+		providerAgent:          providerAgent,
+		tokenQuota:             tokenQuota,
 		maxDiffSize:            o.maxAcceptDiffSize,
+		maxChunkedDiffSize:     o.maxChunkedDiffSize,
 		tokenGenerator:         secret.GetTokenGenerator(o.webhookSecretFile),
 	}
 
+	metrics.ExposeMetrics("chatgpt", config.PushGateway{}, o.instrumentationOptions.MetricsPort)
+
 	health := pjutil.NewHealthOnPort(o.instrumentationOptions.HealthPort)
 	health.ServeReady()
 
@@ -20,13 +20,37 @@ var maxTokens = map[string]int{
 	openai.GPT432K0314:         32768,
 }
 
-// ref: https://github.com/pkoukk/tiktoken-go#counting-tokens-for-chat-api-calls
-func numTokensFromMessages(messages []openai.ChatCompletionMessage, model string) (int, error) {
+func encodingForModel(model string) (*tiktoken.Tiktoken, error) {
 	tkm, err := tiktoken.EncodingForModel(model)
 	if err != nil {
-		return 0, fmt.Errorf("EncodingForModel: %v", err)
+		// models out of OpenAI, estimate it with cl100k_base encoding.
+		tkm, err = tiktoken.GetEncoding(tiktoken.MODEL_CL100K_BASE)
+		if err != nil {
+			return nil, fmt.Errorf("GetEncoding: %v", err)
+		}
 	}
 
+	return tkm, nil
+}
+
+// tokenCounter returns a function to count tokens of text for the model, it
+// falls back to an estimation when the encoding is not available.
+func tokenCounter(model string) func(string) int {
+	tkm, err := encodingForModel(model)
+	if err != nil {
+		return func(text string) int { return len(text)/4 + 1 }
+	}
+
+	return func(text string) int { return len(tkm.Encode(text, nil, nil)) }
+}
+
+// ref: https://github.com/pkoukk/tiktoken-go#counting-tokens-for-chat-api-calls
+//
+// It counts the same way as tokenCounter, so the chunks and the follow-up
+// history sized by it fit in the requests checked by it.
+func numTokensFromMessages(messages []openai.ChatCompletionMessage, model string) int {
+	countTokens := tokenCounter(model)
+
 	var tokens_per_message int
 	var tokens_per_name int
 	switch model {
@@ -45,14 +69,14 @@ func numTokensFromMessages(messages []openai.ChatCompletionMessage, model string
 	num_tokens := 0
 	for _, message := range messages {
 		num_tokens += tokens_per_message
-		num_tokens += len(tkm.Encode(message.Content, nil, nil))
-		num_tokens += len(tkm.Encode(message.Role, nil, nil))
-		num_tokens += len(tkm.Encode(message.Name, nil, nil))
+		num_tokens += countTokens(message.Content)
+		num_tokens += countTokens(message.Role)
+		num_tokens += countTokens(message.Name)
 		if message.Name != "" {
 			num_tokens += tokens_per_name
 		}
 	}
 	num_tokens += 3
 
-	return num_tokens, nil
+	return num_tokens
 }
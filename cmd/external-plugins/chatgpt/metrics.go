@@ -0,0 +1,58 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Prometheus Metrics
+var (
+	chatgptMetrics = struct {
+		tokens          *prometheus.CounterVec
+		requestDuration *prometheus.HistogramVec
+		requestErrors   *prometheus.CounterVec
+		quotaExhausted  *prometheus.CounterVec
+	}{
+		tokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "chatgpt_tokens_total",
+			Help: "Count of tokens consumed by the AI tasks, by token type (prompt/completion).",
+		}, []string{
+			"org",
+			"repo",
+			"task",
+			"type",
+		}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "chatgpt_request_duration_seconds",
+			Help:    "Histogram of the latency of the requests to the AI server.",
+			Buckets: []float64{1, 2, 5, 10, 20, 30, 60, 120, 300},
+		}, []string{
+			"org",
+			"repo",
+			"task",
+			"model",
+		}),
+		requestErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "chatgpt_request_errors_total",
+			Help: "Count of the failed requests to the AI server.",
+		}, []string{
+			"org",
+			"repo",
+			"task",
+		}),
+		quotaExhausted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "chatgpt_quota_exhausted_total",
+			Help: "Count of the requests rejected since the daily token quota is exhausted.",
+		}, []string{
+			"org",
+			"repo",
+			"task",
+		}),
+	}
+)
+
+func init() {
+	prometheus.MustRegister(chatgptMetrics.tokens)
+	prometheus.MustRegister(chatgptMetrics.requestDuration)
+	prometheus.MustRegister(chatgptMetrics.requestErrors)
+	prometheus.MustRegister(chatgptMetrics.quotaExhausted)
+}
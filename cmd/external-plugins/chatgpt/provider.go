@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sirupsen/logrus"
+)
+
+// Supported provider types.
+const (
+	ProviderTypeOpenAI           = "openai"
+	ProviderTypeAzure            = "azure"
+	ProviderTypeAzureAD          = "azure_ad"
+	ProviderTypeOpenAICompatible = "openai_compatible"
+	ProviderTypeAnthropic        = "anthropic"
+
+	defaultRetryBackoff = time.Second
+)
+
+// LLMProvider is a chat completion backend that a task can be routed to.
+type LLMProvider interface {
+	// Model returns the model name used for chat completions.
+	Model() string
+	// MaxContextTokens returns the token limit of the model context, 0 means unknown.
+	MaxContextTokens() int
+	// MaxResponseTokens returns the token limit of the model response, 0 means no limit.
+	MaxResponseTokens() int
+	// ChatCompletion sends the messages to the backend and returns the answer.
+	ChatCompletion(ctx context.Context, req chatRequest) (*chatResponse, error)
+}
+
+type chatRequest struct {
+	Messages    []openai.ChatCompletionMessage
+	MaxTokens   int
+	Temperature float32
+}
+
+type chatResponse struct {
+	Content          string
+	Truncated        bool
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// ProvidersConfig represents the LLM providers that tasks can choose, keyed by provider name.
+type ProvidersConfig map[string]*ProviderConfig
+
+// ProviderConfig represents the config of a LLM provider.
+type ProviderConfig struct {
+	Type              string      `yaml:"type,omitempty" json:"type,omitempty"` // openai | azure | azure_ad | openai_compatible | anthropic
+	Token             string      `yaml:"token,omitempty" json:"token,omitempty"`
+	BaseURL           string      `yaml:"base_url,omitempty" json:"base_url,omitempty"`
+	OrgID             string      `yaml:"org_id,omitempty" json:"org_id,omitempty"`
+	APIVersion        string      `yaml:"api_version,omitempty" json:"api_version,omitempty"` // required for azure, optional for anthropic.
+	Engine            string      `yaml:"engine,omitempty" json:"engine,omitempty"`           // required for azure, it's the deploy instance name.
+	Model             string      `yaml:"model,omitempty" json:"model,omitempty"`
+	MaxContextTokens  int         `yaml:"max_context_tokens,omitempty" json:"max_context_tokens,omitempty"`   // defaults to the known limit of the model.
+	MaxResponseTokens int         `yaml:"max_response_tokens,omitempty" json:"max_response_tokens,omitempty"` // caps the `max_response_tokens` of the tasks.
+	Retry             RetryPolicy `yaml:"retry,omitempty" json:"retry,omitempty"`
+
+	provider LLMProvider
+}
+
+// RetryPolicy represents how to retry the failed requests to a provider.
+type RetryPolicy struct {
+	MaxAttempts int    `yaml:"max_attempts,omitempty" json:"max_attempts,omitempty"` // 0 or 1 means no retries.
+	Backoff     string `yaml:"backoff,omitempty" json:"backoff,omitempty"`           // initial backoff duration, doubled on every retry, defaults to 1s.
+
+	backoff time.Duration
+}
+
+func (cfg *ProviderConfig) initProvider() error {
+	if cfg.provider != nil {
+		return nil
+	}
+
+	if cfg.Retry.Backoff != "" {
+		backoff, err := time.ParseDuration(cfg.Retry.Backoff)
+		if err != nil {
+			return fmt.Errorf("invalid retry backoff %q: %w", cfg.Retry.Backoff, err)
+		}
+		cfg.Retry.backoff = backoff
+	}
+
+	var p LLMProvider
+	switch cfg.Type {
+	case ProviderTypeOpenAI, "":
+		openaiCfg := openai.DefaultConfig(cfg.Token)
+		if cfg.BaseURL != "" {
+			openaiCfg.BaseURL = cfg.BaseURL
+		}
+		openaiCfg.OrgID = cfg.OrgID
+		p = newOpenaiProvider(openai.NewClientWithConfig(openaiCfg), cfg)
+	case ProviderTypeOpenAICompatible:
+		if cfg.BaseURL == "" {
+			return errors.New("base_url is required for openai compatible provider")
+		}
+		openaiCfg := openai.DefaultConfig(cfg.Token)
+		openaiCfg.BaseURL = cfg.BaseURL
+		p = newOpenaiProvider(openai.NewClientWithConfig(openaiCfg), cfg)
+	case ProviderTypeAzure, ProviderTypeAzureAD:
+		if cfg.BaseURL == "" || cfg.Engine == "" {
+			return errors.New("base_url and engine are required for azure provider")
+		}
+		openaiCfg := openai.DefaultAzureConfig(cfg.Token, cfg.BaseURL, cfg.Engine)
+		if cfg.Type == ProviderTypeAzureAD {
+			openaiCfg.APIType = openai.APITypeAzureAD
+		}
+		if cfg.APIVersion != "" {
+			openaiCfg.APIVersion = cfg.APIVersion
+		}
+		p = newOpenaiProvider(openai.NewClientWithConfig(openaiCfg), cfg)
+	case ProviderTypeAnthropic:
+		p = newAnthropicProvider(http.DefaultClient, cfg)
+	default:
+		return fmt.Errorf("unsupported provider type %q", cfg.Type)
+	}
+
+	if cfg.Retry.MaxAttempts > 1 {
+		p = &retryProvider{LLMProvider: p, policy: cfg.Retry}
+	}
+	cfg.provider = p
+
+	return nil
+}
+
+// providerLimits implements the limit parts of LLMProvider from the config.
+type providerLimits struct {
+	model             string
+	maxContextTokens  int
+	maxResponseTokens int
+}
+
+func newProviderLimits(cfg *ProviderConfig) providerLimits {
+	ret := providerLimits{
+		model:             cfg.Model,
+		maxContextTokens:  cfg.MaxContextTokens,
+		maxResponseTokens: cfg.MaxResponseTokens,
+	}
+	if ret.maxContextTokens == 0 {
+		ret.maxContextTokens = maxTokens[cfg.Model]
+	}
+
+	return ret
+}
+
+func (l providerLimits) Model() string          { return l.model }
+func (l providerLimits) MaxContextTokens() int  { return l.maxContextTokens }
+func (l providerLimits) MaxResponseTokens() int { return l.maxResponseTokens }
+
+// statusError is returned by providers when the server responds with a non 2xx status.
+type statusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("status code %d: %s", e.StatusCode, e.Body)
+}
+
+// retryProvider retries the failed chat completions with exponential backoff.
+type retryProvider struct {
+	LLMProvider
+	policy RetryPolicy
+}
+
+func (p *retryProvider) ChatCompletion(ctx context.Context, req chatRequest) (*chatResponse, error) {
+	backoff := p.policy.backoff
+	if backoff == 0 {
+		backoff = defaultRetryBackoff
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		var resp *chatResponse
+		resp, err = p.LLMProvider.ChatCompletion(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		if attempt >= p.policy.MaxAttempts || !isRetryable(err) {
+			break
+		}
+
+		logrus.WithError(err).WithField("attempt", attempt).Debugf("chat completion failed, retry after %s", backoff)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+
+	return nil, err
+}
+
+// isRetryable returns whether the error is caused by rate limiting or a
+// transient server side failure.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	var stErr *statusError
+	var code int
+	switch {
+	// RequestError may wrap an APIError without status code, check it first.
+	case errors.As(err, &reqErr):
+		code = reqErr.HTTPStatusCode
+	case errors.As(err, &apiErr):
+		code = apiErr.HTTPStatusCode
+	case errors.As(err, &stErr):
+		code = stErr.StatusCode
+	default:
+		// network errors.
+		return true
+	}
+
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+// ProviderAgent agent for LLM providers with watching and hot reload.
+type ProviderAgent struct {
+	ConfigAgent[ProvidersConfig]
+}
+
+// NewProviderAgent returns a new provider loader.
+func NewProviderAgent(path string, watchInterval time.Duration) (*ProviderAgent, error) {
+	c := &ProviderAgent{ConfigAgent: ConfigAgent[ProvidersConfig]{path: path}}
+	if err := c.Reload(path); err != nil {
+		return nil, err
+	}
+
+	go c.WatchConfig(context.Background(), watchInterval, c.Reload)
+
+	return c, nil
+}
+
+func (a *ProviderAgent) Reload(file string) error {
+	return a.ConfigAgent.Reload(file, func() error {
+		for name, cfg := range a.config {
+			if err := cfg.initProvider(); err != nil {
+				return fmt.Errorf("provider %s: %w", name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Provider returns the provider with the given name.
+func (a *ProviderAgent) Provider(name string) (LLMProvider, error) {
+	cfg := a.Data()[name]
+	if cfg == nil || cfg.provider == nil {
+		return nil, fmt.Errorf("provider %q not found", name)
+	}
+
+	return cfg.provider, nil
+}
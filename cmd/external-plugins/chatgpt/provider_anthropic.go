@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	anthropicDefaultBaseURL    = "https://api.anthropic.com"
+	anthropicDefaultAPIVersion = "2023-06-01"
+	anthropicStopMaxTokens     = "max_tokens"
+	// anthropicDefaultMaxTokens is sent when the task doesn't limit the
+	// completion, as the messages API requires a positive max_tokens.
+	anthropicDefaultMaxTokens = 4096
+)
+
+// anthropicProvider serves chat completions with the Anthropic style messages API.
+type anthropicProvider struct {
+	providerLimits
+	client     *http.Client
+	baseURL    string
+	token      string
+	apiVersion string
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	MaxTokens   int                `json:"max_tokens"`
+	System      string             `json:"system,omitempty"`
+	Temperature float32            `json:"temperature,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+}
+
+type anthropicResponse struct {
+	Model   string `json:"model"`
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+func newAnthropicProvider(client *http.Client, cfg *ProviderConfig) *anthropicProvider {
+	p := &anthropicProvider{
+		providerLimits: newProviderLimits(cfg),
+		client:         client,
+		baseURL:        strings.TrimRight(cfg.BaseURL, "/"),
+		token:          cfg.Token,
+		apiVersion:     cfg.APIVersion,
+	}
+	if p.baseURL == "" {
+		p.baseURL = anthropicDefaultBaseURL
+	}
+	if p.apiVersion == "" {
+		p.apiVersion = anthropicDefaultAPIVersion
+	}
+
+	return p
+}
+
+func (p *anthropicProvider) ChatCompletion(ctx context.Context, req chatRequest) (*chatResponse, error) {
+	// the messages API does not accept system role messages, they are sent by the `system` field.
+	payload := anthropicRequest{Model: p.model, MaxTokens: req.MaxTokens, Temperature: req.Temperature}
+	if payload.MaxTokens <= 0 {
+		payload.MaxTokens = anthropicDefaultMaxTokens
+	}
+	var systems []string
+	for _, m := range req.Messages {
+		if m.Role == openai.ChatMessageRoleSystem {
+			systems = append(systems, m.Content)
+			continue
+		}
+		payload.Messages = append(payload.Messages, anthropicMessage{Role: m.Role, Content: m.Content})
+	}
+	payload.System = strings.Join(systems, "\n")
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", p.token)
+	httpReq.Header.Set("anthropic-version", p.apiVersion)
+
+	httpResp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("messages API error: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		return nil, fmt.Errorf("messages API error: %w", &statusError{StatusCode: httpResp.StatusCode, Body: string(respBody)})
+	}
+
+	var resp anthropicResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, fmt.Errorf("could not unmarshal messages API response: %w", err)
+	}
+
+	var texts []string
+	for _, c := range resp.Content {
+		if c.Type == "text" {
+			texts = append(texts, c.Text)
+		}
+	}
+
+	return &chatResponse{
+		Content:          strings.Join(texts, ""),
+		Truncated:        resp.StopReason == anthropicStopMaxTokens,
+		Model:            resp.Model,
+		PromptTokens:     resp.Usage.InputTokens,
+		CompletionTokens: resp.Usage.OutputTokens,
+	}, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// openaiProvider serves chat completions with OpenAI, Azure OpenAI and the
+// OpenAI compatible APIs.
+type openaiProvider struct {
+	providerLimits
+	client *openai.Client
+}
+
+func newOpenaiProvider(client *openai.Client, cfg *ProviderConfig) *openaiProvider {
+	return &openaiProvider{providerLimits: newProviderLimits(cfg), client: client}
+}
+
+func (p *openaiProvider) ChatCompletion(ctx context.Context, req chatRequest) (*chatResponse, error) {
+	resp, err := p.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:       p.model,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		Messages:    req.Messages,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ChatCompletion error: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("ChatCompletion error: no choices in response")
+	}
+
+	return &chatResponse{
+		Content:          resp.Choices[0].Message.Content,
+		Truncated:        resp.Choices[0].FinishReason == "length",
+		Model:            resp.Model,
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+	}, nil
+}
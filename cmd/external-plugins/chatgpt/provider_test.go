@@ -0,0 +1,251 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sashabaranov/go-openai"
+)
+
+var testChatRequest = chatRequest{
+	Messages: []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: "You are a reviewer."},
+		{Role: openai.ChatMessageRoleUser, Content: "Review it."},
+	},
+	MaxTokens:   100,
+	Temperature: 0.5,
+}
+
+// fakeServer responds with the given status codes in order, then the body.
+func fakeServer(t *testing.T, wantPath string, statuses []int, body string, check func(r *http.Request)) (*httptest.Server, *int) {
+	calls := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path != wantPath {
+			t.Errorf("unexpected request path %q, want %q", r.URL.Path, wantPath)
+		}
+		if check != nil {
+			check(r)
+		}
+		if calls <= len(statuses) {
+			w.WriteHeader(statuses[calls-1])
+			w.Write([]byte(`{"error": {"message": "fake error"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(s.Close)
+
+	return s, &calls
+}
+
+const fakeOpenaiResponse = `{
+  "model": "fake-model",
+  "choices": [{"message": {"role": "assistant", "content": "LGTM"}, "finish_reason": "length"}],
+  "usage": {"prompt_tokens": 10, "completion_tokens": 2, "total_tokens": 12}
+}`
+
+const fakeAnthropicResponse = `{
+  "model": "fake-claude",
+  "content": [{"type": "text", "text": "LG"}, {"type": "text", "text": "TM"}],
+  "stop_reason": "end_turn",
+  "usage": {"input_tokens": 10, "output_tokens": 2}
+}`
+
+func TestProviders(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       func(url string) *ProviderConfig
+		path      string
+		body      string
+		statuses  []int
+		check     func(t *testing.T, r *http.Request)
+		want      *chatResponse
+		wantErr   bool
+		wantCalls int
+	}{
+		{
+			name: "openai compatible",
+			cfg: func(url string) *ProviderConfig {
+				return &ProviderConfig{Type: ProviderTypeOpenAICompatible, BaseURL: url + "/v1", Model: "fake-model"}
+			},
+			path: "/v1/chat/completions",
+			body: fakeOpenaiResponse,
+			check: func(t *testing.T, r *http.Request) {
+				var req openai.ChatCompletionRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Fatal(err)
+				}
+				if req.Model != "fake-model" || req.MaxTokens != 100 || len(req.Messages) != 2 {
+					t.Errorf("unexpected request: %+v", req)
+				}
+			},
+			want:      &chatResponse{Content: "LGTM", Truncated: true, Model: "fake-model", PromptTokens: 10, CompletionTokens: 2},
+			wantCalls: 1,
+		},
+		{
+			name: "azure",
+			cfg: func(url string) *ProviderConfig {
+				return &ProviderConfig{Type: ProviderTypeAzure, BaseURL: url, Engine: "deploy1", Token: "secret", Model: "gpt-4"}
+			},
+			path: "/openai/deployments/deploy1/chat/completions",
+			body: fakeOpenaiResponse,
+			check: func(t *testing.T, r *http.Request) {
+				if got := r.Header.Get(openai.AzureAPIKeyHeader); got != "secret" {
+					t.Errorf("unexpected api key header %q", got)
+				}
+			},
+			want:      &chatResponse{Content: "LGTM", Truncated: true, Model: "fake-model", PromptTokens: 10, CompletionTokens: 2},
+			wantCalls: 1,
+		},
+		{
+			name: "anthropic",
+			cfg: func(url string) *ProviderConfig {
+				return &ProviderConfig{Type: ProviderTypeAnthropic, BaseURL: url, Token: "secret", Model: "fake-claude"}
+			},
+			path: "/v1/messages",
+			body: fakeAnthropicResponse,
+			check: func(t *testing.T, r *http.Request) {
+				if got := r.Header.Get("x-api-key"); got != "secret" {
+					t.Errorf("unexpected api key header %q", got)
+				}
+				var req anthropicRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Fatal(err)
+				}
+				want := anthropicRequest{
+					Model:       "fake-claude",
+					MaxTokens:   100,
+					System:      "You are a reviewer.",
+					Temperature: 0.5,
+					Messages:    []anthropicMessage{{Role: openai.ChatMessageRoleUser, Content: "Review it."}},
+				}
+				if diff := cmp.Diff(want, req); diff != "" {
+					t.Errorf("unexpected request (-want +got):\n%s", diff)
+				}
+			},
+			want:      &chatResponse{Content: "LGTM", Model: "fake-claude", PromptTokens: 10, CompletionTokens: 2},
+			wantCalls: 1,
+		},
+		{
+			name: "retry on server errors",
+			cfg: func(url string) *ProviderConfig {
+				return &ProviderConfig{Type: ProviderTypeAnthropic, BaseURL: url, Model: "fake-claude",
+					Retry: RetryPolicy{MaxAttempts: 3, Backoff: "1ms"}}
+			},
+			path:      "/v1/messages",
+			body:      fakeAnthropicResponse,
+			statuses:  []int{http.StatusServiceUnavailable, http.StatusTooManyRequests},
+			want:      &chatResponse{Content: "LGTM", Model: "fake-claude", PromptTokens: 10, CompletionTokens: 2},
+			wantCalls: 3,
+		},
+		{
+			name: "retry exhausted",
+			cfg: func(url string) *ProviderConfig {
+				return &ProviderConfig{Type: ProviderTypeOpenAICompatible, BaseURL: url + "/v1", Model: "fake-model",
+					Retry: RetryPolicy{MaxAttempts: 2, Backoff: "1ms"}}
+			},
+			path:      "/v1/chat/completions",
+			body:      fakeOpenaiResponse,
+			statuses:  []int{http.StatusInternalServerError, http.StatusInternalServerError},
+			wantErr:   true,
+			wantCalls: 2,
+		},
+		{
+			name: "no retry on client errors",
+			cfg: func(url string) *ProviderConfig {
+				return &ProviderConfig{Type: ProviderTypeOpenAICompatible, BaseURL: url + "/v1", Model: "fake-model",
+					Retry: RetryPolicy{MaxAttempts: 3, Backoff: "1ms"}}
+			},
+			path:      "/v1/chat/completions",
+			body:      fakeOpenaiResponse,
+			statuses:  []int{http.StatusBadRequest},
+			wantErr:   true,
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var check func(r *http.Request)
+			if tt.check != nil {
+				check = func(r *http.Request) { tt.check(t, r) }
+			}
+			s, calls := fakeServer(t, tt.path, tt.statuses, tt.body, check)
+
+			cfg := tt.cfg(s.URL)
+			if err := cfg.initProvider(); err != nil {
+				t.Fatalf("initProvider() error = %v", err)
+			}
+
+			got, err := cfg.provider.ChatCompletion(context.Background(), testChatRequest)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ChatCompletion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ChatCompletion() mismatch (-want +got):\n%s", diff)
+			}
+			if *calls != tt.wantCalls {
+				t.Errorf("got %d calls, want %d", *calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestAnthropicDefaultMaxTokens(t *testing.T) {
+	var got int
+	s, _ := fakeServer(t, "/v1/messages", nil, fakeAnthropicResponse, func(r *http.Request) {
+		var req anthropicRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		got = req.MaxTokens
+	})
+	cfg := &ProviderConfig{Type: ProviderTypeAnthropic, BaseURL: s.URL, Model: "fake-claude"}
+	if err := cfg.initProvider(); err != nil {
+		t.Fatalf("initProvider() error = %v", err)
+	}
+
+	req := testChatRequest
+	req.MaxTokens = 0
+	if _, err := cfg.provider.ChatCompletion(context.Background(), req); err != nil {
+		t.Fatalf("ChatCompletion() error = %v", err)
+	}
+	if got != anthropicDefaultMaxTokens {
+		t.Errorf("got max_tokens %d, want %d", got, anthropicDefaultMaxTokens)
+	}
+}
+
+func TestProviderConfigInvalid(t *testing.T) {
+	for _, cfg := range []*ProviderConfig{
+		{Type: "unknown"},
+		{Type: ProviderTypeAzure, BaseURL: "https://azure.example.com"},
+		{Type: ProviderTypeOpenAICompatible},
+		{Type: ProviderTypeOpenAI, Retry: RetryPolicy{Backoff: "forever"}},
+	} {
+		if err := cfg.initProvider(); err == nil {
+			t.Errorf("expected error for config %+v", cfg)
+		}
+	}
+}
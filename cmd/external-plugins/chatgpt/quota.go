@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	stdio "io"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/prow/pkg/io"
+)
+
+const (
+	quotaDayLayout          = "2006-01-02"
+	quotaExhaustedMessage   = "Sorry, the daily token quota of this task is exhausted, I will be back tomorrow. Please ask the maintainers if you need a larger quota."
+	quotaStateFlushDuration = 30 * time.Second
+	quotaStateFlushInterval = 10 * time.Second
+)
+
+// errQuotaExhausted is returned when the daily token quota of the task is exhausted.
+var errQuotaExhausted = errors.New("daily token quota exhausted")
+
+// usageKey identifies the task run in a repository for token accounting.
+type usageKey struct {
+	org  string
+	repo string
+	task string
+}
+
+func (k usageKey) String() string {
+	return fmt.Sprintf("%s/%s/%s", k.org, k.repo, k.task)
+}
+
+// quotaUsage is the tokens used in a day (UTC).
+type quotaUsage struct {
+	Day    string `json:"day"`
+	Tokens int    `json:"tokens"`
+}
+
+// opener has methods to read and write paths
+type opener interface {
+	Reader(ctx context.Context, path string) (io.ReadCloser, error)
+	Writer(ctx context.Context, path string, opts ...io.WriterOptions) (io.WriteCloser, error)
+}
+
+// tokenQuota accounts the daily token usages, the usages are persisted to the
+// path when it is given, so that they survive restarts.
+type tokenQuota struct {
+	sync.Mutex
+	usages map[string]*quotaUsage
+	// dirty marks the usages changed since the last flush.
+	dirty bool
+	// flushMu keeps the writes in order.
+	flushMu sync.Mutex
+
+	opener opener
+	path   string
+	now    func() time.Time
+}
+
+// newTokenQuota creates the quota accounting, loading the usages from the path if given.
+func newTokenQuota(opener opener, path string) (*tokenQuota, error) {
+	q := &tokenQuota{
+		usages: map[string]*quotaUsage{},
+		opener: opener,
+		path:   path,
+		now:    time.Now,
+	}
+	if path == "" {
+		return q, nil
+	}
+
+	reader, err := opener.Reader(context.Background(), path)
+	if io.IsNotExist(err) { // No state exists yet. This is not an error.
+		return q, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	defer io.LogClose(reader)
+	raw, err := stdio.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	if err := json.Unmarshal(raw, &q.usages); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	return q, nil
+}
+
+func (q *tokenQuota) today() string {
+	return q.now().UTC().Format(quotaDayLayout)
+}
+
+// Used returns the tokens used today.
+func (q *tokenQuota) Used(key usageKey) int {
+	q.Lock()
+	defer q.Unlock()
+
+	u := q.usages[key.String()]
+	if u == nil || u.Day != q.today() {
+		return 0
+	}
+	return u.Tokens
+}
+
+// Exhausted returns whether the tokens used today reach the limit, 0 means no limit.
+func (q *tokenQuota) Exhausted(key usageKey, limit int) bool {
+	return limit > 0 && q.Used(key) >= limit
+}
+
+// Add accounts the tokens to the usage of today, the usages are persisted by
+// the next Flush.
+func (q *tokenQuota) Add(key usageKey, tokens int) {
+	q.Lock()
+	defer q.Unlock()
+
+	today := q.today()
+	u := q.usages[key.String()]
+	if u == nil || u.Day != today {
+		u = &quotaUsage{Day: today}
+		q.usages[key.String()] = u
+	}
+	u.Tokens += tokens
+	q.dirty = true
+}
+
+// Flush persists the usages if they changed since the last flush.
+func (q *tokenQuota) Flush() {
+	if q.path == "" {
+		return
+	}
+	q.flushMu.Lock()
+	defer q.flushMu.Unlock()
+
+	q.Lock()
+	if !q.dirty {
+		q.Unlock()
+		return
+	}
+	raw, err := json.Marshal(q.usages)
+	q.dirty = false
+	q.Unlock()
+
+	if err == nil {
+		err = q.write(raw)
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("path", q.path).Error("Error flushing token quota state.")
+		q.Lock()
+		q.dirty = true
+		q.Unlock()
+	}
+}
+
+func (q *tokenQuota) write(raw []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), quotaStateFlushDuration)
+	defer cancel()
+	writer, err := q.opener.Writer(ctx, q.path)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	if _, err := writer.Write(raw); err != nil {
+		io.LogClose(writer)
+		return fmt.Errorf("write: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+	return nil
+}
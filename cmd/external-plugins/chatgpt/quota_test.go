@@ -0,0 +1,91 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/prow/pkg/io/fakeopener"
+)
+
+func TestTokenQuota(t *testing.T) {
+	const path = "gs://bucket/chatgpt/quota.json"
+	opener := &fakeopener.FakeOpener{}
+	now := time.Date(2023, 5, 1, 23, 0, 0, 0, time.UTC)
+	key := usageKey{org: "org", repo: "repo", task: "review"}
+	otherKey := usageKey{org: "org", repo: "other", task: "review"}
+
+	q, err := newTokenQuota(opener, path)
+	if err != nil {
+		t.Fatalf("newTokenQuota() error = %v", err)
+	}
+	q.now = func() time.Time { return now }
+
+	q.Add(key, 600)
+	q.Add(key, 500)
+	q.Add(otherKey, 10)
+	if got := q.Used(key); got != 1100 {
+		t.Errorf("Used() = %d, want 1100", got)
+	}
+	if !q.Exhausted(key, 1000) {
+		t.Error("expected the quota to be exhausted")
+	}
+	if q.Exhausted(key, 0) {
+		t.Error("expected no limit for zero quota")
+	}
+	if q.Exhausted(otherKey, 1000) {
+		t.Error("expected the quota of other repo not to be exhausted")
+	}
+
+	// the usages survive restarts once flushed.
+	if _, err := opener.Reader(context.Background(), path); err == nil {
+		t.Error("expected the usages not to be written before the flush")
+	}
+	q.Flush()
+	restored, err := newTokenQuota(opener, path)
+	if err != nil {
+		t.Fatalf("newTokenQuota() error = %v", err)
+	}
+	restored.now = func() time.Time { return now }
+	if got := restored.Used(key); got != 1100 {
+		t.Errorf("Used() after restart = %d, want 1100", got)
+	}
+
+	// the quota is reset in the next day.
+	restored.now = func() time.Time { return now.Add(2 * time.Hour) }
+	if restored.Exhausted(key, 1000) {
+		t.Error("expected the quota to be reset in the next day")
+	}
+	restored.Add(key, 1)
+	if got := restored.Used(key); got != 1 {
+		t.Errorf("Used() in the next day = %d, want 1", got)
+	}
+}
+
+func TestTokenQuotaInMemory(t *testing.T) {
+	q, err := newTokenQuota(nil, "")
+	if err != nil {
+		t.Fatalf("newTokenQuota() error = %v", err)
+	}
+	key := usageKey{org: "org", repo: "repo", task: "review"}
+	q.Add(key, 10)
+	if got := q.Used(key); got != 10 {
+		t.Errorf("Used() = %d, want 10", got)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/prow/pkg/github"
+)
+
+const (
+	inlineReviewOutputPrompt = `Answer ONLY with a JSON object in the following format, without any other text:
+{
+  "summary": "<overall review of the pull request in markdown format>",
+  "findings": [
+    {
+      "file": "<file path in the diff>",
+      "start_line": <first line of the problem in the new version of the file>,
+      "end_line": <last line of the problem in the new version of the file>,
+      "severity": "<one of: critical, major, minor, nit>",
+      "comment": "<description of the problem in markdown format>",
+      "suggestion": "<optional: the code that should replace the lines from start_line to end_line>"
+    }
+  ]
+}`
+	inlineReviewUnmappedTitle = "#### Findings out of the diff"
+)
+
+// Matches the hunk line in unified diffs, such as: @@ -l,s +l,s @@ section head
+var diffHunkRe = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// reviewFinding is a structured finding answered by the AI server.
+type reviewFinding struct {
+	File       string `json:"file"`
+	StartLine  int    `json:"start_line"`
+	EndLine    int    `json:"end_line"`
+	Severity   string `json:"severity"`
+	Comment    string `json:"comment"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// reviewResult is the structured review answered by the AI server.
+type reviewResult struct {
+	Summary  string          `json:"summary"`
+	Findings []reviewFinding `json:"findings"`
+}
+
+// diffHunk is the line range [start, end] of a hunk in the new version of a file.
+type diffHunk struct {
+	start int
+	end   int
+}
+
+// parseReviewResult extracts the structured review from the AI response, the
+// JSON object may be wrapped in a markdown code block.
+func parseReviewResult(resp string) (*reviewResult, error) {
+	start := strings.Index(resp, "{")
+	end := strings.LastIndex(resp, "}")
+	if start < 0 || end < start {
+		return nil, errors.New("no JSON object found in the response")
+	}
+
+	var ret reviewResult
+	if err := json.Unmarshal([]byte(resp[start:end+1]), &ret); err != nil {
+		return nil, fmt.Errorf("could not unmarshal review result: %w", err)
+	}
+
+	return &ret, nil
+}
+
+// parseDiffHunks returns the hunks of every file in the diff keyed by the file
+// path in the new version. Deleted files are not included.
+func parseDiffHunks(diff string) map[string][]diffHunk {
+	ret := make(map[string][]diffHunk)
+
+	var file string
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			file = ""
+		case strings.HasPrefix(line, "+++ "):
+			file = strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/")
+			if file == "/dev/null" {
+				file = ""
+			}
+		case file != "" && strings.HasPrefix(line, "@@"):
+			matches := diffHunkRe.FindStringSubmatch(line)
+			if matches == nil {
+				continue
+			}
+			start, _ := strconv.Atoi(matches[1])
+			length := 1
+			if matches[2] != "" {
+				length, _ = strconv.Atoi(matches[2])
+			}
+			if length == 0 {
+				continue
+			}
+			ret[file] = append(ret[file], diffHunk{start: start, end: start + length - 1})
+		}
+	}
+
+	return ret
+}
+
+// locate returns the hunk containing the whole line range of the finding.
+func (f *reviewFinding) locate(hunks map[string][]diffHunk) (diffHunk, bool) {
+	for _, h := range hunks[f.File] {
+		if f.StartLine >= h.start && f.EndLine <= h.end {
+			return h, true
+		}
+	}
+
+	return diffHunk{}, false
+}
+
+func (f *reviewFinding) body() string {
+	var b strings.Builder
+	if f.Severity != "" {
+		fmt.Fprintf(&b, "**%s**: ", strings.ToLower(f.Severity))
+	}
+	b.WriteString(strings.TrimSpace(f.Comment))
+	if f.Suggestion != "" {
+		fmt.Fprintf(&b, "\n\n```suggestion\n%s\n```", strings.TrimRight(f.Suggestion, "\n"))
+	}
+
+	return b.String()
+}
+
+// buildInlineReview composes the pull request review from the structured
+// result. Findings anchored on the changed hunks are posted as line comments,
+// the others are folded into the review summary.
+func buildInlineReview(result *reviewResult, hunks map[string][]diffHunk) github.DraftReview {
+	var comments []github.DraftReviewComment
+	var unmapped []string
+	for _, f := range result.Findings {
+		if f.EndLine < f.StartLine {
+			f.EndLine = f.StartLine
+		}
+
+		if _, ok := f.locate(hunks); !ok || f.StartLine <= 0 {
+			unmapped = append(unmapped, fmt.Sprintf("- `%s#L%d-L%d` %s", f.File, f.StartLine, f.EndLine, f.body()))
+			continue
+		}
+
+		comment := github.DraftReviewComment{
+			Path: f.File,
+			Line: f.EndLine,
+			Side: github.DiffSideRight,
+			Body: f.body(),
+		}
+		if f.StartLine < f.EndLine {
+			comment.StartLine = f.StartLine
+			comment.StartSide = github.DiffSideRight
+		}
+		comments = append(comments, comment)
+	}
+
+	body := result.Summary
+	if len(unmapped) != 0 {
+		body = strings.Join(append([]string{body, "", inlineReviewUnmappedTitle}, unmapped...), "\n")
+	}
+
+	return github.DraftReview{
+		Body:     body,
+		Action:   github.Comment,
+		Comments: comments,
+	}
+}
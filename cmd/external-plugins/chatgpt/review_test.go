@@ -0,0 +1,139 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"sigs.k8s.io/prow/pkg/github"
+)
+
+const testReviewDiff = `diff --git a/foo.go b/foo.go
+index 65d1c11..e976b8a 100644
+--- a/foo.go
++++ b/foo.go
+@@ -10,6 +10,8 @@ import (
+ 	"fmt"
+ 	"os"
++	"strings"
++	"time"
+ )
+ 
+ func main() {
+@@ -40,3 +42,4 @@ func run() {
+ 	a := 1
++	b := 2
+ }
+diff --git a/removed.go b/removed.go
+deleted file mode 100644
+--- a/removed.go
++++ /dev/null
+@@ -1,2 +0,0 @@
+-package main
+-
+`
+
+func Test_parseDiffHunks(t *testing.T) {
+	want := map[string][]diffHunk{
+		"foo.go": {{start: 10, end: 17}, {start: 42, end: 45}},
+	}
+	if diff := cmp.Diff(want, parseDiffHunks(testReviewDiff), cmp.AllowUnexported(diffHunk{})); diff != "" {
+		t.Errorf("parseDiffHunks() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_parseReviewResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		resp    string
+		want    *reviewResult
+		wantErr bool
+	}{
+		{
+			name: "plain json",
+			resp: `{"summary": "LGTM", "findings": [{"file": "foo.go", "start_line": 12, "end_line": 13, "severity": "minor", "comment": "unused"}]}`,
+			want: &reviewResult{
+				Summary:  "LGTM",
+				Findings: []reviewFinding{{File: "foo.go", StartLine: 12, EndLine: 13, Severity: "minor", Comment: "unused"}},
+			},
+		},
+		{
+			name: "wrapped in code block",
+			resp: "```json\n{\"summary\": \"LGTM\", \"findings\": []}\n```",
+			want: &reviewResult{Summary: "LGTM", Findings: []reviewFinding{}},
+		},
+		{
+			name:    "not json",
+			resp:    "Looks good to me.",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseReviewResult(tt.resp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseReviewResult() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("parseReviewResult() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_buildInlineReview(t *testing.T) {
+	result := &reviewResult{
+		Summary: "Some problems found.",
+		Findings: []reviewFinding{
+			{File: "foo.go", StartLine: 12, EndLine: 13, Severity: "Minor", Comment: "unused imports", Suggestion: ""},
+			{File: "foo.go", StartLine: 43, Severity: "nit", Comment: "rename it", Suggestion: "\tcount := 2\n"},
+			{File: "foo.go", StartLine: 16, EndLine: 42, Severity: "major", Comment: "cross hunks"},
+			{File: "bar.go", StartLine: 1, EndLine: 1, Comment: "not in diff"},
+		},
+	}
+
+	want := github.DraftReview{
+		Body: "Some problems found.\n\n" + inlineReviewUnmappedTitle + "\n" +
+			"- `foo.go#L16-L42` **major**: cross hunks\n" +
+			"- `bar.go#L1-L1` not in diff",
+		Action: github.Comment,
+		Comments: []github.DraftReviewComment{
+			{
+				Path:      "foo.go",
+				Body:      "**minor**: unused imports",
+				Line:      13,
+				Side:      github.DiffSideRight,
+				StartLine: 12,
+				StartSide: github.DiffSideRight,
+			},
+			{
+				Path: "foo.go",
+				Body: "**nit**: rename it\n\n```suggestion\n\tcount := 2\n```",
+				Line: 43,
+				Side: github.DiffSideRight,
+			},
+		},
+	}
+
+	got := buildInlineReview(result, parseDiffHunks(testReviewDiff))
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("buildInlineReview() mismatch (-want +got):\n%s", diff)
+	}
+}
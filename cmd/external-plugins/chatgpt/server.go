@@ -19,6 +19,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"regexp"
@@ -36,9 +37,12 @@ import (
 )
 
 const (
-	pluginName                  = "chatgpt"
-	gitHostBaseURL              = "https://github.com"
-	defaultIssueReviewWorld     = "default"
+	pluginName              = "chatgpt"
+	gitHostBaseURL          = "https://github.com"
+	defaultIssueReviewWorld = "default"
+	// userCommentTask is the name the tasks triggered by comments, including
+	// the follow-ups, are accounted with in the token quotas.
+	userCommentTask             = "user-comment-trigger"
 	splitorHoldingByteCount     = 200
 	splitInstructionMessageText = `The total length of the content that I want to send you is too large to send in only one piece.
 
@@ -59,6 +63,9 @@ type githubClient interface {
 	CreateReview(org, repo string, number int, r github.DraftReview) error
 	GetPullRequest(org, repo string, number int) (*github.PullRequest, error)
 	GetPullRequestDiff(org, repo string, number int) ([]byte, error)
+	ListIssueComments(org, repo string, number int) ([]github.IssueComment, error)
+	ListReviews(org, repo string, number int) ([]github.Review, error)
+	BotUserChecker() (func(candidate string) bool, error)
 }
 
 // HelpProvider construct the pluginhelp.PluginHelp for this plugin.
@@ -78,6 +85,15 @@ func HelpProviderFactory(command string) func(_ []config.OrgRepo) (*pluginhelp.P
 				fmt.Sprintf("/%s do you have any suggestions about this PR?", command),
 			},
 		})
+		pluginHelp.AddCommand(pluginhelp.Command{
+			Usage:       fmt.Sprintf("/%s %s <your question>", command, followupForeword),
+			Description: "ask chatgpt a follow-up question with the previous answers in the PR as context. Replying to an answer by quoting it works too.",
+			Featured:    false,
+			WhoCanUse:   "Anyone",
+			Examples: []string{
+				fmt.Sprintf("/%s %s why is line 40 a problem?", command, followupForeword),
+			},
+		})
 		return pluginHelp, nil
 	}
 }
@@ -89,9 +105,12 @@ type Server struct {
 
 	openaiClientAgent *OpenaiWrapAgent
 	openaiTaskAgent   *TaskAgent
+	providerAgent     *ProviderAgent
+	tokenQuota        *tokenQuota
 
 	issueCommentMatchRegex *regexp.Regexp
 	maxDiffSize            int
+	maxChunkedDiffSize     int
 
 	ghc githubClient
 	log *logrus.Entry
@@ -177,9 +196,10 @@ func (s *Server) handleIssueComment(l *logrus.Entry, ic github.IssueCommentEvent
 		return nil
 	}
 
-	// Ignore comments that are not commands
-	commentMatches := s.issueCommentMatchRegex.FindAllStringSubmatch(ic.Comment.Body, -1)
-	if len(commentMatches) == 0 || len(commentMatches[0]) != 2 {
+	// Ignore comments that are not commands or replies by quoting.
+	foreword, isCommand := s.commandForeword(ic.Comment.Body)
+	quoted, reply, isReply := splitQuoteReply(ic.Comment.Body)
+	if !isCommand && !isReply {
 		return nil
 	}
 
@@ -199,15 +219,30 @@ func (s *Server) handleIssueComment(l *logrus.Entry, ic github.IssueCommentEvent
 		return err
 	}
 
+	if !isCommand {
+		return s.handleFollowup(l, pr, &ic.Comment, reply, quoted)
+	}
+
 	if pr.Mergable != nil && !*pr.Mergable {
 		return s.createComment(l, org, repo, num, &ic.Comment, "I Skip the comment since it is not mergable.")
 	}
-
-	foreword := commentMatches[0][1]
+	if question, ok := followupQuestion(foreword); ok {
+		return s.handleFollowup(l, pr, &ic.Comment, question, "")
+	}
 
 	return s.handle(l, pr, &ic.Comment, foreword)
 }
 
+// commandForeword returns the words following the command in the comment.
+func (s *Server) commandForeword(body string) (string, bool) {
+	commentMatches := s.issueCommentMatchRegex.FindAllStringSubmatch(body, -1)
+	if len(commentMatches) == 0 || len(commentMatches[0]) != 2 {
+		return "", false
+	}
+
+	return commentMatches[0][1], true
+}
+
 func (s *Server) handle(logger *logrus.Entry, pr *github.PullRequest, comment *github.IssueComment, foreword string) error {
 	org := pr.Base.Repo.Owner.Login
 	repo := pr.Base.Repo.Name
@@ -218,11 +253,6 @@ func (s *Server) handle(logger *logrus.Entry, pr *github.PullRequest, comment *g
 	if err != nil {
 		return err
 	}
-	if len(diff) > s.maxDiffSize {
-		skipMessage := fmt.Sprintf("I Skip it since the diff size(%d bytes > %d bytes) is too large", len(diff), s.maxDiffSize)
-		logger.Debug(skipMessage)
-		return s.createComment(logger, org, repo, num, comment, skipMessage)
-	}
 
 	tasks, err := s.getTasks(org, repo, foreword)
 	if err != nil {
@@ -230,15 +260,50 @@ func (s *Server) handle(logger *logrus.Entry, pr *github.PullRequest, comment *g
 		return err
 	}
 
+	ran, tooLargeSize, tooLargeLimit := 0, 0, 0
 	for n, task := range tasks {
-		if err := s.taskRun(logger.WithField("ai-task", n), task, pr, string(diff), comment); err != nil {
+		taskLogger := logger.WithField("ai-task", n)
+		patch, files := taskPatch(task, string(diff))
+		if len(files) == 0 {
+			taskLogger.Debug("no files to review after skipping the path globs.")
+			continue
+		}
+		// only the tasks reviewing in chunks can deal with larger diff, up to a hard limit.
+		limit := s.maxDiffSize
+		if task.Chunking != nil {
+			limit = s.maxChunkedDiffSize
+		}
+		if len(patch) > limit {
+			taskLogger.Debugf("Skip the task since the diff size(%d bytes) is too large.", len(patch))
+			tooLargeSize, tooLargeLimit = max(tooLargeSize, len(patch)), max(tooLargeLimit, limit)
+			continue
+		}
+		ran++
+		if err := s.taskRun(taskLogger, n, task, pr, patch, files, comment); err != nil {
 			return err
 		}
 	}
 
+	if ran == 0 && tooLargeSize > 0 {
+		skipMessage := fmt.Sprintf("I Skip it since the diff size(%d bytes > %d bytes) is too large", tooLargeSize, tooLargeLimit)
+		logger.Debug(skipMessage)
+		return s.createComment(logger, org, repo, num, comment, skipMessage)
+	}
+
 	return nil
 }
 
+// taskPatch returns the patch the task reviews, without the files matching
+// its skip path globs, and its files.
+func taskPatch(task *Task, patch string) (string, []*diffFile) {
+	files := splitDiffFiles(patch)
+	if len(task.SkipPathGlobs) == 0 {
+		return patch, files
+	}
+	files = filterDiffFiles(files, task.SkipPathGlobs)
+	return joinDiffFiles(files), files
+}
+
 func (s *Server) getTasks(org, repo, foreword string) (map[string]*Task, error) {
 	switch foreword {
 	case defaultIssueReviewWorld:
@@ -251,7 +316,7 @@ func (s *Server) getTasks(org, repo, foreword string) (map[string]*Task, error)
 			return nil, err
 		}
 
-		tasks := map[string]*Task{"user-comment-trigger": task}
+		tasks := map[string]*Task{userCommentTask: task}
 		return tasks, nil
 	case "":
 		return s.openaiTaskAgent.TasksFor(org, repo)
@@ -259,7 +324,7 @@ func (s *Server) getTasks(org, repo, foreword string) (map[string]*Task, error)
 		task := s.openaiTaskAgent.DefaultTask()
 		task.UserPrompt = foreword
 
-		tasks := map[string]*Task{"user-comment-trigger": task}
+		tasks := map[string]*Task{userCommentTask: task}
 		return tasks, nil
 	}
 }
@@ -280,13 +345,56 @@ func (s *Server) getPullRequestDiff(l *logrus.Entry, org, repo string, num int)
 	return diff, nil
 }
 
-func (s *Server) taskRun(logger *logrus.Entry, task *Task, pr *github.PullRequest, patch string, comment *github.IssueComment) error {
+func (s *Server) taskRun(logger *logrus.Entry, name string, task *Task, pr *github.PullRequest, patch string, files []*diffFile, comment *github.IssueComment) error {
 	// when triggered by pull request update or open events.
 	if comment == nil && !shouldRunTaskForPR(task, pr) {
 		return nil
 	}
 
 	logger.Debugf("start deal task %s...", task.Description)
+
+	key := usageKey{org: pr.Base.Repo.Owner.Login, repo: pr.Base.Repo.Name, task: name}
+	var resp string
+	var err error
+	if task.Chunking != nil {
+		resp, err = s.mapReduceReview(logger, key, task, pr, patch, files)
+	} else {
+		resp, err = s.chatWithAIServer(logger, key, task, taskMessage(task, pr, patch))
+	}
+	if errors.Is(err, errQuotaExhausted) {
+		logger.Info("Skip the task since the daily token quota is exhausted.")
+		return s.createComment(logger, pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, pr.Number, comment, quotaExhaustedMessage)
+	}
+	if err != nil {
+		logger.Errorf("Failed to send message to OpenAI server: %v", err)
+		return s.createComment(logger, pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, pr.Number, comment,
+			"Sorry, some error happened!")
+	}
+
+	review := github.DraftReview{
+		Body:   resp,
+		Action: github.Comment,
+	}
+	if task.InlineReview {
+		result, err := parseReviewResult(resp)
+		if err != nil {
+			logger.WithError(err).Warn("Failed to parse the structured review, post it as a whole.")
+		} else {
+			review = buildInlineReview(result, parseDiffHunks(patch))
+			review.CommitSHA = pr.Head.SHA
+		}
+	}
+
+	if task.OutputStaticHeadNote != "" {
+		review.Body = fmt.Sprintf("%s\n%s", task.OutputStaticHeadNote, review.Body)
+	}
+	review.Body = markAnswer(review.Body)
+
+	return s.ghc.CreateReview(pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, pr.Number, review)
+}
+
+// taskMessage composes the user message of the task for the patch.
+func taskMessage(task *Task, pr *github.PullRequest, patch string) string {
 	message := strings.Join([]string{
 		task.UserPrompt,
 		"This is the pr title:",
@@ -303,67 +411,105 @@ func (s *Server) taskRun(logger *logrus.Entry, task *Task, pr *github.PullReques
 		patch,
 		"```",
 	}, "\n")
+	if task.InlineReview {
+		message = strings.Join([]string{message, inlineReviewOutputPrompt}, "\n")
+	}
 
-	resp, err := s.chatWithAIServer(logger, task.SystemMessage, message, task.MaxResponseTokens)
-	if err != nil {
-		logger.Errorf("Failed to send message to OpenAI server: %v", err)
-		return s.createComment(logger, pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, pr.Number, comment,
-			"Sorry, some error happened!")
+	return message
+}
+
+func (s *Server) providerFor(task *Task, msgLen int) (LLMProvider, error) {
+	if task.Provider == "" {
+		return s.openaiClientAgent.ProviderFor(msgLen), nil
 	}
 
-	if task.OutputStaticHeadNote != "" {
-		resp = fmt.Sprintf("%s\n%s", task.OutputStaticHeadNote, resp)
+	if s.providerAgent == nil {
+		return nil, fmt.Errorf("provider %q is set but no providers are configured", task.Provider)
 	}
 
-	return s.ghc.CreateReview(pr.Base.Repo.Owner.Login, pr.Base.Repo.Name, pr.Number, github.DraftReview{
-		Body:   resp,
-		Action: github.Comment,
-	})
+	return s.providerAgent.Provider(task.Provider)
 }
 
-func (s *Server) chatWithAIServer(logger *logrus.Entry, systemMessage string, message string, maxResponseTokens int) (string, error) {
-	msgLen := len(message)
-	logger.Debugf("user message len: %d", msgLen)
+func (s *Server) chatWithAIServer(logger *logrus.Entry, key usageKey, task *Task, message string) (string, error) {
+	logger.Debugf("user message len: %d", len(message))
+
+	return s.chatMessagesWithAIServer(logger, key, task, taskMessages(task, message))
+}
 
-	openaiClient, model := s.openaiClientAgent.ClientFor(msgLen)
-	messages := []openai.ChatCompletionMessage{
+// taskMessages returns the system message of the task and the user message.
+func taskMessages(task *Task, message string) []openai.ChatCompletionMessage {
+	return []openai.ChatCompletionMessage{
 		{
 			Role:    openai.ChatMessageRoleSystem,
-			Content: systemMessage,
+			Content: task.SystemMessage,
 		},
 		{
 			Role:    openai.ChatMessageRoleUser,
 			Content: message,
 		},
 	}
+}
+
+func (s *Server) chatMessagesWithAIServer(logger *logrus.Entry, key usageKey, task *Task, messages []openai.ChatCompletionMessage) (string, error) {
+	msgLen := 0
+	for _, m := range messages {
+		msgLen += len(m.Content)
+	}
 
-	needTokens, err := numTokensFromMessages(messages, model)
+	provider, err := s.providerFor(task, msgLen)
 	if err != nil {
-		logger.Error(err)
 		return "", err
 	}
+
+	return s.chatWithProvider(logger, key, task, provider, messages)
+}
+
+// chatWithProvider sends the messages to the given provider, the quota of the
+// task is checked before and charged after.
+func (s *Server) chatWithProvider(logger *logrus.Entry, key usageKey, task *Task, provider LLMProvider, messages []openai.ChatCompletionMessage) (string, error) {
+	if s.tokenQuota != nil && s.tokenQuota.Exhausted(key, task.DailyTokenQuota) {
+		chatgptMetrics.quotaExhausted.WithLabelValues(key.org, key.repo, key.task).Inc()
+		return "", errQuotaExhausted
+	}
+
+	model := provider.Model()
+
+	maxResponseTokens := task.MaxResponseTokens
+	if limit := provider.MaxResponseTokens(); limit > 0 && (maxResponseTokens == 0 || maxResponseTokens > limit) {
+		maxResponseTokens = limit
+	}
+
+	needTokens := numTokensFromMessages(messages, model)
 	logger.Debugf("need tokens: %d", needTokens)
-	for needTokens+maxResponseTokens >= maxTokens[model] {
+	if limit := provider.MaxContextTokens(); limit > 0 && needTokens+maxResponseTokens >= limit {
 		return "", fmt.Errorf("message too large(need tokens: %d)", needTokens)
 	}
 
-	resp, err := openaiClient.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
-		Model:       model,
+	start := time.Now()
+	resp, err := provider.ChatCompletion(context.Background(), chatRequest{
+		Messages:    messages,
 		MaxTokens:   maxResponseTokens,
 		Temperature: defaultTemperature,
-		Messages:    messages,
 	})
+	chatgptMetrics.requestDuration.WithLabelValues(key.org, key.repo, key.task, model).Observe(time.Since(start).Seconds())
 	if err != nil {
-		return "", fmt.Errorf("ChatCompletion error: %w", err)
+		chatgptMetrics.requestErrors.WithLabelValues(key.org, key.repo, key.task).Inc()
+		return "", err
+	}
+
+	chatgptMetrics.tokens.WithLabelValues(key.org, key.repo, key.task, "prompt").Add(float64(resp.PromptTokens))
+	chatgptMetrics.tokens.WithLabelValues(key.org, key.repo, key.task, "completion").Add(float64(resp.CompletionTokens))
+	if s.tokenQuota != nil {
+		s.tokenQuota.Add(key, resp.PromptTokens+resp.CompletionTokens)
 	}
 
-	result := resp.Choices[0].Message.Content
-	if isTruncated := resp.Choices[0].FinishReason == "length"; isTruncated {
+	result := resp.Content
+	if resp.Truncated {
 		result += "\n......\n> Response is trunked for length limits."
 	}
 	logger.WithField("model", resp.Model).Debugf(
-		"openai token usage: (total: %d, prompt: %d, completion: %d)",
-		resp.Usage.TotalTokens, resp.Usage.PromptTokens, resp.Usage.CompletionTokens,
+		"token usage: (total: %d, prompt: %d, completion: %d)",
+		resp.PromptTokens+resp.CompletionTokens, resp.PromptTokens, resp.CompletionTokens,
 	)
 	logger.Debugf("response: %s", result)
 
@@ -168,3 +168,58 @@ func Test_shouldRunTaskForPR(t *testing.T) {
 		})
 	}
 }
+
+func TestServer_getTasks(t *testing.T) {
+	configured := &Task{UserPrompt: "configured", DailyTokenQuota: 10}
+	agent := &TaskAgent{defaultDailyTokenQuota: 1000}
+	agent.config = TasksConfig{"org/configured": {defaultIssueReviewWorld: configured}}
+	s := &Server{openaiTaskAgent: agent}
+
+	tests := []struct {
+		name       string
+		repo       string
+		foreword   string
+		wantPrompt string
+		wantQuota  int
+	}{
+		{
+			name:       "configured task keeps its quota",
+			repo:       "configured",
+			foreword:   defaultIssueReviewWorld,
+			wantPrompt: "configured",
+			wantQuota:  10,
+		},
+		{
+			name:       "default task gets the default quota",
+			repo:       "other",
+			foreword:   defaultIssueReviewWorld,
+			wantPrompt: defaultPromte,
+			wantQuota:  1000,
+		},
+		{
+			name:       "free-form prompt gets the default quota",
+			repo:       "configured",
+			foreword:   "explain the change",
+			wantPrompt: "explain the change",
+			wantQuota:  1000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks, err := s.getTasks("org", tt.repo, tt.foreword)
+			if err != nil {
+				t.Fatalf("getTasks() error = %v", err)
+			}
+			task := tasks[userCommentTask]
+			if task == nil {
+				t.Fatalf("getTasks() = %v, want a %q task", tasks, userCommentTask)
+			}
+			if task.UserPrompt != tt.wantPrompt {
+				t.Errorf("UserPrompt = %q, want %q", task.UserPrompt, tt.wantPrompt)
+			}
+			if task.DailyTokenQuota != tt.wantQuota {
+				t.Errorf("DailyTokenQuota = %d, want %d", task.DailyTokenQuota, tt.wantQuota)
+			}
+		})
+	}
+}
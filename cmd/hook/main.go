@@ -49,6 +49,7 @@ import (
 	"sigs.k8s.io/prow/pkg/repoowners"
 	"sigs.k8s.io/prow/pkg/slack"
 
+	"sigs.k8s.io/prow/pkg/tracing"
 	_ "sigs.k8s.io/prow/pkg/version"
 )
 
@@ -242,6 +243,9 @@ func main() {
 	// Expose prometheus metrics
 	metrics.ExposeMetrics("hook", configAgent.Config().PushGateway, o.instrumentationOptions.MetricsPort)
 	pprof.Instrument(o.instrumentationOptions)
+	if err := tracing.Init("hook", o.instrumentationOptions); err != nil {
+		logrus.WithError(err).Fatal("Error initializing tracing.")
+	}
 
 	server := &hook.Server{
 		ClientAgent:    clientAgent,
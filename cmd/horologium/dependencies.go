@@ -0,0 +1,190 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/pjutil"
+)
+
+// revision is a commit of a branch the postsubmits of a repo ran for.
+type revision struct {
+	branch string
+	sha    string
+}
+
+// syncDownstreamJobs triggers the jobs running after the success of their
+// upstream jobs once all of them succeeded.
+func syncDownstreamJobs(prowJobClient ctrlruntimeclient.Client, cfg *config.Config, jobs []prowapi.ProwJob, now time.Time) []error {
+	var toCreate []prowapi.ProwJob
+
+	latestPeriodics := pjutil.GetLatestProwJobs(jobs, prowapi.PeriodicJob)
+	latestRuns := pjutil.GetLatestProwJobs(jobs, prowapi.PostsubmitJob)
+	for name, pj := range latestPeriodics {
+		if pj.Status.StartTime.After(latestRuns[name].Status.StartTime.Time) {
+			latestRuns[name] = pj
+		}
+	}
+	for _, p := range cfg.Periodics {
+		if len(p.RunAfterSuccess) == 0 {
+			continue
+		}
+		logger := logrus.WithField("job", p.Name)
+		if window := cfg.Horologium.ExcludingWindow(&p, now); window != "" {
+			logger.WithField("exclusion-window", window).Debug("Not triggering during exclusion window.")
+			continue
+		}
+		previous, previousFound := latestPeriodics[p.Name]
+		var upstreams []prowapi.ProwJob
+		for _, name := range p.RunAfterSuccess {
+			run, ok := latestRuns[name]
+			// Each upstream job must have succeeded since the latest run.
+			if !ok || run.Status.State != prowapi.SuccessState || (previousFound && !run.Status.CompletionTime.After(previous.Status.StartTime.Time)) {
+				upstreams = nil
+				break
+			}
+			upstreams = append(upstreams, run)
+		}
+		if upstreams == nil {
+			continue
+		}
+		spec := pjutil.PeriodicSpec(p)
+		spec.Upstream = upstreamJobs(upstreams, logger)
+		toCreate = append(toCreate, pjutil.NewProwJob(spec, p.Labels, p.Annotations, pjutil.RequireScheduling(cfg.Scheduler.Enabled)))
+	}
+
+	for repo, postsubmits := range cfg.PostsubmitsStatic {
+		for _, p := range postsubmits {
+			if len(p.RunAfterSuccess) == 0 {
+				continue
+			}
+			for _, refs := range downstreamRevisions(p, repo, jobs) {
+				spec := pjutil.PostsubmitSpec(p, refs.refs)
+				spec.Upstream = upstreamJobs(refs.upstreams, logrus.WithField("job", p.Name))
+				toCreate = append(toCreate, pjutil.NewProwJob(spec, p.Labels, p.Annotations, pjutil.RequireScheduling(cfg.Scheduler.Enabled)))
+			}
+		}
+	}
+
+	var errs []error
+	for _, prowJob := range toCreate {
+		prowJob.Namespace = cfg.ProwJobNamespace
+		logrus.WithFields(pjutil.ProwJobFields(&prowJob)).WithField("upstream", prowJob.Spec.Upstream).Info("Triggering run after upstream jobs succeeded.")
+		if err := prowJobClient.Create(context.TODO(), &prowJob); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+type downstreamRevision struct {
+	refs      prowapi.Refs
+	upstreams []prowapi.ProwJob
+}
+
+// downstreamRevisions returns the revisions to run the postsubmit for. Like the
+// latest run of a periodic, only the newest revision of each branch the
+// upstream jobs ran for is considered, so that the postsubmit doesn't run for
+// the whole history when it is added.
+func downstreamRevisions(p config.Postsubmit, repo string, jobs []prowapi.ProwJob) []downstreamRevision {
+	upstreamNames := sets.New[string](p.RunAfterSuccess...)
+	latestUpstreams := map[revision]map[string]prowapi.ProwJob{}
+	newest := map[string]prowapi.ProwJob{}
+	ran := sets.New[revision]()
+	for _, pj := range jobs {
+		refs := pj.Spec.Refs
+		if pj.Spec.Type != prowapi.PostsubmitJob || refs == nil || refs.Org+"/"+refs.Repo != repo {
+			continue
+		}
+		rev := revision{branch: refs.BaseRef, sha: refs.BaseSHA}
+		switch {
+		case pj.Spec.Job == p.Name:
+			ran.Insert(rev)
+		case upstreamNames.Has(pj.Spec.Job):
+			if latestUpstreams[rev] == nil {
+				latestUpstreams[rev] = map[string]prowapi.ProwJob{}
+			}
+			if pj.Status.StartTime.After(latestUpstreams[rev][pj.Spec.Job].Status.StartTime.Time) {
+				latestUpstreams[rev][pj.Spec.Job] = pj
+			}
+			if pj.Status.StartTime.After(newest[refs.BaseRef].Status.StartTime.Time) {
+				newest[refs.BaseRef] = pj
+			}
+		}
+	}
+
+	var revisions []downstreamRevision
+	for _, branch := range sets.List(sets.KeySet(newest)) {
+		refs := newest[branch].Spec.Refs
+		rev := revision{branch: branch, sha: refs.BaseSHA}
+		if !p.CouldRun(branch) || ran.Has(rev) {
+			continue
+		}
+		var upstreams []prowapi.ProwJob
+		for _, name := range p.RunAfterSuccess {
+			run, ok := latestUpstreams[rev][name]
+			if !ok || run.Status.State != prowapi.SuccessState {
+				upstreams = nil
+				break
+			}
+			upstreams = append(upstreams, run)
+		}
+		if upstreams == nil {
+			continue
+		}
+		revisions = append(revisions, downstreamRevision{
+			refs: prowapi.Refs{
+				Org:      refs.Org,
+				Repo:     refs.Repo,
+				RepoLink: refs.RepoLink,
+				BaseRef:  refs.BaseRef,
+				BaseSHA:  refs.BaseSHA,
+				BaseLink: refs.BaseLink,
+				Pulls:    refs.Pulls,
+			},
+			upstreams: upstreams,
+		})
+	}
+	return revisions
+}
+
+// upstreamJobs returns the references to the upstream runs passed to the
+// downstream job.
+func upstreamJobs(runs []prowapi.ProwJob, logger *logrus.Entry) []prowapi.UpstreamJob {
+	var upstreams []prowapi.UpstreamJob
+	for _, run := range runs {
+		artifactsURL, err := pjutil.ArtifactsURL(run)
+		if err != nil {
+			logger.WithError(err).WithField("upstream", run.Name).Warn("Failed to determine the artifacts URL of the upstream run.")
+		}
+		upstreams = append(upstreams, prowapi.UpstreamJob{
+			Job:          run.Spec.Job,
+			ProwJob:      run.Name,
+			URL:          run.Status.URL,
+			ArtifactsURL: artifactsURL,
+		})
+	}
+	return upstreams
+}
@@ -48,6 +48,7 @@ import (
 const (
 	defaultTickInterval = time.Minute
 	maxRetries          = 10
+	maxCatchUpRuns      = 10
 )
 
 type options struct {
@@ -152,6 +153,9 @@ func main() {
 type cronClient interface {
 	SyncConfig(cfg *config.Config) error
 	QueuedJobs() []string
+	AddedJobs() []string
+	CatchUpRuns(name string) (int, bool)
+	SetCatchUpRuns(name string, runs int)
 }
 
 func sync(prowJobClient ctrlruntimeclient.Client, cfg *config.Config, cr cronClient, now time.Time) error {
@@ -169,15 +173,32 @@ func sync(prowJobClient ctrlruntimeclient.Client, cfg *config.Config, cr cronCli
 	for _, job := range cr.QueuedJobs() {
 		cronTriggers.Insert(job)
 	}
+	// Triggers of the jobs added to cron at startup may have been missed while
+	// we were down, they stay added until they caught up.
+	cronAdded := sets.New[string](cr.AddedJobs()...)
 
 	var errs []error
 	for _, p := range cfg.Periodics {
+		if !p.HasSchedule() {
+			// Only triggered after the success of its upstream jobs.
+			continue
+		}
 		j, previousFound := latestJobs[p.Name]
 		logger := logrus.WithFields(logrus.Fields{
 			"job":            p.Name,
 			"previous-found": previousFound,
 		})
 
+		if window := cfg.Horologium.ExcludingWindow(&p, now); window != "" {
+			logger.WithField("exclusion-window", window).Debug("Not triggering during exclusion window.")
+			continue
+		}
+
+		var catchUp bool
+		if cronAdded.Has(p.Name) && !cronTriggers.Has(p.Name) {
+			catchUp = shouldCatchUp(cr, &cfg.Horologium, p, j, previousFound, now, logger)
+		}
+
 		var shouldTrigger = false
 		switch {
 		case p.Cron == "": // no cron expression is set, we use interval to trigger
@@ -192,6 +213,8 @@ func sync(prowJobClient ctrlruntimeclient.Client, cfg *config.Config, cr cronCli
 			}
 		case cronTriggers.Has(p.Name):
 			shouldTrigger = j.Complete()
+		case catchUp:
+			shouldTrigger = true
 		default:
 			if !cronTriggers.Has(p.Name) {
 				logger.WithFields(logrus.Fields{
@@ -226,6 +249,7 @@ func sync(prowJobClient ctrlruntimeclient.Client, cfg *config.Config, cr cronCli
 			logger.WithFields(logrus.Fields{
 				"should-trigger": shouldTrigger,
 				"previous-found": previousFound,
+				"catch-up":       catchUp,
 			}).WithFields(
 				pjutil.ProwJobFields(&prowJob),
 			).Info("Triggering new run.")
@@ -234,6 +258,7 @@ func sync(prowJobClient ctrlruntimeclient.Client, cfg *config.Config, cr cronCli
 			}
 		}
 	}
+	errs = append(errs, syncDownstreamJobs(prowJobClient, cfg, jobs.Items, now)...)
 
 	if len(errs) > 0 {
 		return fmt.Errorf("failed to create %d prowjobs: %v", len(errs), errs)
@@ -241,6 +266,49 @@ func sync(prowJobClient ctrlruntimeclient.Client, cfg *config.Config, cr cronCli
 	return nil
 }
 
+// shouldCatchUp returns whether to trigger a run of the cron periodic added at
+// startup for the triggers missed while horologium was down. The runs are
+// triggered one at a time, once the previous run completed, and the periodic
+// keeps catching up until all of them got triggered.
+func shouldCatchUp(cr cronClient, h *config.Horologium, p config.Periodic, latest v1.ProwJob, previousFound bool, now time.Time, logger *logrus.Entry) bool {
+	if !previousFound || (p.CatchUp != config.CatchUpOnce && p.CatchUp != config.CatchUpAll) {
+		cr.SetCatchUpRuns(p.Name, 0)
+		return false
+	}
+	if !latest.Complete() {
+		return false
+	}
+	runs, counted := cr.CatchUpRuns(p.Name)
+	if !counted {
+		runs = countCatchUpRuns(h, p, latest, now, logger)
+	}
+	if runs <= 0 {
+		cr.SetCatchUpRuns(p.Name, 0)
+		return false
+	}
+	cr.SetCatchUpRuns(p.Name, runs-1)
+	return true
+}
+
+// countCatchUpRuns returns how many runs of the cron periodic to trigger for
+// the triggers missed since its latest run completed, according to its
+// catch-up policy. The triggers while it was running are skipped anyway.
+func countCatchUpRuns(h *config.Horologium, p config.Periodic, latest v1.ProwJob, now time.Time, logger *logrus.Entry) int {
+	missed, err := cron.MissedTriggers(h, p, latest.Status.CompletionTime.Time, now)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to compute the missed triggers.")
+		return 0
+	}
+	if len(missed) == 0 {
+		return 0
+	}
+	logger.WithField("missed", len(missed)).WithField("catch-up", p.CatchUp).Info("Catching up on missed triggers.")
+	if p.CatchUp == config.CatchUpOnce {
+		return 1
+	}
+	return min(len(missed), maxCatchUpRuns)
+}
+
 func shouldTriggerFailedRun(j v1.ProwJob, p config.Periodic, now time.Time, logger *logrus.Entry, labels *map[string]string) bool {
 	if p.Retry == nil {
 		return false
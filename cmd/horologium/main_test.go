@@ -19,11 +19,14 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"reflect"
+	"slices"
 	"strconv"
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -58,6 +61,49 @@ func (fc *fakeCron) QueuedJobs() []string {
 	return res
 }
 
+func (fc *fakeCron) AddedJobs() []string {
+	return nil
+}
+
+func (fc *fakeCron) CatchUpRuns(string) (int, bool) {
+	return 0, false
+}
+
+func (fc *fakeCron) SetCatchUpRuns(string, int) {}
+
+// fakeAddedCron has added jobs but never triggers them.
+type fakeAddedCron struct {
+	added []string
+	runs  map[string]int
+}
+
+func (fc *fakeAddedCron) SyncConfig(cfg *config.Config) error {
+	return nil
+}
+
+func (fc *fakeAddedCron) QueuedJobs() []string {
+	return nil
+}
+
+func (fc *fakeAddedCron) AddedJobs() []string {
+	return fc.added
+}
+
+func (fc *fakeAddedCron) CatchUpRuns(name string) (int, bool) {
+	runs, ok := fc.runs[name]
+	return runs, ok
+}
+
+func (fc *fakeAddedCron) SetCatchUpRuns(name string, runs int) {
+	if fc.runs == nil {
+		fc.runs = map[string]int{}
+	}
+	fc.runs[name] = runs
+	if runs <= 0 {
+		fc.added = slices.DeleteFunc(fc.added, func(added string) bool { return added == name })
+	}
+}
+
 // Assumes there is one periodic job called "p" with an interval of one minute.
 func TestSync(t *testing.T) {
 	testcases := []struct {
@@ -497,6 +543,205 @@ func TestSyncCron(t *testing.T) {
 	}
 }
 
+// Test catching up on the cron triggers missed while horologium was down and
+// not triggering periodics during their exclusion windows.
+func TestSyncCatchUpAndExclusions(t *testing.T) {
+	// Wednesday.
+	now := time.Date(2024, 3, 6, 9, 0, 0, 0, time.UTC)
+	testcases := []struct {
+		testName        string
+		periodic        config.Periodic
+		added           bool
+		lastStart       time.Time
+		lastDuration    time.Duration
+		running         bool
+		expectedCreated int
+		// expectedRunsLeft is the number of catch-up runs left, -1 once
+		// caught up.
+		expectedRunsLeft int
+	}{
+		{
+			testName:         "missed triggers are skipped by default",
+			periodic:         config.Periodic{JobBase: config.JobBase{Name: "j"}, Cron: "0 8 * * *"},
+			added:            true,
+			lastStart:        now.Add(-48 * time.Hour),
+			expectedRunsLeft: -1,
+		},
+		{
+			testName:         "run once for missed triggers",
+			periodic:         config.Periodic{JobBase: config.JobBase{Name: "j"}, Cron: "0 8 * * *", CatchUp: config.CatchUpOnce},
+			added:            true,
+			lastStart:        now.Add(-48 * time.Hour),
+			expectedCreated:  1,
+			expectedRunsLeft: -1,
+		},
+		{
+			testName:         "run for each missed trigger, one at a time",
+			periodic:         config.Periodic{JobBase: config.JobBase{Name: "j"}, Cron: "0 8 * * *", CatchUp: config.CatchUpAll},
+			added:            true,
+			lastStart:        now.Add(-48 * time.Hour),
+			expectedCreated:  1,
+			expectedRunsLeft: 1,
+		},
+		{
+			testName:         "runs for missed triggers are capped",
+			periodic:         config.Periodic{JobBase: config.JobBase{Name: "j"}, Cron: "0 8 * * *", CatchUp: config.CatchUpAll},
+			added:            true,
+			lastStart:        now.Add(-30 * 24 * time.Hour),
+			expectedCreated:  1,
+			expectedRunsLeft: maxCatchUpRuns - 1,
+		},
+		{
+			testName:         "missed triggers during exclusion windows are skipped",
+			periodic:         config.Periodic{JobBase: config.JobBase{Name: "j"}, Cron: "0 8 * * *", CatchUp: config.CatchUpAll, ExclusionWindows: []string{"weekends"}},
+			added:            true,
+			lastStart:        time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC),
+			expectedCreated:  1,
+			expectedRunsLeft: 2,
+		},
+		{
+			testName:         "triggers while the latest run was running are not missed",
+			periodic:         config.Periodic{JobBase: config.JobBase{Name: "j"}, Cron: "0 8 * * *", CatchUp: config.CatchUpAll},
+			added:            true,
+			lastStart:        now.Add(-48 * time.Hour),
+			lastDuration:     47 * time.Hour,
+			expectedRunsLeft: -1,
+		},
+		{
+			testName:         "no catching up once the job was added",
+			periodic:         config.Periodic{JobBase: config.JobBase{Name: "j"}, Cron: "0 8 * * *", CatchUp: config.CatchUpAll},
+			lastStart:        now.Add(-48 * time.Hour),
+			expectedRunsLeft: -1,
+		},
+		{
+			testName:  "catching up waits for the latest run to complete",
+			periodic:  config.Periodic{JobBase: config.JobBase{Name: "j"}, Cron: "0 8 * * *", CatchUp: config.CatchUpAll},
+			added:     true,
+			lastStart: now.Add(-48 * time.Hour),
+			running:   true,
+		},
+		{
+			testName:         "nothing missed",
+			periodic:         config.Periodic{JobBase: config.JobBase{Name: "j"}, Cron: "0 8 * * *", CatchUp: config.CatchUpAll},
+			added:            true,
+			lastStart:        now.Add(-time.Hour),
+			expectedRunsLeft: -1,
+		},
+		{
+			testName:         "not triggered during exclusion window",
+			periodic:         config.Periodic{JobBase: config.JobBase{Name: "j"}, Interval: "1h", ExclusionWindows: []string{"freeze"}},
+			lastStart:        now.Add(-48 * time.Hour),
+			expectedRunsLeft: -1,
+		},
+		{
+			testName:         "triggered outside of exclusion windows",
+			periodic:         config.Periodic{JobBase: config.JobBase{Name: "j"}, Interval: "1h", ExclusionWindows: []string{"weekends"}},
+			lastStart:        now.Add(-48 * time.Hour),
+			expectedCreated:  1,
+			expectedRunsLeft: -1,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			cfg := config.Config{
+				ProwConfig: config.ProwConfig{
+					ProwJobNamespace: "prowjobs",
+					Horologium: config.Horologium{
+						ExclusionWindows: map[string]config.ExclusionWindow{
+							"weekends": {Weekdays: []string{"Saturday", "Sunday"}},
+							"freeze": {
+								Start: &metav1.Time{Time: now.Add(-time.Hour)},
+								End:   &metav1.Time{Time: now.Add(time.Hour)},
+							},
+						},
+					},
+				},
+				JobConfig: config.JobConfig{
+					Periodics: []config.Periodic{tc.periodic},
+				},
+			}
+			cfg.Periodics[0].SetInterval(time.Hour)
+			var complete *metav1.Time
+			if !tc.running {
+				complete = &metav1.Time{Time: tc.lastStart.Add(max(tc.lastDuration, time.Minute))}
+			}
+			jobs := []client.Object{&prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "previous",
+					Namespace: "prowjobs",
+				},
+				Spec: prowapi.ProwJobSpec{
+					Type: prowapi.PeriodicJob,
+					Job:  "j",
+				},
+				Status: prowapi.ProwJobStatus{
+					StartTime:      metav1.NewTime(tc.lastStart),
+					CompletionTime: complete,
+				},
+			}}
+			fakeProwJobClient := newCreateTrackingClient(jobs)
+			fc := &fakeAddedCron{}
+			if tc.added {
+				fc.added = []string{"j"}
+			}
+			if err := sync(fakeProwJobClient, &cfg, fc, now); err != nil {
+				t.Fatalf("Didn't expect error: %v", err)
+			}
+			if created := len(fakeProwJobClient.created); created != tc.expectedCreated {
+				t.Errorf("expected %d runs to be triggered, got %d", tc.expectedCreated, created)
+			}
+			runsLeft := -1
+			if len(fc.added) != 0 {
+				runsLeft = fc.runs["j"]
+			}
+			if runsLeft != tc.expectedRunsLeft {
+				t.Errorf("expected %d catch-up runs left, got %d", tc.expectedRunsLeft, runsLeft)
+			}
+		})
+	}
+}
+
+// Test the catch-up runs get triggered one after the other.
+func TestSyncCatchUpSerially(t *testing.T) {
+	now := time.Date(2024, 3, 6, 9, 0, 0, 0, time.UTC)
+	cfg := config.Config{
+		ProwConfig: config.ProwConfig{ProwJobNamespace: "prowjobs"},
+		JobConfig: config.JobConfig{
+			Periodics: []config.Periodic{{JobBase: config.JobBase{Name: "j"}, Cron: "0 8 * * *", CatchUp: config.CatchUpAll}},
+		},
+	}
+	fakeProwJobClient := newCreateTrackingClient([]client.Object{&prowapi.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "previous", Namespace: "prowjobs"},
+		Spec:       prowapi.ProwJobSpec{Type: prowapi.PeriodicJob, Job: "j"},
+		Status: prowapi.ProwJobStatus{
+			StartTime:      metav1.NewTime(now.Add(-72 * time.Hour)),
+			CompletionTime: &metav1.Time{Time: now.Add(-71 * time.Hour)},
+		},
+	}})
+	fc := &fakeAddedCron{added: []string{"j"}}
+
+	for i, expectedCreated := range []int{1, 1, 2, 2, 3, 3} {
+		if err := sync(fakeProwJobClient, &cfg, fc, now); err != nil {
+			t.Fatalf("sync %d: didn't expect error: %v", i, err)
+		}
+		if created := len(fakeProwJobClient.created); created != expectedCreated {
+			t.Fatalf("sync %d: expected %d runs to be triggered, got %d", i, expectedCreated, created)
+		}
+		latest := fakeProwJobClient.created[len(fakeProwJobClient.created)-1].(*prowapi.ProwJob)
+		latest.Status.StartTime = metav1.NewTime(now.Add(time.Duration(len(fakeProwJobClient.created)) * time.Minute))
+		// Complete the latest run every other sync.
+		if i%2 == 1 {
+			latest.Status.CompletionTime = &metav1.Time{Time: latest.Status.StartTime.Add(time.Minute)}
+		}
+		if err := fakeProwJobClient.Update(context.Background(), latest); err != nil {
+			t.Fatalf("sync %d: failed to update the latest run: %v", i, err)
+		}
+	}
+	if len(fc.added) != 0 {
+		t.Errorf("expected the job to have caught up, still added: %v", fc.added)
+	}
+}
+
 func TestFlags(t *testing.T) {
 	cases := []struct {
 		name     string
@@ -602,6 +847,202 @@ func TestFlags(t *testing.T) {
 	}
 }
 
+func TestSyncDownstreamJobs(t *testing.T) {
+	now := time.Date(2024, 3, 6, 9, 0, 0, 0, time.UTC)
+	run := func(name, job string, jobType prowapi.ProwJobType, state prowapi.ProwJobState, started time.Duration, sha string) *prowapi.ProwJob {
+		pj := &prowapi.ProwJob{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "prowjobs"},
+			Spec: prowapi.ProwJobSpec{
+				Type: jobType,
+				Job:  job,
+				DecorationConfig: &prowapi.DecorationConfig{
+					GCSConfiguration: &prowapi.GCSConfiguration{Bucket: "bucket", PathStrategy: prowapi.PathStrategyExplicit},
+				},
+			},
+			Status: prowapi.ProwJobStatus{
+				State:     state,
+				StartTime: metav1.NewTime(now.Add(-started)),
+				BuildID:   name,
+			},
+		}
+		if pj.Complete() || state == prowapi.SuccessState || state == prowapi.FailureState {
+			completed := metav1.NewTime(now.Add(-started + 10*time.Minute))
+			pj.Status.CompletionTime = &completed
+		}
+		if jobType == prowapi.PostsubmitJob {
+			pj.Spec.Refs = &prowapi.Refs{Org: "org", Repo: "repo", BaseRef: "main", BaseSHA: sha}
+		}
+		return pj
+	}
+	postsubmitOn := func(pj *prowapi.ProwJob, branch string) *prowapi.ProwJob {
+		pj.Spec.Refs.BaseRef = branch
+		return pj
+	}
+
+	testCases := []struct {
+		name        string
+		periodics   []config.Periodic
+		postsubmits []config.Postsubmit
+		jobs        []*prowapi.ProwJob
+		// expected are the upstream ProwJobs of each created job, by job.
+		expected map[string][]string
+		// expectedSHA is the revision the created postsubmit runs for.
+		expectedSHA string
+	}{
+		{
+			name:      "periodic runs after its upstream periodic succeeded",
+			periodics: []config.Periodic{{JobBase: config.JobBase{Name: "deploy"}, RunAfterSuccess: []string{"build"}}},
+			jobs: []*prowapi.ProwJob{
+				run("build-1", "build", prowapi.PeriodicJob, prowapi.SuccessState, time.Hour, ""),
+			},
+			expected: map[string][]string{"deploy": {"build-1"}},
+		},
+		{
+			name:      "periodic doesn't run again until its upstream succeeded again",
+			periodics: []config.Periodic{{JobBase: config.JobBase{Name: "deploy"}, RunAfterSuccess: []string{"build"}}},
+			jobs: []*prowapi.ProwJob{
+				run("build-1", "build", prowapi.PeriodicJob, prowapi.SuccessState, time.Hour, ""),
+				run("deploy-1", "deploy", prowapi.PeriodicJob, prowapi.PendingState, 30*time.Minute, ""),
+			},
+		},
+		{
+			name:      "periodic doesn't run after its upstream failed",
+			periodics: []config.Periodic{{JobBase: config.JobBase{Name: "deploy"}, RunAfterSuccess: []string{"build"}}},
+			jobs: []*prowapi.ProwJob{
+				run("build-1", "build", prowapi.PeriodicJob, prowapi.SuccessState, 2*time.Hour, ""),
+				run("build-2", "build", prowapi.PeriodicJob, prowapi.FailureState, time.Hour, ""),
+			},
+		},
+		{
+			name:      "periodic waits for all of its upstream jobs",
+			periodics: []config.Periodic{{JobBase: config.JobBase{Name: "deploy"}, RunAfterSuccess: []string{"build", "lint"}}},
+			jobs: []*prowapi.ProwJob{
+				run("build-1", "build", prowapi.PeriodicJob, prowapi.SuccessState, time.Hour, ""),
+				run("lint-1", "lint", prowapi.PeriodicJob, prowapi.SuccessState, 4*time.Hour, ""),
+				run("deploy-1", "deploy", prowapi.PeriodicJob, prowapi.SuccessState, 3*time.Hour, ""),
+			},
+		},
+		{
+			name:      "periodic runs once all of its upstream jobs succeeded since its latest run",
+			periodics: []config.Periodic{{JobBase: config.JobBase{Name: "deploy"}, RunAfterSuccess: []string{"build", "lint"}}},
+			jobs: []*prowapi.ProwJob{
+				run("build-1", "build", prowapi.PeriodicJob, prowapi.SuccessState, time.Hour, ""),
+				run("lint-1", "lint", prowapi.PeriodicJob, prowapi.SuccessState, 2*time.Hour, ""),
+				run("deploy-1", "deploy", prowapi.PeriodicJob, prowapi.SuccessState, 3*time.Hour, ""),
+			},
+			expected: map[string][]string{"deploy": {"build-1", "lint-1"}},
+		},
+		{
+			name:      "periodic runs after an upstream postsubmit",
+			periodics: []config.Periodic{{JobBase: config.JobBase{Name: "deploy"}, RunAfterSuccess: []string{"post-build"}}},
+			jobs: []*prowapi.ProwJob{
+				run("post-build-1", "post-build", prowapi.PostsubmitJob, prowapi.SuccessState, time.Hour, "abc"),
+			},
+			expected: map[string][]string{"deploy": {"post-build-1"}},
+		},
+		{
+			name: "periodic isn't triggered during exclusion windows",
+			periodics: []config.Periodic{{
+				JobBase:          config.JobBase{Name: "deploy"},
+				RunAfterSuccess:  []string{"build"},
+				ExclusionWindows: []string{"weekdays"},
+			}},
+			jobs: []*prowapi.ProwJob{
+				run("build-1", "build", prowapi.PeriodicJob, prowapi.SuccessState, time.Hour, ""),
+			},
+		},
+		{
+			name:        "postsubmit runs for the revision its upstream jobs succeeded for",
+			postsubmits: []config.Postsubmit{{JobBase: config.JobBase{Name: "post-deploy"}, RunAfterSuccess: []string{"post-build", "post-lint"}}},
+			jobs: []*prowapi.ProwJob{
+				run("post-build-1", "post-build", prowapi.PostsubmitJob, prowapi.SuccessState, time.Hour, "abc"),
+				run("post-lint-1", "post-lint", prowapi.PostsubmitJob, prowapi.FailureState, 2*time.Hour, "abc"),
+				run("post-lint-2", "post-lint", prowapi.PostsubmitJob, prowapi.SuccessState, time.Hour, "abc"),
+			},
+			expected:    map[string][]string{"post-deploy": {"post-build-1", "post-lint-2"}},
+			expectedSHA: "abc",
+		},
+		{
+			name:        "postsubmit runs once per revision",
+			postsubmits: []config.Postsubmit{{JobBase: config.JobBase{Name: "post-deploy"}, RunAfterSuccess: []string{"post-build"}}},
+			jobs: []*prowapi.ProwJob{
+				run("post-build-1", "post-build", prowapi.PostsubmitJob, prowapi.SuccessState, time.Hour, "abc"),
+				run("post-deploy-1", "post-deploy", prowapi.PostsubmitJob, prowapi.FailureState, 30*time.Minute, "abc"),
+			},
+		},
+		{
+			name:        "postsubmit only runs for the newest revision",
+			postsubmits: []config.Postsubmit{{JobBase: config.JobBase{Name: "post-deploy"}, RunAfterSuccess: []string{"post-build"}}},
+			jobs: []*prowapi.ProwJob{
+				run("post-build-1", "post-build", prowapi.PostsubmitJob, prowapi.SuccessState, 2*time.Hour, "abc"),
+				run("post-build-2", "post-build", prowapi.PostsubmitJob, prowapi.PendingState, time.Hour, "def"),
+			},
+		},
+		{
+			name: "postsubmit doesn't run for other branches",
+			postsubmits: []config.Postsubmit{{
+				JobBase:         config.JobBase{Name: "post-deploy"},
+				RunAfterSuccess: []string{"post-build"},
+				Brancher:        config.Brancher{Branches: []string{"main"}},
+			}},
+			jobs: []*prowapi.ProwJob{
+				postsubmitOn(run("post-build-1", "post-build", prowapi.PostsubmitJob, prowapi.SuccessState, time.Hour, "abc"), "release"),
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.Config{
+				ProwConfig: config.ProwConfig{
+					ProwJobNamespace: "prowjobs",
+					Horologium: config.Horologium{
+						ExclusionWindows: map[string]config.ExclusionWindow{
+							"weekdays": {Weekdays: []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday"}},
+						},
+					},
+				},
+				JobConfig: config.JobConfig{
+					Periodics: tc.periodics,
+				},
+			}
+			if err := cfg.SetPostsubmits(map[string][]config.Postsubmit{"org/repo": tc.postsubmits}); err != nil {
+				t.Fatalf("failed to set postsubmits: %v", err)
+			}
+			var objs []client.Object
+			var jobs []prowapi.ProwJob
+			for _, pj := range tc.jobs {
+				objs = append(objs, pj)
+				jobs = append(jobs, *pj)
+			}
+			fakeProwJobClient := newCreateTrackingClient(objs)
+			if errs := syncDownstreamJobs(fakeProwJobClient, &cfg, jobs, now); len(errs) > 0 {
+				t.Fatalf("unexpected errors: %v", errs)
+			}
+
+			actual := map[string][]string{}
+			for _, obj := range fakeProwJobClient.created {
+				pj := obj.(*prowapi.ProwJob)
+				for _, upstream := range pj.Spec.Upstream {
+					actual[pj.Spec.Job] = append(actual[pj.Spec.Job], upstream.ProwJob)
+					expectedURL := fmt.Sprintf("gs://bucket/logs/%s/%s/artifacts", upstream.Job, upstream.ProwJob)
+					if upstream.ArtifactsURL != expectedURL {
+						t.Errorf("expected artifacts URL %s, got %s", expectedURL, upstream.ArtifactsURL)
+					}
+				}
+				if pj.Spec.Type == prowapi.PostsubmitJob && pj.Spec.Refs.BaseSHA != tc.expectedSHA {
+					t.Errorf("expected the postsubmit to run for %s, got %s", tc.expectedSHA, pj.Spec.Refs.BaseSHA)
+				}
+			}
+			if tc.expected == nil {
+				tc.expected = map[string][]string{}
+			}
+			if diff := cmp.Diff(tc.expected, actual); diff != "" {
+				t.Errorf("created jobs differ from expected (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 type createTrackingClient struct {
 	ctrlruntimeclient.Client
 	sawCreate bool
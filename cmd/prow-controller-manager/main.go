@@ -37,6 +37,7 @@ import (
 	"sigs.k8s.io/prow/pkg/pjutil/pprof"
 	"sigs.k8s.io/prow/pkg/scheduler"
 
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
 	"sigs.k8s.io/prow/pkg/flagutil"
 	prowflagutil "sigs.k8s.io/prow/pkg/flagutil"
 	configflagutil "sigs.k8s.io/prow/pkg/flagutil/config"
@@ -46,6 +47,7 @@ import (
 	"sigs.k8s.io/prow/pkg/metrics"
 	"sigs.k8s.io/prow/pkg/plank"
 
+	"sigs.k8s.io/prow/pkg/tracing"
 	_ "sigs.k8s.io/prow/pkg/version"
 )
 
@@ -60,7 +62,7 @@ type options struct {
 
 	dryRun                 bool
 	kubernetes             prowflagutil.KubernetesOptions
-	github                 prowflagutil.GitHubOptions // TODO(fejta): remove
+	github                 prowflagutil.GitHubOptions
 	instrumentationOptions prowflagutil.InstrumentationOptions
 	storage                prowflagutil.StorageClientOptions
 }
@@ -121,6 +123,9 @@ func main() {
 
 	health := pjutil.NewHealthOnPort(o.instrumentationOptions.HealthPort) // Start liveness endpoint
 	pprof.Instrument(o.instrumentationOptions)
+	if err := tracing.Init("plank", o.instrumentationOptions); err != nil {
+		logrus.WithError(err).Fatal("Error initializing tracing.")
+	}
 
 	configAgent, err := o.config.ConfigAgent()
 	if err != nil {
@@ -205,7 +210,21 @@ func main() {
 	}
 
 	if enabledControllersSet.Has(plank.ControllerName) {
-		if err := plank.Add(mgr, buildClusters, knownClusters, cfg, opener, o.totURL, o.selector); err != nil {
+		// The GitHub client looks up the head of the pull requests to preempt
+		// the jobs of their older revisions, enabling the preemption later on
+		// requires a restart.
+		var prs plank.PullRequestGetter
+		if preemption := cfg().Plank.Preemption; preemption.Preempts(prowapi.PresubmitJob) || preemption.Preempts(prowapi.BatchJob) {
+			if o.github.TokenPath == "" && o.github.AppID == "" {
+				logrus.Fatal("Preemption requires GitHub credentials, set --github-token-path or --github-app-id.")
+			}
+			githubClient, err := o.github.GitHubClient(o.dryRun)
+			if err != nil {
+				logrus.WithError(err).Fatal("Error getting GitHub client.")
+			}
+			prs = githubClient
+		}
+		if err := plank.Add(mgr, buildClusters, knownClusters, cfg, opener, o.totURL, o.selector, prs); err != nil {
 			logrus.WithError(err).Fatal("Failed to add plank to manager")
 		}
 	}
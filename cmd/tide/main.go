@@ -40,6 +40,7 @@ import (
 	"sigs.k8s.io/prow/pkg/logrusutil"
 	"sigs.k8s.io/prow/pkg/metrics"
 	"sigs.k8s.io/prow/pkg/tide"
+	"sigs.k8s.io/prow/pkg/tracing"
 )
 
 const (
@@ -71,6 +72,10 @@ type options struct {
 	// a) the gcs credentials can write to this bucket
 	// b) the default acls do not expose any private info
 	historyURI string
+	// historySegmentsURI is the prefix where Tide persists every action to
+	// segments per hour, which can be queried on /history.
+	// Can be /local/path, gs://path/to/dir or s3://path/to/dir.
+	historySegmentsURI string
 
 	// statusURI where Tide store status update state.
 	// Can be a /local/path, gs://path/to/object or s3://path/to/object.
@@ -118,6 +123,7 @@ func gatherOptions(fs *flag.FlagSet, args ...string) options {
 	fs.IntVar(&o.statusThrottle, "status-hourly-tokens", 400, "The maximum number of tokens per hour to be used by the status controller.")
 	fs.IntVar(&o.maxRecordsPerPool, "max-records-per-pool", 1000, "The maximum number of history records stored for an individual Tide pool.")
 	fs.StringVar(&o.historyURI, "history-uri", "", "The /local/path,gs://path/to/object or s3://path/to/object to store tide action history. GCS writes will use the default object ACL for the bucket")
+	fs.StringVar(&o.historySegmentsURI, "history-segments-uri", "", "The /local/path, gs://path/to/dir or s3://path/to/dir to persist every tide action to hourly segments, so that /history can be queried beyond the recent actions. GCS writes will use the default object ACL for the bucket")
 	fs.StringVar(&o.statusURI, "status-path", "", "The /local/path, gs://path/to/object or s3://path/to/object to store status controller state. GCS writes will use the default object ACL for the bucket.")
 	// Gerrit-related flags
 	fs.StringVar(&o.cookiefilePath, "cookiefile", "", "Path to git http.cookiefile; leave empty for anonymous access or if you are using GitHub")
@@ -140,6 +146,9 @@ func main() {
 	}
 
 	pprof.Instrument(o.instrumentationOptions)
+	if err := tracing.Init("tide", o.instrumentationOptions); err != nil {
+		logrus.WithError(err).Fatal("Error initializing tracing.")
+	}
 
 	opener, err := o.storage.StorageClient(context.Background())
 	if err != nil {
@@ -241,6 +250,8 @@ func main() {
 		logrus.Fatalf("Unsupported provider type '%s', this should not happen", provider)
 	}
 
+	c.History().EnableSegments(o.historySegmentsURI)
+
 	interrupts.Run(func(ctx context.Context) {
 		if err := mgr.Start(ctx); err != nil {
 			logrus.WithError(err).Fatal("Mgr failed.")
@@ -182,6 +182,12 @@ type ProwJobSpec struct {
 	// If this field is unspecified or false, a new pod will be created to replace
 	// the evicted one.
 	ErrorOnEviction bool `json:"error_on_eviction,omitempty"`
+	// Retry configures plank to retry the job as a new ProwJob
+	// when it fails for infrastructure reasons.
+	Retry *RetryPolicy `json:"retry,omitempty"`
+	// Upstream are the runs of the jobs this job runs after the
+	// success of, when it was triggered by their completion.
+	Upstream []UpstreamJob `json:"upstream,omitempty"`
 
 	// PodSpec provides the basis for running the test under
 	// a Kubernetes agent
@@ -356,7 +362,66 @@ func (rac *RerunAuthConfig) IsAllowAnyone() bool {
 }
 
 type ReporterConfig struct {
-	Slack *SlackReporterConfig `json:"slack,omitempty"`
+	Slack   *SlackReporterConfig   `json:"slack,omitempty"`
+	Webhook *WebhookReporterConfig `json:"webhook,omitempty"`
+	Lark    *ChatReporterConfig    `json:"lark,omitempty"`
+	Teams   *ChatReporterConfig    `json:"teams,omitempty"`
+}
+
+// ChatReporterConfig configures the reporting of a job as a card posted to
+// a chat channel through an incoming webhook, e.g. on Lark or Teams.
+type ChatReporterConfig struct {
+	// Channel is the name of the incoming webhook of the channel, as listed
+	// in the webhooks file given to crier.
+	Channel string `json:"channel,omitempty"`
+	// JobStatesToReport are the states of the job that are reported. The
+	// final states are reported when empty.
+	JobStatesToReport []ProwJobState `json:"job_states_to_report,omitempty"`
+	// ReportTemplate is a Go template executed against the ProwJob to
+	// produce the text of the card, as the one of the Slack reporter.
+	ReportTemplate string `json:"report_template,omitempty"`
+}
+
+// ShouldReport returns whether the state is one of the states to report.
+func (c *ChatReporterConfig) ShouldReport(state ProwJobState) bool {
+	if len(c.JobStatesToReport) == 0 {
+		return state == SuccessState || state == FailureState || state == ErrorState || state == AbortedState
+	}
+	for _, s := range c.JobStatesToReport {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookReporterConfig configures the reporting of the state changes of a
+// job to an HTTP endpoint.
+type WebhookReporterConfig struct {
+	// URL is the endpoint the payload is POSTed to.
+	URL string `json:"url,omitempty"`
+	// JobStatesToReport are the states of the job that are reported. Every
+	// state change is reported when empty.
+	JobStatesToReport []ProwJobState `json:"job_states_to_report,omitempty"`
+	// PayloadTemplate is a Go template executed against the ProwJob to
+	// produce the payload. Defaults to the ProwJob serialized as JSON.
+	PayloadTemplate string `json:"payload_template,omitempty"`
+	// ContentType is the content type of the payload. Defaults to
+	// application/json.
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// ShouldReport returns whether the state is one of the states to report.
+func (w *WebhookReporterConfig) ShouldReport(state ProwJobState) bool {
+	if len(w.JobStatesToReport) == 0 {
+		return true
+	}
+	for _, s := range w.JobStatesToReport {
+		if s == state {
+			return true
+		}
+	}
+	return false
 }
 
 type SlackReporterConfig struct {
@@ -1121,6 +1186,11 @@ type ProwJobStatus struct {
 	// plank. This field should always be the same as
 	// the ProwJob.ObjectMeta.Name field.
 	PodName string `json:"pod_name,omitempty"`
+	// QueuePosition applies only to triggered ProwJobs queued
+	// by plank's fair-share queueing. This field shows the
+	// position of the job in the queue of its build cluster,
+	// 1 being the next job to start.
+	QueuePosition int `json:"queue_position,omitempty"`
 
 	// BuildID is the build identifier vended either by tot
 	// or the snowflake library for this job and used as an
@@ -1139,6 +1209,117 @@ type ProwJobStatus struct {
 	// PrevReportStates stores the previous reported prowjob state per reporter
 	// So crier won't make duplicated report attempt
 	PrevReportStates map[string]ProwJobState `json:"prev_report_states,omitempty"`
+
+	// Retry records the attempts of a job retried by plank
+	// according to its RetryPolicy.
+	Retry *RetryStatus `json:"retry,omitempty"`
+}
+
+// UpstreamJob is a run of a job a ProwJob was triggered after.
+type UpstreamJob struct {
+	// Job is the name of the upstream job.
+	Job string `json:"job"`
+	// ProwJob is the name of the ProwJob of the upstream run.
+	ProwJob string `json:"prowjob"`
+	// URL links to the upstream run, e.g. in Deck.
+	URL string `json:"url,omitempty"`
+	// ArtifactsURL is the storage URL of the artifacts of the
+	// upstream run, e.g. gs://bucket/logs/job/123/artifacts.
+	ArtifactsURL string `json:"artifacts_url,omitempty"`
+}
+
+// RetryReason is a class of infrastructure failures after
+// which a job can be retried.
+type RetryReason string
+
+const (
+	// RetryOnEviction retries jobs whose pod was evicted,
+	// or whose node was lost.
+	RetryOnEviction RetryReason = "eviction"
+	// RetryOnPodPendingTimeout retries jobs whose pod didn't
+	// get scheduled or didn't start in time, e.g. because
+	// its images could not be pulled.
+	RetryOnPodPendingTimeout RetryReason = "pod_pending_timeout"
+	// RetryOnOOMKilled retries jobs whose pod had a container
+	// killed for running out of memory.
+	RetryOnOOMKilled RetryReason = "oom_killed"
+	// RetryOnExitCode is the reason recorded for jobs retried
+	// because of one of the ExitCodes of their RetryPolicy.
+	RetryOnExitCode RetryReason = "exit_code"
+)
+
+// RetryPolicy configures how a job failing for infrastructure
+// reasons is retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of the job,
+	// including the first one.
+	// +kubebuilder:validation:Minimum=1
+	MaxAttempts int `json:"max_attempts"`
+	// Reasons are the classes of failures that are retried.
+	Reasons []RetryReason `json:"reasons,omitempty"`
+	// ExitCodes are the exit codes of the pod's containers
+	// that are retried, e.g. the one a test harness exits
+	// with when it can't reach the cluster under test.
+	ExitCodes []int32 `json:"exit_codes,omitempty"`
+}
+
+// Retries returns whether the policy retries failures of the
+// given class after the given attempt.
+func (p *RetryPolicy) Retries(reason RetryReason, attempt int) bool {
+	if p == nil || attempt >= p.MaxAttempts {
+		return false
+	}
+	if reason == RetryOnExitCode {
+		return len(p.ExitCodes) > 0
+	}
+	for _, r := range p.Reasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// RetriesExitCode returns whether the policy retries the exit code.
+func (p *RetryPolicy) RetriesExitCode(code int32) bool {
+	if p == nil {
+		return false
+	}
+	for _, c := range p.ExitCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryStatus records the attempts of a retried job.
+type RetryStatus struct {
+	// Attempt is the number of this attempt, starting at 1.
+	Attempt int `json:"attempt"`
+	// PreviousAttempts are the names of the ProwJobs of the
+	// previous attempts, oldest first.
+	PreviousAttempts []string `json:"previous_attempts,omitempty"`
+	// Reason is the class of the failure this attempt was
+	// retried after, if it was.
+	Reason RetryReason `json:"reason,omitempty"`
+	// RetriedBy is the name of the ProwJob of the next attempt,
+	// if this one was retried.
+	RetriedBy string `json:"retried_by,omitempty"`
+}
+
+// Attempt returns the number of the attempt of the job, starting at 1.
+func (j *ProwJob) Attempt() int {
+	if j.Status.Retry == nil || j.Status.Retry.Attempt == 0 {
+		return 1
+	}
+	return j.Status.Retry.Attempt
+}
+
+// Retried returns true if the job failed and was retried by another ProwJob,
+// so that only the final attempt gets reported.
+func (j *ProwJob) Retried() bool {
+	return j.Status.Retry != nil && j.Status.Retry.RetriedBy != ""
 }
 
 // Complete returns true if the prow job has finished
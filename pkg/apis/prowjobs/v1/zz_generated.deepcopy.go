@@ -71,6 +71,27 @@ func (in *CensoringOptions) DeepCopy() *CensoringOptions {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChatReporterConfig) DeepCopyInto(out *ChatReporterConfig) {
+	*out = *in
+	if in.JobStatesToReport != nil {
+		in, out := &in.JobStatesToReport, &out.JobStatesToReport
+		*out = make([]ProwJobState, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChatReporterConfig.
+func (in *ChatReporterConfig) DeepCopy() *ChatReporterConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ChatReporterConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DecorationConfig) DeepCopyInto(out *DecorationConfig) {
 	*out = *in
@@ -432,6 +453,16 @@ func (in *ProwJobSpec) DeepCopyInto(out *ProwJobSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Retry != nil {
+		in, out := &in.Retry, &out.Retry
+		*out = new(RetryPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Upstream != nil {
+		in, out := &in.Upstream, &out.Upstream
+		*out = make([]UpstreamJob, len(*in))
+		copy(*out, *in)
+	}
 	if in.PodSpec != nil {
 		in, out := &in.PodSpec, &out.PodSpec
 		*out = new(corev1.PodSpec)
@@ -504,6 +535,11 @@ func (in *ProwJobStatus) DeepCopyInto(out *ProwJobStatus) {
 			(*out)[key] = val
 		}
 	}
+	if in.Retry != nil {
+		in, out := &in.Retry, &out.Retry
+		*out = new(RetryStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -588,6 +624,21 @@ func (in *ReporterConfig) DeepCopyInto(out *ReporterConfig) {
 		*out = new(SlackReporterConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Webhook != nil {
+		in, out := &in.Webhook, &out.Webhook
+		*out = new(WebhookReporterConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Lark != nil {
+		in, out := &in.Lark, &out.Lark
+		*out = new(ChatReporterConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Teams != nil {
+		in, out := &in.Teams, &out.Teams
+		*out = new(ChatReporterConfig)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -689,6 +740,53 @@ func (in *ResultStoreConfig) DeepCopy() *ResultStoreConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetryPolicy) DeepCopyInto(out *RetryPolicy) {
+	*out = *in
+	if in.Reasons != nil {
+		in, out := &in.Reasons, &out.Reasons
+		*out = make([]RetryReason, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExitCodes != nil {
+		in, out := &in.ExitCodes, &out.ExitCodes
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetryPolicy.
+func (in *RetryPolicy) DeepCopy() *RetryPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RetryPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetryStatus) DeepCopyInto(out *RetryStatus) {
+	*out = *in
+	if in.PreviousAttempts != nil {
+		in, out := &in.PreviousAttempts, &out.PreviousAttempts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetryStatus.
+func (in *RetryStatus) DeepCopy() *RetryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RetryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SchedulingOptions) DeepCopyInto(out *SchedulingOptions) {
 	*out = *in
@@ -764,6 +862,22 @@ func (in *TektonPipelineRunSpec) DeepCopy() *TektonPipelineRunSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpstreamJob) DeepCopyInto(out *UpstreamJob) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpstreamJob.
+func (in *UpstreamJob) DeepCopy() *UpstreamJob {
+	if in == nil {
+		return nil
+	}
+	out := new(UpstreamJob)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UtilityImages) DeepCopyInto(out *UtilityImages) {
 	*out = *in
@@ -779,3 +893,24 @@ func (in *UtilityImages) DeepCopy() *UtilityImages {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookReporterConfig) DeepCopyInto(out *WebhookReporterConfig) {
+	*out = *in
+	if in.JobStatesToReport != nil {
+		in, out := &in.JobStatesToReport, &out.JobStatesToReport
+		*out = make([]ProwJobState, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookReporterConfig.
+func (in *WebhookReporterConfig) DeepCopy() *WebhookReporterConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookReporterConfig)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,398 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package artifactgc deletes the logs and artifacts of the job runs that their
+// retention policy doesn't keep anymore from job storage.
+package artifactgc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	stdio "io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/testgrid/metadata"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"k8s.io/utils/clock"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/io"
+	"sigs.k8s.io/prow/pkg/io/providers"
+	"sigs.k8s.io/prow/pkg/pod-utils/gcs"
+)
+
+var artifactGCMetrics = struct {
+	runsDeleted    *prometheus.CounterVec
+	bytesReclaimed *prometheus.CounterVec
+	syncDuration   prometheus.Gauge
+}{
+	runsDeleted: prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "artifact_gc_runs_deleted_total",
+		Help: "Number of job runs deleted from job storage, or that would have been in dry-run.",
+	}, []string{"bucket", "type", "dry_run"}),
+	bytesReclaimed: prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "artifact_gc_bytes_reclaimed_total",
+		Help: "Bytes of logs and artifacts deleted from job storage, or that would have been in dry-run.",
+	}, []string{"bucket", "type", "dry_run"}),
+	syncDuration: prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "artifact_gc_sync_duration_seconds",
+		Help: "Duration of the last walk of job storage.",
+	}),
+}
+
+func init() {
+	prometheus.MustRegister(artifactGCMetrics.runsDeleted)
+	prometheus.MustRegister(artifactGCMetrics.bytesReclaimed)
+	prometheus.MustRegister(artifactGCMetrics.syncDuration)
+}
+
+// Run is a run of a job in job storage.
+type Run struct {
+	// Path is the directory of the run, e.g. gs://bucket/logs/job/1234/.
+	Path string `json:"path"`
+	Job  string `json:"job"`
+	// Type is read from the prowjob.json of the run, or derived from its path.
+	Type     prowapi.ProwJobType `json:"type"`
+	Result   string              `json:"result"`
+	Finished time.Time           `json:"finished"`
+	// Bytes is the size of the logs and artifacts of the run, only known for
+	// the runs to delete.
+	Bytes int64 `json:"bytes,omitempty"`
+
+	// alias is the path of the link to the run of a presubmit, if any.
+	alias string
+}
+
+// Report lists the runs deleted by a garbage collection.
+type Report struct {
+	// DryRun is true if the runs were only listed, not deleted.
+	DryRun bool  `json:"dry_run"`
+	Runs   []Run `json:"runs"`
+	Bytes  int64 `json:"bytes"`
+}
+
+// Controller walks the job storage and deletes the runs that their retention
+// policy doesn't keep anymore.
+type Controller struct {
+	opener io.Opener
+	config config.Getter
+	dryRun bool
+	clock  clock.Clock
+	logger *logrus.Entry
+
+	// known are the finished runs read by the last sync, by directory. The
+	// metadata of a finished run doesn't change, so their finished.json and
+	// prowjob.json aren't read again.
+	known map[string]Run
+}
+
+// NewController returns a controller deleting runs with the opener. In dry-run
+// the runs to delete are only reported.
+func NewController(opener io.Opener, cfg config.Getter, dryRun bool) *Controller {
+	return &Controller{
+		opener: opener,
+		config: cfg,
+		dryRun: dryRun,
+		clock:  clock.RealClock{},
+		logger: logrus.WithField("component", "artifact-gc"),
+	}
+}
+
+// Sync walks the configured buckets once and deletes the runs to delete.
+func (c *Controller) Sync(ctx context.Context) (*Report, error) {
+	start := c.clock.Now()
+	defer func() {
+		artifactGCMetrics.syncDuration.Set(c.clock.Since(start).Seconds())
+	}()
+
+	gc := c.config().ArtifactGC
+	report := &Report{DryRun: c.dryRun}
+	var errs []error
+	// Only the runs still found are remembered, so that the deleted ones are
+	// forgotten.
+	known := map[string]Run{}
+	defer func() { c.known = known }()
+	for _, bucket := range gc.Buckets {
+		log := c.logger.WithField("bucket", bucket)
+		runs, err := c.runs(ctx, bucket, known)
+		if err != nil {
+			// Still collect the runs found, the others are collected next time.
+			log.WithError(err).Warn("Failed to list some runs.")
+			errs = append(errs, err)
+		}
+		for _, run := range c.toDelete(&gc, runs) {
+			if err := c.delete(ctx, &run); err != nil {
+				log.WithError(err).WithField("run", run.Path).Warn("Failed to delete run.")
+				errs = append(errs, err)
+				continue
+			}
+			log.WithFields(logrus.Fields{"run": run.Path, "result": run.Result, "finished": run.Finished, "bytes": run.Bytes, "dry-run": c.dryRun}).Info("Deleted run.")
+			artifactGCMetrics.runsDeleted.WithLabelValues(bucket, string(run.Type), boolLabel(c.dryRun)).Inc()
+			artifactGCMetrics.bytesReclaimed.WithLabelValues(bucket, string(run.Type), boolLabel(c.dryRun)).Add(float64(run.Bytes))
+			report.Runs = append(report.Runs, run)
+			report.Bytes += run.Bytes
+		}
+	}
+	return report, errors.Join(errs...)
+}
+
+// toDelete returns the runs their policy doesn't keep anymore.
+func (c *Controller) toDelete(gc *config.ArtifactGC, runs []Run) []Run {
+	// The runs of a job are counted per policy, so that e.g. the failures
+	// kept by a policy don't count towards the last runs of another.
+	type key struct {
+		policy *config.RetentionPolicy
+		job    string
+	}
+	byPolicy := map[key][]Run{}
+	var keys []key
+	for _, run := range runs {
+		policy := gc.Policy(run.Job, run.Type, run.Result)
+		if policy == nil || (policy.KeepLast == 0 && policy.MaxAge == nil) {
+			continue
+		}
+		k := key{policy: policy, job: run.Job}
+		if _, ok := byPolicy[k]; !ok {
+			keys = append(keys, k)
+		}
+		byPolicy[k] = append(byPolicy[k], run)
+	}
+
+	now := c.clock.Now()
+	var toDelete []Run
+	for _, k := range keys {
+		runs := byPolicy[k]
+		sort.SliceStable(runs, func(i, j int) bool { return runs[i].Finished.After(runs[j].Finished) })
+		for i, run := range runs {
+			if i < k.policy.KeepLast {
+				continue
+			}
+			if k.policy.MaxAge != nil && now.Sub(run.Finished) <= k.policy.MaxAge.Duration {
+				continue
+			}
+			toDelete = append(toDelete, run)
+		}
+	}
+	return toDelete
+}
+
+// delete deletes the objects of the run, or only sums their size in dry-run.
+func (c *Controller) delete(ctx context.Context, run *Run) error {
+	it, err := c.opener.Iterator(ctx, run.Path, "")
+	if err != nil {
+		return err
+	}
+	var objects []string
+	for {
+		attrs, err := it.Next(ctx)
+		if errors.Is(err, stdio.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		run.Bytes += attrs.Size
+		objects = append(objects, attrs.Name)
+	}
+	if c.dryRun {
+		return nil
+	}
+
+	storageProvider, bucket, _, err := providers.ParseStoragePath(run.Path)
+	if err != nil {
+		return err
+	}
+	// finished.json is deleted last, so that a run whose deletion failed is
+	// still found and deleted next time.
+	sort.Slice(objects, func(i, j int) bool {
+		finishedI, finishedJ := path.Base(objects[i]) == "finished.json", path.Base(objects[j]) == "finished.json"
+		if finishedI != finishedJ {
+			return finishedJ
+		}
+		return objects[i] < objects[j]
+	})
+	for _, object := range objects {
+		if err := c.opener.Delete(ctx, storageProvider+"://"+bucket+"/"+object); err != nil && !io.IsNotExist(err) {
+			return err
+		}
+	}
+	if run.alias != "" {
+		if err := c.opener.Delete(ctx, run.alias); err != nil && !io.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// runs lists the finished runs of the bucket, following the layout of
+// gcsupload: logs/<job>/<build> for the periodics and postsubmits,
+// pr-logs/pull/batch/<job>/<build> for the batches and
+// pr-logs/pull/<org_repo>/<pr>/<job>/<build> for the presubmits. The runs
+// found are added to known.
+func (c *Controller) runs(ctx context.Context, bucket string, known map[string]Run) ([]Run, error) {
+	bucket = strings.TrimSuffix(bucket, "/")
+	var runs []Run
+	var errs []error
+	add := func(jobDir string, jobType prowapi.ProwJobType) {
+		builds, err := c.dirs(ctx, jobDir)
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		job := path.Base(jobDir)
+		for _, build := range builds {
+			run, ok := c.known[build]
+			if !ok {
+				read, err := c.run(ctx, build, job, jobType)
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				if read == nil {
+					continue
+				}
+				run = *read
+			}
+			known[build] = run
+			if jobType == prowapi.PresubmitJob {
+				run.alias = bucket + "/" + path.Join(gcs.PRLogs, "directory", job, path.Base(build)+".txt")
+			}
+			runs = append(runs, run)
+		}
+	}
+
+	jobs, err := c.dirs(ctx, bucket+"/"+gcs.NonPRLogs+"/")
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, job := range jobs {
+		add(job, prowapi.PeriodicJob)
+	}
+
+	repos, err := c.dirs(ctx, bucket+"/"+path.Join(gcs.PRLogs, "pull")+"/")
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, repo := range repos {
+		if path.Base(repo) == "batch" {
+			jobs, err := c.dirs(ctx, repo)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			for _, job := range jobs {
+				add(job, prowapi.BatchJob)
+			}
+			continue
+		}
+		pulls, err := c.dirs(ctx, repo)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, pull := range pulls {
+			jobs, err := c.dirs(ctx, pull)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			for _, job := range jobs {
+				add(job, prowapi.PresubmitJob)
+			}
+		}
+	}
+	return runs, errors.Join(errs...)
+}
+
+// run reads the run in the directory. The type of the job is read from its
+// prowjob.json if any, the jobType derived from the path is used otherwise.
+// Runs that didn't finish are skipped.
+func (c *Controller) run(ctx context.Context, dir, job string, jobType prowapi.ProwJobType) (*Run, error) {
+	var finished metadata.Finished
+	if err := c.readJSON(ctx, dir+"finished.json", &finished); err != nil {
+		if io.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if finished.Timestamp == nil {
+		return nil, nil
+	}
+	var pj prowapi.ProwJob
+	if err := c.readJSON(ctx, dir+"prowjob.json", &pj); err == nil && pj.Spec.Type != "" {
+		jobType = pj.Spec.Type
+	} else if err != nil && !io.IsNotExist(err) {
+		return nil, err
+	}
+
+	result := finished.Result
+	if result == "" && finished.Passed != nil {
+		result = "FAILURE"
+		if *finished.Passed {
+			result = "SUCCESS"
+		}
+	}
+	return &Run{
+		Path:     dir,
+		Job:      job,
+		Type:     jobType,
+		Result:   result,
+		Finished: time.Unix(*finished.Timestamp, 0),
+	}, nil
+}
+
+func (c *Controller) readJSON(ctx context.Context, path string, v interface{}) error {
+	content, err := io.ReadContent(ctx, c.logger, c.opener, path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, v)
+}
+
+// dirs lists the directories in the directory, with a trailing slash.
+func (c *Controller) dirs(ctx context.Context, dir string) ([]string, error) {
+	storageProvider, bucket, _, err := providers.ParseStoragePath(dir)
+	if err != nil {
+		return nil, err
+	}
+	it, err := c.opener.Iterator(ctx, dir, "/")
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for {
+		attrs, err := it.Next(ctx)
+		if errors.Is(err, stdio.EOF) {
+			return dirs, nil
+		}
+		if err != nil {
+			return dirs, err
+		}
+		if attrs.IsDir {
+			dirs = append(dirs, storageProvider+"://"+bucket+"/"+attrs.Name)
+		}
+	}
+}
+
+func boolLabel(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
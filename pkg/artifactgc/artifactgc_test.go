@@ -0,0 +1,220 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package artifactgc
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/fsouza/fake-gcs-server/fakestorage"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/api/iterator"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/io"
+)
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func finished(age time.Duration, result string) []byte {
+	return []byte(fmt.Sprintf(`{"timestamp": %d, "result": %q}`, now.Add(-age).Unix(), result))
+}
+
+func TestSync(t *testing.T) {
+	day := 24 * time.Hour
+	objects := []fakestorage.Object{
+		{Name: "logs/ci-job/1/finished.json", Content: finished(4*day, "SUCCESS")},
+		{Name: "logs/ci-job/1/build-log.txt", Content: []byte("0123456789")},
+		{Name: "logs/ci-job/2/finished.json", Content: []byte(fmt.Sprintf(`{"timestamp": %d, "passed": false}`, now.Add(-3*day).Unix()))},
+		{Name: "logs/ci-job/2/artifacts/junit.xml", Content: []byte("<testsuites/>")},
+		{Name: "logs/ci-job/3/finished.json", Content: finished(2*day, "SUCCESS")},
+		{Name: "logs/ci-job/4/finished.json", Content: finished(day, "SUCCESS")},
+		// Still running.
+		{Name: "logs/ci-job/5/started.json", Content: []byte("{}")},
+		// The type is read from prowjob.json.
+		{Name: "logs/post-job/1/finished.json", Content: finished(100*day, "SUCCESS")},
+		{Name: "logs/post-job/1/prowjob.json", Content: []byte(`{"spec": {"type": "postsubmit"}}`)},
+		{Name: "pr-logs/pull/org_repo/12/pull-job/100/finished.json", Content: finished(100*day, "FAILURE")},
+		{Name: "pr-logs/pull/org_repo/12/pull-job/101/finished.json", Content: finished(10*day, "FAILURE")},
+		{Name: "pr-logs/pull/org_repo/12/pull-job/102/finished.json", Content: finished(10*day, "SUCCESS")},
+		{Name: "pr-logs/pull/org_repo/13/pull-job/103/finished.json", Content: finished(day, "SUCCESS")},
+		{Name: "pr-logs/directory/pull-job/100.txt", Content: []byte("gs://bucket/pr-logs/pull/org_repo/12/pull-job/100")},
+		{Name: "pr-logs/directory/pull-job/102.txt", Content: []byte("gs://bucket/pr-logs/pull/org_repo/12/pull-job/102")},
+		{Name: "pr-logs/pull/batch/batch-job/200/finished.json", Content: finished(10*day, "SUCCESS")},
+	}
+	for i := range objects {
+		objects[i].BucketName = "bucket"
+	}
+	cfg := &config.Config{ProwConfig: config.ProwConfig{ArtifactGC: config.ArtifactGC{
+		Buckets: []string{"gs://bucket"},
+		Policies: []config.RetentionPolicy{
+			{Types: []prowapi.ProwJobType{prowapi.PeriodicJob}, KeepLast: 2},
+			{Types: []prowapi.ProwJobType{prowapi.PresubmitJob}, Results: []string{"FAILURE"}, MaxAge: &metav1.Duration{Duration: 90 * day}},
+			{Types: []prowapi.ProwJobType{prowapi.PresubmitJob, prowapi.BatchJob}, MaxAge: &metav1.Duration{Duration: 7 * day}},
+		},
+	}}}
+
+	testCases := []struct {
+		name              string
+		dryRun            bool
+		expectedRuns      []string
+		expectedBytes     int64
+		expectedRemaining []string
+	}{
+		{
+			name:   "dry-run only reports the runs to delete",
+			dryRun: true,
+			expectedRuns: []string{
+				"gs://bucket/logs/ci-job/1/",
+				"gs://bucket/logs/ci-job/2/",
+				"gs://bucket/pr-logs/pull/batch/batch-job/200/",
+				"gs://bucket/pr-logs/pull/org_repo/12/pull-job/100/",
+				"gs://bucket/pr-logs/pull/org_repo/12/pull-job/102/",
+			},
+			expectedBytes: 10 + 13 + int64(len(objects[0].Content)+len(objects[2].Content)+len(objects[9].Content)+len(objects[11].Content)+len(objects[15].Content)),
+		},
+		{
+			name: "deletes the runs",
+			expectedRuns: []string{
+				"gs://bucket/logs/ci-job/1/",
+				"gs://bucket/logs/ci-job/2/",
+				"gs://bucket/pr-logs/pull/batch/batch-job/200/",
+				"gs://bucket/pr-logs/pull/org_repo/12/pull-job/100/",
+				"gs://bucket/pr-logs/pull/org_repo/12/pull-job/102/",
+			},
+			expectedBytes: 10 + 13 + int64(len(objects[0].Content)+len(objects[2].Content)+len(objects[9].Content)+len(objects[11].Content)+len(objects[15].Content)),
+			expectedRemaining: []string{
+				"logs/ci-job/3/finished.json",
+				"logs/ci-job/4/finished.json",
+				"logs/ci-job/5/started.json",
+				"logs/post-job/1/finished.json",
+				"logs/post-job/1/prowjob.json",
+				"pr-logs/pull/org_repo/12/pull-job/101/finished.json",
+				"pr-logs/pull/org_repo/13/pull-job/103/finished.json",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := fakestorage.NewServer(objects)
+			defer server.Stop()
+			client := server.Client()
+
+			c := &Controller{
+				opener: io.NewGCSOpener(client),
+				config: func() *config.Config { return cfg },
+				dryRun: tc.dryRun,
+				clock:  clocktesting.NewFakeClock(now),
+				logger: logrus.WithField("test", tc.name),
+			}
+			report, err := c.Sync(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var runs []string
+			for _, run := range report.Runs {
+				runs = append(runs, run.Path)
+			}
+			sort.Strings(runs)
+			if diff := cmp.Diff(tc.expectedRuns, runs); diff != "" {
+				t.Errorf("deleted runs differ from expected (-want +got):\n%s", diff)
+			}
+			if report.Bytes != tc.expectedBytes {
+				t.Errorf("expected %d bytes reclaimed, got %d", tc.expectedBytes, report.Bytes)
+			}
+
+			var remaining []string
+			it := client.Bucket("bucket").Objects(context.Background(), nil)
+			for {
+				attrs, err := it.Next()
+				if err == iterator.Done {
+					break
+				}
+				if err != nil {
+					t.Fatalf("failed to list objects: %v", err)
+				}
+				remaining = append(remaining, attrs.Name)
+			}
+			sort.Strings(remaining)
+			expectedRemaining := tc.expectedRemaining
+			if tc.dryRun {
+				for _, o := range objects {
+					expectedRemaining = append(expectedRemaining, o.Name)
+				}
+				sort.Strings(expectedRemaining)
+			}
+			if diff := cmp.Diff(expectedRemaining, remaining); diff != "" {
+				t.Errorf("remaining objects differ from expected (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+type countingOpener struct {
+	io.Opener
+	reads int
+}
+
+func (o *countingOpener) Reader(ctx context.Context, path string) (io.ReadCloser, error) {
+	o.reads++
+	return o.Opener.Reader(ctx, path)
+}
+
+func TestSyncRemembersFinishedRuns(t *testing.T) {
+	objects := []fakestorage.Object{
+		{BucketName: "bucket", Name: "logs/ci-job/1/finished.json", Content: finished(2*time.Hour, "SUCCESS")},
+		{BucketName: "bucket", Name: "logs/ci-job/2/finished.json", Content: finished(time.Hour, "SUCCESS")},
+		{BucketName: "bucket", Name: "logs/ci-job/3/started.json", Content: []byte("{}")},
+	}
+	server := fakestorage.NewServer(objects)
+	defer server.Stop()
+	cfg := &config.Config{ProwConfig: config.ProwConfig{ArtifactGC: config.ArtifactGC{
+		Buckets:  []string{"gs://bucket"},
+		Policies: []config.RetentionPolicy{{KeepLast: 1}},
+	}}}
+	opener := &countingOpener{Opener: io.NewGCSOpener(server.Client())}
+	c := &Controller{
+		opener: opener,
+		config: func() *config.Config { return cfg },
+		dryRun: true,
+		clock:  clocktesting.NewFakeClock(now),
+		logger: logrus.WithField("test", t.Name()),
+	}
+
+	for i, expectedReads := range []int{5, 1} {
+		opener.reads = 0
+		report, err := c.Sync(context.Background())
+		if err != nil {
+			t.Fatalf("sync %d: unexpected error: %v", i, err)
+		}
+		if len(report.Runs) != 1 || report.Runs[0].Path != "gs://bucket/logs/ci-job/1/" {
+			t.Errorf("sync %d: expected to delete gs://bucket/logs/ci-job/1/, got %+v", i, report.Runs)
+		}
+		// The finished.json and prowjob.json of the finished runs are only read
+		// by the first sync, the run that didn't finish is read again.
+		if opener.reads != expectedReads {
+			t.Errorf("sync %d: expected %d reads, got %d", i, expectedReads, opener.reads)
+		}
+	}
+}
@@ -30,12 +30,15 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime/debug"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"text/template"
 	"time"
+	// The timezones of periodics must load in images without a tz database.
+	_ "time/tzdata"
 
 	gitignore "github.com/denormal/go-gitignore"
 	"github.com/google/go-cmp/cmp"
@@ -144,10 +147,12 @@ type ProwConfig struct {
 	Tide                 Tide                 `json:"tide,omitempty"`
 	Plank                Plank                `json:"plank,omitempty"`
 	Sinker               Sinker               `json:"sinker,omitempty"`
+	ArtifactGC           ArtifactGC           `json:"artifact_gc,omitempty"`
 	Deck                 Deck                 `json:"deck,omitempty"`
 	BranchProtection     BranchProtection     `json:"branch-protection"`
 	Gerrit               Gerrit               `json:"gerrit"`
 	GitHubReporter       GitHubReporter       `json:"github_reporter"`
+	WebhookReporter      WebhookReporter      `json:"webhook_reporter,omitempty"`
 	Horologium           Horologium           `json:"horologium"`
 	SlackReporterConfigs SlackReporterConfigs `json:"slack_reporter_configs,omitempty"`
 	InRepoConfig         InRepoConfig         `json:"in_repo_config"`
@@ -714,6 +719,126 @@ type Plank struct {
 	// limit. An example use case would be easier scheduling of jobs using boskos resources.
 	// This mechanism is separate from ProwJob's MaxConcurrency setting.
 	JobQueueCapacities map[string]int `json:"job_queue_capacities,omitempty"`
+
+	// FairShare configures fair-share queueing of the jobs of the build clusters
+	// with a resource budget, so that a busy repo can't starve the others.
+	FairShare *FairShare `json:"fair_share,omitempty"`
+
+	// Preemption configures the aborting of the jobs superseded by a newer
+	// revision of the pull requests or Gerrit changes they test. Older runs of
+	// the same presubmit for the same pull requests are always aborted.
+	Preemption *Preemption `json:"preemption,omitempty"`
+}
+
+// Preemption configures which jobs get aborted when a pull request or Gerrit
+// change they test gets a newer revision, which is detected when a presubmit
+// testing the newer revision gets created.
+type Preemption struct {
+	// Presubmits aborts the presubmits testing an older revision of a pull
+	// request or Gerrit change, including those the new revision doesn't run.
+	Presubmits bool `json:"presubmits,omitempty"`
+	// Batches aborts the batch jobs testing an older revision of any of their
+	// pull requests, as their result can't be used to merge them anymore.
+	Batches bool `json:"batches,omitempty"`
+}
+
+// Preempts returns whether jobs of the type get aborted once superseded.
+func (p *Preemption) Preempts(jobType prowapi.ProwJobType) bool {
+	if p == nil {
+		return false
+	}
+	switch jobType {
+	case prowapi.PresubmitJob:
+		return p.Presubmits
+	case prowapi.BatchJob:
+		return p.Batches
+	}
+	return false
+}
+
+// FairShare configures hierarchical fair-share queueing of ProwJobs. The
+// triggered jobs of a build cluster are started in an order that shares its
+// budget between the orgs, then between the repos of an org, then between the
+// jobs of a repo, in proportion of their weights. The share of each is the
+// largest fraction of the CPU or memory budget requested by its pending jobs.
+type FairShare struct {
+	// Budgets are the CPU and memory the pods of the jobs of each build cluster
+	// can request, keyed by cluster alias. Only the jobs of the clusters with a
+	// budget are queued. The requests of a job are derived from its pod spec.
+	Budgets map[string]v1.ResourceList `json:"budgets,omitempty"`
+	// OrgWeights are the weights of the orgs, 1 by default.
+	OrgWeights map[string]int `json:"org_weights,omitempty"`
+	// RepoWeights are the weights of the repos keyed by org/repo, 1 by default.
+	RepoWeights map[string]int `json:"repo_weights,omitempty"`
+	// JobWeights are the weights of the jobs keyed by name, 1 by default.
+	JobWeights map[string]int `json:"job_weights,omitempty"`
+	// PriorityJobTypes are the types of jobs started before the others
+	// regardless of their share, highest priority first.
+	// Defaults to postsubmit then periodic.
+	PriorityJobTypes []prowapi.ProwJobType `json:"priority_job_types,omitempty"`
+}
+
+// OrgWeight returns the weight of the org.
+func (f *FairShare) OrgWeight(org string) int {
+	return weight(f.OrgWeights, org)
+}
+
+// RepoWeight returns the weight of the org/repo.
+func (f *FairShare) RepoWeight(orgRepo string) int {
+	return weight(f.RepoWeights, orgRepo)
+}
+
+// JobWeight returns the weight of the job.
+func (f *FairShare) JobWeight(job string) int {
+	return weight(f.JobWeights, job)
+}
+
+func weight(weights map[string]int, key string) int {
+	if w, ok := weights[key]; ok {
+		return w
+	}
+	return 1
+}
+
+// Priority returns the priority of the job type, lower first.
+func (f *FairShare) Priority(jobType prowapi.ProwJobType) int {
+	for i, t := range f.PriorityJobTypes {
+		if t == jobType {
+			return i
+		}
+	}
+	return len(f.PriorityJobTypes)
+}
+
+func (f *FairShare) defaultAndValidate() error {
+	if f.PriorityJobTypes == nil {
+		f.PriorityJobTypes = []prowapi.ProwJobType{prowapi.PostsubmitJob, prowapi.PeriodicJob}
+	}
+	for cluster, budget := range f.Budgets {
+		for name, quantity := range budget {
+			if name != v1.ResourceCPU && name != v1.ResourceMemory {
+				return fmt.Errorf("budgets.%s: unsupported resource %q, only cpu and memory are", cluster, name)
+			}
+			if quantity.Sign() <= 0 {
+				return fmt.Errorf("budgets.%s.%s must be positive, got %s", cluster, name, quantity.String())
+			}
+		}
+	}
+	for field, weights := range map[string]map[string]int{"org_weights": f.OrgWeights, "repo_weights": f.RepoWeights, "job_weights": f.JobWeights} {
+		for key, w := range weights {
+			if w <= 0 {
+				return fmt.Errorf("%s.%s must be positive, got %d", field, key, w)
+			}
+		}
+	}
+	for _, t := range f.PriorityJobTypes {
+		switch t {
+		case prowapi.PresubmitJob, prowapi.PostsubmitJob, prowapi.PeriodicJob, prowapi.BatchJob:
+		default:
+			return fmt.Errorf("priority_job_types: invalid job type %q", t)
+		}
+	}
+	return nil
 }
 
 type ProwJobDefaultEntry struct {
@@ -1047,6 +1172,81 @@ type Horologium struct {
 	// TickInterval is the interval in which we check if new jobs need to be
 	// created. Defaults to one minute.
 	TickInterval *metav1.Duration `json:"tick_interval,omitempty"`
+	// ExclusionWindows are periods during which the periodics referencing
+	// them by name aren't triggered, e.g. a release freeze or weekends.
+	ExclusionWindows map[string]ExclusionWindow `json:"exclusion_windows,omitempty"`
+}
+
+// ExclusionWindow is a period during which periodics aren't triggered. A time
+// is in the window if it matches any of its fields. Weekdays and dates are
+// evaluated in the time zone of each periodic.
+type ExclusionWindow struct {
+	// Start and End bound a one-off window, e.g. a release freeze.
+	Start *metav1.Time `json:"start,omitempty"`
+	End   *metav1.Time `json:"end,omitempty"`
+	// Weekdays are whole days of the week, e.g. Saturday and Sunday.
+	Weekdays []string `json:"weekdays,omitempty"`
+	// Dates are whole days formatted as 2006-01-02, e.g. holidays.
+	Dates []string `json:"dates,omitempty"`
+}
+
+const exclusionDateFormat = "2006-01-02"
+
+// Contains returns whether the time is in the window.
+func (w *ExclusionWindow) Contains(t time.Time) bool {
+	if w.Start != nil && w.End != nil && !t.Before(w.Start.Time) && t.Before(w.End.Time) {
+		return true
+	}
+	for _, day := range w.Weekdays {
+		if day == t.Weekday().String() {
+			return true
+		}
+	}
+	for _, date := range w.Dates {
+		if date == t.Format(exclusionDateFormat) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *ExclusionWindow) validate() error {
+	if (w.Start == nil) != (w.End == nil) {
+		return errors.New("start and end must be set together")
+	}
+	if w.Start != nil && !w.Start.Before(w.End) {
+		return fmt.Errorf("start %s must be before end %s", w.Start.Format(time.RFC3339), w.End.Format(time.RFC3339))
+	}
+	if w.Start == nil && len(w.Weekdays) == 0 && len(w.Dates) == 0 {
+		return errors.New("at least one of start and end, weekdays or dates must be set")
+	}
+	for _, day := range w.Weekdays {
+		valid := false
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			valid = valid || day == d.String()
+		}
+		if !valid {
+			return fmt.Errorf("invalid weekday %q", day)
+		}
+	}
+	for _, date := range w.Dates {
+		if _, err := time.Parse(exclusionDateFormat, date); err != nil {
+			return fmt.Errorf("invalid date %q, must be formatted as %s", date, exclusionDateFormat)
+		}
+	}
+	return nil
+}
+
+// ExcludingWindow returns the name of the exclusion window of the periodic the
+// time is in, if any.
+func (h *Horologium) ExcludingWindow(p *Periodic, t time.Time) string {
+	t = t.In(p.GetLocation())
+	for _, name := range p.ExclusionWindows {
+		if w, ok := h.ExclusionWindows[name]; ok && w.Contains(t) {
+			return name
+		}
+	}
+	return ""
 }
 
 // JenkinsOperator is config for the jenkins-operator controller.
@@ -1079,6 +1279,86 @@ type GitHubReporter struct {
 	// comments is only sent when all jobs from current SHA are finished. Status
 	// contexts will still be written.
 	SummaryCommentRepos []string `json:"summary_comment_repos,omitempty"`
+	// CheckRunRepos is a list of orgs and org/repos for which jobs are also
+	// reported as GitHub check runs, with a summary of the results, annotations
+	// on the lines of the failing tests and a button to re-run the job.
+	// Check runs can only be written when authenticating as a GitHub App.
+	CheckRunRepos []string `json:"check_run_repos,omitempty"`
+	// NoStatusContextRepos is a list of orgs and org/repos for which status
+	// contexts should not be written, usually once they moved to check runs.
+	NoStatusContextRepos []string `json:"no_status_context_repos,omitempty"`
+}
+
+// ReportsCheckRuns returns whether the jobs of the repo are reported as check runs.
+func (r *GitHubReporter) ReportsCheckRuns(org, repo string) bool {
+	return matchesOrgRepo(r.CheckRunRepos, org, repo)
+}
+
+// ReportsStatusContexts returns whether the jobs of the repo are reported as status contexts.
+func (r *GitHubReporter) ReportsStatusContexts(org, repo string) bool {
+	return !matchesOrgRepo(r.NoStatusContextRepos, org, repo)
+}
+
+// WebhookReporter holds the config of the webhook reporter of crier.
+type WebhookReporter struct {
+	// AllowedURLPrefixes are the URL prefixes, e.g. https://hooks.example.com/prow/,
+	// that the jobs can set as their reporter_config.webhook.url. The scheme and
+	// the host must match exactly and the path must be within the path of the
+	// prefix. No webhook is allowed when empty.
+	AllowedURLPrefixes []string `json:"allowed_url_prefixes,omitempty"`
+}
+
+// Allows returns whether the webhook URL is within one of the allowed prefixes.
+// URLs with dot segments are never allowed, as they can escape the prefix once
+// resolved by the server.
+func (r *WebhookReporter) Allows(webhookURL string) bool {
+	u, err := url.Parse(webhookURL)
+	if err != nil || u.Opaque != "" || hasDotSegment(u.Path) || hasDotSegment(u.EscapedPath()) {
+		return false
+	}
+	for _, prefix := range r.AllowedURLPrefixes {
+		p, err := url.Parse(prefix)
+		if err != nil || u.Scheme != p.Scheme || !strings.EqualFold(u.Host, p.Host) || u.User != nil {
+			continue
+		}
+		dir := strings.TrimSuffix(p.Path, "/")
+		if dir == "" || u.Path == dir || strings.HasPrefix(u.Path, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasDotSegment returns whether the URL path has a "." or ".." segment,
+// including with backslashes as separators.
+func hasDotSegment(urlPath string) bool {
+	for _, segment := range strings.FieldsFunc(urlPath, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if segment == "." || segment == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *WebhookReporter) validate() error {
+	for _, prefix := range r.AllowedURLPrefixes {
+		u, err := url.Parse(prefix)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook_reporter.allowed_url_prefixes: %q is not a valid http(s) URL", prefix)
+		}
+	}
+	return nil
+}
+
+// matchesOrgRepo returns whether the repo or its org is in the list of orgs and org/repos.
+func matchesOrgRepo(idents []string, org, repo string) bool {
+	fullRepo := fmt.Sprintf("%s/%s", org, repo)
+	for _, ident := range idents {
+		if ident == org || ident == fullRepo {
+			return true
+		}
+	}
+	return false
 }
 
 // Sinker is config for the sinker controller.
@@ -1100,6 +1380,101 @@ type Sinker struct {
 	ExcludeClusters []string `json:"exclude_clusters,omitempty"`
 }
 
+// ArtifactGC is config for the artifact-gc controller, which deletes the logs
+// and artifacts of old job runs from job storage.
+type ArtifactGC struct {
+	// ResyncPeriod is how often the controller walks the job storage.
+	// Defaults to one day.
+	ResyncPeriod *metav1.Duration `json:"resync_period,omitempty"`
+	// Buckets are the buckets whose job runs are garbage-collected, e.g.
+	// gs://prow-logs or s3://prow-logs.
+	Buckets []string `json:"buckets,omitempty"`
+	// Policies are the retention policies of the job runs. The first policy
+	// matching a run applies, the runs matched by no policy are kept.
+	Policies []RetentionPolicy `json:"policies,omitempty"`
+}
+
+// RetentionPolicy is how long the runs of the jobs it matches are kept. A run
+// is deleted if it is neither one of the KeepLast last runs of its job nor
+// younger than MaxAge. A policy setting neither keeps the runs forever.
+type RetentionPolicy struct {
+	// Jobs is a regular expression matching the names of the jobs.
+	// Matches all jobs if empty.
+	Jobs string `json:"jobs,omitempty"`
+	// Types are the types of the jobs, e.g. presubmit.
+	// Matches all types if empty.
+	Types []prowapi.ProwJobType `json:"types,omitempty"`
+	// Results are the results of the runs, e.g. SUCCESS or FAILURE.
+	// Matches all results if empty.
+	Results []string `json:"results,omitempty"`
+	// KeepLast is how many of the last runs of each job matched by the
+	// policy are kept regardless of their age.
+	KeepLast int `json:"keep_last,omitempty"`
+	// MaxAge is how long the runs are kept after they finished.
+	MaxAge *metav1.Duration `json:"max_age,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// Matches returns whether the policy applies to the run of the job.
+func (p *RetentionPolicy) Matches(job string, jobType prowapi.ProwJobType, result string) bool {
+	if p.re != nil && !p.re.MatchString(job) {
+		return false
+	}
+	if len(p.Types) > 0 && !slices.Contains(p.Types, jobType) {
+		return false
+	}
+	if len(p.Results) > 0 && !slices.Contains(p.Results, result) {
+		return false
+	}
+	return true
+}
+
+// Policy returns the retention policy of the run of the job, if any.
+func (a *ArtifactGC) Policy(job string, jobType prowapi.ProwJobType, result string) *RetentionPolicy {
+	for i := range a.Policies {
+		if a.Policies[i].Matches(job, jobType, result) {
+			return &a.Policies[i]
+		}
+	}
+	return nil
+}
+
+func (a *ArtifactGC) defaultAndValidate() error {
+	if a.ResyncPeriod == nil {
+		a.ResyncPeriod = &metav1.Duration{Duration: 24 * time.Hour}
+	}
+	for _, bucket := range a.Buckets {
+		if _, err := prowapi.ParsePath(bucket); err != nil {
+			return fmt.Errorf("invalid bucket %q: %w", bucket, err)
+		}
+	}
+	for i := range a.Policies {
+		p := &a.Policies[i]
+		if p.Jobs != "" {
+			re, err := regexp.Compile(p.Jobs)
+			if err != nil {
+				return fmt.Errorf("policy %d: invalid jobs regexp %q: %w", i, p.Jobs, err)
+			}
+			p.re = re
+		}
+		for _, t := range p.Types {
+			switch t {
+			case prowapi.PresubmitJob, prowapi.PostsubmitJob, prowapi.PeriodicJob, prowapi.BatchJob:
+			default:
+				return fmt.Errorf("policy %d: invalid job type %q", i, t)
+			}
+		}
+		if p.KeepLast < 0 {
+			return fmt.Errorf("policy %d: keep_last must not be negative, got %d", i, p.KeepLast)
+		}
+		if p.MaxAge != nil && p.MaxAge.Duration <= 0 {
+			return fmt.Errorf("policy %d: max_age must be positive, got %s", i, p.MaxAge.Duration)
+		}
+	}
+	return nil
+}
+
 // LensConfig names a specific lens, and optionally provides some configuration for it.
 type LensConfig struct {
 	// Name is the name of the lens.
@@ -1271,6 +1646,60 @@ type Deck struct {
 	// AllKnownStorageBuckets contains all storage buckets configured in all of the
 	// job configs.
 	AllKnownStorageBuckets sets.Set[string] `json:"-"`
+	// Flakes enables the detection of flaky tests from the JUnit results of
+	// finished jobs. The report of the known-flaky tests is served on /flakes
+	// when Spyglass is enabled.
+	Flakes *Flakes `json:"flakes,omitempty"`
+	// Durations enables the trends of the durations of the jobs and of their
+	// tests, from the JUnit results of finished jobs, and the detection of
+	// their slowdowns. The trends are served on /durations.js when Spyglass is
+	// enabled.
+	Durations *Durations `json:"durations,omitempty"`
+}
+
+// Flakes holds the configuration of the flaky test detection.
+type Flakes struct {
+	// SyncPeriod specifies how often the JUnit results of the finished jobs
+	// are ingested. Defaults to 10m.
+	SyncPeriod *metav1.Duration `json:"sync_period,omitempty"`
+	// Lookback specifies how long the results of a job are taken into account.
+	// Defaults to 168h (7 days).
+	Lookback *metav1.Duration `json:"lookback,omitempty"`
+	// MinFlakes is the number of revisions on which a test must have flaked
+	// to be reported as known-flaky. Defaults to 2.
+	MinFlakes int `json:"min_flakes,omitempty"`
+}
+
+// Durations holds the configuration of the duration trends.
+type Durations struct {
+	// SyncPeriod specifies how often the durations of the finished jobs are
+	// ingested. The JUnit results are ingested once for both the flaky tests
+	// and the durations, on the shortest of their sync periods. Defaults to 10m.
+	SyncPeriod *metav1.Duration `json:"sync_period,omitempty"`
+	// Lookback specifies how long the durations of a job are taken into
+	// account. Defaults to 336h (14 days).
+	Lookback *metav1.Duration `json:"lookback,omitempty"`
+	// RecentRuns is the number of the most recent passing runs that are
+	// compared to the previous ones to detect slowdowns. Defaults to 5.
+	RecentRuns int `json:"recent_runs,omitempty"`
+	// MinBaselineRuns is the number of passing runs, before the recent ones,
+	// required to detect slowdowns. Defaults to 10.
+	MinBaselineRuns int `json:"min_baseline_runs,omitempty"`
+	// MinIncreasePercent is the increase of the mean duration, in percent,
+	// below which slowdowns are ignored even when significant. Defaults to 20.
+	MinIncreasePercent int `json:"min_increase_percent,omitempty"`
+	// MinDuration is the mean duration below which the slowdowns of tests are
+	// ignored. Defaults to 1s.
+	MinDuration *metav1.Duration `json:"min_duration,omitempty"`
+	// SlackChannel is the Slack channel that slowdowns are reported to, using
+	// the token given to Deck with --slack-token-file. Slowdowns are not
+	// reported when unset.
+	SlackChannel string `json:"slack_channel,omitempty"`
+	// AlertsPath is the storage path, e.g. gs://bucket/durations/alerts,
+	// where the slowdowns reported to Slack are recorded so that every
+	// slowdown is reported once across the restarts and the replicas of Deck.
+	// Required with SlackChannel.
+	AlertsPath string `json:"alerts_path,omitempty"`
 }
 
 // Validate performs validation and sanitization on the Deck object.
@@ -2178,6 +2607,9 @@ func (c *Config) validateComponentConfig() error {
 			return fmt.Errorf("invalid value for gerrit.deck_url: %v", err)
 		}
 	}
+	if err := c.WebhookReporter.validate(); err != nil {
+		return err
+	}
 
 	var validationErrs []error
 	if c.ManagedWebhooks.OrgRepoConfig != nil {
@@ -2267,6 +2699,12 @@ func (c Config) validateJobBase(v JobBase, jobType prowapi.ProwJobType) error {
 	if err := validateAnnotation(v.Annotations); err != nil {
 		return err
 	}
+	if err := validateReporterConfig(v.ReporterConfig, &c.WebhookReporter); err != nil {
+		return err
+	}
+	if err := validateRetryPolicy(v.RetryPolicy); err != nil {
+		return err
+	}
 	validJobQueueNames := sets.KeySet[string](c.Plank.JobQueueCapacities)
 	if err := validateJobQueueName(v.JobQueueName, validJobQueueNames); err != nil {
 		return err
@@ -2421,7 +2859,7 @@ func (c Config) validatePeriodics(periodics []Periodic) error {
 			errs = append(errs, fmt.Errorf("cron, interval, and minimum_interval are mutually exclusive in periodic %s", p.Name))
 			continue
 		}
-		if seen == 0 {
+		if seen == 0 && len(p.RunAfterSuccess) == 0 {
 			errs = append(errs, fmt.Errorf("at least one of cron, interval, or minimum_interval must be set in periodic %s", p.Name))
 			continue
 		}
@@ -2432,6 +2870,30 @@ func (c Config) validatePeriodics(periodics []Periodic) error {
 			}
 		}
 
+		if p.Timezone != "" {
+			loc, err := time.LoadLocation(p.Timezone)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid timezone %s in periodic %s: %w", p.Timezone, p.Name, err))
+			}
+			periodics[j].location = loc
+		}
+
+		for _, name := range p.ExclusionWindows {
+			if _, ok := c.Horologium.ExclusionWindows[name]; !ok {
+				errs = append(errs, fmt.Errorf("periodic %s references undefined horologium exclusion window %s", p.Name, name))
+			}
+		}
+
+		switch p.CatchUp {
+		case "", CatchUpSkip:
+		case CatchUpOnce, CatchUpAll:
+			if p.Cron == "" {
+				errs = append(errs, fmt.Errorf("catch_up only applies to cron periodics, %s isn't one", p.Name))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("invalid catch_up %q in periodic %s, must be one of %s, %s or %s", p.CatchUp, p.Name, CatchUpSkip, CatchUpOnce, CatchUpAll))
+		}
+
 		// Set the interval on the periodic jobs. It doesn't make sense to do this
 		// for child jobs.
 		if p.Interval != "" {
@@ -2487,6 +2949,10 @@ func (c *Config) ValidateJobConfig() error {
 		errs = append(errs, err)
 	}
 
+	if err := c.validateJobDependencies(); err != nil {
+		errs = append(errs, err)
+	}
+
 	c.Deck.AllKnownStorageBuckets = calculateStorageBuckets(c)
 
 	return utilerrors.NewAggregate(errs)
@@ -2514,10 +2980,26 @@ func parseProwConfig(c *Config) error {
 		c.Plank.MaxRevivals = &maxRetries
 	}
 
+	if c.Plank.FairShare != nil {
+		if err := c.Plank.FairShare.defaultAndValidate(); err != nil {
+			return fmt.Errorf("invalid plank.fair_share config: %w", err)
+		}
+	}
+
+	for name, window := range c.Horologium.ExclusionWindows {
+		if err := window.validate(); err != nil {
+			return fmt.Errorf("invalid horologium.exclusion_windows.%s: %w", name, err)
+		}
+	}
+
 	if err := c.Gerrit.DefaultAndValidate(); err != nil {
 		return fmt.Errorf("validating gerrit config: %w", err)
 	}
 
+	if err := c.Scheduler.DefaultAndValidate(); err != nil {
+		return fmt.Errorf("validating scheduler config: %w", err)
+	}
+
 	if c.Tide.Gerrit != nil {
 		if c.Tide.Gerrit.RateLimit == 0 {
 			c.Tide.Gerrit.RateLimit = 5
@@ -2585,6 +3067,50 @@ func parseProwConfig(c *Config) error {
 		c.Deck.TideUpdatePeriod = &metav1.Duration{Duration: time.Second * 10}
 	}
 
+	if c.Deck.Flakes != nil {
+		if c.Deck.Flakes.SyncPeriod == nil {
+			c.Deck.Flakes.SyncPeriod = &metav1.Duration{Duration: 10 * time.Minute}
+		}
+		if c.Deck.Flakes.Lookback == nil {
+			c.Deck.Flakes.Lookback = &metav1.Duration{Duration: 7 * 24 * time.Hour}
+		}
+		if c.Deck.Flakes.MinFlakes == 0 {
+			c.Deck.Flakes.MinFlakes = 2
+		} else if c.Deck.Flakes.MinFlakes < 0 {
+			return fmt.Errorf("invalid value for deck.flakes.min_flakes, must be >=0")
+		}
+	}
+
+	if c.Deck.Durations != nil {
+		if c.Deck.Durations.SyncPeriod == nil {
+			c.Deck.Durations.SyncPeriod = &metav1.Duration{Duration: 10 * time.Minute}
+		}
+		if c.Deck.Durations.Lookback == nil {
+			c.Deck.Durations.Lookback = &metav1.Duration{Duration: 14 * 24 * time.Hour}
+		}
+		if c.Deck.Durations.MinDuration == nil {
+			c.Deck.Durations.MinDuration = &metav1.Duration{Duration: time.Second}
+		}
+		if c.Deck.Durations.RecentRuns == 0 {
+			c.Deck.Durations.RecentRuns = 5
+		} else if c.Deck.Durations.RecentRuns < 0 {
+			return fmt.Errorf("invalid value for deck.durations.recent_runs, must be >=0")
+		}
+		if c.Deck.Durations.MinBaselineRuns == 0 {
+			c.Deck.Durations.MinBaselineRuns = 10
+		} else if c.Deck.Durations.MinBaselineRuns < 0 {
+			return fmt.Errorf("invalid value for deck.durations.min_baseline_runs, must be >=0")
+		}
+		if c.Deck.Durations.MinIncreasePercent == 0 {
+			c.Deck.Durations.MinIncreasePercent = 20
+		} else if c.Deck.Durations.MinIncreasePercent < 0 {
+			return fmt.Errorf("invalid value for deck.durations.min_increase_percent, must be >=0")
+		}
+		if c.Deck.Durations.SlackChannel != "" && c.Deck.Durations.AlertsPath == "" {
+			return fmt.Errorf("deck.durations.alerts_path is required to report to deck.durations.slack_channel")
+		}
+	}
+
 	if c.Deck.Spyglass.SizeLimit == 0 {
 		c.Deck.Spyglass.SizeLimit = 100e6
 	} else if c.Deck.Spyglass.SizeLimit <= 0 {
@@ -2688,6 +3214,10 @@ func parseProwConfig(c *Config) error {
 		c.Sinker.TerminatedPodTTL = &metav1.Duration{Duration: c.Sinker.MaxPodAge.Duration}
 	}
 
+	if err := c.ArtifactGC.defaultAndValidate(); err != nil {
+		return fmt.Errorf("invalid artifact_gc config: %w", err)
+	}
+
 	if c.Tide.SyncPeriod == nil {
 		c.Tide.SyncPeriod = &metav1.Duration{Duration: time.Minute}
 	}
@@ -2703,6 +3233,12 @@ func parseProwConfig(c *Config) error {
 		return fmt.Errorf("tide has invalid max_goroutines (%d), it needs to be a positive number", c.Tide.MaxGoroutines)
 	}
 
+	for orgRepo, depth := range c.Tide.SpeculativeBatchDepthMap {
+		if depth < 0 {
+			return fmt.Errorf("tide has invalid speculative_batch_depth (%d) for %q, it can't be negative", depth, orgRepo)
+		}
+	}
+
 	if len(c.Tide.TargetURLs) > 0 && c.Tide.TargetURL != "" {
 		return fmt.Errorf("tide.target_url and tide.target_urls are mutually exclusive")
 	}
@@ -2881,6 +3417,61 @@ func validateAnnotation(a map[string]string) error {
 	return nil
 }
 
+func validateReporterConfig(rc *prowapi.ReporterConfig, webhookReporter *WebhookReporter) error {
+	if rc == nil {
+		return nil
+	}
+	if rc.Webhook != nil {
+		u, err := url.Parse(rc.Webhook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("reporter_config.webhook.url: %q is not a valid http(s) URL", rc.Webhook.URL)
+		}
+		if !webhookReporter.Allows(rc.Webhook.URL) {
+			return fmt.Errorf("reporter_config.webhook.url: %q is not allowed by webhook_reporter.allowed_url_prefixes", rc.Webhook.URL)
+		}
+		if _, err := template.New("").Parse(rc.Webhook.PayloadTemplate); err != nil {
+			return fmt.Errorf("reporter_config.webhook.payload_template: %w", err)
+		}
+	}
+	for _, chat := range []struct {
+		name   string
+		config *prowapi.ChatReporterConfig
+	}{{name: "lark", config: rc.Lark}, {name: "teams", config: rc.Teams}} {
+		if chat.config == nil {
+			continue
+		}
+		if chat.config.Channel == "" {
+			return fmt.Errorf("reporter_config.%s.channel must be set", chat.name)
+		}
+		if _, err := template.New("").Parse(chat.config.ReportTemplate); err != nil {
+			return fmt.Errorf("reporter_config.%s.report_template: %w", chat.name, err)
+		}
+	}
+	return nil
+}
+
+func validateRetryPolicy(p *prowapi.RetryPolicy) error {
+	if p == nil {
+		return nil
+	}
+	if p.MaxAttempts < 1 {
+		return fmt.Errorf("retry_policy.max_attempts must be at least 1, got %d", p.MaxAttempts)
+	}
+	for _, reason := range p.Reasons {
+		switch reason {
+		case prowapi.RetryOnEviction, prowapi.RetryOnPodPendingTimeout, prowapi.RetryOnOOMKilled:
+		default:
+			return fmt.Errorf("retry_policy.reasons: invalid failure class %q, must be one of %s, %s or %s", reason, prowapi.RetryOnEviction, prowapi.RetryOnPodPendingTimeout, prowapi.RetryOnOOMKilled)
+		}
+	}
+	for _, code := range p.ExitCodes {
+		if code == 0 {
+			return errors.New("retry_policy.exit_codes: 0 is not a failure")
+		}
+	}
+	return nil
+}
+
 func validateJobQueueName(name string, validNames sets.Set[string]) error {
 	if name != "" && !validNames.Has(name) {
 		return fmt.Errorf("invalid job queue name %s", name)
@@ -2911,6 +3502,8 @@ func validateAgent(v JobBase, podNamespace string) error {
 		return fmt.Errorf("decoration requires agent: %s (found %q)", k, agent)
 	case v.ErrorOnEviction && agent != k:
 		return fmt.Errorf("error_on_eviction only applies to agent: %s (found %q)", k, agent)
+	case v.RetryPolicy != nil && agent != k:
+		return fmt.Errorf("retry_policy only applies to agent: %s (found %q)", k, agent)
 	case v.Namespace == nil || *v.Namespace == "":
 		return fmt.Errorf("failed to default namespace")
 	case *v.Namespace != podNamespace && agent != p:
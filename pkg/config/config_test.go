@@ -179,6 +179,164 @@ func TestDefaultJobBase(t *testing.T) {
 	}
 }
 
+func TestDeckFlakesConfig(t *testing.T) {
+	testCases := []struct {
+		name        string
+		config      string
+		expected    *Flakes
+		expectError bool
+	}{
+		{
+			name:   "disabled",
+			config: "deck: {}",
+		},
+		{
+			name: "defaults",
+			config: `
+deck:
+  flakes: {}
+`,
+			expected: &Flakes{
+				SyncPeriod: &metav1.Duration{Duration: 10 * time.Minute},
+				Lookback:   &metav1.Duration{Duration: 7 * 24 * time.Hour},
+				MinFlakes:  2,
+			},
+		},
+		{
+			name: "configured",
+			config: `
+deck:
+  flakes:
+    sync_period: 1h
+    lookback: 24h
+    min_flakes: 1
+`,
+			expected: &Flakes{
+				SyncPeriod: &metav1.Duration{Duration: time.Hour},
+				Lookback:   &metav1.Duration{Duration: 24 * time.Hour},
+				MinFlakes:  1,
+			},
+		},
+		{
+			name: "invalid min flakes",
+			config: `
+deck:
+  flakes:
+    min_flakes: -1
+`,
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(configPath, []byte(tc.config), 0666); err != nil {
+				t.Fatalf("fail to write config: %v", err)
+			}
+			cfg, err := Load(configPath, "", nil, "")
+			if (err != nil) != tc.expectError {
+				t.Fatalf("expected error: %t, got: %v", tc.expectError, err)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.expected, cfg.Deck.Flakes); diff != "" {
+				t.Errorf("unexpected flakes config: %s", diff)
+			}
+		})
+	}
+}
+
+func TestDeckDurationsConfig(t *testing.T) {
+	testCases := []struct {
+		name        string
+		config      string
+		expected    *Durations
+		expectError bool
+	}{
+		{
+			name:   "disabled",
+			config: "deck: {}",
+		},
+		{
+			name: "defaults",
+			config: `
+deck:
+  durations: {}
+`,
+			expected: &Durations{
+				SyncPeriod:         &metav1.Duration{Duration: 10 * time.Minute},
+				Lookback:           &metav1.Duration{Duration: 14 * 24 * time.Hour},
+				RecentRuns:         5,
+				MinBaselineRuns:    10,
+				MinIncreasePercent: 20,
+				MinDuration:        &metav1.Duration{Duration: time.Second},
+			},
+		},
+		{
+			name: "configured",
+			config: `
+deck:
+  durations:
+    sync_period: 1h
+    lookback: 24h
+    recent_runs: 3
+    min_baseline_runs: 5
+    min_increase_percent: 50
+    min_duration: 10s
+    slack_channel: ci-alerts
+    alerts_path: gs://bucket/durations/alerts
+`,
+			expected: &Durations{
+				SyncPeriod:         &metav1.Duration{Duration: time.Hour},
+				Lookback:           &metav1.Duration{Duration: 24 * time.Hour},
+				RecentRuns:         3,
+				MinBaselineRuns:    5,
+				MinIncreasePercent: 50,
+				MinDuration:        &metav1.Duration{Duration: 10 * time.Second},
+				SlackChannel:       "ci-alerts",
+				AlertsPath:         "gs://bucket/durations/alerts",
+			},
+		},
+		{
+			name: "slack channel without alerts path",
+			config: `
+deck:
+  durations:
+    slack_channel: ci-alerts
+`,
+			expectError: true,
+		},
+		{
+			name: "invalid recent runs",
+			config: `
+deck:
+  durations:
+    recent_runs: -1
+`,
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(configPath, []byte(tc.config), 0666); err != nil {
+				t.Fatalf("fail to write config: %v", err)
+			}
+			cfg, err := Load(configPath, "", nil, "")
+			if (err != nil) != tc.expectError {
+				t.Fatalf("expected error: %t, got: %v", tc.expectError, err)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.expected, cfg.Deck.Durations); diff != "" {
+				t.Errorf("unexpected durations config: %s", diff)
+			}
+		})
+	}
+}
+
 func TestSpyglassConfig(t *testing.T) {
 	testCases := []struct {
 		name                 string
@@ -1428,6 +1586,22 @@ func TestValidateAgent(t *testing.T) {
 			},
 			pass: true,
 		},
+		{
+			name: "retry allowed for kubernetes agent",
+			base: func(j *JobBase) {
+				j.RetryPolicy = &prowapi.RetryPolicy{MaxAttempts: 2}
+			},
+			pass: true,
+		},
+		{
+			name: "reject retry for jenkins agent",
+			base: func(j *JobBase) {
+				j.Agent = jenk
+				j.Spec = nil
+				j.DecorationConfig = nil
+				j.RetryPolicy = &prowapi.RetryPolicy{MaxAttempts: 2}
+			},
+		},
 	}
 
 	for _, tc := range cases {
@@ -1446,6 +1620,52 @@ func TestValidateAgent(t *testing.T) {
 	}
 }
 
+func TestValidateRetryPolicy(t *testing.T) {
+	cases := []struct {
+		name        string
+		policy      *prowapi.RetryPolicy
+		expectedErr string
+	}{
+		{
+			name: "no policy",
+		},
+		{
+			name: "valid policy",
+			policy: &prowapi.RetryPolicy{
+				MaxAttempts: 3,
+				Reasons:     []prowapi.RetryReason{prowapi.RetryOnEviction, prowapi.RetryOnPodPendingTimeout, prowapi.RetryOnOOMKilled},
+				ExitCodes:   []int32{3, 42},
+			},
+		},
+		{
+			name:        "max_attempts must be set",
+			policy:      &prowapi.RetryPolicy{Reasons: []prowapi.RetryReason{prowapi.RetryOnEviction}},
+			expectedErr: "retry_policy.max_attempts must be at least 1, got 0",
+		},
+		{
+			name:        "unknown failure class",
+			policy:      &prowapi.RetryPolicy{MaxAttempts: 2, Reasons: []prowapi.RetryReason{"flake"}},
+			expectedErr: `retry_policy.reasons: invalid failure class "flake", must be one of eviction, pod_pending_timeout or oom_killed`,
+		},
+		{
+			name:        "exit code 0",
+			policy:      &prowapi.RetryPolicy{MaxAttempts: 2, ExitCodes: []int32{0}},
+			expectedErr: "retry_policy.exit_codes: 0 is not a failure",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var errMsg string
+			if err := validateRetryPolicy(tc.policy); err != nil {
+				errMsg = err.Error()
+			}
+			if errMsg != tc.expectedErr {
+				t.Errorf("expected error %q, got %q", tc.expectedErr, errMsg)
+			}
+		})
+	}
+}
+
 func TestValidatePodSpec(t *testing.T) {
 	periodEnv := sets.New[string](downwardapi.EnvForType(prowapi.PeriodicJob)...)
 	postEnv := sets.New[string](downwardapi.EnvForType(prowapi.PostsubmitJob)...)
@@ -2039,8 +2259,9 @@ func TestValidateJobBase(t *testing.T) {
 	}
 	cfg := Config{
 		ProwConfig: ProwConfig{
-			Plank:        Plank{JobQueueCapacities: map[string]int{"queue": 0}},
-			PodNamespace: "target-namespace",
+			Plank:           Plank{JobQueueCapacities: map[string]int{"queue": 0}},
+			PodNamespace:    "target-namespace",
+			WebhookReporter: WebhookReporter{AllowedURLPrefixes: []string{"https://example.com/"}},
 		},
 	}
 	cases := []struct {
@@ -2174,6 +2395,71 @@ func TestValidateJobBase(t *testing.T) {
 			},
 			pass: false,
 		},
+		{
+			name: "valid webhook reporter",
+			base: JobBase{
+				Name: "name",
+				ReporterConfig: &prowapi.ReporterConfig{Webhook: &prowapi.WebhookReporterConfig{
+					URL:             "https://example.com/hook",
+					PayloadTemplate: `{"job": "{{.Spec.Job}}"}`,
+				}},
+			},
+			pass: true,
+		},
+		{
+			name: "webhook reporter URL on a host that isn't allowed",
+			base: JobBase{
+				Name:           "name",
+				ReporterConfig: &prowapi.ReporterConfig{Webhook: &prowapi.WebhookReporterConfig{URL: "https://example.com.attacker.io/hook"}},
+			},
+			pass: false,
+		},
+		{
+			name: "invalid webhook reporter URL",
+			base: JobBase{
+				Name:           "name",
+				ReporterConfig: &prowapi.ReporterConfig{Webhook: &prowapi.WebhookReporterConfig{URL: "example.com/hook"}},
+			},
+			pass: false,
+		},
+		{
+			name: "valid chat reporters",
+			base: JobBase{
+				Name: "name",
+				ReporterConfig: &prowapi.ReporterConfig{
+					Lark:  &prowapi.ChatReporterConfig{Channel: "team", ReportTemplate: "{{.Spec.Job}} {{.Status.State}}"},
+					Teams: &prowapi.ChatReporterConfig{Channel: "team"},
+				},
+			},
+			pass: true,
+		},
+		{
+			name: "chat reporter without channel",
+			base: JobBase{
+				Name:           "name",
+				ReporterConfig: &prowapi.ReporterConfig{Teams: &prowapi.ChatReporterConfig{}},
+			},
+			pass: false,
+		},
+		{
+			name: "invalid chat reporter template",
+			base: JobBase{
+				Name:           "name",
+				ReporterConfig: &prowapi.ReporterConfig{Lark: &prowapi.ChatReporterConfig{Channel: "team", ReportTemplate: "{{.Spec.Job"}},
+			},
+			pass: false,
+		},
+		{
+			name: "invalid webhook reporter template",
+			base: JobBase{
+				Name: "name",
+				ReporterConfig: &prowapi.ReporterConfig{Webhook: &prowapi.WebhookReporterConfig{
+					URL:             "https://example.com/hook",
+					PayloadTemplate: "{{.Spec.Job",
+				}},
+			},
+			pass: false,
+		},
 	}
 
 	for _, tc := range cases {
@@ -2188,6 +2474,41 @@ func TestValidateJobBase(t *testing.T) {
 	}
 }
 
+func TestWebhookReporterAllows(t *testing.T) {
+	r := WebhookReporter{AllowedURLPrefixes: []string{"https://hooks.example.com/prow", "http://internal:8080"}}
+	for _, tc := range []struct {
+		url  string
+		want bool
+	}{
+		{url: "https://hooks.example.com/prow", want: true},
+		{url: "https://hooks.example.com/prow/jobs?team=a", want: true},
+		{url: "https://HOOKS.example.com/prow/jobs", want: true},
+		{url: "http://internal:8080/anything", want: true},
+		{url: "https://hooks.example.com/prowler", want: false},
+		{url: "https://hooks.example.com/other", want: false},
+		{url: "http://hooks.example.com/prow", want: false},
+		{url: "https://hooks.example.com.attacker.io/prow", want: false},
+		{url: "https://hooks.example.com@attacker.io/prow", want: false},
+		{url: "https://user@hooks.example.com/prow", want: false},
+		{url: "http://internal:9090/", want: false},
+		{url: "::", want: false},
+		{url: "https://hooks.example.com/prow/../admin", want: false},
+		{url: "https://hooks.example.com/prow/./jobs", want: false},
+		{url: "https://hooks.example.com/prow/%2e%2e/admin", want: false},
+		{url: "https://hooks.example.com/prow/%2E%2E%2Fadmin", want: false},
+		{url: "https://hooks.example.com/prow/..%5Cadmin", want: false},
+		{url: "https://hooks.example.com/prow/..", want: false},
+		{url: "https://hooks.example.com/prow/jobs..v2", want: true},
+	} {
+		if got := r.Allows(tc.url); got != tc.want {
+			t.Errorf("Allows(%q) = %t, want %t", tc.url, got, tc.want)
+		}
+	}
+	if (&WebhookReporter{}).Allows("https://hooks.example.com/prow") {
+		t.Error("Expected no webhook to be allowed without allowed prefixes")
+	}
+}
+
 func TestValidateDeck(t *testing.T) {
 	boolTrue := true
 	boolFalse := false
@@ -8395,7 +8716,9 @@ func TestProwConfigMerging(t *testing.T) {
 branch-protection:
   allow_disabled_job_policies: true`,
 			},
-			expectedProwConfig: `branch-protection:
+			expectedProwConfig: `artifact_gc:
+  resync_period: 24h0m0s
+branch-protection:
   allow_disabled_job_policies: true
 config_version_sha: abc
 deck:
@@ -8451,6 +8774,7 @@ tide:
   max_goroutines: 20
   status_update_period: 1m0s
   sync_period: 1m0s
+webhook_reporter: {}
 `,
 		},
 		{
@@ -8482,7 +8806,9 @@ plank:
 tide:
   merge_method:
     foo/bar: squash`},
-			expectedProwConfig: `branch-protection: {}
+			expectedProwConfig: `artifact_gc:
+  resync_period: 24h0m0s
+branch-protection: {}
 deck:
   spyglass:
     gcs_browser_prefixes:
@@ -8538,6 +8864,7 @@ tide:
     foo/bar: squash
   status_update_period: 1m0s
   sync_period: 1m0s
+webhook_reporter: {}
 `,
 		},
 		{
@@ -8560,7 +8887,9 @@ tide:
     repos:
     - another/repo
 `},
-			expectedProwConfig: `branch-protection: {}
+			expectedProwConfig: `artifact_gc:
+  resync_period: 24h0m0s
+branch-protection: {}
 deck:
   spyglass:
     gcs_browser_prefixes:
@@ -8621,6 +8950,7 @@ tide:
     - another/repo
   status_update_period: 1m0s
   sync_period: 1m0s
+webhook_reporter: {}
 `,
 		},
 		{
@@ -8642,7 +8972,9 @@ slack_reporter_configs:
     report_template: Job {{.Spec.Job}} ended with state {{.Status.State}}.
 `,
 			},
-			expectedProwConfig: `branch-protection: {}
+			expectedProwConfig: `artifact_gc:
+  resync_period: 24h0m0s
+branch-protection: {}
 config_version_sha: abc
 deck:
   spyglass:
@@ -8709,6 +9041,7 @@ tide:
   max_goroutines: 20
   status_update_period: 1m0s
   sync_period: 1m0s
+webhook_reporter: {}
 `,
 		},
 		{
@@ -9591,3 +9924,676 @@ func TestSplitRepoName(t *testing.T) {
 		})
 	}
 }
+
+func TestArtifactGC(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name           string
+		yaml           string
+		expectedErr    string
+		expectedPolicy map[string]int
+	}{
+		{
+			name: "defaults the resync period",
+			yaml: `
+artifact_gc:
+  buckets:
+  - gs://prow-logs`,
+		},
+		{
+			name: "invalid bucket",
+			yaml: `
+artifact_gc:
+  buckets:
+  - "://prow-logs"`,
+			expectedErr: `invalid artifact_gc config: invalid bucket "://prow-logs"`,
+		},
+		{
+			name: "invalid jobs regexp",
+			yaml: `
+artifact_gc:
+  policies:
+  - jobs: "("`,
+			expectedErr: `invalid artifact_gc config: policy 0: invalid jobs regexp "("`,
+		},
+		{
+			name: "invalid type",
+			yaml: `
+artifact_gc:
+  policies:
+  - types: [nightly]`,
+			expectedErr: `invalid artifact_gc config: policy 0: invalid job type "nightly"`,
+		},
+		{
+			name: "negative keep_last",
+			yaml: `
+artifact_gc:
+  policies:
+  - keep_last: -1`,
+			expectedErr: "invalid artifact_gc config: policy 0: keep_last must not be negative, got -1",
+		},
+		{
+			name: "first matching policy applies",
+			yaml: `
+artifact_gc:
+  policies:
+  - jobs: "^ci-"
+    types: [periodic]
+    keep_last: 1
+  - results: [FAILURE]
+    keep_last: 2
+  - types: [presubmit]
+    keep_last: 3`,
+			expectedPolicy: map[string]int{
+				"ci-e2e/periodic/SUCCESS":     1,
+				"ci-e2e/periodic/FAILURE":     1,
+				"pull-e2e/presubmit/FAILURE":  2,
+				"pull-e2e/presubmit/SUCCESS":  3,
+				"post-e2e/postsubmit/SUCCESS": -1,
+				"ci-e2e/postsubmit/FAILURE":   2,
+				"e2e-ci-foo/periodic/ABORTED": -1,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			cfg, err := loadConfigYaml(tc.yaml, t)
+			if tc.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.ArtifactGC.ResyncPeriod == nil || cfg.ArtifactGC.ResyncPeriod.Duration != 24*time.Hour {
+				t.Errorf("expected the resync period to default to 24h, got %v", cfg.ArtifactGC.ResyncPeriod)
+			}
+			for run, expected := range tc.expectedPolicy {
+				parts := strings.Split(run, "/")
+				keepLast := -1
+				if p := cfg.ArtifactGC.Policy(parts[0], prowapi.ProwJobType(parts[1]), parts[2]); p != nil {
+					keepLast = p.KeepLast
+				}
+				if keepLast != expected {
+					t.Errorf("%s: expected the policy keeping %d runs, got %d", run, expected, keepLast)
+				}
+			}
+		})
+	}
+}
+
+func TestFairShare(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name               string
+		yaml               string
+		expectedErr        string
+		expectedPriorities []prowapi.ProwJobType
+		expectedWeights    map[string]int
+	}{
+		{
+			name: "defaults the priority job types",
+			yaml: `
+plank:
+  fair_share:
+    budgets:
+      default:
+        cpu: "100"
+        memory: 400Gi`,
+			expectedPriorities: []prowapi.ProwJobType{prowapi.PostsubmitJob, prowapi.PeriodicJob},
+		},
+		{
+			name: "weights default to 1",
+			yaml: `
+plank:
+  fair_share:
+    org_weights:
+      kubernetes: 3
+    repo_weights:
+      kubernetes/test-infra: 2
+    job_weights:
+      pull-e2e: 4
+    priority_job_types: [periodic]`,
+			expectedPriorities: []prowapi.ProwJobType{prowapi.PeriodicJob},
+			expectedWeights: map[string]int{
+				"org/kubernetes":             3,
+				"org/kubernetes-sigs":        1,
+				"repo/kubernetes/test-infra": 2,
+				"repo/kubernetes/kubernetes": 1,
+				"job/pull-e2e":               4,
+				"job/pull-unit":              1,
+			},
+		},
+		{
+			name: "unsupported resource",
+			yaml: `
+plank:
+  fair_share:
+    budgets:
+      default:
+        nvidia.com/gpu: "8"`,
+			expectedErr: `invalid plank.fair_share config: budgets.default: unsupported resource "nvidia.com/gpu"`,
+		},
+		{
+			name: "zero budget",
+			yaml: `
+plank:
+  fair_share:
+    budgets:
+      default:
+        cpu: "0"`,
+			expectedErr: "invalid plank.fair_share config: budgets.default.cpu must be positive, got 0",
+		},
+		{
+			name: "negative weight",
+			yaml: `
+plank:
+  fair_share:
+    repo_weights:
+      kubernetes/test-infra: -1`,
+			expectedErr: "invalid plank.fair_share config: repo_weights.kubernetes/test-infra must be positive, got -1",
+		},
+		{
+			name: "invalid job type",
+			yaml: `
+plank:
+  fair_share:
+    priority_job_types: [nightly]`,
+			expectedErr: `invalid plank.fair_share config: priority_job_types: invalid job type "nightly"`,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			cfg, err := loadConfigYaml(tc.yaml, t)
+			if tc.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			fs := cfg.Plank.FairShare
+			if diff := cmp.Diff(tc.expectedPriorities, fs.PriorityJobTypes); diff != "" {
+				t.Errorf("priority job types differ from expected (-want +got):\n%s", diff)
+			}
+			for key, expected := range tc.expectedWeights {
+				kind, name, _ := strings.Cut(key, "/")
+				var actual int
+				switch kind {
+				case "org":
+					actual = fs.OrgWeight(name)
+				case "repo":
+					actual = fs.RepoWeight(name)
+				case "job":
+					actual = fs.JobWeight(name)
+				}
+				if actual != expected {
+					t.Errorf("%s: expected weight %d, got %d", key, expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestPreemptionPreempts(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name       string
+		preemption *Preemption
+		expected   map[prowapi.ProwJobType]bool
+	}{
+		{
+			name: "not configured",
+			expected: map[prowapi.ProwJobType]bool{
+				prowapi.PresubmitJob: false,
+				prowapi.BatchJob:     false,
+			},
+		},
+		{
+			name:       "presubmits",
+			preemption: &Preemption{Presubmits: true},
+			expected: map[prowapi.ProwJobType]bool{
+				prowapi.PresubmitJob: true,
+				prowapi.BatchJob:     false,
+			},
+		},
+		{
+			name:       "presubmits and batches, never postsubmits nor periodics",
+			preemption: &Preemption{Presubmits: true, Batches: true},
+			expected: map[prowapi.ProwJobType]bool{
+				prowapi.PresubmitJob:  true,
+				prowapi.BatchJob:      true,
+				prowapi.PostsubmitJob: false,
+				prowapi.PeriodicJob:   false,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			for jobType, expected := range tc.expected {
+				if actual := tc.preemption.Preempts(jobType); actual != expected {
+					t.Errorf("%s: expected %t, got %t", jobType, expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestPeriodicRetryPolicy(t *testing.T) {
+	t.Parallel()
+	var p Periodic
+	if err := yaml.Unmarshal([]byte(`
+name: ci-e2e
+interval: 1h
+retry:
+  attempts: 2
+  interval: 10m
+retry_policy:
+  max_attempts: 3
+  reasons: [eviction]`), &p); err != nil {
+		t.Fatalf("failed to unmarshal periodic: %v", err)
+	}
+	if p.Retry == nil || p.Retry.Attempts != 2 || p.Retry.Interval != "10m" {
+		t.Errorf("expected the periodic to be retried twice every 10m, got %+v", p.Retry)
+	}
+	expected := &prowapi.RetryPolicy{MaxAttempts: 3, Reasons: []prowapi.RetryReason{prowapi.RetryOnEviction}}
+	if diff := cmp.Diff(expected, p.RetryPolicy); diff != "" {
+		t.Errorf("retry policy differs from expected (-want +got):\n%s", diff)
+	}
+}
+
+func TestPeriodicScheduling(t *testing.T) {
+	t.Parallel()
+	const windows = `
+horologium:
+  exclusion_windows:
+    weekends:
+      weekdays: [Saturday, Sunday]
+    freeze:
+      start: 2024-03-01T00:00:00Z
+      end: 2024-03-15T00:00:00Z
+    holidays:
+      dates: [2024-12-25]
+`
+	testCases := []struct {
+		name             string
+		yaml             string
+		expectedErr      string
+		expectedLocation string
+	}{
+		{
+			name: "defaults to UTC",
+			yaml: windows + `
+periodics:
+- name: nightly
+  cron: "0 2 * * *"
+  catch_up: all
+  exclusion_windows: [weekends, freeze, holidays]
+  spec:
+    containers:
+    - image: alpine`,
+			expectedLocation: "UTC",
+		},
+		{
+			name: "timezone",
+			yaml: `
+periodics:
+- name: nightly
+  cron: "0 2 * * *"
+  timezone: America/Los_Angeles
+  spec:
+    containers:
+    - image: alpine`,
+			expectedLocation: "America/Los_Angeles",
+		},
+		{
+			name: "invalid timezone",
+			yaml: `
+periodics:
+- name: nightly
+  cron: "0 2 * * *"
+  timezone: Mars/Olympus_Mons
+  spec:
+    containers:
+    - image: alpine`,
+			expectedErr: "invalid timezone Mars/Olympus_Mons in periodic nightly",
+		},
+		{
+			name: "undefined exclusion window",
+			yaml: windows + `
+periodics:
+- name: nightly
+  cron: "0 2 * * *"
+  exclusion_windows: [summer]
+  spec:
+    containers:
+    - image: alpine`,
+			expectedErr: "periodic nightly references undefined horologium exclusion window summer",
+		},
+		{
+			name: "catch up on interval periodic",
+			yaml: `
+periodics:
+- name: nightly
+  interval: 24h
+  catch_up: once
+  spec:
+    containers:
+    - image: alpine`,
+			expectedErr: "catch_up only applies to cron periodics, nightly isn't one",
+		},
+		{
+			name: "invalid catch up",
+			yaml: `
+periodics:
+- name: nightly
+  cron: "0 2 * * *"
+  catch_up: twice
+  spec:
+    containers:
+    - image: alpine`,
+			expectedErr: `invalid catch_up "twice" in periodic nightly`,
+		},
+		{
+			name: "exclusion window without end",
+			yaml: `
+horologium:
+  exclusion_windows:
+    freeze:
+      start: 2024-03-01T00:00:00Z`,
+			expectedErr: "invalid horologium.exclusion_windows.freeze: start and end must be set together",
+		},
+		{
+			name: "exclusion window ending before it starts",
+			yaml: `
+horologium:
+  exclusion_windows:
+    freeze:
+      start: 2024-03-15T00:00:00Z
+      end: 2024-03-01T00:00:00Z`,
+			expectedErr: "invalid horologium.exclusion_windows.freeze: start 2024-03-15T00:00:00Z must be before end 2024-03-01T00:00:00Z",
+		},
+		{
+			name: "empty exclusion window",
+			yaml: `
+horologium:
+  exclusion_windows:
+    freeze: {}`,
+			expectedErr: "invalid horologium.exclusion_windows.freeze: at least one of start and end, weekdays or dates must be set",
+		},
+		{
+			name: "invalid weekday",
+			yaml: `
+horologium:
+  exclusion_windows:
+    weekends:
+      weekdays: [sat]`,
+			expectedErr: `invalid horologium.exclusion_windows.weekends: invalid weekday "sat"`,
+		},
+		{
+			name: "invalid date",
+			yaml: `
+horologium:
+  exclusion_windows:
+    holidays:
+      dates: [12/25/2024]`,
+			expectedErr: `invalid horologium.exclusion_windows.holidays: invalid date "12/25/2024"`,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			cfg, err := loadConfigYaml(tc.yaml, t)
+			if tc.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if loc := cfg.Periodics[0].GetLocation().String(); loc != tc.expectedLocation {
+				t.Errorf("expected location %s, got %s", tc.expectedLocation, loc)
+			}
+		})
+	}
+}
+
+func TestExcludingWindow(t *testing.T) {
+	t.Parallel()
+	h := Horologium{
+		ExclusionWindows: map[string]ExclusionWindow{
+			"weekends": {Weekdays: []string{"Saturday", "Sunday"}},
+			"freeze": {
+				Start: &metav1.Time{Time: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+				End:   &metav1.Time{Time: time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)},
+			},
+			"holidays": {Dates: []string{"2024-12-25"}},
+		},
+	}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+	testCases := []struct {
+		name     string
+		location *time.Location
+		windows  []string
+		time     time.Time
+		expected string
+	}{
+		{
+			name:    "no windows",
+			time:    time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC),
+			windows: nil,
+		},
+		{
+			name:     "weekend",
+			windows:  []string{"weekends", "holidays"},
+			time:     time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC),
+			expected: "weekends",
+		},
+		{
+			name:    "weekday",
+			windows: []string{"weekends", "holidays"},
+			time:    time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekend in the time zone of the periodic",
+			location: tokyo,
+			windows:  []string{"weekends"},
+			time:     time.Date(2024, 3, 8, 20, 0, 0, 0, time.UTC),
+			expected: "weekends",
+		},
+		{
+			name:     "holiday",
+			windows:  []string{"weekends", "holidays"},
+			time:     time.Date(2024, 12, 25, 23, 59, 0, 0, time.UTC),
+			expected: "holidays",
+		},
+		{
+			name:     "in freeze",
+			windows:  []string{"freeze"},
+			time:     time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			expected: "freeze",
+		},
+		{
+			name:    "end of freeze is excluded",
+			windows: []string{"freeze"},
+			time:    time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			p := Periodic{ExclusionWindows: tc.windows}
+			p.SetLocation(tc.location)
+			if actual := h.ExcludingWindow(&p, tc.time); actual != tc.expected {
+				t.Errorf("expected window %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestJobDependencies(t *testing.T) {
+	t.Parallel()
+	const jobs = `
+periodics:
+- name: build
+  interval: 1h
+  spec:
+    containers:
+    - image: alpine
+- name: deploy
+  run_after_success: [build, post-build]
+  spec:
+    containers:
+    - image: alpine
+postsubmits:
+  org/repo:
+  - name: post-build
+    spec:
+      containers:
+      - image: alpine
+  - name: post-deploy
+    run_after_success: [post-build]
+    spec:
+      containers:
+      - image: alpine
+`
+	testCases := []struct {
+		name                string
+		yaml                string
+		expectedErr         string
+		expectedDownstreams map[string][]string
+	}{
+		{
+			name: "periodics and postsubmits running after others",
+			yaml: jobs,
+			expectedDownstreams: map[string][]string{
+				"build":       {"deploy"},
+				"post-build":  {"deploy", "post-deploy"},
+				"post-deploy": nil,
+			},
+		},
+		{
+			name: "periodic running after undefined job",
+			yaml: `
+periodics:
+- name: deploy
+  run_after_success: [build]
+  spec:
+    containers:
+    - image: alpine`,
+			expectedErr: "periodic deploy runs after undefined job build",
+		},
+		{
+			name: "postsubmit running after a periodic",
+			yaml: jobs + `
+  - name: post-publish
+    run_after_success: [build]
+    spec:
+      containers:
+      - image: alpine`,
+			expectedErr: "postsubmit post-publish runs after build, which isn't a postsubmit of org/repo",
+		},
+		{
+			name: "postsubmit running after a postsubmit of another repo",
+			yaml: jobs + `
+  org/other:
+  - name: post-publish
+    run_after_success: [post-build]
+    spec:
+      containers:
+      - image: alpine`,
+			expectedErr: "postsubmit post-publish runs after post-build, which isn't a postsubmit of org/other",
+		},
+		{
+			name: "job running after itself",
+			yaml: `
+periodics:
+- name: deploy
+  interval: 1h
+  run_after_success: [deploy]
+  spec:
+    containers:
+    - image: alpine`,
+			expectedErr: "run_after_success dependencies form a cycle: deploy -> deploy",
+		},
+		{
+			name: "cycle",
+			yaml: `
+periodics:
+- name: a
+  interval: 1h
+  run_after_success: [c]
+  spec:
+    containers:
+    - image: alpine
+- name: b
+  run_after_success: [a]
+  spec:
+    containers:
+    - image: alpine
+- name: c
+  run_after_success: [b]
+  spec:
+    containers:
+    - image: alpine`,
+			expectedErr: "run_after_success dependencies form a cycle: a -> c -> b -> a",
+		},
+		{
+			name: "periodic without schedule nor upstream jobs",
+			yaml: `
+periodics:
+- name: deploy
+  spec:
+    containers:
+    - image: alpine`,
+			expectedErr: "at least one of cron, interval, or minimum_interval must be set in periodic deploy",
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			cfg, err := loadConfigYaml(tc.yaml, t)
+			if tc.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for job, expected := range tc.expectedDownstreams {
+				if diff := cmp.Diff(expected, cfg.DownstreamJobs(job)); diff != "" {
+					t.Errorf("downstream jobs of %s differ from expected (-want +got):\n%s", job, diff)
+				}
+			}
+			for _, p := range cfg.AllStaticPostsubmits(nil) {
+				shouldRun, err := p.ShouldRun("main", nil)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if shouldRun == (len(p.RunAfterSuccess) > 0) {
+					t.Errorf("postsubmit %s running after others should only run on push if it doesn't, got %t", p.Name, shouldRun)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// jobDependencies returns the names of the upstream jobs of each job running
+// after the success of others. Jobs are identified by name only, so the
+// upstream jobs of postsubmits of different repos with the same name are
+// merged.
+func (c *JobConfig) jobDependencies() map[string]sets.Set[string] {
+	upstreams := map[string]sets.Set[string]{}
+	add := func(name string, runAfter []string) {
+		if len(runAfter) == 0 {
+			return
+		}
+		if upstreams[name] == nil {
+			upstreams[name] = sets.New[string]()
+		}
+		upstreams[name].Insert(runAfter...)
+	}
+	for _, p := range c.Periodics {
+		add(p.Name, p.RunAfterSuccess)
+	}
+	for _, jobs := range c.PostsubmitsStatic {
+		for _, p := range jobs {
+			add(p.Name, p.RunAfterSuccess)
+		}
+	}
+	return upstreams
+}
+
+// DownstreamJobs returns the names of the jobs that run after the success of
+// the job.
+func (c *JobConfig) DownstreamJobs(name string) []string {
+	downstreams := sets.New[string]()
+	for downstream, upstreams := range c.jobDependencies() {
+		if upstreams.Has(name) {
+			downstreams.Insert(downstream)
+		}
+	}
+	if downstreams.Len() == 0 {
+		return nil
+	}
+	return sets.List(downstreams)
+}
+
+// validateJobDependencies validates that the upstream jobs of the jobs running
+// after the success of others exist and that the dependencies have no cycle.
+func (c *JobConfig) validateJobDependencies() error {
+	var errs []error
+
+	periodics := sets.New[string]()
+	for _, p := range c.Periodics {
+		periodics.Insert(p.Name)
+	}
+	postsubmits := sets.New[string]()
+	for _, jobs := range c.PostsubmitsStatic {
+		for _, p := range jobs {
+			postsubmits.Insert(p.Name)
+		}
+	}
+
+	for _, p := range c.Periodics {
+		for _, upstream := range p.RunAfterSuccess {
+			if !periodics.Has(upstream) && !postsubmits.Has(upstream) {
+				errs = append(errs, fmt.Errorf("periodic %s runs after undefined job %s", p.Name, upstream))
+			}
+		}
+	}
+	for repo, jobs := range c.PostsubmitsStatic {
+		repoPostsubmits := sets.New[string]()
+		for _, p := range jobs {
+			repoPostsubmits.Insert(p.Name)
+		}
+		for _, p := range jobs {
+			for _, upstream := range p.RunAfterSuccess {
+				if !repoPostsubmits.Has(upstream) {
+					errs = append(errs, fmt.Errorf("postsubmit %s runs after %s, which isn't a postsubmit of %s", p.Name, upstream, repo))
+				}
+			}
+		}
+	}
+
+	if cycle := dependencyCycle(c.jobDependencies()); cycle != nil {
+		errs = append(errs, fmt.Errorf("run_after_success dependencies form a cycle: %s", strings.Join(cycle, " -> ")))
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// dependencyCycle returns a cycle of the dependencies, starting and ending with
+// the same job, if there is any.
+func dependencyCycle(upstreams map[string]sets.Set[string]) []string {
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := map[string]int{}
+	var path []string
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		switch state[name] {
+		case visiting:
+			for i, n := range path {
+				if n == name {
+					return append(append([]string{}, path[i:]...), name)
+				}
+			}
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, upstream := range sets.List(upstreams[name]) {
+			if cycle := visit(upstream); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+
+	names := make([]string, 0, len(upstreams))
+	for name := range upstreams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if cycle := visit(name); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
@@ -310,6 +310,9 @@ func DefaultAndValidateProwYAML(c *Config, p *ProwYAML, identifier string) error
 		if !c.InRepoConfigAllowsCluster(post.Cluster, identifier) {
 			errs = append(errs, fmt.Errorf("cluster %q is not allowed for repository %q", post.Cluster, identifier))
 		}
+		if len(post.RunAfterSuccess) > 0 {
+			errs = append(errs, fmt.Errorf("run_after_success is not supported in inrepo config, postsubmit %s", post.Name))
+		}
 	}
 
 	if len(errs) == 0 {
@@ -275,6 +275,22 @@ func testDefaultProwYAMLGetter(clients localgit.Clients, t *testing.T) {
 				return nil
 			},
 		},
+		{
+			name: "Postsubmit running after others is rejected",
+			baseContent: map[string][]byte{
+				".prow.yaml": []byte(`postsubmits: [{"name": "hans", "run_after_success": ["peter"], "spec": {"containers": [{}]}}]`),
+			},
+			validate: func(_ *ProwYAML, err error) error {
+				if err == nil {
+					return errors.New("error is nil")
+				}
+				expectedErrMsg := "run_after_success is not supported in inrepo config, postsubmit hans"
+				if err.Error() != expectedErrMsg {
+					return fmt.Errorf("expected error message to be %q, was %q", expectedErrMsg, err.Error())
+				}
+				return nil
+			},
+		},
 		{
 			name: "No prow.yaml, no error, no nullpointer",
 			validate: func(p *ProwYAML, err error) error {
@@ -123,6 +123,11 @@ type JobBase struct {
 	// If this field is unspecified or false, a new pod will be created to replace
 	// the evicted one.
 	ErrorOnEviction bool `json:"error_on_eviction,omitempty"`
+	// RetryPolicy configures plank to retry the job as a new ProwJob when it
+	// fails for infrastructure reasons, e.g. eviction or pod pending timeout.
+	// Only the final attempt is reported to GitHub and Gerrit. This is unrelated
+	// to the retry of periodics, which horologium triggers again on any failure.
+	RetryPolicy *prowapi.RetryPolicy `json:"retry_policy,omitempty"`
 	// SourcePath contains the path where this job is defined
 	SourcePath string `json:"-"`
 	// Spec is the Kubernetes pod spec used if Agent is kubernetes.
@@ -278,6 +283,11 @@ type Postsubmit struct {
 	Reporter
 
 	JenkinsSpec *JenkinsSpec `json:"jenkins_spec,omitempty"`
+
+	// RunAfterSuccess are the names of postsubmits of the same repo this job
+	// runs after instead of on push: it runs for a commit once all of them
+	// succeeded for it.
+	RunAfterSuccess []string `json:"run_after_success,omitempty"`
 }
 
 // Retry defines the configuration for retrying failed prowjobs.
@@ -307,6 +317,20 @@ type Periodic struct {
 	MinimumInterval string `json:"minimum_interval,omitempty"`
 	// Cron representation of job trigger time
 	Cron string `json:"cron,omitempty"`
+	// Timezone is the IANA time zone the cron expression and the exclusion
+	// windows are evaluated in, e.g. America/Los_Angeles. Defaults to UTC.
+	Timezone string `json:"timezone,omitempty"`
+	// ExclusionWindows are the names of the horologium exclusion windows
+	// during which the job isn't triggered, e.g. a release freeze.
+	ExclusionWindows []string `json:"exclusion_windows,omitempty"`
+	// CatchUp is what horologium does about the cron triggers it missed while
+	// it was down: skip them, run the job once or run it for each of them.
+	// Defaults to skip.
+	CatchUp CatchUpPolicy `json:"catch_up,omitempty"`
+	// RunAfterSuccess are the names of periodics or postsubmits this job runs
+	// after: it runs once all of them succeeded since its latest run. The job
+	// doesn't need a cron or interval then.
+	RunAfterSuccess []string `json:"run_after_success,omitempty"`
 	// Tags for config entries
 	Tags []string `json:"tags,omitempty"`
 
@@ -314,8 +338,22 @@ type Periodic struct {
 
 	interval         time.Duration
 	minimum_interval time.Duration
+	location         *time.Location
 }
 
+// CatchUpPolicy is what horologium does about the cron triggers of a periodic
+// it missed while it was down.
+type CatchUpPolicy string
+
+const (
+	// CatchUpSkip doesn't run the periodic for the missed triggers.
+	CatchUpSkip CatchUpPolicy = "skip"
+	// CatchUpOnce runs the periodic once if any trigger was missed.
+	CatchUpOnce CatchUpPolicy = "once"
+	// CatchUpAll runs the periodic for each missed trigger.
+	CatchUpAll CatchUpPolicy = "all"
+)
+
 // JenkinsSpec holds optional Jenkins job config
 type JenkinsSpec struct {
 	// Job is managed by the GH branch source plugin
@@ -353,6 +391,25 @@ func (p *Periodic) GetMinimumInterval() time.Duration {
 	return p.minimum_interval
 }
 
+// HasSchedule returns whether the periodic is triggered on a schedule, rather
+// than only after the success of its upstream jobs.
+func (p *Periodic) HasSchedule() bool {
+	return p.Cron != "" || p.interval > 0 || p.minimum_interval > 0
+}
+
+// SetLocation updates the time zone the periodic is scheduled in.
+func (p *Periodic) SetLocation(loc *time.Location) {
+	p.location = loc
+}
+
+// GetLocation returns the time zone the periodic is scheduled in, UTC by default.
+func (p *Periodic) GetLocation() *time.Location {
+	if p.location == nil {
+		return time.UTC
+	}
+	return p.location
+}
+
 // +k8s:deepcopy-gen=true
 
 // Brancher is for shared code between jobs that only run against certain
@@ -484,7 +541,8 @@ func (ps Postsubmit) CouldRun(baseRef string) bool {
 // ShouldRun determines if the postsubmit should run in response to a
 // set of changes. This is evaluated lazily, if necessary.
 func (ps Postsubmit) ShouldRun(baseRef string, changes ChangedFilesProvider) (bool, error) {
-	if !ps.CouldRun(baseRef) {
+	// Jobs running after their upstream jobs aren't triggered by changes.
+	if !ps.CouldRun(baseRef) || len(ps.RunAfterSuccess) > 0 {
 		return false, nil
 	}
 
@@ -16,14 +16,58 @@ limitations under the License.
 
 package config
 
-import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
 
 type Scheduler struct {
 	Enabled bool `json:"enabled,omitempty"`
 
-	// Scheduling strategies
-	Failover *FailoverScheduling `json:"failover,omitempty"`
-	External *ExternalScheduling `json:"external,omitempty"`
+	// Scheduling strategies, only one of them can be set.
+	Failover  *FailoverScheduling  `json:"failover,omitempty"`
+	External  *ExternalScheduling  `json:"external,omitempty"`
+	LoadAware *LoadAwareScheduling `json:"load_aware,omitempty"`
+	// Chain composes the strategies above, it can't be set along with them.
+	Chain *ChainScheduling `json:"chain,omitempty"`
+}
+
+// DefaultAndValidate compiles the selectors and the capacities of the
+// scheduling strategies and validates them.
+func (s *Scheduler) DefaultAndValidate() error {
+	if s.Chain != nil {
+		if s.Failover != nil || s.External != nil || s.LoadAware != nil {
+			return errors.New("chain can't be set along with other strategies, add them as stages instead")
+		}
+		if err := s.Chain.DefaultAndValidate(); err != nil {
+			return fmt.Errorf("chain: %w", err)
+		}
+	}
+	var strategies []string
+	if s.Failover != nil {
+		strategies = append(strategies, "failover")
+	}
+	if s.External != nil {
+		strategies = append(strategies, "external")
+	}
+	if s.LoadAware != nil {
+		strategies = append(strategies, "load_aware")
+	}
+	if len(strategies) > 1 {
+		return fmt.Errorf("only one strategy can be set, found %s, use a chain to compose them", strings.Join(strategies, ", "))
+	}
+	if s.LoadAware != nil {
+		if err := s.LoadAware.DefaultAndValidate(); err != nil {
+			return fmt.Errorf("load_aware: %w", err)
+		}
+	}
+	return nil
 }
 
 // FailoverScheduling is a configuration for the Failover scheduling strategy
@@ -51,3 +95,224 @@ type ExternalScheduling struct {
 	// Cache is the cache configuration for the external scheduling strategy
 	Cache ExternalSchedulingCache `json:"cache,omitempty"`
 }
+
+// LoadAwareScheduling is a configuration for the LoadAware scheduling strategy
+// that assigns a ProwJob to the least loaded cluster it is eligible for. The
+// load of a cluster is computed from the ProwJobs already scheduled to it and
+// the resources they request, relative to the capacity of the cluster.
+type LoadAwareScheduling struct {
+	// Clusters are the build clusters managed by the strategy. ProwJobs
+	// configured to a cluster that is not in this list are left untouched.
+	Clusters []LoadAwareCluster `json:"clusters,omitempty"`
+	// AffinityRules restrict the clusters a ProwJob can be scheduled to. A
+	// ProwJob is eligible for the clusters matching the cluster selectors of
+	// all the rules whose job selector matches it. A ProwJob eligible for
+	// no cluster is left unscheduled.
+	AffinityRules []SchedulingAffinityRule `json:"affinity_rules,omitempty"`
+}
+
+// LoadAwareCluster is a build cluster along with its capacity.
+type LoadAwareCluster struct {
+	// Name is the name of the build cluster.
+	Name string `json:"name"`
+	// Labels are matched by the cluster selectors of the affinity rules.
+	Labels map[string]string `json:"labels,omitempty"`
+	// MaxJobs is the number of ProwJobs the cluster can run concurrently.
+	// 0 means unlimited.
+	MaxJobs int `json:"max_jobs,omitempty"`
+	// CPU is the CPU the ProwJobs can request in total on the cluster,
+	// for instance "64" or "64000m". Empty means unlimited.
+	CPU string `json:"cpu,omitempty"`
+	// Memory is the memory the ProwJobs can request in total on the cluster,
+	// for instance "256Gi". Empty means unlimited.
+	Memory string `json:"memory,omitempty"`
+
+	// CPUQuantity is compiled at load time from CPU.
+	CPUQuantity *resource.Quantity `json:"-"`
+	// MemoryQuantity is compiled at load time from Memory.
+	MemoryQuantity *resource.Quantity `json:"-"`
+}
+
+// SchedulingAffinityRule restricts the ProwJobs matching JobSelector to the
+// clusters matching ClusterSelector.
+type SchedulingAffinityRule struct {
+	// JobSelectorString compiles into JobSelector at load time. It is matched
+	// against the labels of the ProwJob, an empty selector matches everything.
+	// For label selector syntax, see below:
+	// https://kubernetes.io/docs/concepts/overview/working-with-objects/labels/#label-selectors
+	JobSelectorString string `json:"job_selector,omitempty"`
+	// ClusterSelectorString compiles into ClusterSelector at load time. It is
+	// matched against the labels of the clusters.
+	ClusterSelectorString string `json:"cluster_selector,omitempty"`
+
+	JobSelector     labels.Selector `json:"-"`
+	ClusterSelector labels.Selector `json:"-"`
+}
+
+// DefaultAndValidate compiles the capacities and the selectors.
+func (l *LoadAwareScheduling) DefaultAndValidate() error {
+	if len(l.Clusters) == 0 {
+		return errors.New("at least one cluster is required")
+	}
+
+	names := sets.New[string]()
+	for i := range l.Clusters {
+		c := &l.Clusters[i]
+		if c.Name == "" {
+			return errors.New("cluster name must not be empty")
+		}
+		if names.Has(c.Name) {
+			return fmt.Errorf("duplicated cluster %q", c.Name)
+		}
+		names.Insert(c.Name)
+		if c.MaxJobs < 0 {
+			return fmt.Errorf("cluster %q: max_jobs must not be negative", c.Name)
+		}
+		if c.CPU != "" {
+			q, err := resource.ParseQuantity(c.CPU)
+			if err != nil {
+				return fmt.Errorf("cluster %q: invalid cpu %q: %w", c.Name, c.CPU, err)
+			}
+			c.CPUQuantity = &q
+		}
+		if c.Memory != "" {
+			q, err := resource.ParseQuantity(c.Memory)
+			if err != nil {
+				return fmt.Errorf("cluster %q: invalid memory %q: %w", c.Name, c.Memory, err)
+			}
+			c.MemoryQuantity = &q
+		}
+	}
+
+	for i := range l.AffinityRules {
+		if err := l.AffinityRules[i].DefaultAndValidate(); err != nil {
+			return fmt.Errorf("affinity_rules[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// DefaultAndValidate compiles the selectors.
+func (r *SchedulingAffinityRule) DefaultAndValidate() error {
+	jobSelector, err := labels.Parse(r.JobSelectorString)
+	if err != nil {
+		return fmt.Errorf("invalid job_selector %q: %w", r.JobSelectorString, err)
+	}
+	clusterSelector, err := labels.Parse(r.ClusterSelectorString)
+	if err != nil {
+		return fmt.Errorf("invalid cluster_selector %q: %w", r.ClusterSelectorString, err)
+	}
+	r.JobSelector = jobSelector
+	r.ClusterSelector = clusterSelector
+	return nil
+}
+
+// Fallbacks of a scheduling stage.
+const (
+	// SchedulingFallbackNext moves on to the next stage.
+	SchedulingFallbackNext = "next"
+	// SchedulingFallbackPassthrough keeps the cluster the ProwJob was
+	// configured to, the remaining stages are skipped.
+	SchedulingFallbackPassthrough = "passthrough"
+	// SchedulingFallbackRequeue fails the scheduling, so that the ProwJob
+	// is retried later.
+	SchedulingFallbackRequeue = "requeue"
+)
+
+// ChainScheduling is a configuration for the Chain scheduling strategy
+// that runs a sequence of strategies. The first stage that takes a decision
+// wins, if none does the ProwJob keeps the cluster it was configured to.
+type ChainScheduling struct {
+	// Stages are the strategies to run, in order.
+	Stages []SchedulingStage `json:"stages,omitempty"`
+}
+
+// SchedulingStage is a strategy of a chain. A stage doesn't take a decision
+// when its strategy has nothing to say about the ProwJob, for instance
+// Failover doesn't have a mapping for its cluster, then the chain moves on
+// to the next stage. When the strategy fails or times out, Fallback applies.
+// Exactly one of Failover, External and LoadAware must be set.
+type SchedulingStage struct {
+	// Name identifies the stage in logs, metrics and in the scheduling
+	// decision annotation. Defaults to the name of the strategy.
+	Name string `json:"name,omitempty"`
+	// JobSelectorString compiles into JobSelector at load time. The stage
+	// only applies to the ProwJobs whose labels match it, an empty selector
+	// matches everything.
+	// For label selector syntax, see below:
+	// https://kubernetes.io/docs/concepts/overview/working-with-objects/labels/#label-selectors
+	JobSelectorString string `json:"job_selector,omitempty"`
+	// Timeout bounds the time the stage can take. 0 means no timeout.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// Fallback is what to do when the stage fails or times out, one of
+	// "next", "passthrough" or "requeue". Defaults to "next".
+	Fallback string `json:"fallback,omitempty"`
+
+	Failover  *FailoverScheduling  `json:"failover,omitempty"`
+	External  *ExternalScheduling  `json:"external,omitempty"`
+	LoadAware *LoadAwareScheduling `json:"load_aware,omitempty"`
+
+	JobSelector labels.Selector `json:"-"`
+}
+
+// DefaultAndValidate defaults and validates the stages.
+func (c *ChainScheduling) DefaultAndValidate() error {
+	if len(c.Stages) == 0 {
+		return errors.New("at least one stage is required")
+	}
+
+	names := sets.New[string]()
+	for i := range c.Stages {
+		stage := &c.Stages[i]
+		if err := stage.DefaultAndValidate(); err != nil {
+			return fmt.Errorf("stages[%d]: %w", i, err)
+		}
+		if names.Has(stage.Name) {
+			return fmt.Errorf("stages[%d]: duplicated stage %q, set a unique name", i, stage.Name)
+		}
+		names.Insert(stage.Name)
+	}
+	return nil
+}
+
+// DefaultAndValidate defaults and validates the stage.
+func (s *SchedulingStage) DefaultAndValidate() error {
+	var strategies []string
+	if s.Failover != nil {
+		strategies = append(strategies, "failover")
+	}
+	if s.External != nil {
+		strategies = append(strategies, "external")
+	}
+	if s.LoadAware != nil {
+		if err := s.LoadAware.DefaultAndValidate(); err != nil {
+			return fmt.Errorf("load_aware: %w", err)
+		}
+		strategies = append(strategies, "load_aware")
+	}
+	if len(strategies) != 1 {
+		return fmt.Errorf("exactly one strategy must be set, found %d", len(strategies))
+	}
+	if s.Name == "" {
+		s.Name = strategies[0]
+	}
+
+	switch s.Fallback {
+	case "":
+		s.Fallback = SchedulingFallbackNext
+	case SchedulingFallbackNext, SchedulingFallbackPassthrough, SchedulingFallbackRequeue:
+	default:
+		return fmt.Errorf("invalid fallback %q", s.Fallback)
+	}
+
+	if s.Timeout != nil && s.Timeout.Duration < 0 {
+		return errors.New("timeout must not be negative")
+	}
+
+	sel, err := labels.Parse(s.JobSelectorString)
+	if err != nil {
+		return fmt.Errorf("invalid job_selector %q: %w", s.JobSelectorString, err)
+	}
+	s.JobSelector = sel
+	return nil
+}
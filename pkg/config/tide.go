@@ -227,6 +227,18 @@ type Tide struct {
 	// starting a new one requires to start new instances of all tests.
 	// Use '*' as key to set this globally. Defaults to true.
 	PrioritizeExistingBatchesMap map[string]bool `json:"prioritize_existing_batches,omitempty"`
+	// SpeculativeBatchDepthMap is a key/value pair of an org or org/repo as the key and
+	// the number of speculative batches Tide tests at once as the value. Use "*" as key
+	// to set a global default. With a depth greater than 1, the picked batch is split
+	// into stacked batches each including the ones before it (A, A+B, A+B+C) that are
+	// tested at the same time, and the longest passing one is merged.
+	// 0 or 1 => a single batch at a time, the default.
+	SpeculativeBatchDepthMap map[string]int `json:"speculative_batch_depth,omitempty"`
+	// BisectFailedBatchesMap configures on org or org/repo level if Tide should bisect
+	// the batches that fail by testing halves of them, until it finds the PR breaking
+	// the batch. That PR is kept out of the batches until its head changes.
+	// Use '*' as key to set this globally. Defaults to false.
+	BisectFailedBatchesMap map[string]bool `json:"bisect_failed_batches,omitempty"`
 
 	TideGitHubConfig `json:",inline"`
 }
@@ -360,6 +372,31 @@ func (t *Tide) PrioritizeExistingBatches(repo OrgRepo) bool {
 	return true
 }
 
+func (t *Tide) BisectFailedBatches(repo OrgRepo) bool {
+	if val, set := t.BisectFailedBatchesMap[repo.String()]; set {
+		return val
+	}
+	if val, set := t.BisectFailedBatchesMap[repo.Org]; set {
+		return val
+	}
+	return t.BisectFailedBatchesMap["*"]
+}
+
+// SpeculativeBatchDepth returns the number of stacked speculative batches
+// tested at once for the repo, 1 means speculative batches are disabled.
+func (t *Tide) SpeculativeBatchDepth(repo OrgRepo) int {
+	depth, ok := t.SpeculativeBatchDepthMap[repo.String()]
+	if !ok {
+		if depth, ok = t.SpeculativeBatchDepthMap[repo.Org]; !ok {
+			depth = t.SpeculativeBatchDepthMap["*"]
+		}
+	}
+	if depth < 1 {
+		return 1
+	}
+	return depth
+}
+
 func (t *Tide) BatchSizeLimit(repo OrgRepo) int {
 	if limit, ok := t.BatchSizeLimitMap[repo.String()]; ok {
 		return limit
@@ -622,6 +622,39 @@ func TestMergeMethod(t *testing.T) {
 	}
 }
 
+func TestSpeculativeBatchDepth(t *testing.T) {
+	ti := &Tide{
+		SpeculativeBatchDepthMap: map[string]int{
+			"*":                  2,
+			"kubernetes":         3,
+			"kubernetes/test":    0,
+			"kubernetes-sigs/kk": 4,
+		},
+	}
+
+	var testcases = []struct {
+		org      string
+		repo     string
+		expected int
+	}{
+		{"other", "repo", 2},
+		{"kubernetes", "kubernetes", 3},
+		{"kubernetes", "test", 1},
+		{"kubernetes-sigs", "kk", 4},
+	}
+
+	for _, test := range testcases {
+		actual := ti.SpeculativeBatchDepth(OrgRepo{Org: test.org, Repo: test.repo})
+		if actual != test.expected {
+			t.Errorf("Expected speculative batch depth %d but got %d for %s/%s", test.expected, actual, test.org, test.repo)
+		}
+	}
+
+	if depth := (&Tide{}).SpeculativeBatchDepth(OrgRepo{Org: "org", Repo: "repo"}); depth != 1 {
+		t.Errorf("Expected speculative batch depth 1 by default but got %d", depth)
+	}
+}
+
 func TestOrgRepoMatchMergeMethod(t *testing.T) {
 	var testCases = []struct {
 		name     string
@@ -88,6 +88,11 @@ func (in *JobBase) DeepCopyInto(out *JobBase) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.RetryPolicy != nil {
+		in, out := &in.RetryPolicy, &out.RetryPolicy
+		*out = new(prowjobsv1.RetryPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Spec != nil {
 		in, out := &in.Spec, &out.Spec
 		*out = new(v1.PodSpec)
@@ -156,6 +161,11 @@ func (in *Postsubmit) DeepCopyInto(out *Postsubmit) {
 		*out = new(JenkinsSpec)
 		**out = **in
 	}
+	if in.RunAfterSuccess != nil {
+		in, out := &in.RunAfterSuccess, &out.RunAfterSuccess
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -50,4 +50,8 @@ type JobInfo struct {
 	Type           v1.ProwJobType `json:"type"`
 	JobHistoryLink string         `json:"jobHistoryLink"`
 	YAMLDefinition string         `json:"yamlDefinition"`
+	// RunAfterSuccess are the upstream jobs the job runs after.
+	RunAfterSuccess []string `json:"runAfterSuccess,omitempty"`
+	// Downstreams are the jobs running after the job.
+	Downstreams []string `json:"downstreams,omitempty"`
 }
@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
@@ -33,6 +34,7 @@ import (
 
 	prowv1 "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
 	"sigs.k8s.io/prow/pkg/crier/reporters/criercommonlib"
+	"sigs.k8s.io/prow/pkg/tracing"
 )
 
 type ReportClient interface {
@@ -139,7 +141,9 @@ func (r *reconciler) reconcile(ctx context.Context, log *logrus.Entry, req recon
 
 	log = log.WithField("jobStatus", pj.Status.State)
 	log.Info("Will report state")
-	pjs, requeue, err := r.reporter.Report(ctx, log, &pj)
+	reportCtx, span := tracing.StartProwJobSpan(ctx, "crier.report", &pj, attribute.String("reporter", r.reporter.GetName()))
+	pjs, requeue, err := r.reporter.Report(reportCtx, log, &pj)
+	tracing.End(span, err)
 	if err != nil {
 		if criercommonlib.IsUserError(err) {
 			log.WithError(err).Debug("Failed to report job.")
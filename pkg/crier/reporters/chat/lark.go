@@ -0,0 +1,125 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+)
+
+// NewLark returns a reporter posting interactive cards to the Lark (Feishu)
+// custom bots of the channels listed in the file returned by webhooks.
+func NewLark(webhooks func() []byte, dryRun bool) *reporter {
+	return newReporter(lark{}, webhooks, dryRun)
+}
+
+type lark struct{}
+
+type larkText struct {
+	Tag     string `json:"tag"`
+	Content string `json:"content"`
+}
+
+type larkElement struct {
+	Tag     string       `json:"tag"`
+	Text    *larkText    `json:"text,omitempty"`
+	Actions []larkButton `json:"actions,omitempty"`
+}
+
+type larkButton struct {
+	Tag  string   `json:"tag"`
+	Text larkText `json:"text"`
+	URL  string   `json:"url"`
+	Type string   `json:"type"`
+}
+
+type larkHeader struct {
+	Title    larkText `json:"title"`
+	Template string   `json:"template"`
+}
+
+type larkCard struct {
+	Header   larkHeader    `json:"header"`
+	Elements []larkElement `json:"elements"`
+}
+
+type larkMessage struct {
+	MsgType string   `json:"msg_type"`
+	Card    larkCard `json:"card"`
+}
+
+func (lark) name() string {
+	return "lark"
+}
+
+func (lark) config(rc *prowapi.ReporterConfig) *prowapi.ChatReporterConfig {
+	return rc.Lark
+}
+
+// larkColor returns the color of the header of the card.
+func larkColor(state prowapi.ProwJobState) string {
+	switch state {
+	case prowapi.SuccessState:
+		return "green"
+	case prowapi.FailureState, prowapi.ErrorState:
+		return "red"
+	case prowapi.AbortedState:
+		return "grey"
+	}
+	return "blue"
+}
+
+func (lark) payload(card Card) interface{} {
+	elements := []larkElement{{Tag: "div", Text: &larkText{Tag: "lark_md", Content: card.Text}}}
+	if card.URL != "" {
+		elements = append(elements, larkElement{Tag: "action", Actions: []larkButton{{
+			Tag:  "button",
+			Text: larkText{Tag: "plain_text", Content: "View logs"},
+			URL:  card.URL,
+			Type: "default",
+		}}})
+	}
+	return larkMessage{
+		MsgType: "interactive",
+		Card: larkCard{
+			Header:   larkHeader{Title: larkText{Tag: "plain_text", Content: card.Title}, Template: larkColor(card.State)},
+			Elements: elements,
+		},
+	}
+}
+
+// checkResponse checks the code in the body too, the custom bots respond
+// with 200 OK to invalid messages.
+func (lark) checkResponse(status int, body []byte) error {
+	if status != http.StatusOK {
+		return fmt.Errorf("response status %d: %s", status, body)
+	}
+	var resp struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("failed to parse the response %q: %w", body, err)
+	}
+	if resp.Code != 0 {
+		return fmt.Errorf("response code %d: %s", resp.Code, resp.Msg)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package chat contains reporters that post the state of the ProwJobs as
+// cards to the incoming webhooks of chat platforms other than Slack.
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/crier/reporters/criercommonlib"
+)
+
+const (
+	// DefaultReportTemplate is the text of the cards when the job doesn't
+	// configure any, the link to the logs is a button of the card.
+	DefaultReportTemplate = `Job {{.Spec.Job}} of type {{.Spec.Type}} ended with state {{.Status.State}}.`
+
+	requestTimeout = 10 * time.Second
+	// maxResponseSize bounds how much of the responses is read.
+	maxResponseSize = 1 << 20
+)
+
+// Card holds what is rendered on the card of a job.
+type Card struct {
+	Title string
+	Text  string
+	// URL is the link to the logs of the job, if any.
+	URL   string
+	State prowapi.ProwJobState
+}
+
+// platform renders the cards in the format of a chat platform.
+type platform interface {
+	// name is the name of the platform, as the key of its configuration in
+	// the reporter_config of the jobs.
+	name() string
+	// config returns the configuration of the job for the platform.
+	config(rc *prowapi.ReporterConfig) *prowapi.ChatReporterConfig
+	// payload returns the message posting the card.
+	payload(card Card) interface{}
+	// checkResponse returns an error when the response reports a failure.
+	checkResponse(status int, body []byte) error
+}
+
+type reporter struct {
+	platform platform
+	client   *http.Client
+	// webhooks returns the content of the file mapping the channels to the
+	// URLs of their incoming webhooks.
+	webhooks func() []byte
+	dryRun   bool
+}
+
+func newReporter(p platform, webhooks func() []byte, dryRun bool) *reporter {
+	return &reporter{
+		platform: p,
+		client:   &http.Client{Timeout: requestTimeout},
+		webhooks: webhooks,
+		dryRun:   dryRun,
+	}
+}
+
+func (r *reporter) GetName() string {
+	return r.platform.name() + "reporter"
+}
+
+func (r *reporter) jobConfig(pj *prowapi.ProwJob) *prowapi.ChatReporterConfig {
+	if pj.Spec.ReporterConfig == nil {
+		return nil
+	}
+	return r.platform.config(pj.Spec.ReporterConfig)
+}
+
+func (r *reporter) ShouldReport(_ context.Context, logger *logrus.Entry, pj *prowapi.ProwJob) bool {
+	cfg := r.jobConfig(pj)
+	shouldReport := cfg != nil && cfg.Channel != "" && cfg.ShouldReport(pj.Status.State)
+	logger.WithField("reporting", shouldReport).Debug("Determined should report")
+	return shouldReport
+}
+
+func (r *reporter) Report(ctx context.Context, log *logrus.Entry, pj *prowapi.ProwJob) ([]*prowapi.ProwJob, *reconcile.Result, error) {
+	return []*prowapi.ProwJob{pj}, nil, r.report(ctx, log, pj)
+}
+
+// webhookURL returns the URL of the incoming webhook of the channel.
+func (r *reporter) webhookURL(channel string) (string, error) {
+	webhooks := map[string]string{}
+	if err := yaml.Unmarshal(r.webhooks(), &webhooks); err != nil {
+		return "", fmt.Errorf("failed to parse the %s webhooks: %w", r.platform.name(), err)
+	}
+	url, ok := webhooks[channel]
+	if !ok {
+		return "", criercommonlib.UserError(fmt.Errorf("no %s webhook is configured for channel %q", r.platform.name(), channel))
+	}
+	return url, nil
+}
+
+// card renders the card of the job with its report template.
+func card(cfg *prowapi.ChatReporterConfig, pj *prowapi.ProwJob) (Card, error) {
+	reportTemplate := cfg.ReportTemplate
+	if reportTemplate == "" {
+		reportTemplate = DefaultReportTemplate
+	}
+	tmpl, err := template.New("").Parse(reportTemplate)
+	if err != nil {
+		return Card{}, fmt.Errorf("failed to parse template: %w", err)
+	}
+	b := &bytes.Buffer{}
+	if err := tmpl.Execute(b, pj); err != nil {
+		return Card{}, fmt.Errorf("failed to execute report template: %w", err)
+	}
+	return Card{
+		Title: fmt.Sprintf("%s: %s", pj.Spec.Job, pj.Status.State),
+		Text:  b.String(),
+		URL:   pj.Status.URL,
+		State: pj.Status.State,
+	}, nil
+}
+
+func (r *reporter) report(ctx context.Context, log *logrus.Entry, pj *prowapi.ProwJob) error {
+	cfg := r.jobConfig(pj)
+	if cfg == nil {
+		return nil
+	}
+	c, err := card(cfg, pj)
+	if err != nil {
+		log.WithError(err).Debug("Failed to render the card.")
+		return criercommonlib.UserError(err)
+	}
+	body, err := json.Marshal(r.platform.payload(c))
+	if err != nil {
+		return fmt.Errorf("failed to marshal the %s card: %w", r.platform.name(), err)
+	}
+	if r.dryRun {
+		log.WithField("channel", cfg.Channel).WithField("card", string(body)).Debug("Skipping reporting because dry-run is enabled")
+		return nil
+	}
+	url, err := r.webhookURL(cfg.Channel)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create the request to the %s webhook of channel %q: %w", r.platform.name(), cfg.Channel, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		// The URL holds the credentials of the webhook, don't log it.
+		return fmt.Errorf("failed to post to the %s webhook of channel %q", r.platform.name(), cfg.Channel)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return fmt.Errorf("failed to read the response of the %s webhook of channel %q: %w", r.platform.name(), cfg.Channel, err)
+	}
+	if err := r.platform.checkResponse(resp.StatusCode, respBody); err != nil {
+		return fmt.Errorf("failed to post to the %s webhook of channel %q: %w", r.platform.name(), cfg.Channel, err)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/crier/reporters/criercommonlib"
+)
+
+func prowJob(rc *prowapi.ReporterConfig, state prowapi.ProwJobState) *prowapi.ProwJob {
+	return &prowapi.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "pj"},
+		Spec: prowapi.ProwJobSpec{
+			Type:           prowapi.PeriodicJob,
+			Job:            "job",
+			ReporterConfig: rc,
+		},
+		Status: prowapi.ProwJobStatus{State: state, URL: "https://prow.example.com/view/1"},
+	}
+}
+
+func TestShouldReport(t *testing.T) {
+	testCases := []struct {
+		name     string
+		reporter *reporter
+		pj       *prowapi.ProwJob
+		expected bool
+	}{
+		{
+			name:     "no reporter config",
+			reporter: NewLark(nil, false),
+			pj:       prowJob(nil, prowapi.SuccessState),
+		},
+		{
+			name:     "configured for the other platform",
+			reporter: NewLark(nil, false),
+			pj:       prowJob(&prowapi.ReporterConfig{Teams: &prowapi.ChatReporterConfig{Channel: "team"}}, prowapi.SuccessState),
+		},
+		{
+			name:     "final state",
+			reporter: NewTeams(nil, false),
+			pj:       prowJob(&prowapi.ReporterConfig{Teams: &prowapi.ChatReporterConfig{Channel: "team"}}, prowapi.FailureState),
+			expected: true,
+		},
+		{
+			name:     "pending state",
+			reporter: NewTeams(nil, false),
+			pj:       prowJob(&prowapi.ReporterConfig{Teams: &prowapi.ChatReporterConfig{Channel: "team"}}, prowapi.PendingState),
+		},
+		{
+			name:     "configured states",
+			reporter: NewLark(nil, false),
+			pj: prowJob(&prowapi.ReporterConfig{Lark: &prowapi.ChatReporterConfig{
+				Channel:           "team",
+				JobStatesToReport: []prowapi.ProwJobState{prowapi.PendingState},
+			}}, prowapi.PendingState),
+			expected: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.reporter.ShouldReport(context.Background(), logrus.NewEntry(logrus.StandardLogger()), tc.pj); got != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, got)
+			}
+		})
+	}
+}
+
+const larkCardJSON = `{
+	"msg_type": "interactive",
+	"card": {
+		"header": {"title": {"tag": "plain_text", "content": "job: failure"}, "template": "red"},
+		"elements": [
+			{"tag": "div", "text": {"tag": "lark_md", "content": "job failed"}},
+			{"tag": "action", "actions": [{"tag": "button", "text": {"tag": "plain_text", "content": "View logs"}, "url": "https://prow.example.com/view/1", "type": "default"}]}
+		]
+	}
+}`
+
+const teamsCardJSON = `{
+	"type": "message",
+	"attachments": [{
+		"contentType": "application/vnd.microsoft.card.adaptive",
+		"content": {
+			"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+			"type": "AdaptiveCard",
+			"version": "1.4",
+			"body": [
+				{"type": "TextBlock", "text": "job: failure", "weight": "Bolder", "size": "Medium", "color": "Attention"},
+				{"type": "TextBlock", "text": "Job job of type periodic ended with state failure.", "wrap": true}
+			],
+			"actions": [{"type": "Action.OpenUrl", "title": "View logs", "url": "https://prow.example.com/view/1"}]
+		}
+	}]
+}`
+
+func TestReport(t *testing.T) {
+	testCases := []struct {
+		name            string
+		newReporter     func(webhooks func() []byte, dryRun bool) *reporter
+		rc              *prowapi.ReporterConfig
+		channel         string
+		response        string
+		dryRun          bool
+		expectedCard    string
+		expectErr       bool
+		expectUserError bool
+	}{
+		{
+			name:         "lark",
+			newReporter:  NewLark,
+			rc:           &prowapi.ReporterConfig{Lark: &prowapi.ChatReporterConfig{Channel: "team", ReportTemplate: "{{.Spec.Job}} failed"}},
+			response:     `{"code":0,"msg":"success"}`,
+			expectedCard: larkCardJSON,
+		},
+		{
+			name:         "lark rejects the card",
+			newReporter:  NewLark,
+			rc:           &prowapi.ReporterConfig{Lark: &prowapi.ChatReporterConfig{Channel: "team", ReportTemplate: "{{.Spec.Job}} failed"}},
+			response:     `{"code":19001,"msg":"param invalid"}`,
+			expectedCard: larkCardJSON,
+			expectErr:    true,
+		},
+		{
+			name:         "teams with the default template",
+			newReporter:  NewTeams,
+			rc:           &prowapi.ReporterConfig{Teams: &prowapi.ChatReporterConfig{Channel: "team"}},
+			response:     "1",
+			expectedCard: teamsCardJSON,
+		},
+		{
+			name:            "unknown channel",
+			newReporter:     NewTeams,
+			rc:              &prowapi.ReporterConfig{Teams: &prowapi.ChatReporterConfig{Channel: "other"}},
+			expectErr:       true,
+			expectUserError: true,
+		},
+		{
+			name:            "invalid template",
+			newReporter:     NewTeams,
+			rc:              &prowapi.ReporterConfig{Teams: &prowapi.ChatReporterConfig{Channel: "team", ReportTemplate: "{{.Spec.Unknown}}"}},
+			expectErr:       true,
+			expectUserError: true,
+		},
+		{
+			name:        "dry run",
+			newReporter: NewLark,
+			rc:          &prowapi.ReporterConfig{Lark: &prowapi.ChatReporterConfig{Channel: "team"}},
+			dryRun:      true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var cards []interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/hooks/team" {
+					http.NotFound(w, r)
+					return
+				}
+				body, _ := io.ReadAll(r.Body)
+				var card interface{}
+				if err := json.Unmarshal(body, &card); err != nil {
+					t.Errorf("failed to unmarshal the card %s: %v", body, err)
+				}
+				cards = append(cards, card)
+				fmt.Fprint(w, tc.response)
+			}))
+			defer server.Close()
+
+			webhooks := func() []byte { return []byte(fmt.Sprintf("team: %s/hooks/team\n", server.URL)) }
+			r := tc.newReporter(webhooks, tc.dryRun)
+			_, _, err := r.Report(context.Background(), logrus.NewEntry(logrus.StandardLogger()), prowJob(tc.rc, prowapi.FailureState))
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error: %t, got: %v", tc.expectErr, err)
+			}
+			if err != nil && criercommonlib.IsUserError(err) != tc.expectUserError {
+				t.Errorf("expected user error: %t, got: %v", tc.expectUserError, err)
+			}
+
+			var expected []interface{}
+			if tc.expectedCard != "" {
+				var card interface{}
+				if err := json.Unmarshal([]byte(tc.expectedCard), &card); err != nil {
+					t.Fatalf("failed to unmarshal the expected card: %v", err)
+				}
+				expected = append(expected, card)
+			}
+			if diff := cmp.Diff(expected, cards); diff != "" {
+				t.Errorf("unexpected cards: %s", diff)
+			}
+		})
+	}
+}
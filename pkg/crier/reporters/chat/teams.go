@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chat
+
+import (
+	"fmt"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+)
+
+// NewTeams returns a reporter posting Adaptive Cards to the Microsoft Teams
+// incoming webhooks of the channels listed in the file returned by webhooks.
+func NewTeams(webhooks func() []byte, dryRun bool) *reporter {
+	return newReporter(teams{}, webhooks, dryRun)
+}
+
+type teams struct{}
+
+type teamsTextBlock struct {
+	Type   string `json:"type"`
+	Text   string `json:"text"`
+	Weight string `json:"weight,omitempty"`
+	Size   string `json:"size,omitempty"`
+	Color  string `json:"color,omitempty"`
+	Wrap   bool   `json:"wrap,omitempty"`
+}
+
+type teamsAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+type teamsCard struct {
+	Schema  string           `json:"$schema"`
+	Type    string           `json:"type"`
+	Version string           `json:"version"`
+	Body    []teamsTextBlock `json:"body"`
+	Actions []teamsAction    `json:"actions,omitempty"`
+}
+
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+func (teams) name() string {
+	return "teams"
+}
+
+func (teams) config(rc *prowapi.ReporterConfig) *prowapi.ChatReporterConfig {
+	return rc.Teams
+}
+
+// teamsColor returns the color of the title of the card.
+func teamsColor(state prowapi.ProwJobState) string {
+	switch state {
+	case prowapi.SuccessState:
+		return "Good"
+	case prowapi.FailureState, prowapi.ErrorState:
+		return "Attention"
+	case prowapi.AbortedState:
+		return "Warning"
+	}
+	return "Default"
+}
+
+func (teams) payload(card Card) interface{} {
+	content := teamsCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+		Body: []teamsTextBlock{
+			{Type: "TextBlock", Text: card.Title, Weight: "Bolder", Size: "Medium", Color: teamsColor(card.State)},
+			{Type: "TextBlock", Text: card.Text, Wrap: true},
+		},
+	}
+	if card.URL != "" {
+		content.Actions = []teamsAction{{Type: "Action.OpenUrl", Title: "View logs", URL: card.URL}}
+	}
+	return teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content:     content,
+		}},
+	}
+}
+
+func (teams) checkResponse(status int, body []byte) error {
+	if status < 200 || status >= 300 {
+		return fmt.Errorf("response status %d: %s", status, body)
+	}
+	return nil
+}
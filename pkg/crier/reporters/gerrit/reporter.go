@@ -187,6 +187,12 @@ func (c *Client) ShouldReport(ctx context.Context, log *logrus.Entry, pj *v1.Pro
 		return false
 	}
 
+	if pj.Retried() {
+		// retried by plank, only the final attempt is reported
+		log.Info("PJ retried")
+		return false
+	}
+
 	// has gerrit metadata (scheduled by gerrit adapter)
 	if pj.ObjectMeta.Annotations[kube.GerritID] == "" ||
 		pj.ObjectMeta.Annotations[kube.GerritInstance] == "" ||
@@ -1968,6 +1968,41 @@ func TestReport(t *testing.T) {
 			expectLabel:       map[string]string{codeReview: lztm},
 			numExpectedReport: 0,
 		},
+		{
+			name: "1 job, retried by plank, should not report",
+			pj: &v1.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						kube.GerritRevision:   "abc",
+						kube.ProwJobTypeLabel: presubmit,
+					},
+					Annotations: map[string]string{
+						kube.GerritID:       "123-abc",
+						kube.GerritInstance: "gerrit",
+					},
+					Name:      "ci-foo",
+					Namespace: "test-pods",
+				},
+				Status: v1.ProwJobStatus{
+					State: v1.ErrorState,
+					URL:   "guber/foo",
+					Retry: &v1.RetryStatus{Attempt: 1, RetriedBy: "ci-foo-attempt-2"},
+				},
+				Spec: v1.ProwJobSpec{
+					Refs: &v1.Refs{
+						Repo: "foo",
+						Pulls: []v1.Pull{
+							{
+								Number: 0,
+							},
+						},
+					},
+					Job:    "ci-foo",
+					Report: true,
+				},
+			},
+			expectReport: false,
+		},
 		{
 			name: "1 job, passed, should vote +1 even after merge",
 			pj: &v1.ProwJob{
@@ -25,6 +25,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/GoogleCloudPlatform/testgrid/metadata/junit"
 	"github.com/sirupsen/logrus"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -32,13 +33,21 @@ import (
 	v1 "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
 	"sigs.k8s.io/prow/pkg/config"
 	"sigs.k8s.io/prow/pkg/crier/reporters/criercommonlib"
+	"sigs.k8s.io/prow/pkg/crier/reporters/gcs/util"
+	"sigs.k8s.io/prow/pkg/flakes"
 	"sigs.k8s.io/prow/pkg/github/report"
+	"sigs.k8s.io/prow/pkg/io"
+	"sigs.k8s.io/prow/pkg/io/providers"
 	"sigs.k8s.io/prow/pkg/kube"
 )
 
 const (
 	// GitHubReporterName is the name for github reporter
 	GitHubReporterName = "github-reporter"
+
+	// maxFailureMessage bounds the length of the failure messages read from
+	// the JUnit results.
+	maxFailureMessage = 4096
 )
 
 // Client is a github reporter client
@@ -48,16 +57,20 @@ type Client struct {
 	reportAgent v1.ProwJobAgent
 	prLocks     *criercommonlib.ShardedLock
 	lister      ctrlruntimeclient.Reader
+	// opener reads the JUnit results the check runs are annotated with, if set.
+	opener io.Opener
 }
 
-// NewReporter returns a reporter client
-func NewReporter(gc report.GitHubClient, cfg config.Getter, reportAgent v1.ProwJobAgent, lister ctrlruntimeclient.Reader) *Client {
+// NewReporter returns a reporter client. The check runs of failed jobs are
+// annotated with the failures read from their artifacts if opener is set.
+func NewReporter(gc report.GitHubClient, cfg config.Getter, reportAgent v1.ProwJobAgent, lister ctrlruntimeclient.Reader, opener io.Opener) *Client {
 	c := &Client{
 		gc:          gc,
 		config:      cfg,
 		reportAgent: reportAgent,
 		prLocks:     criercommonlib.NewShardedLock(),
 		lister:      lister,
+		opener:      opener,
 	}
 	c.prLocks.RunCleanup()
 	return c
@@ -81,6 +94,11 @@ func (c *Client) ShouldReport(_ context.Context, _ *logrus.Entry, pj *v1.ProwJob
 		return false // Report presubmit and postsubmit github jobs for github reporter
 	case c.reportAgent != "" && pj.Spec.Agent != c.reportAgent:
 		return false // Only report for specified agent
+	case pj.Retried():
+		// Only report the final attempt of retried jobs, but complete the
+		// check run of the retried attempt so that it doesn't stay in progress.
+		refs := pj.Spec.Refs
+		return refs != nil && c.config().GitHubReporter.ReportsCheckRuns(refs.Org, refs.Repo)
 	}
 
 	return true
@@ -91,6 +109,27 @@ func (c *Client) Report(ctx context.Context, log *logrus.Entry, pj *v1.ProwJob)
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
 	defer cancel()
 
+	// Check runs are reported alongside status contexts, unless the repo opted
+	// out of the latter, so that both can coexist during a migration.
+	if refs := pj.Spec.Refs; refs != nil && c.config().GitHubReporter.ReportsCheckRuns(refs.Org, refs.Repo) {
+		failures := c.testFailures(ctx, log, pj)
+		if err := report.ReportCheckRun(c.gc, *pj, c.config().GitHubReporter, failures); err != nil {
+			switch {
+			case strings.Contains(err.Error(), "\"message\":\"Not Found\"") || strings.Contains(err.Error(), "\"message\":\"No commit found for SHA:"):
+				log.WithError(err).Debug("Could not find PR commit, skipping retries")
+			case c.config().GitHubReporter.ReportsStatusContexts(refs.Org, refs.Repo) && !pj.Retried():
+				// The status context keeps reporting the job, e.g. when
+				// crier isn't authenticated as a GitHub App.
+				log.WithError(err).Warn("Failed to report the check run, reporting the status context only")
+			default:
+				return []*v1.ProwJob{pj}, nil, err
+			}
+		}
+	}
+	if pj.Retried() {
+		return []*v1.ProwJob{pj}, nil, nil
+	}
+
 	// TODO(krzyzacy): ditch ReportTemplate, and we can drop reference to config.Getter
 	err := report.ReportStatusContext(ctx, c.gc, *pj, c.config().GitHubReporter)
 	if err != nil {
@@ -152,6 +191,47 @@ func (c *Client) Report(ctx context.Context, log *logrus.Entry, pj *v1.ProwJob)
 	return []*v1.ProwJob{pj}, nil, err
 }
 
+// testFailures returns the failed tests of the job, read from the JUnit
+// results in its artifacts.
+func (c *Client) testFailures(ctx context.Context, log *logrus.Entry, pj *v1.ProwJob) []report.TestFailure {
+	if c.opener == nil || pj.Status.State != v1.FailureState || pj.Retried() {
+		return nil
+	}
+	bucket, dir, err := util.GetJobDestination(c.config, pj)
+	if err != nil {
+		log.WithError(err).Debug("Could not get the destination of the job, not annotating its check run")
+		return nil
+	}
+	path, err := providers.StoragePath(bucket, dir)
+	if err != nil {
+		log.WithError(err).Debug("Could not get the storage path of the job, not annotating its check run")
+		return nil
+	}
+	var failures []report.TestFailure
+	if err := flakes.ReadSuites(ctx, c.opener, path, func(suite junit.Suite) {
+		failures = appendFailures(failures, suite)
+	}); err != nil {
+		// The results are only used to annotate the check run, which
+		// is still worth reporting without them.
+		log.WithError(err).Info("Could not read the JUnit results of the job")
+	}
+	return failures
+}
+
+// appendFailures appends the failed tests of the suite and its sub-suites.
+func appendFailures(failures []report.TestFailure, suite junit.Suite) []report.TestFailure {
+	for _, sub := range suite.Suites {
+		failures = appendFailures(failures, sub)
+	}
+	for _, result := range suite.Results {
+		if result.Failure == nil && result.Errored == nil {
+			continue
+		}
+		failures = append(failures, report.TestFailure{Name: result.Name, Message: result.Message(maxFailureMessage)})
+	}
+	return failures
+}
+
 func pjsToReport(ctx context.Context, log *logrus.Entry, lister ctrlruntimeclient.Reader, pj *v1.ProwJob) ([]v1.ProwJob, error) {
 	if len(pj.Spec.Refs.Pulls) != 1 {
 		return nil, nil
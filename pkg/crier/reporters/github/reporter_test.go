@@ -23,6 +23,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/GoogleCloudPlatform/testgrid/metadata/junit"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/sirupsen/logrus"
@@ -30,7 +31,9 @@ import (
 
 	v1 "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
 	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/github"
 	"sigs.k8s.io/prow/pkg/github/fakegithub"
+	"sigs.k8s.io/prow/pkg/github/report"
 	"sigs.k8s.io/prow/pkg/kube"
 
 	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -83,6 +86,60 @@ func TestShouldReport(t *testing.T) {
 			},
 			report: true,
 		},
+		{
+			name: "should not report retried attempt",
+			pj: v1.ProwJob{
+				Spec: v1.ProwJobSpec{
+					Type:   v1.PresubmitJob,
+					Report: true,
+				},
+				Status: v1.ProwJobStatus{
+					Retry: &v1.RetryStatus{Attempt: 1, RetriedBy: "next"},
+				},
+			},
+			report: false,
+		},
+		{
+			name: "should report retried attempt with a check run",
+			pj: v1.ProwJob{
+				Spec: v1.ProwJobSpec{
+					Type:   v1.PresubmitJob,
+					Report: true,
+					Refs:   &v1.Refs{Org: "org", Repo: "checks"},
+				},
+				Status: v1.ProwJobStatus{
+					Retry: &v1.RetryStatus{Attempt: 1, RetriedBy: "next"},
+				},
+			},
+			report: true,
+		},
+		{
+			name: "should not report retried attempt without a check run",
+			pj: v1.ProwJob{
+				Spec: v1.ProwJobSpec{
+					Type:   v1.PresubmitJob,
+					Report: true,
+					Refs:   &v1.Refs{Org: "org", Repo: "statuses"},
+				},
+				Status: v1.ProwJobStatus{
+					Retry: &v1.RetryStatus{Attempt: 1, RetriedBy: "next"},
+				},
+			},
+			report: false,
+		},
+		{
+			name: "should report final attempt",
+			pj: v1.ProwJob{
+				Spec: v1.ProwJobSpec{
+					Type:   v1.PresubmitJob,
+					Report: true,
+				},
+				Status: v1.ProwJobStatus{
+					Retry: &v1.RetryStatus{Attempt: 2, PreviousAttempts: []string{"first"}},
+				},
+			},
+			report: true,
+		},
 		{
 			name: "github should not report gerrit jobs",
 			pj: v1.ProwJob{
@@ -101,7 +158,10 @@ func TestShouldReport(t *testing.T) {
 
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
-			c := NewReporter(nil, nil, tc.reportAgent, nil)
+			cfg := func() *config.Config {
+				return &config.Config{ProwConfig: config.ProwConfig{GitHubReporter: config.GitHubReporter{CheckRunRepos: []string{"org/checks"}}}}
+			}
+			c := NewReporter(nil, cfg, tc.reportAgent, nil, nil)
 			if r := c.ShouldReport(context.Background(), logrus.NewEntry(logrus.StandardLogger()), &tc.pj); r == tc.report {
 				return
 			}
@@ -132,6 +192,7 @@ func TestPresumitReportingLocks(t *testing.T) {
 		},
 		v1.ProwJobAgent(""),
 		nil,
+		nil,
 	)
 
 	pj := &v1.ProwJob{
@@ -263,6 +324,208 @@ func TestReport(t *testing.T) {
 	}
 }
 
+func TestReportCheckRuns(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name              string
+		config            config.GitHubReporter
+		expectedStatuses  int
+		expectedCheckRuns int
+	}{
+		{
+			name:             "status contexts only by default",
+			config:           config.GitHubReporter{JobTypesToReport: []v1.ProwJobType{v1.PostsubmitJob}},
+			expectedStatuses: 1,
+		},
+		{
+			name: "check runs coexist with status contexts",
+			config: config.GitHubReporter{
+				JobTypesToReport: []v1.ProwJobType{v1.PostsubmitJob},
+				CheckRunRepos:    []string{"org"},
+			},
+			expectedStatuses:  1,
+			expectedCheckRuns: 1,
+		},
+		{
+			name: "migrated repo only reports check runs",
+			config: config.GitHubReporter{
+				JobTypesToReport:     []v1.ProwJobType{v1.PostsubmitJob},
+				CheckRunRepos:        []string{"org/repo"},
+				NoStatusContextRepos: []string{"org/repo"},
+			},
+			expectedCheckRuns: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fghc := fakegithub.NewFakeClient()
+			c := NewReporter(fghc, func() *config.Config {
+				return &config.Config{ProwConfig: config.ProwConfig{GitHubReporter: tc.config}}
+			}, "", nil, nil)
+			pj := &v1.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{Name: "pj"},
+				Spec: v1.ProwJobSpec{
+					Type:    v1.PostsubmitJob,
+					Context: "post-unit",
+					Report:  true,
+					Refs:    &v1.Refs{Org: "org", Repo: "repo", BaseSHA: "sha"},
+				},
+				Status: v1.ProwJobStatus{State: v1.PendingState},
+			}
+			for _, state := range []v1.ProwJobState{v1.PendingState, v1.SuccessState} {
+				pj.Status.State = state
+				if state == v1.SuccessState {
+					pj.Status.CompletionTime = &metav1.Time{}
+				}
+				if _, _, err := c.Report(context.Background(), logrus.NewEntry(logrus.StandardLogger()), pj); err != nil {
+					t.Fatalf("error reporting: %v", err)
+				}
+			}
+			if n := len(fghc.CreatedStatuses["sha"]); n != tc.expectedStatuses {
+				t.Errorf("expected %d statuses, got %d", tc.expectedStatuses, n)
+			}
+			runs := fghc.CheckRuns["sha"]
+			if n := len(runs); n != tc.expectedCheckRuns {
+				t.Fatalf("expected %d check runs, got %d", tc.expectedCheckRuns, n)
+			}
+			for _, run := range runs {
+				if run.Status != "completed" || run.Conclusion != "success" {
+					t.Errorf("expected a successful check run, got status %q and conclusion %q", run.Status, run.Conclusion)
+				}
+			}
+		})
+	}
+}
+
+// forbiddenCheckRuns fails to create check runs, as GitHub does when crier
+// isn't authenticated as a GitHub App.
+type forbiddenCheckRuns struct {
+	*fakegithub.FakeClient
+}
+
+func (forbiddenCheckRuns) CreateCheckRun(org, repo string, checkRun github.CheckRun) (int64, error) {
+	return 0, errors.New(`status code 403 not one of [201], body: {"message":"You must authenticate via a GitHub App."}`)
+}
+
+func TestReportCheckRunFailure(t *testing.T) {
+	testCases := []struct {
+		name             string
+		config           config.GitHubReporter
+		expectedStatuses int
+		expectErr        bool
+	}{
+		{
+			name: "status contexts are reported anyway",
+			config: config.GitHubReporter{
+				JobTypesToReport: []v1.ProwJobType{v1.PostsubmitJob},
+				CheckRunRepos:    []string{"org"},
+			},
+			expectedStatuses: 1,
+		},
+		{
+			name: "error is returned for repos reported with check runs only",
+			config: config.GitHubReporter{
+				JobTypesToReport:     []v1.ProwJobType{v1.PostsubmitJob},
+				CheckRunRepos:        []string{"org"},
+				NoStatusContextRepos: []string{"org"},
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fghc := fakegithub.NewFakeClient()
+			c := NewReporter(forbiddenCheckRuns{fghc}, func() *config.Config {
+				return &config.Config{ProwConfig: config.ProwConfig{GitHubReporter: tc.config}}
+			}, "", nil, nil)
+			pj := &v1.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{Name: "pj"},
+				Spec: v1.ProwJobSpec{
+					Type:    v1.PostsubmitJob,
+					Context: "post-unit",
+					Report:  true,
+					Refs:    &v1.Refs{Org: "org", Repo: "repo", BaseSHA: "sha"},
+				},
+				Status: v1.ProwJobStatus{State: v1.PendingState},
+			}
+			_, _, err := c.Report(context.Background(), logrus.NewEntry(logrus.StandardLogger()), pj)
+			if (err != nil) != tc.expectErr {
+				t.Errorf("expected error: %t, got %v", tc.expectErr, err)
+			}
+			if n := len(fghc.CreatedStatuses["sha"]); n != tc.expectedStatuses {
+				t.Errorf("expected %d statuses, got %d", tc.expectedStatuses, n)
+			}
+		})
+	}
+}
+
+func TestReportRetriedAttempt(t *testing.T) {
+	fghc := fakegithub.NewFakeClient()
+	c := NewReporter(fghc, func() *config.Config {
+		return &config.Config{ProwConfig: config.ProwConfig{GitHubReporter: config.GitHubReporter{
+			JobTypesToReport: []v1.ProwJobType{v1.PostsubmitJob},
+			CheckRunRepos:    []string{"org"},
+		}}}
+	}, "", nil, nil)
+	pj := &v1.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "pj"},
+		Spec: v1.ProwJobSpec{
+			Type:    v1.PostsubmitJob,
+			Context: "post-unit",
+			Report:  true,
+			Refs:    &v1.Refs{Org: "org", Repo: "repo", BaseSHA: "sha"},
+		},
+		Status: v1.ProwJobStatus{State: v1.PendingState},
+	}
+	if _, _, err := c.Report(context.Background(), logrus.NewEntry(logrus.StandardLogger()), pj); err != nil {
+		t.Fatalf("error reporting: %v", err)
+	}
+	pj.Status.State = v1.FailureState
+	pj.Status.CompletionTime = &metav1.Time{}
+	pj.Status.Retry = &v1.RetryStatus{Attempt: 1, RetriedBy: "pj-attempt-2"}
+	if _, _, err := c.Report(context.Background(), logrus.NewEntry(logrus.StandardLogger()), pj); err != nil {
+		t.Fatalf("error reporting: %v", err)
+	}
+
+	runs := fghc.CheckRuns["sha"]
+	if len(runs) != 1 {
+		t.Fatalf("expected a check run, got %d", len(runs))
+	}
+	if runs[0].Status != "completed" || runs[0].Conclusion != "neutral" {
+		t.Errorf("expected a neutral check run, got status %q and conclusion %q", runs[0].Status, runs[0].Conclusion)
+	}
+	for _, status := range fghc.CreatedStatuses["sha"] {
+		if status.State != "pending" {
+			t.Errorf("expected the retried attempt not to be reported in the status context, got %q", status.State)
+		}
+	}
+}
+
+func TestAppendFailures(t *testing.T) {
+	failure := "foo_test.go:12: boom"
+	suite := junit.Suite{
+		Results: []junit.Result{
+			{Name: "TestPass"},
+			{Name: "TestFail", Failure: &junit.Failure{Value: failure}},
+		},
+		Suites: []junit.Suite{{
+			Results: []junit.Result{
+				{Name: "TestNested", Errored: &junit.Errored{Value: "panic"}},
+				{Name: "TestSkipped", Skipped: &junit.Skipped{Value: "skip"}},
+			},
+		}},
+	}
+	expected := []report.TestFailure{
+		{Name: "TestNested", Message: "panic"},
+		{Name: "TestFail", Message: failure},
+	}
+	if diff := cmp.Diff(expected, appendFailures(nil, suite)); diff != "" {
+		t.Errorf("failures differ from expected (-want +got):\n%s", diff)
+	}
+}
+
 func TestPjsToReport(t *testing.T) {
 	timeNow := time.Now().Truncate(time.Second) // Truncate so that comparison works.
 	var testcases = []struct {
@@ -0,0 +1,201 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhook contains a reporter that POSTs the state changes of the
+// ProwJobs to the HTTP endpoint configured on the job.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/crier/reporters/criercommonlib"
+	"sigs.k8s.io/prow/pkg/github"
+)
+
+const (
+	reporterName = "webhookreporter"
+
+	// SignatureHeader holds the HMAC signature of the payload, in the same
+	// format as the signature of the GitHub webhooks, e.g. "sha1=<hex>".
+	SignatureHeader = "X-Prow-Signature"
+	// EventHeader holds the kind of the event.
+	EventHeader = "X-Prow-Event"
+	// ProwJobEvent is the event of the state changes of the ProwJobs.
+	ProwJobEvent = "prowjob"
+
+	defaultContentType = "application/json"
+	requestTimeout     = 10 * time.Second
+)
+
+// retryBackoff is the backoff between the attempts to deliver a payload.
+var retryBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    4,
+}
+
+type reporter struct {
+	config config.Getter
+	client *http.Client
+	// hmacSecret returns the key the payloads are signed with, they are not
+	// signed when nil.
+	hmacSecret func() []byte
+	dryRun     bool
+	backoff    wait.Backoff
+}
+
+// New returns a reporter that POSTs the ProwJobs to the webhook configured on
+// them, signing the payloads with the key returned by hmacSecret, if set. Only
+// the webhooks allowed by the webhook_reporter config are delivered to.
+func New(cfg config.Getter, hmacSecret func() []byte, dryRun bool) *reporter {
+	return &reporter{
+		config: cfg,
+		client: &http.Client{
+			Timeout: requestTimeout,
+			// The redirects could lead the signed payloads out of the
+			// allowed webhooks.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		hmacSecret: hmacSecret,
+		dryRun:     dryRun,
+		backoff:    retryBackoff,
+	}
+}
+
+func (r *reporter) GetName() string {
+	return reporterName
+}
+
+func webhookConfig(pj *prowapi.ProwJob) *prowapi.WebhookReporterConfig {
+	if pj.Spec.ReporterConfig == nil {
+		return nil
+	}
+	return pj.Spec.ReporterConfig.Webhook
+}
+
+func (r *reporter) ShouldReport(_ context.Context, logger *logrus.Entry, pj *prowapi.ProwJob) bool {
+	cfg := webhookConfig(pj)
+	shouldReport := cfg != nil && cfg.URL != "" && cfg.ShouldReport(pj.Status.State)
+	logger.WithField("reporting", shouldReport).Debug("Determined should report")
+	return shouldReport
+}
+
+func (r *reporter) Report(ctx context.Context, log *logrus.Entry, pj *prowapi.ProwJob) ([]*prowapi.ProwJob, *reconcile.Result, error) {
+	return []*prowapi.ProwJob{pj}, nil, r.report(ctx, log, pj)
+}
+
+// payload returns the payload reporting the job.
+func payload(cfg *prowapi.WebhookReporterConfig, pj *prowapi.ProwJob) ([]byte, error) {
+	if cfg.PayloadTemplate == "" {
+		return json.Marshal(pj)
+	}
+	tmpl, err := template.New("").Parse(cfg.PayloadTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	b := &bytes.Buffer{}
+	if err := tmpl.Execute(b, pj); err != nil {
+		return nil, fmt.Errorf("failed to execute payload template: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+func (r *reporter) report(ctx context.Context, log *logrus.Entry, pj *prowapi.ProwJob) error {
+	cfg := webhookConfig(pj)
+	if cfg == nil {
+		return nil
+	}
+	if webhookReporter := r.config().WebhookReporter; !webhookReporter.Allows(cfg.URL) {
+		return criercommonlib.UserError(fmt.Errorf("webhook %s is not allowed by webhook_reporter.allowed_url_prefixes", cfg.URL))
+	}
+	body, err := payload(cfg, pj)
+	if err != nil {
+		return criercommonlib.UserError(err)
+	}
+	if r.dryRun {
+		log.WithField("url", cfg.URL).WithField("payload", string(body)).Debug("Skipping reporting because dry-run is enabled")
+		return nil
+	}
+
+	var lastErr error
+	err = wait.ExponentialBackoffWithContext(ctx, r.backoff, func(ctx context.Context) (bool, error) {
+		lastErr = r.deliver(ctx, cfg, body)
+		if lastErr == nil {
+			return true, nil
+		}
+		if criercommonlib.IsUserError(lastErr) {
+			return false, lastErr
+		}
+		log.WithError(lastErr).WithField("url", cfg.URL).Debug("Failed to deliver the webhook, retrying.")
+		return false, nil
+	})
+	if err != nil {
+		if lastErr != nil {
+			return lastErr
+		}
+		return fmt.Errorf("failed to deliver the webhook to %s: %w", cfg.URL, err)
+	}
+	return nil
+}
+
+// deliver POSTs the payload once. Client errors are returned as user errors
+// as retrying won't help.
+func (r *reporter) deliver(ctx context.Context, cfg *prowapi.WebhookReporterConfig, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return criercommonlib.UserError(fmt.Errorf("failed to create the request to %s: %w", cfg.URL, err))
+	}
+	contentType := cfg.ContentType
+	if contentType == "" {
+		contentType = defaultContentType
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set(EventHeader, ProwJobEvent)
+	if r.hmacSecret != nil {
+		req.Header.Set(SignatureHeader, github.PayloadSignature(body, r.hmacSecret()))
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to deliver the webhook to %s: %w", cfg.URL, err)
+	}
+	defer resp.Body.Close()
+	// Drain the body so that the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("webhook %s responded with status %d", cfg.URL, resp.StatusCode)
+	default:
+		return criercommonlib.UserError(fmt.Errorf("webhook %s responded with status %d", cfg.URL, resp.StatusCode))
+	}
+}
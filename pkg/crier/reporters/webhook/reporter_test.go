@@ -0,0 +1,262 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/crier/reporters/criercommonlib"
+	"sigs.k8s.io/prow/pkg/github"
+)
+
+func prowJob(cfg *prowapi.WebhookReporterConfig, state prowapi.ProwJobState) *prowapi.ProwJob {
+	return &prowapi.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "pj"},
+		Spec: prowapi.ProwJobSpec{
+			Job:            "job",
+			ReporterConfig: &prowapi.ReporterConfig{Webhook: cfg},
+		},
+		Status: prowapi.ProwJobStatus{State: state},
+	}
+}
+
+func TestShouldReport(t *testing.T) {
+	testCases := []struct {
+		name     string
+		pj       *prowapi.ProwJob
+		expected bool
+	}{
+		{
+			name:     "no reporter config",
+			pj:       &prowapi.ProwJob{Status: prowapi.ProwJobStatus{State: prowapi.SuccessState}},
+			expected: false,
+		},
+		{
+			name:     "no URL",
+			pj:       prowJob(&prowapi.WebhookReporterConfig{}, prowapi.SuccessState),
+			expected: false,
+		},
+		{
+			name:     "all states",
+			pj:       prowJob(&prowapi.WebhookReporterConfig{URL: "https://example.com"}, prowapi.PendingState),
+			expected: true,
+		},
+		{
+			name: "matching state",
+			pj: prowJob(&prowapi.WebhookReporterConfig{
+				URL:               "https://example.com",
+				JobStatesToReport: []prowapi.ProwJobState{prowapi.FailureState, prowapi.ErrorState},
+			}, prowapi.FailureState),
+			expected: true,
+		},
+		{
+			name: "other state",
+			pj: prowJob(&prowapi.WebhookReporterConfig{
+				URL:               "https://example.com",
+				JobStatesToReport: []prowapi.ProwJobState{prowapi.FailureState, prowapi.ErrorState},
+			}, prowapi.SuccessState),
+			expected: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := New(func() *config.Config { return &config.Config{} }, nil, false)
+			if got := r.ShouldReport(context.Background(), logrus.NewEntry(logrus.StandardLogger()), tc.pj); got != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, got)
+			}
+		})
+	}
+}
+
+type request struct {
+	ContentType string
+	Event       string
+	Signature   string
+	Body        string
+}
+
+func TestReport(t *testing.T) {
+	secret := []byte("secret")
+	testCases := []struct {
+		name            string
+		cfg             prowapi.WebhookReporterConfig
+		hmacSecret      func() []byte
+		statuses        []int
+		dryRun          bool
+		notAllowed      bool
+		expectedBody    string
+		expectedCalls   int
+		expectErr       bool
+		expectUserError bool
+	}{
+		{
+			name:          "template payload",
+			cfg:           prowapi.WebhookReporterConfig{PayloadTemplate: `{"job":"{{.Spec.Job}}","state":"{{.Status.State}}"}`},
+			statuses:      []int{http.StatusOK},
+			expectedBody:  `{"job":"job","state":"failure"}`,
+			expectedCalls: 1,
+		},
+		{
+			name:          "signed payload",
+			cfg:           prowapi.WebhookReporterConfig{PayloadTemplate: `{{.Spec.Job}}`, ContentType: "text/plain"},
+			hmacSecret:    func() []byte { return secret },
+			statuses:      []int{http.StatusNoContent},
+			expectedBody:  "job",
+			expectedCalls: 1,
+		},
+		{
+			name:          "retries server errors",
+			cfg:           prowapi.WebhookReporterConfig{PayloadTemplate: `{{.Spec.Job}}`},
+			statuses:      []int{http.StatusBadGateway, http.StatusTooManyRequests, http.StatusOK},
+			expectedBody:  "job",
+			expectedCalls: 3,
+		},
+		{
+			name:          "gives up",
+			cfg:           prowapi.WebhookReporterConfig{PayloadTemplate: `{{.Spec.Job}}`},
+			statuses:      []int{http.StatusInternalServerError},
+			expectedBody:  "job",
+			expectedCalls: 3,
+			expectErr:     true,
+		},
+		{
+			name:            "client errors are not retried",
+			cfg:             prowapi.WebhookReporterConfig{PayloadTemplate: `{{.Spec.Job}}`},
+			statuses:        []int{http.StatusNotFound},
+			expectedBody:    "job",
+			expectedCalls:   1,
+			expectErr:       true,
+			expectUserError: true,
+		},
+		{
+			name:            "redirects are not followed",
+			cfg:             prowapi.WebhookReporterConfig{PayloadTemplate: `{{.Spec.Job}}`},
+			statuses:        []int{http.StatusTemporaryRedirect, http.StatusOK},
+			expectedBody:    "job",
+			expectedCalls:   1,
+			expectErr:       true,
+			expectUserError: true,
+		},
+		{
+			name:            "webhook not allowed",
+			cfg:             prowapi.WebhookReporterConfig{PayloadTemplate: `{{.Spec.Job}}`},
+			statuses:        []int{http.StatusOK},
+			notAllowed:      true,
+			expectErr:       true,
+			expectUserError: true,
+		},
+		{
+			name:            "invalid template",
+			cfg:             prowapi.WebhookReporterConfig{PayloadTemplate: `{{.Spec.Unknown}}`},
+			expectErr:       true,
+			expectUserError: true,
+		},
+		{
+			name:     "dry run",
+			cfg:      prowapi.WebhookReporterConfig{},
+			statuses: []int{http.StatusOK},
+			dryRun:   true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var lock sync.Mutex
+			var requests []request
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				lock.Lock()
+				defer lock.Unlock()
+				requests = append(requests, request{
+					ContentType: r.Header.Get("Content-Type"),
+					Event:       r.Header.Get(EventHeader),
+					Signature:   r.Header.Get(SignatureHeader),
+					Body:        string(body),
+				})
+				status := tc.statuses[len(tc.statuses)-1]
+				if len(requests) <= len(tc.statuses) {
+					status = tc.statuses[len(requests)-1]
+				}
+				w.Header().Set("Location", "/redirected")
+				w.WriteHeader(status)
+			}))
+			defer server.Close()
+
+			allowed := []string{server.URL}
+			if tc.notAllowed {
+				allowed = []string{"https://hooks.example.com"}
+			}
+			cfg := func() *config.Config {
+				return &config.Config{ProwConfig: config.ProwConfig{WebhookReporter: config.WebhookReporter{AllowedURLPrefixes: allowed}}}
+			}
+			r := New(cfg, tc.hmacSecret, tc.dryRun)
+			r.backoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 3}
+			webhook := tc.cfg
+			webhook.URL = server.URL
+			_, _, err := r.Report(context.Background(), logrus.NewEntry(logrus.StandardLogger()), prowJob(&webhook, prowapi.FailureState))
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error: %t, got: %v", tc.expectErr, err)
+			}
+			if err != nil && criercommonlib.IsUserError(err) != tc.expectUserError {
+				t.Errorf("expected user error: %t, got: %v", tc.expectUserError, err)
+			}
+			if len(requests) != tc.expectedCalls {
+				t.Fatalf("expected %d calls, got %d", tc.expectedCalls, len(requests))
+			}
+			for _, req := range requests {
+				expected := request{ContentType: "application/json", Event: ProwJobEvent, Body: tc.expectedBody}
+				if tc.cfg.ContentType != "" {
+					expected.ContentType = tc.cfg.ContentType
+				}
+				if tc.hmacSecret != nil {
+					expected.Signature = github.PayloadSignature([]byte(tc.expectedBody), secret)
+				}
+				if diff := cmp.Diff(expected, req); diff != "" {
+					t.Errorf("unexpected request: %s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultPayload(t *testing.T) {
+	pj := prowJob(&prowapi.WebhookReporterConfig{URL: "https://example.com"}, prowapi.SuccessState)
+	b, err := payload(pj.Spec.ReporterConfig.Webhook, pj)
+	if err != nil {
+		t.Fatalf("failed to build the payload: %v", err)
+	}
+	var got prowapi.ProwJob
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("expected the ProwJob as JSON, got %s: %v", b, err)
+	}
+	if diff := cmp.Diff(pj, &got); diff != "" {
+		t.Errorf("unexpected payload: %s", diff)
+	}
+}
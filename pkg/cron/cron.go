@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	cron "gopkg.in/robfig/cron.v2" // using v2 api, doc at https://godoc.org/gopkg.in/robfig/cron.v2
@@ -36,8 +37,14 @@ type jobStatus struct {
 	entryID cron.EntryID
 	// triggered marks if a job has been triggered for the next cron.QueuedJobs() call
 	triggered bool
+	// added marks if a job has been added at startup and still has to catch
+	// up on the triggers missed while horologium was down
+	added bool
+	// catchUpRuns is the number of catch-up runs left, once computed
+	catchUpRuns    int
+	catchUpCounted bool
 	// cronStr is a cache for job's cron status
-	// cron entry will be regenerated if cron string changes from the periodic job
+	// cron entry will be regenerated if cron string or timezone changes from the periodic job
 	cronStr string
 }
 
@@ -47,6 +54,9 @@ type Cron struct {
 	jobs      map[string]*jobStatus
 	logger    *logrus.Entry
 	lock      sync.Mutex
+	// synced is set once the config got synced, the jobs added later are
+	// new or got their cron updated and missed no trigger.
+	synced bool
 }
 
 // New makes a new Cron object
@@ -84,6 +94,51 @@ func (c *Cron) QueuedJobs() []string {
 	return res
 }
 
+// AddedJobs returns a list of jobs that have been added by the first sync of
+// the config, for which triggers may have been missed while horologium was
+// down, until their catch-up is done
+func (c *Cron) AddedJobs() []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	res := []string{}
+	for k, v := range c.jobs {
+		if v.added {
+			res = append(res, k)
+		}
+	}
+	return res
+}
+
+// CatchUpRuns returns the catch-up runs left for an added job, and whether
+// they were set already
+func (c *Cron) CatchUpRuns(name string) (int, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	job, ok := c.jobs[name]
+	if !ok {
+		return 0, false
+	}
+	return job.catchUpRuns, job.catchUpCounted
+}
+
+// SetCatchUpRuns sets the catch-up runs left for an added job, the catch-up
+// of the job is done once none is left
+func (c *Cron) SetCatchUpRuns(name string, runs int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	job, ok := c.jobs[name]
+	if !ok {
+		return
+	}
+	job.catchUpRuns, job.catchUpCounted = runs, true
+	if runs <= 0 {
+		job.added = false
+	}
+}
+
 // SyncConfig syncs current cronAgent with current prow config
 // which add/delete jobs accordingly.
 func (c *Cron) SyncConfig(cfg *config.Config) error {
@@ -95,6 +150,7 @@ func (c *Cron) SyncConfig(cfg *config.Config) error {
 			return err
 		}
 	}
+	c.synced = true
 
 	periodicNames := sets.New[string]()
 	for _, p := range cfg.AllPeriodics() {
@@ -130,8 +186,9 @@ func (c *Cron) addPeriodic(p config.Periodic) error {
 		return nil
 	}
 
+	spec := cronSpec(p)
 	if job, ok := c.jobs[p.Name]; ok {
-		if job.cronStr == p.Cron {
+		if job.cronStr == spec {
 			return nil
 		}
 		// job updated, remove old entry
@@ -140,16 +197,38 @@ func (c *Cron) addPeriodic(p config.Periodic) error {
 		}
 	}
 
-	if err := c.addJob(p.Name, p.Cron); err != nil {
+	if err := c.addJob(p.Name, spec); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// cronSpec returns the cron expression of the periodic in its timezone
+func cronSpec(p config.Periodic) string {
+	return fmt.Sprintf("TZ=%s %s", p.GetLocation(), p.Cron)
+}
+
+// MissedTriggers returns the times the periodic should have been triggered
+// after since and until now, oldest first, outside of its exclusion windows
+func MissedTriggers(h *config.Horologium, p config.Periodic, since, now time.Time) ([]time.Time, error) {
+	schedule, err := cron.Parse(cronSpec(p))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cron %s of job %s: %w", p.Cron, p.Name, err)
+	}
+
+	var missed []time.Time
+	for t := schedule.Next(since); !t.IsZero() && !t.After(now); t = schedule.Next(t) {
+		if h.ExcludingWindow(&p, t) == "" {
+			missed = append(missed, t)
+		}
+	}
+	return missed, nil
+}
+
 // addJob adds a cron entry for a job to cronAgent
-func (c *Cron) addJob(name, cron string) error {
-	id, err := c.cronAgent.AddFunc("TZ=UTC "+cron, func() {
+func (c *Cron) addJob(name, spec string) error {
+	id, err := c.cronAgent.AddFunc(spec, func() {
 		c.lock.Lock()
 		defer c.lock.Unlock()
 
@@ -158,17 +237,18 @@ func (c *Cron) addJob(name, cron string) error {
 	})
 
 	if err != nil {
-		return fmt.Errorf("cronAgent fails to add job %s with cron %s: %w", name, cron, err)
+		return fmt.Errorf("cronAgent fails to add job %s with cron %s: %w", name, spec, err)
 	}
 
 	c.jobs[name] = &jobStatus{
 		entryID: id,
-		cronStr: cron,
+		cronStr: spec,
 		// try to kick of a periodic trigger right away
-		triggered: strings.HasPrefix(cron, "@every"),
+		triggered: strings.Contains(spec, " @every"),
+		added:     !c.synced,
 	}
 
-	c.logger.Infof("Added new cron job %s with trigger %s.", name, cron)
+	c.logger.Infof("Added new cron job %s with trigger %s.", name, spec)
 	return nil
 }
 
@@ -18,8 +18,13 @@ package cron
 
 import (
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
 	cron "gopkg.in/robfig/cron.v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
 	"sigs.k8s.io/prow/pkg/config"
 )
 
@@ -200,3 +205,145 @@ func TestTrigger(t *testing.T) {
 		t.Error("should have triggered job 'periodic'")
 	}
 }
+
+func TestAddedJobs(t *testing.T) {
+	c := New()
+	periodic := config.Periodic{
+		JobBase: config.JobBase{
+			Name: "cron",
+		},
+		Cron: "0 8 * * *",
+	}
+	cfg := &config.Config{JobConfig: config.JobConfig{Periodics: []config.Periodic{periodic}}}
+
+	if err := c.SyncConfig(cfg); err != nil {
+		t.Fatalf("error sync config: %v", err)
+	}
+	if added := c.AddedJobs(); !sets.New(added...).Equal(sets.New("cron")) {
+		t.Errorf("1st sync, expected job 'cron' to be added, got %v", added)
+	}
+
+	if err := c.SyncConfig(cfg); err != nil {
+		t.Fatalf("error sync config: %v", err)
+	}
+	if added := c.AddedJobs(); !sets.New(added...).Equal(sets.New("cron")) {
+		t.Errorf("2nd sync, expected job 'cron' to be added until it caught up, got %v", added)
+	}
+	if _, counted := c.CatchUpRuns("cron"); counted {
+		t.Error("expected the catch-up runs of job 'cron' not to be counted yet")
+	}
+	c.SetCatchUpRuns("cron", 2)
+	if runs, counted := c.CatchUpRuns("cron"); runs != 2 || !counted {
+		t.Errorf("expected 2 catch-up runs left for job 'cron', got %d (counted: %t)", runs, counted)
+	}
+	if added := c.AddedJobs(); !sets.New(added...).Equal(sets.New("cron")) {
+		t.Errorf("expected job 'cron' to be added while catching up, got %v", added)
+	}
+	c.SetCatchUpRuns("cron", 0)
+	if added := c.AddedJobs(); len(added) != 0 {
+		t.Errorf("expected no added job once caught up, got %v", added)
+	}
+
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+	cfg.Periodics[0].SetLocation(loc)
+	if err := c.SyncConfig(cfg); err != nil {
+		t.Fatalf("error sync config: %v", err)
+	}
+	if added := c.AddedJobs(); len(added) != 0 {
+		t.Errorf("3rd sync, expected job 'cron' updated with its new timezone not to be added, got %v", added)
+	}
+	if expected := "TZ=Europe/Berlin 0 8 * * *"; c.jobs["cron"].cronStr != expected {
+		t.Errorf("expected cron %q, got %q", expected, c.jobs["cron"].cronStr)
+	}
+
+	cfg.Periodics = append(cfg.Periodics, config.Periodic{JobBase: config.JobBase{Name: "new"}, Cron: "0 9 * * *"})
+	if err := c.SyncConfig(cfg); err != nil {
+		t.Fatalf("error sync config: %v", err)
+	}
+	if added := c.AddedJobs(); len(added) != 0 {
+		t.Errorf("4th sync, expected the new job 'new' not to be added, got %v", added)
+	}
+	if !c.HasJob("new") {
+		t.Error("expected the new job 'new' to be scheduled")
+	}
+}
+
+func TestMissedTriggers(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+	horologium := &config.Horologium{
+		ExclusionWindows: map[string]config.ExclusionWindow{
+			"weekends": {Weekdays: []string{"Saturday", "Sunday"}},
+			"freeze": {
+				Start: &metav1.Time{Time: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+				End:   &metav1.Time{Time: time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC)},
+			},
+		},
+	}
+	// Friday.
+	since := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name       string
+		location   *time.Location
+		exclusions []string
+		now        time.Time
+		expected   []time.Time
+	}{
+		{
+			name: "nothing missed",
+			now:  time.Date(2024, 3, 2, 7, 59, 0, 0, time.UTC),
+		},
+		{
+			name: "missed in UTC",
+			now:  time.Date(2024, 3, 3, 8, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2024, 3, 2, 8, 0, 0, 0, time.UTC),
+				time.Date(2024, 3, 3, 8, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:     "missed in the timezone of the periodic",
+			location: berlin,
+			now:      time.Date(2024, 3, 3, 8, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2024, 3, 2, 7, 0, 0, 0, time.UTC),
+				time.Date(2024, 3, 3, 7, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:       "excluded triggers are not missed",
+			exclusions: []string{"weekends", "freeze"},
+			now:        time.Date(2024, 3, 6, 9, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC),
+				time.Date(2024, 3, 6, 8, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := config.Periodic{
+				JobBase:          config.JobBase{Name: "cron"},
+				Cron:             "0 8 * * *",
+				ExclusionWindows: tc.exclusions,
+			}
+			p.SetLocation(tc.location)
+			missed, err := MissedTriggers(horologium, p, since, tc.now)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for i := range missed {
+				missed[i] = missed[i].UTC()
+			}
+			if diff := cmp.Diff(tc.expected, missed); diff != "" {
+				t.Errorf("missed triggers differ from expected (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,362 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package durations
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
+
+	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/flakes"
+	pio "sigs.k8s.io/prow/pkg/io"
+	"sigs.k8s.io/prow/pkg/io/providers"
+)
+
+// SlackClient is the subset of the Slack client required to report
+// regressions.
+type SlackClient interface {
+	WriteMessage(text, channel string) error
+}
+
+// Run holds the durations of a finished run of a job.
+type Run struct {
+	Job      string
+	BuildID  string
+	Finished time.Time
+	// Passed is whether the job succeeded, the durations of the other runs
+	// are not comparable.
+	Passed   bool
+	Duration time.Duration
+	// Tests are the durations of the tests that passed.
+	Tests map[string]time.Duration
+}
+
+// Report lists the current regressions.
+type Report struct {
+	Generated   time.Time    `json:"generated"`
+	Regressions []Regression `json:"regressions"`
+}
+
+// Trend holds the durations of a job and of its regressed tests.
+type Trend struct {
+	Job         Series       `json:"job"`
+	Tests       []Series     `json:"tests,omitempty"`
+	Regressions []Regression `json:"regressions,omitempty"`
+}
+
+// Opener is the subset of pio.Opener required to record the reported
+// regressions.
+type Opener interface {
+	Writer(ctx context.Context, path string, opts ...pio.WriterOptions) (pio.WriteCloser, error)
+	Iterator(ctx context.Context, prefix, delimiter string) (pio.ObjectIterator, error)
+	Delete(ctx context.Context, path string) error
+}
+
+// Detector detects the regressions of the durations of the runs ingested by
+// the flakes ingester, and reports the new ones.
+type Detector struct {
+	ctx    context.Context
+	opener Opener
+	config config.Getter
+	slack  SlackClient
+	logger *logrus.Entry
+
+	lock   sync.RWMutex
+	runs   []Run
+	report Report
+}
+
+// NewDetector returns a Detector of the duration regressions. The regressions
+// are reported to Slack when slack is set.
+func NewDetector(ctx context.Context, opener Opener, cfg config.Getter, slack SlackClient) *Detector {
+	return &Detector{
+		ctx:    ctx,
+		opener: opener,
+		config: cfg,
+		slack:  slack,
+		logger: logrus.WithField("component", "durations"),
+	}
+}
+
+func regressionKey(r Regression) string {
+	return r.Job + "/" + r.Test
+}
+
+// Sync detects the regressions in the ingested runs that finished within the
+// lookback and reports the new ones. It is meant to be registered with
+// flakes.Ingester.OnSync.
+func (d *Detector) Sync(ingested []flakes.Run) error {
+	cfg := d.config().Deck.Durations
+	if cfg == nil {
+		return nil
+	}
+	cutoff := time.Now().Add(-cfg.Lookback.Duration)
+
+	var runs []Run
+	for _, run := range ingested {
+		if run.Finished.Before(cutoff) {
+			continue
+		}
+		runs = append(runs, Run{
+			Job:      run.Job,
+			BuildID:  run.BuildID,
+			Finished: run.Finished,
+			Passed:   run.Passed,
+			Duration: run.Duration,
+			Tests:    run.Durations,
+		})
+	}
+	opts := Options{
+		RecentRuns:         cfg.RecentRuns,
+		MinBaselineRuns:    cfg.MinBaselineRuns,
+		MinIncreasePercent: cfg.MinIncreasePercent,
+		MinDuration:        cfg.MinDuration.Duration,
+	}
+	report := Report{Generated: time.Now(), Regressions: Compute(runs, opts)}
+	d.lock.Lock()
+	d.runs = runs
+	d.report = report
+	d.lock.Unlock()
+	d.logger.WithField("runs", len(runs)).WithField("regressions", len(report.Regressions)).Debug("Computed the duration regressions.")
+
+	return d.notify(cfg, report.Regressions)
+}
+
+// notify reports the regressions that weren't reported yet to the Slack
+// channel, if any. Every reported regression is recorded under the alerts
+// path by a write that fails when the record exists, so that it is reported
+// once across the restarts and the replicas of Deck. The records of the
+// regressions that are over are deleted, they are reported again if they
+// come back.
+func (d *Detector) notify(cfg *config.Durations, regressions []Regression) error {
+	if d.slack == nil || cfg.SlackChannel == "" || cfg.AlertsPath == "" {
+		return nil
+	}
+	alerted, err := d.alerted(cfg.AlertsPath)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	var fresh []Regression
+	current := sets.New[string]()
+	for _, r := range regressions {
+		path := alertPath(cfg.AlertsPath, r)
+		current.Insert(path)
+		if alerted.Has(path) {
+			continue
+		}
+		claimed, err := d.claim(path, r)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if claimed {
+			fresh = append(fresh, r)
+		}
+	}
+	for _, path := range sets.List(alerted.Difference(current)) {
+		if err := d.opener.Delete(d.ctx, path); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete %s: %w", path, err))
+		}
+	}
+
+	if len(fresh) > 0 {
+		lines := []string{"Detected duration regressions:"}
+		for _, r := range fresh {
+			lines = append(lines, "• "+r.String())
+		}
+		if err := d.slack.WriteMessage(strings.Join(lines, "\n"), cfg.SlackChannel); err != nil {
+			errs = append(errs, fmt.Errorf("failed to report the regressions to %s: %w", cfg.SlackChannel, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// alertPath returns the path of the record of the reported regression.
+func alertPath(alertsPath string, r Regression) string {
+	return fmt.Sprintf("%s/%x.json", strings.TrimSuffix(alertsPath, "/"), sha256.Sum256([]byte(regressionKey(r))))
+}
+
+// alerted returns the paths of the records of the reported regressions.
+func (d *Detector) alerted(alertsPath string) (sets.Set[string], error) {
+	provider, bucket, _, err := providers.ParseStoragePath(alertsPath)
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.TrimSuffix(alertsPath, "/") + "/"
+	it, err := d.opener.Iterator(d.ctx, prefix, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", prefix, err)
+	}
+	alerted := sets.New[string]()
+	for {
+		attrs, err := it.Next(d.ctx)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", prefix, err)
+		}
+		if !attrs.IsDir {
+			alerted.Insert(fmt.Sprintf("%s://%s/%s", provider, bucket, strings.TrimPrefix(attrs.Name, "/")))
+		}
+	}
+	return alerted, nil
+}
+
+// claim records the regression at path, unless it is already recorded. It
+// returns whether the regression was recorded, and so should be reported.
+func (d *Detector) claim(path string, r Regression) (bool, error) {
+	content, err := json.Marshal(r)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal the regression: %w", err)
+	}
+	w, err := d.opener.Writer(d.ctx, path, pio.WriterOptions{PreconditionDoesNotExist: ptr.To(true)})
+	if err == nil {
+		if _, err = w.Write(content); err != nil {
+			pio.LogClose(w)
+		} else {
+			err = w.Close()
+		}
+	}
+	if pio.IsPreconditionFailed(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// jobSeries are the durations of a job and of its tests.
+type jobSeries struct {
+	// job are the durations of the passing runs of the job.
+	job Series
+	// tests are the durations of each test in the runs it passed in.
+	tests map[string]*Series
+}
+
+// seriesByJob groups the durations of the runs by job and test in a single
+// pass over the runs.
+func seriesByJob(runs []Run) map[string]*jobSeries {
+	jobs := make(map[string]*jobSeries)
+	for _, run := range runs {
+		series, ok := jobs[run.Job]
+		if !ok {
+			series = &jobSeries{job: Series{Job: run.Job}, tests: make(map[string]*Series)}
+			jobs[run.Job] = series
+		}
+		if run.Passed {
+			series.job.Points = append(series.job.Points, Point{BuildID: run.BuildID, Finished: run.Finished, Duration: run.Duration})
+		}
+		for test, d := range run.Tests {
+			testSeries, ok := series.tests[test]
+			if !ok {
+				testSeries = &Series{Job: run.Job, Test: test}
+				series.tests[test] = testSeries
+			}
+			testSeries.Points = append(testSeries.Points, Point{BuildID: run.BuildID, Finished: run.Finished, Duration: d})
+		}
+	}
+	for _, series := range jobs {
+		sortPoints(series.job.Points)
+		for _, testSeries := range series.tests {
+			sortPoints(testSeries.Points)
+		}
+	}
+	return jobs
+}
+
+func sortPoints(points []Point) {
+	sort.Slice(points, func(i, j int) bool { return points[i].Finished.Before(points[j].Finished) })
+}
+
+// Compute returns the regressions of the jobs and of their tests, the
+// largest increase first.
+func Compute(runs []Run, opts Options) []Regression {
+	var regressions []Regression
+	for _, series := range seriesByJob(runs) {
+		if r, ok := Detect(series.job, opts); ok {
+			regressions = append(regressions, r)
+		}
+		for _, testSeries := range series.tests {
+			if r, ok := Detect(*testSeries, opts); ok {
+				regressions = append(regressions, r)
+			}
+		}
+	}
+	sort.Slice(regressions, func(i, j int) bool {
+		if regressions[i].IncreasePercent != regressions[j].IncreasePercent {
+			return regressions[i].IncreasePercent > regressions[j].IncreasePercent
+		}
+		return regressionKey(regressions[i]) < regressionKey(regressions[j])
+	})
+	return regressions
+}
+
+// Report returns the last computed report.
+func (d *Detector) Report() Report {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	return d.report
+}
+
+// Trend returns the durations of the job and of its regressed tests.
+func (d *Detector) Trend(job string) Trend {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	var runs []Run
+	for _, run := range d.runs {
+		if run.Job == job {
+			runs = append(runs, run)
+		}
+	}
+	series, ok := seriesByJob(runs)[job]
+	if !ok {
+		series = &jobSeries{job: Series{Job: job}}
+	}
+	trend := Trend{Job: series.job}
+	for _, r := range d.report.Regressions {
+		if r.Job != job {
+			continue
+		}
+		trend.Regressions = append(trend.Regressions, r)
+		if r.Test == "" {
+			continue
+		}
+		testSeries, ok := series.tests[r.Test]
+		if !ok {
+			testSeries = &Series{Job: job, Test: r.Test}
+		}
+		trend.Tests = append(trend.Tests, *testSeries)
+	}
+	return trend
+}
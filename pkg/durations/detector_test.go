@@ -0,0 +1,199 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package durations
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/flakes"
+	pio "sigs.k8s.io/prow/pkg/io"
+)
+
+// fakeOpener stores the files in memory, keyed by their full path.
+type fakeOpener struct {
+	files map[string]string
+}
+
+type fakeWriter struct {
+	bytes.Buffer
+	close func(content string)
+}
+
+func (w *fakeWriter) Close() error {
+	w.close(w.String())
+	return nil
+}
+
+func (o *fakeOpener) Writer(_ context.Context, path string, opts ...pio.WriterOptions) (pio.WriteCloser, error) {
+	for _, opt := range opts {
+		if _, exists := o.files[path]; exists && opt.PreconditionDoesNotExist != nil && *opt.PreconditionDoesNotExist {
+			return nil, os.ErrExist
+		}
+	}
+	return &fakeWriter{close: func(content string) { o.files[path] = content }}, nil
+}
+
+func (o *fakeOpener) Delete(_ context.Context, path string) error {
+	delete(o.files, path)
+	return nil
+}
+
+func (o *fakeOpener) Iterator(_ context.Context, prefix, _ string) (pio.ObjectIterator, error) {
+	var attrs []pio.ObjectAttributes
+	for path := range o.files {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		// Names are relative to the bucket.
+		name := strings.SplitN(strings.TrimPrefix(path, "gs://"), "/", 2)[1]
+		attrs = append(attrs, pio.ObjectAttributes{Name: name, ObjName: name[strings.LastIndex(name, "/")+1:]})
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Name < attrs[j].Name })
+	return &fakeIterator{attrs: attrs}, nil
+}
+
+type fakeIterator struct {
+	attrs []pio.ObjectAttributes
+}
+
+func (i *fakeIterator) Next(_ context.Context) (pio.ObjectAttributes, error) {
+	if len(i.attrs) == 0 {
+		return pio.ObjectAttributes{}, io.EOF
+	}
+	attr := i.attrs[0]
+	i.attrs = i.attrs[1:]
+	return attr, nil
+}
+
+type fakeSlack struct {
+	messages []string
+}
+
+func (s *fakeSlack) WriteMessage(text, channel string) error {
+	s.messages = append(s.messages, channel+": "+text)
+	return nil
+}
+
+func TestDetector(t *testing.T) {
+	now := time.Now()
+	var runs []flakes.Run
+	addRun := func(build int, passed bool, duration time.Duration, tests map[string]time.Duration) {
+		runs = append(runs, flakes.Run{
+			Job:       "job",
+			BuildID:   fmt.Sprint(build),
+			Finished:  now.Add(time.Duration(build-20) * time.Hour),
+			Passed:    passed,
+			Duration:  duration,
+			Durations: tests,
+		})
+	}
+	// TestSlow doubles from build 6 on, the job only slows down by a fifth
+	// and TestFast fails in most runs.
+	for build := 1; build <= 7; build++ {
+		slow, duration := 30*time.Second, 3*time.Minute
+		if build >= 6 {
+			slow, duration = time.Minute, 4*time.Minute
+		}
+		tests := map[string]time.Duration{"pkg: TestSlow": slow}
+		if build%2 != 0 {
+			tests["pkg: TestFast"] = 2 * time.Second
+		}
+		addRun(build, true, duration, tests)
+	}
+	// Failed runs don't count towards the duration of the job.
+	addRun(8, false, time.Minute, map[string]time.Duration{"pkg: TestSlow": time.Minute})
+	// Too old.
+	addRun(-100, true, time.Hour, map[string]time.Duration{"pkg: TestSlow": time.Second, "pkg: TestFast": 2 * time.Second})
+
+	cfg := func() *config.Config {
+		return &config.Config{ProwConfig: config.ProwConfig{Deck: config.Deck{Durations: &config.Durations{
+			Lookback:           &metav1.Duration{Duration: 24 * time.Hour},
+			RecentRuns:         3,
+			MinBaselineRuns:    4,
+			MinIncreasePercent: 50,
+			MinDuration:        &metav1.Duration{Duration: time.Second},
+			SlackChannel:       "alerts",
+			AlertsPath:         "gs://bucket/durations/alerts",
+		}}}}
+	}
+
+	opener := &fakeOpener{files: map[string]string{}}
+	slack := &fakeSlack{}
+	detector := NewDetector(context.Background(), opener, cfg, slack)
+	if err := detector.Sync(runs); err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	report := detector.Report()
+	want := []Regression{{Job: "job", Test: "pkg: TestSlow", Baseline: 30 * time.Second, Recent: time.Minute, IncreasePercent: 100, FirstBuild: "6"}}
+	if diff := cmp.Diff(want, report.Regressions, cmpopts.IgnoreFields(Regression{}, "TStatistic"), cmpopts.EquateApprox(0, 0.01)); diff != "" {
+		t.Errorf("Unexpected regressions: %s", diff)
+	}
+	expectedMessage := "alerts: Detected duration regressions:\n• pkg: TestSlow of job slowed down by 100% since build 6: 1m0s on average, was 30s"
+	if diff := cmp.Diff([]string{expectedMessage}, slack.messages); diff != "" {
+		t.Errorf("Unexpected Slack messages: %s", diff)
+	}
+	if _, ok := opener.files[alertPath("gs://bucket/durations/alerts", want[0])]; !ok {
+		t.Errorf("Expected the reported regression to be recorded, got %v", opener.files)
+	}
+
+	trend := detector.Trend("job")
+	if n := len(trend.Job.Points); n != 7 {
+		t.Errorf("Expected the 7 passing runs in the job series, got %d", n)
+	}
+	if len(trend.Tests) != 1 || len(trend.Tests[0].Points) != 8 {
+		t.Errorf("Expected the 8 runs of the regressed test, got %+v", trend.Tests)
+	}
+
+	// Known regressions are only reported once, also by a restarted or
+	// another replica of Deck.
+	if err := detector.Sync(runs); err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	if err := NewDetector(context.Background(), opener, cfg, slack).Sync(runs); err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	if n := len(slack.messages); n != 1 {
+		t.Errorf("Expected the regression to be reported once, got %d messages", n)
+	}
+
+	// A regression that is over is reported again when it comes back.
+	if err := detector.Sync(runs[:5]); err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	if len(opener.files) != 0 {
+		t.Errorf("Expected the record of the regression to be deleted, got %v", opener.files)
+	}
+	if err := detector.Sync(runs); err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	if n := len(slack.messages); n != 2 {
+		t.Errorf("Expected the regression to be reported again, got %d messages", n)
+	}
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package durations aggregates the durations of the jobs and of their tests
+// into time series and detects the significant slowdowns.
+package durations
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// minTStatistic is the Welch's t-statistic above which the difference
+// between the recent and the baseline durations is deemed significant.
+const minTStatistic = 3
+
+// Point is the duration of a job or test in a run.
+type Point struct {
+	BuildID  string        `json:"build_id"`
+	Finished time.Time     `json:"finished"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Series holds the durations of a job, or of one of its tests when Test is
+// set, oldest first.
+type Series struct {
+	Job    string  `json:"job"`
+	Test   string  `json:"test,omitempty"`
+	Points []Point `json:"points"`
+}
+
+// Regression is a significant slowdown of a job or test.
+type Regression struct {
+	Job  string `json:"job"`
+	Test string `json:"test,omitempty"`
+	// Baseline is the mean duration of the runs before the recent ones.
+	Baseline time.Duration `json:"baseline"`
+	// Recent is the mean duration of the recent runs.
+	Recent          time.Duration `json:"recent"`
+	IncreasePercent float64       `json:"increase_percent"`
+	// TStatistic is the Welch's t-statistic of the recent durations against
+	// the baseline ones.
+	TStatistic float64 `json:"t_statistic"`
+	// FirstBuild is the build ID of the oldest of the recent runs.
+	FirstBuild string `json:"first_build"`
+}
+
+// String describes the regression.
+func (r Regression) String() string {
+	subject := r.Job
+	if r.Test != "" {
+		subject = fmt.Sprintf("%s of %s", r.Test, r.Job)
+	}
+	return fmt.Sprintf("%s slowed down by %.0f%% since build %s: %s on average, was %s",
+		subject, r.IncreasePercent, r.FirstBuild, r.Recent.Round(time.Second), r.Baseline.Round(time.Second))
+}
+
+// Options tune the detection of regressions.
+type Options struct {
+	// RecentRuns is the number of the most recent points compared to the
+	// previous ones.
+	RecentRuns int
+	// MinBaselineRuns is the number of points before the recent ones that
+	// are required.
+	MinBaselineRuns int
+	// MinIncreasePercent is the increase of the mean below which slowdowns
+	// are ignored.
+	MinIncreasePercent int
+	// MinDuration is the baseline mean below which slowdowns are ignored.
+	MinDuration time.Duration
+}
+
+// Detect returns the regression of the series, if its most recent points
+// are significantly slower than the previous ones.
+func Detect(series Series, opts Options) (Regression, bool) {
+	if opts.RecentRuns < 1 || len(series.Points) < opts.RecentRuns+opts.MinBaselineRuns {
+		return Regression{}, false
+	}
+	points := make([]Point, len(series.Points))
+	copy(points, series.Points)
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Finished.Before(points[j].Finished) })
+
+	split := len(points) - opts.RecentRuns
+	baseline, recent := seconds(points[:split]), seconds(points[split:])
+	baselineMean, baselineVar := meanVariance(baseline)
+	recentMean, recentVar := meanVariance(recent)
+	if baselineMean <= 0 || baselineMean < opts.MinDuration.Seconds() {
+		return Regression{}, false
+	}
+	increase := (recentMean - baselineMean) / baselineMean * 100
+	if increase < float64(opts.MinIncreasePercent) {
+		return Regression{}, false
+	}
+	t := welch(baselineMean, baselineVar, len(baseline), recentMean, recentVar, len(recent))
+	if t < minTStatistic {
+		return Regression{}, false
+	}
+	return Regression{
+		Job:             series.Job,
+		Test:            series.Test,
+		Baseline:        toDuration(baselineMean),
+		Recent:          toDuration(recentMean),
+		IncreasePercent: increase,
+		TStatistic:      t,
+		FirstBuild:      points[split].BuildID,
+	}, true
+}
+
+func seconds(points []Point) []float64 {
+	values := make([]float64, 0, len(points))
+	for _, p := range points {
+		values = append(values, p.Duration.Seconds())
+	}
+	return values
+}
+
+func toDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// meanVariance returns the mean and the unbiased variance of the values.
+func meanVariance(values []float64) (float64, float64) {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+	var squares float64
+	for _, v := range values {
+		squares += (v - mean) * (v - mean)
+	}
+	return mean, squares / float64(len(values)-1)
+}
+
+// minStandardError bounds the standard error of the difference of the means,
+// so that samples without any variance, e.g. durations rounded to the second,
+// still have a finite t-statistic.
+const minStandardError = 0.001
+
+// welch returns the Welch's t-statistic of the second sample against the
+// first one.
+func welch(mean1, var1 float64, n1 int, mean2, var2 float64, n2 int) float64 {
+	stderr := math.Max(math.Sqrt(var1/float64(n1)+var2/float64(n2)), minStandardError)
+	return (mean2 - mean1) / stderr
+}
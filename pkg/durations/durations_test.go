@@ -0,0 +1,112 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package durations
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func series(durations ...time.Duration) Series {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := Series{Job: "job"}
+	for i, d := range durations {
+		s.Points = append(s.Points, Point{
+			BuildID:  string(rune('a' + i)),
+			Finished: start.Add(time.Duration(i) * time.Hour),
+			Duration: d,
+		})
+	}
+	return s
+}
+
+func TestDetect(t *testing.T) {
+	opts := Options{RecentRuns: 3, MinBaselineRuns: 4, MinIncreasePercent: 20, MinDuration: time.Second}
+	m := time.Minute
+	testCases := []struct {
+		name     string
+		series   Series
+		expected *Regression
+	}{
+		{
+			name:   "not enough runs",
+			series: series(m, m, m, 2*m, 2*m, 2*m),
+		},
+		{
+			name:   "stable",
+			series: series(m, 61*time.Second, 59*time.Second, m, m, 61*time.Second, 59*time.Second),
+		},
+		{
+			name:   "noisy",
+			series: series(m, 3*m, m, 3*m, 3*m, m, 3*m),
+		},
+		{
+			name:   "small increase",
+			series: series(m, m, m, m, 66*time.Second, 66*time.Second, 66*time.Second),
+		},
+		{
+			name:   "faster",
+			series: series(2*m, 2*m, 2*m, 2*m, m, m, m),
+		},
+		{
+			name:   "too short",
+			series: series(100*time.Millisecond, 100*time.Millisecond, 100*time.Millisecond, 100*time.Millisecond, time.Second, time.Second, time.Second),
+		},
+		{
+			name:   "slowdown",
+			series: series(m, 62*time.Second, 58*time.Second, m, 2*m, 122*time.Second, 118*time.Second),
+			expected: &Regression{
+				Job:             "job",
+				Baseline:        m,
+				Recent:          2 * m,
+				IncreasePercent: 100,
+				FirstBuild:      "e",
+			},
+		},
+		{
+			name:   "constant durations",
+			series: series(m, m, m, m, 2*m, 2*m, 2*m),
+			expected: &Regression{
+				Job:             "job",
+				Baseline:        m,
+				Recent:          2 * m,
+				IncreasePercent: 100,
+				FirstBuild:      "e",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, ok := Detect(tc.series, opts)
+			if ok != (tc.expected != nil) {
+				t.Fatalf("expected regression: %t, got: %+v", tc.expected != nil, r)
+			}
+			if !ok {
+				return
+			}
+			if r.TStatistic < minTStatistic {
+				t.Errorf("expected a t-statistic of at least %d, got %f", minTStatistic, r.TStatistic)
+			}
+			if diff := cmp.Diff(*tc.expected, r, cmpopts.IgnoreFields(Regression{}, "TStatistic"), cmpopts.EquateApprox(0, 0.01)); diff != "" {
+				t.Errorf("unexpected regression: %s", diff)
+			}
+		})
+	}
+}
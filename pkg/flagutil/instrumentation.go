@@ -17,6 +17,7 @@ limitations under the License.
 package flagutil
 
 import (
+	"errors"
 	"flag"
 	"time"
 )
@@ -27,6 +28,8 @@ const (
 	DefaultHealthPort  = 8081
 
 	DefaultMemoryProfileInterval = 30 * time.Second
+
+	DefaultTracingSampleRatio = 1.0
 )
 
 // InstrumentationOptions holds common options which are used across Prow components
@@ -42,6 +45,12 @@ type InstrumentationOptions struct {
 	ProfileMemory bool
 	// MemoryProfileInterval is the interval at which memory profiles should be dumped
 	MemoryProfileInterval time.Duration
+
+	// TracingEndpoint is the URL of the OTLP/HTTP endpoint the spans are exported to,
+	// tracing is disabled if empty
+	TracingEndpoint string
+	// TracingSampleRatio is the ratio of the traces started by the component that are sampled
+	TracingSampleRatio float64
 }
 
 // DefaultInstrumentationOptions returns an initialized options struct, mostly for use in tests.
@@ -52,6 +61,7 @@ func DefaultInstrumentationOptions() InstrumentationOptions {
 		HealthPort:            DefaultHealthPort,
 		ProfileMemory:         false,
 		MemoryProfileInterval: DefaultMemoryProfileInterval,
+		TracingSampleRatio:    DefaultTracingSampleRatio,
 	}
 }
 
@@ -62,8 +72,13 @@ func (o *InstrumentationOptions) AddFlags(fs *flag.FlagSet) {
 	fs.IntVar(&o.HealthPort, "health-port", DefaultHealthPort, "port to serve liveness and readiness")
 	fs.BoolVar(&o.ProfileMemory, "profile-memory-usage", false, "profile memory usage for analysis")
 	fs.DurationVar(&o.MemoryProfileInterval, "memory-profile-interval", DefaultMemoryProfileInterval, "duration at which memory profiles should be dumped")
+	fs.StringVar(&o.TracingEndpoint, "tracing-endpoint", "", "URL of the OTLP/HTTP endpoint to export the spans to, e.g. http://otel-collector:4318, tracing is disabled if empty")
+	fs.Float64Var(&o.TracingSampleRatio, "tracing-sample-ratio", DefaultTracingSampleRatio, "ratio of the traces started by the component to sample, the traces continued from other components follow their sampling decision")
 }
 
 func (o *InstrumentationOptions) Validate(_ bool) error {
+	if o.TracingSampleRatio < 0 || o.TracingSampleRatio > 1 {
+		return errors.New("--tracing-sample-ratio must be between 0 and 1")
+	}
 	return nil
 }
@@ -20,8 +20,10 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"strings"
 
 	"sigs.k8s.io/prow/pkg/io"
+	"sigs.k8s.io/prow/pkg/io/providers"
 )
 
 type StorageClientOptions struct {
@@ -36,12 +38,29 @@ type StorageClientOptions struct {
 	// If not, go cloud credential auto-discovery is used
 	// For more details see the prow/io/providers pkg.
 	S3CredentialsFile string `json:"s3_credentials_file,omitempty"`
+	// S3CompatibleCredentialsFiles adds S3-compatible stores, e.g. MinIO, under
+	// their own scheme so that they can be used along with S3.
+	// The files the credentials of the stores are stored in are keyed by the
+	// scheme and have the format of S3CredentialsFile, e.g. minio: /etc/minio/credentials.json
+	// makes minio:// paths read/written with the endpoint of /etc/minio/credentials.json.
+	S3CompatibleCredentialsFiles map[string]string `json:"s3_compatible_credentials_files,omitempty"`
 }
 
 // AddFlags injects status client options into the given FlagSet.
 func (o *StorageClientOptions) AddFlags(fs *flag.FlagSet) {
 	fs.StringVar(&o.GCSCredentialsFile, "gcs-credentials-file", "", "File where GCS credentials are stored")
 	fs.StringVar(&o.S3CredentialsFile, "s3-credentials-file", "", "File where s3 credentials are stored. For the exact format see https://github.com/kubernetes-sigs/prow/blob/main/pkg/io/providers/providers.go")
+	fs.Func("s3-compatible-credentials-file", "Scheme and file where the credentials of an S3-compatible store are stored, e.g. minio=/etc/minio/credentials.json to read/write minio:// paths. Same format as --s3-credentials-file. Can be passed multiple times.", func(value string) error {
+		scheme, file, ok := strings.Cut(value, "=")
+		if !ok || scheme == "" || file == "" {
+			return fmt.Errorf("expected <scheme>=<file>, got %q", value)
+		}
+		if o.S3CompatibleCredentialsFiles == nil {
+			o.S3CompatibleCredentialsFiles = map[string]string{}
+		}
+		o.S3CompatibleCredentialsFiles[scheme] = file
+		return nil
+	})
 }
 
 func (o *StorageClientOptions) HasGCSCredentials() bool {
@@ -54,12 +73,25 @@ func (o *StorageClientOptions) HasS3Credentials() bool {
 
 // Validate validates options.
 func (o *StorageClientOptions) Validate(dryRun bool) error {
+	for scheme := range o.S3CompatibleCredentialsFiles {
+		if scheme == providers.GS || scheme == providers.S3 || scheme == providers.Azure || scheme == providers.File {
+			return fmt.Errorf("--s3-compatible-credentials-file: scheme %q is reserved", scheme)
+		}
+	}
 	return nil
 }
 
 // StorageClient returns a Storage client.
 func (o *StorageClientOptions) StorageClient(ctx context.Context) (io.Opener, error) {
-	opener, err := io.NewOpener(ctx, o.GCSCredentialsFile, o.S3CredentialsFile)
+	credentialsFiles := map[string]string{}
+	if o.S3CredentialsFile != "" {
+		credentialsFiles[providers.S3] = o.S3CredentialsFile
+	}
+	for scheme, file := range o.S3CompatibleCredentialsFiles {
+		providers.Register(scheme, providers.S3Compatible(scheme))
+		credentialsFiles[scheme] = file
+	}
+	opener, err := io.NewOpenerWithCredentials(ctx, o.GCSCredentialsFile, credentialsFiles)
 	if err != nil {
 		message := ""
 		if o.GCSCredentialsFile != "" {
@@ -68,6 +100,9 @@ func (o *StorageClientOptions) StorageClient(ctx context.Context) (io.Opener, er
 		if o.S3CredentialsFile != "" {
 			message = fmt.Sprintf("%s s3-credentials-file: %s", message, o.S3CredentialsFile)
 		}
+		for scheme, file := range o.S3CompatibleCredentialsFiles {
+			message = fmt.Sprintf("%s s3-compatible-credentials-file: %s=%s", message, scheme, file)
+		}
 		return opener, fmt.Errorf("error creating opener%s: %w", message, err)
 	}
 	return opener, nil
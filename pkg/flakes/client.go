@@ -0,0 +1,136 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flakes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+)
+
+// Failure is a test that failed in a run.
+type Failure struct {
+	Test string `json:"test"`
+	// KnownFlake is true when the test is known-flaky for the job.
+	KnownFlake bool `json:"known_flake"`
+	// Flakes is the number of revisions on which the test flaked.
+	Flakes int `json:"flakes,omitempty"`
+}
+
+// RunFailures lists the failures of a run, as served by Deck on
+// /flakes.js?job=<job>&build=<build>.
+type RunFailures struct {
+	Job      string        `json:"job"`
+	BuildID  string        `json:"build_id"`
+	Refs     *prowapi.Refs `json:"refs,omitempty"`
+	Failures []Failure     `json:"failures"`
+}
+
+// AllKnownFlakes returns whether the run failed only on known-flaky tests.
+func (r RunFailures) AllKnownFlakes() bool {
+	for _, failure := range r.Failures {
+		if !failure.KnownFlake {
+			return false
+		}
+	}
+	return len(r.Failures) > 0
+}
+
+// Failures matches the failures of the run against the report.
+func (r *Report) Failures(run Run) RunFailures {
+	failures := RunFailures{Job: run.Job, BuildID: run.BuildID, Refs: run.Refs, Failures: []Failure{}}
+	for _, test := range run.Failures() {
+		failure := Failure{Test: test}
+		if flake, ok := r.Find(run.Job, test); ok {
+			failure.KnownFlake = true
+			failure.Flakes = flake.Flakes
+		}
+		failures.Failures = append(failures.Failures, failure)
+	}
+	return failures
+}
+
+// Client fetches the flaky test report from Deck.
+type Client struct {
+	// endpoint is the URL of the /flakes.js endpoint of Deck.
+	endpoint string
+	ttl      time.Duration
+	client   *http.Client
+
+	lock    sync.Mutex
+	report  *Report
+	fetched time.Time
+}
+
+// NewClient returns a Client of the /flakes.js endpoint of Deck that caches
+// the report for ttl.
+func NewClient(endpoint string, ttl time.Duration) *Client {
+	return &Client{
+		endpoint: endpoint,
+		ttl:      ttl,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Report returns the flaky test report.
+func (c *Client) Report() (*Report, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.report != nil && time.Since(c.fetched) < c.ttl {
+		return c.report, nil
+	}
+	report := &Report{}
+	if err := c.get(c.endpoint, report); err != nil {
+		return nil, err
+	}
+	c.report, c.fetched = report, time.Now()
+	return report, nil
+}
+
+// RunFailures returns the failures of the run of the job.
+func (c *Client) RunFailures(job, buildID string) (*RunFailures, error) {
+	u, err := url.Parse(c.endpoint)
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = url.Values{"job": []string{job}, "build": []string{buildID}}.Encode()
+	failures := &RunFailures{}
+	if err := c.get(u.String(), failures); err != nil {
+		return nil, err
+	}
+	return failures, nil
+}
+
+func (c *Client) get(u string, into interface{}) error {
+	resp, err := c.client.Get(u)
+	if err != nil {
+		return fmt.Errorf("failed to get %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get %s: status %d", u, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(into); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", u, err)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package flakes detects flaky tests from the JUnit results of finished
+// ProwJobs. A test is flaky when it both failed and passed on the same
+// revision, either within a single run or across runs, e.g. on retest.
+package flakes
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+)
+
+// TestStatus is the outcome of a test within a single run.
+type TestStatus string
+
+const (
+	// Passed means that all the executions of the test passed.
+	Passed TestStatus = "Passed"
+	// Failed means that all the executions of the test failed.
+	Failed TestStatus = "Failed"
+	// Flaky means that the test both failed and passed within the run.
+	Flaky TestStatus = "Flaky"
+)
+
+// Run holds the test results of a finished ProwJob.
+type Run struct {
+	Job     string `json:"job"`
+	BuildID string `json:"build_id"`
+	// Revision identifies the code that was tested, runs of the same job on
+	// the same revision are expected to have the same results.
+	Revision string        `json:"revision,omitempty"`
+	Refs     *prowapi.Refs `json:"refs,omitempty"`
+	Finished time.Time     `json:"finished"`
+	// Passed is whether the job succeeded.
+	Passed bool `json:"passed,omitempty"`
+	// Duration is the duration of the job.
+	Duration time.Duration `json:"duration,omitempty"`
+	// Tests maps the test names to their status in the run, skipped tests
+	// are left out.
+	Tests map[string]TestStatus `json:"tests,omitempty"`
+	// Durations maps the names of the tests that passed to their duration,
+	// the executions of a test add up.
+	Durations map[string]time.Duration `json:"durations,omitempty"`
+}
+
+// Failures returns the sorted names of the tests that failed in the run.
+func (r Run) Failures() []string {
+	var failures []string
+	for test, status := range r.Tests {
+		if status == Failed {
+			failures = append(failures, test)
+		}
+	}
+	sort.Strings(failures)
+	return failures
+}
+
+// Flake holds the flakiness of a test of a job.
+type Flake struct {
+	Job  string `json:"job"`
+	Test string `json:"test"`
+	// Runs is the number of runs of the job that ran the test.
+	Runs int `json:"runs"`
+	// Flakes is the number of revisions on which the test flaked.
+	Flakes int `json:"flakes"`
+	// LastFlake is the finish time of the last run in which the test flaked.
+	LastFlake time.Time `json:"last_flake"`
+	// LastFlakeBuild is the build ID of that run.
+	LastFlakeBuild string `json:"last_flake_build"`
+}
+
+// Report lists the known-flaky tests.
+type Report struct {
+	Generated time.Time `json:"generated"`
+	Flakes    []Flake   `json:"flakes"`
+}
+
+// Find returns the flake of the test of the job, if it is known-flaky.
+func (r *Report) Find(job, test string) (Flake, bool) {
+	for _, flake := range r.Flakes {
+		if flake.Job == job && flake.Test == test {
+			return flake, true
+		}
+	}
+	return Flake{}, false
+}
+
+// IsFlaky returns whether the test of the job is known-flaky.
+func (r *Report) IsFlaky(job, test string) bool {
+	_, ok := r.Find(job, test)
+	return ok
+}
+
+// TestName returns the name under which a JUnit test case is tracked.
+func TestName(class, name string) string {
+	if class == "" {
+		return name
+	}
+	return fmt.Sprintf("%s: %s", class, name)
+}
+
+// Revision returns the revision tested by the refs: the heads of the pulls
+// for presubmits, so that retests against a newer base still match, and the
+// base SHA otherwise.
+func Revision(refs *prowapi.Refs) string {
+	if refs == nil {
+		return ""
+	}
+	if len(refs.Pulls) == 0 {
+		return refs.BaseSHA
+	}
+	var heads []string
+	for _, pull := range refs.Pulls {
+		heads = append(heads, fmt.Sprintf("%d:%s", pull.Number, pull.SHA))
+	}
+	return strings.Join(heads, ",")
+}
+
+// Compute returns the tests that flaked on at least minFlakes revisions, the
+// flakiest first. Runs without a revision are only compared to themselves.
+func Compute(runs []Run, minFlakes int) []Flake {
+	type testKey struct {
+		job, test string
+	}
+	type revisionKey struct {
+		job, revision string
+	}
+	type revisionResult struct {
+		passed, failed bool
+		// last is the last run of the revision in which the test failed.
+		last Run
+	}
+
+	flakes := make(map[testKey]*Flake)
+	results := make(map[testKey]map[revisionKey]*revisionResult)
+	for _, run := range runs {
+		rev := revisionKey{job: run.Job, revision: run.Revision}
+		if rev.revision == "" {
+			rev.revision = "build:" + run.BuildID
+		}
+		for test, status := range run.Tests {
+			key := testKey{job: run.Job, test: test}
+			if flakes[key] == nil {
+				flakes[key] = &Flake{Job: run.Job, Test: test}
+				results[key] = make(map[revisionKey]*revisionResult)
+			}
+			flakes[key].Runs++
+			result := results[key][rev]
+			if result == nil {
+				result = &revisionResult{}
+				results[key][rev] = result
+			}
+			switch status {
+			case Passed:
+				result.passed = true
+			case Failed:
+				result.failed = true
+			case Flaky:
+				result.passed, result.failed = true, true
+			}
+			if status != Passed && (result.last.BuildID == "" || run.Finished.After(result.last.Finished)) {
+				result.last = run
+			}
+		}
+	}
+
+	var report []Flake
+	for key, flake := range flakes {
+		for _, result := range results[key] {
+			if !result.passed || !result.failed {
+				continue
+			}
+			flake.Flakes++
+			if result.last.Finished.After(flake.LastFlake) || flake.LastFlakeBuild == "" {
+				flake.LastFlake = result.last.Finished
+				flake.LastFlakeBuild = result.last.BuildID
+			}
+		}
+		if flake.Flakes > 0 && flake.Flakes >= minFlakes {
+			report = append(report, *flake)
+		}
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Flakes != report[j].Flakes {
+			return report[i].Flakes > report[j].Flakes
+		}
+		if report[i].Job != report[j].Job {
+			return report[i].Job < report[j].Job
+		}
+		return report[i].Test < report[j].Test
+	})
+	return report
+}
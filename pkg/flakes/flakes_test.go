@@ -0,0 +1,145 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flakes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+)
+
+func TestRevision(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		refs *prowapi.Refs
+		want string
+	}{
+		{
+			name: "no refs",
+		},
+		{
+			name: "postsubmit",
+			refs: &prowapi.Refs{BaseSHA: "base"},
+			want: "base",
+		},
+		{
+			name: "presubmit ignores the base",
+			refs: &prowapi.Refs{BaseSHA: "base", Pulls: []prowapi.Pull{{Number: 1, SHA: "head"}}},
+			want: "1:head",
+		},
+		{
+			name: "batch",
+			refs: &prowapi.Refs{BaseSHA: "base", Pulls: []prowapi.Pull{{Number: 1, SHA: "a"}, {Number: 2, SHA: "b"}}},
+			want: "1:a,2:b",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Revision(tc.refs); got != tc.want {
+				t.Errorf("Expected revision %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCompute(t *testing.T) {
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	run := func(build, revision string, ago time.Duration, tests map[string]TestStatus) Run {
+		return Run{Job: "job", BuildID: build, Revision: revision, Finished: now.Add(-ago), Tests: tests}
+	}
+	for _, tc := range []struct {
+		name      string
+		runs      []Run
+		minFlakes int
+		want      []Flake
+	}{
+		{
+			name: "no runs",
+		},
+		{
+			name: "test failing then passing on retest",
+			runs: []Run{
+				run("1", "1:a", 2*time.Hour, map[string]TestStatus{"TestA": Failed, "TestB": Passed}),
+				run("2", "1:a", time.Hour, map[string]TestStatus{"TestA": Passed, "TestB": Passed}),
+			},
+			minFlakes: 1,
+			want:      []Flake{{Job: "job", Test: "TestA", Runs: 2, Flakes: 1, LastFlake: now.Add(-2 * time.Hour), LastFlakeBuild: "1"}},
+		},
+		{
+			name: "test failing on one revision and passing on another is not flaky",
+			runs: []Run{
+				run("1", "1:a", 2*time.Hour, map[string]TestStatus{"TestA": Failed}),
+				run("2", "1:b", time.Hour, map[string]TestStatus{"TestA": Passed}),
+			},
+			minFlakes: 1,
+		},
+		{
+			name: "test flaky within a run without revision",
+			runs: []Run{
+				run("1", "", 2*time.Hour, map[string]TestStatus{"TestA": Flaky}),
+				run("2", "", time.Hour, map[string]TestStatus{"TestA": Failed}),
+			},
+			minFlakes: 1,
+			want:      []Flake{{Job: "job", Test: "TestA", Runs: 2, Flakes: 1, LastFlake: now.Add(-2 * time.Hour), LastFlakeBuild: "1"}},
+		},
+		{
+			name: "tests below the threshold are left out",
+			runs: []Run{
+				run("1", "1:a", 4*time.Hour, map[string]TestStatus{"TestA": Failed, "TestB": Failed}),
+				run("2", "1:a", 3*time.Hour, map[string]TestStatus{"TestA": Passed, "TestB": Passed}),
+				run("3", "2:a", 2*time.Hour, map[string]TestStatus{"TestA": Passed, "TestB": Passed}),
+				run("4", "2:a", time.Hour, map[string]TestStatus{"TestA": Failed, "TestB": Passed}),
+			},
+			minFlakes: 2,
+			want:      []Flake{{Job: "job", Test: "TestA", Runs: 4, Flakes: 2, LastFlake: now.Add(-time.Hour), LastFlakeBuild: "4"}},
+		},
+		{
+			name: "flakiest tests first",
+			runs: []Run{
+				run("1", "1:a", 4*time.Hour, map[string]TestStatus{"TestA": Flaky, "TestB": Flaky}),
+				run("2", "2:a", 3*time.Hour, map[string]TestStatus{"TestA": Passed, "TestB": Flaky}),
+			},
+			minFlakes: 1,
+			want: []Flake{
+				{Job: "job", Test: "TestB", Runs: 2, Flakes: 2, LastFlake: now.Add(-3 * time.Hour), LastFlakeBuild: "2"},
+				{Job: "job", Test: "TestA", Runs: 2, Flakes: 1, LastFlake: now.Add(-4 * time.Hour), LastFlakeBuild: "1"},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, Compute(tc.runs, tc.minFlakes)); diff != "" {
+				t.Errorf("Unexpected flakes: %s", diff)
+			}
+		})
+	}
+}
+
+func TestReportFailures(t *testing.T) {
+	report := &Report{Flakes: []Flake{{Job: "job", Test: "TestA", Flakes: 3}, {Job: "other", Test: "TestB", Flakes: 2}}}
+	run := Run{Job: "job", BuildID: "1", Tests: map[string]TestStatus{"TestA": Failed, "TestB": Failed, "TestC": Passed, "TestD": Flaky}}
+
+	got := report.Failures(run)
+	want := RunFailures{Job: "job", BuildID: "1", Failures: []Failure{{Test: "TestA", KnownFlake: true, Flakes: 3}, {Test: "TestB"}}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected failures: %s", diff)
+	}
+	if got.AllKnownFlakes() {
+		t.Error("Expected TestB not to be a known flake")
+	}
+}
@@ -0,0 +1,280 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flakes
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/utils/clock"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/crier/reporters/gcs/util"
+	"sigs.k8s.io/prow/pkg/interrupts"
+	"sigs.k8s.io/prow/pkg/io/providers"
+)
+
+const (
+	// ingestRetryBackoff is the delay before ingesting a job again after the
+	// first failure, it doubles with every failure.
+	ingestRetryBackoff = 10 * time.Minute
+	// maxIngestAttempts is the number of failures after which the results
+	// of a job are not ingested anymore.
+	maxIngestAttempts = 5
+)
+
+// ingestFailure tracks the failures to ingest the results of a job.
+type ingestFailure struct {
+	attempts int
+	retryAt  time.Time
+	finished time.Time
+}
+
+// Ingester periodically ingests the JUnit results of the finished ProwJobs
+// and keeps the report of the known-flaky tests up to date. The ingested runs
+// are shared with the reports registered with OnSync, e.g. the durations.
+type Ingester struct {
+	ctx      context.Context
+	opener   Opener
+	config   config.Getter
+	prowJobs func() []prowapi.ProwJob
+	logger   *logrus.Entry
+	onSync   []func(runs []Run) error
+	clock    clock.PassiveClock
+
+	lock sync.RWMutex
+	// runs are the ingested runs, keyed by job and build ID.
+	runs map[string]Run
+	// failures are the jobs whose results failed to be ingested, keyed by
+	// job and build ID, so that they are retried with a backoff.
+	failures map[string]ingestFailure
+	report   Report
+}
+
+// NewIngester returns an Ingester of the JUnit results of the ProwJobs
+// listed by prowJobs.
+func NewIngester(ctx context.Context, opener Opener, cfg config.Getter, prowJobs func() []prowapi.ProwJob) *Ingester {
+	return &Ingester{
+		ctx:      ctx,
+		opener:   opener,
+		config:   cfg,
+		prowJobs: prowJobs,
+		logger:   logrus.WithField("component", "flakes"),
+		clock:    clock.RealClock{},
+		runs:     make(map[string]Run),
+		failures: make(map[string]ingestFailure),
+	}
+}
+
+// OnSync registers a function called with the runs within the lookback after
+// every sync.
+func (i *Ingester) OnSync(f func(runs []Run) error) {
+	i.onSync = append(i.onSync, f)
+}
+
+// Start ingests the results on the configured period until an interrupt is
+// received.
+func (i *Ingester) Start() {
+	interrupts.Tick(func() {
+		if err := i.Sync(); err != nil {
+			i.logger.WithError(err).Warn("Failed to ingest the results of some jobs.")
+		}
+	}, func() time.Duration {
+		return syncPeriod(&i.config().Deck)
+	})
+}
+
+// syncPeriod returns the shortest sync period of the reports of the results.
+func syncPeriod(deck *config.Deck) time.Duration {
+	var periods []time.Duration
+	if deck.Flakes != nil && deck.Flakes.SyncPeriod != nil {
+		periods = append(periods, deck.Flakes.SyncPeriod.Duration)
+	}
+	if deck.Durations != nil && deck.Durations.SyncPeriod != nil {
+		periods = append(periods, deck.Durations.SyncPeriod.Duration)
+	}
+	if len(periods) == 0 {
+		return 10 * time.Minute
+	}
+	return slices.Min(periods)
+}
+
+// lookback returns the longest lookback of the reports of the results, or
+// false when none is enabled.
+func lookback(deck *config.Deck) (time.Duration, bool) {
+	if deck.Flakes == nil && deck.Durations == nil {
+		return 0, false
+	}
+	var lookback time.Duration
+	if deck.Flakes != nil {
+		lookback = deck.Flakes.Lookback.Duration
+	}
+	if deck.Durations != nil && deck.Durations.Lookback.Duration > lookback {
+		lookback = deck.Durations.Lookback.Duration
+	}
+	return lookback, true
+}
+
+func runKey(job, buildID string) string {
+	return job + "/" + buildID
+}
+
+// Sync ingests the results of the jobs that finished within the lookback,
+// retrying the failed ones with a backoff up to maxIngestAttempts times,
+// forgets the older ones, computes the report again and passes the runs to
+// the functions registered with OnSync.
+func (i *Ingester) Sync() error {
+	deck := i.config().Deck
+	lookback, ok := lookback(&deck)
+	if !ok {
+		return nil
+	}
+	now := i.clock.Now()
+	cutoff := now.Add(-lookback)
+
+	var errs []error
+	for _, pj := range i.prowJobs() {
+		if !ingestible(&pj) || pj.Status.CompletionTime.Time.Before(cutoff) {
+			continue
+		}
+		key := runKey(pj.Spec.Job, pj.Status.BuildID)
+		i.lock.RLock()
+		_, ingested := i.runs[key]
+		failure := i.failures[key]
+		i.lock.RUnlock()
+		if ingested || failure.attempts >= maxIngestAttempts || now.Before(failure.retryAt) {
+			continue
+		}
+		_, err := i.Ingest(&pj)
+		i.lock.Lock()
+		if err != nil {
+			errs = append(errs, err)
+			failure.attempts++
+			failure.retryAt = now.Add(ingestRetryBackoff << (failure.attempts - 1))
+			failure.finished = pj.Status.CompletionTime.Time
+			i.failures[key] = failure
+			if failure.attempts == maxIngestAttempts {
+				i.logger.WithError(err).WithField("job", key).Warn("Giving up ingesting the results of the job.")
+			}
+		} else {
+			delete(i.failures, key)
+		}
+		i.lock.Unlock()
+	}
+
+	i.lock.Lock()
+	for key, failure := range i.failures {
+		if failure.finished.Before(cutoff) {
+			delete(i.failures, key)
+		}
+	}
+	var runs []Run
+	for key, run := range i.runs {
+		if run.Finished.Before(cutoff) {
+			delete(i.runs, key)
+			continue
+		}
+		runs = append(runs, run)
+	}
+	if cfg := deck.Flakes; cfg != nil {
+		flakesCutoff := now.Add(-cfg.Lookback.Duration)
+		var recent []Run
+		for _, run := range runs {
+			if !run.Finished.Before(flakesCutoff) {
+				recent = append(recent, run)
+			}
+		}
+		i.report = Report{Generated: now, Flakes: Compute(recent, cfg.MinFlakes)}
+		i.logger.WithField("runs", len(recent)).WithField("flakes", len(i.report.Flakes)).Debug("Computed the flaky test report.")
+	}
+	i.lock.Unlock()
+
+	for _, f := range i.onSync {
+		if err := f(runs); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to ingest %d jobs, first error: %w", len(errs), errs[0])
+	}
+	return nil
+}
+
+// ingestible returns whether the results of the job can be ingested: the
+// job succeeded or failed, errored and aborted jobs may not have run the
+// tests at all.
+func ingestible(pj *prowapi.ProwJob) bool {
+	if pj.Status.BuildID == "" || pj.Status.CompletionTime == nil {
+		return false
+	}
+	return pj.Status.State == prowapi.SuccessState || pj.Status.State == prowapi.FailureState
+}
+
+// Ingest returns the run of the job, reading its results from storage if it
+// wasn't ingested yet.
+func (i *Ingester) Ingest(pj *prowapi.ProwJob) (Run, error) {
+	key := runKey(pj.Spec.Job, pj.Status.BuildID)
+	i.lock.RLock()
+	run, ok := i.runs[key]
+	i.lock.RUnlock()
+	if ok {
+		return run, nil
+	}
+	if !ingestible(pj) {
+		return Run{}, fmt.Errorf("job %s is not finished", key)
+	}
+
+	bucket, dir, err := util.GetJobDestination(i.config, pj)
+	if err != nil {
+		return Run{}, fmt.Errorf("failed to get the destination of job %s: %w", key, err)
+	}
+	path, err := providers.StoragePath(bucket, dir)
+	if err != nil {
+		return Run{}, fmt.Errorf("failed to get the storage path of job %s: %w", key, err)
+	}
+	tests, durations, err := readTests(i.ctx, i.opener, path)
+	if err != nil {
+		return Run{}, fmt.Errorf("failed to read the results of job %s: %w", key, err)
+	}
+	run = Run{
+		Job:       pj.Spec.Job,
+		BuildID:   pj.Status.BuildID,
+		Revision:  Revision(pj.Spec.Refs),
+		Refs:      pj.Spec.Refs,
+		Finished:  pj.Status.CompletionTime.Time,
+		Passed:    pj.Status.State == prowapi.SuccessState,
+		Duration:  pj.Status.CompletionTime.Sub(pj.Status.StartTime.Time),
+		Tests:     tests,
+		Durations: durations,
+	}
+	i.lock.Lock()
+	i.runs[key] = run
+	i.lock.Unlock()
+	return run, nil
+}
+
+// Report returns the last computed report.
+func (i *Ingester) Report() Report {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	return i.report
+}
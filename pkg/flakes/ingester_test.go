@@ -0,0 +1,216 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flakes
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testingclock "k8s.io/utils/clock/testing"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/config"
+	pio "sigs.k8s.io/prow/pkg/io"
+)
+
+// fakeOpener serves the files from memory, keyed by their full path.
+type fakeOpener struct {
+	files map[string]string
+	// listErr fails the listings, which are counted in listed.
+	listErr error
+	listed  int
+}
+
+func (o *fakeOpener) Reader(_ context.Context, path string) (pio.ReadCloser, error) {
+	content, ok := o.files[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return io.NopCloser(bytes.NewBufferString(content)), nil
+}
+
+func (o *fakeOpener) Iterator(_ context.Context, prefix, _ string) (pio.ObjectIterator, error) {
+	o.listed++
+	if o.listErr != nil {
+		return nil, o.listErr
+	}
+	var attrs []pio.ObjectAttributes
+	for path := range o.files {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		// Names are relative to the bucket.
+		name := strings.SplitN(strings.TrimPrefix(path, "gs://"), "/", 2)[1]
+		attrs = append(attrs, pio.ObjectAttributes{Name: name, ObjName: name[strings.LastIndex(name, "/")+1:]})
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Name < attrs[j].Name })
+	return &fakeIterator{attrs: attrs}, nil
+}
+
+type fakeIterator struct {
+	attrs []pio.ObjectAttributes
+}
+
+func (i *fakeIterator) Next(_ context.Context) (pio.ObjectAttributes, error) {
+	if len(i.attrs) == 0 {
+		return pio.ObjectAttributes{}, io.EOF
+	}
+	attr := i.attrs[0]
+	i.attrs = i.attrs[1:]
+	return attr, nil
+}
+
+const junitTemplate = `<testsuites><testsuite name="suite">
+<testcase classname="pkg" name="TestA">%A%</testcase>
+<testcase classname="pkg" name="TestB" time="1.5"></testcase>
+<testcase classname="pkg" name="TestC"><skipped/></testcase>
+</testsuite></testsuites>`
+
+func junitFile(testAFails bool) string {
+	failure := ""
+	if testAFails {
+		failure = `<failure message="boom"/>`
+	}
+	return strings.Replace(junitTemplate, "%A%", failure, 1)
+}
+
+func TestIngester(t *testing.T) {
+	now := time.Now()
+	job := func(build string, state prowapi.ProwJobState, finished time.Time) prowapi.ProwJob {
+		completion := metav1.NewTime(finished)
+		return prowapi.ProwJob{
+			Spec: prowapi.ProwJobSpec{
+				Type: prowapi.PresubmitJob,
+				Job:  "job",
+				Refs: &prowapi.Refs{Org: "org", Repo: "repo", BaseSHA: "base", Pulls: []prowapi.Pull{{Number: 1, SHA: "head"}}},
+				DecorationConfig: &prowapi.DecorationConfig{GCSConfiguration: &prowapi.GCSConfiguration{
+					Bucket:       "bucket",
+					PathStrategy: prowapi.PathStrategyExplicit,
+				}},
+			},
+			Status: prowapi.ProwJobStatus{State: state, BuildID: build, CompletionTime: &completion},
+		}
+	}
+	opener := &fakeOpener{files: map[string]string{
+		"gs://bucket/pr-logs/pull/org_repo/1/job/1/artifacts/junit_01.xml":  junitFile(true),
+		"gs://bucket/pr-logs/pull/org_repo/1/job/2/artifacts/junit_01.xml":  junitFile(false),
+		"gs://bucket/pr-logs/pull/org_repo/1/job/2/artifacts/build-log.txt": "not junit",
+		"gs://bucket/pr-logs/pull/org_repo/1/job/3/artifacts/junit_01.xml":  junitFile(true),
+		"gs://bucket/pr-logs/pull/org_repo/1/job/4/artifacts/junit_01.xml":  junitFile(true),
+	}}
+	pjs := []prowapi.ProwJob{
+		job("1", prowapi.FailureState, now.Add(-2*time.Hour)),
+		job("2", prowapi.SuccessState, now.Add(-time.Hour)),
+		// Too old.
+		job("3", prowapi.FailureState, now.Add(-48*time.Hour)),
+		// Not finished.
+		{Spec: prowapi.ProwJobSpec{Job: "job"}, Status: prowapi.ProwJobStatus{State: prowapi.PendingState, BuildID: "5"}},
+	}
+	cfg := func() *config.Config {
+		return &config.Config{ProwConfig: config.ProwConfig{Deck: config.Deck{Flakes: &config.Flakes{
+			Lookback:  &metav1.Duration{Duration: 24 * time.Hour},
+			MinFlakes: 1,
+		}}}}
+	}
+
+	ingester := NewIngester(context.Background(), opener, cfg, func() []prowapi.ProwJob { return pjs })
+	var synced []Run
+	ingester.OnSync(func(runs []Run) error {
+		synced = runs
+		return nil
+	})
+	if err := ingester.Sync(); err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	report := ingester.Report()
+	want := []Flake{{Job: "job", Test: "pkg: TestA", Runs: 2, Flakes: 1, LastFlake: pjs[0].Status.CompletionTime.Time, LastFlakeBuild: "1"}}
+	if diff := cmp.Diff(want, report.Flakes); diff != "" {
+		t.Errorf("Unexpected flakes: %s", diff)
+	}
+
+	if n := len(synced); n != 2 {
+		t.Errorf("Expected the 2 runs within the lookback to be passed on, got %d", n)
+	}
+
+	// The failures of a run are matched against the report.
+	failed := job("4", prowapi.FailureState, now)
+	run, err := ingester.Ingest(&failed)
+	if err != nil {
+		t.Fatalf("Failed to ingest: %v", err)
+	}
+	if diff := cmp.Diff(map[string]time.Duration{"pkg: TestB": 1500 * time.Millisecond}, run.Durations); diff != "" {
+		t.Errorf("Unexpected durations of the passed tests: %s", diff)
+	}
+	failures := report.Failures(run)
+	if !failures.AllKnownFlakes() {
+		t.Errorf("Expected the failures to be known flakes, got %+v", failures)
+	}
+}
+
+func TestIngesterRetriesFailures(t *testing.T) {
+	clock := testingclock.NewFakeClock(time.Now())
+	completion := metav1.NewTime(clock.Now().Add(-time.Hour))
+	pjs := []prowapi.ProwJob{{
+		Spec: prowapi.ProwJobSpec{
+			Type: prowapi.PeriodicJob,
+			Job:  "job",
+			DecorationConfig: &prowapi.DecorationConfig{GCSConfiguration: &prowapi.GCSConfiguration{
+				Bucket:       "bucket",
+				PathStrategy: prowapi.PathStrategyExplicit,
+			}},
+		},
+		Status: prowapi.ProwJobStatus{State: prowapi.FailureState, BuildID: "1", CompletionTime: &completion},
+	}}
+	cfg := func() *config.Config {
+		return &config.Config{ProwConfig: config.ProwConfig{Deck: config.Deck{Flakes: &config.Flakes{
+			Lookback: &metav1.Duration{Duration: 24 * time.Hour},
+		}}}}
+	}
+	opener := &fakeOpener{listErr: errors.New("injected list error")}
+	ingester := NewIngester(context.Background(), opener, cfg, func() []prowapi.ProwJob { return pjs })
+	ingester.clock = clock
+
+	// The job is retried after 10m, 20m, 40m and 80m, then given up.
+	for _, tc := range []struct {
+		after      time.Duration
+		wantListed int
+	}{
+		{after: 0, wantListed: 1},
+		{after: 5 * time.Minute, wantListed: 1},
+		{after: 5 * time.Minute, wantListed: 2},
+		{after: 10 * time.Minute, wantListed: 2},
+		{after: 10 * time.Minute, wantListed: 3},
+		{after: 40 * time.Minute, wantListed: 4},
+		{after: 80 * time.Minute, wantListed: 5},
+		{after: 10 * time.Hour, wantListed: 5},
+	} {
+		clock.Step(tc.after)
+		err := ingester.Sync()
+		if opener.listed != tc.wantListed {
+			t.Fatalf("After %s: expected %d listings, got %d (sync error: %v)", tc.after, tc.wantListed, opener.listed, err)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flakes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/testgrid/metadata/junit"
+	"github.com/sirupsen/logrus"
+
+	pio "sigs.k8s.io/prow/pkg/io"
+	"sigs.k8s.io/prow/pkg/io/providers"
+)
+
+// junitRE matches the JUnit files uploaded to the artifacts, as the junit
+// lens does by default.
+var junitRE = regexp.MustCompile(`^junit.*\.xml$`)
+
+// Opener is the subset of pio.Opener required.
+type Opener interface {
+	Reader(ctx context.Context, path string) (pio.ReadCloser, error)
+	Iterator(ctx context.Context, prefix, delimiter string) (pio.ObjectIterator, error)
+}
+
+// readTests reads the JUnit files found in the artifacts of the job whose
+// results were uploaded to dir, e.g. "gs://bucket/logs/job/1". It returns the
+// status of the tests and the durations of the ones that passed.
+func readTests(ctx context.Context, opener Opener, dir string) (map[string]TestStatus, map[string]time.Duration, error) {
+	tests := make(map[string]TestStatus)
+	durations := make(map[string]time.Duration)
+	err := ReadSuites(ctx, opener, dir, func(suite junit.Suite) {
+		recordSuite(tests, durations, suite)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	for test, status := range tests {
+		if status != Passed {
+			delete(durations, test)
+		}
+	}
+	return tests, durations, nil
+}
+
+// ReadSuites calls record with the top-level suites of the JUnit files found
+// in the artifacts of the job whose results were uploaded to dir, e.g.
+// "gs://bucket/logs/job/1". The files that cannot be parsed are skipped.
+func ReadSuites(ctx context.Context, opener Opener, dir string, record func(junit.Suite)) error {
+	provider, bucket, _, err := providers.ParseStoragePath(dir)
+	if err != nil {
+		return err
+	}
+	prefix := strings.TrimSuffix(dir, "/") + "/artifacts/"
+	it, err := opener.Iterator(ctx, prefix, "")
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", prefix, err)
+	}
+
+	for {
+		attrs, err := it.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list %s: %w", prefix, err)
+		}
+		if attrs.IsDir || !junitRE.MatchString(path.Base(attrs.Name)) {
+			continue
+		}
+		file := fmt.Sprintf("%s://%s/%s", provider, bucket, strings.TrimPrefix(attrs.Name, "/"))
+		content, err := readFile(ctx, opener, file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		suites, err := junit.Parse(content)
+		if err != nil {
+			logrus.WithError(err).WithField("file", file).Debug("Failed to parse JUnit file.")
+			continue
+		}
+		for _, suite := range suites.Suites {
+			record(suite)
+		}
+	}
+	return nil
+}
+
+func readFile(ctx context.Context, opener Opener, file string) ([]byte, error) {
+	r, err := opener.Reader(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// recordSuite records the status and the duration of the tests of the suite,
+// the executions of a test that both failed and passed make it flaky.
+func recordSuite(tests map[string]TestStatus, durations map[string]time.Duration, suite junit.Suite) {
+	for _, sub := range suite.Suites {
+		recordSuite(tests, durations, sub)
+	}
+	for _, result := range suite.Results {
+		if result.Skipped != nil {
+			continue
+		}
+		status := Passed
+		if result.Failure != nil || result.Errored != nil {
+			status = Failed
+		}
+		name := TestName(result.ClassName, result.Name)
+		if previous, ok := tests[name]; ok && previous != status {
+			status = Flaky
+		}
+		tests[name] = status
+		durations[name] += time.Duration(result.Time * float64(time.Second))
+	}
+}
@@ -38,6 +38,8 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	githubql "github.com/shurcooL/githubv4"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/oauth2"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -280,6 +282,7 @@ type Client interface {
 	SetMax404Retries(int)
 
 	WithFields(fields logrus.Fields) Client
+	WithContext(ctx context.Context) Client
 	ForPlugin(plugin string) Client
 	ForSubcomponent(subcomponent string) Client
 	Used() bool
@@ -296,9 +299,12 @@ type client struct {
 	logger *logrus.Entry
 	// identifier is used to add more identification to the user-agent header
 	identifier string
-	gqlc       gqlClient
-	used       bool
-	mutUsed    sync.Mutex // protects used
+	// ctx is the context of the requests of the methods that don't take
+	// one, e.g. of the trace of the event being handled.
+	ctx     context.Context
+	gqlc    gqlClient
+	used    bool
+	mutUsed sync.Mutex // protects used
 	*delegate
 }
 
@@ -351,6 +357,7 @@ func (c *client) forKeyValue(key, value string) Client {
 	newClient := &client{
 		identifier: value,
 		logger:     c.logger.WithField(key, value),
+		ctx:        c.ctx,
 		delegate:   c.delegate,
 	}
 	newClient.gqlc = c.gqlc.forUserAgent(newClient.userAgent())
@@ -370,11 +377,33 @@ func (c *client) WithFields(fields logrus.Fields) Client {
 	return &client{
 		logger:     c.logger.WithFields(fields),
 		identifier: c.identifier,
+		ctx:        c.ctx,
 		gqlc:       c.gqlc,
 		delegate:   c.delegate,
 	}
 }
 
+// WithContext clones the client, keeping the underlying delegate the same but
+// making the requests of the methods that don't take a context with ctx.
+func (c *client) WithContext(ctx context.Context) Client {
+	return &client{
+		logger:     c.logger,
+		identifier: c.identifier,
+		ctx:        ctx,
+		gqlc:       c.gqlc,
+		delegate:   c.delegate,
+	}
+}
+
+// context returns the context of the requests of the methods that don't
+// take one.
+func (c *client) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
 var (
 	teamRe = regexp.MustCompile(`^(.*)/(.*)$`)
 
@@ -591,6 +620,11 @@ func NewClientFromOptions(fields logrus.Fields, options ClientOptions) (TokenGen
 	if options.BaseRoundTripper == nil {
 		options.BaseRoundTripper = http.DefaultTransport
 	}
+	// Requests made on behalf of a traced event are added to its trace, the
+	// others are not traced at all.
+	options.BaseRoundTripper = otelhttp.NewTransport(options.BaseRoundTripper, otelhttp.WithFilter(func(r *http.Request) bool {
+		return trace.SpanContextFromContext(r.Context()).IsValid()
+	}))
 
 	httpClient := &http.Client{
 		Transport: options.BaseRoundTripper,
@@ -898,7 +932,7 @@ func IsForbidden(err error) bool {
 // Make a request with retries. If ret is not nil, unmarshal the response body
 // into it. Returns an error if the exit code is not one of the provided codes.
 func (c *client) request(r *request, ret interface{}) (int, error) {
-	return c.requestWithContext(context.Background(), r, ret)
+	return c.requestWithContext(c.context(), r, ret)
 }
 
 func (c *client) requestWithContext(ctx context.Context, r *request, ret interface{}) (int, error) {
@@ -917,7 +951,7 @@ func (c *client) requestWithContext(ctx context.Context, r *request, ret interfa
 // requestRaw makes a request with retries and returns the response body.
 // Returns an error if the exit code is not one of the provided codes.
 func (c *client) requestRaw(r *request) (int, []byte, error) {
-	return c.requestRawWithContext(context.Background(), r)
+	return c.requestRawWithContext(c.context(), r)
 }
 
 func (c *client) requestRawWithContext(ctx context.Context, r *request) (int, []byte, error) {
@@ -955,7 +989,7 @@ func (c *client) requestRawWithContext(ctx context.Context, r *request) (int, []
 // ratelimit exceeded, and retries 404s a couple times.
 // This function closes the response body iff it also returns an error.
 func (c *client) requestRetry(method, path, accept, org string, body interface{}) (*http.Response, error) {
-	return c.requestRetryWithContext(context.Background(), method, path, accept, org, body)
+	return c.requestRetryWithContext(c.context(), method, path, accept, org, body)
 }
 
 func (c *client) requestRetryWithContext(ctx context.Context, method, path, accept, org string, body interface{}) (*http.Response, error) {
@@ -1240,7 +1274,7 @@ func (c *client) BotUser() (*UserData, error) {
 }
 
 func (c *client) BotUserChecker() (func(candidate string) bool, error) {
-	return c.BotUserCheckerWithContext(context.Background())
+	return c.BotUserCheckerWithContext(c.context())
 }
 
 func (c *client) BotUserCheckerWithContext(ctx context.Context) (func(candidate string) bool, error) {
@@ -1724,7 +1758,7 @@ func (c *client) RemoveOrgMembership(org, user string) error {
 //
 // See https://developer.github.com/v3/issues/comments/#create-a-comment
 func (c *client) CreateComment(org, repo string, number int, comment string) error {
-	return c.CreateCommentWithContext(context.Background(), org, repo, number, comment)
+	return c.CreateCommentWithContext(c.context(), org, repo, number, comment)
 }
 
 func (c *client) CreateCommentWithContext(ctx context.Context, org, repo string, number int, comment string) error {
@@ -1746,7 +1780,7 @@ func (c *client) CreateCommentWithContext(ctx context.Context, org, repo string,
 //
 // See https://developer.github.com/v3/issues/comments/#delete-a-comment
 func (c *client) DeleteComment(org, repo string, id int) error {
-	return c.DeleteCommentWithContext(context.Background(), org, repo, id)
+	return c.DeleteCommentWithContext(c.context(), org, repo, id)
 }
 
 func (c *client) DeleteCommentWithContext(ctx context.Context, org, repo string, id int) error {
@@ -1764,7 +1798,7 @@ func (c *client) DeleteCommentWithContext(ctx context.Context, org, repo string,
 //
 // See https://developer.github.com/v3/issues/comments/#edit-a-comment
 func (c *client) EditComment(org, repo string, id int, comment string) error {
-	return c.EditCommentWithContext(context.Background(), org, repo, id, comment)
+	return c.EditCommentWithContext(c.context(), org, repo, id, comment)
 }
 
 func (c *client) EditCommentWithContext(ctx context.Context, org, repo string, id int, comment string) error {
@@ -1859,7 +1893,7 @@ func (c *client) CreateIssueReaction(org, repo string, id int, reaction string)
 // DeleteStaleComments iterates over comments on an issue/PR, deleting those which the 'isStale'
 // function identifies as stale. If 'comments' is nil, the comments will be fetched from GitHub.
 func (c *client) DeleteStaleComments(org, repo string, number int, comments []IssueComment, isStale func(IssueComment) bool) error {
-	return c.DeleteStaleCommentsWithContext(context.Background(), org, repo, number, comments, isStale)
+	return c.DeleteStaleCommentsWithContext(c.context(), org, repo, number, comments, isStale)
 }
 
 func (c *client) DeleteStaleCommentsWithContext(ctx context.Context, org, repo string, number int, comments []IssueComment, isStale func(IssueComment) bool) error {
@@ -1887,7 +1921,7 @@ func (c *client) DeleteStaleCommentsWithContext(ctx context.Context, org, repo s
 //
 // Returns an error any call to GitHub or object marshalling fails.
 func (c *client) readPaginatedResults(path, accept, org string, newObj func() interface{}, accumulate func(interface{})) error {
-	return c.readPaginatedResultsWithContext(context.Background(), path, accept, org, newObj, accumulate)
+	return c.readPaginatedResultsWithContext(c.context(), path, accept, org, newObj, accumulate)
 }
 
 func (c *client) readPaginatedResultsWithContext(ctx context.Context, path, accept, org string, newObj func() interface{}, accumulate func(interface{})) error {
@@ -1899,7 +1933,7 @@ func (c *client) readPaginatedResultsWithContext(ctx context.Context, path, acce
 
 // readPaginatedResultsWithValues is an override that allows control over the query string.
 func (c *client) readPaginatedResultsWithValues(path string, values url.Values, accept, org string, newObj func() interface{}, accumulate func(interface{})) error {
-	return c.readPaginatedResultsWithValuesWithContext(context.Background(), path, values, accept, org, newObj, accumulate)
+	return c.readPaginatedResultsWithValuesWithContext(c.context(), path, values, accept, org, newObj, accumulate)
 }
 
 func (c *client) readPaginatedResultsWithValuesWithContext(ctx context.Context, path string, values url.Values, accept, org string, newObj func() interface{}, accumulate func(interface{})) error {
@@ -1962,7 +1996,7 @@ func (c *client) readPaginatedResultsWithValuesWithContext(ctx context.Context,
 //
 // See https://developer.github.com/v3/issues/comments/#list-comments-on-an-issue
 func (c *client) ListIssueComments(org, repo string, number int) ([]IssueComment, error) {
-	return c.ListIssueCommentsWithContext(context.Background(), org, repo, number)
+	return c.ListIssueCommentsWithContext(c.context(), org, repo, number)
 }
 
 func (c *client) ListIssueCommentsWithContext(ctx context.Context, org, repo string, number int) ([]IssueComment, error) {
@@ -2463,7 +2497,7 @@ func (c *client) ListReviews(org, repo string, number int) ([]Review, error) {
 //
 // See https://docs.github.com/en/rest/reference/commits#create-a-commit-status
 func (c *client) CreateStatus(org, repo, SHA string, s Status) error {
-	return c.CreateStatusWithContext(context.Background(), org, repo, SHA, s)
+	return c.CreateStatusWithContext(c.context(), org, repo, SHA, s)
 }
 
 func (c *client) CreateStatusWithContext(ctx context.Context, org, repo, SHA string, s Status) error {
@@ -2889,7 +2923,7 @@ func (c *client) GetIssueLabels(org, repo string, number int) ([]Label, error) {
 //
 // See https://developer.github.com/v3/issues/labels/#add-labels-to-an-issue
 func (c *client) AddLabel(org, repo string, number int, label string) error {
-	return c.AddLabelWithContext(context.Background(), org, repo, number, label)
+	return c.AddLabelWithContext(c.context(), org, repo, number, label)
 }
 
 func (c *client) AddLabelWithContext(ctx context.Context, org, repo string, number int, label string) error {
@@ -2900,7 +2934,7 @@ func (c *client) AddLabelWithContext(ctx context.Context, org, repo string, numb
 //
 // See https://developer.github.com/v3/issues/labels/#add-labels-to-an-issue
 func (c *client) AddLabels(org, repo string, number int, labels ...string) error {
-	return c.AddLabelsWithContext(context.Background(), org, repo, number, labels...)
+	return c.AddLabelsWithContext(c.context(), org, repo, number, labels...)
 }
 
 func (c *client) AddLabelsWithContext(ctx context.Context, org, repo string, number int, labels ...string) error {
@@ -2925,7 +2959,7 @@ type githubError struct {
 //
 // See https://developer.github.com/v3/issues/labels/#remove-a-label-from-an-issue
 func (c *client) RemoveLabel(org, repo string, number int, label string) error {
-	return c.RemoveLabelWithContext(context.Background(), org, repo, number, label)
+	return c.RemoveLabelWithContext(c.context(), org, repo, number, label)
 }
 
 func (c *client) RemoveLabelWithContext(ctx context.Context, org, repo string, number int, label string) error {
@@ -4859,7 +4893,7 @@ func (c *client) getAppInstallationToken(installationId int64) (*AppInstallation
 
 // GetApp gets the current app. Will not work with a Personal Access Token.
 func (c *client) GetApp() (*App, error) {
-	return c.GetAppWithContext(context.Background())
+	return c.GetAppWithContext(c.context())
 }
 
 func (c *client) GetAppWithContext(ctx context.Context) (*App, error) {
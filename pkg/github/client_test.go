@@ -3506,8 +3506,9 @@ func TestThrottlerRespectsContexts(t *testing.T) {
 
 	clientMethods := getCallForAllClientMethodsThroughReflection(c,
 		// Skip all method whose first arg is not of type context.Context (self is the actual first arg)
+		// and WithContext, which doesn't make any request.
 		func(m reflect.Method) bool {
-			return m.Func.Type().NumIn() < 2 || m.Func.Type().In(1).String() != "context.Context"
+			return m.Func.Type().NumIn() < 2 || m.Func.Type().In(1).String() != "context.Context" || m.Name == "WithContext"
 		},
 		// Insert our custom ctx for any arg of type context.Context
 		func(typeName string) interface{} {
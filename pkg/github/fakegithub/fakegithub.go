@@ -61,6 +61,10 @@ type FakeClient struct {
 	IssueEvents                map[int][]github.ListedIssueEvent
 	Commits                    map[string]github.RepositoryCommit
 
+	// sha:check runs
+	CheckRuns  map[string][]github.CheckRun
+	CheckRunID int64
+
 	// All Labels That Exist In The Repo
 	RepoLabelsExisting []string
 	// org/repo#number:label
@@ -536,6 +540,50 @@ func (f *FakeClient) CreateStatusWithContext(_ context.Context, owner, repo, SHA
 	return nil
 }
 
+// ListCheckRuns returns the check runs of a commit.
+func (f *FakeClient) ListCheckRuns(org, repo, ref string) (*github.CheckRunList, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	runs := f.CheckRuns[ref]
+	return &github.CheckRunList{Total: len(runs), CheckRuns: append([]github.CheckRun(nil), runs...)}, nil
+}
+
+// CreateCheckRun adds a check run to a commit.
+func (f *FakeClient) CreateCheckRun(org, repo string, checkRun github.CheckRun) (int64, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.Error != nil {
+		return 0, f.Error
+	}
+	if f.CheckRuns == nil {
+		f.CheckRuns = make(map[string][]github.CheckRun)
+	}
+	f.CheckRunID++
+	checkRun.ID = f.CheckRunID
+	f.CheckRuns[checkRun.HeadSHA] = append(f.CheckRuns[checkRun.HeadSHA], checkRun)
+	return checkRun.ID, nil
+}
+
+// UpdateCheckRun replaces a check run.
+func (f *FakeClient) UpdateCheckRun(org, repo string, checkRunId int64, checkRun github.CheckRun) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.Error != nil {
+		return f.Error
+	}
+	for sha, runs := range f.CheckRuns {
+		for i := range runs {
+			if runs[i].ID == checkRunId {
+				checkRun.ID = checkRunId
+				checkRun.HeadSHA = sha
+				runs[i] = checkRun
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("check run %d not found", checkRunId)
+}
+
 // ListStatuses returns individual status contexts on a commit.
 func (f *FakeClient) ListStatuses(org, repo, ref string) ([]github.Status, error) {
 	f.lock.RLock()
@@ -0,0 +1,254 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/github"
+)
+
+const (
+	// RerunActionIdentifier identifies the action of the check runs that
+	// re-runs their ProwJob.
+	RerunActionIdentifier = "rerun"
+
+	// GitHub accepts at most 50 annotations per request.
+	maxAnnotations = 50
+	// maxSummaryFailures bounds the number of failed tests listed in the summary.
+	maxSummaryFailures = 10
+	// maxAnnotationMessage bounds the length of the message of an annotation.
+	maxAnnotationMessage = 4096
+
+	checkRunQueued     = "queued"
+	checkRunInProgress = "in_progress"
+	checkRunCompleted  = "completed"
+)
+
+// TestFailure is a test that failed in a ProwJob, usually read from the
+// JUnit files of its artifacts.
+type TestFailure struct {
+	Name    string
+	Message string
+}
+
+// fileReference matches the references to source files in test output, e.g.
+// "pkg/foo/foo_test.go:42" or "/go/src/github.com/org/repo/main.py:7:3".
+var fileReference = regexp.MustCompile(`(/?(?:[\w.-]+/)*[\w.-]+\.(?:go|py|js|jsx|ts|tsx|java|kt|scala|rb|rs|c|cc|cpp|h|hpp|cs|swift|php|sh|bzl|proto|tf|ya?ml|json)):(\d+)\b`)
+
+// checkRunStatus maps ProwJob states to check run statuses and conclusions.
+// https://docs.github.com/en/rest/checks/runs#create-a-check-run
+func checkRunStatus(pjState prowapi.ProwJobState) (string, string, error) {
+	switch pjState {
+	case prowapi.TriggeredState:
+		return checkRunQueued, "", nil
+	case prowapi.PendingState:
+		return checkRunInProgress, "", nil
+	case prowapi.SuccessState:
+		return checkRunCompleted, "success", nil
+	case prowapi.ErrorState, prowapi.FailureState:
+		return checkRunCompleted, "failure", nil
+	case prowapi.AbortedState:
+		return checkRunCompleted, "cancelled", nil
+	}
+	return "", "", fmt.Errorf("Unknown prowjob state: %s", pjState)
+}
+
+// ReportCheckRun creates or updates the check run of the ProwJob if its repo
+// is configured to be reported with check runs. The failures are listed in
+// the summary and the lines they reference are annotated.
+func ReportCheckRun(ghc GitHubClient, pj prowapi.ProwJob, config config.GitHubReporter, failures []TestFailure) error {
+	if ghc == nil {
+		return fmt.Errorf("trying to report pj %s, but found empty github client", pj.ObjectMeta.Name)
+	}
+
+	if !ShouldReport(pj, config.JobTypesToReport) {
+		return nil
+	}
+
+	refs := pj.Spec.Refs
+	// we are not reporting for batch jobs, as for status contexts
+	if refs == nil || len(refs.Pulls) > 1 || !config.ReportsCheckRuns(refs.Org, refs.Repo) {
+		return nil
+	}
+
+	run, err := checkRun(pj, failures)
+	if err != nil {
+		return err
+	}
+	runs, err := ghc.ListCheckRuns(refs.Org, refs.Repo, run.HeadSHA)
+	if err != nil {
+		return fmt.Errorf("error listing check runs: %w", err)
+	}
+	for _, existing := range runs.CheckRuns {
+		if existing.ExternalID != pj.Name {
+			continue
+		}
+		if err := ghc.UpdateCheckRun(refs.Org, refs.Repo, existing.ID, run); err != nil {
+			return fmt.Errorf("error updating check run: %w", err)
+		}
+		return nil
+	}
+	if _, err := ghc.CreateCheckRun(refs.Org, refs.Repo, run); err != nil {
+		return fmt.Errorf("error creating check run: %w", err)
+	}
+	return nil
+}
+
+// checkRun returns the check run of the ProwJob, identified by the name of
+// the ProwJob.
+func checkRun(pj prowapi.ProwJob, failures []TestFailure) (github.CheckRun, error) {
+	status, conclusion, err := checkRunStatus(pj.Status.State)
+	if err != nil {
+		return github.CheckRun{}, err
+	}
+	if pj.Retried() {
+		// The next attempt reports its own check run, this one mustn't stay
+		// in progress nor fail the commit.
+		status, conclusion = checkRunCompleted, "neutral"
+	}
+	refs := pj.Spec.Refs
+	sha := refs.BaseSHA
+	if len(refs.Pulls) > 0 {
+		sha = refs.Pulls[0].SHA
+	}
+	title := pj.Status.Description
+	if title == "" {
+		title = fmt.Sprintf("Job %s.", pj.Status.State)
+	}
+	run := github.CheckRun{
+		Name:       pj.Spec.Context,
+		HeadSHA:    sha,
+		ExternalID: pj.Name,
+		DetailsURL: pj.Status.URL,
+		Status:     status,
+		Conclusion: conclusion,
+		Output: github.CheckRunOutput{
+			Title:   title,
+			Summary: checkRunSummary(pj, failures),
+		},
+	}
+	if !pj.Status.StartTime.IsZero() {
+		run.StartedAt = pj.Status.StartTime.UTC().Format(time.RFC3339)
+	}
+	if pj.Complete() {
+		run.CompletedAt = pj.Status.CompletionTime.UTC().Format(time.RFC3339)
+	}
+	if pj.Complete() && !pj.Retried() {
+		run.Output.Annotations = annotations(refs, failures)
+		run.Actions = []github.CheckRunAction{{
+			Label:       "Re-run",
+			Description: "Run this job again",
+			Identifier:  RerunActionIdentifier,
+		}}
+	}
+	return run, nil
+}
+
+// checkRunSummary returns the markdown summary of the check run.
+func checkRunSummary(pj prowapi.ProwJob, failures []TestFailure) string {
+	lines := []string{
+		"Job | State | Duration",
+		"--- | --- | ---",
+	}
+	duration := "-"
+	if pj.Status.CompletionTime != nil {
+		duration = pj.Status.CompletionTime.Sub(pj.Status.StartTime.Time).Round(time.Second).String()
+	}
+	lines = append(lines, fmt.Sprintf("[%s](%s) | %s | %s", pj.Spec.Job, pj.Status.URL, pj.Status.State, duration))
+	if len(failures) > 0 {
+		plural := ""
+		if len(failures) > 1 {
+			plural = "s"
+		}
+		lines = append(lines, "", fmt.Sprintf("%d test%s failed:", len(failures), plural))
+		for i, failure := range failures {
+			if i == maxSummaryFailures {
+				lines = append(lines, fmt.Sprintf("- and %d more", len(failures)-maxSummaryFailures))
+				break
+			}
+			lines = append(lines, fmt.Sprintf("- `%s`", failure.Name))
+		}
+	}
+	if pj.Complete() && !pj.Retried() && pj.Spec.RerunCommand != "" {
+		lines = append(lines, "", fmt.Sprintf("Click **Re-run** or comment `%s` to run the job again.", pj.Spec.RerunCommand))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// annotations returns the annotations of the lines of the repo referenced by
+// the failures. The references outside of the repo and the bare file names,
+// e.g. "foo_test.go:42" printed by go test relative to the package, which
+// don't resolve to a file of the repo, are ignored.
+func annotations(refs *prowapi.Refs, failures []TestFailure) []github.CheckRunAnnotation {
+	var annotations []github.CheckRunAnnotation
+	seen := map[string]bool{}
+	repoDir := fmt.Sprintf("/%s/%s/", refs.Org, refs.Repo)
+	for _, failure := range failures {
+		message := truncate(failure.Message, maxAnnotationMessage)
+		for _, match := range fileReference.FindAllStringSubmatch(failure.Message, -1) {
+			path := match[1]
+			if i := strings.LastIndex(path, repoDir); i >= 0 {
+				path = path[i+len(repoDir):]
+			}
+			path = strings.TrimPrefix(path, "./")
+			if strings.HasPrefix(path, "/") || !strings.Contains(path, "/") {
+				continue
+			}
+			line, err := strconv.Atoi(match[2])
+			if err != nil || line == 0 {
+				continue
+			}
+			key := fmt.Sprintf("%s:%d:%s", path, line, failure.Name)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			annotations = append(annotations, github.CheckRunAnnotation{
+				Path:            path,
+				StartLine:       line,
+				EndLine:         line,
+				AnnotationLevel: "failure",
+				Title:           failure.Name,
+				Message:         message,
+			})
+			if len(annotations) == maxAnnotations {
+				return annotations
+			}
+		}
+	}
+	return annotations
+}
+
+// truncate returns the longest prefix of s of at most n bytes that doesn't
+// split a rune.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,313 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/github"
+)
+
+func TestReportCheckRun(t *testing.T) {
+	start := metav1.NewTime(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	end := metav1.NewTime(start.Add(90 * time.Second))
+	newPJ := func(state prowapi.ProwJobState) prowapi.ProwJob {
+		pj := prowapi.ProwJob{
+			ObjectMeta: metav1.ObjectMeta{Name: "pj-1"},
+			Spec: prowapi.ProwJobSpec{
+				Type:         prowapi.PresubmitJob,
+				Job:          "pull-unit",
+				Context:      "pull-unit",
+				Report:       true,
+				RerunCommand: "/test pull-unit",
+				Refs: &prowapi.Refs{
+					Org:     "org",
+					Repo:    "repo",
+					BaseSHA: "base",
+					Pulls:   []prowapi.Pull{{Number: 1, SHA: "head"}},
+				},
+			},
+			Status: prowapi.ProwJobStatus{
+				State:     state,
+				StartTime: start,
+				URL:       "https://prow/view/pj-1",
+			},
+		}
+		if pj.Status.State != prowapi.PendingState && pj.Status.State != prowapi.TriggeredState {
+			pj.Status.CompletionTime = &end
+		}
+		return pj
+	}
+	reporterConfig := config.GitHubReporter{
+		JobTypesToReport: []prowapi.ProwJobType{prowapi.PresubmitJob},
+		CheckRunRepos:    []string{"org"},
+	}
+
+	testCases := []struct {
+		name     string
+		pj       prowapi.ProwJob
+		config   config.GitHubReporter
+		existing []github.CheckRun
+		failures []TestFailure
+		expected []github.CheckRun
+	}{
+		{
+			name:   "repo not reported as check runs",
+			pj:     newPJ(prowapi.PendingState),
+			config: config.GitHubReporter{JobTypesToReport: []prowapi.ProwJobType{prowapi.PresubmitJob}},
+		},
+		{
+			name:   "pending job creates an in progress check run",
+			pj:     newPJ(prowapi.PendingState),
+			config: reporterConfig,
+			expected: []github.CheckRun{{
+				ID:         1,
+				Name:       "pull-unit",
+				HeadSHA:    "head",
+				ExternalID: "pj-1",
+				DetailsURL: "https://prow/view/pj-1",
+				Status:     "in_progress",
+				StartedAt:  "2024-01-02T03:04:05Z",
+				Output: github.CheckRunOutput{
+					Title:   "Job pending.",
+					Summary: "Job | State | Duration\n--- | --- | ---\n[pull-unit](https://prow/view/pj-1) | pending | -",
+				},
+			}},
+		},
+		{
+			name:   "failed job completes the existing check run",
+			pj:     newPJ(prowapi.FailureState),
+			config: reporterConfig,
+			existing: []github.CheckRun{
+				{ID: 1, ExternalID: "other", Status: "completed"},
+				{ID: 2, ExternalID: "pj-1", Status: "in_progress"},
+			},
+			failures: []TestFailure{
+				{Name: "TestFoo", Message: "/home/prow/go/src/github.com/org/repo/pkg/foo/foo_test.go:42: unexpected value"},
+				{Name: "TestBar", Message: "panic\n/usr/local/go/src/testing/testing.go:1595 +0x1\n./bar/bar_test.go:7:3: oops"},
+			},
+			expected: []github.CheckRun{
+				{ID: 1, ExternalID: "other", Status: "completed"},
+				{
+					ID:          2,
+					Name:        "pull-unit",
+					HeadSHA:     "head",
+					ExternalID:  "pj-1",
+					DetailsURL:  "https://prow/view/pj-1",
+					Status:      "completed",
+					Conclusion:  "failure",
+					StartedAt:   "2024-01-02T03:04:05Z",
+					CompletedAt: "2024-01-02T03:05:35Z",
+					Output: github.CheckRunOutput{
+						Title:   "Job failure.",
+						Summary: "Job | State | Duration\n--- | --- | ---\n[pull-unit](https://prow/view/pj-1) | failure | 1m30s\n\n2 tests failed:\n- `TestFoo`\n- `TestBar`\n\nClick **Re-run** or comment `/test pull-unit` to run the job again.",
+						Annotations: []github.CheckRunAnnotation{
+							{
+								Path:            "pkg/foo/foo_test.go",
+								StartLine:       42,
+								EndLine:         42,
+								AnnotationLevel: "failure",
+								Title:           "TestFoo",
+								Message:         "/home/prow/go/src/github.com/org/repo/pkg/foo/foo_test.go:42: unexpected value",
+							},
+							{
+								Path:            "bar/bar_test.go",
+								StartLine:       7,
+								EndLine:         7,
+								AnnotationLevel: "failure",
+								Title:           "TestBar",
+								Message:         "panic\n/usr/local/go/src/testing/testing.go:1595 +0x1\n./bar/bar_test.go:7:3: oops",
+							},
+						},
+					},
+					Actions: []github.CheckRunAction{{Label: "Re-run", Description: "Run this job again", Identifier: "rerun"}},
+				},
+			},
+		},
+		{
+			name: "retried attempt completes its check run as neutral",
+			pj: func() prowapi.ProwJob {
+				pj := newPJ(prowapi.FailureState)
+				pj.Status.Description = "Pod got deleted unexpectedly. Retrying as attempt 2 of 3 (pod_deleted)."
+				pj.Status.Retry = &prowapi.RetryStatus{Attempt: 1, RetriedBy: "pj-1-attempt-2"}
+				return pj
+			}(),
+			config:   reporterConfig,
+			existing: []github.CheckRun{{ID: 1, ExternalID: "pj-1", Status: "in_progress"}},
+			failures: []TestFailure{{Name: "TestFoo", Message: "foo_test.go:42: unexpected value"}},
+			expected: []github.CheckRun{{
+				ID:          1,
+				Name:        "pull-unit",
+				HeadSHA:     "head",
+				ExternalID:  "pj-1",
+				DetailsURL:  "https://prow/view/pj-1",
+				Status:      "completed",
+				Conclusion:  "neutral",
+				StartedAt:   "2024-01-02T03:04:05Z",
+				CompletedAt: "2024-01-02T03:05:35Z",
+				Output: github.CheckRunOutput{
+					Title:   "Pod got deleted unexpectedly. Retrying as attempt 2 of 3 (pod_deleted).",
+					Summary: "Job | State | Duration\n--- | --- | ---\n[pull-unit](https://prow/view/pj-1) | failure | 1m30s\n\n1 test failed:\n- `TestFoo`",
+				},
+			}},
+		},
+		{
+			name:   "aborted job is cancelled",
+			pj:     newPJ(prowapi.AbortedState),
+			config: reporterConfig,
+			expected: []github.CheckRun{{
+				ID:          1,
+				Name:        "pull-unit",
+				HeadSHA:     "head",
+				ExternalID:  "pj-1",
+				DetailsURL:  "https://prow/view/pj-1",
+				Status:      "completed",
+				Conclusion:  "cancelled",
+				StartedAt:   "2024-01-02T03:04:05Z",
+				CompletedAt: "2024-01-02T03:05:35Z",
+				Output: github.CheckRunOutput{
+					Title:   "Job aborted.",
+					Summary: "Job | State | Duration\n--- | --- | ---\n[pull-unit](https://prow/view/pj-1) | aborted | 1m30s\n\nClick **Re-run** or comment `/test pull-unit` to run the job again.",
+				},
+				Actions: []github.CheckRunAction{{Label: "Re-run", Description: "Run this job again", Identifier: "rerun"}},
+			}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ghc := &fakeGhClient{checkRuns: tc.existing}
+			if err := ReportCheckRun(ghc, tc.pj, tc.config, tc.failures); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, ghc.checkRuns); diff != "" {
+				t.Errorf("check runs differ from expected (-want +got):\n%s", diff)
+			}
+			if len(ghc.status) > 0 {
+				t.Errorf("unexpected statuses: %v", ghc.status)
+			}
+		})
+	}
+}
+
+func TestReportStatusContextOptOut(t *testing.T) {
+	pj := prowapi.ProwJob{
+		Spec: prowapi.ProwJobSpec{
+			Type:    prowapi.PresubmitJob,
+			Context: "pull-unit",
+			Report:  true,
+			Refs: &prowapi.Refs{
+				Org:   "org",
+				Repo:  "repo",
+				Pulls: []prowapi.Pull{{Number: 1, SHA: "head"}},
+			},
+		},
+		Status: prowapi.ProwJobStatus{State: prowapi.PendingState},
+	}
+	testCases := []struct {
+		name     string
+		idents   []string
+		expected int
+	}{
+		{name: "statuses are written by default", expected: 1},
+		{name: "org opted out", idents: []string{"org"}},
+		{name: "repo opted out", idents: []string{"org/repo"}},
+		{name: "other repo opted out", idents: []string{"org/other"}, expected: 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ghc := &fakeGhClient{}
+			reporterConfig := config.GitHubReporter{
+				JobTypesToReport:     []prowapi.ProwJobType{prowapi.PresubmitJob},
+				NoStatusContextRepos: tc.idents,
+			}
+			if err := ReportStatusContext(context.Background(), ghc, pj, reporterConfig); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(ghc.status) != tc.expected {
+				t.Errorf("expected %d statuses, got %d", tc.expected, len(ghc.status))
+			}
+		})
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	refs := &prowapi.Refs{Org: "org", Repo: "repo"}
+	testCases := []struct {
+		name     string
+		failures []TestFailure
+		expected []github.CheckRunAnnotation
+	}{
+		{
+			name:     "bare file names don't resolve to a file of the repo",
+			failures: []TestFailure{{Name: "TestFoo", Message: "foo_test.go:42: unexpected value\n./bar_test.go:7: oops"}},
+		},
+		{
+			name:     "references outside of the repo are ignored",
+			failures: []TestFailure{{Name: "TestFoo", Message: "/usr/local/go/src/testing/testing.go:1595 +0x1"}},
+		},
+		{
+			name:     "paths within the repo",
+			failures: []TestFailure{{Name: "TestFoo", Message: "/home/prow/go/src/github.com/org/repo/pkg/foo/foo_test.go:42: unexpected value"}},
+			expected: []github.CheckRunAnnotation{{
+				Path:            "pkg/foo/foo_test.go",
+				StartLine:       42,
+				EndLine:         42,
+				AnnotationLevel: "failure",
+				Title:           "TestFoo",
+				Message:         "/home/prow/go/src/github.com/org/repo/pkg/foo/foo_test.go:42: unexpected value",
+			}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expected, annotations(refs, tc.failures)); diff != "" {
+				t.Errorf("annotations differ from expected (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		s        string
+		n        int
+		expected string
+	}{
+		{name: "short string", s: "abc", n: 4, expected: "abc"},
+		{name: "ascii", s: "abcdef", n: 4, expected: "abcd"},
+		{name: "multi-byte rune isn't split", s: "ab€cd", n: 4, expected: "ab"},
+		{name: "on a rune boundary", s: "ab€cd", n: 5, expected: "ab€"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := truncate(tc.s, tc.n); actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -48,6 +48,9 @@ type GitHubClient interface {
 	CreateCommentWithContext(ctx context.Context, org, repo string, number int, comment string) error
 	DeleteCommentWithContext(ctx context.Context, org, repo string, ID int) error
 	EditCommentWithContext(ctx context.Context, org, repo string, ID int, comment string) error
+	ListCheckRuns(org, repo, ref string) (*github.CheckRunList, error)
+	CreateCheckRun(org, repo string, checkRun github.CheckRun) (int64, error)
+	UpdateCheckRun(org, repo string, checkRunId int64, checkRun github.CheckRun) error
 }
 
 // prowjobStateToGitHubStatus maps prowjob status to github states.
@@ -141,6 +144,10 @@ func ReportStatusContext(ctx context.Context, ghc GitHubClient, pj prowapi.ProwJ
 		return nil
 	}
 
+	if !config.ReportsStatusContexts(refs.Org, refs.Repo) {
+		return nil
+	}
+
 	if err := reportStatus(ctx, ghc, pj); err != nil {
 		return fmt.Errorf("error setting status: %w", err)
 	}
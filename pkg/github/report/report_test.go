@@ -225,8 +225,9 @@ func createReportEntry(context string, isRequired bool) string {
 }
 
 type fakeGhClient struct {
-	status   []github.Status
-	comments []string
+	status    []github.Status
+	comments  []string
+	checkRuns []github.CheckRun
 }
 
 func (gh fakeGhClient) BotUserCheckerWithContext(_ context.Context) (func(string) bool, error) {
@@ -259,6 +260,20 @@ func (gh fakeGhClient) EditCommentWithContext(_ context.Context, org, repo strin
 	return nil
 }
 
+func (gh fakeGhClient) ListCheckRuns(org, repo, ref string) (*github.CheckRunList, error) {
+	return &github.CheckRunList{CheckRuns: gh.checkRuns}, nil
+}
+func (gh *fakeGhClient) CreateCheckRun(org, repo string, checkRun github.CheckRun) (int64, error) {
+	checkRun.ID = int64(len(gh.checkRuns) + 1)
+	gh.checkRuns = append(gh.checkRuns, checkRun)
+	return checkRun.ID, nil
+}
+func (gh *fakeGhClient) UpdateCheckRun(org, repo string, checkRunId int64, checkRun github.CheckRun) error {
+	checkRun.ID = checkRunId
+	gh.checkRuns[checkRunId-1] = checkRun
+	return nil
+}
+
 func shout(i int) string {
 	if i == 0 {
 		return "start"
@@ -915,6 +915,42 @@ type StatusEvent struct {
 	GUID string
 }
 
+// CheckRunEventAction enumerates the triggers for this
+// webhook payload type. See also:
+// https://docs.github.com/en/webhooks/webhook-events-and-payloads#check_run
+type CheckRunEventAction string
+
+const (
+	// CheckRunActionCreated means a check run was created.
+	CheckRunActionCreated CheckRunEventAction = "created"
+	// CheckRunActionCompleted means a check run completed.
+	CheckRunActionCompleted CheckRunEventAction = "completed"
+	// CheckRunActionRerequested means someone asked to re-run the check run.
+	CheckRunActionRerequested CheckRunEventAction = "rerequested"
+	// CheckRunActionRequestedAction means someone clicked one of the actions
+	// of the check run.
+	CheckRunActionRequestedAction CheckRunEventAction = "requested_action"
+)
+
+// CheckRunEvent fires whenever a check run is created, completed,
+// re-requested or one of its actions is requested.
+type CheckRunEvent struct {
+	Action   CheckRunEventAction `json:"action"`
+	CheckRun CheckRun            `json:"check_run"`
+	// RequestedAction is only set for the requested_action action.
+	RequestedAction *CheckRunRequestedAction `json:"requested_action,omitempty"`
+	Repo            Repo                     `json:"repository"`
+	Sender          User                     `json:"sender"`
+
+	// GUID is included in the header of the request received by GitHub.
+	GUID string
+}
+
+// CheckRunRequestedAction identifies the action requested on a check run.
+type CheckRunRequestedAction struct {
+	Identifier string `json:"identifier"`
+}
+
 // IssuesSearchResult represents the result of an issues search.
 type IssuesSearchResult struct {
 	Total  int     `json:"total_count,omitempty"`
@@ -1091,8 +1127,18 @@ type DraftReview struct {
 type DraftReviewComment struct {
 	Path string `json:"path"`
 	// Position in the patch, not the line number in the file.
-	Position int    `json:"position"`
+	// Leave it unset when addressing the comment with Line.
+	Position int    `json:"position,omitempty"`
 	Body     string `json:"body"`
+	// Line is the line of the blob in the pull request diff that the comment
+	// applies to. For a multi-line comment, the last line of the range.
+	Line int `json:"line,omitempty"`
+	// Side is the side of the diff that Line applies to: LEFT or RIGHT.
+	Side DiffSide `json:"side,omitempty"`
+	// StartLine is the first line of the range for a multi-line comment.
+	StartLine int `json:"start_line,omitempty"`
+	// StartSide is the side of the diff that StartLine applies to.
+	StartSide DiffSide `json:"start_side,omitempty"`
 }
 
 // Content is some base64 encoded github file content
@@ -1445,6 +1491,21 @@ type CheckRun struct {
 	CheckSuite   CheckSuite     `json:"check_suite,omitempty"`
 	App          App            `json:"app,omitempty"`
 	PullRequests []PullRequest  `json:"pull_requests,omitempty"`
+	// Actions are the buttons offered on the check run, GitHub sends a
+	// CheckRunEvent with the requested_action action when one is clicked.
+	Actions []CheckRunAction `json:"actions,omitempty"`
+}
+
+// CheckRunAction is a button offered on a check run.
+//
+// See https://docs.github.com/en/rest/guides/using-the-rest-api-to-interact-with-checks#check-runs-and-requested-actions
+type CheckRunAction struct {
+	// Label is the text of the button, at most 20 characters.
+	Label string `json:"label"`
+	// Description is shown when hovering the button, at most 40 characters.
+	Description string `json:"description"`
+	// Identifier is sent back in the CheckRunEvent, at most 20 characters.
+	Identifier string `json:"identifier"`
 }
 
 type CheckRunOutput struct {
@@ -28,6 +28,8 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"sigs.k8s.io/prow/pkg/config"
 	"sigs.k8s.io/prow/pkg/github"
@@ -35,6 +37,7 @@ import (
 	pluginhelp_externalplugins "sigs.k8s.io/prow/pkg/pluginhelp/externalplugins"
 	pluginhelp_hook "sigs.k8s.io/prow/pkg/pluginhelp/hook"
 	"sigs.k8s.io/prow/pkg/plugins"
+	"sigs.k8s.io/prow/pkg/tracing"
 )
 
 const (
@@ -225,6 +228,20 @@ func (s *serveMuxHandler) handleEvent(eventType, eventGUID string, payload []byt
 
 	l := logrus.WithFields(logrus.Fields{eventTypeField: eventType, github.EventGUID: eventGUID})
 
+	// The span continues the trace of hook if it forwarded the event, and
+	// ends once the handlers of the event returned.
+	ctx, span := tracing.Tracer().Start(tracing.ExtractHeader(context.Background(), h), "githubeventserver.event", trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+		attribute.String("github.event", eventType),
+		attribute.String("github.event_guid", eventGUID),
+	))
+	handlers := &sync.WaitGroup{}
+	defer func() {
+		go func() {
+			handlers.Wait()
+			span.End()
+		}()
+	}()
+
 	// We don't want to fail the webhook due to a metrics error.
 	if counter, err := s.metrics.WebhookCounter.GetMetricWithLabelValues(eventType); err != nil {
 		l.WithError(err).Warn("Failed to get metric for eventType " + eventType)
@@ -245,8 +262,10 @@ func (s *serveMuxHandler) handleEvent(eventType, eventGUID string, payload []byt
 		for _, issueEventHandler := range s.issueEventHandlers {
 			fn := issueEventHandler
 			s.wg.Add(1)
+			handlers.Add(1)
 			go func() {
 				defer s.wg.Done()
+				defer handlers.Done()
 				fn(l.WithFields(logrus.Fields{
 					github.OrgLogField:  i.Repo.Owner.Login,
 					github.RepoLogField: i.Repo.Name,
@@ -269,8 +288,10 @@ func (s *serveMuxHandler) handleEvent(eventType, eventGUID string, payload []byt
 		for _, issueCommentEventHandler := range s.issueCommentEventHandlers {
 			fn := issueCommentEventHandler
 			s.wg.Add(1)
+			handlers.Add(1)
 			go func() {
 				defer s.wg.Done()
+				defer handlers.Done()
 				fn(l.WithFields(logrus.Fields{
 					github.OrgLogField:  ic.Repo.Owner.Login,
 					github.RepoLogField: ic.Repo.Name,
@@ -293,8 +314,10 @@ func (s *serveMuxHandler) handleEvent(eventType, eventGUID string, payload []byt
 		for _, pullRequestHandler := range s.pullRequestHandlers {
 			fn := pullRequestHandler
 			s.wg.Add(1)
+			handlers.Add(1)
 			go func() {
 				defer s.wg.Done()
+				defer handlers.Done()
 				fn(l.WithFields(logrus.Fields{
 					github.OrgLogField:  pr.Repo.Owner.Login,
 					github.RepoLogField: pr.Repo.Name,
@@ -317,8 +340,10 @@ func (s *serveMuxHandler) handleEvent(eventType, eventGUID string, payload []byt
 		for _, reviewEventHandler := range s.reviewEventHandlers {
 			fn := reviewEventHandler
 			s.wg.Add(1)
+			handlers.Add(1)
 			go func() {
 				defer s.wg.Done()
+				defer handlers.Done()
 				fn(l.WithFields(logrus.Fields{
 					github.OrgLogField:  re.Repo.Owner.Login,
 					github.RepoLogField: re.Repo.Name,
@@ -342,8 +367,10 @@ func (s *serveMuxHandler) handleEvent(eventType, eventGUID string, payload []byt
 		for _, reviewCommentEventHandler := range s.reviewCommentEventHandlers {
 			fn := reviewCommentEventHandler
 			s.wg.Add(1)
+			handlers.Add(1)
 			go func() {
 				defer s.wg.Done()
+				defer handlers.Done()
 				fn(l.WithFields(logrus.Fields{
 					github.OrgLogField:  rce.Repo.Owner.Login,
 					github.RepoLogField: rce.Repo.Name,
@@ -367,8 +394,10 @@ func (s *serveMuxHandler) handleEvent(eventType, eventGUID string, payload []byt
 		for _, pushEventHandler := range s.pushEventHandlers {
 			fn := pushEventHandler
 			s.wg.Add(1)
+			handlers.Add(1)
 			go func() {
 				defer s.wg.Done()
+				defer handlers.Done()
 				fn(l.WithFields(logrus.Fields{
 					github.OrgLogField:  pe.Repo.Owner.Name,
 					github.RepoLogField: pe.Repo.Name,
@@ -390,8 +419,10 @@ func (s *serveMuxHandler) handleEvent(eventType, eventGUID string, payload []byt
 		for _, statusEventHandler := range s.statusEventHandlers {
 			fn := statusEventHandler
 			s.wg.Add(1)
+			handlers.Add(1)
 			go func() {
 				defer s.wg.Done()
+				defer handlers.Done()
 				fn(l.WithFields(logrus.Fields{
 					github.OrgLogField:  se.Repo.Owner.Login,
 					github.RepoLogField: se.Repo.Name,
@@ -415,8 +446,10 @@ func (s *serveMuxHandler) handleEvent(eventType, eventGUID string, payload []byt
 		for _, workflowRunEventHandler := range s.workflowRunEventHandler {
 			fn := workflowRunEventHandler
 			s.wg.Add(1)
+			handlers.Add(1)
 			go func() {
 				defer s.wg.Done()
+				defer handlers.Done()
 				fn(l.WithFields(logrus.Fields{
 					github.OrgLogField:  wre.Repo.Owner.Login,
 					github.RepoLogField: wre.Repo.Name,
@@ -437,8 +470,10 @@ func (s *serveMuxHandler) handleEvent(eventType, eventGUID string, payload []byt
 		for _, registryPackageEventHandler := range s.registryPackageEventHandlers {
 			fn := registryPackageEventHandler
 			s.wg.Add(1)
+			handlers.Add(1)
 			go func() {
 				defer s.wg.Done()
+				defer handlers.Done()
 				fn(l.WithFields(logrus.Fields{
 					github.OrgLogField:    rpe.Repo.Owner.Login,
 					github.RepoLogField:   rpe.Repo.Name,
@@ -452,6 +487,7 @@ func (s *serveMuxHandler) handleEvent(eventType, eventGUID string, payload []byt
 	}
 
 	// Redirect event to external plugins if necessary
+	tracing.InjectHeader(ctx, h)
 	s.demuxExternal(l, s.getExternalPluginsForEvent(org, repo, eventType), payload, h, s.wg)
 
 	return nil
@@ -17,6 +17,7 @@ limitations under the License.
 package hook
 
 import (
+	"context"
 	"fmt"
 	"runtime/debug"
 	"strconv"
@@ -24,9 +25,12 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"sigs.k8s.io/prow/pkg/github"
 	"sigs.k8s.io/prow/pkg/plugins"
+	"sigs.k8s.io/prow/pkg/tracing"
 )
 
 const FailedCommentCoerceFmt = "Could not coerce %s event to a GenericCommentEvent. Unknown 'action': %q."
@@ -69,8 +73,8 @@ var (
 	}
 )
 
-func (s *Server) handleReviewEvent(l *logrus.Entry, re github.ReviewEvent) {
-	defer s.wg.Done()
+func (s *Server) handleReviewEvent(ctx context.Context, l *logrus.Entry, re github.ReviewEvent) {
+	defer s.handlerDone(ctx)
 	l = l.WithFields(logrus.Fields{
 		github.OrgLogField:  re.Repo.Owner.Login,
 		github.RepoLogField: re.Repo.Name,
@@ -81,10 +85,11 @@ func (s *Server) handleReviewEvent(l *logrus.Entry, re github.ReviewEvent) {
 	})
 	l.Infof("Review %s.", re.Action)
 	for p, h := range s.Plugins.ReviewEventHandlers(re.PullRequest.Base.Repo.Owner.Login, re.PullRequest.Base.Repo.Name) {
-		s.wg.Add(1)
+		s.addHandler(ctx)
 		go func(p string, h plugins.ReviewEventHandler) {
-			defer s.wg.Done()
+			defer s.handlerDone(ctx)
 			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, re.Repo.Owner.Login, s.Metrics.Metrics, l, p)
+			span := tracePlugin(ctx, p, &agent)
 			agent.InitializeCommentPruner(
 				re.Repo.Owner.Login,
 				re.Repo.Name,
@@ -92,6 +97,7 @@ func (s *Server) handleReviewEvent(l *logrus.Entry, re github.ReviewEvent) {
 			)
 			start := time.Now()
 			err := errorOnPanic(func() error { return h(agent, re) })
+			tracing.End(span, err)
 			labels := prometheus.Labels{"event_type": l.Data[eventTypeField].(string), "action": string(re.Action), "plugin": p, "took_action": strconv.FormatBool(agent.TookAction())}
 			if err != nil {
 				agent.Logger.WithError(err).Error("Error handling ReviewEvent.")
@@ -112,11 +118,11 @@ func (s *Server) handleReviewEvent(l *logrus.Entry, re github.ReviewEvent) {
 		return
 	}
 
-	s.handleGenericComment(l, gce)
+	s.handleGenericComment(ctx, l, gce)
 }
 
-func (s *Server) handleReviewCommentEvent(l *logrus.Entry, rce github.ReviewCommentEvent) {
-	defer s.wg.Done()
+func (s *Server) handleReviewCommentEvent(ctx context.Context, l *logrus.Entry, rce github.ReviewCommentEvent) {
+	defer s.handlerDone(ctx)
 	l = l.WithFields(logrus.Fields{
 		github.OrgLogField:  rce.Repo.Owner.Login,
 		github.RepoLogField: rce.Repo.Name,
@@ -127,10 +133,11 @@ func (s *Server) handleReviewCommentEvent(l *logrus.Entry, rce github.ReviewComm
 	})
 	l.Infof("Review comment %s.", rce.Action)
 	for p, h := range s.Plugins.ReviewCommentEventHandlers(rce.PullRequest.Base.Repo.Owner.Login, rce.PullRequest.Base.Repo.Name) {
-		s.wg.Add(1)
+		s.addHandler(ctx)
 		go func(p string, h plugins.ReviewCommentEventHandler) {
-			defer s.wg.Done()
+			defer s.handlerDone(ctx)
 			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, rce.Repo.Owner.Login, s.Metrics.Metrics, l, p)
+			span := tracePlugin(ctx, p, &agent)
 			agent.InitializeCommentPruner(
 				rce.Repo.Owner.Login,
 				rce.Repo.Name,
@@ -138,6 +145,7 @@ func (s *Server) handleReviewCommentEvent(l *logrus.Entry, rce github.ReviewComm
 			)
 			start := time.Now()
 			err := errorOnPanic(func() error { return h(agent, rce) })
+			tracing.End(span, err)
 			labels := prometheus.Labels{"event_type": l.Data[eventTypeField].(string), "action": string(rce.Action), "plugin": p, "took_action": strconv.FormatBool(agent.TookAction())}
 			if err != nil {
 				agent.Logger.WithError(err).Error("Error handling ReviewCommentEvent.")
@@ -158,11 +166,11 @@ func (s *Server) handleReviewCommentEvent(l *logrus.Entry, rce github.ReviewComm
 		return
 	}
 
-	s.handleGenericComment(l, gce)
+	s.handleGenericComment(ctx, l, gce)
 }
 
-func (s *Server) handlePullRequestEvent(l *logrus.Entry, pr github.PullRequestEvent) {
-	defer s.wg.Done()
+func (s *Server) handlePullRequestEvent(ctx context.Context, l *logrus.Entry, pr github.PullRequestEvent) {
+	defer s.handlerDone(ctx)
 	l = l.WithFields(logrus.Fields{
 		github.OrgLogField:  pr.Repo.Owner.Login,
 		github.RepoLogField: pr.Repo.Name,
@@ -172,10 +180,11 @@ func (s *Server) handlePullRequestEvent(l *logrus.Entry, pr github.PullRequestEv
 	})
 	l.Infof("Pull request %s.", pr.Action)
 	for p, h := range s.Plugins.PullRequestHandlers(pr.PullRequest.Base.Repo.Owner.Login, pr.PullRequest.Base.Repo.Name) {
-		s.wg.Add(1)
+		s.addHandler(ctx)
 		go func(p string, h plugins.PullRequestHandler) {
-			defer s.wg.Done()
+			defer s.handlerDone(ctx)
 			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, pr.Repo.Owner.Login, s.Metrics.Metrics, l, p)
+			span := tracePlugin(ctx, p, &agent)
 			agent.InitializeCommentPruner(
 				pr.Repo.Owner.Login,
 				pr.Repo.Name,
@@ -183,6 +192,7 @@ func (s *Server) handlePullRequestEvent(l *logrus.Entry, pr github.PullRequestEv
 			)
 			start := time.Now()
 			err := errorOnPanic(func() error { return h(agent, pr) })
+			tracing.End(span, err)
 			labels := prometheus.Labels{"event_type": l.Data[eventTypeField].(string), "action": string(pr.Action), "plugin": p, "took_action": strconv.FormatBool(agent.TookAction())}
 			if err != nil {
 				agent.Logger.WithError(err).Error("Error handling PullRequestEvent.")
@@ -205,11 +215,11 @@ func (s *Server) handlePullRequestEvent(l *logrus.Entry, pr github.PullRequestEv
 		return
 	}
 
-	s.handleGenericComment(l, gce)
+	s.handleGenericComment(ctx, l, gce)
 }
 
-func (s *Server) handlePushEvent(l *logrus.Entry, pe github.PushEvent) {
-	defer s.wg.Done()
+func (s *Server) handlePushEvent(ctx context.Context, l *logrus.Entry, pe github.PushEvent) {
+	defer s.handlerDone(ctx)
 	l = l.WithFields(logrus.Fields{
 		github.OrgLogField:  pe.Repo.Owner.Name,
 		github.RepoLogField: pe.Repo.Name,
@@ -218,12 +228,14 @@ func (s *Server) handlePushEvent(l *logrus.Entry, pe github.PushEvent) {
 	})
 	l.Info("Push event.")
 	for p, h := range s.Plugins.PushEventHandlers(pe.Repo.Owner.Name, pe.Repo.Name) {
-		s.wg.Add(1)
+		s.addHandler(ctx)
 		go func(p string, h plugins.PushEventHandler) {
-			defer s.wg.Done()
+			defer s.handlerDone(ctx)
 			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, pe.Repo.Owner.Login, s.Metrics.Metrics, l, p)
+			span := tracePlugin(ctx, p, &agent)
 			start := time.Now()
 			err := errorOnPanic(func() error { return h(agent, pe) })
+			tracing.End(span, err)
 			labels := prometheus.Labels{"event_type": l.Data[eventTypeField].(string), "action": "none", "plugin": p, "took_action": strconv.FormatBool(agent.TookAction())}
 			if err != nil {
 				agent.Logger.WithError(err).Error("Error handling PushEvent.")
@@ -234,8 +246,8 @@ func (s *Server) handlePushEvent(l *logrus.Entry, pe github.PushEvent) {
 	}
 }
 
-func (s *Server) handleIssueEvent(l *logrus.Entry, i github.IssueEvent) {
-	defer s.wg.Done()
+func (s *Server) handleIssueEvent(ctx context.Context, l *logrus.Entry, i github.IssueEvent) {
+	defer s.handlerDone(ctx)
 	l = l.WithFields(logrus.Fields{
 		github.OrgLogField:  i.Repo.Owner.Login,
 		github.RepoLogField: i.Repo.Name,
@@ -245,10 +257,11 @@ func (s *Server) handleIssueEvent(l *logrus.Entry, i github.IssueEvent) {
 	})
 	l.Infof("Issue %s.", i.Action)
 	for p, h := range s.Plugins.IssueHandlers(i.Repo.Owner.Login, i.Repo.Name) {
-		s.wg.Add(1)
+		s.addHandler(ctx)
 		go func(p string, h plugins.IssueHandler) {
-			defer s.wg.Done()
+			defer s.handlerDone(ctx)
 			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, i.Repo.Owner.Login, s.Metrics.Metrics, l, p)
+			span := tracePlugin(ctx, p, &agent)
 			agent.InitializeCommentPruner(
 				i.Repo.Owner.Login,
 				i.Repo.Name,
@@ -256,6 +269,7 @@ func (s *Server) handleIssueEvent(l *logrus.Entry, i github.IssueEvent) {
 			)
 			start := time.Now()
 			err := errorOnPanic(func() error { return h(agent, i) })
+			tracing.End(span, err)
 			labels := prometheus.Labels{"event_type": l.Data[eventTypeField].(string), "action": string(i.Action), "plugin": p, "took_action": strconv.FormatBool(agent.TookAction())}
 			if err != nil {
 				agent.Logger.WithError(err).Error("Error handling IssueEvent.")
@@ -278,11 +292,11 @@ func (s *Server) handleIssueEvent(l *logrus.Entry, i github.IssueEvent) {
 		return
 	}
 
-	s.handleGenericComment(l, gce)
+	s.handleGenericComment(ctx, l, gce)
 }
 
-func (s *Server) handleIssueCommentEvent(l *logrus.Entry, ic github.IssueCommentEvent) {
-	defer s.wg.Done()
+func (s *Server) handleIssueCommentEvent(ctx context.Context, l *logrus.Entry, ic github.IssueCommentEvent) {
+	defer s.handlerDone(ctx)
 	l = l.WithFields(logrus.Fields{
 		github.OrgLogField:  ic.Repo.Owner.Login,
 		github.RepoLogField: ic.Repo.Name,
@@ -292,10 +306,11 @@ func (s *Server) handleIssueCommentEvent(l *logrus.Entry, ic github.IssueComment
 	})
 	l.Infof("Issue comment %s.", ic.Action)
 	for p, h := range s.Plugins.IssueCommentHandlers(ic.Repo.Owner.Login, ic.Repo.Name) {
-		s.wg.Add(1)
+		s.addHandler(ctx)
 		go func(p string, h plugins.IssueCommentHandler) {
-			defer s.wg.Done()
+			defer s.handlerDone(ctx)
 			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, ic.Repo.Owner.Login, s.Metrics.Metrics, l, p)
+			span := tracePlugin(ctx, p, &agent)
 			agent.InitializeCommentPruner(
 				ic.Repo.Owner.Login,
 				ic.Repo.Name,
@@ -303,6 +318,7 @@ func (s *Server) handleIssueCommentEvent(l *logrus.Entry, ic github.IssueComment
 			)
 			start := time.Now()
 			err := errorOnPanic(func() error { return h(agent, ic) })
+			tracing.End(span, err)
 			labels := prometheus.Labels{"event_type": l.Data[eventTypeField].(string), "action": string(ic.Action), "plugin": p, "took_action": strconv.FormatBool(agent.TookAction())}
 			if err != nil {
 				agent.Logger.WithError(err).Error("Error handling IssueCommentEvent.")
@@ -323,11 +339,11 @@ func (s *Server) handleIssueCommentEvent(l *logrus.Entry, ic github.IssueComment
 		return
 	}
 
-	s.handleGenericComment(l, gce)
+	s.handleGenericComment(ctx, l, gce)
 }
 
-func (s *Server) handleStatusEvent(l *logrus.Entry, se github.StatusEvent) {
-	defer s.wg.Done()
+func (s *Server) handleStatusEvent(ctx context.Context, l *logrus.Entry, se github.StatusEvent) {
+	defer s.handlerDone(ctx)
 	l = l.WithFields(logrus.Fields{
 		github.OrgLogField:  se.Repo.Owner.Login,
 		github.RepoLogField: se.Repo.Name,
@@ -338,12 +354,14 @@ func (s *Server) handleStatusEvent(l *logrus.Entry, se github.StatusEvent) {
 	})
 	l.Infof("Status description %s.", se.Description)
 	for p, h := range s.Plugins.StatusEventHandlers(se.Repo.Owner.Login, se.Repo.Name) {
-		s.wg.Add(1)
+		s.addHandler(ctx)
 		go func(p string, h plugins.StatusEventHandler) {
-			defer s.wg.Done()
+			defer s.handlerDone(ctx)
 			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, se.Repo.Owner.Login, s.Metrics.Metrics, l, p)
+			span := tracePlugin(ctx, p, &agent)
 			start := time.Now()
 			err := errorOnPanic(func() error { return h(agent, se) })
+			tracing.End(span, err)
 			labels := prometheus.Labels{"event_type": l.Data[eventTypeField].(string), "action": "none", "plugin": p, "took_action": strconv.FormatBool(agent.TookAction())}
 			if err != nil {
 				agent.Logger.WithError(err).Error("Error handling StatusEvent.")
@@ -354,12 +372,43 @@ func (s *Server) handleStatusEvent(l *logrus.Entry, se github.StatusEvent) {
 	}
 }
 
-func (s *Server) handleGenericComment(l *logrus.Entry, ce *github.GenericCommentEvent) {
+func (s *Server) handleCheckRunEvent(ctx context.Context, l *logrus.Entry, ce github.CheckRunEvent) {
+	defer s.handlerDone(ctx)
+	l = l.WithFields(logrus.Fields{
+		github.OrgLogField:  ce.Repo.Owner.Login,
+		github.RepoLogField: ce.Repo.Name,
+		"name":              ce.CheckRun.Name,
+		"sha":               ce.CheckRun.HeadSHA,
+		"id":                ce.CheckRun.ID,
+		"external_id":       ce.CheckRun.ExternalID,
+	})
+	l.Infof("Check run %s.", ce.Action)
+	for p, h := range s.Plugins.CheckRunEventHandlers(ce.Repo.Owner.Login, ce.Repo.Name) {
+		s.addHandler(ctx)
+		go func(p string, h plugins.CheckRunEventHandler) {
+			defer s.handlerDone(ctx)
+			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, ce.Repo.Owner.Login, s.Metrics.Metrics, l, p)
+			span := tracePlugin(ctx, p, &agent)
+			start := time.Now()
+			err := errorOnPanic(func() error { return h(agent, ce) })
+			tracing.End(span, err)
+			labels := prometheus.Labels{"event_type": l.Data[eventTypeField].(string), "action": string(ce.Action), "plugin": p, "took_action": strconv.FormatBool(agent.TookAction())}
+			if err != nil {
+				agent.Logger.WithError(err).Error("Error handling CheckRunEvent.")
+				s.Metrics.PluginHandleErrors.With(labels).Inc()
+			}
+			s.Metrics.PluginHandleDuration.With(labels).Observe(time.Since(start).Seconds())
+		}(p, h)
+	}
+}
+
+func (s *Server) handleGenericComment(ctx context.Context, l *logrus.Entry, ce *github.GenericCommentEvent) {
 	for p, h := range s.Plugins.GenericCommentHandlers(ce.Repo.Owner.Login, ce.Repo.Name) {
-		s.wg.Add(1)
+		s.addHandler(ctx)
 		go func(p string, h plugins.GenericCommentHandler) {
-			defer s.wg.Done()
+			defer s.handlerDone(ctx)
 			agent := plugins.NewAgent(s.ConfigAgent, s.Plugins, s.ClientAgent, ce.Repo.Owner.Login, s.Metrics.Metrics, l, p)
+			span := tracePlugin(ctx, p, &agent)
 			agent.InitializeCommentPruner(
 				ce.Repo.Owner.Login,
 				ce.Repo.Name,
@@ -367,6 +416,7 @@ func (s *Server) handleGenericComment(l *logrus.Entry, ce *github.GenericComment
 			)
 			start := time.Now()
 			err := errorOnPanic(func() error { return h(agent, *ce) })
+			tracing.End(span, err)
 			labels := prometheus.Labels{"event_type": l.Data[eventTypeField].(string), "action": string(ce.Action), "plugin": p, "took_action": strconv.FormatBool(agent.TookAction())}
 			if err != nil {
 				agent.Logger.WithError(err).Error("Error handling GenericCommentEvent.")
@@ -377,6 +427,15 @@ func (s *Server) handleGenericComment(l *logrus.Entry, ce *github.GenericComment
 	}
 }
 
+// tracePlugin starts the span of the plugin handling the event, the GitHub
+// requests of the plugin and the ProwJobs it creates continue its trace.
+func tracePlugin(ctx context.Context, plugin string, agent *plugins.Agent) trace.Span {
+	ctx, span := tracing.Tracer().Start(ctx, "hook.plugin", trace.WithAttributes(attribute.String("plugin", plugin)))
+	agent.WithContext(ctx)
+	agent.ProwJobClient = tracing.ProwJobClient(ctx, agent.ProwJobClient)
+	return span
+}
+
 func errorOnPanic(f func() error) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
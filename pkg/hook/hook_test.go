@@ -18,10 +18,15 @@ package hook
 
 import (
 	"encoding/json"
+	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
 	"sigs.k8s.io/prow/pkg/bugzilla"
 	"sigs.k8s.io/prow/pkg/config"
 	"sigs.k8s.io/prow/pkg/github"
@@ -31,6 +36,7 @@ import (
 	"sigs.k8s.io/prow/pkg/plugins"
 	"sigs.k8s.io/prow/pkg/plugins/ownersconfig"
 	"sigs.k8s.io/prow/pkg/repoowners"
+	"sigs.k8s.io/prow/pkg/tracing"
 )
 
 var ice = github.IssueCommentEvent{
@@ -185,3 +191,64 @@ func TestHook(t *testing.T) {
 		t.Error("Plugin not called after one second.")
 	}
 }
+
+// TestEventSpanEndsAfterPlugins ensures that the span of an event covers the
+// plugins handling it.
+func TestEventSpanEndsAfterPlugins(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(tracing.NewTracerProvider("hook", 1, sdktrace.WithSyncer(exporter)))
+	defer otel.SetTracerProvider(previous)
+
+	release := make(chan struct{})
+	plugins.RegisterIssueHandler(
+		"traced",
+		func(pc plugins.Agent, ie github.IssueEvent) error {
+			<-release
+			return nil
+		},
+		nil,
+	)
+	pa := &plugins.ConfigAgent{}
+	pa.Set(&plugins.Configuration{Plugins: plugins.Plugins{"foo/bar": {Plugins: []string{"traced"}}}})
+	s := &Server{
+		ClientAgent: &plugins.ClientAgent{
+			GitHubClient:   github.NewFakeClient(),
+			OwnersClient:   repoowners.NewClient(nil, nil, func(org, repo string) bool { return false }, func(org, repo string) bool { return false }, func() *config.OwnersDirDenylist { return &config.OwnersDirDenylist{} }, ownersconfig.FakeResolver),
+			JiraClient:     &fakejira.FakeClient{},
+			BugzillaClient: &bugzilla.Fake{},
+		},
+		Plugins:     pa,
+		ConfigAgent: &config.Agent{},
+		Metrics:     githubeventserver.NewMetrics(),
+		RepoEnabled: func(org, repo string) bool { return true },
+	}
+	payload, err := json.Marshal(&ice)
+	if err != nil {
+		t.Fatalf("Marshalling ICE: %v", err)
+	}
+	if err := s.demuxEvent("issues", "guid", payload, http.Header{}); err != nil {
+		t.Fatalf("Demuxing the event: %v", err)
+	}
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Fatalf("Expected no span to end before the plugin, got %d", len(spans))
+	}
+	close(release)
+	s.GracefulShutdown()
+
+	deadline := time.Now().Add(time.Second)
+	for len(exporter.GetSpans()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected the spans of the plugin and of the event, got %d", len(spans))
+	}
+	plugin, event := spans[0], spans[1]
+	if plugin.Name != "hook.plugin" || event.Name != "hook.event" {
+		t.Fatalf("Expected the plugin span to end before the event span, got %s and %s", plugin.Name, event.Name)
+	}
+	if plugin.Parent.SpanID() != event.SpanContext.SpanID() {
+		t.Errorf("Expected the plugin span to be a child of the event span")
+	}
+}
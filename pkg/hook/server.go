@@ -18,6 +18,7 @@ package hook
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -28,12 +29,15 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"sigs.k8s.io/prow/pkg/config"
 	"sigs.k8s.io/prow/pkg/github"
 	"sigs.k8s.io/prow/pkg/githubeventserver"
 	_ "sigs.k8s.io/prow/pkg/hook/plugin-imports"
 	"sigs.k8s.io/prow/pkg/plugins"
+	"sigs.k8s.io/prow/pkg/tracing"
 )
 
 // Server implements http.Handler. It validates incoming GitHub webhooks and
@@ -81,6 +85,19 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 			github.EventGUID: eventGUID,
 		},
 	)
+	// The span of the event ends once the plugins handled it.
+	ctx, span := tracing.Tracer().Start(context.Background(), "hook.event", trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+		attribute.String("github.event", eventType),
+		attribute.String("github.event_guid", eventGUID),
+	))
+	handlers := &sync.WaitGroup{}
+	ctx = context.WithValue(ctx, eventHandlersKey{}, handlers)
+	defer func() {
+		go func() {
+			handlers.Wait()
+			span.End()
+		}()
+	}()
 	// We don't want to fail the webhook due to a metrics error.
 	if counter, err := s.Metrics.WebhookCounter.GetMetricWithLabelValues(eventType); err != nil {
 		l.WithError(err).Warn("Failed to get metric for eventType " + eventType)
@@ -97,8 +114,8 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 		i.GUID = eventGUID
 		srcRepo = i.Repo.FullName
 		if s.RepoEnabled(i.Repo.Owner.Login, i.Repo.Name) {
-			s.wg.Add(1)
-			go s.handleIssueEvent(l, i)
+			s.addHandler(ctx)
+			go s.handleIssueEvent(ctx, l, i)
 		}
 	case "issue_comment":
 		var ic github.IssueCommentEvent
@@ -108,8 +125,8 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 		ic.GUID = eventGUID
 		srcRepo = ic.Repo.FullName
 		if s.RepoEnabled(ic.Repo.Owner.Login, ic.Repo.Name) {
-			s.wg.Add(1)
-			go s.handleIssueCommentEvent(l, ic)
+			s.addHandler(ctx)
+			go s.handleIssueCommentEvent(ctx, l, ic)
 		}
 	case "pull_request":
 		var pr github.PullRequestEvent
@@ -119,8 +136,8 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 		pr.GUID = eventGUID
 		srcRepo = pr.Repo.FullName
 		if s.RepoEnabled(pr.Repo.Owner.Login, pr.Repo.Name) {
-			s.wg.Add(1)
-			go s.handlePullRequestEvent(l, pr)
+			s.addHandler(ctx)
+			go s.handlePullRequestEvent(ctx, l, pr)
 		}
 	case "pull_request_review":
 		var re github.ReviewEvent
@@ -130,8 +147,8 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 		re.GUID = eventGUID
 		srcRepo = re.Repo.FullName
 		if s.RepoEnabled(re.Repo.Owner.Login, re.Repo.Name) {
-			s.wg.Add(1)
-			go s.handleReviewEvent(l, re)
+			s.addHandler(ctx)
+			go s.handleReviewEvent(ctx, l, re)
 		}
 	case "pull_request_review_comment":
 		var rce github.ReviewCommentEvent
@@ -141,8 +158,8 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 		rce.GUID = eventGUID
 		srcRepo = rce.Repo.FullName
 		if s.RepoEnabled(rce.Repo.Owner.Login, rce.Repo.Name) {
-			s.wg.Add(1)
-			go s.handleReviewCommentEvent(l, rce)
+			s.addHandler(ctx)
+			go s.handleReviewCommentEvent(ctx, l, rce)
 		}
 	case "push":
 		var pe github.PushEvent
@@ -152,8 +169,8 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 		pe.GUID = eventGUID
 		srcRepo = pe.Repo.FullName
 		if s.RepoEnabled(pe.Repo.Owner.Login, pe.Repo.Name) {
-			s.wg.Add(1)
-			go s.handlePushEvent(l, pe)
+			s.addHandler(ctx)
+			go s.handlePushEvent(ctx, l, pe)
 		}
 	case "status":
 		var se github.StatusEvent
@@ -163,8 +180,19 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 		se.GUID = eventGUID
 		srcRepo = se.Repo.FullName
 		if s.RepoEnabled(se.Repo.Owner.Login, se.Repo.Name) {
-			s.wg.Add(1)
-			go s.handleStatusEvent(l, se)
+			s.addHandler(ctx)
+			go s.handleStatusEvent(ctx, l, se)
+		}
+	case "check_run":
+		var ce github.CheckRunEvent
+		if err := json.Unmarshal(payload, &ce); err != nil {
+			return err
+		}
+		ce.GUID = eventGUID
+		srcRepo = ce.Repo.FullName
+		if s.RepoEnabled(ce.Repo.Owner.Login, ce.Repo.Name) {
+			s.addHandler(ctx)
+			go s.handleCheckRunEvent(ctx, l, ce)
 		}
 	default:
 		var ge github.GenericEvent
@@ -176,8 +204,8 @@ func (s *Server) demuxEvent(eventType, eventGUID string, payload []byte, h http.
 	}
 	// Demux events only to external plugins that require this event.
 	if external := s.needDemux(eventType, srcRepo); len(external) > 0 {
-		s.wg.Add(1)
-		go s.demuxExternal(l, external, payload, h)
+		s.addHandler(ctx)
+		go s.demuxExternal(ctx, l, external, payload, h)
 	}
 	return nil
 }
@@ -220,14 +248,36 @@ func (s *Server) needDemux(eventType, orgRepo string) []plugins.ExternalPlugin {
 	return matching
 }
 
+// eventHandlersKey is the context key of the handlers of a webhook event
+// still running.
+type eventHandlersKey struct{}
+
+// addHandler registers a handler of the event of ctx, to be waited for on
+// shutdown and before ending the span of the event.
+func (s *Server) addHandler(ctx context.Context) {
+	s.wg.Add(1)
+	if handlers, ok := ctx.Value(eventHandlersKey{}).(*sync.WaitGroup); ok {
+		handlers.Add(1)
+	}
+}
+
+// handlerDone marks a handler registered with addHandler as done.
+func (s *Server) handlerDone(ctx context.Context) {
+	if handlers, ok := ctx.Value(eventHandlersKey{}).(*sync.WaitGroup); ok {
+		handlers.Done()
+	}
+	s.wg.Done()
+}
+
 // demuxExternal dispatches the provided payload to the external plugins.
-func (s *Server) demuxExternal(l *logrus.Entry, externalPlugins []plugins.ExternalPlugin, payload []byte, h http.Header) {
-	defer s.wg.Done()
+func (s *Server) demuxExternal(ctx context.Context, l *logrus.Entry, externalPlugins []plugins.ExternalPlugin, payload []byte, h http.Header) {
+	defer s.handlerDone(ctx)
 	h.Set("User-Agent", "ProwHook")
+	tracing.InjectHeader(ctx, h)
 	for _, p := range externalPlugins {
-		s.wg.Add(1)
+		s.addHandler(ctx)
 		go func(p plugins.ExternalPlugin) {
-			defer s.wg.Done()
+			defer s.handlerDone(ctx)
 			if err := s.dispatch(p.Endpoint, payload, h); err != nil {
 				l.WithError(err).WithField("external-plugin", p.Name).Error("Error dispatching event to external plugin.")
 			} else {
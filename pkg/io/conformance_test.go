@@ -0,0 +1,456 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package io
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fsouza/fake-gcs-server/fakestorage"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+	"gocloud.dev/blob"
+	"gocloud.dev/blob/fileblob"
+
+	"sigs.k8s.io/prow/pkg/io/providers"
+)
+
+// conformanceProvider is a provider the opener is tested against, backed by a
+// local fake of its storage.
+type conformanceProvider struct {
+	name string
+	// setup returns an opener for the paths of the bucket, e.g. gs://bucket,
+	// and an HTTP client that can fetch the signed URLs of the provider.
+	setup func(t *testing.T) (o Opener, bucket string, client *http.Client)
+}
+
+var conformanceProviders = []conformanceProvider{
+	{
+		name: "gcs",
+		setup: func(t *testing.T) (Opener, string, *http.Client) {
+			server := fakestorage.NewServer(nil)
+			t.Cleanup(server.Stop)
+			server.CreateBucket("bucket")
+			return NewGCSOpener(server.Client()), "gs://bucket", server.HTTPClient()
+		},
+	},
+	{
+		name: "s3-compatible store with a custom endpoint and path-style addressing",
+		setup: func(t *testing.T) (Opener, string, *http.Client) {
+			server := httptest.NewServer(newFakeS3("bucket"))
+			t.Cleanup(server.Close)
+			providers.Register("fakes3", providers.S3Compatible("Fake S3"))
+			t.Cleanup(func() { providers.Unregister("fakes3") })
+			credentials := fmt.Sprintf(`{"region": "fake", "endpoint": %q, "s3_force_path_style": true, "access_key": "access", "secret_key": "secret"}`, server.URL)
+			o := &opener{
+				credentials:   map[string][]byte{"fakes3": []byte(credentials)},
+				cachedBuckets: map[string]*blob.Bucket{},
+			}
+			return o, "fakes3://bucket", server.Client()
+		},
+	},
+	{
+		name: "registered provider",
+		setup: func(t *testing.T) (Opener, string, *http.Client) {
+			dir := t.TempDir()
+			server := httptest.NewUnstartedServer(nil)
+			signer := fileblob.NewURLSignerHMAC(&url.URL{Scheme: "http", Host: server.Listener.Addr().String(), Path: "/signed"}, []byte("secret"))
+			providers.Register("local", providers.Provider{
+				DisplayName: "Local",
+				OpenBucket: func(ctx context.Context, _ []byte, bucket string) (*blob.Bucket, error) {
+					return fileblob.OpenBucket(filepath.Join(dir, bucket), &fileblob.Options{URLSigner: signer, CreateDir: true})
+				},
+			})
+			t.Cleanup(func() { providers.Unregister("local") })
+			o := &opener{cachedBuckets: map[string]*blob.Bucket{}}
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				key, err := signer.KeyFromURL(r.Context(), r.URL)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusForbidden)
+					return
+				}
+				content, err := ReadContent(r.Context(), logrus.NewEntry(logrus.StandardLogger()), o, "local://bucket/"+key)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusNotFound)
+					return
+				}
+				w.Write(content)
+			})
+			server.Start()
+			t.Cleanup(server.Close)
+			return o, "local://bucket", server.Client()
+		},
+	},
+}
+
+func TestOpenerConformance(t *testing.T) {
+	for _, provider := range conformanceProviders {
+		t.Run(provider.name, func(t *testing.T) {
+			ctx := context.Background()
+			o, bucket, client := provider.setup(t)
+			path := func(name string) string { return bucket + "/" + name }
+
+			contentType := "text/plain"
+			for name, content := range map[string]string{"logs/build-log.txt": "hello world", "logs/artifacts/junit.xml": "<testsuites/>", "finished.json": "{}"} {
+				w, err := o.Writer(ctx, path(name), WriterOptions{ContentType: &contentType, Metadata: map[string]string{"origin": "prow"}})
+				if err != nil {
+					t.Fatalf("Writer(%s): %v", name, err)
+				}
+				if _, err := w.Write([]byte(content)); err != nil {
+					t.Fatalf("Write(%s): %v", name, err)
+				}
+				if err := w.Close(); err != nil {
+					t.Fatalf("Close(%s): %v", name, err)
+				}
+			}
+
+			t.Run("Reader", func(t *testing.T) {
+				r, err := o.Reader(ctx, path("logs/build-log.txt"))
+				if err != nil {
+					t.Fatalf("Reader: %v", err)
+				}
+				defer r.Close()
+				content, err := io.ReadAll(r)
+				if err != nil {
+					t.Fatalf("ReadAll: %v", err)
+				}
+				if diff := cmp.Diff("hello world", string(content)); diff != "" {
+					t.Errorf("content differs (-want +got):\n%s", diff)
+				}
+				if _, err := o.Reader(ctx, path("logs/missing.txt")); !IsNotExist(err) {
+					t.Errorf("expected a not found error for a missing object, got %v", err)
+				}
+			})
+
+			t.Run("RangeReader", func(t *testing.T) {
+				r, err := o.RangeReader(ctx, path("logs/build-log.txt"), 6, 5)
+				if err != nil {
+					t.Fatalf("RangeReader: %v", err)
+				}
+				defer r.Close()
+				content, err := io.ReadAll(r)
+				if err != nil {
+					t.Fatalf("ReadAll: %v", err)
+				}
+				if diff := cmp.Diff("world", string(content)); diff != "" {
+					t.Errorf("content differs (-want +got):\n%s", diff)
+				}
+			})
+
+			t.Run("Attributes and UpdateAttributes", func(t *testing.T) {
+				attrs, err := o.Attributes(ctx, path("logs/build-log.txt"))
+				if err != nil {
+					t.Fatalf("Attributes: %v", err)
+				}
+				if attrs.Size != int64(len("hello world")) {
+					t.Errorf("expected size %d, got %d", len("hello world"), attrs.Size)
+				}
+				if !strings.HasPrefix(attrs.ContentType, contentType) {
+					t.Errorf("expected content type %q, got %q", contentType, attrs.ContentType)
+				}
+				if diff := cmp.Diff(map[string]string{"origin": "prow"}, attrs.Metadata); diff != "" {
+					t.Errorf("metadata differs (-want +got):\n%s", diff)
+				}
+
+				if _, err := o.UpdateAttributes(ctx, path("logs/build-log.txt"), ObjectAttrsToUpdate{Metadata: map[string]string{"state": "done"}}); err != nil {
+					t.Fatalf("UpdateAttributes: %v", err)
+				}
+				attrs, err = o.Attributes(ctx, path("logs/build-log.txt"))
+				if err != nil {
+					t.Fatalf("Attributes: %v", err)
+				}
+				if diff := cmp.Diff(map[string]string{"origin": "prow", "state": "done"}, attrs.Metadata); diff != "" {
+					t.Errorf("updated metadata differs (-want +got):\n%s", diff)
+				}
+				content, err := ReadContent(ctx, logrus.NewEntry(logrus.StandardLogger()), o, path("logs/build-log.txt"))
+				if err != nil {
+					t.Fatalf("ReadContent: %v", err)
+				}
+				if diff := cmp.Diff("hello world", string(content)); diff != "" {
+					t.Errorf("content changed by the update (-want +got):\n%s", diff)
+				}
+			})
+
+			t.Run("Iterator", func(t *testing.T) {
+				it, err := o.Iterator(ctx, path("logs/"), "/")
+				if err != nil {
+					t.Fatalf("Iterator: %v", err)
+				}
+				var got []ObjectAttributes
+				for {
+					attrs, err := it.Next(ctx)
+					if errors.Is(err, io.EOF) {
+						break
+					}
+					if err != nil {
+						t.Fatalf("Next: %v", err)
+					}
+					got = append(got, ObjectAttributes{Name: attrs.Name, IsDir: attrs.IsDir})
+				}
+				sort.Slice(got, func(i, j int) bool { return got[i].Name < got[j].Name })
+				want := []ObjectAttributes{
+					{Name: "logs/artifacts/", IsDir: true},
+					{Name: "logs/build-log.txt"},
+				}
+				if diff := cmp.Diff(want, got); diff != "" {
+					t.Errorf("listed objects differ (-want +got):\n%s", diff)
+				}
+			})
+
+			t.Run("Delete", func(t *testing.T) {
+				if err := o.Delete(ctx, path("finished.json")); err != nil {
+					t.Fatalf("Delete: %v", err)
+				}
+				if _, err := o.Attributes(ctx, path("finished.json")); !IsNotExist(err) {
+					t.Errorf("expected a not found error for a deleted object, got %v", err)
+				}
+				if err := o.Delete(ctx, path("finished.json")); !IsNotExist(err) {
+					t.Errorf("expected a not found error when deleting a missing object, got %v", err)
+				}
+			})
+
+			t.Run("SignedURL", func(t *testing.T) {
+				signedURL, err := o.SignedURL(ctx, path("logs/build-log.txt"), SignedURLOptions{})
+				if err != nil {
+					t.Fatalf("SignedURL: %v", err)
+				}
+				resp, err := client.Get(signedURL)
+				if err != nil {
+					t.Fatalf("Get(%s): %v", signedURL, err)
+				}
+				defer resp.Body.Close()
+				content, err := io.ReadAll(resp.Body)
+				if err != nil {
+					t.Fatalf("ReadAll: %v", err)
+				}
+				if resp.StatusCode != http.StatusOK {
+					t.Fatalf("Get(%s) returned %d: %s", signedURL, resp.StatusCode, content)
+				}
+				if diff := cmp.Diff("hello world", string(content)); diff != "" {
+					t.Errorf("content differs (-want +got):\n%s", diff)
+				}
+			})
+		})
+	}
+}
+
+func TestUpdateAttributesCopiesS3Objects(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeS3("bucket")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	credentials := fmt.Sprintf(`{"region": "fake", "endpoint": %q, "s3_force_path_style": true, "access_key": "access", "secret_key": "secret"}`, server.URL)
+	o := &opener{
+		credentials:   map[string][]byte{providers.S3: []byte(credentials)},
+		cachedBuckets: map[string]*blob.Bucket{},
+	}
+
+	if err := WriteContent(ctx, logrus.NewEntry(logrus.StandardLogger()), o, "s3://bucket/logs/build-log.txt", []byte("hello world")); err != nil {
+		t.Fatalf("WriteContent: %v", err)
+	}
+	encoding := "gzip"
+	attrs, err := o.UpdateAttributes(ctx, "s3://bucket/logs/build-log.txt", ObjectAttrsToUpdate{ContentEncoding: &encoding, Metadata: map[string]string{"state": "done"}})
+	if err != nil {
+		t.Fatalf("UpdateAttributes: %v", err)
+	}
+	if diff := cmp.Diff(&Attributes{ContentEncoding: "gzip", Size: int64(len("hello world")), Metadata: map[string]string{"state": "done"}}, attrs); diff != "" {
+		t.Errorf("attributes differ (-want +got):\n%s", diff)
+	}
+	if fake.uploads != 1 || fake.copies != 1 {
+		t.Errorf("expected the object to be uploaded once and copied once, got %d uploads and %d copies", fake.uploads, fake.copies)
+	}
+	obj := fake.objects["logs/build-log.txt"]
+	if diff := cmp.Diff("hello world", string(obj.content)); diff != "" {
+		t.Errorf("content differs (-want +got):\n%s", diff)
+	}
+	if got := obj.header.Get("Content-Encoding"); got != "gzip" {
+		t.Errorf("expected the gzip content encoding, got %q", got)
+	}
+}
+
+// fakeS3 is an in-memory S3 serving a single bucket with path-style addressing,
+// i.e. at /<bucket>/<key>. It doesn't check the signatures of the requests.
+type fakeS3 struct {
+	bucket  string
+	lock    sync.Mutex
+	objects map[string]fakeS3Object
+	// uploads and copies count the objects written by uploading their content
+	// and by copying another object.
+	uploads, copies int
+}
+
+type fakeS3Object struct {
+	content  []byte
+	header   http.Header
+	modified time.Time
+}
+
+func newFakeS3(bucket string) *fakeS3 {
+	return &fakeS3{bucket: bucket, objects: map[string]fakeS3Object{}}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != f.bucket {
+		f.error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		f.list(w, r.URL.Query().Get("prefix"), r.URL.Query().Get("delimiter"))
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		source, err := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+		if err != nil {
+			f.error(w, http.StatusBadRequest, "InvalidArgument")
+			return
+		}
+		sourceBucket, sourceKey, _ := strings.Cut(strings.TrimPrefix(source, "/"), "/")
+		obj, ok := f.objects[sourceKey]
+		if sourceBucket != f.bucket || !ok {
+			f.error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		if r.Header.Get("X-Amz-Metadata-Directive") == "REPLACE" {
+			obj.header = http.Header{}
+			for name, values := range r.Header {
+				if strings.HasPrefix(name, "X-Amz-Meta-") || name == "Content-Type" || name == "Content-Encoding" || name == "Cache-Control" {
+					obj.header[name] = values
+				}
+			}
+		}
+		obj.modified = time.Now()
+		f.objects[key] = obj
+		f.copies++
+		w.Header().Set("Content-Type", "application/xml")
+		fmt.Fprintf(w, "<CopyObjectResult><ETag>\"etag\"</ETag><LastModified>%s</LastModified></CopyObjectResult>", obj.modified.UTC().Format(time.RFC3339))
+	case r.Method == http.MethodPut:
+		content, err := io.ReadAll(r.Body)
+		if err != nil {
+			f.error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		f.uploads++
+		header := http.Header{}
+		for name, values := range r.Header {
+			if strings.HasPrefix(name, "X-Amz-Meta-") || name == "Content-Type" || name == "Content-Encoding" || name == "Cache-Control" {
+				header[name] = values
+			}
+		}
+		f.objects[key] = fakeS3Object{content: content, header: header, modified: time.Now()}
+		w.Header().Set("ETag", `"`+strconv.Itoa(len(f.objects))+`"`)
+	case r.Method == http.MethodDelete:
+		// Like S3, deleting a missing object succeeds.
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		obj, ok := f.objects[key]
+		if !ok {
+			f.error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		for name, values := range obj.header {
+			w.Header()[name] = values
+		}
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Last-Modified", obj.modified.UTC().Format(http.TimeFormat))
+		content, status := obj.content, http.StatusOK
+		if rng := r.Header.Get("Range"); rng != "" {
+			var start, end int
+			if _, err := fmt.Sscanf(rng, "bytes=%d-%d", &start, &end); err != nil || end >= len(content) {
+				end = len(content) - 1
+			}
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(content)))
+			content, status = content[start:end+1], http.StatusPartialContent
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.WriteHeader(status)
+		if r.Method == http.MethodGet {
+			w.Write(content)
+		}
+	default:
+		f.error(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, prefix, delimiter string) {
+	type object struct {
+		Key          string
+		LastModified string
+		ETag         string
+		Size         int
+	}
+	type commonPrefix struct {
+		Prefix string
+	}
+	result := struct {
+		XMLName        xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+		Name           string
+		Prefix         string
+		KeyCount       int
+		MaxKeys        int
+		IsTruncated    bool
+		Contents       []object
+		CommonPrefixes []commonPrefix
+	}{Name: f.bucket, Prefix: prefix, MaxKeys: 1000}
+
+	var keys []string
+	for key := range f.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	seen := map[string]bool{}
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if i := strings.Index(key[len(prefix):], delimiter); delimiter != "" && i >= 0 {
+			dir := key[:len(prefix)+i+len(delimiter)]
+			if !seen[dir] {
+				seen[dir] = true
+				result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: dir})
+			}
+			continue
+		}
+		obj := f.objects[key]
+		result.Contents = append(result.Contents, object{Key: key, LastModified: obj.modified.UTC().Format(time.RFC3339), ETag: `"etag"`, Size: len(obj.content)})
+	}
+	result.KeyCount = len(result.Contents) + len(result.CommonPrefixes)
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(result)
+}
+
+func (f *fakeS3) error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
@@ -33,6 +33,9 @@ import (
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 
 	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/sirupsen/logrus"
 	"gocloud.dev/blob"
 	"gocloud.dev/gcerrors"
@@ -93,12 +96,13 @@ type Opener interface {
 	SignedURL(ctx context.Context, path string, opts SignedURLOptions) (string, error)
 	Iterator(ctx context.Context, prefix, delimiter string) (ObjectIterator, error)
 	UpdateAttributes(context.Context, string, ObjectAttrsToUpdate) (*Attributes, error)
+	Delete(ctx context.Context, path string) error
 }
 
 type opener struct {
 	gcsCredentialsFile string
 	gcsClient          storageClient
-	s3Credentials      []byte
+	credentials        map[string][]byte
 	cachedBuckets      map[string]*blob.Bucket
 	cachedBucketsMutex sync.Mutex
 }
@@ -109,21 +113,32 @@ type opener struct {
 // In all other cases gocloud auto-discovery is used to detect credentials, if credentialsFile is empty.
 // For more details about the possible content of the credentialsFile see prow/io/providers.GetBucket
 func NewOpener(ctx context.Context, gcsCredentialsFile, s3CredentialsFile string) (Opener, error) {
+	credentialsFiles := map[string]string{}
+	if s3CredentialsFile != "" {
+		credentialsFiles[providers.S3] = s3CredentialsFile
+	}
+	return NewOpenerWithCredentials(ctx, gcsCredentialsFile, credentialsFiles)
+}
+
+// NewOpenerWithCredentials returns an opener that can read GCS, local paths and
+// the paths of all registered providers. The credentials of the providers
+// other than GCS are read from the files keyed by their scheme, the providers
+// without a file use their own defaults.
+func NewOpenerWithCredentials(ctx context.Context, gcsCredentialsFile string, credentialsFiles map[string]string) (Opener, error) {
 	gcsClient, err := createGCSClient(ctx, gcsCredentialsFile)
 	if err != nil {
 		return nil, err
 	}
-	var s3Credentials []byte
-	if s3CredentialsFile != "" {
-		s3Credentials, err = os.ReadFile(s3CredentialsFile)
-		if err != nil {
+	credentials := map[string][]byte{}
+	for scheme, file := range credentialsFiles {
+		if credentials[scheme], err = os.ReadFile(file); err != nil {
 			return nil, err
 		}
 	}
 	return &opener{
 		gcsClient:          gcsClient,
 		gcsCredentialsFile: gcsCredentialsFile,
-		s3Credentials:      s3Credentials,
+		credentials:        credentials,
 		cachedBuckets:      map[string]*blob.Bucket{},
 	}, nil
 }
@@ -200,24 +215,25 @@ func (o *opener) openGCS(path string) (*storage.ObjectHandle, error) {
 
 // getBucket opens a bucket
 // The storageProvider is discovered based on the given path.
-// The buckets are cached per bucket name. So we don't open a bucket multiple times in the same process
+// The buckets are cached per storageProvider and bucket name. So we don't open a bucket multiple times in the same process
 func (o *opener) getBucket(ctx context.Context, path string) (*blob.Bucket, string, error) {
-	_, bucketName, relativePath, err := providers.ParseStoragePath(path)
+	storageProvider, bucketName, relativePath, err := providers.ParseStoragePath(path)
 	if err != nil {
 		return nil, "", fmt.Errorf("could not get bucket: %w", err)
 	}
+	key := storageProvider + "://" + bucketName
 
 	o.cachedBucketsMutex.Lock()
 	defer o.cachedBucketsMutex.Unlock()
-	if bucket, ok := o.cachedBuckets[bucketName]; ok {
+	if bucket, ok := o.cachedBuckets[key]; ok {
 		return bucket, relativePath, nil
 	}
 
-	bucket, err := providers.GetBucket(ctx, o.s3Credentials, path)
+	bucket, err := providers.GetBucket(ctx, o.credentials[storageProvider], path)
 	if err != nil {
 		return nil, "", err
 	}
-	o.cachedBuckets[bucketName] = bucket
+	o.cachedBuckets[key] = bucket
 	return bucket, relativePath, nil
 }
 
@@ -367,7 +383,7 @@ func (o *opener) Attributes(ctx context.Context, path string) (Attributes, error
 
 func (o *opener) UpdateAttributes(ctx context.Context, path string, attrs ObjectAttrsToUpdate) (*Attributes, error) {
 	if !strings.HasPrefix(path, providers.GS+"://") {
-		return nil, fmt.Errorf("unsupported provider: %q", path)
+		return o.updateBucketAttributes(ctx, path, attrs)
 	}
 
 	g, err := o.openGCS(path)
@@ -391,6 +407,115 @@ func (o *opener) UpdateAttributes(ctx context.Context, path string, attrs Object
 	}, nil
 }
 
+// Delete deletes the object at the path, returning an IsNotExist() error when missing
+func (o *opener) Delete(ctx context.Context, path string) error {
+	if strings.HasPrefix(path, providers.GS+"://") {
+		g, err := o.openGCS(path)
+		if err != nil {
+			return fmt.Errorf("bad gcs path: %w", err)
+		}
+		return g.Delete(ctx)
+	}
+	if strings.HasPrefix(path, "/") {
+		return os.Remove(path)
+	}
+
+	bucket, relativePath, err := o.getBucket(ctx, path)
+	if err != nil {
+		return err
+	}
+	return bucket.Delete(ctx, relativePath)
+}
+
+// updateBucketAttributes updates the attributes of an object of a gocloud
+// bucket. The metadata is merged like GCS does: the keys of attrs.Metadata are
+// updated and an empty map removes all.
+// gocloud can't update the attributes of an object in place, so the objects of
+// S3 and the S3-compatible stores are copied onto themselves with the updated
+// attributes, and the objects of the other providers are written again.
+func (o *opener) updateBucketAttributes(ctx context.Context, path string, attrs ObjectAttrsToUpdate) (*Attributes, error) {
+	bucket, relativePath, err := o.getBucket(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	current, err := bucket.Attributes(ctx, relativePath)
+	if err != nil {
+		return nil, fmt.Errorf("attributes: %w", err)
+	}
+
+	wOpts := &blob.WriterOptions{
+		ContentType:        current.ContentType,
+		ContentEncoding:    current.ContentEncoding,
+		ContentDisposition: current.ContentDisposition,
+		ContentLanguage:    current.ContentLanguage,
+		CacheControl:       current.CacheControl,
+		Metadata:           current.Metadata,
+	}
+	if attrs.ContentEncoding != nil {
+		wOpts.ContentEncoding = *attrs.ContentEncoding
+	}
+	if attrs.Metadata != nil {
+		metadata := map[string]string{}
+		if len(attrs.Metadata) > 0 {
+			for k, v := range current.Metadata {
+				metadata[k] = v
+			}
+			for k, v := range attrs.Metadata {
+				metadata[k] = v
+			}
+		}
+		wOpts.Metadata = metadata
+	}
+
+	var s3Client *s3.Client
+	if bucket.As(&s3Client) {
+		_, bucketName, _, err := providers.ParseStoragePath(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := copyS3Object(ctx, s3Client, bucketName, relativePath, wOpts); err != nil {
+			return nil, fmt.Errorf("copy: %w", err)
+		}
+	} else {
+		content, err := bucket.ReadAll(ctx, relativePath)
+		if err != nil {
+			return nil, fmt.Errorf("read: %w", err)
+		}
+		if err := bucket.WriteAll(ctx, relativePath, content, wOpts); err != nil {
+			return nil, fmt.Errorf("write: %w", err)
+		}
+	}
+	return &Attributes{
+		ContentEncoding: wOpts.ContentEncoding,
+		Size:            current.Size,
+		Metadata:        wOpts.Metadata,
+	}, nil
+}
+
+// copyS3Object copies the object onto itself, replacing its attributes by the
+// ones of wOpts.
+func copyS3Object(ctx context.Context, client *s3.Client, bucket, key string, wOpts *blob.WriterOptions) error {
+	optional := func(s string) *string {
+		if s == "" {
+			return nil
+		}
+		return aws.String(s)
+	}
+	_, err := client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:             aws.String(bucket),
+		Key:                aws.String(key),
+		CopySource:         aws.String(url.PathEscape(bucket + "/" + key)),
+		MetadataDirective:  s3types.MetadataDirectiveReplace,
+		Metadata:           wOpts.Metadata,
+		ContentType:        optional(wOpts.ContentType),
+		ContentEncoding:    optional(wOpts.ContentEncoding),
+		ContentDisposition: optional(wOpts.ContentDisposition),
+		ContentLanguage:    optional(wOpts.ContentLanguage),
+		CacheControl:       optional(wOpts.CacheControl),
+	})
+	return err
+}
+
 const (
 	GSAnonHost   = "storage.googleapis.com"
 	GSCookieHost = "storage.cloud.google.com"
@@ -478,7 +603,7 @@ func (o *opener) Iterator(ctx context.Context, prefix, delimiter string) (Object
 		}
 		if delimiter == "" {
 			// query.SetAttrSelection cannot be used in directory-like mode (when delimiter != "").
-			if err := query.SetAttrSelection([]string{"Name"}); err != nil {
+			if err := query.SetAttrSelection([]string{"Name", "Size"}); err != nil {
 				return nil, err
 			}
 		}
@@ -536,6 +661,12 @@ func WriteContent(ctx context.Context, logger *logrus.Entry, opener Opener, path
 	return utilerrors.NewAggregate([]error{writeErr, closeErr})
 }
 
+// IsPreconditionFailed returns whether the write failed because the object
+// already exists while PreconditionDoesNotExist was set.
+func IsPreconditionFailed(err error) bool {
+	return err != nil && (!isErrUnexpected(err) || errors.Is(err, os.ErrExist))
+}
+
 func isErrUnexpected(err error) bool {
 	if err == nil {
 		return false
@@ -30,6 +30,9 @@ import (
 const (
 	S3 = "s3"
 	GS = "gs"
+	// Azure paths are opened by the gocloud URL opener of Azure Blob, which
+	// gets its credentials from the AZURE_STORAGE_* environment variables.
+	Azure = "azblob"
 	// TODO(danilo-gemoli): complete the implementation since at this time only opener.Writer()
 	// is supported
 	File = "file"
@@ -37,13 +40,8 @@ const (
 
 // DisplayName turns canonical provider IDs into displayable names.
 func DisplayName(provider string) string {
-	switch provider {
-	case GS:
-		return "GCS"
-	case S3:
-		return "S3"
-	case File:
-		return "File"
+	if p, ok := Lookup(provider); ok && p.DisplayName != "" {
+		return p.DisplayName
 	}
 	return provider
 }
@@ -72,15 +70,22 @@ func DisplayName(provider string) string {
 //     "access_key": "access_key",
 //     "secret_key": "secret_key"
 //     }
-func GetBucket(ctx context.Context, S3Credentials []byte, path string) (*blob.Bucket, error) {
+//
+// Paths of the providers added with Register are opened by their OpenBucket
+// function, which gets the credentials as is.
+func GetBucket(ctx context.Context, credentials []byte, path string) (*blob.Bucket, error) {
 	storageProvider, bucket, _, err := ParseStoragePath(path)
 	if err != nil {
 		return nil, err
 	}
-	if storageProvider == S3 && len(S3Credentials) > 0 {
-		return getS3Bucket(ctx, S3Credentials, bucket)
+	if p, ok := Lookup(storageProvider); ok && p.OpenBucket != nil {
+		return p.OpenBucket(ctx, credentials, bucket)
 	}
+	return openBucketURL(ctx, storageProvider, bucket)
+}
 
+// openBucketURL opens the bucket with the gocloud URL opener of the storageProvider.
+func openBucketURL(ctx context.Context, storageProvider, bucket string) (*blob.Bucket, error) {
 	bkt, err := blob.OpenBucket(ctx, fmt.Sprintf("%s://%s", storageProvider, bucket))
 	if err != nil {
 		return nil, fmt.Errorf("error opening file bucket: %w", err)
@@ -106,12 +111,17 @@ type S3Credentials struct {
 // * gs/kubernetes-jenkins returns true
 // * kubernetes-jenkins returns false
 func HasStorageProviderPrefix(path string) bool {
-	return strings.HasPrefix(path, GS+"/") || strings.HasPrefix(path, S3+"/")
+	for _, scheme := range Schemes() {
+		if scheme != File && strings.HasPrefix(path, scheme+"/") {
+			return true
+		}
+	}
+	return false
 }
 
 // ParseStoragePath parses storagePath and returns the storageProvider, bucket and relativePath
 // For example gs://prow-artifacts/test.log results in (gs, prow-artifacts, test.log)
-// Currently detected storageProviders are GS, S3 and file.
+// Currently detected storageProviders are GS, S3, Azure, file and the providers added with Register.
 // Paths with a leading / instead of a storageProvider prefix are treated as file paths for backwards
 // compatibility reasons.
 // File paths are split into a directory and a file. Directory is returned as bucket, file is returned.
@@ -17,8 +17,12 @@ limitations under the License.
 package providers_test
 
 import (
+	"context"
+	"encoding/base64"
 	"testing"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+
 	"sigs.k8s.io/prow/pkg/io/providers"
 )
 
@@ -183,3 +187,68 @@ func TestStoragePath(t *testing.T) {
 		})
 	}
 }
+
+func TestRegister(t *testing.T) {
+	providers.Register("minio", providers.S3Compatible("MinIO"))
+	defer providers.Unregister("minio")
+
+	tests := []struct {
+		name              string
+		provider          string
+		wantDisplayName   string
+		wantPrefixInPaths bool
+	}{
+		{
+			name:              "built-in provider",
+			provider:          providers.GS,
+			wantDisplayName:   "GCS",
+			wantPrefixInPaths: true,
+		},
+		{
+			name:              "registered provider",
+			provider:          "minio",
+			wantDisplayName:   "MinIO",
+			wantPrefixInPaths: true,
+		},
+		{
+			name:              "azure",
+			provider:          providers.Azure,
+			wantDisplayName:   "Azure Blob",
+			wantPrefixInPaths: true,
+		},
+		{
+			name:              "unknown provider",
+			provider:          "s4",
+			wantDisplayName:   "s4",
+			wantPrefixInPaths: false,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := providers.DisplayName(tc.provider); got != tc.wantDisplayName {
+				t.Errorf("DisplayName() = %q, want %q", got, tc.wantDisplayName)
+			}
+			if got := providers.HasStorageProviderPrefix(tc.provider + "/bucket"); got != tc.wantPrefixInPaths {
+				t.Errorf("HasStorageProviderPrefix() = %v, want %v", got, tc.wantPrefixInPaths)
+			}
+		})
+	}
+}
+
+func TestGetBucketAzure(t *testing.T) {
+	t.Setenv("AZURE_STORAGE_ACCOUNT", "account")
+	t.Setenv("AZURE_STORAGE_KEY", base64.StdEncoding.EncodeToString([]byte("key")))
+
+	bucket, err := providers.GetBucket(context.Background(), nil, "azblob://container/logs/build-log.txt")
+	if err != nil {
+		t.Fatalf("GetBucket: %v", err)
+	}
+	defer bucket.Close()
+	var client *container.Client
+	if !bucket.As(&client) {
+		t.Fatal("expected an Azure Blob container")
+	}
+	if want := "https://account.blob.core.windows.net/container"; client.URL() != want {
+		t.Errorf("expected the container %s, got %s", want, client.URL())
+	}
+}
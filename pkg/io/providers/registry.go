@@ -0,0 +1,100 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"gocloud.dev/blob"
+	_ "gocloud.dev/blob/azureblob"
+)
+
+// Provider is a storage provider artifacts can be stored in. Its paths are
+// prefixed with the scheme it is registered under, e.g. s3://bucket/path.
+type Provider struct {
+	// DisplayName is the name of the provider shown to users, e.g. "S3".
+	DisplayName string
+	// OpenBucket opens a bucket of the provider. The credentials are the
+	// content of the credentials file configured for the provider, if any.
+	// If unset, the bucket is opened by the gocloud URL opener registered for
+	// the scheme, e.g. by importing gocloud.dev/blob/azureblob for azblob://.
+	OpenBucket func(ctx context.Context, credentials []byte, bucket string) (*blob.Bucket, error)
+}
+
+var (
+	registryLock sync.RWMutex
+	registry     = map[string]Provider{}
+)
+
+func init() {
+	Register(GS, Provider{DisplayName: "GCS"})
+	Register(S3, S3Compatible("S3"))
+	Register(Azure, Provider{DisplayName: "Azure Blob"})
+	Register(File, Provider{DisplayName: "File"})
+}
+
+// Register makes the provider available for the paths with the scheme,
+// replacing the provider previously registered for it, if any.
+func Register(scheme string, provider Provider) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	registry[scheme] = provider
+}
+
+// Unregister removes the provider registered for the scheme, if any.
+func Unregister(scheme string) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	delete(registry, scheme)
+}
+
+// Lookup returns the provider registered for the scheme.
+func Lookup(scheme string) (Provider, bool) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	p, ok := registry[scheme]
+	return p, ok
+}
+
+// Schemes returns the sorted schemes of the registered providers.
+func Schemes() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	schemes := make([]string, 0, len(registry))
+	for scheme := range registry {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// S3Compatible returns a provider of an S3-compatible store, e.g. MinIO or
+// Ceph, configured by credentials in the format of S3Credentials. Without
+// credentials, the buckets are opened with the AWS defaults.
+func S3Compatible(displayName string) Provider {
+	return Provider{
+		DisplayName: displayName,
+		OpenBucket: func(ctx context.Context, credentials []byte, bucket string) (*blob.Bucket, error) {
+			if len(credentials) == 0 {
+				return openBucketURL(ctx, S3, bucket)
+			}
+			return getS3Bucket(ctx, credentials, bucket)
+		},
+	}
+}
@@ -187,6 +187,24 @@ func (f *fghc) EditCommentWithContext(_ context.Context, org, repo string, ID in
 	return nil
 }
 
+func (f *fghc) ListCheckRuns(org, repo, ref string) (*github.CheckRunList, error) {
+	f.Lock()
+	defer f.Unlock()
+	return &github.CheckRunList{}, nil
+}
+
+func (f *fghc) CreateCheckRun(org, repo string, checkRun github.CheckRun) (int64, error) {
+	f.Lock()
+	defer f.Unlock()
+	return 0, nil
+}
+
+func (f *fghc) UpdateCheckRun(org, repo string, checkRunId int64, checkRun github.CheckRun) error {
+	f.Lock()
+	defer f.Unlock()
+	return nil
+}
+
 func TestSyncTriggeredJobs(t *testing.T) {
 	fakeClock := clocktesting.NewFakeClock(time.Now().Truncate(1 * time.Second))
 
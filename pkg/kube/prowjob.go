@@ -26,6 +26,9 @@ const (
 	// CreatedByTideLabel is added by tide when it triggered a job.
 	// TODO: Namespace this label.
 	CreatedByTideLabel = "created-by-tide"
+	// TideBisectionLabel is added by tide on the batch jobs it triggered to
+	// bisect a failed batch.
+	TideBisectionLabel = "prow.k8s.io/tide-bisection"
 	// ProwJobTypeLabel is added in resources created by prow and
 	// carries the job type (presubmit, postsubmit, periodic, batch)
 	// that the pod is running.
@@ -72,6 +75,9 @@ const (
 	// ReRunLabel is added in periodics that are configured to be
 	// re-runned several times, value starts from 0 and it's increased on re-run
 	ReRunLabel = "prow.k8s.io/re-run"
+	// SchedulingDecisionAnnotation is added to ProwJobs by the scheduler and
+	// carries the reason why the ProwJob was assigned to its cluster.
+	SchedulingDecisionAnnotation = "prow.k8s.io/scheduling-decision"
 
 	// Gerrit related labels that are used by Prow
 
@@ -0,0 +1,122 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loganalysis
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+const (
+	// SignatureMetadataKey is the key of the normalized failure signature in
+	// the metadata of finished.json.
+	SignatureMetadataKey = "failure-signature"
+	// SignatureIDMetadataKey is the key of the ID of the failure signature in
+	// the metadata of finished.json.
+	SignatureIDMetadataKey = "failure-signature-id"
+
+	// maxSignatureLength bounds the length of the normalized text.
+	maxSignatureLength = 512
+)
+
+// Signature identifies a failure regardless of the run it happened in.
+type Signature struct {
+	// Text is the normalized line of the first error.
+	Text string `json:"text"`
+	// ID is a hash of the text, suitable for searching across jobs.
+	ID string `json:"id"`
+}
+
+// The Kubernetes random suffixes don't use vowels nor 0, 1 and 3.
+const podSuffixChars = `[bcdfghjklmnpqrstvwxz2456789]`
+
+// normalizers replace the parts of a line that vary from one run to another,
+// in order.
+var normalizers = []struct {
+	re   *regexp.Regexp
+	repl string
+	// keep returns whether a match is kept as is rather than replaced.
+	keep func(match string) bool
+}{
+	// JSON logs of the entrypoint, only keep the message.
+	{re: regexp.MustCompile(`^\{.*"msg":"([^"]*)".*\}$`), repl: "$1"},
+	// klog headers, e.g. "E0102 15:04:05.123456    1234 file.go:12]".
+	{re: regexp.MustCompile(`\b[IWEF]\d{4} \d{2}:\d{2}:\d{2}\.\d+\s+\d+ `), repl: "<time> "},
+	{re: regexp.MustCompile(`\b\d{4}[-/]\d{2}[-/]\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?`), repl: "<time>"},
+	{re: regexp.MustCompile(`\b\d{2}:\d{2}:\d{2}(?:\.\d+)?\b`), repl: "<time>"},
+	{re: regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`), repl: "<uuid>"},
+	// Pods of deployments and jobs, or of any other controller.
+	{re: regexp.MustCompile(`\b([a-z0-9][-a-z0-9]*?)-` + podSuffixChars + `{8,10}-` + podSuffixChars + `{5}\b`), repl: "$1-<pod>"},
+	{re: regexp.MustCompile(`\b([a-z0-9][-a-z0-9]*?)-` + podSuffixChars + `{5}\b`), repl: "$1-<pod>"},
+	{re: regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}(?::\d+)?\b`), repl: "<ip>"},
+	{re: regexp.MustCompile(`\b0x[0-9a-fA-F]+\b`), repl: "<addr>"},
+	// Hashes, but not the words only made of the letters a to f, e.g. "deadbeef" or "defaced".
+	{re: regexp.MustCompile(`\b[0-9a-f]{7,64}\b`), repl: "<hash>", keep: func(match string) bool { return !strings.ContainsAny(match, "0123456789") }},
+	{re: regexp.MustCompile(`\b(?:\d+(?:\.\d+)?(?:ns|µs|us|ms|s|m|h))+\b`), repl: "<duration>"},
+	{re: regexp.MustCompile(`/tmp/[^\s/:]+`), repl: "/tmp/<tmp>"},
+	{re: regexp.MustCompile(`\s+`), repl: " "},
+}
+
+// Normalize strips the parts of the line that vary from one run to another:
+// timestamps, durations, pod names, addresses and hashes.
+func Normalize(line string) string {
+	for _, n := range normalizers {
+		if n.keep == nil {
+			line = n.re.ReplaceAllString(line, n.repl)
+			continue
+		}
+		line = n.re.ReplaceAllStringFunc(line, func(match string) string {
+			if n.keep(match) {
+				return match
+			}
+			return n.repl
+		})
+	}
+	line = strings.TrimSpace(line)
+	if len(line) > maxSignatureLength {
+		line = line[:maxSignatureLength]
+	}
+	return line
+}
+
+// NewSignature returns the signature of the error line.
+func NewSignature(line string) *Signature {
+	text := Normalize(line)
+	sum := sha256.Sum256([]byte(text))
+	return &Signature{Text: text, ID: hex.EncodeToString(sum[:])[:16]}
+}
+
+// Metadata returns the signature as finished.json metadata.
+func (s *Signature) Metadata() map[string]interface{} {
+	return map[string]interface{}{
+		SignatureMetadataKey:   s.Text,
+		SignatureIDMetadataKey: s.ID,
+	}
+}
+
+// SignatureFromMetadata returns the signature stored in finished.json
+// metadata, if any.
+func SignatureFromMetadata(metadata map[string]interface{}) *Signature {
+	text, _ := metadata[SignatureMetadataKey].(string)
+	id, _ := metadata[SignatureIDMetadataKey].(string)
+	if text == "" || id == "" {
+		return nil
+	}
+	return &Signature{Text: text, ID: id}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loganalysis
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNormalize(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		line string
+		want string
+	}{
+		{
+			name: "klog header, pod and duration",
+			line: "E0102 15:04:05.123456    1234 controller.go:12] failed to sync pod default/web-7d9f8b6c5d-x2k9z: timeout after 30s",
+			want: "<time> controller.go:12] failed to sync pod default/web-<pod>: timeout after <duration>",
+		},
+		{
+			name: "RFC3339 timestamp and address",
+			line: "2024-01-02T15:04:05.123Z ERROR: connecting to 10.0.0.12:8080 failed",
+			want: "<time> ERROR: connecting to <ip> failed",
+		},
+		{
+			name: "pointers",
+			line: "panic: runtime error [signal SIGSEGV: code=0x1 addr=0x0 pc=0x4a5b6c]",
+			want: "panic: runtime error [signal SIGSEGV: code=<addr> addr=<addr> pc=<addr>]",
+		},
+		{
+			name: "temporary directory",
+			line: "error: open /tmp/tmp.Xy12ab/config.yaml: no such file or directory",
+			want: "error: open /tmp/<tmp>/config.yaml: no such file or directory",
+		},
+		{
+			name: "hashes, UUIDs and pods of jobs",
+			line: "FAILED: commit 3f2a9c1d2e4b5a6 build 123e4567-e89b-12d3-a456-426614174000 pod job-x7k2p",
+			want: "FAILED: commit <hash> build <uuid> pod job-<pod>",
+		},
+		{
+			name: "words made of hex letters are not hashes",
+			line: "deadbeef: the request was acceded but the image was defaced at 0ddba11",
+			want: "deadbeef: the request was acceded but the image was defaced at <hash>",
+		},
+		{
+			name: "entrypoint log",
+			line: `{"component":"entrypoint","level":"error","msg":"Process did not finish before 2h0m0s timeout","severity":"error","time":"2024-01-02T15:04:05Z"}`,
+			want: "Process did not finish before <duration> timeout",
+		},
+		{
+			name: "whitespace",
+			line: "  error:\tsomething   went wrong  ",
+			want: "error: something went wrong",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Normalize(tc.line); got != tc.want {
+				t.Errorf("Expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSignature(t *testing.T) {
+	first := NewSignature("I0102 15:04:05.000001    1 main.go:1] error: pod web-x7k2p failed after 3s")
+	second := NewSignature("I0305 01:02:03.999999    7 main.go:1] error: pod web-b2c4d failed after 12s")
+	if diff := cmp.Diff(first, second); diff != "" {
+		t.Errorf("Expected the same signature for the same failure: %s", diff)
+	}
+	if other := NewSignature("error: something else"); other.ID == first.ID {
+		t.Errorf("Expected different IDs for different failures, got %q", other.ID)
+	}
+	if len(first.ID) != 16 {
+		t.Errorf("Expected an ID of 16 characters, got %q", first.ID)
+	}
+
+	if diff := cmp.Diff(first, SignatureFromMetadata(first.Metadata())); diff != "" {
+		t.Errorf("Unexpected signature read from the metadata: %s", diff)
+	}
+	if got := SignatureFromMetadata(map[string]interface{}{"other": "value"}); got != nil {
+		t.Errorf("Expected no signature, got %+v", got)
+	}
+}
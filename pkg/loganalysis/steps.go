@@ -0,0 +1,260 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package loganalysis groups build logs into steps, finds the first real
+// error and computes a normalized failure signature that can be searched
+// across jobs.
+package loganalysis
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Step is a group of consecutive lines of a log.
+type Step struct {
+	// Name describes the step, e.g. "go test sigs.k8s.io/prow/pkg/tide".
+	// It is empty for the output between recognized steps.
+	Name string
+	// Tool is the tool whose output was recognized, e.g. "go test".
+	Tool string
+	// Start and End are the first and last lines of the step, starting at 1.
+	Start, End int
+	// Failed is true when the step reported a failure.
+	Failed bool
+	// FirstError is the line of the first error of the step, or 0.
+	FirstError int
+}
+
+// Lines returns the number of lines of the step.
+func (s Step) Lines() int {
+	return s.End - s.Start + 1
+}
+
+// Analysis is the result of the analysis of a log.
+type Analysis struct {
+	Steps []Step
+	// FirstError is the line of the first real error of the log, or 0.
+	FirstError int
+	// Signature is the failure signature of the first error, if any.
+	Signature *Signature
+}
+
+const (
+	toolEntrypoint = "entrypoint"
+	toolGoTest     = "go test"
+	toolMake       = "make"
+	toolBazel      = "bazel"
+)
+
+type markerKind int
+
+const (
+	// startMarker starts a new step at the line.
+	startMarker markerKind = iota
+	// endMarker ends the current step at the line.
+	endMarker
+)
+
+// marker recognizes the lines that start or end a step.
+type marker struct {
+	re   *regexp.Regexp
+	kind markerKind
+	tool string
+	// name returns the name of the step from the submatches.
+	name func(m []string) string
+	// failed returns whether the step failed from the submatches.
+	failed func(m []string) bool
+}
+
+var markers = []marker{
+	{
+		// The entrypoint logs to the process log in JSON.
+		re:   regexp.MustCompile(`^\{.*"component":"entrypoint".*"msg":"([^"]*)"`),
+		kind: startMarker,
+		tool: toolEntrypoint,
+		name: func(m []string) string { return "entrypoint: " + m[1] },
+	},
+	{
+		re:     regexp.MustCompile(`^(ok|FAIL|\?)\s+(\S+)\s`),
+		kind:   endMarker,
+		tool:   toolGoTest,
+		name:   func(m []string) string { return "go test " + m[2] },
+		failed: func(m []string) bool { return m[1] == "FAIL" },
+	},
+	{
+		re:   regexp.MustCompile(`^make(?:\[\d+\])?: Entering directory '([^']*)'`),
+		kind: startMarker,
+		tool: toolMake,
+		name: func(m []string) string { return "make: " + m[1] },
+	},
+	{
+		re:   regexp.MustCompile(`^make(?:\[\d+\])?: Leaving directory`),
+		kind: endMarker,
+		tool: toolMake,
+	},
+	{
+		re:   regexp.MustCompile(`^(?:Starting local Bazel server|INFO: Invocation ID: |INFO: Analyzed \d+ targets?)`),
+		kind: startMarker,
+		tool: toolBazel,
+		name: func([]string) string { return "bazel" },
+	},
+	{
+		re:     regexp.MustCompile(`^(?:INFO: Build completed|FAILED: Build did NOT complete successfully|Executed \d+ out of \d+ tests?: )`),
+		kind:   endMarker,
+		tool:   toolBazel,
+		failed: func(m []string) bool { return strings.HasPrefix(m[0], "FAILED") || strings.Contains(m[0], "fail") },
+	},
+}
+
+// errorREs match the lines of real errors, from the most to the least
+// specific. The first error of a log is the first line matching the most
+// specific regex.
+var errorREs = []*regexp.Regexp{
+	regexp.MustCompile(`^(?:--- FAIL: |panic: |fatal error: |FAIL\s|ERROR: |FAILED: |make(?:\[\d+\])?: \*\*\* )`),
+	regexp.MustCompile(`^\S+\.go:\d+:(?:\d+:)? `),
+	regexp.MustCompile(`^\{.*"component":"entrypoint".*"level":"error"`),
+	regexp.MustCompile(`(?i)^\s*(?:error|fatal)\b|\b(?:error|failed|failure):`),
+}
+
+// maxLineLength bounds the lines that are read, longer lines are truncated.
+const maxLineLength = 1024 * 1024
+
+// Analyze reads the log and groups its lines into steps.
+func Analyze(r io.Reader) (*Analysis, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return AnalyzeLines(lines), nil
+}
+
+// AnalyzeTail analyzes the last maxBytes of the log, starting from its first
+// complete line. The line numbers of the analysis are relative to that tail.
+func AnalyzeTail(r io.ReadSeeker, maxBytes int64) (*Analysis, error) {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	offset := size - maxBytes
+	if offset <= 0 {
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return Analyze(r)
+	}
+
+	// Start from the byte preceding the tail to know whether the tail starts
+	// with a complete line, and skip the partial line otherwise.
+	if _, err := r.Seek(offset-1, io.SeekStart); err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(r)
+	for {
+		b, err := reader.ReadByte()
+		if err == io.EOF {
+			return AnalyzeLines(nil), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if b == '\n' {
+			break
+		}
+	}
+	return Analyze(reader)
+}
+
+// AnalyzeLines groups the lines of a log into steps.
+func AnalyzeLines(lines []string) *Analysis {
+	analysis := &Analysis{}
+	current := Step{Start: 1}
+	closeStep := func(end int) {
+		current.End = end
+		if current.Lines() > 0 {
+			analysis.Steps = append(analysis.Steps, current)
+		}
+	}
+
+	for i, line := range lines {
+		num := i + 1
+		for _, marker := range markers {
+			m := marker.re.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			if marker.kind == startMarker {
+				if current.Tool == marker.tool {
+					// Already in a step of the tool.
+					break
+				}
+				closeStep(num - 1)
+				current = Step{Name: marker.name(m), Tool: marker.tool, Start: num}
+				break
+			}
+			if marker.name == nil && current.Tool != marker.tool {
+				// Only ends a step of the tool.
+				break
+			}
+			if marker.name != nil && (current.Name == "" || current.Tool != marker.tool) {
+				// The end markers that name the step are more specific than
+				// the step they end, e.g. a package tested by make.
+				current.Name = marker.name(m)
+				current.Tool = marker.tool
+			}
+			if marker.failed != nil && marker.failed(m) {
+				current.Failed = true
+			}
+			if current.FirstError == 0 && current.Failed {
+				current.FirstError = firstError(lines, current.Start, num)
+			}
+			closeStep(num)
+			current = Step{Start: num + 1}
+			break
+		}
+		if current.FirstError == 0 && current.Start <= num && errorREs[0].MatchString(line) {
+			current.FirstError = num
+			current.Failed = true
+		}
+	}
+	closeStep(len(lines))
+
+	analysis.FirstError = firstError(lines, 1, len(lines))
+	if analysis.FirstError > 0 {
+		analysis.Signature = NewSignature(lines[analysis.FirstError-1])
+	}
+	return analysis
+}
+
+// firstError returns the first line between start and end that matches the
+// most specific error regex, or 0.
+func firstError(lines []string, start, end int) int {
+	for _, re := range errorREs {
+		for num := start; num <= end && num <= len(lines); num++ {
+			if re.MatchString(lines[num-1]) {
+				return num
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loganalysis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAnalyze(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		log            string
+		wantSteps      []Step
+		wantFirstError int
+		wantSignature  string
+	}{
+		{
+			name:      "no recognized step",
+			log:       "hello\nworld",
+			wantSteps: []Step{{Start: 1, End: 2}},
+		},
+		{
+			name: "go test",
+			log: `+ go test ./...
+=== RUN   TestA
+--- PASS: TestA (0.00s)
+ok  	example.com/a	0.012s
+=== RUN   TestB
+    b_test.go:12: expected 1, got 2
+--- FAIL: TestB (0.01s)
+FAIL
+FAIL	example.com/b	0.020s
+done`,
+			wantSteps: []Step{
+				{Name: "go test example.com/a", Tool: "go test", Start: 1, End: 4},
+				{Name: "go test example.com/b", Tool: "go test", Start: 5, End: 9, Failed: true, FirstError: 7},
+				{Start: 10, End: 10},
+			},
+			wantFirstError: 7,
+			wantSignature:  "--- FAIL: TestB (<duration>)",
+		},
+		{
+			name: "make and entrypoint",
+			log: `{"component":"entrypoint","file":"run.go:1","func":"Run","level":"info","msg":"Running the test","severity":"info"}
+make[1]: Entering directory '/src/app'
+cc -o app main.c
+main.c:3: undefined reference to foo
+make[1]: *** [Makefile:2: app] Error 1
+make[1]: Leaving directory '/src/app'
+{"component":"entrypoint","error":"wrapped process failed: exit status 2","file":"run.go:2","func":"Run","level":"error","msg":"Error executing test process","severity":"error"}`,
+			wantSteps: []Step{
+				{Name: "entrypoint: Running the test", Tool: "entrypoint", Start: 1, End: 1},
+				{Name: "make: /src/app", Tool: "make", Start: 2, End: 6, Failed: true, FirstError: 5},
+				{Name: "entrypoint: Error executing test process", Tool: "entrypoint", Start: 7, End: 7},
+			},
+			wantFirstError: 5,
+			wantSignature:  "make[1]: *** [Makefile:2: app] Error 1",
+		},
+		{
+			name: "bazel",
+			log: `Starting local Bazel server and connecting to it...
+INFO: Analyzed 3 targets (10 packages loaded).
+ERROR: /src/BUILD:1:1: Compiling main.go failed
+FAILED: Build did NOT complete successfully`,
+			wantSteps: []Step{
+				{Name: "bazel", Tool: "bazel", Start: 1, End: 4, Failed: true, FirstError: 3},
+			},
+			wantFirstError: 3,
+			wantSignature:  "ERROR: /src/BUILD:1:1: Compiling main.go failed",
+		},
+		{
+			name: "less specific errors",
+			log: `fetching dependencies
+error: could not resolve host
+retrying`,
+			wantSteps:      []Step{{Start: 1, End: 3}},
+			wantFirstError: 2,
+			wantSignature:  "error: could not resolve host",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			analysis, err := Analyze(strings.NewReader(tc.log))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantSteps, analysis.Steps); diff != "" {
+				t.Errorf("Unexpected steps: %s", diff)
+			}
+			if analysis.FirstError != tc.wantFirstError {
+				t.Errorf("Expected the first error on line %d, got %d", tc.wantFirstError, analysis.FirstError)
+			}
+			var signature string
+			if analysis.Signature != nil {
+				signature = analysis.Signature.Text
+			}
+			if signature != tc.wantSignature {
+				t.Errorf("Expected signature %q, got %q", tc.wantSignature, signature)
+			}
+		})
+	}
+}
+
+func TestAnalyzeTail(t *testing.T) {
+	log := "--- FAIL: TestA (0.01s)\nok\n--- FAIL: TestB (0.02s)\ndone\n"
+	for _, tc := range []struct {
+		name          string
+		maxBytes      int64
+		wantSignature string
+	}{
+		{
+			name:          "whole log",
+			maxBytes:      int64(len(log)),
+			wantSignature: "--- FAIL: TestA (<duration>)",
+		},
+		{
+			name:          "tail starting on a line",
+			maxBytes:      int64(len(log) - strings.Index(log, "ok")),
+			wantSignature: "--- FAIL: TestB (<duration>)",
+		},
+		{
+			name:          "partial line is skipped",
+			maxBytes:      int64(len(log) - strings.Index(log, "0.01s")),
+			wantSignature: "--- FAIL: TestB (<duration>)",
+		},
+		{
+			name:     "tail without a complete line",
+			maxBytes: 3,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			analysis, err := AnalyzeTail(strings.NewReader(log), tc.maxBytes)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var signature string
+			if analysis.Signature != nil {
+				signature = analysis.Signature.Text
+			}
+			if signature != tc.wantSignature {
+				t.Errorf("Expected signature %q, got %q", tc.wantSignature, signature)
+			}
+		})
+	}
+}
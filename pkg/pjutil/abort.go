@@ -59,6 +59,13 @@ func digestRefs(ref prowapi.Refs) string {
 // the prowjob to complete. The responsible agent is expected to react to the aborted state by aborting the actual
 // test payload and then setting the ProwJob to completed.
 func TerminateOlderJobs(pjc patchClient, log *logrus.Entry, pjs []prowapi.ProwJob) error {
+	_, err := AbortOlderJobs(pjc, log, pjs)
+	return err
+}
+
+// AbortOlderJobs is TerminateOlderJobs, returning the jobs it aborted.
+func AbortOlderJobs(pjc patchClient, log *logrus.Entry, pjs []prowapi.ProwJob) ([]prowapi.ProwJob, error) {
+	var aborted []prowapi.ProwJob
 	dupes := map[string]int{}
 	for i, pj := range pjs {
 		if pj.Complete() || pj.Spec.Type != prowapi.PresubmitJob {
@@ -100,25 +107,33 @@ func TerminateOlderJobs(pjc patchClient, log *logrus.Entry, pjs []prowapi.ProwJo
 		toCancel := pjs[cancelIndex]
 		prevPJ := toCancel.DeepCopy()
 
-		toCancel.Status.State = prowapi.AbortedState
-		if toCancel.Status.PrevReportStates == nil {
-			toCancel.Status.PrevReportStates = map[string]prowapi.ProwJobState{}
-		}
-		toCancel.Status.PrevReportStates[reporter.GitHubReporterName] = toCancel.Status.State
+		AbortSuperseded(&toCancel, fmt.Sprintf("Aborted, superseded by %s.", pjs[dupes[ji]].Name))
 
 		log.WithFields(ProwJobFields(&toCancel)).
 			WithField("from", prevPJ.Status.State).
 			WithField("to", toCancel.Status.State).Info("Transitioning states")
 
 		if err := pjc.Patch(context.Background(), &toCancel, ctrlruntimeclient.MergeFrom(prevPJ)); err != nil {
-			return err
+			return aborted, err
 		}
 
 		// Update the cancelled jobs entry in pjs.
 		pjs[cancelIndex] = toCancel
+		aborted = append(aborted, toCancel)
 	}
 
-	return nil
+	return aborted, nil
+}
+
+// AbortSuperseded marks the job aborted in favor of a newer one. The abort
+// isn't reported to GitHub, where the newer job reports.
+func AbortSuperseded(pj *prowapi.ProwJob, description string) {
+	pj.Status.State = prowapi.AbortedState
+	pj.Status.Description = description
+	if pj.Status.PrevReportStates == nil {
+		pj.Status.PrevReportStates = map[string]prowapi.ProwJobState{}
+	}
+	pj.Status.PrevReportStates[reporter.GitHubReporterName] = pj.Status.State
 }
 
 func PatchProwjob(ctx context.Context, pjc prowClient, log *logrus.Entry, srcPJ prowapi.ProwJob, destPJ prowapi.ProwJob) (*prowapi.ProwJob, error) {
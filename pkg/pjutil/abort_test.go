@@ -455,6 +455,9 @@ func TestTerminateOlderJobs(t *testing.T) {
 					if job.Complete() {
 						t.Errorf("job %s was set to complete, TerminateOlderJobs must never set prowjobs as completed", job.Name)
 					}
+					if job.Status.Description == "" {
+						t.Errorf("job %s was aborted without a description", job.Name)
+					}
 					actuallyAbortedJobs.Insert(job.Name)
 				}
 			}
@@ -32,6 +32,7 @@ import (
 	"sigs.k8s.io/prow/pkg/config"
 	"sigs.k8s.io/prow/pkg/gcsupload"
 	"sigs.k8s.io/prow/pkg/github"
+	"sigs.k8s.io/prow/pkg/io/providers"
 	"sigs.k8s.io/prow/pkg/kube"
 	"sigs.k8s.io/prow/pkg/pod-utils/decorate"
 	"sigs.k8s.io/prow/pkg/pod-utils/downwardapi"
@@ -234,6 +235,7 @@ func specFromJobBase(jb config.JobBase) prowapi.ProwJobSpec {
 		Namespace:       namespace,
 		MaxConcurrency:  jb.MaxConcurrency,
 		ErrorOnEviction: jb.ErrorOnEviction,
+		Retry:           jb.RetryPolicy,
 
 		ExtraRefs:        DecorateExtraRefs(jb.ExtraRefs, jb),
 		DecorationConfig: jb.DecorationConfig,
@@ -405,6 +407,18 @@ func JobURL(plank config.Plank, pj prowapi.ProwJob, log *logrus.Entry) (string,
 	return "", nil
 }
 
+// ArtifactsURL returns the storage URL of the artifacts of a decorated ProwJob,
+// e.g. gs://bucket/logs/job/123/artifacts.
+func ArtifactsURL(pj prowapi.ProwJob) (string, error) {
+	if pj.Spec.DecorationConfig == nil || pj.Spec.DecorationConfig.GCSConfiguration == nil {
+		return "", nil
+	}
+	spec := downwardapi.NewJobSpec(pj.Spec, pj.Status.BuildID, pj.Name)
+	gcsConfig := pj.Spec.DecorationConfig.GCSConfiguration
+	_, gcsPath, _ := gcsupload.PathsForJob(gcsConfig, &spec, "artifacts")
+	return providers.StoragePath(gcsConfig.Bucket, gcsPath)
+}
+
 // ClusterToCtx converts the prow job's cluster to a cluster context
 func ClusterToCtx(cluster string) string {
 	if cluster == kube.InClusterContext {
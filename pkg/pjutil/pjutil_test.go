@@ -1316,6 +1316,59 @@ func TestJobURL(t *testing.T) {
 	}
 }
 
+func TestArtifactsURL(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		pj       prowapi.ProwJob
+		expected string
+	}{
+		{
+			name: "undecorated job has no artifacts",
+			pj:   prowapi.ProwJob{Spec: prowapi.ProwJobSpec{Type: prowapi.PeriodicJob, Job: "job"}},
+		},
+		{
+			name: "periodic in gcs",
+			pj: prowapi.ProwJob{
+				Spec: prowapi.ProwJobSpec{
+					Type: prowapi.PeriodicJob,
+					Job:  "job",
+					DecorationConfig: &prowapi.DecorationConfig{
+						GCSConfiguration: &prowapi.GCSConfiguration{Bucket: "bucket", PathStrategy: prowapi.PathStrategyExplicit},
+					},
+				},
+				Status: prowapi.ProwJobStatus{BuildID: "123"},
+			},
+			expected: "gs://bucket/logs/job/123/artifacts",
+		},
+		{
+			name: "postsubmit in s3 with a path prefix",
+			pj: prowapi.ProwJob{
+				Spec: prowapi.ProwJobSpec{
+					Type: prowapi.PostsubmitJob,
+					Job:  "job",
+					Refs: &prowapi.Refs{Org: "org", Repo: "repo", BaseRef: "main", BaseSHA: "abc"},
+					DecorationConfig: &prowapi.DecorationConfig{
+						GCSConfiguration: &prowapi.GCSConfiguration{Bucket: "s3://bucket", PathPrefix: "prefix", PathStrategy: prowapi.PathStrategyExplicit},
+					},
+				},
+				Status: prowapi.ProwJobStatus{BuildID: "123"},
+			},
+			expected: "s3://bucket/prefix/logs/job/123/artifacts",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := ArtifactsURL(tc.pj)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != tc.expected {
+				t.Errorf("expected artifacts URL %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
 func TestSetReportDefault(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -34,6 +34,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
 	kapierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -325,16 +326,18 @@ func TestSyncTriggeredJobs(t *testing.T) {
 		MaxConcurrency int
 		Pods           map[string][]v1.Pod
 		PodErr         error
-
-		ExpectedState       prowapi.ProwJobState
-		ExpectedPodHasName  bool
-		ExpectedNumPods     map[string]int
-		ExpectedCreatedPJs  int
-		ExpectedComplete    bool
-		ExpectedURL         string
-		ExpectedBuildID     string
-		ExpectError         bool
-		ExpectedPendingTime *metav1.Time
+		FairShare      *config.FairShare
+
+		ExpectedState         prowapi.ProwJobState
+		ExpectedPodHasName    bool
+		ExpectedNumPods       map[string]int
+		ExpectedCreatedPJs    int
+		ExpectedComplete      bool
+		ExpectedURL           string
+		ExpectedBuildID       string
+		ExpectError           bool
+		ExpectedPendingTime   *metav1.Time
+		ExpectedQueuePosition int
 	}
 
 	testcases := []testCase{
@@ -705,6 +708,66 @@ func TestSyncTriggeredJobs(t *testing.T) {
 			ExpectedBuildID:     "0987654321",
 			ExpectedPodHasName:  true,
 		},
+		{
+			Name: "fair-share budget exceeded, job is queued",
+			PJ: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "blabla",
+					Namespace: "prowjobs",
+				},
+				Spec: prowapi.ProwJobSpec{
+					Job:  "boop",
+					Type: prowapi.PeriodicJob,
+					PodSpec: &v1.PodSpec{Containers: []v1.Container{{
+						Name: "test-name",
+						Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+							v1.ResourceCPU: resource.MustParse("2"),
+						}},
+					}}},
+				},
+				Status: prowapi.ProwJobStatus{
+					State: prowapi.TriggeredState,
+				},
+			},
+			PendingJobs: map[string]int{"motherearth": 1},
+			FairShare: &config.FairShare{Budgets: map[string]v1.ResourceList{
+				"default": {v1.ResourceCPU: resource.MustParse("1")},
+			}},
+			ExpectedState:         prowapi.TriggeredState,
+			ExpectedNumPods:       map[string]int{"default": 0},
+			ExpectedQueuePosition: 1,
+		},
+		{
+			Name: "fair-share budget available, job is started",
+			PJ: prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "blabla",
+					Namespace: "prowjobs",
+				},
+				Spec: prowapi.ProwJobSpec{
+					Job:  "boop",
+					Type: prowapi.PeriodicJob,
+					PodSpec: &v1.PodSpec{Containers: []v1.Container{{
+						Name: "test-name",
+						Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+							v1.ResourceCPU: resource.MustParse("2"),
+						}},
+					}}},
+				},
+				Status: prowapi.ProwJobStatus{
+					State:         prowapi.TriggeredState,
+					QueuePosition: 3,
+				},
+			},
+			PendingJobs: map[string]int{"motherearth": 1},
+			FairShare: &config.FairShare{Budgets: map[string]v1.ResourceList{
+				"default": {v1.ResourceCPU: resource.MustParse("4")},
+			}},
+			ExpectedState:       prowapi.PendingState,
+			ExpectedNumPods:     map[string]int{"default": 1},
+			ExpectedPendingTime: &pendingTime,
+			ExpectedPodHasName:  true,
+		},
 	}
 
 	for _, tc := range testcases {
@@ -720,7 +783,9 @@ func TestSyncTriggeredJobs(t *testing.T) {
 			tc.PJ.Spec.Agent = prowapi.KubernetesAgent
 
 			ctx := context.Background()
-			config := newFakeConfigAgent(t, tc.MaxConcurrency, nil).Config
+			fca := newFakeConfigAgent(t, tc.MaxConcurrency, nil)
+			fca.c.Plank.FairShare = tc.FairShare
+			config := fca.Config
 			fakeMgr, err := testutil.NewFakeManager(
 				ctx,
 				[]runtime.Object{&tc.PJ},
@@ -834,6 +899,9 @@ func TestSyncTriggeredJobs(t *testing.T) {
 			if actual.Complete() != tc.ExpectedComplete {
 				t.Error("got wrong completion")
 			}
+			if actual.Status.QueuePosition != tc.ExpectedQueuePosition {
+				t.Errorf("expected queue position %d, got %d", tc.ExpectedQueuePosition, actual.Status.QueuePosition)
+			}
 		})
 	}
 }
@@ -1824,6 +1892,220 @@ func TestSyncPendingJob(t *testing.T) {
 	}
 }
 
+func TestSyncPendingJobRetry(t *testing.T) {
+	evicted := v1.PodStatus{Phase: v1.PodFailed, Reason: Evicted}
+	failed := func(reason string, exitCode int32) v1.PodStatus {
+		return v1.PodStatus{
+			Phase: v1.PodFailed,
+			ContainerStatuses: []v1.ContainerStatus{{
+				Name:  "test",
+				State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: reason, ExitCode: exitCode}},
+			}},
+		}
+	}
+	pendingTooLong := v1.PodStatus{Phase: v1.PodPending, StartTime: startTime(time.Now().Add(-podPendingTimeout))}
+
+	testcases := []struct {
+		name      string
+		policy    *prowapi.RetryPolicy
+		retry     *prowapi.RetryStatus
+		podStatus v1.PodStatus
+
+		expectedState  prowapi.ProwJobState
+		expectedStatus *prowapi.RetryStatus
+		expectedRetry  *prowapi.RetryStatus
+	}{
+		{
+			name:          "evicted pod is retried",
+			policy:        &prowapi.RetryPolicy{MaxAttempts: 2, Reasons: []prowapi.RetryReason{prowapi.RetryOnEviction}},
+			podStatus:     evicted,
+			expectedState: prowapi.ErrorState,
+			expectedStatus: &prowapi.RetryStatus{
+				Attempt:   1,
+				RetriedBy: "boop-attempt-2",
+			},
+			expectedRetry: &prowapi.RetryStatus{
+				Attempt:          2,
+				PreviousAttempts: []string{"boop"},
+				Reason:           prowapi.RetryOnEviction,
+			},
+		},
+		{
+			name:          "evicted pod is not retried without a policy",
+			podStatus:     evicted,
+			expectedState: prowapi.ErrorState,
+		},
+		{
+			name:          "evicted pod is not retried after the last attempt",
+			policy:        &prowapi.RetryPolicy{MaxAttempts: 2, Reasons: []prowapi.RetryReason{prowapi.RetryOnEviction}},
+			retry:         &prowapi.RetryStatus{Attempt: 2, PreviousAttempts: []string{"boop-0"}, Reason: prowapi.RetryOnEviction},
+			podStatus:     evicted,
+			expectedState: prowapi.ErrorState,
+			expectedStatus: &prowapi.RetryStatus{
+				Attempt:          2,
+				PreviousAttempts: []string{"boop-0"},
+				Reason:           prowapi.RetryOnEviction,
+			},
+		},
+		{
+			name:          "pending timeout is retried with the attempt chain",
+			policy:        &prowapi.RetryPolicy{MaxAttempts: 3, Reasons: []prowapi.RetryReason{prowapi.RetryOnPodPendingTimeout}},
+			retry:         &prowapi.RetryStatus{Attempt: 2, PreviousAttempts: []string{"boop-0"}, Reason: prowapi.RetryOnPodPendingTimeout},
+			podStatus:     pendingTooLong,
+			expectedState: prowapi.ErrorState,
+			expectedStatus: &prowapi.RetryStatus{
+				Attempt:          2,
+				PreviousAttempts: []string{"boop-0"},
+				Reason:           prowapi.RetryOnPodPendingTimeout,
+				RetriedBy:        "boop-0-attempt-3",
+			},
+			expectedRetry: &prowapi.RetryStatus{
+				Attempt:          3,
+				PreviousAttempts: []string{"boop-0", "boop"},
+				Reason:           prowapi.RetryOnPodPendingTimeout,
+			},
+		},
+		{
+			name:          "pending timeout is not retried if the policy doesn't retry it",
+			policy:        &prowapi.RetryPolicy{MaxAttempts: 3, Reasons: []prowapi.RetryReason{prowapi.RetryOnEviction}},
+			podStatus:     pendingTooLong,
+			expectedState: prowapi.ErrorState,
+		},
+		{
+			name:          "OOMKilled container is retried",
+			policy:        &prowapi.RetryPolicy{MaxAttempts: 2, Reasons: []prowapi.RetryReason{prowapi.RetryOnOOMKilled}},
+			podStatus:     failed("OOMKilled", 137),
+			expectedState: prowapi.FailureState,
+			expectedStatus: &prowapi.RetryStatus{
+				Attempt:   1,
+				RetriedBy: "boop-attempt-2",
+			},
+			expectedRetry: &prowapi.RetryStatus{
+				Attempt:          2,
+				PreviousAttempts: []string{"boop"},
+				Reason:           prowapi.RetryOnOOMKilled,
+			},
+		},
+		{
+			name:          "exit code of the policy is retried",
+			policy:        &prowapi.RetryPolicy{MaxAttempts: 2, ExitCodes: []int32{3}},
+			podStatus:     failed("Error", 3),
+			expectedState: prowapi.FailureState,
+			expectedStatus: &prowapi.RetryStatus{
+				Attempt:   1,
+				RetriedBy: "boop-attempt-2",
+			},
+			expectedRetry: &prowapi.RetryStatus{
+				Attempt:          2,
+				PreviousAttempts: []string{"boop"},
+				Reason:           prowapi.RetryOnExitCode,
+			},
+		},
+		{
+			name:          "other exit codes are not retried",
+			policy:        &prowapi.RetryPolicy{MaxAttempts: 2, ExitCodes: []int32{3}, Reasons: []prowapi.RetryReason{prowapi.RetryOnOOMKilled}},
+			podStatus:     failed("Error", 1),
+			expectedState: prowapi.FailureState,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			pj := prowapi.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "boop",
+					Namespace: "prowjobs",
+					Labels:    map[string]string{kube.ProwJobTypeLabel: string(prowapi.PresubmitJob)},
+				},
+				Spec: prowapi.ProwJobSpec{
+					Type:            prowapi.PresubmitJob,
+					Job:             "pull-boop",
+					ErrorOnEviction: true,
+					Retry:           tc.policy,
+					PodSpec:         &v1.PodSpec{Containers: []v1.Container{{Name: "test"}}},
+				},
+				Status: prowapi.ProwJobStatus{
+					State:   prowapi.PendingState,
+					PodName: "boop",
+					Retry:   tc.retry,
+				},
+			}
+			pod := v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "boop", Namespace: "pods"},
+				Status:     tc.podStatus,
+			}
+			ctx := context.Background()
+			config := newFakeConfigAgent(t, 0, nil).Config
+			fakeMgr, err := testutil.NewFakeManager(
+				ctx,
+				[]runtime.Object{&pj},
+				func(ctx context.Context, indexer ctrlruntimeclient.FieldIndexer) error {
+					return setupIndexes(ctx, indexer, config)
+				},
+			)
+			if err != nil {
+				t.Fatalf("Failed to setup fake manager: %v", err)
+			}
+			r := &reconciler{
+				pjClient: fakeMgr.GetClient(),
+				buildClients: map[string]buildClient{
+					prowapi.DefaultClusterAlias: {Client: &clientWrapper{Client: fakectrlruntimeclient.NewFakeClient(&pod)}},
+				},
+				log:    logrus.NewEntry(logrus.StandardLogger()),
+				config: config,
+				clock:  clock.RealClock{},
+			}
+			if _, err := r.syncPendingJob(ctx, &pj); err != nil {
+				t.Fatalf("syncPendingJob failed: %v", err)
+			}
+
+			pjs := &prowapi.ProwJobList{}
+			if err := r.pjClient.List(ctx, pjs); err != nil {
+				t.Fatalf("could not list prowjobs: %v", err)
+			}
+			var retried *prowapi.ProwJob
+			for i := range pjs.Items {
+				actual := &pjs.Items[i]
+				if actual.Name == "boop" {
+					if actual.Status.State != tc.expectedState {
+						t.Errorf("expected state %s, got %s", tc.expectedState, actual.Status.State)
+					}
+					if diff := cmp.Diff(tc.expectedStatus, actual.Status.Retry); diff != "" {
+						t.Errorf("retry status differs from expected (-want +got):\n%s", diff)
+					}
+					continue
+				}
+				retried = actual
+			}
+
+			if tc.expectedRetry == nil {
+				if retried != nil {
+					t.Fatalf("expected no retry, got %s", retried.Name)
+				}
+				return
+			}
+			if retried == nil {
+				t.Fatal("expected a retry, got none")
+			}
+			if retried.Name != tc.expectedStatus.RetriedBy {
+				t.Errorf("expected the retry to be named %s, got %s", tc.expectedStatus.RetriedBy, retried.Name)
+			}
+			if retried.Status.State != prowapi.TriggeredState {
+				t.Errorf("expected the retry to be triggered, got %s", retried.Status.State)
+			}
+			if diff := cmp.Diff(tc.expectedRetry, retried.Status.Retry); diff != "" {
+				t.Errorf("retry status of the next attempt differs from expected (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(pj.Spec, retried.Spec); diff != "" {
+				t.Errorf("spec of the next attempt differs from the job (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(pj.Labels, retried.Labels); diff != "" {
+				t.Errorf("labels of the next attempt differ from the job (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func podWouldBeGone(pod corev1.Pod) bool {
 	if pod.DeletionTimestamp != nil {
 		return true
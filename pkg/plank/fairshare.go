@@ -0,0 +1,326 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plank
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	prowv1 "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/config"
+)
+
+// podRequests returns the CPU and memory requested by the pod of the job, the
+// larger of the sum of its containers and of any of its init containers. The
+// limit is used for the containers that don't request a resource.
+func podRequests(pj *prowv1.ProwJob) corev1.ResourceList {
+	requests := corev1.ResourceList{}
+	if pj.Spec.PodSpec == nil {
+		return requests
+	}
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		sum := resource.Quantity{}
+		for _, c := range pj.Spec.PodSpec.Containers {
+			sum.Add(containerRequest(c, name))
+		}
+		for _, c := range pj.Spec.PodSpec.InitContainers {
+			if q := containerRequest(c, name); q.Cmp(sum) > 0 {
+				sum = q
+			}
+		}
+		requests[name] = sum
+	}
+	return requests
+}
+
+func containerRequest(c corev1.Container, name corev1.ResourceName) resource.Quantity {
+	if q, ok := c.Resources.Requests[name]; ok {
+		return q
+	}
+	return c.Resources.Limits[name]
+}
+
+// usage is the resources requested by the pending jobs of a node of the
+// fair-share hierarchy: an org, a repo or a job.
+type usage struct {
+	requests corev1.ResourceList
+	jobs     int
+}
+
+func (u *usage) add(requests corev1.ResourceList) {
+	if u.requests == nil {
+		u.requests = corev1.ResourceList{}
+	}
+	for name, q := range requests {
+		sum := u.requests[name]
+		sum.Add(q)
+		u.requests[name] = sum
+	}
+	u.jobs++
+}
+
+// share returns the weighted dominant share of the budget used by the node,
+// then its weighted number of jobs to break ties between nodes whose jobs
+// don't request any resource.
+func (u *usage) share(budget corev1.ResourceList, weight int) (float64, float64) {
+	var dominant float64
+	for name, total := range budget {
+		used := u.requests[name]
+		if total.IsZero() {
+			continue
+		}
+		if s := used.AsApproximateFloat64() / total.AsApproximateFloat64(); s > dominant {
+			dominant = s
+		}
+	}
+	return dominant / float64(weight), float64(u.jobs) / float64(weight)
+}
+
+// fits returns whether the requests fit in the budget.
+func fits(requests, budget corev1.ResourceList) bool {
+	for name, total := range budget {
+		if used := requests[name]; used.Cmp(total) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// orgRepo returns the org and the org/repo the job is attributed to.
+func orgRepo(pj *prowv1.ProwJob) (string, string) {
+	refs := pj.Spec.Refs
+	if refs == nil && len(pj.Spec.ExtraRefs) > 0 {
+		refs = &pj.Spec.ExtraRefs[0]
+	}
+	if refs == nil {
+		return "", ""
+	}
+	return refs.Org, refs.Org + "/" + refs.Repo
+}
+
+// fairShareQueue orders the triggered jobs of a build cluster.
+type fairShareQueue struct {
+	config *config.FairShare
+	budget corev1.ResourceList
+	usage  map[string]*usage
+}
+
+func (q *fairShareQueue) usageOf(key string) *usage {
+	u, ok := q.usage[key]
+	if !ok {
+		u = &usage{}
+		q.usage[key] = u
+	}
+	return u
+}
+
+// keys returns the keys of the org, repo and job of the job in the usage map.
+func keys(pj *prowv1.ProwJob) (string, string, string) {
+	org, repo := orgRepo(pj)
+	return "org:" + org, "repo:" + repo, "job:" + repo + ":" + pj.Spec.Job
+}
+
+func (q *fairShareQueue) add(pj *prowv1.ProwJob) {
+	requests := podRequests(pj)
+	org, repo, job := keys(pj)
+	for _, key := range []string{org, repo, job} {
+		q.usageOf(key).add(requests)
+	}
+}
+
+// pick returns the index of the job to start next among the candidates.
+func (q *fairShareQueue) pick(candidates []*prowv1.ProwJob) int {
+	// Only the jobs of the highest priority are candidates.
+	priority := -1
+	for _, pj := range candidates {
+		if p := q.config.Priority(pj.Spec.Type); priority == -1 || p < priority {
+			priority = p
+		}
+	}
+	var indexes []int
+	for i, pj := range candidates {
+		if q.config.Priority(pj.Spec.Type) == priority {
+			indexes = append(indexes, i)
+		}
+	}
+
+	// Then walk down the hierarchy, picking the org, the repo then the job
+	// with the lowest share, and the oldest run of the job.
+	levels := []struct {
+		key    func(pj *prowv1.ProwJob) string
+		weight func(pj *prowv1.ProwJob) int
+	}{
+		{
+			key:    func(pj *prowv1.ProwJob) string { org, _, _ := keys(pj); return org },
+			weight: func(pj *prowv1.ProwJob) int { org, _ := orgRepo(pj); return q.config.OrgWeight(org) },
+		},
+		{
+			key:    func(pj *prowv1.ProwJob) string { _, repo, _ := keys(pj); return repo },
+			weight: func(pj *prowv1.ProwJob) int { _, repo := orgRepo(pj); return q.config.RepoWeight(repo) },
+		},
+		{
+			key:    func(pj *prowv1.ProwJob) string { _, _, job := keys(pj); return job },
+			weight: func(pj *prowv1.ProwJob) int { return q.config.JobWeight(pj.Spec.Job) },
+		},
+	}
+	for _, level := range levels {
+		var best string
+		var bestDominant, bestCount float64
+		bestOldest := -1
+		oldest := map[string]int{}
+		for _, i := range indexes {
+			key := level.key(candidates[i])
+			if j, ok := oldest[key]; !ok || older(candidates[i], candidates[j]) {
+				oldest[key] = i
+			}
+		}
+		for _, i := range indexes {
+			key := level.key(candidates[i])
+			if key == best {
+				continue
+			}
+			dominant, count := q.usageOf(key).share(q.budget, level.weight(candidates[i]))
+			if bestOldest == -1 || dominant < bestDominant ||
+				(dominant == bestDominant && count < bestCount) ||
+				(dominant == bestDominant && count == bestCount && older(candidates[oldest[key]], candidates[bestOldest])) {
+				best, bestDominant, bestCount, bestOldest = key, dominant, count, oldest[key]
+			}
+		}
+		var next []int
+		for _, i := range indexes {
+			if level.key(candidates[i]) == best {
+				next = append(next, i)
+			}
+		}
+		indexes = next
+	}
+
+	sort.Slice(indexes, func(a, b int) bool { return older(candidates[indexes[a]], candidates[indexes[b]]) })
+	return indexes[0]
+}
+
+func older(a, b *prowv1.ProwJob) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	return a.Name < b.Name
+}
+
+// fairShareOrder returns the triggered jobs of a build cluster in the order
+// the fair-share queueing starts them, given its budget and its pending jobs.
+func fairShareOrder(cfg *config.FairShare, budget corev1.ResourceList, pending, triggered []prowv1.ProwJob) []*prowv1.ProwJob {
+	q := &fairShareQueue{config: cfg, budget: budget, usage: map[string]*usage{}}
+	for i := range pending {
+		q.add(&pending[i])
+	}
+	var candidates []*prowv1.ProwJob
+	for i := range triggered {
+		candidates = append(candidates, &triggered[i])
+	}
+	var order []*prowv1.ProwJob
+	for len(candidates) > 0 {
+		i := q.pick(candidates)
+		order = append(order, candidates[i])
+		q.add(candidates[i])
+		candidates = append(candidates[:i], candidates[i+1:]...)
+	}
+	return order
+}
+
+// fairSharePositions returns the positions of the triggered jobs in the queue
+// of their build cluster keyed by name, 0 if a job can start because all the
+// jobs up to it fit in the budget. A job requesting more than the whole budget
+// starts once the cluster has no pending job, rather than blocking the queue
+// forever.
+func fairSharePositions(cfg *config.FairShare, budget corev1.ResourceList, pending, triggered []prowv1.ProwJob) map[string]int {
+	used := &usage{}
+	for i := range pending {
+		used.add(podRequests(&pending[i]))
+	}
+	positions := make(map[string]int, len(triggered))
+	for i, next := range fairShareOrder(cfg, budget, pending, triggered) {
+		used.add(podRequests(next))
+		if fits(used.requests, budget) || (i == 0 && len(pending) == 0) {
+			positions[next.Name] = 0
+		} else {
+			positions[next.Name] = i + 1
+		}
+	}
+	return positions
+}
+
+// fairSharePosition returns the position of the triggered job in the queue of
+// its build cluster, see fairSharePositions.
+func fairSharePosition(cfg *config.FairShare, budget corev1.ResourceList, pj *prowv1.ProwJob, pending, triggered []prowv1.ProwJob) int {
+	return fairShareSnapshot{positions: fairSharePositions(cfg, budget, pending, triggered), length: len(triggered)}.position(pj.Name)
+}
+
+// fairShareQueueTTL is how long the queue of a build cluster is reused by the
+// jobs of the cluster, as ordering it is quadratic in the number of jobs.
+const fairShareQueueTTL = 10 * time.Second
+
+// fairShareSnapshot is the queue of a build cluster at some point in time.
+type fairShareSnapshot struct {
+	positions map[string]int
+	length    int
+	expires   time.Time
+}
+
+// position returns the position of the job, the jobs that weren't triggered
+// yet when the queue was computed wait behind the whole queue.
+func (s fairShareSnapshot) position(name string) int {
+	if position, ok := s.positions[name]; ok {
+		return position
+	}
+	return s.length + 1
+}
+
+// fairShareQueues caches the queues of the build clusters, so that the queue
+// is computed once per cluster for every fairShareQueueTTL rather than once
+// for every reconciliation of a triggered job.
+type fairShareQueues struct {
+	lock   sync.Mutex
+	queues map[string]fairShareSnapshot
+}
+
+// position returns the position of the job in the queue of the cluster, the
+// queue is computed again by compute once it expired.
+func (q *fairShareQueues) position(cluster, name string, now time.Time, compute func() (map[string]int, int, error)) (int, error) {
+	q.lock.Lock()
+	snapshot, ok := q.queues[cluster]
+	q.lock.Unlock()
+	if ok && now.Before(snapshot.expires) {
+		return snapshot.position(name), nil
+	}
+
+	positions, length, err := compute()
+	if err != nil {
+		return 0, err
+	}
+	snapshot = fairShareSnapshot{positions: positions, length: length, expires: now.Add(fairShareQueueTTL)}
+	q.lock.Lock()
+	if q.queues == nil {
+		q.queues = map[string]fairShareSnapshot{}
+	}
+	q.queues[cluster] = snapshot
+	q.lock.Unlock()
+	return snapshot.position(name), nil
+}
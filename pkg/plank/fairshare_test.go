@@ -0,0 +1,333 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plank
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	prowv1 "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/config"
+)
+
+func fairShareJob(name, job, orgRepo string, jobType prowv1.ProwJobType, age time.Duration, cpu, memory string) prowv1.ProwJob {
+	pj := prowv1.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			CreationTimestamp: metav1.NewTime(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC).Add(-age)),
+		},
+		Spec: prowv1.ProwJobSpec{
+			Type: jobType,
+			Job:  job,
+			PodSpec: &corev1.PodSpec{Containers: []corev1.Container{{
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse(cpu),
+					corev1.ResourceMemory: resource.MustParse(memory),
+				}},
+			}}},
+		},
+	}
+	if orgRepo != "" {
+		org, repo, _ := strings.Cut(orgRepo, "/")
+		pj.Spec.Refs = &prowv1.Refs{Org: org, Repo: repo}
+	}
+	return pj
+}
+
+func TestPodRequests(t *testing.T) {
+	testCases := []struct {
+		name     string
+		spec     *corev1.PodSpec
+		expected corev1.ResourceList
+	}{
+		{
+			name:     "no pod spec",
+			expected: corev1.ResourceList{},
+		},
+		{
+			name: "containers are summed, limits are used without requests",
+			spec: &corev1.PodSpec{Containers: []corev1.Container{
+				{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("500m"),
+					corev1.ResourceMemory: resource.MustParse("1Gi"),
+				}}},
+				{Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{
+					corev1.ResourceCPU: resource.MustParse("2"),
+				}}},
+			}},
+			expected: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("2500m"),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+		},
+		{
+			name: "init containers larger than the containers",
+			spec: &corev1.PodSpec{
+				InitContainers: []corev1.Container{
+					{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+						corev1.ResourceCPU: resource.MustParse("4"),
+					}}},
+				},
+				Containers: []corev1.Container{
+					{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("1"),
+						corev1.ResourceMemory: resource.MustParse("1Gi"),
+					}}},
+				},
+			},
+			expected: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("4"),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := podRequests(&prowv1.ProwJob{Spec: prowv1.ProwJobSpec{PodSpec: tc.spec}})
+			for name, q := range tc.expected {
+				if actual := actual[name]; actual.Cmp(q) != 0 {
+					t.Errorf("expected %s %s, got %s", name, q.String(), actual.String())
+				}
+			}
+			if len(actual) != len(tc.expected) {
+				t.Errorf("expected %d resources, got %v", len(tc.expected), actual)
+			}
+		})
+	}
+}
+
+func TestFairShareOrder(t *testing.T) {
+	budget := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("10"),
+		corev1.ResourceMemory: resource.MustParse("40Gi"),
+	}
+	testCases := []struct {
+		name      string
+		config    config.FairShare
+		pending   []prowv1.ProwJob
+		triggered []prowv1.ProwJob
+		expected  []string
+	}{
+		{
+			name: "oldest first within a job",
+			triggered: []prowv1.ProwJob{
+				fairShareJob("b", "job", "org/repo", prowv1.PresubmitJob, time.Minute, "1", "1Gi"),
+				fairShareJob("a", "job", "org/repo", prowv1.PresubmitJob, time.Hour, "1", "1Gi"),
+			},
+			expected: []string{"a", "b"},
+		},
+		{
+			name: "orgs take turns",
+			triggered: []prowv1.ProwJob{
+				fairShareJob("a1", "job", "a/repo", prowv1.PresubmitJob, 4*time.Hour, "1", "1Gi"),
+				fairShareJob("a2", "job", "a/repo", prowv1.PresubmitJob, 3*time.Hour, "1", "1Gi"),
+				fairShareJob("a3", "job", "a/repo", prowv1.PresubmitJob, 2*time.Hour, "1", "1Gi"),
+				fairShareJob("b1", "job", "b/repo", prowv1.PresubmitJob, time.Hour, "1", "1Gi"),
+				fairShareJob("b2", "job", "b/repo", prowv1.PresubmitJob, time.Minute, "1", "1Gi"),
+			},
+			expected: []string{"a1", "b1", "a2", "b2", "a3"},
+		},
+		{
+			name: "pending jobs count towards the share of their org",
+			pending: []prowv1.ProwJob{
+				fairShareJob("running", "job", "a/repo", prowv1.PresubmitJob, 5*time.Hour, "4", "1Gi"),
+			},
+			triggered: []prowv1.ProwJob{
+				fairShareJob("a1", "job", "a/repo", prowv1.PresubmitJob, 4*time.Hour, "1", "1Gi"),
+				fairShareJob("b1", "job", "b/repo", prowv1.PresubmitJob, time.Hour, "1", "1Gi"),
+				fairShareJob("b2", "job", "b/repo", prowv1.PresubmitJob, time.Minute, "1", "1Gi"),
+			},
+			expected: []string{"b1", "b2", "a1"},
+		},
+		{
+			name:   "weighted orgs get a larger share",
+			config: config.FairShare{OrgWeights: map[string]int{"a": 2}},
+			triggered: []prowv1.ProwJob{
+				fairShareJob("a1", "job", "a/repo", prowv1.PresubmitJob, 4*time.Hour, "1", "1Gi"),
+				fairShareJob("a2", "job", "a/repo", prowv1.PresubmitJob, 3*time.Hour, "1", "1Gi"),
+				fairShareJob("a3", "job", "a/repo", prowv1.PresubmitJob, 2*time.Hour, "1", "1Gi"),
+				fairShareJob("b1", "job", "b/repo", prowv1.PresubmitJob, time.Hour, "1", "1Gi"),
+				fairShareJob("b2", "job", "b/repo", prowv1.PresubmitJob, time.Minute, "1", "1Gi"),
+			},
+			expected: []string{"a1", "b1", "a2", "a3", "b2"},
+		},
+		{
+			name: "repos take turns within an org",
+			triggered: []prowv1.ProwJob{
+				fairShareJob("one1", "job", "org/one", prowv1.PresubmitJob, 4*time.Hour, "1", "1Gi"),
+				fairShareJob("one2", "job", "org/one", prowv1.PresubmitJob, 3*time.Hour, "1", "1Gi"),
+				fairShareJob("two1", "job", "org/two", prowv1.PresubmitJob, time.Hour, "1", "1Gi"),
+			},
+			expected: []string{"one1", "two1", "one2"},
+		},
+		{
+			name: "jobs take turns within a repo",
+			triggered: []prowv1.ProwJob{
+				fairShareJob("unit1", "unit", "org/repo", prowv1.PresubmitJob, 4*time.Hour, "1", "1Gi"),
+				fairShareJob("unit2", "unit", "org/repo", prowv1.PresubmitJob, 3*time.Hour, "1", "1Gi"),
+				fairShareJob("e2e1", "e2e", "org/repo", prowv1.PresubmitJob, time.Hour, "1", "1Gi"),
+			},
+			expected: []string{"unit1", "e2e1", "unit2"},
+		},
+		{
+			name: "postsubmits and periodics go first",
+			config: config.FairShare{
+				PriorityJobTypes: []prowv1.ProwJobType{prowv1.PostsubmitJob, prowv1.PeriodicJob},
+			},
+			triggered: []prowv1.ProwJob{
+				fairShareJob("presubmit", "job", "a/repo", prowv1.PresubmitJob, 4*time.Hour, "1", "1Gi"),
+				fairShareJob("periodic", "periodic", "", prowv1.PeriodicJob, 3*time.Hour, "1", "1Gi"),
+				fairShareJob("postsubmit", "post", "b/repo", prowv1.PostsubmitJob, time.Hour, "1", "1Gi"),
+			},
+			expected: []string{"postsubmit", "periodic", "presubmit"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var actual []string
+			for _, pj := range fairShareOrder(&tc.config, budget, tc.pending, tc.triggered) {
+				actual = append(actual, pj.Name)
+			}
+			if diff := cmp.Diff(tc.expected, actual); diff != "" {
+				t.Errorf("order differs from expected (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFairSharePosition(t *testing.T) {
+	budget := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("4"),
+		corev1.ResourceMemory: resource.MustParse("16Gi"),
+	}
+	testCases := []struct {
+		name      string
+		job       string
+		pending   []prowv1.ProwJob
+		triggered []prowv1.ProwJob
+		expected  int
+	}{
+		{
+			name: "job fits in the budget",
+			job:  "b",
+			triggered: []prowv1.ProwJob{
+				fairShareJob("a", "job", "org/repo", prowv1.PresubmitJob, time.Hour, "2", "1Gi"),
+				fairShareJob("b", "job", "org/repo", prowv1.PresubmitJob, time.Minute, "2", "1Gi"),
+			},
+		},
+		{
+			name: "job doesn't fit behind the jobs ahead of it",
+			job:  "c",
+			triggered: []prowv1.ProwJob{
+				fairShareJob("a", "job", "org/repo", prowv1.PresubmitJob, time.Hour, "2", "1Gi"),
+				fairShareJob("b", "job", "org/repo", prowv1.PresubmitJob, 2*time.Minute, "2", "1Gi"),
+				fairShareJob("c", "job", "org/repo", prowv1.PresubmitJob, time.Minute, "1", "1Gi"),
+			},
+			expected: 3,
+		},
+		{
+			name: "job doesn't fit next to the pending jobs",
+			job:  "a",
+			pending: []prowv1.ProwJob{
+				fairShareJob("running", "job", "org/repo", prowv1.PresubmitJob, 2*time.Hour, "1", "15Gi"),
+			},
+			triggered: []prowv1.ProwJob{
+				fairShareJob("a", "job", "org/repo", prowv1.PresubmitJob, time.Hour, "1", "2Gi"),
+			},
+			expected: 1,
+		},
+		{
+			name: "job larger than the budget starts when the cluster is idle",
+			job:  "a",
+			triggered: []prowv1.ProwJob{
+				fairShareJob("a", "job", "org/repo", prowv1.PresubmitJob, time.Hour, "8", "1Gi"),
+			},
+		},
+		{
+			name: "job larger than the budget waits for the pending jobs",
+			job:  "a",
+			pending: []prowv1.ProwJob{
+				fairShareJob("running", "job", "org/repo", prowv1.PresubmitJob, 2*time.Hour, "1", "1Gi"),
+			},
+			triggered: []prowv1.ProwJob{
+				fairShareJob("a", "job", "org/repo", prowv1.PresubmitJob, time.Hour, "8", "1Gi"),
+			},
+			expected: 1,
+		},
+		{
+			name: "job not in the cache yet",
+			job:  "b",
+			triggered: []prowv1.ProwJob{
+				fairShareJob("a", "job", "org/repo", prowv1.PresubmitJob, time.Hour, "1", "1Gi"),
+			},
+			expected: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pj := &prowv1.ProwJob{ObjectMeta: metav1.ObjectMeta{Name: tc.job}}
+			if actual := fairSharePosition(&config.FairShare{}, budget, pj, tc.pending, tc.triggered); actual != tc.expected {
+				t.Errorf("expected position %d, got %d", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFairShareQueues(t *testing.T) {
+	var queues fairShareQueues
+	now := time.Now()
+	computed := 0
+	compute := func(positions map[string]int, length int) func() (map[string]int, int, error) {
+		return func() (map[string]int, int, error) {
+			computed++
+			return positions, length, nil
+		}
+	}
+	position := func(cluster, name string, now time.Time, positions map[string]int) int {
+		t.Helper()
+		p, err := queues.position(cluster, name, now, compute(positions, len(positions)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return p
+	}
+
+	if p := position("default", "a", now, map[string]int{"a": 0, "b": 2}); p != 0 || computed != 1 {
+		t.Errorf("expected position 0 after computing the queue once, got %d after %d", p, computed)
+	}
+	if p := position("default", "b", now.Add(time.Second), map[string]int{"b": 0}); p != 2 || computed != 1 {
+		t.Errorf("expected the cached position 2, got %d after computing the queue %d times", p, computed)
+	}
+	if p := position("default", "c", now.Add(time.Second), nil); p != 3 || computed != 1 {
+		t.Errorf("expected a job missing from the cached queue behind it, got %d after computing the queue %d times", p, computed)
+	}
+	if p := position("other", "a", now.Add(time.Second), map[string]int{"a": 1}); p != 1 || computed != 2 {
+		t.Errorf("expected the queue of another cluster to be computed, got %d after computing the queues %d times", p, computed)
+	}
+	if p := position("default", "b", now.Add(fairShareQueueTTL), map[string]int{"b": 0}); p != 0 || computed != 3 {
+		t.Errorf("expected the expired queue to be computed again, got %d after computing the queues %d times", p, computed)
+	}
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plank
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	prowv1 "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/github"
+	"sigs.k8s.io/prow/pkg/kube"
+	"sigs.k8s.io/prow/pkg/pjutil"
+)
+
+const (
+	// preemptedByNewerRun is the reason of the jobs aborted in favor of a
+	// newer run of the same presubmit for the same pull requests.
+	preemptedByNewerRun = "newer_run"
+	// preemptedBySupersededRevision is the reason of the jobs aborted because
+	// a pull request or Gerrit change they test got a newer revision.
+	preemptedBySupersededRevision = "superseded_revision"
+
+	// headRevisionTTL is how long the head revision of a pull request is
+	// cached, so that the jobs of a pull request reconciled in the meantime
+	// share a single lookup.
+	headRevisionTTL = time.Minute
+)
+
+var preemptedJobs = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "plank_preempted_jobs_total",
+	Help: "Number of ProwJobs aborted because they were superseded by a newer one.",
+}, []string{
+	// type of the aborted job
+	"type",
+	// reason the job got aborted
+	"reason",
+})
+
+func init() {
+	prometheus.MustRegister(preemptedJobs)
+}
+
+func (r *reconciler) terminateDupes(ctx context.Context, pj *prowv1.ProwJob) error {
+	pjs := &prowv1.ProwJobList{}
+	if err := r.pjClient.List(ctx, pjs, optPendingTriggeredJobsNamed(pj.Spec.Job)); err != nil {
+		return fmt.Errorf("failed to list prowjobs: %w", err)
+	}
+
+	aborted, err := pjutil.AbortOlderJobs(r.pjClient, r.log, pjs.Items)
+	for _, dupe := range aborted {
+		preemptedJobs.WithLabelValues(string(dupe.Spec.Type), preemptedByNewerRun).Inc()
+		if dupe.Name == pj.Name {
+			pj.Status = dupe.Status
+		}
+	}
+	return err
+}
+
+// PullRequestGetter gets the GitHub pull requests, to find the revision their
+// head is at.
+type PullRequestGetter interface {
+	GetPullRequest(org, repo string, number int) (*github.PullRequest, error)
+}
+
+// preemptIfSuperseded aborts the job if the preemption policy applies to it
+// and a presubmit created after it tests the head revision of one of its pull
+// requests or Gerrit changes, which the job doesn't test.
+func (r *reconciler) preemptIfSuperseded(ctx context.Context, pj *prowv1.ProwJob) error {
+	if !r.config().Plank.Preemption.Preempts(pj.Spec.Type) || pj.Complete() || pj.Status.State == prowv1.AbortedState || pj.Spec.Refs == nil {
+		return nil
+	}
+
+	for _, pull := range pj.Spec.Refs.Pulls {
+		pjs := &prowv1.ProwJobList{}
+		if err := r.pjClient.List(ctx, pjs, optPresubmitsForPull(pj.Spec.Refs.Org, pj.Spec.Refs.Repo, pull.Number)); err != nil {
+			return fmt.Errorf("failed to list prowjobs: %w", err)
+		}
+		// Only look the head up when a later presubmit tests another revision.
+		if supersedingJob(pj, pull, pjs.Items, func(sha string) bool { return sha != pull.SHA }) == nil {
+			continue
+		}
+		head, err := r.headRevision(pj, pull, pjs.Items)
+		if err != nil {
+			r.log.WithFields(pjutil.ProwJobFields(pj)).WithError(err).Warn("Could not get the head revision, not preempting the job.")
+			continue
+		}
+		if head == "" || head == pull.SHA {
+			continue
+		}
+		newer := supersedingJob(pj, pull, pjs.Items, func(sha string) bool { return sha == head })
+		if newer == nil {
+			continue
+		}
+
+		prevPJ := pj.DeepCopy()
+		pjutil.AbortSuperseded(pj, fmt.Sprintf("Aborted, #%d was updated to %s tested by %s.", pull.Number, shortSHA(head), newer.Name))
+		r.log.WithFields(pjutil.ProwJobFields(pj)).
+			WithField("from", prevPJ.Status.State).
+			WithField("to", pj.Status.State).
+			WithField("superseded-by", newer.Name).Info("Transitioning states.")
+		if err := r.pjClient.Patch(ctx, pj.DeepCopy(), ctrlruntimeclient.MergeFrom(prevPJ)); err != nil {
+			return fmt.Errorf("patch prowjob: %w", err)
+		}
+		preemptedJobs.WithLabelValues(string(pj.Spec.Type), preemptedBySupersededRevision).Inc()
+		return nil
+	}
+
+	return nil
+}
+
+// headRevision returns the revision the head of the pull request or Gerrit
+// change is at, or an empty string if it is unknown. The head of a Gerrit
+// change is its latest patchset tested by a presubmit, the head of a GitHub
+// pull request is looked up, as the presubmits can test older revisions when
+// they get rerun.
+func (r *reconciler) headRevision(pj *prowv1.ProwJob, pull prowv1.Pull, presubmits []prowv1.ProwJob) (string, error) {
+	if _, ok := pj.Labels[kube.GerritPatchset]; ok {
+		return latestPatchset(pull.Number, presubmits), nil
+	}
+	if r.prs == nil {
+		return "", nil
+	}
+	key := fmt.Sprintf("%s/%s#%d", pj.Spec.Refs.Org, pj.Spec.Refs.Repo, pull.Number)
+	return r.headRevisions.get(key, r.clock.Now(), func() (string, error) {
+		pr, err := r.prs.GetPullRequest(pj.Spec.Refs.Org, pj.Spec.Refs.Repo, pull.Number)
+		if err != nil {
+			return "", fmt.Errorf("failed to get pull request %s: %w", key, err)
+		}
+		return pr.Head.SHA, nil
+	})
+}
+
+type cachedHeadRevision struct {
+	sha     string
+	expires time.Time
+}
+
+// headRevisions caches the head revision of the pull requests for
+// headRevisionTTL rather than looking it up for every reconciliation of
+// their jobs.
+type headRevisions struct {
+	lock  sync.Mutex
+	heads map[string]cachedHeadRevision
+}
+
+// get returns the head revision of the pull request, which is looked up
+// again by lookup once it expired.
+func (h *headRevisions) get(key string, now time.Time, lookup func() (string, error)) (string, error) {
+	h.lock.Lock()
+	cached, ok := h.heads[key]
+	h.lock.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.sha, nil
+	}
+
+	sha, err := lookup()
+	if err != nil {
+		return "", err
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.heads == nil {
+		h.heads = map[string]cachedHeadRevision{}
+	}
+	for other, cached := range h.heads {
+		if !now.Before(cached.expires) {
+			delete(h.heads, other)
+		}
+	}
+	h.heads[key] = cachedHeadRevision{sha: sha, expires: now.Add(headRevisionTTL)}
+	return sha, nil
+}
+
+// latestPatchset returns the revision of the latest patchset of the Gerrit
+// change tested by the presubmits.
+func latestPatchset(number int, presubmits []prowv1.ProwJob) string {
+	var latest int
+	var sha string
+	for i := range presubmits {
+		patchset, err := strconv.Atoi(presubmits[i].Labels[kube.GerritPatchset])
+		if err != nil || patchset <= latest {
+			continue
+		}
+		if revision := newerSHA(&presubmits[i], number); revision != "" {
+			latest, sha = patchset, revision
+		}
+	}
+	return sha
+}
+
+// supersedingJob returns the newest of the presubmits created after the job
+// that test the pull request at a revision matching the predicate, if any.
+func supersedingJob(pj *prowv1.ProwJob, pull prowv1.Pull, presubmits []prowv1.ProwJob, matches func(sha string) bool) *prowv1.ProwJob {
+	var newest *prowv1.ProwJob
+	for i := range presubmits {
+		candidate := &presubmits[i]
+		if candidate.Name == pj.Name || !pj.CreationTimestamp.Before(&candidate.CreationTimestamp) {
+			continue
+		}
+		if sha := newerSHA(candidate, pull.Number); sha == "" || !matches(sha) {
+			continue
+		}
+		if newest == nil || newest.CreationTimestamp.Before(&candidate.CreationTimestamp) {
+			newest = candidate
+		}
+	}
+	return newest
+}
+
+// newerSHA returns the revision of the pull request the presubmit tests.
+func newerSHA(pj *prowv1.ProwJob, number int) string {
+	for _, pull := range pj.Spec.Refs.Pulls {
+		if pull.Number == number {
+			return pull.SHA
+		}
+	}
+	return ""
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
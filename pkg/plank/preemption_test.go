@@ -0,0 +1,265 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plank
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	testingclock "k8s.io/utils/clock/testing"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	prowv1 "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/github"
+	"sigs.k8s.io/prow/pkg/github/fakegithub"
+	"sigs.k8s.io/prow/pkg/kube"
+	"sigs.k8s.io/prow/pkg/testutil"
+)
+
+func TestPreemptIfSuperseded(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	job := func(name string, jobType prowv1.ProwJobType, age time.Duration, state prowv1.ProwJobState, pulls ...prowv1.Pull) *prowv1.ProwJob {
+		pj := &prowv1.ProwJob{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "prowjobs",
+				CreationTimestamp: metav1.NewTime(now.Add(-age)),
+			},
+			Spec: prowv1.ProwJobSpec{
+				Type:  jobType,
+				Job:   name,
+				Agent: prowv1.KubernetesAgent,
+				Refs:  &prowv1.Refs{Org: "org", Repo: "repo", Pulls: pulls},
+			},
+			Status: prowv1.ProwJobStatus{State: state},
+		}
+		if state == prowv1.FailureState {
+			pj.SetComplete()
+		}
+		return pj
+	}
+	oldRevision := prowv1.Pull{Number: 1, SHA: "1111111111"}
+	newRevision := prowv1.Pull{Number: 1, SHA: "2222222222"}
+	otherPull := prowv1.Pull{Number: 2, SHA: "3333333333"}
+	olderRevision := prowv1.Pull{Number: 1, SHA: "0000000000"}
+	patchset := func(pj *prowv1.ProwJob, number string) *prowv1.ProwJob {
+		pj.Labels = map[string]string{kube.GerritPatchset: number}
+		return pj
+	}
+
+	testCases := []struct {
+		name                string
+		preemption          *config.Preemption
+		pj                  *prowv1.ProwJob
+		others              []runtime.Object
+		noHead              bool
+		expectedState       prowv1.ProwJobState
+		expectedDescription string
+	}{
+		{
+			name:          "preemption not configured",
+			pj:            job("unit", prowv1.PresubmitJob, time.Hour, prowv1.PendingState, oldRevision),
+			others:        []runtime.Object{job("e2e", prowv1.PresubmitJob, time.Minute, prowv1.TriggeredState, newRevision)},
+			expectedState: prowv1.PendingState,
+		},
+		{
+			name:                "presubmit superseded by a newer revision",
+			preemption:          &config.Preemption{Presubmits: true},
+			pj:                  job("unit", prowv1.PresubmitJob, time.Hour, prowv1.PendingState, oldRevision),
+			others:              []runtime.Object{job("e2e", prowv1.PresubmitJob, time.Minute, prowv1.SuccessState, newRevision)},
+			expectedState:       prowv1.AbortedState,
+			expectedDescription: "Aborted, #1 was updated to 22222222 tested by e2e.",
+		},
+		{
+			name:          "presubmit of the latest revision",
+			preemption:    &config.Preemption{Presubmits: true},
+			pj:            job("unit", prowv1.PresubmitJob, time.Minute, prowv1.PendingState, newRevision),
+			others:        []runtime.Object{job("e2e", prowv1.PresubmitJob, time.Hour, prowv1.PendingState, oldRevision)},
+			expectedState: prowv1.PendingState,
+		},
+		{
+			name:          "newer presubmit of the same revision",
+			preemption:    &config.Preemption{Presubmits: true},
+			pj:            job("unit", prowv1.PresubmitJob, time.Hour, prowv1.TriggeredState, oldRevision),
+			others:        []runtime.Object{job("e2e", prowv1.PresubmitJob, time.Minute, prowv1.PendingState, oldRevision)},
+			expectedState: prowv1.TriggeredState,
+		},
+		{
+			name:          "batches are not preempted unless configured",
+			preemption:    &config.Preemption{Presubmits: true},
+			pj:            job("batch", prowv1.BatchJob, time.Hour, prowv1.PendingState, otherPull, oldRevision),
+			others:        []runtime.Object{job("e2e", prowv1.PresubmitJob, time.Minute, prowv1.PendingState, newRevision)},
+			expectedState: prowv1.PendingState,
+		},
+		{
+			name:                "batch with a superseded pull request",
+			preemption:          &config.Preemption{Batches: true},
+			pj:                  job("batch", prowv1.BatchJob, time.Hour, prowv1.PendingState, otherPull, oldRevision),
+			others:              []runtime.Object{job("e2e", prowv1.PresubmitJob, time.Minute, prowv1.PendingState, newRevision)},
+			expectedState:       prowv1.AbortedState,
+			expectedDescription: "Aborted, #1 was updated to 22222222 tested by e2e.",
+		},
+		{
+			name:          "newer batches don't supersede revisions",
+			preemption:    &config.Preemption{Presubmits: true},
+			pj:            job("unit", prowv1.PresubmitJob, time.Hour, prowv1.PendingState, newRevision),
+			others:        []runtime.Object{job("batch", prowv1.BatchJob, time.Minute, prowv1.PendingState, oldRevision)},
+			expectedState: prowv1.PendingState,
+		},
+		{
+			name:          "rerun of an older revision doesn't supersede the head",
+			preemption:    &config.Preemption{Presubmits: true},
+			pj:            job("unit", prowv1.PresubmitJob, time.Hour, prowv1.PendingState, newRevision),
+			others:        []runtime.Object{job("unit-rerun", prowv1.PresubmitJob, time.Minute, prowv1.PendingState, oldRevision)},
+			expectedState: prowv1.PendingState,
+		},
+		{
+			name:          "rerun of an older revision isn't preempted by an earlier job of the head",
+			preemption:    &config.Preemption{Presubmits: true},
+			pj:            job("unit-rerun", prowv1.PresubmitJob, time.Minute, prowv1.PendingState, oldRevision),
+			others:        []runtime.Object{job("unit", prowv1.PresubmitJob, time.Hour, prowv1.PendingState, newRevision)},
+			expectedState: prowv1.PendingState,
+		},
+		{
+			name:          "rerun of another older revision doesn't supersede",
+			preemption:    &config.Preemption{Presubmits: true},
+			pj:            job("unit", prowv1.PresubmitJob, time.Hour, prowv1.PendingState, oldRevision),
+			others:        []runtime.Object{job("unit-rerun", prowv1.PresubmitJob, time.Minute, prowv1.PendingState, olderRevision)},
+			expectedState: prowv1.PendingState,
+		},
+		{
+			name:          "unknown head doesn't supersede",
+			preemption:    &config.Preemption{Presubmits: true},
+			pj:            job("unit", prowv1.PresubmitJob, time.Hour, prowv1.PendingState, oldRevision),
+			others:        []runtime.Object{job("e2e", prowv1.PresubmitJob, time.Minute, prowv1.PendingState, newRevision)},
+			noHead:        true,
+			expectedState: prowv1.PendingState,
+		},
+		{
+			name:                "gerrit change superseded by a newer patchset",
+			preemption:          &config.Preemption{Presubmits: true},
+			pj:                  patchset(job("unit", prowv1.PresubmitJob, time.Hour, prowv1.PendingState, oldRevision), "1"),
+			others:              []runtime.Object{patchset(job("e2e", prowv1.PresubmitJob, time.Minute, prowv1.PendingState, newRevision), "2")},
+			noHead:              true,
+			expectedState:       prowv1.AbortedState,
+			expectedDescription: "Aborted, #1 was updated to 22222222 tested by e2e.",
+		},
+		{
+			name:          "rerun of an older gerrit patchset doesn't supersede the latest one",
+			preemption:    &config.Preemption{Presubmits: true},
+			pj:            patchset(job("unit", prowv1.PresubmitJob, time.Hour, prowv1.PendingState, newRevision), "2"),
+			others:        []runtime.Object{patchset(job("unit-rerun", prowv1.PresubmitJob, time.Minute, prowv1.PendingState, oldRevision), "1")},
+			noHead:        true,
+			expectedState: prowv1.PendingState,
+		},
+		{
+			name:          "complete jobs are left alone",
+			preemption:    &config.Preemption{Presubmits: true},
+			pj:            job("unit", prowv1.PresubmitJob, time.Hour, prowv1.FailureState, oldRevision),
+			others:        []runtime.Object{job("e2e", prowv1.PresubmitJob, time.Minute, prowv1.PendingState, newRevision)},
+			expectedState: prowv1.FailureState,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			fca := newFakeConfigAgent(t, 0, nil)
+			fca.c.Plank.Preemption = tc.preemption
+			fakeMgr, err := testutil.NewFakeManager(
+				ctx,
+				append(tc.others, tc.pj),
+				func(ctx context.Context, indexer ctrlruntimeclient.FieldIndexer) error {
+					return setupIndexes(ctx, indexer, fca.Config)
+				},
+			)
+			if err != nil {
+				t.Fatalf("Failed to setup fake manager: %v", err)
+			}
+			prs := fakegithub.NewFakeClient()
+			if !tc.noHead {
+				prs.PullRequests = map[int]*github.PullRequest{1: {Number: 1, Head: github.PullRequestBranch{SHA: newRevision.SHA}}}
+			}
+			r := &reconciler{
+				pjClient: fakeMgr.GetClient(),
+				log:      logrus.NewEntry(logrus.StandardLogger()),
+				config:   fca.Config,
+				prs:      prs,
+				clock:    testingclock.NewFakeClock(time.Now()),
+			}
+
+			pj := tc.pj.DeepCopy()
+			if err := r.preemptIfSuperseded(ctx, pj); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var actual prowv1.ProwJob
+			if err := r.pjClient.Get(ctx, ctrlruntimeclient.ObjectKeyFromObject(tc.pj), &actual); err != nil {
+				t.Fatalf("failed to get prowjob: %v", err)
+			}
+			for _, state := range []prowv1.ProwJobState{pj.Status.State, actual.Status.State} {
+				if state != tc.expectedState {
+					t.Errorf("expected state %s, got %s", tc.expectedState, state)
+				}
+			}
+			if actual.Status.Description != tc.expectedDescription {
+				t.Errorf("expected description %q, got %q", tc.expectedDescription, actual.Status.Description)
+			}
+		})
+	}
+}
+
+func TestHeadRevisions(t *testing.T) {
+	now := time.Now()
+	var lookups int
+	lookup := func(sha string) func() (string, error) {
+		return func() (string, error) {
+			lookups++
+			return sha, nil
+		}
+	}
+	var heads headRevisions
+
+	for _, tc := range []struct {
+		name            string
+		key             string
+		now             time.Time
+		sha             string
+		expectedSHA     string
+		expectedLookups int
+	}{
+		{name: "first lookup", key: "org/repo#1", now: now, sha: "a", expectedSHA: "a", expectedLookups: 1},
+		{name: "cached", key: "org/repo#1", now: now.Add(headRevisionTTL / 2), sha: "b", expectedSHA: "a", expectedLookups: 1},
+		{name: "other pull request", key: "org/repo#2", now: now.Add(headRevisionTTL / 2), sha: "c", expectedSHA: "c", expectedLookups: 2},
+		{name: "expired", key: "org/repo#1", now: now.Add(headRevisionTTL), sha: "b", expectedSHA: "b", expectedLookups: 3},
+	} {
+		sha, err := heads.get(tc.key, tc.now, lookup(tc.sha))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		if sha != tc.expectedSHA {
+			t.Errorf("%s: expected head %q, got %q", tc.name, tc.expectedSHA, sha)
+		}
+		if lookups != tc.expectedLookups {
+			t.Errorf("%s: expected %d lookups, got %d", tc.name, tc.expectedLookups, lookups)
+		}
+	}
+}
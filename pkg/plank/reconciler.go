@@ -26,6 +26,7 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -55,6 +56,7 @@ import (
 	"sigs.k8s.io/prow/pkg/kube"
 	"sigs.k8s.io/prow/pkg/pjutil"
 	"sigs.k8s.io/prow/pkg/pod-utils/decorate"
+	"sigs.k8s.io/prow/pkg/tracing"
 	"sigs.k8s.io/prow/pkg/version"
 )
 
@@ -104,8 +106,9 @@ func Add(
 	opener io.Opener,
 	totURL string,
 	additionalSelector string,
+	prs PullRequestGetter,
 ) error {
-	return add(mgr, buildClusters, knownClusters, cfg, opener, totURL, additionalSelector, nil, nil, 10)
+	return add(mgr, buildClusters, knownClusters, cfg, opener, totURL, additionalSelector, prs, nil, nil, 10)
 }
 
 func add(
@@ -116,6 +119,7 @@ func add(
 	opener io.Opener,
 	totURL string,
 	additionalSelector string,
+	prs PullRequestGetter,
 	overwriteReconcile reconcile.Func,
 	predicateCallback func(bool),
 	numWorkers int,
@@ -139,6 +143,7 @@ func add(
 		WithOptions(controller.Options{MaxConcurrentReconciles: numWorkers})
 
 	r := newReconciler(ctx, mgr.GetClient(), overwriteReconcile, cfg, opener, totURL)
+	r.prs = prs
 	for buildClusterName, buildCluster := range buildClusters {
 		r.log.WithFields(logrus.Fields{
 			"buildCluster": buildClusterName,
@@ -205,6 +210,10 @@ func newReconciler(ctx context.Context, pjClient ctrlruntimeclient.Client, overw
 			mapLock: &sync.Mutex{},
 			locks:   map[string]*sync.Mutex{},
 		},
+		fairShareSerializationLocks: &shardedLock{
+			mapLock: &sync.Mutex{},
+			locks:   map[string]*sync.Mutex{},
+		},
 	}
 }
 
@@ -216,14 +225,21 @@ type reconciler struct {
 	config             config.Getter
 	opener             io.Opener
 	totURL             string
-	clock              clock.WithTickerAndDelayedExecution
-	/* maxConcurrencySerializationLocks and jobQueueSerializationLocks are used to serialize
-	   reconciliation of ProwJobs that have concurrency limits that might affect eachother.
+	// prs finds the head of the pull requests to preempt the presubmits of
+	// their older revisions, if set.
+	prs PullRequestGetter
+	// headRevisions caches the head revision of the pull requests.
+	headRevisions headRevisions
+	clock         clock.WithTickerAndDelayedExecution
+	/* maxConcurrencySerializationLocks, jobQueueSerializationLocks and fairShareSerializationLocks
+	   are used to serialize reconciliation of ProwJobs that have concurrency limits that might
+	   affect eachother.
 
 	   The concurrency management strategy has 3 basic parts. Each part is skipped if the ProwJob
-	   does not specify a MaxConcurrency or JobQueueName.
+	   does not specify a MaxConcurrency or JobQueueName and its build cluster has no fair-share
+	   budget.
 
-	   1. Serialize per the job and/or queue name as needed using these locks. This prevents
+	   1. Serialize per the job, queue name and/or build cluster as needed using these locks. This prevents
 	      concurrent reconciliation threads from triggering jobs beyond the concurrency limit.
 	   2. Compare against the ProwJob index to see how many jobs there are for the job and job queue
 	      and only trigger the job if it won't exceed the concurrency limit(s).
@@ -234,6 +250,9 @@ type reconciler struct {
 	*/
 	maxConcurrencySerializationLocks *shardedLock
 	jobQueueSerializationLocks       *shardedLock
+	fairShareSerializationLocks      *shardedLock
+	// fairShareQueues caches the fair-share queues of the build clusters.
+	fairShareQueues fairShareQueues
 }
 
 type shardedLock struct {
@@ -390,8 +409,9 @@ func (r *reconciler) defaultReconcile(ctx context.Context, request reconcile.Req
 	return *res, err
 }
 
-// serializeIfNeeded serializes the reconciliation of Jobs that have a MaxConcurrency or a JobQueueName set, otherwise
-// multiple reconciliations of the same job or queue may race and not properly respect that setting.
+// serializeIfNeeded serializes the reconciliation of Jobs that have a MaxConcurrency or a JobQueueName set or run in a
+// build cluster with a fair-share budget, otherwise multiple reconciliations of the same job, queue or cluster may race
+// and not properly respect that setting.
 func (r *reconciler) serializeIfNeeded(ctx context.Context, pj *prowv1.ProwJob) (*reconcile.Result, error) {
 	if pj.Spec.MaxConcurrency > 0 {
 		// We need to serialize handling of this job name.
@@ -412,15 +432,37 @@ func (r *reconciler) serializeIfNeeded(ctx context.Context, pj *prowv1.ProwJob)
 		}
 		defer lock.Unlock()
 	}
+
+	if r.fairShareBudget(pj) != nil {
+		// We need to serialize handling of the jobs of this build cluster.
+		lock := r.fairShareSerializationLocks.getLock(pj.ClusterAlias())
+		// Use TryAcquire to avoid blocking workers waiting for the lock
+		if !lock.TryLock() {
+			return &reconcile.Result{RequeueAfter: time.Second}, nil
+		}
+		defer lock.Unlock()
+	}
 	return r.reconcile(ctx, pj)
 }
 
-func (r *reconciler) reconcile(ctx context.Context, pj *prowv1.ProwJob) (*reconcile.Result, error) {
+func (r *reconciler) reconcile(ctx context.Context, pj *prowv1.ProwJob) (res *reconcile.Result, err error) {
+	start, prevState, prevComplete := time.Now(), pj.Status.State, pj.Complete()
+	defer func() {
+		// The jobs are reconciled on every event of their pod, only the
+		// reconciles failing or transitioning the job are traced.
+		if err != nil || pj.Status.State != prevState || pj.Complete() != prevComplete {
+			tracing.RecordProwJobSpan(ctx, "plank.reconcile", pj, start, err, attribute.String("prowjob.previous_state", string(prevState)))
+		}
+	}()
+
 	// terminateDupes first, as that might reduce cluster load and prevent us
 	// from doing pointless work.
 	if err := r.terminateDupes(ctx, pj); err != nil {
 		return nil, fmt.Errorf("terminateDupes failed: %w", err)
 	}
+	if err := r.preemptIfSuperseded(ctx, pj); err != nil {
+		return nil, fmt.Errorf("preemptIfSuperseded failed: %w", err)
+	}
 
 	switch pj.Status.State {
 	case prowv1.PendingState:
@@ -434,15 +476,6 @@ func (r *reconciler) reconcile(ctx context.Context, pj *prowv1.ProwJob) (*reconc
 	return nil, nil
 }
 
-func (r *reconciler) terminateDupes(ctx context.Context, pj *prowv1.ProwJob) error {
-	pjs := &prowv1.ProwJobList{}
-	if err := r.pjClient.List(ctx, pjs, optPendingTriggeredJobsNamed(pj.Spec.Job)); err != nil {
-		return fmt.Errorf("failed to list prowjobs: %w", err)
-	}
-
-	return pjutil.TerminateOlderJobs(r.pjClient, r.log, pjs.Items)
-}
-
 // syncPendingJob syncs jobs for which we already created the test workload
 func (r *reconciler) syncPendingJob(ctx context.Context, pj *prowv1.ProwJob) (*reconcile.Result, error) {
 	prevPJ := pj.DeepCopy()
@@ -452,6 +485,10 @@ func (r *reconciler) syncPendingJob(ctx context.Context, pj *prowv1.ProwJob) (*r
 		return nil, err
 	}
 
+	// retryReason is the class of the infrastructure failure the job
+	// completed with, if any.
+	var retryReason prowv1.RetryReason
+
 	if !podExists {
 		// Pod is missing. This can happen in case the previous pod was deleted manually or by
 		// a rescheduler. Start a new pod.
@@ -477,12 +514,14 @@ func (r *reconciler) syncPendingJob(ctx context.Context, pj *prowv1.ProwJob) (*r
 			pj.SetComplete()
 			pj.Status.State = prowv1.ErrorState
 			pj.Status.Description = "Job pod was evicted by the cluster."
+			retryReason = prowv1.RetryOnEviction
 		case pj.Status.PodRevivalCount >= *r.config().Plank.MaxRevivals:
 			// MaxRevivals is reached, complete the PJ and mark it as errored.
 			r.log.WithField("unexpected-stop-cause", podUnexpectedStopCause).WithFields(pjutil.ProwJobFields(pj)).Info("Pod Node reached max retries, fail job.")
 			pj.SetComplete()
 			pj.Status.State = prowv1.ErrorState
 			pj.Status.Description = fmt.Sprintf("Job pod reached max revivals (%d) after being stopped unexpectedly (%s)", pj.Status.PodRevivalCount, podUnexpectedStopCause)
+			retryReason = prowv1.RetryOnEviction
 		default:
 			// Update the revival count and delete the pod so it gets recreated in the next resync.
 			pj.Status.PodRevivalCount++
@@ -532,6 +571,7 @@ func (r *reconciler) syncPendingJob(ctx context.Context, pj *prowv1.ProwJob) (*r
 			pj.SetComplete()
 			pj.Status.State = prowv1.FailureState
 			pj.Status.Description = "Job failed."
+			retryReason = podFailureRetryReason(pod, pj.Spec.Retry)
 
 		case corev1.PodPending:
 			var requeueAfter time.Duration
@@ -550,6 +590,7 @@ func (r *reconciler) syncPendingJob(ctx context.Context, pj *prowv1.ProwJob) (*r
 					pj.SetComplete()
 					pj.Status.State = prowv1.ErrorState
 					pj.Status.Description = "Pod scheduling timeout."
+					retryReason = prowv1.RetryOnPodPendingTimeout
 					r.log.WithFields(pjutil.ProwJobFields(pj)).Info("Marked job for stale unscheduled pod as errored.")
 					if err := r.deletePod(ctx, pj); err != nil {
 						return nil, fmt.Errorf("failed to delete pod %s/%s in cluster %s: %w", pod.Namespace, pod.Name, pj.ClusterAlias(), err)
@@ -567,6 +608,7 @@ func (r *reconciler) syncPendingJob(ctx context.Context, pj *prowv1.ProwJob) (*r
 					pj.SetComplete()
 					pj.Status.State = prowv1.ErrorState
 					pj.Status.Description = "Pod pending timeout."
+					retryReason = prowv1.RetryOnPodPendingTimeout
 					r.log.WithFields(pjutil.ProwJobFields(pj)).Info("Marked job for stale pending pod as errored.")
 					if err := r.deletePod(ctx, pj); err != nil {
 						return nil, fmt.Errorf("failed to delete pod %s/%s in cluster %s: %w", pod.Namespace, pod.Name, pj.ClusterAlias(), err)
@@ -618,6 +660,13 @@ func (r *reconciler) syncPendingJob(ctx context.Context, pj *prowv1.ProwJob) (*r
 		pj.SetComplete()
 		pj.Status.State = prowv1.ErrorState
 		pj.Status.Description = "Pod got deleted unexpectedly"
+		retryReason = prowv1.RetryOnEviction
+	}
+
+	if retryReason != "" && pj.Complete() && pj.Spec.Retry.Retries(retryReason, pj.Attempt()) {
+		if err := r.retry(ctx, pj, retryReason); err != nil {
+			return nil, fmt.Errorf("failed to retry prowjob: %w", err)
+		}
 	}
 
 	pj.Status.URL, err = pjutil.JobURL(r.config().Plank, *pj, r.log)
@@ -680,6 +729,80 @@ func getPodUnexpectedStopCause(pod *corev1.Pod) PodUnexpectedStopCause {
 	return PodUnexpectedStopCauseNone
 }
 
+// podFailureRetryReason returns the class of the failure of the failed pod
+// if the policy retries it: a container that ran out of memory, or that exited
+// with one of the exit codes of the policy.
+func podFailureRetryReason(pod *corev1.Pod, policy *prowv1.RetryPolicy) prowv1.RetryReason {
+	if policy == nil {
+		return ""
+	}
+	var reason prowv1.RetryReason
+	for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		terminated := status.State.Terminated
+		if terminated == nil || terminated.ExitCode == 0 {
+			continue
+		}
+		if terminated.Reason == "OOMKilled" {
+			return prowv1.RetryOnOOMKilled
+		}
+		if policy.RetriesExitCode(terminated.ExitCode) {
+			reason = prowv1.RetryOnExitCode
+		}
+	}
+	return reason
+}
+
+// retryName returns the name of the ProwJob of the next attempt of the job.
+// It is derived from the name of the first attempt, so that creating it is
+// idempotent.
+func retryName(pj *prowv1.ProwJob) string {
+	first := pj.Name
+	if pj.Status.Retry != nil && len(pj.Status.Retry.PreviousAttempts) > 0 {
+		first = pj.Status.Retry.PreviousAttempts[0]
+	}
+	return fmt.Sprintf("%s-attempt-%d", first, pj.Attempt()+1)
+}
+
+// retry creates the next attempt of the job, which failed for the reason, and
+// records it in the status of the job. The job is patched by the caller.
+func (r *reconciler) retry(ctx context.Context, pj *prowv1.ProwJob, reason prowv1.RetryReason) error {
+	copied := pj.DeepCopy()
+	var previous []string
+	if copied.Status.Retry != nil {
+		previous = copied.Status.Retry.PreviousAttempts
+	}
+	next := prowv1.ProwJob{
+		TypeMeta: copied.TypeMeta,
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        retryName(pj),
+			Namespace:   copied.Namespace,
+			Labels:      copied.Labels,
+			Annotations: copied.Annotations,
+		},
+		Spec: copied.Spec,
+		Status: prowv1.ProwJobStatus{
+			StartTime: metav1.NewTime(r.clock.Now()),
+			State:     prowv1.TriggeredState,
+			Retry: &prowv1.RetryStatus{
+				Attempt:          pj.Attempt() + 1,
+				PreviousAttempts: append(previous, pj.Name),
+				Reason:           reason,
+			},
+		},
+	}
+	if err := r.pjClient.Create(ctx, &next); err != nil && !kerrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create prowjob %s: %w", next.Name, err)
+	}
+
+	if pj.Status.Retry == nil {
+		pj.Status.Retry = &prowv1.RetryStatus{Attempt: 1}
+	}
+	pj.Status.Retry.RetriedBy = next.Name
+	pj.Status.Description = fmt.Sprintf("%s Retrying as attempt %d of %d (%s).", pj.Status.Description, next.Status.Retry.Attempt, pj.Spec.Retry.MaxAttempts, reason)
+	r.log.WithFields(pjutil.ProwJobFields(pj)).WithField("retry", next.Name).WithField("reason", reason).Info("Retrying job.")
+	return nil
+}
+
 // syncTriggeredJob syncs jobs that do not yet have an associated test workload running
 func (r *reconciler) syncTriggeredJob(ctx context.Context, pj *prowv1.ProwJob) (*reconcile.Result, error) {
 	prevPJ := pj.DeepCopy()
@@ -704,6 +827,12 @@ func (r *reconciler) syncTriggeredJob(ctx context.Context, pj *prowv1.ProwJob) (
 			return nil, fmt.Errorf("canExecuteConcurrently: %w", err)
 		}
 		if !canExecuteConcurrently {
+			// Let users know where the job stands in the fair-share queue.
+			if pj.Status.QueuePosition != prevPJ.Status.QueuePosition {
+				if err := r.pjClient.Patch(ctx, pj.DeepCopy(), ctrlruntimeclient.MergeFrom(prevPJ)); err != nil {
+					return nil, fmt.Errorf("patch prowjob: %w", err)
+				}
+			}
 			return &reconcile.Result{RequeueAfter: 10 * time.Second}, nil
 		}
 		// We haven't started the pod yet. Do so.
@@ -726,6 +855,7 @@ func (r *reconciler) syncTriggeredJob(ctx context.Context, pj *prowv1.ProwJob) (
 		pj.Status.PendingTime = &now
 		pj.Status.State = prowv1.PendingState
 		pj.Status.PodName = pn
+		pj.Status.QueuePosition = 0
 		pj.Status.Description = "Job triggered."
 		pj.Status.URL, err = pjutil.JobURL(r.config().Plank, *pj, r.log)
 		if err != nil {
@@ -742,10 +872,11 @@ func (r *reconciler) syncTriggeredJob(ctx context.Context, pj *prowv1.ProwJob) (
 		return nil, fmt.Errorf("patch prowjob: %w", err)
 	}
 
-	// If the job has either MaxConcurrency or JobQueueName configured, we must block here until we observe the state transition in our cache,
-	// otherwise subequent reconciliations for a different run of the same job might incorrectly conclude that they
-	// can run because that decision is made based on the data in the cache.
-	if pj.Spec.MaxConcurrency == 0 && pj.Spec.JobQueueName == "" {
+	// If the job has either MaxConcurrency or JobQueueName configured or runs in a build cluster with a fair-share budget,
+	// we must block here until we observe the state transition in our cache, otherwise subequent reconciliations for a
+	// different run of the same job might incorrectly conclude that they can run because that decision is made based on
+	// the data in the cache.
+	if pj.Spec.MaxConcurrency == 0 && pj.Spec.JobQueueName == "" && r.fairShareBudget(pj) == nil {
 		return nil, nil
 	}
 	nn := types.NamespacedName{Namespace: pj.Namespace, Name: pj.Name}
@@ -897,7 +1028,11 @@ func (r *reconciler) canExecuteConcurrently(ctx context.Context, pj *prowv1.Prow
 		return canExecute, err
 	}
 
-	return r.canExecuteConcurrentlyPerQueue(ctx, pj)
+	if canExecute, err := r.canExecuteConcurrentlyPerQueue(ctx, pj); err != nil || !canExecute {
+		return canExecute, err
+	}
+
+	return r.canExecuteInFairShare(ctx, pj)
 }
 
 func (r *reconciler) canExecuteConcurrentlyPerJob(ctx context.Context, pj *prowv1.ProwJob) (bool, error) {
@@ -956,6 +1091,55 @@ func (r *reconciler) canExecuteConcurrentlyPerQueue(ctx context.Context, pj *pro
 	return true, nil
 }
 
+// fairShareBudget returns the fair-share budget of the build cluster of the
+// job, nil if its jobs aren't queued.
+func (r *reconciler) fairShareBudget(pj *prowv1.ProwJob) corev1.ResourceList {
+	fs := r.config().Plank.FairShare
+	if fs == nil {
+		return nil
+	}
+	return fs.Budgets[pj.ClusterAlias()]
+}
+
+// canExecuteInFairShare determines if the job is next in the fair-share queue
+// of its build cluster and fits in its budget, and records its position in
+// the queue otherwise.
+func (r *reconciler) canExecuteInFairShare(ctx context.Context, pj *prowv1.ProwJob) (bool, error) {
+	budget := r.fairShareBudget(pj)
+	if budget == nil {
+		return true, nil
+	}
+
+	position, err := r.fairShareQueues.position(pj.ClusterAlias(), pj.Name, r.clock.Now(), func() (map[string]int, int, error) {
+		pjs := &prowv1.ProwJobList{}
+		if err := r.pjClient.List(ctx, pjs, optPendingTriggeredJobsInCluster(pj.ClusterAlias())); err != nil {
+			return nil, 0, fmt.Errorf("failed listing prowjobs in cluster %s: %w", pj.ClusterAlias(), err)
+		}
+		var pending, triggered []prowv1.ProwJob
+		for _, item := range pjs.Items {
+			if item.Status.State == prowv1.PendingState {
+				pending = append(pending, item)
+			} else {
+				triggered = append(triggered, item)
+			}
+		}
+		return fairSharePositions(r.config().Plank.FairShare, budget, pending, triggered), len(triggered), nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	pj.Status.QueuePosition = position
+	if pj.Status.QueuePosition > 0 {
+		r.log.WithFields(pjutil.ProwJobFields(pj)).
+			Debugf("Not starting %s, it is at position %d in the fair-share queue of cluster %s",
+				pj.Spec.Job, pj.Status.QueuePosition, pj.ClusterAlias())
+		return false, nil
+	}
+
+	return true, nil
+}
+
 func prowJobPredicate(callback func(bool)) predicate.Predicate {
 	return predicate.NewPredicateFuncs(func(o ctrlruntimeclient.Object) bool {
 		result := func() bool {
@@ -1028,6 +1212,14 @@ func pendingTriggeredIndexKeyByJobQueueName(jobQueueName string) string {
 	return fmt.Sprintf("pending-triggered-with-job-queue-name-%s", jobQueueName)
 }
 
+func pendingTriggeredIndexKeyByCluster(cluster string) string {
+	return fmt.Sprintf("pending-triggered-in-cluster-%s", cluster)
+}
+
+func presubmitIndexKeyByPull(org, repo string, number int) string {
+	return fmt.Sprintf("presubmit-for-pull-%s/%s#%d", org, repo, number)
+}
+
 func prowJobIndexer(prowJobNamespace string) ctrlruntimeclient.IndexerFunc {
 	return func(o ctrlruntimeclient.Object) []string {
 		pj := o.(*prowv1.ProwJob)
@@ -1047,6 +1239,14 @@ func prowJobIndexer(prowJobNamespace string) ctrlruntimeclient.IndexerFunc {
 			if pj.Spec.JobQueueName != "" {
 				indexes = append(indexes, pendingTriggeredIndexKeyByJobQueueName(pj.Spec.JobQueueName))
 			}
+
+			indexes = append(indexes, pendingTriggeredIndexKeyByCluster(pj.ClusterAlias()))
+		}
+
+		if pj.Spec.Type == prowv1.PresubmitJob && pj.Spec.Refs != nil {
+			for _, pull := range pj.Spec.Refs.Pulls {
+				indexes = append(indexes, presubmitIndexKeyByPull(pj.Spec.Refs.Org, pj.Spec.Refs.Repo, pull.Number))
+			}
 		}
 
 		return indexes
@@ -1069,6 +1269,14 @@ func optPendingTriggeredJobsInQueue(queueName string) ctrlruntimeclient.ListOpti
 	return ctrlruntimeclient.MatchingFields{prowJobIndexName: pendingTriggeredIndexKeyByJobQueueName(queueName)}
 }
 
+func optPendingTriggeredJobsInCluster(cluster string) ctrlruntimeclient.ListOption {
+	return ctrlruntimeclient.MatchingFields{prowJobIndexName: pendingTriggeredIndexKeyByCluster(cluster)}
+}
+
+func optPresubmitsForPull(org, repo string, number int) ctrlruntimeclient.ListOption {
+	return ctrlruntimeclient.MatchingFields{prowJobIndexName: presubmitIndexKeyByPull(org, repo, number)}
+}
+
 func didPodSucceed(p *corev1.Pod) bool {
 	if p.Status.Phase != corev1.PodSucceeded {
 		return false
@@ -179,7 +179,7 @@ func TestAdd(t *testing.T) {
 				predicateResultChan <- !b
 			}
 			var errMsg string
-			if err := add(mgr, buildMgrs, nil, cfg, nil, "", tc.additionalSelector, reconcile, predicateCallBack, 1); err != nil {
+			if err := add(mgr, buildMgrs, nil, cfg, nil, "", tc.additionalSelector, nil, reconcile, predicateCallBack, 1); err != nil {
 				errMsg = err.Error()
 			}
 			if errMsg != tc.expectedError {
@@ -327,6 +327,7 @@ func TestProwJobIndexer(t *testing.T) {
 				prowJobIndexKeyPending,
 				pendingTriggeredIndexKeyByName(pjName),
 				pendingTriggeredIndexKeyByJobQueueName(pjJobQueue),
+				pendingTriggeredIndexKeyByCluster(prowv1.DefaultClusterAlias),
 			},
 		},
 		{
@@ -336,6 +337,34 @@ func TestProwJobIndexer(t *testing.T) {
 				prowJobIndexKeyAll,
 				pendingTriggeredIndexKeyByName(pjName),
 				pendingTriggeredIndexKeyByJobQueueName(pjJobQueue),
+				pendingTriggeredIndexKeyByCluster(prowv1.DefaultClusterAlias),
+			},
+		},
+		{
+			name:   "Changing cluster changes pendingTriggeredIndexKeyByCluster index",
+			modify: func(pj *prowv1.ProwJob) { pj.Spec.Cluster = "build-1" },
+			expected: []string{
+				prowJobIndexKeyAll,
+				prowJobIndexKeyPending,
+				pendingTriggeredIndexKeyByName(pjName),
+				pendingTriggeredIndexKeyByJobQueueName(pjJobQueue),
+				pendingTriggeredIndexKeyByCluster("build-1"),
+			},
+		},
+		{
+			name: "Presubmit goes into the index of its pull requests",
+			modify: func(pj *prowv1.ProwJob) {
+				pj.Spec.Type = prowv1.PresubmitJob
+				pj.Spec.Refs = &prowv1.Refs{Org: "org", Repo: "repo", Pulls: []prowv1.Pull{{Number: 1}, {Number: 2}}}
+			},
+			expected: []string{
+				prowJobIndexKeyAll,
+				prowJobIndexKeyPending,
+				pendingTriggeredIndexKeyByName(pjName),
+				pendingTriggeredIndexKeyByJobQueueName(pjJobQueue),
+				pendingTriggeredIndexKeyByCluster(prowv1.DefaultClusterAlias),
+				presubmitIndexKeyByPull("org", "repo", 1),
+				presubmitIndexKeyByPull("org", "repo", 2),
 			},
 		},
 		{
@@ -359,6 +388,7 @@ func TestProwJobIndexer(t *testing.T) {
 				prowJobIndexKeyPending,
 				pendingTriggeredIndexKeyByName("some-name"),
 				pendingTriggeredIndexKeyByJobQueueName(pjJobQueue),
+				pendingTriggeredIndexKeyByCluster(prowv1.DefaultClusterAlias),
 			},
 		},
 		{
@@ -369,6 +399,7 @@ func TestProwJobIndexer(t *testing.T) {
 				prowJobIndexKeyPending,
 				pendingTriggeredIndexKeyByName(pjName),
 				pendingTriggeredIndexKeyByJobQueueName("some-name"),
+				pendingTriggeredIndexKeyByCluster(prowv1.DefaultClusterAlias),
 			},
 		},
 	}
@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
 	"path"
 	"reflect"
 	"regexp"
@@ -77,6 +78,7 @@ type Configuration struct {
 	CherryPickUnapproved CherryPickUnapproved         `json:"cherry_pick_unapproved,omitempty"`
 	ConfigUpdater        ConfigUpdater                `json:"config_updater,omitempty"`
 	Dco                  map[string]*Dco              `json:"dco,omitempty"`
+	Flakes               Flakes                       `json:"flakes,omitempty"`
 	Golint               Golint                       `json:"golint,omitempty"`
 	Goose                Goose                        `json:"goose,omitempty"`
 	Heart                Heart                        `json:"heart,omitempty"`
@@ -413,6 +415,14 @@ type Goose struct {
 	KeyPath string `json:"key_path,omitempty"`
 }
 
+// Flakes contains the configuration for the flakes plugin.
+type Flakes struct {
+	// DeckURL is the root URL of the Deck instance with flaky test detection
+	// enabled, e.g. https://prow.k8s.io/. Only the statuses whose target URL
+	// is a Spyglass view of this Deck are considered.
+	DeckURL string `json:"deck_url,omitempty"`
+}
+
 // Label contains the configuration for the label plugin.
 type Label struct {
 	// AdditionalLabels is a set of additional labels enabled for use
@@ -1260,6 +1270,20 @@ type ConfigMapID struct {
 	Name, Namespace, Cluster string
 }
 
+func validateFlakes(f Flakes) error {
+	if f.DeckURL == "" {
+		return nil
+	}
+	u, err := url.Parse(f.DeckURL)
+	if err != nil {
+		return fmt.Errorf("invalid flakes.deck_url: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid flakes.deck_url %q: it must be an absolute URL", f.DeckURL)
+	}
+	return nil
+}
+
 func validateConfigUpdater(updater *ConfigUpdater) error {
 	updater.SetDefaults()
 	configMapKeys := map[ConfigMapID]sets.Set[string]{}
@@ -1480,6 +1504,9 @@ func (c *Configuration) Validate() error {
 	if err := validateConfigUpdater(&c.ConfigUpdater); err != nil {
 		return err
 	}
+	if err := validateFlakes(c.Flakes); err != nil {
+		return err
+	}
 	if err := validateSizes(c.Size); err != nil {
 		return err
 	}
@@ -0,0 +1,161 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package flakes comments on pull requests whose job failed only on tests
+// known to be flaky, as reported by Deck.
+package flakes
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/flakes"
+	"sigs.k8s.io/prow/pkg/github"
+	"sigs.k8s.io/prow/pkg/pluginhelp"
+	"sigs.k8s.io/prow/pkg/plugins"
+)
+
+const pluginName = "flakes"
+
+func init() {
+	plugins.RegisterStatusEventHandler(pluginName, handleStatusEvent, helpProvider)
+}
+
+func helpProvider(config *plugins.Configuration, _ []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
+	yamlSnippet, err := plugins.CommentMap.GenYaml(&plugins.Configuration{
+		Flakes: plugins.Flakes{
+			DeckURL: "https://prow.k8s.io/",
+		},
+	})
+	if err != nil {
+		logrus.WithError(err).Warnf("cannot generate comments for %s plugin", pluginName)
+	}
+	// The {WhoCanUse, Usage, Examples} fields are omitted because this plugin cannot be
+	// manually triggered.
+	return &pluginhelp.PluginHelp{
+		Description: "The flakes plugin comments on pull requests when a job fails only on tests that Deck reports as known-flaky for the job. It requires flaky test detection to be enabled in Deck (deck.flakes).",
+		Config: map[string]string{
+			"": fmt.Sprintf("The flakes plugin gets the known flakes from the Deck at %q.", config.Flakes.DeckURL),
+		},
+		Snippet: yamlSnippet,
+	}, nil
+}
+
+type githubClient interface {
+	CreateComment(owner, repo string, number int, comment string) error
+	ListIssueComments(org, repo string, number int) ([]github.IssueComment, error)
+}
+
+type flakesClient interface {
+	RunFailures(job, buildID string) (*flakes.RunFailures, error)
+}
+
+// newFlakesClient returns a client of the /flakes.js endpoint of Deck.
+var newFlakesClient = func(endpoint string) flakesClient {
+	return flakes.NewClient(endpoint, time.Minute)
+}
+
+func handleStatusEvent(pc plugins.Agent, se github.StatusEvent) error {
+	return handle(pc.GitHubClient, pc.Logger, pc.PluginConfig.Flakes.DeckURL, se)
+}
+
+// parseTargetURL returns the job and build of the target URL when it is a
+// Spyglass view of the Deck at deckURL. Spyglass views end with .../<job>/<build>.
+func parseTargetURL(deckURL, target string) (job, build string, ok bool) {
+	deck, err := url.Parse(deckURL)
+	if err != nil || deck.Host == "" {
+		return "", "", false
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != deck.Scheme || u.Host != deck.Host {
+		return "", "", false
+	}
+	view, found := strings.CutPrefix(u.Path, strings.TrimSuffix(deck.Path, "/")+"/view/")
+	if !found {
+		return "", "", false
+	}
+	parts := strings.Split(strings.Trim(view, "/"), "/")
+	if len(parts) < 3 {
+		return "", "", false
+	}
+	return parts[len(parts)-2], parts[len(parts)-1], true
+}
+
+// flakesEndpoint returns the /flakes.js endpoint of the Deck at deckURL.
+func flakesEndpoint(deckURL string) string {
+	return strings.TrimSuffix(deckURL, "/") + "/flakes.js"
+}
+
+func marker(job, build string) string {
+	return fmt.Sprintf("<!-- flakes: %s/%s -->", job, build)
+}
+
+func handle(gc githubClient, log *logrus.Entry, deckURL string, se github.StatusEvent) error {
+	if se.State != github.StatusFailure && se.State != github.StatusError {
+		return nil
+	}
+	if deckURL == "" {
+		log.Debug("No Deck URL is configured for the flakes plugin.")
+		return nil
+	}
+	job, build, ok := parseTargetURL(deckURL, se.TargetURL)
+	if !ok {
+		return nil
+	}
+	log = log.WithFields(logrus.Fields{"job": job, "build": build})
+
+	failures, err := newFlakesClient(flakesEndpoint(deckURL)).RunFailures(job, build)
+	if err != nil {
+		// The target may not be served by a Deck with flaky test detection.
+		log.WithError(err).Debug("Failed to get the failures of the job.")
+		return nil
+	}
+	if !failures.AllKnownFlakes() {
+		return nil
+	}
+	refs := failures.Refs
+	if refs == nil || len(refs.Pulls) != 1 {
+		return nil
+	}
+	org, repo, pull := se.Repo.Owner.Login, se.Repo.Name, refs.Pulls[0]
+	if refs.Org != org || refs.Repo != repo || pull.SHA != se.SHA {
+		return nil
+	}
+
+	comments, err := gc.ListIssueComments(org, repo, pull.Number)
+	if err != nil {
+		return fmt.Errorf("failed to list the comments of %s/%s#%d: %w", org, repo, pull.Number, err)
+	}
+	for _, comment := range comments {
+		if strings.Contains(comment.Body, marker(job, build)) {
+			return nil
+		}
+	}
+
+	var tests []string
+	for _, failure := range failures.Failures {
+		tests = append(tests, fmt.Sprintf("- `%s` flaked on %d revisions recently", failure.Test, failure.Flakes))
+	}
+	message := fmt.Sprintf("[%s](%s) failed only on tests known to be flaky for this job:\n%s\n\nYou can run it again with `/retest`.",
+		job, se.TargetURL, strings.Join(tests, "\n"))
+	log.WithField("pr", pull.Number).Info("Commenting on known flakes.")
+	return gc.CreateComment(org, repo, pull.Number, plugins.FormatSimpleResponse(message)+"\n"+marker(job, build))
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flakes
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/flakes"
+	"sigs.k8s.io/prow/pkg/github"
+	"sigs.k8s.io/prow/pkg/github/fakegithub"
+)
+
+type fakeFlakesClient struct {
+	endpoint string
+	failures map[string]*flakes.RunFailures
+}
+
+func (c *fakeFlakesClient) RunFailures(job, buildID string) (*flakes.RunFailures, error) {
+	failures, ok := c.failures[job+"/"+buildID]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return failures, nil
+}
+
+func TestParseTargetURL(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		deckURL   string
+		target    string
+		wantJob   string
+		wantBuild string
+		wantOK    bool
+	}{
+		{
+			name:      "spyglass view",
+			deckURL:   "https://prow.k8s.io/",
+			target:    "https://prow.k8s.io/view/gs/bucket/pr-logs/pull/org_repo/1/pull-job/123",
+			wantJob:   "pull-job",
+			wantBuild: "123",
+			wantOK:    true,
+		},
+		{
+			name:      "deck served under a path",
+			deckURL:   "https://example.com/prow",
+			target:    "https://example.com/prow/view/gs/bucket/logs/job/123/",
+			wantJob:   "job",
+			wantBuild: "123",
+			wantOK:    true,
+		},
+		{
+			name:    "spyglass view of another host",
+			deckURL: "https://prow.k8s.io/",
+			target:  "https://attacker.example.com/view/gs/bucket/logs/job/123",
+		},
+		{
+			name:    "another scheme",
+			deckURL: "https://prow.k8s.io/",
+			target:  "http://prow.k8s.io/view/gs/bucket/logs/job/123",
+		},
+		{
+			name:    "outside of the deck path",
+			deckURL: "https://example.com/prow",
+			target:  "https://example.com/other/view/gs/bucket/logs/job/123",
+		},
+		{
+			name:    "not a spyglass view",
+			deckURL: "https://ci.example.com/",
+			target:  "https://ci.example.com/job/123",
+		},
+		{
+			name:    "no target",
+			deckURL: "https://prow.k8s.io/",
+		},
+		{
+			name:   "no deck",
+			target: "https://prow.k8s.io/view/gs/bucket/pr-logs/pull/org_repo/1/pull-job/123",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			job, build, ok := parseTargetURL(tc.deckURL, tc.target)
+			if ok != tc.wantOK || job != tc.wantJob || build != tc.wantBuild {
+				t.Errorf("Expected (%q, %q, %t), got (%q, %q, %t)", tc.wantJob, tc.wantBuild, tc.wantOK, job, build, ok)
+			}
+		})
+	}
+}
+
+func TestHandle(t *testing.T) {
+	refs := &prowapi.Refs{Org: "org", Repo: "repo", Pulls: []prowapi.Pull{{Number: 1, SHA: "head"}}}
+	knownFlakes := &flakes.RunFailures{Job: "pull-job", BuildID: "1", Refs: refs, Failures: []flakes.Failure{{Test: "TestA", KnownFlake: true, Flakes: 3}}}
+	for _, tc := range []struct {
+		name        string
+		state       string
+		sha         string
+		deckURL     string
+		noDeck      bool
+		failures    *flakes.RunFailures
+		comments    []github.IssueComment
+		wantComment bool
+	}{
+		{
+			name:        "failure on known flakes",
+			state:       github.StatusFailure,
+			failures:    knownFlakes,
+			wantComment: true,
+		},
+		{
+			name:     "success",
+			state:    github.StatusSuccess,
+			failures: knownFlakes,
+		},
+		{
+			name:  "failure on a test that is not a known flake",
+			state: github.StatusFailure,
+			failures: &flakes.RunFailures{Job: "pull-job", BuildID: "1", Refs: refs, Failures: []flakes.Failure{
+				{Test: "TestA", KnownFlake: true, Flakes: 3},
+				{Test: "TestB"},
+			}},
+		},
+		{
+			name:  "failure without failed tests",
+			state: github.StatusFailure,
+			failures: &flakes.RunFailures{Job: "pull-job", BuildID: "1", Refs: refs, Failures: []flakes.Failure{
+				{Test: "TestB"},
+			}},
+		},
+		{
+			name:  "results unavailable",
+			state: github.StatusFailure,
+		},
+		{
+			name:     "no deck configured",
+			state:    github.StatusFailure,
+			noDeck:   true,
+			failures: knownFlakes,
+		},
+		{
+			name:     "target served by another deck",
+			state:    github.StatusFailure,
+			deckURL:  "https://other.example.com/",
+			failures: knownFlakes,
+		},
+		{
+			name:     "status of an older head",
+			state:    github.StatusFailure,
+			sha:      "old",
+			failures: knownFlakes,
+		},
+		{
+			name:     "already commented",
+			state:    github.StatusFailure,
+			failures: knownFlakes,
+			comments: []github.IssueComment{{Body: "... " + marker("pull-job", "1")}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := &fakeFlakesClient{failures: map[string]*flakes.RunFailures{}}
+			if tc.failures != nil {
+				fc.failures["pull-job/1"] = tc.failures
+			}
+			newFlakesClient = func(endpoint string) flakesClient {
+				fc.endpoint = endpoint
+				return fc
+			}
+			gc := fakegithub.NewFakeClient()
+			gc.IssueComments = map[int][]github.IssueComment{1: tc.comments}
+			sha := tc.sha
+			if sha == "" {
+				sha = "head"
+			}
+			se := github.StatusEvent{
+				State:     tc.state,
+				SHA:       sha,
+				Context:   "pull-job",
+				TargetURL: "https://prow.k8s.io/view/gs/bucket/pr-logs/pull/org_repo/1/pull-job/1",
+				Repo:      github.Repo{Owner: github.User{Login: "org"}, Name: "repo"},
+			}
+
+			deckURL := tc.deckURL
+			if deckURL == "" && !tc.noDeck {
+				deckURL = "https://prow.k8s.io/"
+			}
+
+			if err := handle(gc, logrus.WithField("plugin", pluginName), deckURL, se); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := len(gc.IssueCommentsAdded) > 0; got != tc.wantComment {
+				t.Fatalf("Expected comment: %t, got comments %v", tc.wantComment, gc.IssueCommentsAdded)
+			}
+			if tc.wantComment {
+				if fc.endpoint != "https://prow.k8s.io/flakes.js" {
+					t.Errorf("Unexpected endpoint %q", fc.endpoint)
+				}
+				if comment := gc.IssueCommentsAdded[0]; !strings.HasPrefix(comment, "org/repo#1:") || !strings.Contains(comment, "`TestA` flaked on 3 revisions") {
+					t.Errorf("Unexpected comment %q", comment)
+				}
+			}
+		})
+	}
+}
@@ -64,6 +64,7 @@ var (
 	reviewEventHandlers        = map[string]ReviewEventHandler{}
 	reviewCommentEventHandlers = map[string]ReviewCommentEventHandler{}
 	statusEventHandlers        = map[string]StatusEventHandler{}
+	checkRunEventHandlers      = map[string]CheckRunEventHandler{}
 	// CommentMap is used by many plugins for printing help messages defined in
 	// config.go.
 	CommentMap, _ = genyaml.NewCommentMap(func(dir string) (string, error) { return "", nil }, nil)
@@ -143,6 +144,15 @@ func RegisterStatusEventHandler(name string, fn StatusEventHandler, help HelpPro
 	statusEventHandlers[name] = fn
 }
 
+// CheckRunEventHandler defines the function contract for a github.CheckRunEvent handler.
+type CheckRunEventHandler func(Agent, github.CheckRunEvent) error
+
+// RegisterCheckRunEventHandler registers a plugin's github.CheckRunEvent handler.
+func RegisterCheckRunEventHandler(name string, fn CheckRunEventHandler, help HelpProvider) {
+	pluginHelp[name] = help
+	checkRunEventHandlers[name] = fn
+}
+
 // PushEventHandler defines the function contract for a github.PushEvent handler.
 type PushEventHandler func(Agent, github.PushEvent) error
 
@@ -239,6 +249,19 @@ func NewAgent(configAgent *config.Agent, pluginConfigAgent *ConfigAgent, clientA
 	}
 }
 
+// WithContext makes the requests of the GitHub client of the agent that don't
+// take a context part of ctx, e.g. of the trace of the event being handled.
+func (a *Agent) WithContext(ctx context.Context) {
+	c, ok := a.GitHubClient.(*githubV4OrgAddingWrapper)
+	if !ok {
+		return
+	}
+	a.GitHubClient = &githubV4OrgAddingWrapper{org: c.org, Client: c.Client.WithContext(ctx)}
+	if a.OwnersClient != nil {
+		a.OwnersClient = a.OwnersClient.WithGitHubClient(a.GitHubClient)
+	}
+}
+
 // InitializeCommentPruner attaches a commentpruner.EventClient to the agent to handle
 // pruning comments.
 func (a *Agent) InitializeCommentPruner(org, repo string, pr int) {
@@ -512,6 +535,21 @@ func (pa *ConfigAgent) StatusEventHandlers(owner, repo string) map[string]Status
 	return hs
 }
 
+// CheckRunEventHandlers returns a map of plugin names to handlers for the repo.
+func (pa *ConfigAgent) CheckRunEventHandlers(owner, repo string) map[string]CheckRunEventHandler {
+	pa.mut.Lock()
+	defer pa.mut.Unlock()
+
+	hs := map[string]CheckRunEventHandler{}
+	for _, p := range pa.getPlugins(owner, repo) {
+		if h, ok := checkRunEventHandlers[p]; ok {
+			hs[p] = h
+		}
+	}
+
+	return hs
+}
+
 // PushEventHandlers returns a map of plugin names to handlers for the repo.
 func (pa *ConfigAgent) PushEventHandlers(owner, repo string) map[string]PushEventHandler {
 	pa.mut.Lock()
@@ -564,6 +602,9 @@ func EventsForPlugin(name string) []string {
 	if _, ok := statusEventHandlers[name]; ok {
 		events = append(events, "status")
 	}
+	if _, ok := checkRunEventHandlers[name]; ok {
+		events = append(events, "check_run")
+	}
 	if _, ok := genericCommentHandlers[name]; ok {
 		events = append(events, "GenericCommentEvent (any event for user text)")
 	}
@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/github"
+	"sigs.k8s.io/prow/pkg/github/report"
+	"sigs.k8s.io/prow/pkg/pjutil"
+	"sigs.k8s.io/prow/pkg/plugins"
+)
+
+// handleCR re-runs the ProwJob of the check run when its re-run action is
+// requested, crier sets the name of the ProwJob as the external ID of the
+// check runs it reports.
+func handleCR(c Client, trigger plugins.Trigger, ce github.CheckRunEvent) error {
+	if ce.Action != github.CheckRunActionRequestedAction || ce.RequestedAction == nil || ce.RequestedAction.Identifier != report.RerunActionIdentifier {
+		return nil
+	}
+	name := ce.CheckRun.ExternalID
+	if name == "" {
+		return nil
+	}
+	org := ce.Repo.Owner.Login
+	repo := ce.Repo.Name
+	user := ce.Sender.Login
+
+	pj, err := c.ProwJobClient.Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			c.Logger.Infof("Not re-running ProwJob %s, it was not found.", name)
+			return nil
+		}
+		return fmt.Errorf("failed to get ProwJob %s: %w", name, err)
+	}
+	// Other apps can set the same external IDs, only ever re-run the jobs
+	// of the repo of the check run.
+	refs := pj.Spec.Refs
+	if refs == nil || refs.Org != org || refs.Repo != repo {
+		c.Logger.Infof("Not re-running ProwJob %s, it doesn't belong to %s/%s.", name, org, repo)
+		return nil
+	}
+
+	if pj.Spec.Type == prowapi.PresubmitJob && len(refs.Pulls) == 1 {
+		pr, err := c.GitHubClient.GetPullRequest(org, repo, refs.Pulls[0].Number)
+		if err != nil {
+			return err
+		}
+		if pr.State != github.PullRequestStateOpen || pr.Head.SHA != refs.Pulls[0].SHA {
+			c.Logger.Infof("Not re-running ProwJob %s, the pull request was closed or updated since.", name)
+			return nil
+		}
+	}
+
+	allowed, err := canRerun(c, trigger, user, pj)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		c.Logger.Infof("Not re-running ProwJob %s, %s is not allowed to.", name, user)
+		return nil
+	}
+
+	newPJ := pjutil.NewProwJob(pj.Spec, pj.Labels, pj.Annotations, pjutil.RequireScheduling(c.Config.Scheduler.Enabled))
+	newPJ.Labels[github.EventGUID] = ce.GUID
+	c.Logger.WithFields(pjutil.ProwJobFields(&newPJ)).Info("Creating a new prowjob.")
+	return createWithRetry(context.TODO(), c.ProwJobClient, &newPJ)
+}
+
+// canRerun returns whether the user is allowed to re-run the ProwJob, with the
+// same rules as the re-run button of deck: the user is either authorized by
+// the rerun auth configs or could trigger the job with a comment.
+func canRerun(c Client, trigger plugins.Trigger, user string, pj *prowapi.ProwJob) (bool, error) {
+	org := pj.Spec.Refs.Org
+	repo := pj.Spec.Refs.Repo
+	for _, rac := range []*prowapi.RerunAuthConfig{c.Config.Deck.GetRerunAuthConfig(&pj.Spec), pj.Spec.RerunAuthConfig} {
+		if allowed, err := rac.IsAuthorized(org, user, c.GitHubClient); err != nil {
+			return false, err
+		} else if allowed {
+			return true, nil
+		}
+	}
+
+	if pj.Spec.Type == prowapi.PresubmitJob && len(pj.Spec.Refs.Pulls) == 1 {
+		_, allowed, err := TrustedPullRequest(c.GitHubClient, trigger, user, org, repo, pj.Spec.Refs.Pulls[0].Number, nil)
+		return allowed, err
+	}
+	trusted, err := TrustedUser(c.GitHubClient, trigger.OnlyOrgMembers, trigger.TrustedApps, trigger.TrustedOrg, user, org, repo)
+	return trusted.IsTrusted, err
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clienttesting "k8s.io/client-go/testing"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/client/clientset/versioned/fake"
+	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/github"
+	"sigs.k8s.io/prow/pkg/github/fakegithub"
+	"sigs.k8s.io/prow/pkg/plugins"
+)
+
+func TestHandleCR(t *testing.T) {
+	rerun := func(externalID, sender string) github.CheckRunEvent {
+		return github.CheckRunEvent{
+			Action:          github.CheckRunActionRequestedAction,
+			CheckRun:        github.CheckRun{ExternalID: externalID},
+			RequestedAction: &github.CheckRunRequestedAction{Identifier: "rerun"},
+			Repo:            github.Repo{Owner: github.User{Login: "org"}, Name: "repo"},
+			Sender:          github.User{Login: sender},
+			GUID:            "guid",
+		}
+	}
+	presubmit := func(sha string) *prowapi.ProwJob {
+		return &prowapi.ProwJob{
+			ObjectMeta: metav1.ObjectMeta{Name: "pj", Namespace: "prowjobs"},
+			Spec: prowapi.ProwJobSpec{
+				Type: prowapi.PresubmitJob,
+				Job:  "pull-unit",
+				Refs: &prowapi.Refs{
+					Org:   "org",
+					Repo:  "repo",
+					Pulls: []prowapi.Pull{{Number: 1, SHA: sha, Author: "author"}},
+				},
+			},
+			Status: prowapi.ProwJobStatus{State: prowapi.FailureState},
+		}
+	}
+	postsubmit := &prowapi.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "pj", Namespace: "prowjobs"},
+		Spec: prowapi.ProwJobSpec{
+			Type: prowapi.PostsubmitJob,
+			Job:  "post-unit",
+			Refs: &prowapi.Refs{Org: "org", Repo: "repo", BaseSHA: "base"},
+		},
+		Status: prowapi.ProwJobStatus{State: prowapi.FailureState},
+	}
+
+	testCases := []struct {
+		name     string
+		event    github.CheckRunEvent
+		pj       *prowapi.ProwJob
+		expected bool
+	}{
+		{
+			name:     "member re-runs a presubmit",
+			event:    rerun("pj", "member"),
+			pj:       presubmit("head"),
+			expected: true,
+		},
+		{
+			name:  "outsider can't re-run a presubmit",
+			event: rerun("pj", "outsider"),
+			pj:    presubmit("head"),
+		},
+		{
+			name:  "presubmit of an outdated commit is not re-run",
+			event: rerun("pj", "member"),
+			pj:    presubmit("old"),
+		},
+		{
+			name:     "member re-runs a postsubmit",
+			event:    rerun("pj", "member"),
+			pj:       postsubmit,
+			expected: true,
+		},
+		{
+			name:  "unknown ProwJob is ignored",
+			event: rerun("other", "member"),
+			pj:    postsubmit,
+		},
+		{
+			name: "other actions are ignored",
+			event: func() github.CheckRunEvent {
+				e := rerun("pj", "member")
+				e.Action = github.CheckRunActionRerequested
+				return e
+			}(),
+			pj: postsubmit,
+		},
+		{
+			name: "ProwJob of another repo is not re-run",
+			event: func() github.CheckRunEvent {
+				e := rerun("pj", "member")
+				e.Repo.Name = "fork"
+				return e
+			}(),
+			pj: postsubmit,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := fakegithub.NewFakeClient()
+			g.OrgMembers["org"] = []string{"member"}
+			g.PullRequests[1] = &github.PullRequest{Number: 1, State: github.PullRequestStateOpen, Head: github.PullRequestBranch{SHA: "head"}}
+			fakeProwJobClient := fake.NewSimpleClientset(tc.pj)
+			c := Client{
+				GitHubClient:  g,
+				ProwJobClient: fakeProwJobClient.ProwV1().ProwJobs("prowjobs"),
+				Config:        &config.Config{ProwConfig: config.ProwConfig{ProwJobNamespace: "prowjobs"}},
+				Logger:        logrus.WithField("plugin", PluginName),
+			}
+			if err := handleCR(c, plugins.Trigger{}, tc.event); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var created *prowapi.ProwJob
+			for _, action := range fakeProwJobClient.Fake.Actions() {
+				if create, ok := action.(clienttesting.CreateActionImpl); ok {
+					created = create.GetObject().(*prowapi.ProwJob)
+				}
+			}
+			if (created != nil) != tc.expected {
+				t.Fatalf("expected a re-run: %t, got %v", tc.expected, created)
+			}
+			if created == nil {
+				return
+			}
+			if created.Spec.Job != tc.pj.Spec.Job || created.Name == tc.pj.Name {
+				t.Errorf("expected a new ProwJob of %s, got %s named %s", tc.pj.Spec.Job, created.Spec.Job, created.Name)
+			}
+			if guid := created.Labels[github.EventGUID]; guid != "guid" {
+				t.Errorf("expected the event GUID label, got %q", guid)
+			}
+			if _, err := fakeProwJobClient.ProwV1().ProwJobs("prowjobs").Get(context.Background(), created.Name, metav1.GetOptions{}); err != nil {
+				t.Errorf("re-run wasn't created: %v", err)
+			}
+		})
+	}
+}
@@ -75,6 +75,7 @@ func init() {
 	plugins.RegisterGenericCommentHandler(PluginName, handleGenericCommentEvent, helpProvider)
 	plugins.RegisterPullRequestHandler(PluginName, handlePullRequest, helpProvider)
 	plugins.RegisterPushEventHandler(PluginName, handlePush, helpProvider)
+	plugins.RegisterCheckRunEventHandler(PluginName, handleCheckRun, helpProvider)
 }
 
 func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo) (*pluginhelp.PluginHelp, error) {
@@ -110,7 +111,8 @@ func helpProvider(config *plugins.Configuration, enabledRepos []config.OrgRepo)
 <br>Trigger will not automatically start jobs for a PR in draft state, and if a PR is changed to draft it cancels pending jobs.
 <br>If jobs are not run automatically for a PR because it is not trusted or is in draft state, a trusted user can still start jobs manually via the '/test' command.
 <br>The '/retest' command can be used to rerun jobs that have reported failure.
-<br>Trigger starts postsubmit jobs when commits are pushed if the filters on the job match files and branches affected by that push.`,
+<br>Trigger starts postsubmit jobs when commits are pushed if the filters on the job match files and branches affected by that push.
+<br>When crier reports jobs as check runs, their 'Re-run' button reruns the job for the users allowed to rerun it from Deck or to trigger it with a comment.`,
 		Config:  configInfo,
 		Snippet: yamlSnippet,
 	}
@@ -164,6 +166,8 @@ type githubClient interface {
 	TriggerFailedGitHubWorkflow(org, repo string, id int) error
 	DeleteStaleComments(org, repo string, number int, comments []github.IssueComment, isStale func(github.IssueComment) bool) error
 	GetIssueLabels(org, repo string, number int) ([]github.Label, error)
+	TeamBySlugHasMember(org string, teamSlug string, memberLogin string) (bool, error)
+	TeamHasMember(org string, teamID int, memberLogin string) (bool, error)
 }
 
 type trustedPullRequestClient interface {
@@ -173,6 +177,7 @@ type trustedPullRequestClient interface {
 
 type prowJobClient interface {
 	Create(context.Context, *prowapi.ProwJob, metav1.CreateOptions) (*prowapi.ProwJob, error)
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*prowapi.ProwJob, error)
 	List(ctx context.Context, opts metav1.ListOptions) (*prowapi.ProwJobList, error)
 	Update(context.Context, *prowapi.ProwJob, metav1.UpdateOptions) (*prowapi.ProwJob, error)
 }
@@ -218,6 +223,10 @@ func handlePush(pc plugins.Agent, pe github.PushEvent) error {
 	return handlePE(getClient(pc), pe)
 }
 
+func handleCheckRun(pc plugins.Agent, ce github.CheckRunEvent) error {
+	return handleCR(getClient(pc), pc.PluginConfig.TriggerFor(ce.Repo.Owner.Login, ce.Repo.Name), ce)
+}
+
 // TrustedUserResponse is a response from TrustedUser. It contains the boolean response for trust as well
 // a reason for denial if the user is not trusted.
 type TrustedUserResponse struct {
@@ -22,6 +22,7 @@ import (
 	"os"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/GoogleCloudPlatform/testgrid/metadata"
@@ -46,6 +47,9 @@ type JobSpec struct {
 
 	DecorationConfig *prowapi.DecorationConfig `json:"decoration_config,omitempty"`
 
+	// Upstream are the runs of the jobs this job runs after the success of
+	Upstream []prowapi.UpstreamJob `json:"upstream,omitempty"`
+
 	// we need to keep track of the agent until we
 	// migrate everyone away from using the $BUILD_NUMBER
 	// environment variable
@@ -62,6 +66,7 @@ func NewJobSpec(spec prowapi.ProwJobSpec, buildID, prowJobID string) JobSpec {
 		Refs:             spec.Refs,
 		ExtraRefs:        spec.ExtraRefs,
 		DecorationConfig: spec.DecorationConfig,
+		Upstream:         spec.Upstream,
 		agent:            spec.Agent,
 	}
 }
@@ -105,6 +110,10 @@ const (
 	PullPullShaEnv = "PULL_PULL_SHA"
 	PullHeadRefEnv = "PULL_HEAD_REF"
 	PullTitleEnv   = "PULL_TITLE"
+
+	// UpstreamArtifactsEnv lists the artifacts of the upstream runs as
+	// space-separated job=url pairs.
+	UpstreamArtifactsEnv = "UPSTREAM_ARTIFACTS"
 )
 
 // EnvForSpec returns a mapping of environment variables
@@ -125,6 +134,14 @@ func EnvForSpec(spec JobSpec) (map[string]string, error) {
 		env[ProwBuildIDEnv] = spec.BuildID
 	}
 
+	if len(spec.Upstream) > 0 {
+		var artifacts []string
+		for _, upstream := range spec.Upstream {
+			artifacts = append(artifacts, fmt.Sprintf("%s=%s", upstream.Job, upstream.ArtifactsURL))
+		}
+		env[UpstreamArtifactsEnv] = strings.Join(artifacts, " ")
+	}
+
 	raw, err := json.Marshal(spec)
 	if err != nil {
 		return env, fmt.Errorf("failed to marshal job spec: %w", err)
@@ -155,7 +172,7 @@ func EnvForSpec(spec JobSpec) (map[string]string, error) {
 
 // EnvForType returns the slice of environment variables to export for jobType
 func EnvForType(jobType prowapi.ProwJobType) []string {
-	baseEnv := []string{CI, JobNameEnv, JobSpecEnv, JobTypeEnv, ProwJobIDEnv, BuildIDEnv, ProwBuildIDEnv}
+	baseEnv := []string{CI, JobNameEnv, JobSpecEnv, JobTypeEnv, ProwJobIDEnv, BuildIDEnv, ProwBuildIDEnv, UpstreamArtifactsEnv}
 	refsEnv := []string{RepoOwnerEnv, RepoNameEnv, PullBaseRefEnv, PullBaseShaEnv, PullRefsEnv}
 	pullEnv := []string{PullNumberEnv, PullPullShaEnv, PullHeadRefEnv, PullTitleEnv}
 
@@ -47,6 +47,28 @@ func TestEnvironmentForSpec(t *testing.T) {
 				"JOB_SPEC":    `{"type":"periodic","job":"job-name","buildid":"0","prowjobid":"prowjob"}`,
 			},
 		},
+		{
+			name: "periodic job after upstream jobs",
+			spec: JobSpec{
+				Type:      prowapi.PeriodicJob,
+				Job:       "job-name",
+				BuildID:   "0",
+				ProwJobID: "prowjob",
+				Upstream: []prowapi.UpstreamJob{
+					{Job: "build", ProwJob: "build-prowjob", ArtifactsURL: "gs://bucket/logs/build/1/artifacts"},
+					{Job: "lint", ProwJob: "lint-prowjob", ArtifactsURL: "gs://bucket/logs/lint/2/artifacts"},
+				},
+			},
+			expected: map[string]string{
+				"CI":                 "true",
+				"JOB_NAME":           "job-name",
+				"BUILD_ID":           "0",
+				"PROW_JOB_ID":        "prowjob",
+				"JOB_TYPE":           "periodic",
+				"JOB_SPEC":           `{"type":"periodic","job":"job-name","buildid":"0","prowjobid":"prowjob","upstream":[{"job":"build","prowjob":"build-prowjob","artifacts_url":"gs://bucket/logs/build/1/artifacts"},{"job":"lint","prowjob":"lint-prowjob","artifacts_url":"gs://bucket/logs/lint/2/artifacts"}]}`,
+				"UPSTREAM_ARTIFACTS": "build=gs://bucket/logs/build/1/artifacts lint=gs://bucket/logs/lint/2/artifacts",
+			},
+		},
 		{
 			name: "postsubmit job",
 			spec: JobSpec{
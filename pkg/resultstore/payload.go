@@ -31,6 +31,7 @@ import (
 	v1 "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
 	gerrit "sigs.k8s.io/prow/pkg/gerrit/source"
 	"sigs.k8s.io/prow/pkg/kube"
+	"sigs.k8s.io/prow/pkg/loganalysis"
 )
 
 type Payload struct {
@@ -60,7 +61,7 @@ func (p *Payload) Invocation() (*resultstore.Invocation, error) {
 		Timing:               invocationTiming(p.Job),
 		InvocationAttributes: invocationAttributes(p.ProjectID, p.Job),
 		WorkspaceInfo:        workspaceInfo(p.Job),
-		Properties:           invocationProperties(p.Job, p.Started),
+		Properties:           invocationProperties(p.Job, p.Started, p.Finished),
 		Files:                p.Files,
 	}
 	return i, nil
@@ -159,10 +160,11 @@ func commandLines(pj *v1.ProwJob) []*resultstore.CommandLine {
 	return cl
 }
 
-func invocationProperties(pj *v1.ProwJob, started *metadata.Started) []*resultstore.Property {
+func invocationProperties(pj *v1.ProwJob, started *metadata.Started, finished *metadata.Finished) []*resultstore.Property {
 	var ps []*resultstore.Property
 	ps = append(ps, jobProperties(pj)...)
 	ps = append(ps, startedProperties(started)...)
+	ps = append(ps, finishedProperties(finished)...)
 	return ps
 }
 
@@ -247,6 +249,28 @@ func startedProperties(started *metadata.Started) []*resultstore.Property {
 	return ps
 }
 
+// finishedProperties returns the failure signature written by the sidecar,
+// so that failures can be searched across invocations.
+func finishedProperties(finished *metadata.Finished) []*resultstore.Property {
+	if finished == nil {
+		return nil
+	}
+	signature := loganalysis.SignatureFromMetadata(finished.Metadata)
+	if signature == nil {
+		return nil
+	}
+	return []*resultstore.Property{
+		{
+			Key:   "Failure_Signature",
+			Value: signature.Text,
+		},
+		{
+			Key:   "Failure_Signature_ID",
+			Value: signature.ID,
+		},
+	}
+}
+
 const defaultConfigurationId = "default"
 
 func (p *Payload) DefaultConfiguration() *resultstore.Configuration {
@@ -488,7 +488,7 @@ func TestInvocationProperties(t *testing.T) {
 		},
 	} {
 		t.Run(tc.desc, func(t *testing.T) {
-			got := invocationProperties(tc.job, tc.started)
+			got := invocationProperties(tc.job, tc.started, nil)
 			if diff := cmp.Diff(tc.want, got, protocmp.Transform()); diff != "" {
 				t.Errorf("properties differ (-want +got):\n%s", diff)
 			}
@@ -598,6 +598,51 @@ func TestStartedProperties(t *testing.T) {
 	}
 }
 
+func TestFinishedProperties(t *testing.T) {
+	for _, tc := range []struct {
+		desc     string
+		finished *metadata.Finished
+		want     []*resultstore.Property
+	}{
+		{
+			desc: "failure signature",
+			finished: &metadata.Finished{
+				Metadata: metadata.Metadata{
+					"failure-signature":    "--- FAIL: TestA (<duration>)",
+					"failure-signature-id": "0123456789abcdef",
+				},
+			},
+			want: []*resultstore.Property{
+				{
+					Key:   "Failure_Signature",
+					Value: "--- FAIL: TestA (<duration>)",
+				},
+				{
+					Key:   "Failure_Signature_ID",
+					Value: "0123456789abcdef",
+				},
+			},
+		},
+		{
+			desc:     "no signature",
+			finished: &metadata.Finished{},
+			want:     nil,
+		},
+		{
+			desc:     "nil",
+			finished: nil,
+			want:     nil,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := finishedProperties(tc.finished)
+			if diff := cmp.Diff(tc.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("properties differ (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestPodSpecProperties(t *testing.T) {
 	for _, tc := range []struct {
 		desc    string
@@ -53,7 +53,7 @@ func Add(mgr controllerruntime.Manager, cfg config.Getter, numWorkers int) error
 	return nil
 }
 
-type StrategyGetter func(cfg *config.Config, log *logrus.Entry) strategy.Interface
+type StrategyGetter func(cfg *config.Config, pjClient client.Reader, log *logrus.Entry) strategy.Interface
 
 type Reconciler struct {
 	pjClient    client.Client
@@ -82,7 +82,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, request reconcile.Request) (
 	// if we're reconciling a job having a different agent (or no agent at all) applying
 	// the passthrough strategy may be the safest approach.
 	if pj.Spec.Agent == prowv1.KubernetesAgent || pj.Spec.Agent == prowv1.TektonAgent {
-		result, err = r.strategy(r.cfg(), log).Schedule(ctx, pj)
+		result, err = r.strategy(r.cfg(), r.pjClient, log).Schedule(ctx, pj)
 	} else {
 		result, err = r.passthrough.Schedule(ctx, pj)
 	}
@@ -95,6 +95,14 @@ func (r *Reconciler) Reconcile(ctx context.Context, request reconcile.Request) (
 	// Don't mess the cache up
 	scheduled := pj.DeepCopy()
 	scheduled.Spec.Cluster = result.Cluster
+	if len(result.Annotations) != 0 {
+		if scheduled.Annotations == nil {
+			scheduled.Annotations = make(map[string]string, len(result.Annotations))
+		}
+		for k, v := range result.Annotations {
+			scheduled.Annotations[k] = v
+		}
+	}
 	scheduled.Status.State = prowv1.TriggeredState
 
 	if err := r.pjClient.Patch(ctx, scheduled, client.MergeFrom(pj.DeepCopy())); err != nil {
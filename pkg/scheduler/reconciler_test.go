@@ -39,12 +39,13 @@ import (
 )
 
 type fakeStrategy struct {
-	cluster string
-	err     error
+	cluster     string
+	annotations map[string]string
+	err         error
 }
 
 func (fs *fakeStrategy) Schedule(context.Context, *prowv1.ProwJob) (strategy.Result, error) {
-	return strategy.Result{Cluster: fs.cluster}, fs.err
+	return strategy.Result{Cluster: fs.cluster, Annotations: fs.annotations}, fs.err
 }
 
 // Alright our controller-runtime dependency is old as hell so I have to
@@ -74,6 +75,7 @@ func TestReconcile(t *testing.T) {
 		pj              *prowv1.ProwJob
 		request         reconcile.Request
 		cluster         string
+		annotations     map[string]string
 		schedulingError error
 		clientErrors    map[string]error
 		wantPJ          *prowv1.ProwJob
@@ -107,6 +109,21 @@ func TestReconcile(t *testing.T) {
 				Status:     prowv1.ProwJobStatus{State: prowv1.TriggeredState},
 			},
 		},
+		{
+			name: "Record the annotations of the decision",
+			pj: &prowv1.ProwJob{
+				ObjectMeta: v1.ObjectMeta{Name: "pj", Namespace: "ns", ResourceVersion: "1", Annotations: map[string]string{"keep": "me"}},
+				Spec:       prowv1.ProwJobSpec{Agent: prowv1.KubernetesAgent},
+			},
+			request:     reconcile.Request{NamespacedName: types.NamespacedName{Name: "pj", Namespace: "ns"}},
+			cluster:     "foo",
+			annotations: map[string]string{"decision": "because"},
+			wantPJ: &prowv1.ProwJob{
+				ObjectMeta: v1.ObjectMeta{Name: "pj", Namespace: "ns", ResourceVersion: "2", Annotations: map[string]string{"keep": "me", "decision": "because"}},
+				Spec:       prowv1.ProwJobSpec{Cluster: "foo", Agent: prowv1.KubernetesAgent},
+				Status:     prowv1.ProwJobStatus{State: prowv1.TriggeredState},
+			},
+		},
 		{
 			name:    "Skip ProwJob not found",
 			request: reconcile.Request{NamespacedName: types.NamespacedName{Name: "pj", Namespace: "ns"}},
@@ -174,8 +191,8 @@ func TestReconcile(t *testing.T) {
 
 			r := scheduler.NewReconciler(pjClient,
 				func() *config.Config { return nil },
-				func(_ *config.Config, _ *logrus.Entry) strategy.Interface {
-					return &fakeStrategy{cluster: tc.cluster, err: tc.schedulingError}
+				func(_ *config.Config, _ client.Reader, _ *logrus.Entry) strategy.Interface {
+					return &fakeStrategy{cluster: tc.cluster, annotations: tc.annotations, err: tc.schedulingError}
 				})
 			_, err := r.Reconcile(context.TODO(), tc.request)
 
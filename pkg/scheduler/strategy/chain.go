@@ -0,0 +1,186 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package strategy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/labels"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	prowv1 "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/kube"
+)
+
+// Outcomes of a stage of the Chain strategy.
+const (
+	ChainStageDecided   = "decided"
+	ChainStageUndecided = "undecided"
+	ChainStageFailed    = "failed"
+	ChainStageTimedOut  = "timed-out"
+)
+
+var chainMetrics = struct {
+	stageOutcomes *prometheus.CounterVec
+}{
+	stageOutcomes: prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "scheduler_chain_stage_outcomes",
+		Help: "Number of ProwJobs evaluated by the stages of the chain strategy, by stage and outcome.",
+	}, []string{
+		"stage",
+		"outcome",
+	}),
+}
+
+func init() {
+	prometheus.MustRegister(chainMetrics.stageOutcomes)
+}
+
+type chainStage struct {
+	cfg      config.SchedulingStage
+	strategy Interface
+}
+
+type stageDecision struct {
+	result  Result
+	decided bool
+	err     error
+}
+
+// decide runs the strategy of the stage within the timeout. The strategy may
+// not honor the context, so it runs on its own copy of the ProwJob and its
+// late decision is discarded.
+func (s *chainStage) decide(ctx context.Context, pj *prowv1.ProwJob) (Result, bool, error) {
+	if s.cfg.Timeout != nil && s.cfg.Timeout.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.Timeout.Duration)
+		defer cancel()
+	}
+
+	decisions := make(chan stageDecision, 1)
+	go func(pj *prowv1.ProwJob) {
+		var d stageDecision
+		if decider, ok := s.strategy.(decider); ok {
+			d.result, d.decided, d.err = decider.decide(ctx, pj)
+		} else {
+			d.result, d.err = s.strategy.Schedule(ctx, pj)
+			d.decided = d.err == nil
+		}
+		decisions <- d
+	}(pj.DeepCopy())
+
+	select {
+	case <-ctx.Done():
+		return Result{}, false, ctx.Err()
+	case d := <-decisions:
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return Result{}, false, ctxErr
+		}
+		return d.result, d.decided, d.err
+	}
+}
+
+// Chain is a scheduling strategy that composes other strategies. The stages
+// whose job selector matches the ProwJob run in order until one of them
+// takes a decision. If none does, the ProwJob keeps the cluster it was
+// configured to, like Passthrough.
+type Chain struct {
+	stages []chainStage
+	log    *logrus.Entry
+}
+
+var _ Interface = &Chain{}
+
+func NewChain(cfg config.ChainScheduling, pjClient ctrlruntimeclient.Reader, namespace string, log *logrus.Entry) *Chain {
+	c := &Chain{log: log}
+	for _, stageCfg := range cfg.Stages {
+		stage := chainStage{cfg: stageCfg}
+		stageLog := log.WithField("stage", stageCfg.Name)
+		switch {
+		case stageCfg.Failover != nil:
+			stage.strategy = NewFailover(*stageCfg.Failover)
+		case stageCfg.External != nil:
+			stage.strategy = NewExternal(*stageCfg.External, stageLog)
+		case stageCfg.LoadAware != nil:
+			stage.strategy = NewLoadAware(*stageCfg.LoadAware, pjClient, namespace, stageLog)
+		default:
+			// Not supposed to happen with a validated config.
+			stage.strategy = &Passthrough{}
+		}
+		c.stages = append(c.stages, stage)
+	}
+	return c
+}
+
+func (c *Chain) Schedule(ctx context.Context, pj *prowv1.ProwJob) (Result, error) {
+	for i := range c.stages {
+		stage := &c.stages[i]
+		if stage.cfg.JobSelector != nil && !stage.cfg.JobSelector.Matches(labels.Set(pj.Labels)) {
+			continue
+		}
+		log := c.log.WithField("stage", stage.cfg.Name)
+
+		r, decided, err := stage.decide(ctx, pj)
+		if err != nil {
+			outcome := ChainStageFailed
+			if errors.Is(err, context.DeadlineExceeded) {
+				outcome = ChainStageTimedOut
+			}
+			chainMetrics.stageOutcomes.WithLabelValues(stage.cfg.Name, outcome).Inc()
+			log.WithError(err).WithField("fallback", stage.cfg.Fallback).Warn("Scheduling stage failed")
+
+			switch stage.cfg.Fallback {
+			case config.SchedulingFallbackPassthrough:
+				return stageResult(stage.cfg.Name, Result{Cluster: pj.Spec.Cluster}, outcome+", passthrough"), nil
+			case config.SchedulingFallbackRequeue:
+				return Result{}, fmt.Errorf("stage %s: %w", stage.cfg.Name, err)
+			}
+			continue
+		}
+		if !decided {
+			chainMetrics.stageOutcomes.WithLabelValues(stage.cfg.Name, ChainStageUndecided).Inc()
+			continue
+		}
+
+		chainMetrics.stageOutcomes.WithLabelValues(stage.cfg.Name, ChainStageDecided).Inc()
+		return stageResult(stage.cfg.Name, r, ""), nil
+	}
+
+	return Result{Cluster: pj.Spec.Cluster}, nil
+}
+
+// stageResult records the stage in the decision annotation of the result.
+func stageResult(stage string, r Result, note string) Result {
+	decision := fmt.Sprintf("chain: stage %s", stage)
+	if note != "" {
+		decision += " " + note
+	}
+	annotations := make(map[string]string, len(r.Annotations)+1)
+	for k, v := range r.Annotations {
+		annotations[k] = v
+	}
+	if inner := annotations[kube.SchedulingDecisionAnnotation]; inner != "" {
+		decision += "; " + inner
+	}
+	annotations[kube.SchedulingDecisionAnnotation] = decision
+	r.Annotations = annotations
+	return r
+}
@@ -0,0 +1,358 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package strategy_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	prowv1 "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/kube"
+	"sigs.k8s.io/prow/pkg/scheduler/strategy"
+)
+
+func TestChain(t *testing.T) {
+	okServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(strategy.SchedulingResponse{Cluster: "external"})
+	}))
+	t.Cleanup(okServer.Close)
+	failingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(failingServer.Close)
+	release := make(chan struct{})
+	slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		json.NewEncoder(w).Encode(strategy.SchedulingResponse{Cluster: "external"})
+	}))
+	t.Cleanup(slowServer.Close)
+	t.Cleanup(func() { close(release) })
+
+	external := func(url string) *config.ExternalScheduling {
+		return &config.ExternalScheduling{
+			URL: url,
+			Cache: config.ExternalSchedulingCache{
+				EntryTimeoutInterval: &metav1.Duration{Duration: time.Minute},
+				CleanupInterval:      &metav1.Duration{Duration: time.Minute},
+			},
+		}
+	}
+	failover := &config.FailoverScheduling{ClusterMappings: map[string]string{"broken": "healthy"}}
+
+	for _, tc := range []struct {
+		name         string
+		stages       []config.SchedulingStage
+		pj           *prowv1.ProwJob
+		wantDecision strategy.Result
+		wantErr      bool
+	}{
+		{
+			name: "First stage decides",
+			stages: []config.SchedulingStage{
+				{External: external(okServer.URL)},
+				{Failover: failover},
+			},
+			pj: &prowv1.ProwJob{Spec: prowv1.ProwJobSpec{Cluster: "broken"}},
+			wantDecision: strategy.Result{
+				Cluster:     "external",
+				Annotations: map[string]string{kube.SchedulingDecisionAnnotation: "chain: stage external"},
+			},
+		},
+		{
+			name: "Failing stage falls back to the next one",
+			stages: []config.SchedulingStage{
+				{External: external(failingServer.URL)},
+				{Failover: failover},
+			},
+			pj: &prowv1.ProwJob{Spec: prowv1.ProwJobSpec{Cluster: "broken"}},
+			wantDecision: strategy.Result{
+				Cluster:     "healthy",
+				Annotations: map[string]string{kube.SchedulingDecisionAnnotation: "chain: stage failover"},
+			},
+		},
+		{
+			name: "No stage decides, then passthrough",
+			stages: []config.SchedulingStage{
+				{External: external(failingServer.URL)},
+				{Failover: failover},
+			},
+			pj:           &prowv1.ProwJob{Spec: prowv1.ProwJobSpec{Cluster: "fine"}},
+			wantDecision: strategy.Result{Cluster: "fine"},
+		},
+		{
+			name: "Stage skipped when the job selector does not match",
+			stages: []config.SchedulingStage{
+				{Failover: failover, JobSelectorString: "failover=true"},
+			},
+			pj:           &prowv1.ProwJob{Spec: prowv1.ProwJobSpec{Cluster: "broken"}},
+			wantDecision: strategy.Result{Cluster: "broken"},
+		},
+		{
+			name: "Stage applies when the job selector matches",
+			stages: []config.SchedulingStage{
+				{Failover: failover, JobSelectorString: "failover=true"},
+			},
+			pj: &prowv1.ProwJob{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"failover": "true"}},
+				Spec:       prowv1.ProwJobSpec{Cluster: "broken"},
+			},
+			wantDecision: strategy.Result{
+				Cluster:     "healthy",
+				Annotations: map[string]string{kube.SchedulingDecisionAnnotation: "chain: stage failover"},
+			},
+		},
+		{
+			name: "Timed out stage falls back to the next one",
+			stages: []config.SchedulingStage{
+				{External: external(slowServer.URL), Timeout: &metav1.Duration{Duration: 50 * time.Millisecond}},
+				{Name: "mapping", Failover: failover},
+			},
+			pj: &prowv1.ProwJob{Spec: prowv1.ProwJobSpec{Cluster: "broken"}},
+			wantDecision: strategy.Result{
+				Cluster:     "healthy",
+				Annotations: map[string]string{kube.SchedulingDecisionAnnotation: "chain: stage mapping"},
+			},
+		},
+		{
+			name: "Failing stage with passthrough fallback stops the chain",
+			stages: []config.SchedulingStage{
+				{External: external(failingServer.URL), Fallback: config.SchedulingFallbackPassthrough},
+				{Failover: failover},
+			},
+			pj: &prowv1.ProwJob{Spec: prowv1.ProwJobSpec{Cluster: "broken"}},
+			wantDecision: strategy.Result{
+				Cluster:     "broken",
+				Annotations: map[string]string{kube.SchedulingDecisionAnnotation: "chain: stage external failed, passthrough"},
+			},
+		},
+		{
+			name: "Failing stage with requeue fallback fails the scheduling",
+			stages: []config.SchedulingStage{
+				{External: external(failingServer.URL), Fallback: config.SchedulingFallbackRequeue},
+				{Failover: failover},
+			},
+			pj:      &prowv1.ProwJob{Spec: prowv1.ProwJobSpec{Cluster: "broken"}},
+			wantErr: true,
+		},
+		{
+			name: "Decision annotation of the stage is kept",
+			stages: []config.SchedulingStage{
+				{LoadAware: &config.LoadAwareScheduling{Clusters: []config.LoadAwareCluster{{Name: "build01", MaxJobs: 2}}}},
+			},
+			pj: &prowv1.ProwJob{Spec: prowv1.ProwJobSpec{Cluster: "build01"}},
+			wantDecision: strategy.Result{
+				Cluster:     "build01",
+				Annotations: map[string]string{kube.SchedulingDecisionAnnotation: "chain: stage load_aware; load-aware: least-loaded (build01=0.50)"},
+			},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := config.ChainScheduling{Stages: tc.stages}
+			if err := cfg.DefaultAndValidate(); err != nil {
+				t.Fatalf("Invalid config: %s", err)
+			}
+			pjClient := fakectrlruntimeclient.NewClientBuilder().Build()
+			chain := strategy.NewChain(cfg, pjClient, "", logrus.NewEntry(logrus.StandardLogger()))
+
+			d, err := chain.Schedule(context.TODO(), tc.pj)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("Expected error %t but got %v", tc.wantErr, err)
+			}
+			if diff := cmp.Diff(tc.wantDecision, d); diff != "" {
+				t.Errorf("Unexpected decision: %s", diff)
+			}
+		})
+	}
+}
+
+// blockingReader blocks the listings until it is released, ignoring the
+// context.
+type blockingReader struct {
+	ctrlruntimeclient.Reader
+	release chan struct{}
+}
+
+func (r *blockingReader) List(ctx context.Context, list ctrlruntimeclient.ObjectList, opts ...ctrlruntimeclient.ListOption) error {
+	<-r.release
+	return r.Reader.List(ctx, list, opts...)
+}
+
+func TestChainStageTimeoutIgnoredByStrategy(t *testing.T) {
+	pjClient := &blockingReader{Reader: fakectrlruntimeclient.NewClientBuilder().Build(), release: make(chan struct{})}
+	t.Cleanup(func() { close(pjClient.release) })
+	cfg := config.ChainScheduling{Stages: []config.SchedulingStage{
+		{
+			LoadAware: &config.LoadAwareScheduling{Clusters: []config.LoadAwareCluster{{Name: "build01", MaxJobs: 2}}},
+			Timeout:   &metav1.Duration{Duration: 50 * time.Millisecond},
+		},
+		{Name: "mapping", Failover: &config.FailoverScheduling{ClusterMappings: map[string]string{"build01": "build02"}}},
+	}}
+	if err := cfg.DefaultAndValidate(); err != nil {
+		t.Fatalf("Invalid config: %s", err)
+	}
+	chain := strategy.NewChain(cfg, pjClient, "", logrus.NewEntry(logrus.StandardLogger()))
+
+	decided := make(chan strategy.Result)
+	go func() {
+		d, err := chain.Schedule(context.TODO(), &prowv1.ProwJob{Spec: prowv1.ProwJobSpec{Cluster: "build01"}})
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		decided <- d
+	}()
+	select {
+	case d := <-decided:
+		want := strategy.Result{
+			Cluster:     "build02",
+			Annotations: map[string]string{kube.SchedulingDecisionAnnotation: "chain: stage mapping"},
+		}
+		if diff := cmp.Diff(want, d); diff != "" {
+			t.Errorf("Unexpected decision: %s", diff)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("The timeout of the stage was not enforced")
+	}
+}
+
+func TestChainSchedulingDefaultAndValidate(t *testing.T) {
+	failover := &config.FailoverScheduling{}
+	for _, tc := range []struct {
+		name    string
+		cfg     config.ChainScheduling
+		wantErr bool
+	}{
+		{
+			name: "Valid config",
+			cfg: config.ChainScheduling{Stages: []config.SchedulingStage{
+				{Failover: failover, JobSelectorString: "a=b", Fallback: config.SchedulingFallbackRequeue},
+				{Name: "another", Failover: failover, Timeout: &metav1.Duration{Duration: time.Second}},
+			}},
+		},
+		{
+			name:    "No stages",
+			wantErr: true,
+		},
+		{
+			name:    "No strategy",
+			cfg:     config.ChainScheduling{Stages: []config.SchedulingStage{{Name: "empty"}}},
+			wantErr: true,
+		},
+		{
+			name: "Several strategies",
+			cfg: config.ChainScheduling{Stages: []config.SchedulingStage{
+				{Failover: failover, External: &config.ExternalScheduling{}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "Duplicated stage names",
+			cfg: config.ChainScheduling{Stages: []config.SchedulingStage{
+				{Failover: failover},
+				{Failover: failover},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "Invalid fallback",
+			cfg: config.ChainScheduling{Stages: []config.SchedulingStage{
+				{Failover: failover, Fallback: "retry"},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "Invalid job selector",
+			cfg: config.ChainScheduling{Stages: []config.SchedulingStage{
+				{Failover: failover, JobSelectorString: "a in b"},
+			}},
+			wantErr: true,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.cfg.DefaultAndValidate()
+			if tc.wantErr != (err != nil) {
+				t.Errorf("Expected error %t but got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSchedulerDefaultAndValidate(t *testing.T) {
+	failover := &config.FailoverScheduling{}
+	loadAware := func() *config.LoadAwareScheduling {
+		return &config.LoadAwareScheduling{Clusters: []config.LoadAwareCluster{{Name: "build01", MaxJobs: 1}}}
+	}
+	for _, tc := range []struct {
+		name    string
+		cfg     config.Scheduler
+		wantErr bool
+	}{
+		{
+			name: "Single strategy",
+			cfg:  config.Scheduler{LoadAware: loadAware()},
+		},
+		{
+			name: "Chain",
+			cfg:  config.Scheduler{Chain: &config.ChainScheduling{Stages: []config.SchedulingStage{{Failover: failover}}}},
+		},
+		{
+			name:    "Chain along with a strategy",
+			cfg:     config.Scheduler{Failover: failover, Chain: &config.ChainScheduling{Stages: []config.SchedulingStage{{Failover: failover}}}},
+			wantErr: true,
+		},
+		{
+			name:    "Failover and load aware",
+			cfg:     config.Scheduler{Failover: failover, LoadAware: loadAware()},
+			wantErr: true,
+		},
+		{
+			name:    "External and load aware",
+			cfg:     config.Scheduler{External: &config.ExternalScheduling{}, LoadAware: loadAware()},
+			wantErr: true,
+		},
+		{
+			name:    "Failover and external",
+			cfg:     config.Scheduler{Failover: failover, External: &config.ExternalScheduling{}},
+			wantErr: true,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.cfg.DefaultAndValidate()
+			if tc.wantErr != (err != nil) {
+				t.Errorf("Expected error %t but got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 
 	"github.com/sirupsen/logrus"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 	prowv1 "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
 	"sigs.k8s.io/prow/pkg/config"
 )
@@ -28,6 +29,9 @@ import (
 type Result struct {
 	// A candidate cluster, the chosen one
 	Cluster string
+	// Annotations are added to the ProwJob, they may record how the
+	// decision was made
+	Annotations map[string]string
 }
 
 // Interface is an interface over scheduling strategies
@@ -35,15 +39,29 @@ type Interface interface {
 	Schedule(context.Context, *prowv1.ProwJob) (Result, error)
 }
 
+// decider is implemented by the strategies that may have nothing to say about
+// a ProwJob. It reports whether a decision was taken, and returns the errors
+// the strategy would otherwise hide behind a fallback to Spec.Cluster, so
+// that a Chain can move on to its next stage instead.
+type decider interface {
+	decide(context.Context, *prowv1.ProwJob) (Result, bool, error)
+}
+
 // Get gets a scheduling strategy in accordance to configuration. It defaults
 // to Passthrough strategy if none has been configured.
-func Get(cfg *config.Config, log *logrus.Entry) Interface {
+func Get(cfg *config.Config, pjClient ctrlruntimeclient.Reader, log *logrus.Entry) Interface {
+	if cfg.Scheduler.Chain != nil {
+		return NewChain(*cfg.Scheduler.Chain, pjClient, cfg.ProwJobNamespace, log)
+	}
 	if cfg.Scheduler.Failover != nil {
 		return NewFailover(*cfg.Scheduler.Failover)
 	}
 	if cfg.Scheduler.External != nil {
 		return NewExternal(*cfg.Scheduler.External, log)
 	}
+	if cfg.Scheduler.LoadAware != nil {
+		return NewLoadAware(*cfg.Scheduler.LoadAware, pjClient, cfg.ProwJobNamespace, log)
+	}
 	return &Passthrough{}
 }
 
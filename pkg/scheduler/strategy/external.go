@@ -65,13 +65,18 @@ func NewExternal(cfg config.ExternalScheduling, log *logrus.Entry) *External {
 
 // query sends a POST request to the specified URL with the provided request body
 // and returns the response as a SchedulingResponse object
-func query(url string, reqBody SchedulingRequest) (SchedulingResponse, error) {
+func query(ctx context.Context, url string, reqBody SchedulingRequest) (SchedulingResponse, error) {
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
 		return SchedulingResponse{}, fmt.Errorf("error marshaling JSON: %w", err)
 	}
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return SchedulingResponse{}, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return SchedulingResponse{}, fmt.Errorf("error sending request: %w", err)
 	}
@@ -89,28 +94,43 @@ func query(url string, reqBody SchedulingRequest) (SchedulingResponse, error) {
 	return respBody, nil
 }
 
-// Schedule checks the cache for a valid response or queries the REST API if the cache is stale
-func (e *External) Schedule(_ context.Context, pj *prowv1.ProwJob) (Result, error) {
+// Schedule checks the cache for a valid response or queries the REST API if the cache is stale.
+// It falls back to the cluster of the ProwJob when the REST API can't be queried.
+func (e *External) Schedule(ctx context.Context, pj *prowv1.ProwJob) (Result, error) {
+	r, err := e.schedule(ctx, pj)
+	if err != nil {
+		e.log.WithError(err).WithField("job", pj.Spec.Job).WithField("cluster", pj.Spec.Cluster).Warn("scheduling failed, using Spec.Cluster entry")
+		return Result{Cluster: pj.Spec.Cluster}, nil
+	}
+	return r, nil
+}
+
+// decide doesn't fall back, so that a chain can move on to its next stage.
+func (e *External) decide(ctx context.Context, pj *prowv1.ProwJob) (Result, bool, error) {
+	r, err := e.schedule(ctx, pj)
+	return r, err == nil, err
+}
+
+func (e *External) schedule(ctx context.Context, pj *prowv1.ProwJob) (Result, error) {
 	if time.Since(e.timestamp) > e.cfg.Cache.CleanupInterval.Duration {
 		e.cleanupCache()
 	}
 	entry, found := e.cache[pj.Spec.Job]
 
 	if found && time.Since(entry.timestamp) < e.cfg.Cache.EntryTimeoutInterval.Duration {
-		return Result(entry.r), nil
+		return Result{Cluster: entry.r.Cluster}, nil
 	}
 
-	resp, err := query(e.cfg.URL, SchedulingRequest{Job: pj.Spec.Job})
+	resp, err := query(ctx, e.cfg.URL, SchedulingRequest{Job: pj.Spec.Job})
 	if err != nil {
-		e.log.WithField("job", pj.Spec.Job).WithField("cluster", pj.Spec.Cluster).Warn("scheduling failed, using Spec.Cluster entry")
-		return Result{Cluster: pj.Spec.Cluster}, nil
+		return Result{}, err
 	}
 
 	e.cache[pj.Spec.Job] = &cacheEntry{
 		r:         resp,
 		timestamp: time.Now(),
 	}
-	return Result(resp), nil
+	return Result{Cluster: resp.Cluster}, nil
 }
 
 // cleanupCache removes stale entries from the cache
@@ -33,11 +33,17 @@ type Failover struct {
 
 var _ Interface = &Failover{}
 
-func (f *Failover) Schedule(_ context.Context, pj *prowv1.ProwJob) (Result, error) {
+func (f *Failover) Schedule(ctx context.Context, pj *prowv1.ProwJob) (Result, error) {
+	r, _, err := f.decide(ctx, pj)
+	return r, err
+}
+
+// decide takes a decision only if the cluster of the ProwJob is mapped.
+func (f *Failover) decide(_ context.Context, pj *prowv1.ProwJob) (Result, bool, error) {
 	if cluster, exists := f.cfg.ClusterMappings[pj.Spec.Cluster]; exists {
-		return Result{Cluster: cluster}, nil
+		return Result{Cluster: cluster}, true, nil
 	}
-	return Result{Cluster: pj.Spec.Cluster}, nil
+	return Result{Cluster: pj.Spec.Cluster}, false, nil
 }
 
 func NewFailover(cfg config.FailoverScheduling) *Failover {
@@ -0,0 +1,268 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/labels"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	prowv1 "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/kube"
+)
+
+// Reasons of the LoadAware scheduling decisions.
+const (
+	LoadAwareLeastLoaded     = "least-loaded"
+	LoadAwareOverCapacity    = "over-capacity"
+	LoadAwareNoEligible      = "no-eligible-cluster"
+	LoadAwareUnmanagedTarget = "unmanaged-cluster"
+)
+
+var loadAwareMetrics = struct {
+	decisions   *prometheus.CounterVec
+	utilization *prometheus.GaugeVec
+}{
+	decisions: prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "scheduler_load_aware_decisions",
+		Help: "Number of ProwJobs scheduled by the load aware strategy, by cluster and reason.",
+	}, []string{
+		"cluster",
+		"reason",
+	}),
+	utilization: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "scheduler_load_aware_cluster_utilization",
+		Help: "Utilization of the clusters, from 0 to 1, observed by the load aware strategy on its last decision.",
+	}, []string{
+		"cluster",
+	}),
+}
+
+func init() {
+	prometheus.MustRegister(loadAwareMetrics.decisions)
+	prometheus.MustRegister(loadAwareMetrics.utilization)
+}
+
+// clusterLoad is the amount of ProwJobs and resources requested on a cluster.
+type clusterLoad struct {
+	jobs int
+	// cpu is in millicores.
+	cpu int64
+	// memory is in bytes.
+	memory int64
+}
+
+func (l *clusterLoad) add(o clusterLoad) {
+	l.jobs += o.jobs
+	l.cpu += o.cpu
+	l.memory += o.memory
+}
+
+// utilization returns the highest ratio between the load and the capacity
+// of the cluster. Unlimited capacities are ignored.
+func (l clusterLoad) utilization(c *config.LoadAwareCluster) float64 {
+	var ret float64
+	if c.MaxJobs > 0 {
+		ret = max(ret, float64(l.jobs)/float64(c.MaxJobs))
+	}
+	if c.CPUQuantity != nil && c.CPUQuantity.MilliValue() > 0 {
+		ret = max(ret, float64(l.cpu)/float64(c.CPUQuantity.MilliValue()))
+	}
+	if c.MemoryQuantity != nil && c.MemoryQuantity.Value() > 0 {
+		ret = max(ret, float64(l.memory)/float64(c.MemoryQuantity.Value()))
+	}
+	return ret
+}
+
+// prowJobLoad returns the load a ProwJob puts on its cluster. As the init
+// containers run one after another before the containers, a resource is
+// requested by the sum of the containers or the largest init container,
+// whichever is higher.
+func prowJobLoad(pj *prowv1.ProwJob) clusterLoad {
+	ret := clusterLoad{jobs: 1}
+	if pj.Spec.PodSpec == nil {
+		return ret
+	}
+	for _, c := range pj.Spec.PodSpec.Containers {
+		ret.cpu += c.Resources.Requests.Cpu().MilliValue()
+		ret.memory += c.Resources.Requests.Memory().Value()
+	}
+	for _, c := range pj.Spec.PodSpec.InitContainers {
+		ret.cpu = max(ret.cpu, c.Resources.Requests.Cpu().MilliValue())
+		ret.memory = max(ret.memory, c.Resources.Requests.Memory().Value())
+	}
+	return ret
+}
+
+// LoadAware is a scheduling strategy that assigns a ProwJob to the least
+// loaded cluster among the ones it is eligible for. The load is derived
+// from the triggered and pending ProwJobs of every cluster along with their
+// resource requests, and weighted by the configured cluster capacity.
+type LoadAware struct {
+	cfg       config.LoadAwareScheduling
+	pjClient  ctrlruntimeclient.Reader
+	namespace string
+	log       *logrus.Entry
+}
+
+var _ Interface = &LoadAware{}
+
+func NewLoadAware(cfg config.LoadAwareScheduling, pjClient ctrlruntimeclient.Reader, namespace string, log *logrus.Entry) *LoadAware {
+	return &LoadAware{cfg: cfg, pjClient: pjClient, namespace: namespace, log: log}
+}
+
+func (la *LoadAware) Schedule(ctx context.Context, pj *prowv1.ProwJob) (Result, error) {
+	r, _, err := la.decide(ctx, pj)
+	return r, err
+}
+
+// decide takes a decision only if the cluster of the ProwJob is managed.
+func (la *LoadAware) decide(ctx context.Context, pj *prowv1.ProwJob) (Result, bool, error) {
+	managed := false
+	for _, c := range la.cfg.Clusters {
+		if c.Name == pj.Spec.Cluster {
+			managed = true
+			break
+		}
+	}
+	if !managed {
+		loadAwareMetrics.decisions.WithLabelValues(pj.Spec.Cluster, LoadAwareUnmanagedTarget).Inc()
+		return Result{Cluster: pj.Spec.Cluster}, false, nil
+	}
+
+	eligible := la.eligibleClusters(pj)
+	if len(eligible) == 0 {
+		// The affinity rules exclude Spec.Cluster too, so leave the job
+		// unscheduled until the configuration or its labels change.
+		loadAwareMetrics.decisions.WithLabelValues(pj.Spec.Cluster, LoadAwareNoEligible).Inc()
+		return Result{}, false, fmt.Errorf("no eligible cluster for the affinity rules matching %s", pj.Name)
+	}
+
+	loads, err := la.clusterLoads(ctx, pj)
+	if err != nil {
+		return Result{}, false, fmt.Errorf("compute cluster loads: %w", err)
+	}
+
+	type candidate struct {
+		cluster     *config.LoadAwareCluster
+		load        clusterLoad
+		utilization float64
+	}
+	jobLoad := prowJobLoad(pj)
+	candidates := make([]candidate, 0, len(eligible))
+	for _, c := range eligible {
+		load := loads[c.Name]
+		loadAwareMetrics.utilization.WithLabelValues(c.Name).Set(load.utilization(c))
+		load.add(jobLoad)
+		candidates = append(candidates, candidate{cluster: c, load: load, utilization: load.utilization(c)})
+	}
+	// Ties are broken by the number of jobs, then in favor of the cluster
+	// the job was configured to, then by the order of the configuration.
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].utilization != candidates[j].utilization {
+			return candidates[i].utilization < candidates[j].utilization
+		}
+		if candidates[i].load.jobs != candidates[j].load.jobs {
+			return candidates[i].load.jobs < candidates[j].load.jobs
+		}
+		return candidates[i].cluster.Name == pj.Spec.Cluster && candidates[j].cluster.Name != pj.Spec.Cluster
+	})
+
+	chosen := candidates[0]
+	reason := LoadAwareLeastLoaded
+	if chosen.utilization > 1 {
+		reason = LoadAwareOverCapacity
+	}
+	utilizations := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		utilizations = append(utilizations, fmt.Sprintf("%s=%.2f", c.cluster.Name, c.utilization))
+	}
+
+	loadAwareMetrics.decisions.WithLabelValues(chosen.cluster.Name, reason).Inc()
+	return decisionResult(chosen.cluster.Name, reason, utilizations), true, nil
+}
+
+// eligibleClusters returns the clusters allowed by all the affinity rules
+// matching the ProwJob, in configuration order.
+func (la *LoadAware) eligibleClusters(pj *prowv1.ProwJob) []*config.LoadAwareCluster {
+	var rules []config.SchedulingAffinityRule
+	for _, r := range la.cfg.AffinityRules {
+		if r.JobSelector.Matches(labels.Set(pj.Labels)) {
+			rules = append(rules, r)
+		}
+	}
+
+	var ret []*config.LoadAwareCluster
+	for i := range la.cfg.Clusters {
+		c := &la.cfg.Clusters[i]
+		eligible := true
+		for _, r := range rules {
+			if !r.ClusterSelector.Matches(labels.Set(c.Labels)) {
+				eligible = false
+				break
+			}
+		}
+		if eligible {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+// clusterLoads sums the load of the ProwJobs that occupy a cluster, that
+// is the ones already scheduled but not completed yet.
+func (la *LoadAware) clusterLoads(ctx context.Context, scheduling *prowv1.ProwJob) (map[string]clusterLoad, error) {
+	pjs := &prowv1.ProwJobList{}
+	if err := la.pjClient.List(ctx, pjs, ctrlruntimeclient.InNamespace(la.namespace)); err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]clusterLoad)
+	for i := range pjs.Items {
+		pj := &pjs.Items[i]
+		if pj.Name == scheduling.Name {
+			continue
+		}
+		if pj.Status.State != prowv1.TriggeredState && pj.Status.State != prowv1.PendingState {
+			continue
+		}
+		if pj.Spec.Agent != prowv1.KubernetesAgent && pj.Spec.Agent != prowv1.TektonAgent {
+			continue
+		}
+		load := ret[pj.Spec.Cluster]
+		load.add(prowJobLoad(pj))
+		ret[pj.Spec.Cluster] = load
+	}
+	return ret, nil
+}
+
+func decisionResult(cluster, reason string, utilizations []string) Result {
+	decision := fmt.Sprintf("load-aware: %s", reason)
+	if len(utilizations) != 0 {
+		decision += fmt.Sprintf(" (%s)", strings.Join(utilizations, ", "))
+	}
+	return Result{
+		Cluster:     cluster,
+		Annotations: map[string]string{kube.SchedulingDecisionAnnotation: decision},
+	}
+}
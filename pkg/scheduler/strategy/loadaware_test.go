@@ -0,0 +1,255 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package strategy_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	prowv1 "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/kube"
+	"sigs.k8s.io/prow/pkg/scheduler/strategy"
+)
+
+func loadAwarePJ(name, cluster string, state prowv1.ProwJobState, cpu string, labels map[string]string) *prowv1.ProwJob {
+	pj := &prowv1.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "prowjobs", Labels: labels},
+		Spec:       prowv1.ProwJobSpec{Agent: prowv1.KubernetesAgent, Cluster: cluster},
+		Status:     prowv1.ProwJobStatus{State: state},
+	}
+	if cpu != "" {
+		pj.Spec.PodSpec = &corev1.PodSpec{Containers: []corev1.Container{{
+			Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)}},
+		}}}
+	}
+	return pj
+}
+
+func withInitContainer(pj *prowv1.ProwJob, cpu string) *prowv1.ProwJob {
+	pj.Spec.PodSpec.InitContainers = append(pj.Spec.PodSpec.InitContainers, corev1.Container{
+		Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)}},
+	})
+	return pj
+}
+
+func TestLoadAware(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		cfg          config.LoadAwareScheduling
+		pjs          []ctrlruntimeclient.Object
+		pj           *prowv1.ProwJob
+		wantDecision strategy.Result
+		wantErr      bool
+	}{
+		{
+			name: "Unmanaged cluster is left untouched",
+			cfg: config.LoadAwareScheduling{Clusters: []config.LoadAwareCluster{
+				{Name: "build01", MaxJobs: 10},
+			}},
+			pj:           loadAwarePJ("pj", "default", prowv1.SchedulingState, "", nil),
+			wantDecision: strategy.Result{Cluster: "default"},
+		},
+		{
+			name: "Least loaded cluster by number of jobs",
+			cfg: config.LoadAwareScheduling{Clusters: []config.LoadAwareCluster{
+				{Name: "build01", MaxJobs: 4},
+				{Name: "build02", MaxJobs: 4},
+			}},
+			pjs: []ctrlruntimeclient.Object{
+				loadAwarePJ("a", "build01", prowv1.PendingState, "", nil),
+				loadAwarePJ("b", "build01", prowv1.TriggeredState, "", nil),
+				loadAwarePJ("c", "build02", prowv1.PendingState, "", nil),
+				loadAwarePJ("d", "build02", prowv1.SuccessState, "", nil),
+			},
+			pj: loadAwarePJ("pj", "build01", prowv1.SchedulingState, "", nil),
+			wantDecision: strategy.Result{
+				Cluster:     "build02",
+				Annotations: map[string]string{kube.SchedulingDecisionAnnotation: "load-aware: least-loaded (build02=0.50, build01=0.75)"},
+			},
+		},
+		{
+			name: "Capacity weights the load",
+			cfg: config.LoadAwareScheduling{Clusters: []config.LoadAwareCluster{
+				{Name: "small", CPU: "4"},
+				{Name: "large", CPU: "16"},
+			}},
+			pjs: []ctrlruntimeclient.Object{
+				loadAwarePJ("a", "small", prowv1.PendingState, "1", nil),
+				loadAwarePJ("b", "large", prowv1.PendingState, "6", nil),
+			},
+			pj: loadAwarePJ("pj", "small", prowv1.SchedulingState, "2", nil),
+			wantDecision: strategy.Result{
+				Cluster:     "large",
+				Annotations: map[string]string{kube.SchedulingDecisionAnnotation: "load-aware: least-loaded (large=0.50, small=0.75)"},
+			},
+		},
+		{
+			name: "Ties go to the configured cluster",
+			cfg: config.LoadAwareScheduling{Clusters: []config.LoadAwareCluster{
+				{Name: "build01", MaxJobs: 2},
+				{Name: "build02", MaxJobs: 2},
+			}},
+			pj: loadAwarePJ("pj", "build02", prowv1.SchedulingState, "", nil),
+			wantDecision: strategy.Result{
+				Cluster:     "build02",
+				Annotations: map[string]string{kube.SchedulingDecisionAnnotation: "load-aware: least-loaded (build02=0.50, build01=0.50)"},
+			},
+		},
+		{
+			name: "All clusters over capacity",
+			cfg: config.LoadAwareScheduling{Clusters: []config.LoadAwareCluster{
+				{Name: "build01", MaxJobs: 1},
+				{Name: "build02", MaxJobs: 2},
+			}},
+			pjs: []ctrlruntimeclient.Object{
+				loadAwarePJ("a", "build01", prowv1.PendingState, "", nil),
+				loadAwarePJ("b", "build02", prowv1.PendingState, "", nil),
+				loadAwarePJ("c", "build02", prowv1.PendingState, "", nil),
+			},
+			pj: loadAwarePJ("pj", "build01", prowv1.SchedulingState, "", nil),
+			wantDecision: strategy.Result{
+				Cluster:     "build02",
+				Annotations: map[string]string{kube.SchedulingDecisionAnnotation: "load-aware: over-capacity (build02=1.50, build01=2.00)"},
+			},
+		},
+		{
+			name: "Affinity rules restrict the clusters",
+			cfg: config.LoadAwareScheduling{
+				Clusters: []config.LoadAwareCluster{
+					{Name: "build01", MaxJobs: 10, Labels: map[string]string{"gpu": "false"}},
+					{Name: "build02", MaxJobs: 10, Labels: map[string]string{"gpu": "true"}},
+					{Name: "build03", MaxJobs: 10, Labels: map[string]string{"gpu": "true"}},
+				},
+				AffinityRules: []config.SchedulingAffinityRule{
+					{JobSelectorString: "needs-gpu=true", ClusterSelectorString: "gpu=true"},
+				},
+			},
+			pjs: []ctrlruntimeclient.Object{
+				loadAwarePJ("a", "build02", prowv1.PendingState, "", nil),
+			},
+			pj: loadAwarePJ("pj", "build02", prowv1.SchedulingState, "", map[string]string{"needs-gpu": "true"}),
+			wantDecision: strategy.Result{
+				Cluster:     "build03",
+				Annotations: map[string]string{kube.SchedulingDecisionAnnotation: "load-aware: least-loaded (build03=0.10, build02=0.20)"},
+			},
+		},
+		{
+			name: "Init containers count by the largest one",
+			cfg: config.LoadAwareScheduling{Clusters: []config.LoadAwareCluster{
+				{Name: "build01", CPU: "4"},
+				{Name: "build02", CPU: "4"},
+			}},
+			pjs: []ctrlruntimeclient.Object{
+				withInitContainer(withInitContainer(loadAwarePJ("a", "build01", prowv1.PendingState, "1", nil), "3"), "2"),
+				withInitContainer(loadAwarePJ("b", "build02", prowv1.PendingState, "2", nil), "1"),
+			},
+			pj: loadAwarePJ("pj", "build01", prowv1.SchedulingState, "", nil),
+			wantDecision: strategy.Result{
+				Cluster:     "build02",
+				Annotations: map[string]string{kube.SchedulingDecisionAnnotation: "load-aware: least-loaded (build02=0.50, build01=0.75)"},
+			},
+		},
+		{
+			name: "No eligible cluster leaves the job unscheduled",
+			cfg: config.LoadAwareScheduling{
+				Clusters: []config.LoadAwareCluster{
+					{Name: "build01", MaxJobs: 10},
+				},
+				AffinityRules: []config.SchedulingAffinityRule{
+					{ClusterSelectorString: "gpu=true"},
+				},
+			},
+			pj:      loadAwarePJ("pj", "build01", prowv1.SchedulingState, "", nil),
+			wantErr: true,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := tc.cfg.DefaultAndValidate(); err != nil {
+				t.Fatalf("Invalid config: %s", err)
+			}
+			pjClient := fakectrlruntimeclient.NewClientBuilder().WithObjects(tc.pjs...).Build()
+			la := strategy.NewLoadAware(tc.cfg, pjClient, "prowjobs", logrus.NewEntry(logrus.StandardLogger()))
+
+			d, err := la.Schedule(context.TODO(), tc.pj)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("Expected error %t but got %v", tc.wantErr, err)
+			}
+			if diff := cmp.Diff(tc.wantDecision, d); diff != "" {
+				t.Errorf("Unexpected decision: %s", diff)
+			}
+		})
+	}
+}
+
+func TestLoadAwareSchedulingDefaultAndValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		cfg     config.LoadAwareScheduling
+		wantErr bool
+	}{
+		{
+			name: "Valid config",
+			cfg: config.LoadAwareScheduling{
+				Clusters:      []config.LoadAwareCluster{{Name: "build01", CPU: "1500m", Memory: "64Gi"}},
+				AffinityRules: []config.SchedulingAffinityRule{{JobSelectorString: "a in (b,c)", ClusterSelectorString: "!gpu"}},
+			},
+		},
+		{
+			name:    "No clusters",
+			wantErr: true,
+		},
+		{
+			name:    "Duplicated cluster",
+			cfg:     config.LoadAwareScheduling{Clusters: []config.LoadAwareCluster{{Name: "build01"}, {Name: "build01"}}},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid quantity",
+			cfg:     config.LoadAwareScheduling{Clusters: []config.LoadAwareCluster{{Name: "build01", Memory: "lots"}}},
+			wantErr: true,
+		},
+		{
+			name: "Invalid selector",
+			cfg: config.LoadAwareScheduling{
+				Clusters:      []config.LoadAwareCluster{{Name: "build01"}},
+				AffinityRules: []config.SchedulingAffinityRule{{JobSelectorString: "a in b"}},
+			},
+			wantErr: true,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.cfg.DefaultAndValidate()
+			if tc.wantErr != (err != nil) {
+				t.Errorf("Expected error %t but got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
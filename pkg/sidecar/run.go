@@ -37,6 +37,7 @@ import (
 
 	prowv1 "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
 	"sigs.k8s.io/prow/pkg/entrypoint"
+	"sigs.k8s.io/prow/pkg/loganalysis"
 	"sigs.k8s.io/prow/pkg/pod-utils/downwardapi"
 	"sigs.k8s.io/prow/pkg/pod-utils/gcs"
 	"sigs.k8s.io/prow/pkg/pod-utils/wrapper"
@@ -151,6 +152,9 @@ func (o Options) Run(ctx context.Context, logFile *os.File) (int, error) {
 
 	buildLogs := logReadersFuncs(entries)
 	metadata := combineMetadata(entries)
+	if !passed && !aborted {
+		addFailureSignature(metadata, entries)
+	}
 	return failures, o.doUpload(context.Background(), spec, passed, aborted, metadata, buildLogs, logFile, &once)
 }
 
@@ -216,6 +220,66 @@ func combineMetadata(entries []wrapper.Options) map[string]interface{} {
 	return metadata
 }
 
+const (
+	// failureSignatureMaxLogBytes bounds the tail of the logs analyzed for the
+	// failure signature.
+	failureSignatureMaxLogBytes = 10 * 1024 * 1024
+	// failureSignatureTimeout bounds the time the upload waits for the
+	// failure signature.
+	failureSignatureTimeout = 30 * time.Second
+)
+
+// addFailureSignature adds the signature of the first error of the logs of
+// the entries to the metadata, unless the test process already set one. The
+// signature is best-effort: it is skipped when the analysis fails or takes
+// too long.
+func addFailureSignature(metadata map[string]interface{}, entries []wrapper.Options) {
+	if loganalysis.SignatureFromMetadata(metadata) != nil {
+		return
+	}
+	result := make(chan map[string]interface{}, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				logrus.Warnf("Failed to analyze the logs: %v", r)
+				result <- nil
+			}
+		}()
+		result <- failureSignature(entries)
+	}()
+
+	select {
+	case signature := <-result:
+		for k, v := range signature {
+			metadata[k] = v
+		}
+	case <-time.After(failureSignatureTimeout):
+		logrus.Warn("Timed out analyzing the logs, the failure signature is skipped.")
+	}
+}
+
+// failureSignature returns the metadata of the signature of the first error
+// in the tails of the logs of the entries, or nil.
+func failureSignature(entries []wrapper.Options) map[string]interface{} {
+	for _, opt := range entries {
+		log, err := os.Open(opt.ProcessLog)
+		if err != nil {
+			logrus.WithError(err).Warnf("Failed to open %s", opt.ProcessLog)
+			continue
+		}
+		analysis, err := loganalysis.AnalyzeTail(log, failureSignatureMaxLogBytes)
+		log.Close()
+		if err != nil {
+			logrus.WithError(err).Warnf("Failed to analyze %s", opt.ProcessLog)
+			continue
+		}
+		if analysis.Signature != nil {
+			return analysis.Signature.Metadata()
+		}
+	}
+	return nil
+}
+
 // preUpload performs steps required before actual upload
 func (o Options) preUpload() {
 	if o.DeprecatedWrapperOptions != nil {
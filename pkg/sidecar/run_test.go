@@ -34,6 +34,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"sigs.k8s.io/prow/pkg/entrypoint"
 	"sigs.k8s.io/prow/pkg/gcsupload"
+	"sigs.k8s.io/prow/pkg/loganalysis"
 	"sigs.k8s.io/prow/pkg/pod-utils/downwardapi"
 	"sigs.k8s.io/prow/pkg/pod-utils/wrapper"
 
@@ -383,6 +384,62 @@ func TestCombineMetadata(t *testing.T) {
 	}
 }
 
+func TestAddFailureSignature(t *testing.T) {
+	cases := []struct {
+		name     string
+		logs     []string
+		metadata map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			name:     "no error in the logs",
+			logs:     []string{"hello\nworld\n"},
+			metadata: map[string]interface{}{},
+			expected: map[string]interface{}{},
+		},
+		{
+			name:     "first error of the logs",
+			logs:     []string{"hello\n", "=== RUN TestA\n--- FAIL: TestA (1.20s)\nFAIL\n"},
+			metadata: map[string]interface{}{},
+			expected: map[string]interface{}{
+				"failure-signature":    "--- FAIL: TestA (<duration>)",
+				"failure-signature-id": loganalysis.NewSignature("--- FAIL: TestA (1.20s)").ID,
+			},
+		},
+		{
+			name: "signature set by the test process",
+			logs: []string{"--- FAIL: TestA (1.20s)\n"},
+			metadata: map[string]interface{}{
+				"failure-signature":    "custom",
+				"failure-signature-id": "1",
+			},
+			expected: map[string]interface{}{
+				"failure-signature":    "custom",
+				"failure-signature-id": "1",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			entries := []wrapper.Options{{ProcessLog: path.Join(tmpDir, "missing.txt")}}
+			for i, log := range tc.logs {
+				p := path.Join(tmpDir, fmt.Sprintf("process-log-%d.txt", i))
+				if err := os.WriteFile(p, []byte(log), 0600); err != nil {
+					t.Fatalf("could not create log %d: %v", i, err)
+				}
+				entries = append(entries, wrapper.Options{ProcessLog: p})
+			}
+
+			addFailureSignature(tc.metadata, entries)
+			if !equality.Semantic.DeepEqual(tc.expected, tc.metadata) {
+				t.Errorf("maps do not match:\n%s", diff.ObjectReflectDiff(tc.expected, tc.metadata))
+			}
+		})
+	}
+}
+
 func name(idx int) string {
 	return nameEntry(idx, wrapper.Options{})
 }
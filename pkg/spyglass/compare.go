@@ -0,0 +1,373 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/testgrid/metadata/junit"
+	"github.com/sirupsen/logrus"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/flakes"
+)
+
+const (
+	podInfoFile = "podinfo.json"
+
+	// durationRegressionFactor and minDurationRegression define the tests
+	// whose duration regressed: they must take both this many times longer
+	// and at least this much longer than in the base run.
+	durationRegressionFactor = 1.5
+	minDurationRegression    = 10 * time.Second
+)
+
+// junitRE matches the JUnit files of a run, as the junit lens does by default.
+var junitRE = regexp.MustCompile(`(^|/)junit.*\.xml$`)
+
+// FieldDiff is a field whose value differs between two runs. The value is
+// empty when the field is missing from a run.
+type FieldDiff struct {
+	Field string
+	Base  string
+	Head  string
+}
+
+// TestState is the state of a test in a run.
+type TestState string
+
+const (
+	TestPassed  TestState = "passed"
+	TestFailed  TestState = "failed"
+	TestFlaky   TestState = "flaky"
+	TestSkipped TestState = "skipped"
+	// TestMissing is the state of a test that did not run.
+	TestMissing TestState = "missing"
+)
+
+// TestResult is the result of a test in a run.
+type TestResult struct {
+	State    TestState
+	Duration time.Duration
+}
+
+// TestDiff is a test whose result differs between two runs.
+type TestDiff struct {
+	Name string
+	Base TestResult
+	Head TestResult
+}
+
+// TestComparison compares the JUnit results of two runs.
+type TestComparison struct {
+	// NewFailures failed in the head run but not in the base run.
+	NewFailures []TestDiff
+	// Fixed failed in the base run and passed in the head run.
+	Fixed []TestDiff
+	// DurationRegressions passed in both runs but took significantly longer
+	// in the head run.
+	DurationRegressions []TestDiff
+}
+
+// RunComparison compares two runs of the same job.
+type RunComparison struct {
+	Job       string
+	BaseBuild string
+	HeadBuild string
+	// BaseSource and HeadSource are the Spyglass sources of the runs.
+	BaseSource string
+	HeadSource string
+	// Metadata compares started.json and finished.json.
+	Metadata []FieldDiff
+	// Refs compares the refs of the ProwJobs, from prowjob.json.
+	Refs  []FieldDiff
+	Tests TestComparison
+	// PodSpec compares the pod specs, from podinfo.json.
+	PodSpec []FieldDiff
+}
+
+// runArtifacts holds the artifacts of a run that are compared.
+type runArtifacts struct {
+	metadata map[string]string
+	refs     map[string]string
+	podSpec  map[string]string
+	tests    map[string]TestResult
+}
+
+// RunSource returns the Spyglass source of the build of the job whose
+// results are under jobPath, as returned by JobPath. Dot segments are
+// rejected, so that the source stays under the path of the job.
+func (sg *Spyglass) RunSource(jobPath, buildID string) (string, error) {
+	if buildID == "" || buildID == "." || buildID == ".." || strings.ContainsAny(buildID, "/\\") {
+		return "", fmt.Errorf("invalid build ID %q", buildID)
+	}
+	for _, segment := range strings.Split(jobPath, "/") {
+		if segment == "." || segment == ".." || strings.Contains(segment, "\\") {
+			return "", fmt.Errorf("invalid job path %q", jobPath)
+		}
+	}
+	return sg.ResolveSymlink(path.Join(strings.TrimSuffix(jobPath, "/"), buildID))
+}
+
+// CompareRuns compares the artifacts of two runs of the same job.
+func (sg *Spyglass) CompareRuns(ctx context.Context, baseSrc, headSrc string) (*RunComparison, error) {
+	baseJob, baseBuild, err := keyToJob(baseSrc)
+	if err != nil {
+		return nil, err
+	}
+	headJob, headBuild, err := keyToJob(headSrc)
+	if err != nil {
+		return nil, err
+	}
+	if baseJob != headJob {
+		return nil, fmt.Errorf("cannot compare runs of different jobs %q and %q", baseJob, headJob)
+	}
+
+	base, err := sg.runArtifacts(ctx, baseSrc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the artifacts of %s: %w", baseSrc, err)
+	}
+	head, err := sg.runArtifacts(ctx, headSrc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the artifacts of %s: %w", headSrc, err)
+	}
+	return &RunComparison{
+		Job:        headJob,
+		BaseBuild:  baseBuild,
+		HeadBuild:  headBuild,
+		BaseSource: baseSrc,
+		HeadSource: headSrc,
+		Metadata:   diffFields(base.metadata, head.metadata),
+		Refs:       diffFields(base.refs, head.refs),
+		Tests:      compareTests(base.tests, head.tests),
+		PodSpec:    diffFields(base.podSpec, head.podSpec),
+	}, nil
+}
+
+func keyToJob(src string) (string, string, error) {
+	split := strings.Split(strings.Trim(src, "/"), "/")
+	if len(split) < 3 {
+		return "", "", fmt.Errorf("invalid src %s: expected <key-type>/.../<job-name>/<build-id>", src)
+	}
+	return split[len(split)-2], split[len(split)-1], nil
+}
+
+// runArtifacts fetches the artifacts of the run through the artifact
+// fetchers, so that runs can be compared whatever their storage.
+func (sg *Spyglass) runArtifacts(ctx context.Context, src string) (*runArtifacts, error) {
+	names, err := sg.ListArtifacts(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	var wanted []string
+	for _, name := range names {
+		switch {
+		case name == prowapi.StartedStatusFile, name == prowapi.FinishedStatusFile,
+			name == prowapi.ProwJobFile, name == podInfoFile, junitRE.MatchString(name):
+			wanted = append(wanted, name)
+		}
+	}
+	artifacts, err := sg.FetchArtifacts(ctx, src, "", sg.config().Deck.Spyglass.SizeLimit, wanted)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &runArtifacts{
+		metadata: map[string]string{},
+		refs:     map[string]string{},
+		podSpec:  map[string]string{},
+		tests:    map[string]TestResult{},
+	}
+	for _, artifact := range artifacts {
+		name := artifact.JobPath()
+		log := logrus.WithFields(logrus.Fields{"src": src, "artifact": name})
+		content, err := artifact.ReadAll()
+		if err != nil {
+			log.WithError(err).Info("Failed to read artifact.")
+			continue
+		}
+		switch name {
+		case prowapi.StartedStatusFile, prowapi.FinishedStatusFile:
+			err = flattenJSON(content, strings.TrimSuffix(name, ".json"), nil, run.metadata)
+		case prowapi.ProwJobFile:
+			var pj prowapi.ProwJob
+			if err = json.Unmarshal(content, &pj); err == nil {
+				flattenValue(struct {
+					Refs      *prowapi.Refs  `json:"refs,omitempty"`
+					ExtraRefs []prowapi.Refs `json:"extra_refs,omitempty"`
+				}{pj.Spec.Refs, pj.Spec.ExtraRefs}, run.refs)
+			}
+		case podInfoFile:
+			err = flattenJSON(content, "", []string{"pod", "spec"}, run.podSpec)
+		default:
+			err = readTestResults(content, run.tests)
+		}
+		if err != nil {
+			log.WithError(err).Info("Failed to parse artifact.")
+		}
+	}
+	return run, nil
+}
+
+// flattenJSON flattens the JSON object found at the path of the content.
+func flattenJSON(content []byte, prefix string, at []string, fields map[string]string) error {
+	var v interface{}
+	if err := json.Unmarshal(content, &v); err != nil {
+		return err
+	}
+	for _, key := range at {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	flatten(prefix, v, fields)
+	return nil
+}
+
+// flattenValue flattens the JSON representation of the value.
+func flattenValue(value interface{}, fields map[string]string) {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	_ = flattenJSON(content, "", nil, fields)
+}
+
+// flatten records the leaves of the JSON value by their path, e.g.
+// "containers[0].image".
+func flatten(prefix string, v interface{}, fields map[string]string) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			field := key
+			if prefix != "" {
+				field = prefix + "." + key
+			}
+			flatten(field, value, fields)
+		}
+	case []interface{}:
+		for i, value := range v {
+			flatten(fmt.Sprintf("%s[%d]", prefix, i), value, fields)
+		}
+	case nil:
+	case string:
+		fields[prefix] = v
+	default:
+		content, _ := json.Marshal(v)
+		fields[prefix] = string(content)
+	}
+}
+
+// diffFields returns the fields whose values differ, sorted by field.
+func diffFields(base, head map[string]string) []FieldDiff {
+	var diffs []FieldDiff
+	for field, value := range base {
+		if head[field] != value {
+			diffs = append(diffs, FieldDiff{Field: field, Base: value, Head: head[field]})
+		}
+	}
+	for field, value := range head {
+		if _, ok := base[field]; !ok {
+			diffs = append(diffs, FieldDiff{Field: field, Head: value})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Field < diffs[j].Field })
+	return diffs
+}
+
+// readTestResults records the results of the tests of the JUnit file.
+// Tests that both passed and failed, e.g. when retried, are flaky.
+func readTestResults(content []byte, tests map[string]TestResult) error {
+	suites, err := junit.Parse(content)
+	if err != nil {
+		return err
+	}
+	var record func(suite junit.Suite)
+	record = func(suite junit.Suite) {
+		for _, sub := range suite.Suites {
+			record(sub)
+		}
+		for _, result := range suite.Results {
+			name := flakes.TestName(result.ClassName, result.Name)
+			state := TestPassed
+			switch {
+			case result.Skipped != nil:
+				state = TestSkipped
+			case result.Failure != nil || result.Errored != nil:
+				state = TestFailed
+			}
+			duration := time.Duration(result.Time * float64(time.Second))
+			previous, seen := tests[name]
+			switch {
+			case !seen, previous.State == TestSkipped:
+			case previous.State != state && state != TestSkipped:
+				state = TestFlaky
+			default:
+				state = previous.State
+			}
+			tests[name] = TestResult{State: state, Duration: previous.Duration + duration}
+		}
+	}
+	for _, suite := range suites.Suites {
+		record(suite)
+	}
+	return nil
+}
+
+func compareTests(base, head map[string]TestResult) TestComparison {
+	var comparison TestComparison
+	for name, headResult := range head {
+		baseResult, ok := base[name]
+		if !ok {
+			baseResult.State = TestMissing
+		}
+		diff := TestDiff{Name: name, Base: baseResult, Head: headResult}
+		switch {
+		case headResult.State == TestFailed && baseResult.State != TestFailed:
+			comparison.NewFailures = append(comparison.NewFailures, diff)
+		case baseResult.State == TestFailed && headResult.State == TestPassed:
+			comparison.Fixed = append(comparison.Fixed, diff)
+		case baseResult.State == TestPassed && headResult.State == TestPassed &&
+			float64(headResult.Duration) >= durationRegressionFactor*float64(baseResult.Duration) &&
+			headResult.Duration-baseResult.Duration >= minDurationRegression:
+			comparison.DurationRegressions = append(comparison.DurationRegressions, diff)
+		}
+	}
+	byName := func(diffs []TestDiff) {
+		sort.Slice(diffs, func(i, j int) bool { return diffs[i].Name < diffs[j].Name })
+	}
+	byName(comparison.NewFailures)
+	byName(comparison.Fixed)
+	regressions := comparison.DurationRegressions
+	sort.Slice(regressions, func(i, j int) bool {
+		di, dj := regressions[i].Head.Duration-regressions[i].Base.Duration, regressions[j].Head.Duration-regressions[j].Base.Duration
+		if di != dj {
+			return di > dj
+		}
+		return regressions[i].Name < regressions[j].Name
+	})
+	return comparison
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spyglass
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fsouza/fake-gcs-server/fakestorage"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/deck/jobs"
+	"sigs.k8s.io/prow/pkg/io"
+	"sigs.k8s.io/prow/pkg/kube"
+)
+
+func TestCompareRuns(t *testing.T) {
+	object := func(name, content string) fakestorage.Object {
+		return fakestorage.Object{BucketName: "test-bucket", Name: name, Content: []byte(content)}
+	}
+	gcsServer := fakestorage.NewServer([]fakestorage.Object{
+		object("logs/some-job/1/started.json", `{"timestamp": 1, "repo-commit": "aaa"}`),
+		object("logs/some-job/1/finished.json", `{"timestamp": 2, "passed": true, "result": "SUCCESS"}`),
+		object("logs/some-job/1/prowjob.json", `{"spec": {"refs": {"org": "org", "repo": "repo", "base_sha": "aaa"}}}`),
+		object("logs/some-job/1/podinfo.json", `{"pod": {"spec": {"containers": [{"name": "test", "image": "golang:1.21"}]}}}`),
+		object("logs/some-job/1/artifacts/junit_01.xml", `<testsuites><testsuite name="suite">
+<testcase classname="pkg" name="TestA" time="1"></testcase>
+<testcase classname="pkg" name="TestB" time="1"><failure message="boom"/></testcase>
+<testcase classname="pkg" name="TestC" time="10"></testcase>
+<testcase classname="pkg" name="TestD" time="10"></testcase>
+</testsuite></testsuites>`),
+		object("logs/some-job/2/started.json", `{"timestamp": 3, "repo-commit": "bbb"}`),
+		object("logs/some-job/2/finished.json", `{"timestamp": 4, "passed": false, "result": "FAILURE"}`),
+		object("logs/some-job/2/prowjob.json", `{"spec": {"refs": {"org": "org", "repo": "repo", "base_sha": "bbb"}}}`),
+		object("logs/some-job/2/podinfo.json", `{"pod": {"spec": {"containers": [{"name": "test", "image": "golang:1.22"}]}}}`),
+		object("logs/some-job/2/artifacts/junit_01.xml", `<testsuites><testsuite name="suite">
+<testcase classname="pkg" name="TestA" time="1"><failure message="boom"/></testcase>
+<testcase classname="pkg" name="TestB" time="1"></testcase>
+<testcase classname="pkg" name="TestC" time="30"></testcase>
+<testcase classname="pkg" name="TestD" time="12"></testcase>
+<testcase classname="pkg" name="TestE" time="1"><failure message="boom"/></testcase>
+</testsuite></testsuites>`),
+	})
+	defer gcsServer.Stop()
+
+	fakeConfigAgent := fca{
+		c: config.Config{
+			ProwConfig: config.ProwConfig{
+				Deck: config.Deck{
+					AllKnownStorageBuckets: sets.New[string]("test-bucket"),
+					Spyglass:               config.Spyglass{SizeLimit: 1000000},
+				},
+			},
+		},
+	}
+	ja := jobs.NewJobAgent(context.Background(), fkc{}, false, true, []string{}, map[string]jobs.PodLogClient{kube.DefaultClusterAlias: fpkc("clusterA")}, fakeConfigAgent.Config)
+	ja.Start()
+	sg := New(context.Background(), ja, fakeConfigAgent.Config, io.NewGCSOpener(gcsServer.Client()), false)
+
+	base, err := sg.RunSource("gs/test-bucket/logs/some-job", "1")
+	if err != nil {
+		t.Fatalf("Failed to get the source of the base run: %v", err)
+	}
+	head, err := sg.RunSource("gs/test-bucket/logs/some-job/", "2")
+	if err != nil {
+		t.Fatalf("Failed to get the source of the head run: %v", err)
+	}
+	got, err := sg.CompareRuns(context.Background(), base, head)
+	if err != nil {
+		t.Fatalf("Failed to compare runs: %v", err)
+	}
+
+	want := &RunComparison{
+		Job:        "some-job",
+		BaseBuild:  "1",
+		HeadBuild:  "2",
+		BaseSource: "gs/test-bucket/logs/some-job/1",
+		HeadSource: "gs/test-bucket/logs/some-job/2",
+		Metadata: []FieldDiff{
+			{Field: "finished.passed", Base: "true", Head: "false"},
+			{Field: "finished.result", Base: "SUCCESS", Head: "FAILURE"},
+			{Field: "finished.timestamp", Base: "2", Head: "4"},
+			{Field: "started.repo-commit", Base: "aaa", Head: "bbb"},
+			{Field: "started.timestamp", Base: "1", Head: "3"},
+		},
+		Refs: []FieldDiff{
+			{Field: "refs.base_sha", Base: "aaa", Head: "bbb"},
+		},
+		Tests: TestComparison{
+			NewFailures: []TestDiff{
+				{Name: "pkg: TestA", Base: TestResult{State: TestPassed, Duration: time.Second}, Head: TestResult{State: TestFailed, Duration: time.Second}},
+				{Name: "pkg: TestE", Base: TestResult{State: TestMissing}, Head: TestResult{State: TestFailed, Duration: time.Second}},
+			},
+			Fixed: []TestDiff{
+				{Name: "pkg: TestB", Base: TestResult{State: TestFailed, Duration: time.Second}, Head: TestResult{State: TestPassed, Duration: time.Second}},
+			},
+			DurationRegressions: []TestDiff{
+				{Name: "pkg: TestC", Base: TestResult{State: TestPassed, Duration: 10 * time.Second}, Head: TestResult{State: TestPassed, Duration: 30 * time.Second}},
+			},
+		},
+		PodSpec: []FieldDiff{
+			{Field: "containers[0].image", Base: "golang:1.21", Head: "golang:1.22"},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected comparison (-want +got):\n%s", diff)
+	}
+
+	if _, err := sg.CompareRuns(context.Background(), base, "gs/test-bucket/logs/other-job/2"); err == nil {
+		t.Error("Expected an error comparing runs of different jobs")
+	}
+}
+
+func TestReadTestResults(t *testing.T) {
+	tests := map[string]TestResult{}
+	if err := readTestResults([]byte(`<testsuite name="suite">
+<testcase classname="pkg" name="TestA" time="1"><failure message="boom"/></testcase>
+<testcase classname="pkg" name="TestA" time="2"></testcase>
+<testcase classname="pkg" name="TestB" time="1"><skipped/></testcase>
+<testcase classname="pkg" name="TestB" time="1"></testcase>
+<testcase classname="pkg" name="TestC" time="1"><error message="boom"/></testcase>
+</testsuite>`), tests); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := map[string]TestResult{
+		"pkg: TestA": {State: TestFlaky, Duration: 3 * time.Second},
+		"pkg: TestB": {State: TestPassed, Duration: 2 * time.Second},
+		"pkg: TestC": {State: TestFailed, Duration: time.Second},
+	}
+	if diff := cmp.Diff(want, tests); diff != "" {
+		t.Errorf("Unexpected results (-want +got):\n%s", diff)
+	}
+}
+
+func TestRunSourceRejectsDotSegments(t *testing.T) {
+	sg := &Spyglass{}
+	for _, tc := range []struct {
+		jobPath string
+		buildID string
+	}{
+		{jobPath: "gs/bucket/logs/job", buildID: ".."},
+		{jobPath: "gs/bucket/logs/job", buildID: "."},
+		{jobPath: "gs/bucket/logs/job", buildID: "1/2"},
+		{jobPath: "gs/bucket/logs/job", buildID: `..\admin`},
+		{jobPath: "gs/bucket/logs/job", buildID: ""},
+		{jobPath: "gs/bucket/logs/../other", buildID: "1"},
+		{jobPath: "gs/bucket/./job", buildID: "1"},
+		{jobPath: `gs/bucket/logs/..\other`, buildID: "1"},
+	} {
+		if src, err := sg.RunSource(tc.jobPath, tc.buildID); err == nil {
+			t.Errorf("Expected an error for job path %q and build %q, got source %q", tc.jobPath, tc.buildID, src)
+		}
+	}
+}
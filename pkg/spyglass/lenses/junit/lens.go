@@ -22,14 +22,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"net/url"
 	"path/filepath"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/GoogleCloudPlatform/testgrid/metadata/junit"
 	"github.com/sirupsen/logrus"
 
 	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/flakes"
 	"sigs.k8s.io/prow/pkg/spyglass/api"
 	"sigs.k8s.io/prow/pkg/spyglass/lenses"
 )
@@ -41,6 +45,15 @@ const (
 	passedStatus  testStatus = "Passed"
 	failedStatus  testStatus = "Failed"
 	skippedStatus testStatus = "Skipped"
+
+	// flakeReportTTL is how long the flaky test report is cached.
+	flakeReportTTL = 5 * time.Minute
+)
+
+var (
+	flakeClientsLock sync.Mutex
+	// flakeClients are the clients of the flaky test reports, by URL.
+	flakeClients = map[string]*flakes.Client{}
 )
 
 func init() {
@@ -52,6 +65,12 @@ type testStatus string
 // Lens is the implementation of a JUnit-rendering Spyglass lens.
 type Lens struct{}
 
+type lensConfig struct {
+	// FlakeReportURL is the URL of the /flakes.js endpoint of Deck. When set,
+	// the failures of the tests known to be flaky for the job are labelled.
+	FlakeReportURL string `json:"flake_report_url,omitempty"`
+}
+
 type JVD struct {
 	NumTests int
 	Passed   []TestResult
@@ -117,11 +136,16 @@ func (jr JunitResult) SkippedReason() string {
 type TestResult struct {
 	Junit []JunitResult
 	Link  string
+	// KnownFlake is true when the test failed and is known to be flaky.
+	KnownFlake bool
 }
 
 // Body renders the <body> for JUnit tests
 func (lens Lens) Body(artifacts []api.Artifact, resourceDir string, data string, config json.RawMessage, spyglassConfig config.Spyglass) string {
 	jvd := lens.getJvd(artifacts)
+	if report := flakeReport(config); report != nil && len(artifacts) > 0 {
+		labelKnownFlakes(&jvd, report, jobName(artifacts[0]))
+	}
 
 	junitTemplate, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
 	if err != nil {
@@ -280,3 +304,62 @@ func (lens Lens) getJvd(artifacts []api.Artifact) JVD {
 	jvd.NumTests = len(jvd.Passed) + len(jvd.Failed) + len(jvd.Flaky) + len(jvd.Skipped) - duplicates
 	return jvd
 }
+
+// flakeReport returns the flaky test report configured for the lens, if any.
+func flakeReport(rawConfig json.RawMessage) *flakes.Report {
+	if len(rawConfig) == 0 {
+		return nil
+	}
+	var conf lensConfig
+	if err := json.Unmarshal(rawConfig, &conf); err != nil {
+		logrus.WithError(err).Error("Failed to decode junit config")
+		return nil
+	}
+	if conf.FlakeReportURL == "" {
+		return nil
+	}
+
+	flakeClientsLock.Lock()
+	client, ok := flakeClients[conf.FlakeReportURL]
+	if !ok {
+		client = flakes.NewClient(conf.FlakeReportURL, flakeReportTTL)
+		flakeClients[conf.FlakeReportURL] = client
+	}
+	flakeClientsLock.Unlock()
+
+	report, err := client.Report()
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to get the flaky test report.")
+		return nil
+	}
+	return report
+}
+
+// jobName returns the name of the job that uploaded the artifact, which is
+// stored under .../<job>/<build>/<path within the job>.
+func jobName(artifact api.Artifact) string {
+	u, err := url.Parse(artifact.CanonicalLink())
+	if err != nil {
+		return ""
+	}
+	if !strings.HasSuffix(u.Path, "/"+artifact.JobPath()) {
+		return ""
+	}
+	parts := strings.Split(strings.TrimSuffix(u.Path, "/"+artifact.JobPath()), "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[len(parts)-2]
+}
+
+// labelKnownFlakes labels the failed tests that are known to be flaky for the
+// job.
+func labelKnownFlakes(jvd *JVD, report *flakes.Report, job string) {
+	if job == "" {
+		return
+	}
+	for i, test := range jvd.Failed {
+		first := test.Junit[0]
+		jvd.Failed[i].KnownFlake = report.IsFlaky(job, flakes.TestName(first.ClassName, first.Name))
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"k8s.io/utils/ptr"
 
+	"sigs.k8s.io/prow/pkg/flakes"
 	"sigs.k8s.io/prow/pkg/spyglass/api"
 	"sigs.k8s.io/prow/pkg/spyglass/lenses"
 )
@@ -45,6 +46,7 @@ type FakeArtifact struct {
 	path      string
 	content   []byte
 	sizeLimit int64
+	link      string
 }
 
 func (fa *FakeArtifact) JobPath() string {
@@ -56,6 +58,9 @@ func (fa *FakeArtifact) Size() (int64, error) {
 }
 
 func (fa *FakeArtifact) CanonicalLink() string {
+	if fa.link != "" {
+		return fa.link
+	}
 	return fakeCanonicalLink
 }
 
@@ -908,6 +913,16 @@ func TestTemplate(t *testing.T) {
 				`error`,
 			},
 		},
+		{
+			name: "Known flakes get labelled",
+			input: JVD{NumTests: 1, Failed: []TestResult{{
+				Junit:      []JunitResult{{Result: junit.Result{Name: "TestA"}}},
+				KnownFlake: true,
+			}}},
+			expectedSubstrings: []string{
+				`<span class="known-flake"`,
+			},
+		},
 		{
 			name: "Both stdout and stderr get rendered for flaky tests",
 			input: JVD{NumTests: 1, Flaky: []TestResult{{
@@ -947,3 +962,49 @@ func TestTemplate(t *testing.T) {
 		})
 	}
 }
+
+func TestJobName(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		artifact *FakeArtifact
+		want     string
+	}{
+		{
+			name:     "gcsweb link",
+			artifact: &FakeArtifact{path: "artifacts/junit_01.xml", link: "https://gcsweb.k8s.io/gcs/bucket/logs/my-job/123/artifacts/junit_01.xml"},
+			want:     "my-job",
+		},
+		{
+			name:     "signed URL of a presubmit",
+			artifact: &FakeArtifact{path: "artifacts/junit_01.xml", link: "https://storage.googleapis.com/bucket/pr-logs/pull/org_repo/1/pull-job/123/artifacts/junit_01.xml?X-Goog-Signature=abc"},
+			want:     "pull-job",
+		},
+		{
+			name:     "unknown link",
+			artifact: &FakeArtifact{path: "artifacts/junit_01.xml"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := jobName(tc.artifact); got != tc.want {
+				t.Errorf("Expected job %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestLabelKnownFlakes(t *testing.T) {
+	jvd := JVD{Failed: []TestResult{
+		{Junit: []JunitResult{{Result: junit.Result{ClassName: "pkg", Name: "TestA"}}}},
+		{Junit: []JunitResult{{Result: junit.Result{ClassName: "pkg", Name: "TestB"}}}},
+	}}
+	report := &flakes.Report{Flakes: []flakes.Flake{{Job: "job", Test: "pkg: TestA"}, {Job: "other", Test: "pkg: TestB"}}}
+
+	labelKnownFlakes(&jvd, report, "job")
+	var got []bool
+	for _, test := range jvd.Failed {
+		got = append(got, test.KnownFlake)
+	}
+	if diff := cmp.Diff([]bool{true, false}, got); diff != "" {
+		t.Errorf("Unexpected known flakes: %s", diff)
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package logsteps provides a lens that groups build logs into steps and
+// jumps to their first error.
+package logsteps
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	prowconfig "sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/loganalysis"
+	"sigs.k8s.io/prow/pkg/spyglass/api"
+	"sigs.k8s.io/prow/pkg/spyglass/lenses"
+)
+
+const name = "logsteps"
+
+func init() {
+	lenses.RegisterLens(Lens{})
+}
+
+// Lens is the implementation of a log steps rendering Spyglass lens.
+type Lens struct{}
+
+// Config returns the lens's configuration.
+func (lens Lens) Config() lenses.LensConfig {
+	return lenses.LensConfig{
+		Name:     name,
+		Title:    "Build Log Steps",
+		Priority: 9,
+	}
+}
+
+// Header returns the content of <head>
+func (lens Lens) Header(artifacts []api.Artifact, resourceDir string, config json.RawMessage, spyglassConfig prowconfig.Spyglass) string {
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		return fmt.Sprintf("<!-- FAILED LOADING HEADER: %v -->", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, "header", nil); err != nil {
+		return fmt.Sprintf("<!-- FAILED EXECUTING HEADER TEMPLATE: %v -->", err)
+	}
+	return buf.String()
+}
+
+// Callback is used to retrieve new log segments
+func (lens Lens) Callback(artifacts []api.Artifact, resourceDir string, data string, config json.RawMessage, spyglassConfig prowconfig.Spyglass) string {
+	return ""
+}
+
+// Line is a line of a step.
+type Line struct {
+	Number     int
+	Text       string
+	FirstError bool
+}
+
+// StepView is a step of a log.
+type StepView struct {
+	loganalysis.Step
+	Lines []Line
+}
+
+// LogView is the analysis of a log artifact.
+type LogView struct {
+	ArtifactName string
+	ArtifactLink string
+	Steps        []StepView
+	Signature    *loganalysis.Signature
+	// FirstErrorID is the ID of the element of the first error, if any.
+	FirstErrorID string
+}
+
+// LineID returns the ID of the element of the line.
+func (v LogView) LineID(number int) string {
+	return fmt.Sprintf("%s:steps:%d", v.ArtifactName, number)
+}
+
+// Body returns the <body> content for the steps of the build logs.
+func (lens Lens) Body(artifacts []api.Artifact, resourceDir string, data string, config json.RawMessage, spyglassConfig prowconfig.Spyglass) string {
+	var views []LogView
+	for _, a := range artifacts {
+		view, err := analyze(a)
+		if err != nil {
+			logrus.WithError(err).Info("Error analyzing log.")
+			continue
+		}
+		views = append(views, *view)
+	}
+
+	t, err := template.ParseFiles(filepath.Join(resourceDir, "template.html"))
+	if err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+		return fmt.Sprintf("Failed to load template file: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, "body", views); err != nil {
+		logrus.WithError(err).Error("Error executing template.")
+	}
+	return buf.String()
+}
+
+// analyze groups the lines of the log artifact into steps.
+func analyze(a api.Artifact) (*LogView, error) {
+	content, err := a.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log %q: %w", a.JobPath(), err)
+	}
+	lines := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	if len(content) == 0 {
+		lines = nil
+	}
+	analysis := loganalysis.AnalyzeLines(lines)
+
+	view := &LogView{
+		ArtifactName: a.JobPath(),
+		ArtifactLink: a.CanonicalLink(),
+		Signature:    analysis.Signature,
+	}
+	if analysis.FirstError > 0 {
+		view.FirstErrorID = view.LineID(analysis.FirstError)
+	}
+	for _, step := range analysis.Steps {
+		sv := StepView{Step: step}
+		for num := step.Start; num <= step.End; num++ {
+			sv.Lines = append(sv.Lines, Line{
+				Number:     num,
+				Text:       lines[num-1],
+				FirstError: num == analysis.FirstError,
+			})
+		}
+		view.Steps = append(view.Steps, sv)
+	}
+	return view, nil
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logsteps
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/spyglass/api"
+	"sigs.k8s.io/prow/pkg/spyglass/lenses/fake"
+)
+
+const log = `=== RUN   TestA
+--- PASS: TestA (0.00s)
+ok  	example.com/a	0.012s
+=== RUN   TestB
+--- FAIL: TestB (0.01s)
+FAIL	example.com/b	0.020s
+`
+
+func TestAnalyze(t *testing.T) {
+	view, err := analyze(&fake.Artifact{Path: "build-log.txt", Content: []byte(log)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var steps []string
+	for _, step := range view.Steps {
+		steps = append(steps, step.Name)
+	}
+	if diff := cmp.Diff([]string{"go test example.com/a", "go test example.com/b"}, steps); diff != "" {
+		t.Errorf("Unexpected steps: %s", diff)
+	}
+	if got := view.Steps[1].Lines[0]; got.Number != 4 || got.Text != "=== RUN   TestB" {
+		t.Errorf("Unexpected first line of the second step: %+v", got)
+	}
+	if !view.Steps[1].Lines[1].FirstError {
+		t.Errorf("Expected line 5 to be the first error")
+	}
+	if view.FirstErrorID != "build-log.txt:steps:5" {
+		t.Errorf("Unexpected ID of the first error %q", view.FirstErrorID)
+	}
+	if view.Signature == nil || view.Signature.Text != "--- FAIL: TestB (<duration>)" {
+		t.Errorf("Unexpected signature %+v", view.Signature)
+	}
+}
+
+func TestBody(t *testing.T) {
+	artifacts := []api.Artifact{
+		&fake.Artifact{Path: "build-log.txt", Content: []byte(log)},
+		&fake.Artifact{Path: "empty-build-log.txt"},
+	}
+	body := Lens{}.Body(artifacts, ".", "", nil, config.Spyglass{})
+	for _, want := range []string{
+		`<details class="step failed" open>`,
+		`data-target="build-log.txt:steps:5"`,
+		`<div class="line first-error" id="build-log.txt:steps:5">`,
+		`empty-build-log.txt`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in the body:\n%s", want, body)
+		}
+	}
+}
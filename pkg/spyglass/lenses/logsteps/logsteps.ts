@@ -0,0 +1,22 @@
+window.addEventListener('load', () => {
+  document.querySelectorAll<HTMLDetailsElement>('details.step').forEach((e) => {
+    e.addEventListener('toggle', () => spyglass.contentUpdated());
+  });
+
+  document.querySelectorAll<HTMLAnchorElement>('a.jump-to-error').forEach((e) => {
+    e.onclick = (event) => {
+      const target = document.getElementById(e.dataset.target || '');
+      if (!target) {
+        return;
+      }
+      event.preventDefault();
+      const step = target.closest('details');
+      if (step) {
+        step.open = true;
+      }
+      spyglass.contentUpdated();
+      const top = target.getBoundingClientRect().top + window.pageYOffset;
+      spyglass.scrollTo(0, top);
+    };
+  });
+});
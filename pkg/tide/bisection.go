@@ -0,0 +1,170 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/kube"
+)
+
+// A failed batch is bisected by testing both of its halves as batches. The
+// halves that fail are bisected in turn, until a batch of a single PR fails:
+// that PR is the culprit and it is kept out of the batches until its head
+// changes. The halves that pass are merged like any other batch.
+
+// bisect splits the batch in two halves.
+func bisect(batch []CodeReviewCommon) ([]CodeReviewCommon, []CodeReviewCommon) {
+	half := (len(batch) + 1) / 2
+	return batch[:half], batch[half:]
+}
+
+// batchKey identifies the PRs of a batch regardless of their order.
+func batchKey(prs []CodeReviewCommon) string {
+	nums := prNumbers(prs)
+	sort.Ints(nums)
+	keys := make([]string, 0, len(nums))
+	for _, num := range nums {
+		keys = append(keys, strconv.Itoa(num))
+	}
+	return strings.Join(keys, ",")
+}
+
+// batchFailed returns whether all the required presubmits of the batch
+// completed and at least one of them failed.
+func batchFailed(state *batchState, requiredPresubmits []config.Presubmit) bool {
+	var failed bool
+	for _, ps := range requiredPresubmits {
+		switch s, ok := state.jobStates[ps.Context]; {
+		case !ok, s == pendingState:
+			return false
+		case s == failureState:
+			failed = true
+		}
+	}
+	return failed
+}
+
+// batchesToBisect returns the failed batches of the subpool whose halves were
+// not tested yet, the smallest first.
+func (c *syncController) batchesToBisect(sp subpool) [][]CodeReviewCommon {
+	states := batchStates(sp)
+	tested := sets.New[string]()
+	for _, state := range states {
+		if state.validPulls {
+			tested.Insert(batchKey(state.prs))
+		}
+	}
+
+	var batches [][]CodeReviewCommon
+	for ref, state := range states {
+		if !state.validPulls || len(state.prs) < 2 {
+			continue
+		}
+		requiredPresubmits, err := c.presubmitsForBatch(state.prs, sp.org, sp.repo, sp.sha, sp.branch)
+		if err != nil {
+			sp.log.WithError(err).WithField("batch", ref).Error("Error getting presubmits for batch")
+			continue
+		}
+		if !batchFailed(state, requiredPresubmits) {
+			continue
+		}
+		first, second := bisect(state.prs)
+		if tested.Has(batchKey(first)) && tested.Has(batchKey(second)) {
+			continue
+		}
+		batches = append(batches, state.prs)
+	}
+	sort.SliceStable(batches, func(i, j int) bool {
+		if len(batches[i]) != len(batches[j]) {
+			return len(batches[i]) < len(batches[j])
+		}
+		return batchKey(batches[i]) < batchKey(batches[j])
+	})
+	return batches
+}
+
+// triggerBisection triggers the halves of the failed batch, as batch jobs
+// even if they contain a single PR.
+func (c *syncController) triggerBisection(sp subpool, batch []CodeReviewCommon) error {
+	first, second := bisect(batch)
+	for _, prs := range [][]CodeReviewCommon{first, second} {
+		presubmits, err := c.presubmitsForBatch(prs, sp.org, sp.repo, sp.sha, sp.branch)
+		if err != nil {
+			return err
+		}
+		if err := c.triggerJobs(sp, presubmits, prs, true, map[string]string{kube.TideBisectionLabel: "true"}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bisectionCulprits returns the PRs of the subpool whose batch of a single PR
+// failed while bisecting, along with the contexts that failed. The base of
+// the batch doesn't matter, a PR stays a culprit until its head changes.
+func (c *syncController) bisectionCulprits(sp *subpool) (map[int][]string, error) {
+	if !c.config().Tide.BisectFailedBatches(config.OrgRepo{Org: sp.org, Repo: sp.repo}) {
+		return nil, nil
+	}
+	pjs := &prowapi.ProwJobList{}
+	if err := c.prowJobClient.List(
+		c.ctx,
+		pjs,
+		ctrlruntimeclient.MatchingLabels{kube.TideBisectionLabel: "true"},
+		ctrlruntimeclient.InNamespace(c.config().ProwJobNamespace),
+	); err != nil {
+		return nil, fmt.Errorf("failed to list bisection jobs: %w", err)
+	}
+
+	heads := make(map[int]string, len(sp.prs))
+	for _, pr := range sp.prs {
+		heads[pr.Number] = pr.HeadRefOID
+	}
+	contexts := make(map[int]sets.Set[string])
+	for _, pj := range pjs.Items {
+		refs := pj.Spec.Refs
+		if pj.Status.State != prowapi.FailureState || refs == nil || len(refs.Pulls) != 1 {
+			continue
+		}
+		if refs.Org != sp.org || refs.Repo != sp.repo || refs.BaseRef != sp.branch {
+			continue
+		}
+		pull := refs.Pulls[0]
+		if head, ok := heads[pull.Number]; !ok || head != pull.SHA {
+			continue
+		}
+		if contexts[pull.Number] == nil {
+			contexts[pull.Number] = sets.New[string]()
+		}
+		contexts[pull.Number].Insert(pj.Spec.Context)
+	}
+
+	culprits := make(map[int][]string, len(contexts))
+	for num, ctxs := range contexts {
+		culprits[num] = sets.List(ctxs)
+	}
+	return culprits, nil
+}
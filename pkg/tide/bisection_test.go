@@ -0,0 +1,233 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tide
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/config"
+	"sigs.k8s.io/prow/pkg/kube"
+)
+
+func bisectionTestPRs(n int) []CodeReviewCommon {
+	var prs []CodeReviewCommon
+	for i := 1; i <= n; i++ {
+		prs = append(prs, CodeReviewCommon{Org: "org", Repo: "repo", NameWithOwner: "org/repo", Number: i, HeadRefOID: string(rune('a' + i - 1))})
+	}
+	return prs
+}
+
+func bisectionTestJob(context string, state prowapi.ProwJobState, prs ...CodeReviewCommon) prowapi.ProwJob {
+	pj := prowapi.ProwJob{
+		Spec: prowapi.ProwJobSpec{
+			Job:     context,
+			Context: context,
+			Type:    prowapi.BatchJob,
+			Refs:    &prowapi.Refs{Org: "org", Repo: "repo", BaseRef: "main", BaseSHA: "base"},
+		},
+		Status: prowapi.ProwJobStatus{State: state},
+	}
+	for _, pr := range prs {
+		pj.Spec.Refs.Pulls = append(pj.Spec.Refs.Pulls, prowapi.Pull{Number: pr.Number, SHA: pr.HeadRefOID})
+	}
+	return pj
+}
+
+func bisectionTestConfig() config.Getter {
+	return func() *config.Config {
+		return &config.Config{
+			JobConfig: config.JobConfig{
+				PresubmitsStatic: map[string][]config.Presubmit{
+					"org/repo": {
+						{JobBase: config.JobBase{Name: "foo"}, AlwaysRun: true, Reporter: config.Reporter{Context: "foo"}},
+						{JobBase: config.JobBase{Name: "bar"}, AlwaysRun: true, Reporter: config.Reporter{Context: "bar"}},
+					},
+				},
+			},
+			ProwConfig: config.ProwConfig{
+				ProwJobNamespace: "prowjobs",
+				Tide: config.Tide{
+					BisectFailedBatchesMap: map[string]bool{"org/repo": true},
+				},
+			},
+		}
+	}
+}
+
+func TestBatchesToBisect(t *testing.T) {
+	prs := bisectionTestPRs(4)
+	for _, tc := range []struct {
+		name string
+		pjs  []prowapi.ProwJob
+		want [][]int
+	}{
+		{
+			name: "no batch",
+		},
+		{
+			name: "failed batch",
+			pjs: []prowapi.ProwJob{
+				bisectionTestJob("foo", prowapi.FailureState, prs...),
+				bisectionTestJob("bar", prowapi.SuccessState, prs...),
+			},
+			want: [][]int{{1, 2, 3, 4}},
+		},
+		{
+			name: "batch still running",
+			pjs: []prowapi.ProwJob{
+				bisectionTestJob("foo", prowapi.FailureState, prs...),
+				bisectionTestJob("bar", prowapi.PendingState, prs...),
+			},
+		},
+		{
+			name: "passing batch",
+			pjs: []prowapi.ProwJob{
+				bisectionTestJob("foo", prowapi.SuccessState, prs...),
+				bisectionTestJob("bar", prowapi.SuccessState, prs...),
+			},
+		},
+		{
+			name: "failed batch whose halves are tested",
+			pjs: []prowapi.ProwJob{
+				bisectionTestJob("foo", prowapi.FailureState, prs...),
+				bisectionTestJob("bar", prowapi.SuccessState, prs...),
+				bisectionTestJob("foo", prowapi.PendingState, prs[:2]...),
+				bisectionTestJob("foo", prowapi.PendingState, prs[2:]...),
+			},
+		},
+		{
+			name: "failed half is bisected in turn",
+			pjs: []prowapi.ProwJob{
+				bisectionTestJob("foo", prowapi.FailureState, prs...),
+				bisectionTestJob("bar", prowapi.SuccessState, prs...),
+				bisectionTestJob("foo", prowapi.FailureState, prs[:2]...),
+				bisectionTestJob("bar", prowapi.SuccessState, prs[:2]...),
+				bisectionTestJob("foo", prowapi.PendingState, prs[2:]...),
+			},
+			want: [][]int{{1, 2}},
+		},
+		{
+			name: "failed batch with a PR whose head changed",
+			pjs: []prowapi.ProwJob{
+				bisectionTestJob("foo", prowapi.FailureState, append([]CodeReviewCommon{{Number: 1, HeadRefOID: "old"}}, prs[1:]...)...),
+				bisectionTestJob("bar", prowapi.SuccessState, append([]CodeReviewCommon{{Number: 1, HeadRefOID: "old"}}, prs[1:]...)...),
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &syncController{
+				config:       bisectionTestConfig(),
+				provider:     newGitHubProvider(logrus.WithField("test", tc.name), nil, nil, bisectionTestConfig(), nil, false),
+				changedFiles: &changedFilesAgent{},
+				logger:       logrus.WithField("test", tc.name),
+			}
+			sp := subpool{org: "org", repo: "repo", branch: "main", sha: "base", prs: prs, pjs: tc.pjs, log: logrus.WithField("test", tc.name)}
+
+			var got [][]int
+			for _, batch := range c.batchesToBisect(sp) {
+				got = append(got, prNumbers(batch))
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected batches to bisect: %s", diff)
+			}
+		})
+	}
+}
+
+func TestTriggerBisection(t *testing.T) {
+	ctx := context.Background()
+	mgr := newFakeManager(t, ctx)
+	c := &syncController{
+		ctx:           ctx,
+		config:        bisectionTestConfig(),
+		provider:      newGitHubProvider(logrus.WithField("test", t.Name()), nil, nil, bisectionTestConfig(), nil, false),
+		prowJobClient: mgr.GetClient(),
+		changedFiles:  &changedFilesAgent{},
+		logger:        logrus.WithField("test", t.Name()),
+	}
+	sp := subpool{org: "org", repo: "repo", branch: "main", sha: "base", prs: bisectionTestPRs(3), log: logrus.WithField("test", t.Name())}
+
+	if err := c.triggerBisection(sp, sp.prs); err != nil {
+		t.Fatalf("Failed to trigger the bisection: %v", err)
+	}
+
+	pjs := &prowapi.ProwJobList{}
+	if err := c.prowJobClient.List(ctx, pjs); err != nil {
+		t.Fatalf("Failed to list ProwJobs: %v", err)
+	}
+	var got []string
+	for _, pj := range pjs.Items {
+		if pj.Spec.Type != prowapi.BatchJob {
+			t.Errorf("Expected a batch job but got a %s job", pj.Spec.Type)
+		}
+		if pj.Labels[kube.TideBisectionLabel] != "true" {
+			t.Errorf("Expected the %s label on the ProwJob, got labels %v", kube.TideBisectionLabel, pj.Labels)
+		}
+		var prs []CodeReviewCommon
+		for _, pull := range pj.Spec.Refs.Pulls {
+			prs = append(prs, CodeReviewCommon{Number: pull.Number})
+		}
+		got = append(got, pj.Spec.Context+":"+batchKey(prs))
+	}
+	sort.Strings(got)
+	if diff := cmp.Diff([]string{"bar:1,2", "bar:3", "foo:1,2", "foo:3"}, got); diff != "" {
+		t.Errorf("Unexpected bisection jobs: %s", diff)
+	}
+}
+
+func TestBisectionCulprits(t *testing.T) {
+	prs := bisectionTestPRs(4)
+	bisectionJob := func(context string, state prowapi.ProwJobState, prs ...CodeReviewCommon) runtime.Object {
+		pj := bisectionTestJob(context, state, prs...)
+		pj.Name = context + batchKey(prs) + string(state)
+		pj.Namespace = "prowjobs"
+		pj.Labels = map[string]string{kube.TideBisectionLabel: "true"}
+		return &pj
+	}
+	ctx := context.Background()
+	mgr := newFakeManager(t, ctx,
+		bisectionJob("foo", prowapi.FailureState, prs[0]),
+		bisectionJob("bar", prowapi.FailureState, prs[0]),
+		bisectionJob("foo", prowapi.SuccessState, prs[1]),
+		bisectionJob("foo", prowapi.FailureState, prs[2:]...),
+		// The head of the PR changed since.
+		bisectionJob("foo", prowapi.FailureState, CodeReviewCommon{Number: 4, HeadRefOID: "old"}),
+	)
+	c := &syncController{
+		ctx:           ctx,
+		config:        bisectionTestConfig(),
+		prowJobClient: mgr.GetClient(),
+	}
+
+	culprits, err := c.bisectionCulprits(&subpool{org: "org", repo: "repo", branch: "main", sha: "newbase", prs: prs})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(map[int][]string{1: {"bar", "foo"}}, culprits); diff != "" {
+		t.Errorf("Unexpected culprits: %s", diff)
+	}
+	if diff := cmp.Diff(map[string]string{"org/repo#1": "In merge pool. Broke a batch (bar, foo), kept out of batches until updated."}, poolStatusMap(map[string]*subpool{"org/repo:main": {org: "org", repo: "repo", prs: prs, culprits: culprits}}, &c.config().Tide)); diff != "" {
+		t.Errorf("Unexpected pool statuses: %s", diff)
+	}
+}
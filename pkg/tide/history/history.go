@@ -15,12 +15,14 @@ limitations under the License.
 */
 
 // Package history provides an append only, size limited log of recent actions
-// that Tide has taken for each subpool.
+// that Tide has taken for each subpool. The actions can also be persisted to
+// segments per hour, which are queryable beyond the recent actions.
 package history
 
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	stdio "io"
 	"net/http"
@@ -47,6 +49,8 @@ type History struct {
 
 	opener opener
 	path   string
+
+	segments *segmentStore
 }
 
 // opener has methods to read and write paths
@@ -148,6 +152,16 @@ func New(maxRecordsPerKey int, opener io.Opener, path string) (*History, error)
 	return hist, nil
 }
 
+// EnableSegments persists every record to the segments under the prefix, in
+// addition to the recent records. The prefix can be a /local/path,
+// gs://path/to/dir or s3://path/to/dir.
+func (h *History) EnableSegments(prefix string) {
+	if prefix == "" {
+		return
+	}
+	h.segments = newSegmentStore(h.opener, prefix)
+}
+
 // Record appends an entry to the recordlog specified by the poolKey.
 func (h *History) Record(poolKey, action, baseSHA, err string, targets []prowapi.Pull, tenantIDs []string) {
 	t := now()
@@ -166,6 +180,9 @@ func (h *History) Record(poolKey, action, baseSHA, err string, targets []prowapi
 }
 
 func (h *History) addRecord(poolKey string, rec *Record) {
+	if h.segments != nil {
+		h.segments.add(poolKey, rec)
+	}
 	h.Lock()
 	defer h.Unlock()
 	if _, ok := h.logs[poolKey]; !ok {
@@ -175,7 +192,14 @@ func (h *History) addRecord(poolKey string, rec *Record) {
 }
 
 // ServeHTTP serves a JSON mapping from pool key -> sorted records for the pool.
+// If the request has query parameters, it serves a page of the records
+// matching the query instead, see ParseQuery.
 func (h *History) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if IsQuery(r.URL.Query()) {
+		h.serveQuery(w, r)
+		return
+	}
+
 	b, err := json.Marshal(h.AllRecords())
 	if err != nil {
 		logrus.WithError(err).Error("Encoding JSON history.")
@@ -186,8 +210,39 @@ func (h *History) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (h *History) serveQuery(w http.ResponseWriter, r *http.Request) {
+	q, err := ParseQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	res, err := h.Query(r.Context(), *q)
+	if errors.Is(err, errInvalidQuery) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Querying history.")
+		http.Error(w, "failed to query the history", http.StatusInternalServerError)
+		return
+	}
+	b, err := json.Marshal(res)
+	if err != nil {
+		logrus.WithError(err).Error("Encoding JSON history query result.")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(b); err != nil {
+		logrus.WithError(err).Debug("Writing JSON history query response.")
+	}
+}
+
 // Flush writes the action history to persistent storage if configured to do so.
 func (h *History) Flush() {
+	if h.segments != nil {
+		h.flushSegments()
+	}
 	if h.path == "" {
 		return
 	}
@@ -205,6 +260,18 @@ func (h *History) Flush() {
 	}
 }
 
+func (h *History) flushSegments() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	start := time.Now()
+	log := logrus.WithField("prefix", h.segments.prefix)
+	if err := h.segments.flush(ctx); err != nil {
+		log.WithError(err).Error("Error flushing action history segments.")
+		return
+	}
+	log.WithField("duration", time.Since(start).String()).Debug("Successfully flushed action history segments.")
+}
+
 // AllRecords generates a map from pool key -> sorted records for the pool.
 func (h *History) AllRecords() map[string][]*Record {
 	h.Lock()
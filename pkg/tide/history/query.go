@@ -0,0 +1,260 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultQueryPageSize = 100
+	maxQueryPageSize     = 1000
+	defaultQueryRange    = 24 * time.Hour
+	// maxQueryRange bounds the number of segments read by a query.
+	maxQueryRange = 31 * 24 * time.Hour
+)
+
+// errInvalidQuery is wrapped by the errors caused by the query itself rather
+// than by the storage of the records.
+var errInvalidQuery = errors.New("invalid query")
+
+// Query selects the records of the history. The zero values of the
+// filters match all records.
+type Query struct {
+	// From and To bound the time of the records, both inclusive.
+	From time.Time
+	To   time.Time
+	// Repo is the "org/repo" of the pool.
+	Repo string
+	// PR is the number of a pull request in the target of the records.
+	PR int
+	// Author is the author of a pull request in the target of the records.
+	Author string
+	// Action is the action of the records, e.g. MERGE or TRIGGER_BATCH.
+	Action string
+
+	PageSize  int
+	PageToken string
+}
+
+// QueryResult is a page of records, the most recent first.
+type QueryResult struct {
+	Records []PoolRecord `json:"records"`
+	// NextPageToken is set if there are more records, pass it as the
+	// page_token of the same query to get them.
+	NextPageToken string `json:"next_page_token,omitempty"`
+}
+
+// pageToken pins the time range of the query, so that the records added
+// after the first page don't shift the next pages.
+type pageToken struct {
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	Offset int       `json:"offset"`
+}
+
+func (p pageToken) encode() string {
+	b, _ := json.Marshal(p)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodePageToken(token string) (pageToken, error) {
+	var p pageToken
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return p, fmt.Errorf("decode: %w", err)
+	}
+	if err := json.Unmarshal(b, &p); err != nil {
+		return p, fmt.Errorf("unmarshal: %w", err)
+	}
+	if p.Offset < 0 {
+		return p, errors.New("negative offset")
+	}
+	return p, nil
+}
+
+// IsQuery returns whether the request parameters ask for a query rather
+// than for the recent records of all pools.
+func IsQuery(values url.Values) bool {
+	for _, param := range []string{"from", "to", "repo", "pr", "author", "action", "page_size", "page_token"} {
+		if values.Has(param) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseQueryTime accepts RFC3339 times and YYYY-MM-DD dates. The end of
+// range dates include the whole day.
+func parseQueryTime(value string, endOfRange bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(dayLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a RFC3339 time nor a YYYY-MM-DD date", value)
+	}
+	if endOfRange {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
+
+// ParseQuery parses the query from the request parameters.
+func ParseQuery(values url.Values) (*Query, error) {
+	q := &Query{
+		Repo:      values.Get("repo"),
+		Author:    values.Get("author"),
+		Action:    values.Get("action"),
+		PageToken: values.Get("page_token"),
+	}
+	var err error
+	if v := values.Get("from"); v != "" {
+		if q.From, err = parseQueryTime(v, false); err != nil {
+			return nil, fmt.Errorf("invalid from: %w", err)
+		}
+	}
+	if v := values.Get("to"); v != "" {
+		if q.To, err = parseQueryTime(v, true); err != nil {
+			return nil, fmt.Errorf("invalid to: %w", err)
+		}
+	}
+	if v := values.Get("pr"); v != "" {
+		if q.PR, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("invalid pr %q: %w", v, err)
+		}
+	}
+	if v := values.Get("page_size"); v != "" {
+		if q.PageSize, err = strconv.Atoi(v); err != nil || q.PageSize <= 0 {
+			return nil, fmt.Errorf("invalid page_size %q", v)
+		}
+	}
+	return q, nil
+}
+
+// normalize defaults the time range and the page size and applies the page token.
+func (q *Query) normalize() (offset int, err error) {
+	if q.PageToken != "" {
+		token, err := decodePageToken(q.PageToken)
+		if err != nil {
+			return 0, fmt.Errorf("%w: invalid page_token: %w", errInvalidQuery, err)
+		}
+		q.From, q.To, offset = token.From, token.To, token.Offset
+	}
+	if q.To.IsZero() {
+		q.To = now()
+	}
+	if q.From.IsZero() {
+		q.From = q.To.Add(-defaultQueryRange)
+	}
+	if q.To.Before(q.From) {
+		return 0, fmt.Errorf("%w: to is before from", errInvalidQuery)
+	}
+	if q.To.Sub(q.From) > maxQueryRange {
+		return 0, fmt.Errorf("%w: the time range can't exceed %s", errInvalidQuery, maxQueryRange)
+	}
+	if q.PageSize <= 0 {
+		q.PageSize = defaultQueryPageSize
+	}
+	if q.PageSize > maxQueryPageSize {
+		q.PageSize = maxQueryPageSize
+	}
+	return offset, nil
+}
+
+func (q *Query) matches(rec *PoolRecord) bool {
+	if rec.Time.Before(q.From) || rec.Time.After(q.To) {
+		return false
+	}
+	if q.Repo != "" && !strings.HasPrefix(rec.Pool, q.Repo+":") {
+		return false
+	}
+	if q.Action != "" && rec.Action != q.Action {
+		return false
+	}
+	if q.PR == 0 && q.Author == "" {
+		return true
+	}
+	for _, pr := range rec.Target {
+		if (q.PR == 0 || pr.Number == q.PR) && (q.Author == "" || strings.EqualFold(pr.Author, q.Author)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Query returns the records matching the query, the most recent first. The
+// records are read from the segments when they are configured, otherwise
+// only the recent records kept in memory can be queried.
+func (h *History) Query(ctx context.Context, q Query) (*QueryResult, error) {
+	offset, err := q.normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []PoolRecord
+	if h.segments != nil {
+		for hour := q.From.UTC().Truncate(time.Hour); !hour.After(q.To); hour = hour.Add(time.Hour) {
+			records, err := h.segments.records(ctx, segmentOf(hour))
+			if err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, records...)
+		}
+	} else {
+		for pool, records := range h.AllRecords() {
+			for _, rec := range records {
+				candidates = append(candidates, PoolRecord{Pool: pool, Record: *rec})
+			}
+		}
+	}
+
+	var matched []PoolRecord
+	for i := range candidates {
+		if q.matches(&candidates[i]) {
+			matched = append(matched, candidates[i])
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		if !matched[i].Time.Equal(matched[j].Time) {
+			return matched[i].Time.After(matched[j].Time)
+		}
+		return matched[i].Pool < matched[j].Pool
+	})
+
+	res := &QueryResult{Records: []PoolRecord{}}
+	if offset >= len(matched) {
+		return res, nil
+	}
+	end := offset + q.PageSize
+	if end < len(matched) {
+		res.NextPageToken = pageToken{From: q.From, To: q.To, Offset: end}.encode()
+	} else {
+		end = len(matched)
+	}
+	res.Records = matched[offset:end]
+	return res, nil
+}
@@ -0,0 +1,314 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	pkgio "sigs.k8s.io/prow/pkg/io"
+	"sigs.k8s.io/prow/pkg/io/fakeopener"
+)
+
+func TestSegments(t *testing.T) {
+	nowTime := time.Date(2024, 3, 4, 23, 0, 0, 0, time.UTC)
+	oldNow := now
+	now = func() time.Time { return nowTime }
+	defer func() { now = oldNow }()
+
+	opener := &fakeopener.FakeOpener{}
+	hist, err := New(2, opener, "")
+	if err != nil {
+		t.Fatalf("Failed to create history client: %v", err)
+	}
+	hist.EnableSegments("gs://bucket/history/")
+
+	record := func(pool, action string, pulls ...prowapi.Pull) {
+		nowTime = nowTime.Add(30 * time.Minute)
+		hist.Record(pool, action, "sha", "", pulls, nil)
+	}
+	record("org/repo:main", "TRIGGER", prowapi.Pull{Number: 1, Author: "alice"})
+	record("org/repo:main", "MERGE", prowapi.Pull{Number: 1, Author: "alice"})
+	hist.Flush()
+	record("org/other:main", "TRIGGER_BATCH", prowapi.Pull{Number: 2, Author: "bob"}, prowapi.Pull{Number: 3, Author: "alice"})
+	record("org/other:main", "MERGE_BATCH", prowapi.Pull{Number: 2, Author: "bob"}, prowapi.Pull{Number: 3, Author: "alice"})
+	hist.Flush()
+
+	var paths []string
+	for path := range opener.Buffer {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	if diff := cmp.Diff([]string{"gs://bucket/history/2024-03-04/23.json", "gs://bucket/history/2024-03-05/00.json", "gs://bucket/history/2024-03-05/01.json"}, paths); diff != "" {
+		t.Fatalf("Unexpected segments: %s", diff)
+	}
+	if lines := strings.Count(opener.Buffer["gs://bucket/history/2024-03-05/00.json"].String(), "\n"); lines != 2 {
+		t.Errorf("Expected 2 records in the segment of 2024-03-05/00 but got %d", lines)
+	}
+
+	// A restarted Tide appends to the segment of the current hour and queries
+	// the records that rolled over the in-memory window.
+	hist, err = New(2, opener, "")
+	if err != nil {
+		t.Fatalf("Failed to create history client: %v", err)
+	}
+	hist.EnableSegments("gs://bucket/history")
+	record("org/repo:main", "TRIGGER", prowapi.Pull{Number: 4, Author: "carol"})
+
+	from := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name        string
+		query       Query
+		wantActions []string
+	}{
+		{
+			name:        "all records, the most recent first",
+			query:       Query{From: from},
+			wantActions: []string{"TRIGGER", "MERGE_BATCH", "TRIGGER_BATCH", "MERGE", "TRIGGER"},
+		},
+		{
+			name:        "by PR",
+			query:       Query{From: from, Repo: "org/repo", PR: 1},
+			wantActions: []string{"MERGE", "TRIGGER"},
+		},
+		{
+			name:        "by author in a batch",
+			query:       Query{From: from, Author: "alice", Action: "MERGE_BATCH"},
+			wantActions: []string{"MERGE_BATCH"},
+		},
+		{
+			name:        "by time range",
+			query:       Query{From: from, To: time.Date(2024, 3, 4, 23, 59, 0, 0, time.UTC)},
+			wantActions: []string{"TRIGGER"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := hist.Query(context.Background(), tc.query)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var actions []string
+			for _, rec := range res.Records {
+				actions = append(actions, rec.Action)
+			}
+			if diff := cmp.Diff(tc.wantActions, actions); diff != "" {
+				t.Errorf("Unexpected records: %s", diff)
+			}
+		})
+	}
+
+	// Page through the records.
+	var pools []string
+	q := Query{From: from, PageSize: 2}
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("Too many pages")
+		}
+		res, err := hist.Query(context.Background(), q)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, rec := range res.Records {
+			pools = append(pools, rec.Pool)
+		}
+		// Records added after the first page don't shift the next pages.
+		record("org/new:main", "TRIGGER")
+		if res.NextPageToken == "" {
+			break
+		}
+		q.PageToken = res.NextPageToken
+	}
+	if diff := cmp.Diff([]string{"org/repo:main", "org/other:main", "org/other:main", "org/repo:main", "org/repo:main"}, pools); diff != "" {
+		t.Errorf("Unexpected paged records: %s", diff)
+	}
+}
+
+// blockingOpener blocks the reads of the storage until it is released.
+type blockingOpener struct {
+	*fakeopener.FakeOpener
+	reading chan struct{}
+	release chan struct{}
+}
+
+func (o *blockingOpener) Reader(ctx context.Context, path string) (pkgio.ReadCloser, error) {
+	o.reading <- struct{}{}
+	<-o.release
+	return o.FakeOpener.Reader(ctx, path)
+}
+
+func TestSegmentsFlushDoesNotBlockAdd(t *testing.T) {
+	nowTime := time.Date(2024, 3, 4, 23, 0, 0, 0, time.UTC)
+	oldNow := now
+	now = func() time.Time { return nowTime }
+	defer func() { now = oldNow }()
+
+	opener := &blockingOpener{FakeOpener: &fakeopener.FakeOpener{}, reading: make(chan struct{}), release: make(chan struct{})}
+	store := newSegmentStore(opener, "gs://bucket/history")
+	store.add("org/repo:main", &Record{Time: nowTime, Action: "TRIGGER"})
+
+	flushed := make(chan error)
+	go func() { flushed <- store.flush(context.Background()) }()
+	<-opener.reading
+
+	added := make(chan struct{})
+	go func() {
+		store.add("org/repo:main", &Record{Time: nowTime, Action: "MERGE"})
+		close(added)
+	}()
+	select {
+	case <-added:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Adding a record is blocked by the flush")
+	}
+	close(opener.release)
+	if err := <-flushed; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := store.flush(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	records, err := newSegmentStore(opener.FakeOpener, "gs://bucket/history").readSegment(context.Background(), "2024-03-04/23")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var actions []string
+	for _, rec := range records {
+		actions = append(actions, rec.Action)
+	}
+	if diff := cmp.Diff([]string{"TRIGGER", "MERGE"}, actions); diff != "" {
+		t.Errorf("Unexpected records: %s", diff)
+	}
+}
+
+func TestServeQuery(t *testing.T) {
+	nowTime := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	oldNow := now
+	now = func() time.Time { return nowTime }
+	defer func() { now = oldNow }()
+
+	hist, err := New(10, nil, "")
+	if err != nil {
+		t.Fatalf("Failed to create history client: %v", err)
+	}
+	hist.Record("org/repo:main", "MERGE", "sha", "", []prowapi.Pull{{Number: 1234, Author: "alice"}}, nil)
+	hist.Record("org/repo:main", "TRIGGER", "sha", "", []prowapi.Pull{{Number: 5, Author: "bob"}}, nil)
+
+	for _, tc := range []struct {
+		name       string
+		params     url.Values
+		wantStatus int
+		wantPRs    []int
+	}{
+		{
+			name:       "query by PR",
+			params:     url.Values{"pr": {"1234"}, "from": {"2024-03-04"}},
+			wantStatus: http.StatusOK,
+			wantPRs:    []int{1234},
+		},
+		{
+			name:       "invalid PR",
+			params:     url.Values{"pr": {"abc"}},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "range too large",
+			params:     url.Values{"from": {"2023-01-01"}, "to": {"2024-03-04"}},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid page token",
+			params:     url.Values{"page_token": {"nope"}},
+			wantStatus: http.StatusBadRequest,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/history?"+tc.params.Encode(), nil)
+			rr := httptest.NewRecorder()
+			hist.ServeHTTP(rr, req)
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("Expected status %d but got %d: %s", tc.wantStatus, rr.Code, rr.Body.String())
+			}
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+			var res QueryResult
+			if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+				t.Fatalf("Failed to unmarshal the response: %v", err)
+			}
+			var prs []int
+			for _, rec := range res.Records {
+				for _, pr := range rec.Target {
+					prs = append(prs, pr.Number)
+				}
+			}
+			if diff := cmp.Diff(tc.wantPRs, prs); diff != "" {
+				t.Errorf("Unexpected records: %s", diff)
+			}
+		})
+	}
+}
+
+func TestServeQueryStorageError(t *testing.T) {
+	nowTime := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	oldNow := now
+	now = func() time.Time { return nowTime }
+	defer func() { now = oldNow }()
+
+	hist, err := New(10, &fakeopener.FakeOpener{ReadError: errors.New("injected read error")}, "")
+	if err != nil {
+		t.Fatalf("Failed to create history client: %v", err)
+	}
+	hist.EnableSegments("gs://bucket/history")
+
+	for _, tc := range []struct {
+		name       string
+		params     url.Values
+		wantStatus int
+	}{
+		{
+			name:       "storage failure",
+			params:     url.Values{"pr": {"1234"}},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "invalid query",
+			params:     url.Values{"from": {"2024-03-04"}, "to": {"2024-03-03"}},
+			wantStatus: http.StatusBadRequest,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/history?"+tc.params.Encode(), nil)
+			rr := httptest.NewRecorder()
+			hist.ServeHTTP(rr, req)
+			if rr.Code != tc.wantStatus {
+				t.Errorf("Expected status %d but got %d: %s", tc.wantStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"sigs.k8s.io/prow/pkg/io"
+)
+
+const (
+	dayLayout = "2006-01-02"
+	// segmentLayout is the layout of the path of a segment under the prefix.
+	segmentLayout = dayLayout + "/15"
+)
+
+// PoolRecord is a Record along with the key of the pool it belongs to. It is
+// the format of the lines of the segments.
+type PoolRecord struct {
+	Pool string `json:"pool"`
+	Record
+}
+
+// segmentStore is an append only store of the records, segmented per hour.
+// Every segment is a newline delimited JSON object stored at
+// <prefix>/<YYYY-MM-DD>/<HH>.json, the hour being the UTC hour of the
+// records. Since object storages don't support appending, the segment of the
+// current hour is kept in memory and rewritten on flush, so that a flush
+// writes at most an hour of records. The segments of the previous hours are
+// never modified after the hour rolled over.
+type segmentStore struct {
+	opener opener
+	prefix string
+
+	// flushLock serializes the flushes, which access the storage without
+	// holding the lock of the records.
+	flushLock sync.Mutex
+	sync.Mutex
+	// segments holds the records of the segments touched since the last flush.
+	segments map[string][]PoolRecord
+	// loaded are the segments whose records from the storage were loaded in segments.
+	loaded sets.Set[string]
+	dirty  sets.Set[string]
+}
+
+func newSegmentStore(opener opener, prefix string) *segmentStore {
+	return &segmentStore{
+		opener:   opener,
+		prefix:   strings.TrimSuffix(prefix, "/"),
+		segments: map[string][]PoolRecord{},
+		loaded:   sets.New[string](),
+		dirty:    sets.New[string](),
+	}
+}
+
+// segmentOf returns the segment of the records at the time.
+func segmentOf(t time.Time) string {
+	return t.UTC().Format(segmentLayout)
+}
+
+func (s *segmentStore) segmentPath(segment string) string {
+	return fmt.Sprintf("%s/%s.json", s.prefix, segment)
+}
+
+func (s *segmentStore) add(poolKey string, rec *Record) {
+	s.Lock()
+	defer s.Unlock()
+	segment := segmentOf(rec.Time)
+	s.segments[segment] = append(s.segments[segment], PoolRecord{Pool: poolKey, Record: *rec})
+	s.dirty.Insert(segment)
+}
+
+// readSegment reads the records of the segment from the storage. A missing
+// segment is not an error, there is simply no record for that hour.
+func (s *segmentStore) readSegment(ctx context.Context, segment string) ([]PoolRecord, error) {
+	reader, err := s.opener.Reader(ctx, s.segmentPath(segment))
+	if io.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	defer io.LogClose(reader)
+
+	var records []PoolRecord
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var rec PoolRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return nil, fmt.Errorf("unmarshal: %w", err)
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	return records, nil
+}
+
+func (s *segmentStore) writeSegment(ctx context.Context, segment string, records []PoolRecord) error {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	for i := range records {
+		if err := encoder.Encode(&records[i]); err != nil {
+			return fmt.Errorf("marshal: %w", err)
+		}
+	}
+
+	writer, err := s.opener.Writer(ctx, s.segmentPath(segment))
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	if _, err := writer.Write(b.Bytes()); err != nil {
+		io.LogClose(writer)
+		return fmt.Errorf("write: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+	return nil
+}
+
+// flush writes the segments having new records. The segments other than the
+// current one are dropped from memory once they are written. The
+// records to write are copied under the lock, the storage is accessed without
+// holding it so that adding records is never blocked by the storage.
+func (s *segmentStore) flush(ctx context.Context) error {
+	s.flushLock.Lock()
+	defer s.flushLock.Unlock()
+
+	type dirtySegment struct {
+		segment string
+		records []PoolRecord
+		loaded  bool
+	}
+	s.Lock()
+	var dirty []dirtySegment
+	for _, segment := range sets.List(s.dirty) {
+		dirty = append(dirty, dirtySegment{segment: segment, records: append([]PoolRecord(nil), s.segments[segment]...), loaded: s.loaded.Has(segment)})
+	}
+	s.dirty.Clear()
+	s.Unlock()
+
+	var errs []error
+	for _, d := range dirty {
+		var stored []PoolRecord
+		err := func() error {
+			if !d.loaded {
+				var err error
+				if stored, err = s.readSegment(ctx, d.segment); err != nil {
+					return fmt.Errorf("read segment %s: %w", d.segment, err)
+				}
+			}
+			if err := s.writeSegment(ctx, d.segment, append(stored, d.records...)); err != nil {
+				return fmt.Errorf("write segment %s: %w", d.segment, err)
+			}
+			return nil
+		}()
+
+		s.Lock()
+		if err != nil {
+			errs = append(errs, err)
+			s.dirty.Insert(d.segment)
+		} else if !d.loaded && !s.loaded.Has(d.segment) {
+			// The records added in the meantime follow the stored ones.
+			s.segments[d.segment] = append(stored, s.segments[d.segment]...)
+			s.loaded.Insert(d.segment)
+		}
+		s.Unlock()
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	current := segmentOf(now())
+	for segment := range s.segments {
+		if segment != current && !s.dirty.Has(segment) {
+			delete(s.segments, segment)
+			s.loaded.Delete(segment)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// records returns the records of the segment, including the ones not flushed yet.
+func (s *segmentStore) records(ctx context.Context, segment string) ([]PoolRecord, error) {
+	s.Lock()
+	if s.loaded.Has(segment) {
+		defer s.Unlock()
+		return append([]PoolRecord(nil), s.segments[segment]...), nil
+	}
+	s.Unlock()
+
+	stored, err := s.readSegment(ctx, segment)
+	if err != nil {
+		return nil, fmt.Errorf("read segment %s: %w", segment, err)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	// A flush in the meantime merged the stored records into memory.
+	if s.loaded.Has(segment) {
+		return append([]PoolRecord(nil), s.segments[segment]...), nil
+	}
+	return append(stored, s.segments[segment]...), nil
+}
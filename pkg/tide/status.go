@@ -98,6 +98,9 @@ type statusUpdate struct {
 	poolPRs          map[string]CodeReviewCommon
 	baseSHAs         map[string]string
 	requiredContexts map[string][]string
+	// poolStatuses maps the PRs to the description of their status replacing
+	// statusInPool, e.g. to tell the speculative batch they are tested in.
+	poolStatuses map[string]string
 	sync.Mutex
 	// dontUpdateStatus contains all PRs for which the Tide sync controller
 	// updated the status to success prior to merging. As the name suggests,
@@ -108,6 +111,22 @@ type statusUpdate struct {
 	newPoolPending chan bool
 }
 
+// speculativeBatch is the position of a PR in the stacked speculative batches
+// of its pool, level 1 being the smallest batch.
+type speculativeBatch struct {
+	level int
+	depth int
+}
+
+func (sb speculativeBatch) status() string {
+	return fmt.Sprintf("%s Speculative batch %d/%d.", statusInPool, sb.level, sb.depth)
+}
+
+// culpritStatus is the status of a PR that broke a bisected batch.
+func culpritStatus(contexts []string) string {
+	return fmt.Sprintf("%s Broke a batch (%s), kept out of batches until updated.", statusInPool, strings.Join(contexts, ", "))
+}
+
 func (sc *statusController) shutdown() {
 	close(sc.newPoolPending)
 	<-sc.shutDown
@@ -405,7 +424,7 @@ func targetURL(c *config.Config, crc *CodeReviewCommon, log *logrus.Entry) strin
 }
 
 // setStatues sets GitHub context status.
-func (sc *statusController) setStatuses(all []CodeReviewCommon, pool map[string]CodeReviewCommon, blocks blockers.Blockers, baseSHAs map[string]string, requiredContexts map[string][]string) {
+func (sc *statusController) setStatuses(all []CodeReviewCommon, pool map[string]CodeReviewCommon, blocks blockers.Blockers, baseSHAs map[string]string, requiredContexts map[string][]string, poolStatuses map[string]string) {
 	c := sc.config()
 	// queryMap caches which queries match a repo.
 	// Make a new one each sync loop as queries will change.
@@ -436,6 +455,9 @@ func (sc *statusController) setStatuses(all []CodeReviewCommon, pool map[string]
 			log.WithError(err).Error("getting expected status")
 			return
 		}
+		if desc, ok := poolStatuses[prKey(pr)]; ok && wantDesc == statusInPool {
+			wantDesc = desc
+		}
 		var actualState githubql.StatusState
 		var actualDesc string
 		for _, ctx := range contexts {
@@ -591,8 +613,9 @@ func (sc *statusController) waitSync() {
 				baseSHAs = map[string]string{}
 			}
 			requiredContexts := sc.requiredContexts
+			poolStatuses := sc.poolStatuses
 			sc.statusUpdate.Unlock()
-			sc.sync(pool, blocks, baseSHAs, requiredContexts)
+			sc.sync(pool, blocks, baseSHAs, requiredContexts, poolStatuses)
 			return
 		case more := <-sc.newPoolPending:
 			if !more {
@@ -602,7 +625,7 @@ func (sc *statusController) waitSync() {
 	}
 }
 
-func (sc *statusController) sync(pool map[string]CodeReviewCommon, blocks blockers.Blockers, baseSHAs map[string]string, requiredContexts map[string][]string) {
+func (sc *statusController) sync(pool map[string]CodeReviewCommon, blocks blockers.Blockers, baseSHAs map[string]string, requiredContexts map[string][]string, poolStatuses map[string]string) {
 	sc.lastSyncStart = time.Now()
 	defer func() {
 		duration := time.Since(sc.lastSyncStart)
@@ -611,7 +634,7 @@ func (sc *statusController) sync(pool map[string]CodeReviewCommon, blocks blocke
 		tideMetrics.syncHeartbeat.WithLabelValues("status-update").Inc()
 	}()
 
-	sc.setStatuses(sc.search(), pool, blocks, baseSHAs, requiredContexts)
+	sc.setStatuses(sc.search(), pool, blocks, baseSHAs, requiredContexts, poolStatuses)
 }
 
 func (sc *statusController) search() []CodeReviewCommon {
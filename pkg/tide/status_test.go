@@ -1016,7 +1016,7 @@ func TestSetStatuses(t *testing.T) {
 		if tc.inDontSetStatus {
 			sc.dontUpdateStatus = &threadSafePRSet{data: map[pullRequestIdentifier]struct{}{{}: {}}}
 		}
-		sc.setStatuses([]CodeReviewCommon{*crc}, pool, blockers.Blockers{}, nil, nil)
+		sc.setStatuses([]CodeReviewCommon{*crc}, pool, blockers.Blockers{}, nil, nil, nil)
 		if str, err := log.String(); err != nil {
 			t.Fatalf("For case %s: failed to get log output: %v", tc.name, err)
 		} else if str != initialLog {
@@ -1259,7 +1259,7 @@ func TestSetStatusRespectsRequiredContexts(t *testing.T) {
 	}
 	crc := CodeReviewCommonFromPullRequest(&pr)
 	pool := map[string]CodeReviewCommon{prKey(crc): *crc}
-	sc.setStatuses([]CodeReviewCommon{*crc}, pool, blockers.Blockers{}, nil, requiredContexts)
+	sc.setStatuses([]CodeReviewCommon{*crc}, pool, blockers.Blockers{}, nil, requiredContexts, nil)
 	if str, err := log.String(); err != nil {
 		t.Fatalf("Failed to get log output: %v", err)
 	} else if str != initialLog {
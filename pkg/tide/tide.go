@@ -34,6 +34,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	githubql "github.com/shurcooL/githubv4"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
@@ -47,6 +48,7 @@ import (
 	"sigs.k8s.io/prow/pkg/pjutil"
 	"sigs.k8s.io/prow/pkg/tide/blockers"
 	"sigs.k8s.io/prow/pkg/tide/history"
+	"sigs.k8s.io/prow/pkg/tracing"
 	_ "sigs.k8s.io/prow/pkg/version"
 )
 
@@ -105,18 +107,24 @@ const (
 	Wait         Action = "WAIT"
 	Trigger      Action = "TRIGGER"
 	TriggerBatch Action = "TRIGGER_BATCH"
-	Merge        Action = "MERGE"
-	MergeBatch   Action = "MERGE_BATCH"
-	PoolBlocked  Action = "BLOCKED"
+	// TriggerSpeculativeBatch triggers stacked batches (A, A+B, A+B+C) at once.
+	TriggerSpeculativeBatch Action = "TRIGGER_SPECULATIVE_BATCH"
+	// TriggerBisection triggers the halves of a failed batch.
+	TriggerBisection Action = "TRIGGER_BISECTION"
+	Merge            Action = "MERGE"
+	MergeBatch       Action = "MERGE_BATCH"
+	PoolBlocked      Action = "BLOCKED"
 )
 
 // recordableActions is the subset of actions that we keep historical record of.
 // Ignore idle actions to avoid flooding the records with useless data.
 var recordableActions = map[Action]bool{
-	Trigger:      true,
-	TriggerBatch: true,
-	Merge:        true,
-	MergeBatch:   true,
+	Trigger:                 true,
+	TriggerBatch:            true,
+	TriggerSpeculativeBatch: true,
+	TriggerBisection:        true,
+	Merge:                   true,
+	MergeBatch:              true,
 }
 
 // Pool represents information about a tide pool. There is one for every
@@ -474,7 +482,9 @@ func contextsToStrings(contexts []Context) []string {
 }
 
 // Sync runs one sync iteration.
-func (c *syncController) Sync() error {
+func (c *syncController) Sync() (err error) {
+	_, span := tracing.Tracer().Start(context.Background(), "tide.sync")
+	defer func() { tracing.End(span, err) }()
 	start := time.Now()
 	defer func() {
 		duration := time.Since(start)
@@ -517,6 +527,7 @@ func (c *syncController) Sync() error {
 	c.statusUpdate.poolPRs = poolPRMap(filteredPools)
 	c.statusUpdate.baseSHAs = baseSHAMap(filteredPools)
 	c.statusUpdate.requiredContexts = requiredContextsMap(filteredPools)
+	c.statusUpdate.poolStatuses = poolStatusMap(filteredPools, &c.config().Tide)
 	select {
 	case c.statusUpdate.newPoolPending <- true:
 		c.statusUpdate.dontUpdateStatus.reset()
@@ -653,6 +664,11 @@ func (c *syncController) initSubpoolData(sp *subpool) error {
 			return fmt.Errorf("error setting up context checker for pr %d: %w", pr.Number, err)
 		}
 	}
+
+	sp.culprits, err = c.bisectionCulprits(sp)
+	if err != nil {
+		return fmt.Errorf("error determining the culprits of the bisected batches: %w", err)
+	}
 	return nil
 }
 
@@ -727,6 +743,69 @@ func filterPR(provider provider, mergeAllowed func(*CodeReviewCommon) (string, e
 	return false
 }
 
+// poolStatusMap collects the descriptions of the tide status replacing
+// statusInPool for the PRs of the subpools.
+func poolStatusMap(subpoolMap map[string]*subpool, tide *config.Tide) map[string]string {
+	statuses := make(map[string]string)
+	for key, sb := range speculativeBatchMap(subpoolMap, tide) {
+		statuses[key] = sb.status()
+	}
+	for _, sp := range subpoolMap {
+		for i := range sp.prs {
+			if contexts, ok := sp.culprits[sp.prs[i].Number]; ok {
+				statuses[prKey(&sp.prs[i])] = culpritStatus(contexts)
+			}
+		}
+	}
+	return statuses
+}
+
+// speculativeBatchMap maps the PRs of the subpools testing speculative
+// batches to the first pending speculative batch they are part of.
+func speculativeBatchMap(subpoolMap map[string]*subpool, tide *config.Tide) map[string]speculativeBatch {
+	res := make(map[string]speculativeBatch)
+	for _, sp := range subpoolMap {
+		if tide.SpeculativeBatchDepth(config.OrgRepo{Org: sp.org, Repo: sp.repo}) < 2 {
+			continue
+		}
+		prs := make(map[int]*CodeReviewCommon, len(sp.prs))
+		for i := range sp.prs {
+			prs[sp.prs[i].Number] = &sp.prs[i]
+		}
+		batches := make(map[string][]int)
+		for _, pj := range sp.pjs {
+			if pj.Spec.Type != prowapi.BatchJob || toSimpleState(pj.Status.State) != pendingState {
+				continue
+			}
+			var nums []int
+			for _, pull := range pj.Spec.Refs.Pulls {
+				if pr, ok := prs[pull.Number]; !ok || pr.HeadRefOID != pull.SHA {
+					nums = nil
+					break
+				}
+				nums = append(nums, pull.Number)
+			}
+			if len(nums) > 0 {
+				batches[pj.Spec.Refs.String()] = nums
+			}
+		}
+		stacked := make([][]int, 0, len(batches))
+		for _, nums := range batches {
+			stacked = append(stacked, nums)
+		}
+		sort.Slice(stacked, func(i, j int) bool { return len(stacked[i]) < len(stacked[j]) })
+		for level, nums := range stacked {
+			for _, num := range nums {
+				key := prKey(prs[num])
+				if _, ok := res[key]; !ok {
+					res[key] = speculativeBatch{level: level + 1, depth: len(stacked)}
+				}
+			}
+		}
+	}
+	return res
+}
+
 func baseSHAMap(subpoolMap map[string]*subpool) map[string]string {
 	baseSHAs := make(map[string]string, len(subpoolMap))
 	for key, sp := range subpoolMap {
@@ -880,18 +959,76 @@ func pickHighestPriorityPR(log *logrus.Entry, prs []CodeReviewCommon, cc map[int
 // batch.
 func (c *syncController) accumulateBatch(sp subpool) (successBatch []CodeReviewCommon, pendingBatch []CodeReviewCommon) {
 	sp.log.Debug("accumulating PRs for batch testing")
+	states := batchStates(sp)
+	var successBatches, pendingBatches [][]CodeReviewCommon
+	for ref, state := range states {
+		if !state.validPulls {
+			continue
+		}
+
+		// presubmitsForBatch includes jobs that are `run_before_merge`, the
+		// jobs are not triggered before entering tide pool, and will need to be
+		// handled below.
+		requiredPresubmits, err := c.presubmitsForBatch(state.prs, sp.org, sp.repo, sp.sha, sp.branch)
+		if err != nil {
+			sp.log.WithError(err).Error("Error getting presubmits for batch")
+			continue
+		}
+
+		overallState := successState
+		for _, p := range requiredPresubmits {
+			if s, ok := state.jobStates[p.Context]; !ok {
+				// This could happen to jobs configured as `run_before_merge` as
+				// these jobs are triggered only by tide. There is no need to
+				// handle it differently as a new batch is expected in both cases.
+				overallState = failureState
+				sp.log.WithField("batch", ref).Debugf("batch invalid, required presubmit %s is missing", p.Context)
+				break
+			} else if s == failureState {
+				overallState = failureState
+				sp.log.WithField("batch", ref).Debugf("batch invalid, required presubmit %s is not passing", p.Context)
+				break
+			} else if s == pendingState && overallState == successState {
+				overallState = pendingState
+			}
+		}
+		switch overallState {
+		case pendingState:
+			pendingBatches = append(pendingBatches, state.prs)
+		case successState:
+			successBatches = append(successBatches, state.prs)
+		}
+	}
+	if c.config().Tide.SpeculativeBatchDepth(config.OrgRepo{Org: sp.org, Repo: sp.repo}) > 1 {
+		return pickSpeculativeBatches(successBatches, pendingBatches)
+	}
+	// Currently we only consider 1 pending batch and 1 success batch at a time.
+	// If more are somehow present they will be ignored.
+	if len(successBatches) > 0 {
+		successBatch = successBatches[len(successBatches)-1]
+	}
+	if len(pendingBatches) > 0 {
+		pendingBatch = pendingBatches[len(pendingBatches)-1]
+	}
+	return successBatch, pendingBatch
+}
+
+// batchState is the accumulated state of the batch jobs testing the same refs.
+type batchState struct {
+	prs       []CodeReviewCommon
+	jobStates map[string]simpleState
+	// Are the pull requests in the ref still acceptable? That is, do they
+	// still point to the heads of the PRs?
+	validPulls bool
+}
+
+// batchStates accumulates the states of the batch jobs of the subpool by refs.
+func batchStates(sp subpool) map[string]*batchState {
 	prNums := make(map[int]CodeReviewCommon)
 	for _, pr := range sp.prs {
 		prNums[pr.Number] = pr
 	}
-	type accState struct {
-		prs       []CodeReviewCommon
-		jobStates map[string]simpleState
-		// Are the pull requests in the ref still acceptable? That is, do they
-		// still point to the heads of the PRs?
-		validPulls bool
-	}
-	states := make(map[string]*accState)
+	states := make(map[string]*batchState)
 	for _, pj := range sp.pjs {
 		if pj.Spec.Type != prowapi.BatchJob {
 			continue
@@ -899,7 +1036,7 @@ func (c *syncController) accumulateBatch(sp subpool) (successBatch []CodeReviewC
 		// First validate the batch job's refs.
 		ref := pj.Spec.Refs.String()
 		if _, ok := states[ref]; !ok {
-			state := &accState{
+			state := &batchState{
 				jobStates:  make(map[string]simpleState),
 				validPulls: true,
 			}
@@ -929,49 +1066,46 @@ func (c *syncController) accumulateBatch(sp subpool) (successBatch []CodeReviewC
 		// Store the best result for this ref+context.
 		states[ref].jobStates[context] = getBetterSimpleState(states[ref].jobStates[context], jobState)
 	}
-	for ref, state := range states {
-		if !state.validPulls {
-			continue
-		}
+	return states
+}
 
-		// presubmitsForBatch includes jobs that are `run_before_merge`, the
-		// jobs are not triggered before entering tide pool, and will need to be
-		// handled below.
-		requiredPresubmits, err := c.presubmitsForBatch(state.prs, sp.org, sp.repo, sp.sha, sp.branch)
-		if err != nil {
-			sp.log.WithError(err).Error("Error getting presubmits for batch")
-			continue
+// pickSpeculativeBatches picks the longest passing batch to merge, unless a
+// larger batch including it is still pending, and the largest pending batch.
+func pickSpeculativeBatches(successBatches, pendingBatches [][]CodeReviewCommon) (successBatch []CodeReviewCommon, pendingBatch []CodeReviewCommon) {
+	for _, batch := range successBatches {
+		if len(batch) > len(successBatch) {
+			successBatch = batch
 		}
-
-		overallState := successState
-		for _, p := range requiredPresubmits {
-			if s, ok := state.jobStates[p.Context]; !ok {
-				// This could happen to jobs configured as `run_before_merge` as
-				// these jobs are triggered only by tide. There is no need to
-				// handle it differently as a new batch is expected in both cases.
-				overallState = failureState
-				sp.log.WithField("batch", ref).Debugf("batch invalid, required presubmit %s is missing", p.Context)
-				break
-			} else if s == failureState {
-				overallState = failureState
-				sp.log.WithField("batch", ref).Debugf("batch invalid, required presubmit %s is not passing", p.Context)
-				break
-			} else if s == pendingState && overallState == successState {
-				overallState = pendingState
-			}
-		}
-		switch overallState {
-		// Currently we only consider 1 pending batch and 1 success batch at a time.
-		// If more are somehow present they will be ignored.
-		case pendingState:
-			pendingBatch = state.prs
-		case successState:
-			successBatch = state.prs
+	}
+	for _, batch := range pendingBatches {
+		if len(batch) > len(pendingBatch) {
+			pendingBatch = batch
 		}
 	}
+	if len(pendingBatch) <= len(successBatch) {
+		return successBatch, pendingBatch
+	}
+	pending := sets.New[int](prNumbers(pendingBatch)...)
+	if pending.HasAll(prNumbers(successBatch)...) {
+		// Wait for the larger batch, it may allow to merge more PRs at once.
+		return nil, pendingBatch
+	}
 	return successBatch, pendingBatch
 }
 
+// speculativeBatches splits the batch in depth stacked batches, each one
+// including the PRs of the previous one and the last one being the whole batch.
+func speculativeBatches(batch []CodeReviewCommon, depth int) [][]CodeReviewCommon {
+	if depth > len(batch) {
+		depth = len(batch)
+	}
+	var res [][]CodeReviewCommon
+	for level := 1; level <= depth; level++ {
+		res = append(res, batch[:(len(batch)*level+depth-1)/depth])
+	}
+	return res
+}
+
 // prowJobsFromContexts constructs ProwJob objects from all successful presubmit contexts that include a baseSHA.
 // This is needed because otherwise we would always need retesting for results that are older than sinkers
 // max_prowjob_age.
@@ -1143,6 +1277,10 @@ func (c *syncController) pickBatch(sp subpool, cc map[int]contextChecker, newBat
 
 	var candidates []CodeReviewCommon
 	for _, pr := range sp.prs {
+		if contexts, ok := sp.culprits[pr.Number]; ok {
+			sp.log.WithFields(pr.logFields()).WithField("contexts", contexts).Debug("PR broke a bisected batch, excluding it from the batch")
+			continue
+		}
 		// c.isRetestEligible appends `Commits` into the passed in PullRequest
 		// struct, which is used later to avoid repeatedly looking up on GitHub.
 		if c.isRetestEligible(sp.log, &pr, cc[pr.Number]) {
@@ -1358,6 +1496,28 @@ func tryMerge(mergeFunc func() error) (bool, error) {
 }
 
 func (c *syncController) trigger(sp subpool, presubmits []config.Presubmit, prs []CodeReviewCommon) error {
+	return c.triggerJobs(sp, presubmits, prs, len(prs) > 1, nil)
+}
+
+// triggerSpeculative triggers the stacked batches of the batch at once.
+func (c *syncController) triggerSpeculative(sp subpool, batch []CodeReviewCommon, depth int) error {
+	for _, prs := range speculativeBatches(batch, depth) {
+		presubmits, err := c.presubmitsForBatch(prs, sp.org, sp.repo, sp.sha, sp.branch)
+		if err != nil {
+			return err
+		}
+		// Even a speculative batch with a single PR is a batch job, so that
+		// it is accounted for like the other speculative batches.
+		if err := c.triggerJobs(sp, presubmits, prs, true, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// triggerJobs triggers the presubmits for the PRs, as batch jobs if batch is
+// set, adding the extra labels to the ProwJobs.
+func (c *syncController) triggerJobs(sp subpool, presubmits []config.Presubmit, prs []CodeReviewCommon, batch bool, extraLabels map[string]string) error {
 	refs, err := c.provider.refsForJob(sp, prs)
 	if err != nil {
 		return fmt.Errorf("failed creating refs: %v", err)
@@ -1374,7 +1534,7 @@ func (c *syncController) trigger(sp subpool, presubmits []config.Presubmit, prs
 		triggeredContexts.Insert(string(ps.Context))
 		enableScheduling := c.config().Scheduler.Enabled
 		var spec prowapi.ProwJobSpec
-		if len(prs) == 1 {
+		if !batch {
 			spec = pjutil.PresubmitSpec(ps, refs)
 		} else {
 			if c.nonFailedBatchForJobAndRefsExists(ps.Name, &refs) {
@@ -1391,7 +1551,16 @@ func (c *syncController) trigger(sp subpool, presubmits []config.Presubmit, prs
 			pj.Labels = map[string]string{}
 		}
 		pj.Labels[kube.CreatedByTideLabel] = "true"
-		if err := c.prowJobClient.Create(c.ctx, &pj); err != nil {
+		for k, v := range extraLabels {
+			pj.Labels[k] = v
+		}
+		// Each ProwJob triggered by tide starts its own trace, the sync loop
+		// has no event to continue the trace of.
+		ctx, span := tracing.StartProwJobSpan(context.Background(), "tide.trigger", &pj, attribute.String("tide.pool", sp.org+"/"+sp.repo+":"+sp.branch))
+		tracing.Inject(ctx, &pj)
+		err := c.prowJobClient.Create(c.ctx, &pj)
+		tracing.End(span, err)
+		if err != nil {
 			log.WithField("duration", time.Since(start).String()).Debug("Failed to create ProwJob on the cluster.")
 			return fmt.Errorf("failed to create a ProwJob for job: %q, PRs: %v: %w", spec.Job, prNumbers(prs), err)
 		}
@@ -1441,6 +1610,12 @@ func (c *syncController) takeAction(sp subpool, batchPending, successes, pending
 	if len(sp.presubmits) == 0 {
 		return Wait, nil, nil
 	}
+	// Bisect a failed batch before picking a new one.
+	if len(batchPending) == 0 && c.config().Tide.BisectFailedBatches(config.OrgRepo{Org: sp.org, Repo: sp.repo}) {
+		if batches := c.batchesToBisect(sp); len(batches) > 0 {
+			return TriggerBisection, batches[0], c.triggerBisection(sp, batches[0])
+		}
+	}
 	// If we have no batch, trigger one.
 	if len(sp.prs) > 1 && len(batchPending) == 0 {
 		batch, presubmits, err := c.pickBatch(sp, sp.cc, c.pickNewBatch)
@@ -1448,6 +1623,9 @@ func (c *syncController) takeAction(sp subpool, batchPending, successes, pending
 			return Wait, nil, err
 		}
 		if len(batch) > 1 {
+			if depth := c.config().Tide.SpeculativeBatchDepth(config.OrgRepo{Org: sp.org, Repo: sp.repo}); depth > 1 {
+				return TriggerSpeculativeBatch, batch, c.triggerSpeculative(sp, batch, depth)
+			}
 			return TriggerBatch, batch, c.trigger(sp, presubmits, batch)
 		}
 	}
@@ -1770,6 +1948,9 @@ type subpool struct {
 	// presubmit contains all required presubmits for each PR
 	// in this subpool
 	presubmits map[int][]config.Presubmit
+	// culprits maps the PRs that broke a bisected batch to the contexts that
+	// failed. They are kept out of the batches.
+	culprits map[int][]string
 }
 
 func (sp subpool) TenantIDs() []string {
@@ -108,11 +108,12 @@ func TestAccumulateBatch(t *testing.T) {
 		state prowapi.ProwJobState
 	}
 	tests := []struct {
-		name           string
-		presubmits     []config.Presubmit
-		pulls          []pull
-		prowJobs       []prowjob
-		prowYAMLGetter config.ProwYAMLGetter
+		name             string
+		presubmits       []config.Presubmit
+		pulls            []pull
+		prowJobs         []prowjob
+		prowYAMLGetter   config.ProwYAMLGetter
+		speculativeDepth int
 
 		merges  []int
 		pending bool
@@ -241,6 +242,43 @@ func TestAccumulateBatch(t *testing.T) {
 			pending: false,
 			merges:  []int{2},
 		},
+		{
+			name:             "speculative batches, the longest passing one is merged",
+			presubmits:       []config.Presubmit{{Reporter: config.Reporter{Context: "foo"}}},
+			speculativeDepth: 3,
+			pulls:            []pull{{1, "a"}, {2, "b"}, {3, "c"}},
+			prowJobs: []prowjob{
+				{job: "foo", state: prowapi.SuccessState, prs: []pull{{1, "a"}}},
+				{job: "foo", state: prowapi.SuccessState, prs: []pull{{1, "a"}, {2, "b"}}},
+				{job: "foo", state: prowapi.FailureState, prs: []pull{{1, "a"}, {2, "b"}, {3, "c"}}},
+			},
+			merges: []int{1, 2},
+		},
+		{
+			name:             "speculative batches, a larger batch is pending",
+			presubmits:       []config.Presubmit{{Reporter: config.Reporter{Context: "foo"}}},
+			speculativeDepth: 3,
+			pulls:            []pull{{1, "a"}, {2, "b"}, {3, "c"}},
+			prowJobs: []prowjob{
+				{job: "foo", state: prowapi.SuccessState, prs: []pull{{1, "a"}}},
+				{job: "foo", state: prowapi.PendingState, prs: []pull{{1, "a"}, {2, "b"}}},
+				{job: "foo", state: prowapi.SuccessState, prs: []pull{{1, "a"}, {2, "b"}, {3, "c"}}},
+			},
+			pending: true,
+			merges:  []int{1, 2, 3},
+		},
+		{
+			name:             "speculative batches, wait for the larger pending batch",
+			presubmits:       []config.Presubmit{{Reporter: config.Reporter{Context: "foo"}}},
+			speculativeDepth: 3,
+			pulls:            []pull{{1, "a"}, {2, "b"}, {3, "c"}},
+			prowJobs: []prowjob{
+				{job: "foo", state: prowapi.SuccessState, prs: []pull{{1, "a"}}},
+				{job: "foo", state: prowapi.SuccessState, prs: []pull{{1, "a"}, {2, "b"}}},
+				{job: "foo", state: prowapi.PendingState, prs: []pull{{1, "a"}, {2, "b"}, {3, "c"}}},
+			},
+			pending: true,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -289,6 +327,9 @@ func TestAccumulateBatch(t *testing.T) {
 					},
 					ProwConfig: config.ProwConfig{
 						InRepoConfig: inrepoconfig,
+						Tide: config.Tide{
+							SpeculativeBatchDepthMap: map[string]int{"*": test.speculativeDepth},
+						},
 					},
 				}
 			}
@@ -307,6 +348,108 @@ func TestAccumulateBatch(t *testing.T) {
 	}
 }
 
+func TestSpeculativeBatches(t *testing.T) {
+	var batch []CodeReviewCommon
+	for i := 1; i <= 6; i++ {
+		batch = append(batch, CodeReviewCommon{Number: i})
+	}
+	for _, tc := range []struct {
+		name  string
+		size  int
+		depth int
+		want  [][]int
+	}{
+		{
+			name:  "as many PRs as the depth",
+			size:  3,
+			depth: 3,
+			want:  [][]int{{1}, {1, 2}, {1, 2, 3}},
+		},
+		{
+			name:  "fewer PRs than the depth",
+			size:  2,
+			depth: 3,
+			want:  [][]int{{1}, {1, 2}},
+		},
+		{
+			name:  "more PRs than the depth",
+			size:  6,
+			depth: 4,
+			want:  [][]int{{1, 2}, {1, 2, 3}, {1, 2, 3, 4, 5}, {1, 2, 3, 4, 5, 6}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var got [][]int
+			for _, prs := range speculativeBatches(batch[:tc.size], tc.depth) {
+				got = append(got, prNumbers(prs))
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected speculative batches: %s", diff)
+			}
+		})
+	}
+}
+
+func TestSpeculativeBatchMap(t *testing.T) {
+	batchJob := func(state prowapi.ProwJobState, pulls ...prowapi.Pull) prowapi.ProwJob {
+		return prowapi.ProwJob{
+			Spec:   prowapi.ProwJobSpec{Type: prowapi.BatchJob, Refs: &prowapi.Refs{Org: "org", Repo: "repo", Pulls: pulls}},
+			Status: prowapi.ProwJobStatus{State: state},
+		}
+	}
+	a, b, c := prowapi.Pull{Number: 1, SHA: "a"}, prowapi.Pull{Number: 2, SHA: "b"}, prowapi.Pull{Number: 3, SHA: "c"}
+	sp := &subpool{
+		org:  "org",
+		repo: "repo",
+		prs: []CodeReviewCommon{
+			{Org: "org", Repo: "repo", NameWithOwner: "org/repo", Number: 1, HeadRefOID: "a"},
+			{Org: "org", Repo: "repo", NameWithOwner: "org/repo", Number: 2, HeadRefOID: "b"},
+			{Org: "org", Repo: "repo", NameWithOwner: "org/repo", Number: 3, HeadRefOID: "c"},
+			{Org: "org", Repo: "repo", NameWithOwner: "org/repo", Number: 4, HeadRefOID: "d"},
+		},
+		pjs: []prowapi.ProwJob{
+			batchJob(prowapi.PendingState, a),
+			batchJob(prowapi.TriggeredState, a, b),
+			batchJob(prowapi.PendingState, a, b, c),
+			// Finished and outdated batches are ignored.
+			batchJob(prowapi.FailureState, a, b, c, prowapi.Pull{Number: 4, SHA: "d"}),
+			batchJob(prowapi.PendingState, a, prowapi.Pull{Number: 4, SHA: "old"}),
+		},
+	}
+
+	for _, tc := range []struct {
+		name  string
+		depth int
+		want  map[string]string
+	}{
+		{
+			name:  "speculative batches disabled",
+			depth: 1,
+			want:  map[string]string{},
+		},
+		{
+			name:  "speculative batches enabled",
+			depth: 3,
+			want: map[string]string{
+				"org/repo#1": "In merge pool. Speculative batch 1/3.",
+				"org/repo#2": "In merge pool. Speculative batch 2/3.",
+				"org/repo#3": "In merge pool. Speculative batch 3/3.",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tide := &config.Tide{SpeculativeBatchDepthMap: map[string]int{"org/repo": tc.depth}}
+			got := map[string]string{}
+			for key, sb := range speculativeBatchMap(map[string]*subpool{"org/repo:main": sp}, tide) {
+				got[key] = sb.status()
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected speculative batches: %s", diff)
+			}
+		})
+	}
+}
+
 func TestAccumulate(t *testing.T) {
 
 	const baseSHA = "8d287a3aeae90fd0aef4a70009c715712ff302cd"
@@ -0,0 +1,179 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing exports OpenTelemetry spans and carries their trace context
+// across components on the ProwJobs, so that a webhook can be followed through
+// hook, the ProwJob it triggered, its pod and its reports.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	prowv1 "sigs.k8s.io/prow/pkg/client/clientset/versioned/typed/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/flagutil"
+	"sigs.k8s.io/prow/pkg/interrupts"
+)
+
+const (
+	// TraceParentAnnotation holds the W3C trace context of the span the
+	// ProwJob was created in, the components handling the ProwJob continue
+	// that trace.
+	TraceParentAnnotation = "prow.k8s.io/traceparent"
+
+	instrumentationName = "sigs.k8s.io/prow"
+)
+
+// propagator is used explicitly rather than through the global propagator,
+// so that the trace context is carried whether tracing is enabled or not.
+var propagator = propagation.TraceContext{}
+
+// Init exports the spans of the component to the OTLP/HTTP endpoint of the
+// options. Tracing is disabled if no endpoint is set.
+func Init(component string, o flagutil.InstrumentationOptions) error {
+	if o.TracingEndpoint == "" {
+		return nil
+	}
+	exporter, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(o.TracingEndpoint))
+	if err != nil {
+		return fmt.Errorf("failed to create the OTLP exporter: %w", err)
+	}
+	provider := NewTracerProvider(component, o.TracingSampleRatio, sdktrace.WithBatcher(exporter))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagator)
+	interrupts.OnInterrupt(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			logrus.WithError(err).Warn("Failed to export the remaining spans.")
+		}
+	})
+	return nil
+}
+
+// NewTracerProvider returns a provider of the spans of the component. The
+// ratio applies to the traces started by the component, the traces continued
+// from other components follow the sampling decision of their parent.
+func NewTracerProvider(component string, sampleRatio float64, opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	opts = append([]sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(component))),
+	}, opts...)
+	return sdktrace.NewTracerProvider(opts...)
+}
+
+// Tracer returns the tracer of the spans of Prow.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// End records the error, if any, and ends the span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject stores the trace context of ctx on the ProwJob.
+func Inject(ctx context.Context, pj *prowapi.ProwJob) {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	traceParent := carrier.Get("traceparent")
+	if traceParent == "" {
+		return
+	}
+	if pj.Annotations == nil {
+		pj.Annotations = map[string]string{}
+	}
+	pj.Annotations[TraceParentAnnotation] = traceParent
+}
+
+// Extract returns ctx with the trace context stored on the ProwJob, if any.
+func Extract(ctx context.Context, pj *prowapi.ProwJob) context.Context {
+	traceParent, ok := pj.Annotations[TraceParentAnnotation]
+	if !ok {
+		return ctx
+	}
+	return propagator.Extract(ctx, propagation.MapCarrier{"traceparent": traceParent})
+}
+
+// InjectHeader stores the trace context of ctx in the headers of a request.
+func InjectHeader(ctx context.Context, header http.Header) {
+	propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// ExtractHeader returns ctx with the trace context stored in the headers of a
+// request, if any.
+func ExtractHeader(ctx context.Context, header http.Header) context.Context {
+	return propagator.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// StartProwJobSpan starts a span continuing the trace the ProwJob was created in.
+func StartProwJobSpan(ctx context.Context, name string, pj *prowapi.ProwJob, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(Extract(ctx, pj), name, trace.WithAttributes(prowJobAttributes(pj, attrs)...))
+}
+
+// RecordProwJobSpan records a span of the ProwJob from start until now, for
+// the operations only worth tracing once they turned out to do some work.
+func RecordProwJobSpan(ctx context.Context, name string, pj *prowapi.ProwJob, start time.Time, err error, attrs ...attribute.KeyValue) {
+	_, span := Tracer().Start(Extract(ctx, pj), name, trace.WithTimestamp(start), trace.WithAttributes(prowJobAttributes(pj, attrs)...))
+	End(span, err)
+}
+
+func prowJobAttributes(pj *prowapi.ProwJob, attrs []attribute.KeyValue) []attribute.KeyValue {
+	return append([]attribute.KeyValue{
+		attribute.String("prowjob.name", pj.Name),
+		attribute.String("prowjob.job", pj.Spec.Job),
+		attribute.String("prowjob.type", string(pj.Spec.Type)),
+		attribute.String("prowjob.state", string(pj.Status.State)),
+	}, attrs...)
+}
+
+// ProwJobClient returns a client storing the trace context of ctx on the
+// ProwJobs it creates.
+func ProwJobClient(ctx context.Context, client prowv1.ProwJobInterface) prowv1.ProwJobInterface {
+	if client == nil {
+		return nil
+	}
+	return &prowJobClient{ProwJobInterface: client, ctx: ctx}
+}
+
+type prowJobClient struct {
+	prowv1.ProwJobInterface
+	ctx context.Context
+}
+
+func (c *prowJobClient) Create(ctx context.Context, pj *prowapi.ProwJob, opts metav1.CreateOptions) (*prowapi.ProwJob, error) {
+	Inject(c.ctx, pj)
+	return c.ProwJobInterface.Create(ctx, pj, opts)
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	prowapi "sigs.k8s.io/prow/pkg/apis/prowjobs/v1"
+	"sigs.k8s.io/prow/pkg/client/clientset/versioned/fake"
+)
+
+// recordSpans makes the global provider record the spans in memory for the
+// duration of the test.
+func recordSpans(t *testing.T, sampleRatio float64) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := NewTracerProvider("test", sampleRatio, sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return exporter
+}
+
+func TestProwJobContinuesTrace(t *testing.T) {
+	exporter := recordSpans(t, 1)
+	ctx, hookSpan := Tracer().Start(context.Background(), "hook.plugin")
+	client := ProwJobClient(ctx, fake.NewSimpleClientset().ProwV1().ProwJobs("prowjobs"))
+	created, err := client.Create(context.Background(), &prowapi.ProwJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "pj", Namespace: "prowjobs"},
+		Spec:       prowapi.ProwJobSpec{Job: "job", Type: prowapi.PresubmitJob},
+		Status:     prowapi.ProwJobStatus{State: prowapi.TriggeredState},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("failed to create the ProwJob: %v", err)
+	}
+	hookSpan.End()
+	if created.Annotations[TraceParentAnnotation] == "" {
+		t.Fatalf("expected the %s annotation, got %v", TraceParentAnnotation, created.Annotations)
+	}
+
+	_, plankSpan := StartProwJobSpan(context.Background(), "plank.reconcile", created)
+	End(plankSpan, errors.New("failed to create the pod"))
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	hook, plank := spans[0], spans[1]
+	if hook.SpanContext.TraceID() != plank.SpanContext.TraceID() {
+		t.Errorf("expected the spans to be in the same trace, got %s and %s", hook.SpanContext.TraceID(), plank.SpanContext.TraceID())
+	}
+	if plank.Parent.SpanID() != hook.SpanContext.SpanID() {
+		t.Errorf("expected the plank span to be a child of the hook span")
+	}
+	if plank.Status.Code != codes.Error {
+		t.Errorf("expected the error to be recorded, got status %v", plank.Status)
+	}
+	attrs := map[string]string{}
+	for _, attr := range plank.Attributes {
+		attrs[string(attr.Key)] = attr.Value.Emit()
+	}
+	if attrs["prowjob.job"] != "job" || attrs["prowjob.state"] != "triggered" {
+		t.Errorf("unexpected attributes %v", attrs)
+	}
+}
+
+func TestInjectWithoutSpan(t *testing.T) {
+	recordSpans(t, 1)
+	pj := &prowapi.ProwJob{}
+	Inject(context.Background(), pj)
+	if _, ok := pj.Annotations[TraceParentAnnotation]; ok {
+		t.Errorf("expected no trace context outside of a span, got %v", pj.Annotations)
+	}
+	if ctx := Extract(context.Background(), pj); ctx != context.Background() {
+		t.Errorf("expected the context to be unchanged")
+	}
+}
+
+func TestSampling(t *testing.T) {
+	exporter := recordSpans(t, 0)
+	ctx, span := Tracer().Start(context.Background(), "root")
+	span.End()
+	if span.SpanContext().IsSampled() {
+		t.Errorf("expected the root span not to be sampled")
+	}
+	pj := &prowapi.ProwJob{}
+	Inject(ctx, pj)
+	_, child := StartProwJobSpan(context.Background(), "child", pj)
+	child.End()
+	if child.SpanContext().IsSampled() {
+		t.Errorf("expected the child of an unsampled span not to be sampled")
+	}
+	if n := len(exporter.GetSpans()); n != 0 {
+		t.Errorf("expected no span to be exported, got %d", n)
+	}
+}
+
+func TestRecordProwJobSpan(t *testing.T) {
+	exporter := recordSpans(t, 1)
+	start := time.Now().Add(-time.Second)
+	pj := &prowapi.ProwJob{
+		Spec:   prowapi.ProwJobSpec{Job: "job", Type: prowapi.PresubmitJob},
+		Status: prowapi.ProwJobStatus{State: prowapi.PendingState},
+	}
+	RecordProwJobSpan(context.Background(), "plank.reconcile", pj, start, nil)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if !spans[0].StartTime.Equal(start) {
+		t.Errorf("expected the span to start at %s, got %s", start, spans[0].StartTime)
+	}
+	if spans[0].EndTime.Before(start.Add(time.Second)) {
+		t.Errorf("expected the span to end now, got %s", spans[0].EndTime)
+	}
+}